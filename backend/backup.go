@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"error-logs/internal/database"
+	"error-logs/internal/services"
+)
+
+// runBackupCommand handles "backup <file>" and "restore <file>". The
+// passphrase is read from BACKUP_PASSPHRASE so it stays out of the process
+// list and shell history.
+func runBackupCommand(db *database.DB, command string, args []string) {
+	if len(args) != 1 {
+		log.Fatalf("Usage: %s %s <file>", os.Args[0], command)
+	}
+	passphrase := os.Getenv("BACKUP_PASSPHRASE")
+	if passphrase == "" {
+		log.Fatalf("Set BACKUP_PASSPHRASE to the passphrase of the backup")
+	}
+
+	// Only Postgres is needed; audit entries are written straight to it
+	backupService := services.NewBackupService(db, services.NewAuditService(db, nil))
+	ctx := context.Background()
+
+	if command == "backup" {
+		sealed, err := backupService.CreateBackup(ctx, passphrase, "cli")
+		if err != nil {
+			log.Fatalf("Backup failed: %v", err)
+		}
+		if err := os.WriteFile(args[0], sealed, 0o600); err != nil {
+			log.Fatalf("Failed to write backup: %v", err)
+		}
+		log.Printf("Wrote encrypted backup to %s", args[0])
+		return
+	}
+
+	sealed, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Fatalf("Failed to read backup: %v", err)
+	}
+	result, err := backupService.RestoreBackup(ctx, sealed, passphrase, "cli")
+	if err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+	log.Printf("Restored %v; skipped existing %v", result.Restored, result.Skipped)
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -9,6 +11,126 @@ type Config struct {
 	RedisURL    string
 	Port        string
 	Environment string
+
+	SpikeThresholdPerMinute int
+	SpikeSampleRate         int
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	LogDebugSampleRate int
+	LogInfoSampleRate  int
+	LogRetentionDays   int
+
+	TrashRetentionDays int
+
+	SymbolStorageDir  string
+	SymbolMaxUploadMB int
+
+	MinidumpStorageDir  string
+	MinidumpMaxUploadMB int
+
+	ExportStorageDir     string
+	ExportRetentionHours int
+	ExportSigningKey     string
+	ExportS3Bucket       string
+	ExportS3Region       string
+	ExportS3Endpoint     string
+	ExportS3Prefix       string
+	AWSAccessKeyID       string
+	AWSSecretAccessKey   string
+
+	GoInAppPrefixes []string
+
+	PublicKeyRateLimitPerMinute int
+
+	MaxBodyKB      int
+	MaxEventBodyKB int
+	MaxBatchBodyKB int
+	MaxImportMB    int
+
+	RequestTimeoutSeconds     int
+	IngestTimeoutSeconds      int
+	LongRequestTimeoutSeconds int
+
+	SlowQueryThresholdMS int
+
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeSeconds int
+	DBConnMaxIdleTimeSeconds int
+
+	StartupWaitSeconds int
+
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	ACMEDomains  []string
+	ACMEEmail    string
+	ACMECacheDir string
+
+	// TrustedProxies are the CIDRs whose forwarding headers name the client
+	TrustedProxies []string
+
+	SignatureToleranceSeconds int
+
+	EncryptionKeys     []string
+	EncryptIPAddresses bool
+
+	ReadOnlyMode bool
+
+	// BackupAPIEnabled serves POST /api/admin/backup and /api/admin/restore
+	BackupAPIEnabled bool
+
+	MaxQueueLength int
+
+	MaxContextKeys       int
+	MaxContextValueBytes int
+
+	LeaderLeaseSeconds int
+
+	CacheWarmIntervalSeconds    int
+	CacheRefreshIntervalSeconds int
+
+	RedisCacheBudgetMB              int
+	RedisMemoryCheckIntervalSeconds int
+
+	HostDownAfterSeconds     int
+	HostMetricsRetentionDays int
+
+	GELFUDPAddr   string
+	GELFTCPAddr   string
+	GELFProjectID string
+
+	VercelLogDrainSecret string
+	VercelVerifyToken    string
+
+	ErrorClusteringIntervalMinutes   int
+	ErrorClusteringSimilarityPercent int
+
+	LLMAPIURL          string
+	LLMAPIKey          string
+	LLMModel           string
+	LLMSummaryTTLHours int
+
+	ReportRecipients []string
+	ReportPeriods    []string
+	ReportTimezone   string
+
+	BillingWebhookURL    string
+	BillingWebhookSecret string
+
+	NotificationBatchThreshold     int
+	NotificationBatchWindowSeconds int
+
+	OpenSearchURL      string
+	OpenSearchIndex    string
+	OpenSearchUsername string
+	OpenSearchPassword string
 }
 
 func Load() *Config {
@@ -17,6 +139,124 @@ func Load() *Config {
 		RedisURL:    getEnvOrDefault("REDIS_URL", "redis://localhost:6379"),
 		Port:        getEnvOrDefault("PORT", "8080"),
 		Environment: getEnvOrDefault("ENVIRONMENT", "development"),
+
+		SpikeThresholdPerMinute: getEnvIntOrDefault("SPIKE_THRESHOLD_PER_MINUTE", 1000),
+		SpikeSampleRate:         getEnvIntOrDefault("SPIKE_SAMPLE_RATE", 100),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvOrDefault("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getEnvOrDefault("SMTP_FROM", "alerts@error-logs.local"),
+
+		LogDebugSampleRate: getEnvIntOrDefault("LOG_DEBUG_SAMPLE_RATE", 10),
+		LogInfoSampleRate:  getEnvIntOrDefault("LOG_INFO_SAMPLE_RATE", 1),
+		LogRetentionDays:   getEnvIntOrDefault("LOG_RETENTION_DAYS", 7),
+
+		TrashRetentionDays: getEnvIntOrDefault("TRASH_RETENTION_DAYS", 30),
+
+		SymbolStorageDir:  getEnvOrDefault("SYMBOL_STORAGE_DIR", "./data/symbols"),
+		SymbolMaxUploadMB: getEnvIntOrDefault("SYMBOL_MAX_UPLOAD_MB", 512),
+
+		MinidumpStorageDir:  getEnvOrDefault("MINIDUMP_STORAGE_DIR", "./data/minidumps"),
+		MinidumpMaxUploadMB: getEnvIntOrDefault("MINIDUMP_MAX_UPLOAD_MB", 100),
+
+		ExportStorageDir:     getEnvOrDefault("EXPORT_STORAGE_DIR", "./data/exports"),
+		ExportRetentionHours: getEnvIntOrDefault("EXPORT_RETENTION_HOURS", 24),
+		ExportSigningKey:     os.Getenv("EXPORT_SIGNING_KEY"),
+		ExportS3Bucket:       os.Getenv("EXPORT_S3_BUCKET"),
+		ExportS3Region:       getEnvOrDefault("EXPORT_S3_REGION", "us-east-1"),
+		ExportS3Endpoint:     os.Getenv("EXPORT_S3_ENDPOINT"),
+		ExportS3Prefix:       getEnvOrDefault("EXPORT_S3_PREFIX", "exports/"),
+		AWSAccessKeyID:       os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+
+		GoInAppPrefixes: getEnvListOrDefault("GO_IN_APP_PREFIXES", nil),
+
+		PublicKeyRateLimitPerMinute: getEnvIntOrDefault("PUBLIC_KEY_RATE_LIMIT_PER_MINUTE", 30),
+
+		MaxBodyKB:      getEnvIntOrDefault("MAX_BODY_KB", 1024),
+		MaxEventBodyKB: getEnvIntOrDefault("MAX_EVENT_BODY_KB", 256),
+		MaxBatchBodyKB: getEnvIntOrDefault("MAX_BATCH_BODY_KB", 5120),
+		MaxImportMB:    getEnvIntOrDefault("MAX_IMPORT_MB", 100),
+
+		RequestTimeoutSeconds:     getEnvIntOrDefault("REQUEST_TIMEOUT_SECONDS", 30),
+		IngestTimeoutSeconds:      getEnvIntOrDefault("INGEST_TIMEOUT_SECONDS", 10),
+		LongRequestTimeoutSeconds: getEnvIntOrDefault("LONG_REQUEST_TIMEOUT_SECONDS", 300),
+
+		SlowQueryThresholdMS: getEnvIntOrDefault("SLOW_QUERY_THRESHOLD_MS", 500),
+
+		DBMaxOpenConns:           getEnvIntOrDefault("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:           getEnvIntOrDefault("DB_MAX_IDLE_CONNS", 25),
+		DBConnMaxLifetimeSeconds: getEnvIntOrDefault("DB_CONN_MAX_LIFETIME_SECONDS", 300),
+		DBConnMaxIdleTimeSeconds: getEnvIntOrDefault("DB_CONN_MAX_IDLE_TIME_SECONDS", 0),
+
+		StartupWaitSeconds: getEnvIntOrDefault("STARTUP_WAIT_SECONDS", 60),
+
+		TLSCertFile:     os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:      os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+
+		ACMEDomains:  getEnvListOrDefault("ACME_DOMAINS", nil),
+		ACMEEmail:    os.Getenv("ACME_EMAIL"),
+		ACMECacheDir: getEnvOrDefault("ACME_CACHE_DIR", "./certs"),
+
+		TrustedProxies: getEnvListOrDefault("TRUSTED_PROXIES", nil),
+
+		SignatureToleranceSeconds: getEnvIntOrDefault("SIGNATURE_TOLERANCE_SECONDS", 300),
+
+		EncryptionKeys:     getEnvListOrDefault("ENCRYPTION_KEYS", nil),
+		EncryptIPAddresses: getEnvOrDefault("ENCRYPT_IP_ADDRESSES", "false") == "true",
+
+		ReadOnlyMode: getEnvOrDefault("READ_ONLY_MODE", "false") == "true",
+
+		BackupAPIEnabled: getEnvOrDefault("BACKUP_API_ENABLED", "false") == "true",
+
+		MaxQueueLength: getEnvIntOrDefault("MAX_QUEUE_LENGTH", 100000),
+
+		MaxContextKeys:       getEnvIntOrDefault("MAX_CONTEXT_KEYS", 100),
+		MaxContextValueBytes: getEnvIntOrDefault("MAX_CONTEXT_VALUE_BYTES", 8192),
+
+		LeaderLeaseSeconds: getEnvIntOrDefault("LEADER_LEASE_SECONDS", 15),
+
+		CacheWarmIntervalSeconds:    getEnvIntOrDefault("CACHE_WARM_INTERVAL_SECONDS", 30),
+		CacheRefreshIntervalSeconds: getEnvIntOrDefault("CACHE_REFRESH_INTERVAL_SECONDS", 15),
+
+		RedisCacheBudgetMB:              getEnvIntOrDefault("REDIS_CACHE_BUDGET_MB", 256),
+		RedisMemoryCheckIntervalSeconds: getEnvIntOrDefault("REDIS_MEMORY_CHECK_INTERVAL_SECONDS", 60),
+
+		HostDownAfterSeconds:     getEnvIntOrDefault("HOST_DOWN_AFTER_SECONDS", 180),
+		HostMetricsRetentionDays: getEnvIntOrDefault("HOST_METRICS_RETENTION_DAYS", 7),
+
+		GELFUDPAddr:   getEnvOrDefault("GELF_UDP_ADDR", ""),
+		GELFTCPAddr:   getEnvOrDefault("GELF_TCP_ADDR", ""),
+		GELFProjectID: getEnvOrDefault("GELF_PROJECT_ID", ""),
+
+		VercelLogDrainSecret: getEnvOrDefault("VERCEL_LOG_DRAIN_SECRET", ""),
+		VercelVerifyToken:    getEnvOrDefault("VERCEL_VERIFY_TOKEN", ""),
+
+		ErrorClusteringIntervalMinutes:   getEnvIntOrDefault("ERROR_CLUSTERING_INTERVAL_MINUTES", 0),
+		ErrorClusteringSimilarityPercent: getEnvIntOrDefault("ERROR_CLUSTERING_SIMILARITY_PERCENT", 70),
+
+		LLMAPIURL:          getEnvOrDefault("LLM_API_URL", ""),
+		LLMAPIKey:          getEnvOrDefault("LLM_API_KEY", ""),
+		LLMModel:           getEnvOrDefault("LLM_MODEL", "gpt-4o-mini"),
+		LLMSummaryTTLHours: getEnvIntOrDefault("LLM_SUMMARY_TTL_HOURS", 168),
+
+		ReportRecipients: getEnvListOrDefault("REPORT_RECIPIENTS", nil),
+		ReportPeriods:    getEnvListOrDefault("REPORT_PERIODS", []string{"weekly"}),
+		ReportTimezone:   getEnvOrDefault("REPORT_TIMEZONE", "UTC"),
+
+		BillingWebhookURL:    os.Getenv("BILLING_WEBHOOK_URL"),
+		BillingWebhookSecret: os.Getenv("BILLING_WEBHOOK_SECRET"),
+
+		NotificationBatchThreshold:     getEnvIntOrDefault("NOTIFICATION_BATCH_THRESHOLD", 10),
+		NotificationBatchWindowSeconds: getEnvIntOrDefault("NOTIFICATION_BATCH_WINDOW_SECONDS", 300),
+
+		OpenSearchURL:      os.Getenv("OPENSEARCH_URL"),
+		OpenSearchIndex:    getEnvOrDefault("OPENSEARCH_INDEX", "error-events"),
+		OpenSearchUsername: os.Getenv("OPENSEARCH_USERNAME"),
+		OpenSearchPassword: os.Getenv("OPENSEARCH_PASSWORD"),
 	}
 }
 
@@ -26,3 +266,25 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return defaultValue
+}
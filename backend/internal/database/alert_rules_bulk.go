@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ApplyAlertRules creates, updates and deletes alert rules in one
+// transaction, so a rule document is applied completely or not at all
+func (db *DB) ApplyAlertRules(created, updated []models.AlertRule, deleted []uuid.UUID) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range created {
+		if err := db.insertAlertRule(tx, &created[i]); err != nil {
+			return fmt.Errorf("failed to create alert rule %q: %w", created[i].Name, err)
+		}
+	}
+	for i := range updated {
+		if err := db.updateAlertRule(tx, &updated[i], 0); err != nil {
+			return fmt.Errorf("failed to update alert rule %q: %w", updated[i].Name, err)
+		}
+	}
+	for _, id := range deleted {
+		if _, err := tx.Exec("DELETE FROM alert_rules WHERE id = $1", id); err != nil {
+			return fmt.Errorf("failed to delete alert rule %s: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
+}
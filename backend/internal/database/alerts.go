@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// alertMetricExpressions maps alert condition metrics to their SQL aggregate
+var alertMetricExpressions = map[string]string{
+	"error_count":      "COUNT(*)",
+	"error_rate":       "COUNT(*) / GREATEST($1 / 60.0, 1)", // errors per minute; $1 is the window in seconds
+	"critical_count":   "COUNT(*) FILTER (WHERE level = 'error')",
+	"unresolved_count": "COUNT(*) FILTER (WHERE resolved = false)",
+	"event_count":      "COUNT(*) + (%s)", // errors plus log events; see logEventCountExpression
+	"hosts_down":       "(SELECT COUNT(*) FROM hosts WHERE last_seen < NOW() - make_interval(secs => $1))",
+}
+
+// logEventCountExpression counts log events in the window for event_count.
+// Log events have no fingerprint, so fingerprint-scoped rules only count errors.
+const logEventCountExpression = "SELECT COUNT(*) FROM log_events WHERE timestamp >= NOW() - make_interval(secs => $1)%s"
+
+// CustomMetricPrefix marks alert metrics that read from custom metric rollups,
+// e.g. "metric:checkout.completed"
+const CustomMetricPrefix = "metric:"
+
+// customMetricExpression evaluates a custom metric over the window: the total
+// for counters and the average for gauges. $1 is the window in seconds.
+const customMetricExpression = `(
+	SELECT COALESCE(CASE WHEN bool_and(metric_type = 'gauge')
+		THEN SUM(value_sum) / NULLIF(SUM(sample_count), 0)
+		ELSE SUM(value_sum) END, 0)
+	FROM metric_rollups
+	WHERE name = $%d AND resolution = 'minute'
+	  AND bucket >= date_trunc('minute', NOW() - make_interval(secs => $1))
+)`
+
+// IsAlertMetric reports whether the metric can be evaluated by the alert evaluator
+func IsAlertMetric(metric string) bool {
+	if name, ok := strings.CutPrefix(metric, CustomMetricPrefix); ok {
+		return name != ""
+	}
+	_, ok := alertMetricExpressions[metric]
+	return ok
+}
+
+// buildAlertScope compiles an alert scope into a WHERE clause over errors,
+// starting placeholders at argIndex
+func buildAlertScope(scope models.AlertScope, argIndex int) (string, []interface{}) {
+	whereClause := ""
+	args := []interface{}{}
+
+	if scope.ProjectID != nil {
+		whereClause += fmt.Sprintf(" AND project_id = $%d", argIndex)
+		args = append(args, *scope.ProjectID)
+		argIndex++
+	}
+	if scope.Source != "" {
+		whereClause += fmt.Sprintf(" AND source = $%d", argIndex)
+		args = append(args, scope.Source)
+		argIndex++
+	}
+	if scope.Environment != "" {
+		whereClause += fmt.Sprintf(" AND environment = $%d", argIndex)
+		args = append(args, scope.Environment)
+		argIndex++
+	}
+	if scope.Fingerprint != "" {
+		whereClause += fmt.Sprintf(" AND fingerprint = $%d", argIndex)
+		args = append(args, scope.Fingerprint)
+		argIndex++
+	}
+	if scope.Level != "" {
+		whereClause += fmt.Sprintf(" AND level = $%d", argIndex)
+		args = append(args, scope.Level)
+		argIndex++
+	}
+
+	return whereClause, args
+}
+
+// EvaluateAlertMetrics computes several metrics over the errors in the scope
+// during the trailing window with a single query, so they share a snapshot
+func (db *DB) EvaluateAlertMetrics(metrics []string, scope models.AlertScope, window time.Duration) (map[string]float64, error) {
+	if len(metrics) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	scopeClause, scopeArgs := buildAlertScope(scope, 2)
+	args := append([]interface{}{window.Seconds()}, scopeArgs...)
+
+	// Custom metrics are scalar subqueries in the same statement, so they are
+	// read from the same snapshot as the error metrics
+	expressions := make([]string, len(metrics))
+	for i, metric := range metrics {
+		if name, ok := strings.CutPrefix(metric, CustomMetricPrefix); ok && name != "" {
+			args = append(args, name)
+			expressions[i] = fmt.Sprintf("("+customMetricExpression+")::float8", len(args))
+			continue
+		}
+
+		expression, ok := alertMetricExpressions[metric]
+		if !ok {
+			return nil, fmt.Errorf("unknown alert metric: %s", metric)
+		}
+		if metric == "event_count" {
+			logCount := "0"
+			if scope.Fingerprint == "" {
+				// The scope placeholders are shared; log_events has the same columns
+				logCount = fmt.Sprintf(logEventCountExpression, scopeClause)
+			}
+			expression = fmt.Sprintf(expression, logCount)
+		}
+		expressions[i] = fmt.Sprintf("(%s)::float8", expression)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM errors
+		WHERE timestamp >= NOW() - make_interval(secs => $1) AND deleted_at IS NULL%s
+	`, strings.Join(expressions, ", "), scopeClause)
+
+	results := make([]float64, len(metrics))
+	dest := make([]interface{}, len(metrics))
+	for i := range results {
+		dest[i] = &results[i]
+	}
+
+	if err := db.QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to evaluate alert metrics: %w", err)
+	}
+
+	values := make(map[string]float64, len(metrics))
+	for i, metric := range metrics {
+		values[metric] = results[i]
+	}
+
+	return values, nil
+}
+
+// GetErrorCountBuckets returns how many errors in the scope arrived in each
+// bucket-sized interval from since until now, oldest first, with zeros for
+// empty buckets
+func (db *DB) GetErrorCountBuckets(scope models.AlertScope, bucket time.Duration, since time.Time) ([]int, error) {
+	scopeClause, scopeArgs := buildAlertScope(scope, 3)
+	args := append([]interface{}{since, bucket.Seconds()}, scopeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT FLOOR(EXTRACT(EPOCH FROM timestamp - $1) / $2)::int AS idx, COUNT(*)
+		FROM errors
+		WHERE timestamp >= $1 AND deleted_at IS NULL%s
+		GROUP BY idx
+	`, scopeClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error history: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]int, int(time.Since(since)/bucket)+1)
+	for rows.Next() {
+		var idx, count int
+		if err := rows.Scan(&idx, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan error history: %w", err)
+		}
+		if idx >= 0 && idx < len(counts) {
+			counts[idx] = count
+		}
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const annotationColumns = "id, project_id, environment, source, type, text, url, incident_id, created_by, timestamp, created_at"
+
+func (db *DB) CreateAnnotation(annotation *models.Annotation) error {
+	query := fmt.Sprintf(`
+		INSERT INTO annotations (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, annotationColumns)
+
+	_, err := db.Exec(query,
+		annotation.ID, annotation.ProjectID, annotation.Environment, annotation.Source,
+		annotation.Type, annotation.Text, annotation.URL, annotation.IncidentID,
+		annotation.CreatedBy, annotation.Timestamp, annotation.CreatedAt,
+	)
+	return err
+}
+
+// GetAnnotations returns a page of the annotations matching filter, newest
+// first, and how many match in total. A project filter includes annotations
+// that apply to every project.
+func (db *DB) GetAnnotations(limit, offset int, filter models.AnnotationFilter) ([]models.Annotation, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.ProjectID != nil {
+		conditions = append(conditions, fmt.Sprintf("(project_id = $%d OR project_id IS NULL)", argIndex))
+		args = append(args, *filter.ProjectID)
+		argIndex++
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, fmt.Sprintf("type = $%d", argIndex))
+		args = append(args, filter.Type)
+		argIndex++
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argIndex))
+		args = append(args, *filter.Since)
+		argIndex++
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp < $%d", argIndex))
+		args = append(args, *filter.Until)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM annotations "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count annotations: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM annotations %s
+		ORDER BY timestamp DESC
+		LIMIT $%d OFFSET $%d
+	`, annotationColumns, whereClause, argIndex, argIndex+1)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	annotations, err := scanAnnotations(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return annotations, total, nil
+}
+
+// GetTrendAnnotations returns the annotations since the given time that
+// apply to a stats scope, oldest first. Annotations without a project,
+// environment or source apply to any.
+func (db *DB) GetTrendAnnotations(filter models.StatsFilter, since time.Time) ([]models.Annotation, error) {
+	whereClause := "WHERE timestamp >= $1"
+	args := []interface{}{since}
+
+	if filter.ProjectID != nil {
+		args = append(args, *filter.ProjectID)
+		whereClause += fmt.Sprintf(" AND (project_id = $%d OR project_id IS NULL)", len(args))
+	}
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		whereClause += fmt.Sprintf(" AND (environment = $%d OR environment IS NULL)", len(args))
+	}
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		whereClause += fmt.Sprintf(" AND (source = $%d OR source IS NULL)", len(args))
+	}
+
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT %s FROM annotations %s ORDER BY timestamp", annotationColumns, whereClause,
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trend annotations: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAnnotations(rows)
+}
+
+func (db *DB) DeleteAnnotation(id uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM annotations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("annotation not found")
+	}
+	return nil
+}
+
+func scanAnnotations(rows *sql.Rows) ([]models.Annotation, error) {
+	annotations := []models.Annotation{}
+	for rows.Next() {
+		var a models.Annotation
+		err := rows.Scan(
+			&a.ID, &a.ProjectID, &a.Environment, &a.Source, &a.Type, &a.Text,
+			&a.URL, &a.IncidentID, &a.CreatedBy, &a.Timestamp, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, rows.Err()
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+func (db *DB) CreateAuditLogEntry(entry *models.AuditLogEntry) error {
+	query := `
+		INSERT INTO audit_log (id, action, actor, target_type, target_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	detailsJSON, err := json.Marshal(entry.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	_, err = db.Exec(query,
+		entry.ID, entry.Action, entry.Actor, entry.TargetType, entry.TargetID,
+		detailsJSON, entry.CreatedAt,
+	)
+
+	return err
+}
+
+// GetAuditLog returns matching entries newest first, and the total number of matches
+func (db *DB) GetAuditLog(filter models.AuditLogFilter, limit, offset int) ([]models.AuditLogEntry, int, error) {
+	whereClause, args := auditLogWhere(filter)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_log "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit log: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, action, actor, target_type, target_id, details, created_at
+		FROM audit_log %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)+1, len(args)+2)
+
+	entries, err := db.queryAuditLog(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// GetAuditLogBefore returns the next page of matching entries after the
+// (created_at, id) position, newest first. A nil beforeID starts from the
+// newest entry.
+func (db *DB) GetAuditLogBefore(filter models.AuditLogFilter, beforeCreatedAt time.Time, beforeID *uuid.UUID, limit int) ([]models.AuditLogEntry, error) {
+	whereClause, args := auditLogWhere(filter)
+	if beforeID != nil {
+		whereClause += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, beforeCreatedAt, *beforeID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, action, actor, target_type, target_id, details, created_at
+		FROM audit_log %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, whereClause, len(args)+1)
+
+	return db.queryAuditLog(query, append(args, limit)...)
+}
+
+func auditLogWhere(filter models.AuditLogFilter) (string, []interface{}) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+
+	for _, f := range []struct {
+		column string
+		value  string
+	}{
+		{"action", filter.Action},
+		{"actor", filter.Actor},
+		{"target_type", filter.TargetType},
+		{"target_id", filter.TargetID},
+	} {
+		if f.value == "" {
+			continue
+		}
+		args = append(args, f.value)
+		whereClause += fmt.Sprintf(" AND %s = $%d", f.column, len(args))
+	}
+	return whereClause, args
+}
+
+func (db *DB) queryAuditLog(query string, args ...interface{}) ([]models.AuditLogEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditLogEntry{}
+	for rows.Next() {
+		var entry models.AuditLogEntry
+		var detailsJSON []byte
+		err := rows.Scan(
+			&entry.ID, &entry.Action, &entry.Actor, &entry.TargetType, &entry.TargetID,
+			&detailsJSON, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		if err := json.Unmarshal(detailsJSON, &entry.Details); err != nil || entry.Details == nil {
+			entry.Details = map[string]interface{}{}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+func (db *DB) GetAutoResolvePolicies() ([]models.AutoResolvePolicy, error) {
+	rows, err := db.Query("SELECT project_id, days, updated_at FROM auto_resolve_policies")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auto-resolve policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.AutoResolvePolicy{}
+	for rows.Next() {
+		var policy models.AutoResolvePolicy
+		if err := rows.Scan(&policy.ProjectID, &policy.Days, &policy.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan auto-resolve policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+func (db *DB) GetAutoResolvePolicy(projectID uuid.UUID) (*models.AutoResolvePolicy, error) {
+	var policy models.AutoResolvePolicy
+	err := db.QueryRow("SELECT project_id, days, updated_at FROM auto_resolve_policies WHERE project_id = $1", projectID).
+		Scan(&policy.ProjectID, &policy.Days, &policy.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("auto-resolve policy not found")
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// UpsertAutoResolvePolicy sets a project's policy, returning "project not
+// found" for unknown projects
+func (db *DB) UpsertAutoResolvePolicy(policy *models.AutoResolvePolicy) error {
+	query := `
+		INSERT INTO auto_resolve_policies (project_id, days, updated_at)
+		SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM projects WHERE id = $1)
+		ON CONFLICT (project_id) DO UPDATE SET days = EXCLUDED.days, updated_at = EXCLUDED.updated_at
+	`
+	result, err := db.Exec(query, policy.ProjectID, policy.Days, policy.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("project not found")
+	}
+	return nil
+}
+
+func (db *DB) DeleteAutoResolvePolicy(projectID uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM auto_resolve_policies WHERE project_id = $1", projectID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("auto-resolve policy not found")
+	}
+	return nil
+}
+
+// AutoResolveStaleErrors resolves a project's unresolved errors whose group
+// has had no events since cutoff, and returns the IDs and fingerprints it
+// resolved. Errors without a fingerprint go by their own timestamp.
+func (db *DB) AutoResolveStaleErrors(projectID uuid.UUID, cutoff time.Time, note string) ([]uuid.UUID, []string, error) {
+	query := `
+		UPDATE errors SET resolved = true, state = 'resolved', auto_resolved = true, resolved_by = 'system',
+			resolved_at = NOW(), resolution_note = $3, updated_at = NOW()
+		WHERE project_id = $1 AND resolved = false AND deleted_at IS NULL AND timestamp < $2
+		  AND (fingerprint IS NULL OR NOT EXISTS (
+			SELECT 1 FROM errors newer
+			WHERE newer.fingerprint = errors.fingerprint AND newer.timestamp >= $2 AND newer.deleted_at IS NULL
+		  ))
+		RETURNING id, fingerprint
+	`
+	rows, err := db.Query(query, projectID, cutoff, note)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to auto-resolve errors: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	var fingerprints []string
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		var fingerprint sql.NullString
+		if err := rows.Scan(&id, &fingerprint); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan auto-resolved error: %w", err)
+		}
+		ids = append(ids, id)
+		if fingerprint.Valid && !seen[fingerprint.String] {
+			seen[fingerprint.String] = true
+			fingerprints = append(fingerprints, fingerprint.String)
+		}
+	}
+	return ids, fingerprints, rows.Err()
+}
+
+// ReopenAutoResolved reopens a group that was auto-resolved as a regression
+// and reports whether it was
+func (db *DB) ReopenAutoResolved(fingerprint string) (bool, error) {
+	query := `
+		UPDATE errors SET resolved = false, state = 'regressed', regressed = true, auto_resolved = false,
+			resolved_by = NULL, resolved_at = NULL, resolution_note = NULL, updated_at = NOW()
+		WHERE fingerprint = $1 AND auto_resolved = true AND state = 'resolved' AND deleted_at IS NULL
+	`
+	result, err := db.Exec(query, fingerprint)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,332 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// BackupFormatVersion is written to new backups; restores refuse newer ones
+const BackupFormatVersion = 1
+
+// GetConfigBackup reads the configuration saved by a backup: organizations,
+// projects, active API keys, alert rules, integrations and the team. Secrets
+// are decrypted, so the result must only be written encrypted.
+func (db *DB) GetConfigBackup() (*models.ConfigBackup, error) {
+	backup := &models.ConfigBackup{FormatVersion: BackupFormatVersion}
+	var err error
+
+	if backup.Organizations, err = db.GetOrganizations(); err != nil {
+		return nil, err
+	}
+	if backup.Projects, err = db.GetProjects(nil); err != nil {
+		return nil, err
+	}
+	if backup.APIKeys, err = db.getAPIKeyBackups(); err != nil {
+		return nil, err
+	}
+	if backup.AlertRules, err = db.GetAlertRules(); err != nil {
+		return nil, err
+	}
+
+	integrations, err := db.GetIntegrations()
+	if err != nil {
+		return nil, err
+	}
+	backup.Integrations = make([]models.IntegrationBackup, len(integrations))
+	for i, integration := range integrations {
+		backup.Integrations[i] = models.IntegrationBackup{Integration: integration, Secret: integration.Secret}
+	}
+
+	if backup.TeamMembers, err = db.GetTeamMembers(); err != nil {
+		return nil, err
+	}
+	if backup.TeamMembers == nil {
+		backup.TeamMembers = []models.TeamMember{}
+	}
+	teams, err := db.GetTeams()
+	if err != nil {
+		return nil, err
+	}
+	backup.Teams = make([]models.TeamBackup, len(teams))
+	for i, team := range teams {
+		memberIDs := make([]uuid.UUID, len(team.Members))
+		for j, member := range team.Members {
+			memberIDs[j] = member.ID
+		}
+		backup.Teams[i] = models.TeamBackup{
+			ID:          team.ID,
+			Name:        team.Name,
+			Description: team.Description,
+			MemberIDs:   memberIDs,
+			CreatedAt:   team.CreatedAt,
+		}
+	}
+
+	return backup, nil
+}
+
+func (db *DB) getAPIKeyBackups() ([]models.APIKeyBackup, error) {
+	keys, err := db.GetAPIKeys(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT id, signing_secret FROM api_keys WHERE active = true AND signing_secret IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signing secrets: %w", err)
+	}
+	defer rows.Close()
+	secrets := make(map[uuid.UUID]*string)
+	for rows.Next() {
+		var id uuid.UUID
+		var secret *string
+		if err := rows.Scan(&id, &secret); err != nil {
+			return nil, fmt.Errorf("failed to scan signing secret: %w", err)
+		}
+		if secrets[id], err = db.decryptOptional(secret); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", id, err)
+		}
+	}
+
+	backups := make([]models.APIKeyBackup, len(keys))
+	for i, key := range keys {
+		backups[i] = models.APIKeyBackup{APIKey: key, KeyHash: key.KeyHash, SigningSecret: secrets[key.ID]}
+	}
+	return backups, nil
+}
+
+// RestoreConfigBackup adds the configuration of a backup in one transaction.
+// Items that already exist are skipped and left as they are: organizations
+// and projects with the same slug, team members with the same email in their
+// organization and teams with the same name stand in for the backed up ones,
+// so items that refer to them are attached to the existing ones.
+func (db *DB) RestoreConfigBackup(backup *models.ConfigBackup) (*models.BackupRestoreResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.BackupRestoreResult{Restored: map[string]int{}, Skipped: map[string]int{}}
+	tally := func(kind string, restored bool) {
+		if restored {
+			result.Restored[kind]++
+		} else {
+			result.Skipped[kind]++
+		}
+	}
+
+	orgIDs := make(map[uuid.UUID]uuid.UUID)
+	for _, org := range backup.Organizations {
+		settingsJSON, err := json.Marshal(org.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal settings: %w", err)
+		}
+		id, restored, err := insertOrFind(tx,
+			fmt.Sprintf("INSERT INTO organizations (%s) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING RETURNING id", organizationColumns),
+			[]interface{}{org.ID, org.Name, org.Slug, settingsJSON, org.CreatedAt},
+			"SELECT id FROM organizations WHERE id = $1 OR slug = $2 ORDER BY id = $1 DESC LIMIT 1", org.ID, org.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore organization %q: %w", org.Slug, err)
+		}
+		orgIDs[org.ID] = id
+		tally("organizations", restored)
+	}
+
+	projectIDs := make(map[uuid.UUID]uuid.UUID)
+	for _, project := range backup.Projects {
+		id, restored, err := insertOrFind(tx,
+			"INSERT INTO projects (id, org_id, name, slug, external_id, version, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING RETURNING id",
+			[]interface{}{project.ID, remapID(orgIDs, project.OrgID), project.Name, project.Slug, project.ExternalID, project.Version, project.CreatedAt},
+			"SELECT id FROM projects WHERE id = $1 OR slug = $2 ORDER BY id = $1 DESC LIMIT 1", project.ID, project.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore project %q: %w", project.Slug, err)
+		}
+		projectIDs[project.ID] = id
+		tally("projects", restored)
+	}
+
+	for _, key := range backup.APIKeys {
+		permissionsJSON, err := json.Marshal(key.Permissions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal permissions: %w", err)
+		}
+		originsJSON, err := json.Marshal(key.AllowedOrigins)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal allowed origins: %w", err)
+		}
+		secret, err := db.encryptOptional(key.SigningSecret)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO api_keys (
+				id, key_hash, name, permissions, project_id, org_id, active, expires_at, created_at, last_used,
+				key_type, allowed_origins, signing_secret, external_id, version
+			) VALUES ($1, $2, $3, $4, $5, $6, true, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT DO NOTHING
+		`, key.ID, key.KeyHash, key.Name, permissionsJSON,
+			remapID(projectIDs, key.ProjectID), remapID(orgIDs, key.OrgID), key.ExpiresAt, key.CreatedAt, key.LastUsed,
+			key.KeyType, originsJSON, secret, key.ExternalID, key.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore API key %q: %w", key.Name, err)
+		}
+		n, _ := res.RowsAffected()
+		tally("api_keys", n > 0)
+	}
+
+	memberIDs := make(map[uuid.UUID]uuid.UUID)
+	for _, member := range backup.TeamMembers {
+		// Looked up first: the unique email constraint doesn't cover members
+		// without an organization
+		orgID := remapID(orgIDs, member.OrgID)
+		id := member.ID
+		err := tx.QueryRow(
+			"SELECT id FROM team_members WHERE id = $1 OR (org_id IS NOT DISTINCT FROM $2 AND email = $3) ORDER BY id = $1 DESC LIMIT 1",
+			member.ID, orgID, member.Email,
+		).Scan(&id)
+		restored := err == sql.ErrNoRows
+		if restored {
+			_, err = tx.Exec(`
+				INSERT INTO team_members (id, name, email, role, status, org_id, last_active, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			`, member.ID, member.Name, member.Email, member.Role, member.Status, orgID, member.LastActive, member.CreatedAt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore team member %q: %w", member.Email, err)
+		}
+		memberIDs[member.ID] = id
+		tally("team_members", restored)
+	}
+
+	teamIDs := make(map[uuid.UUID]uuid.UUID)
+	for _, team := range backup.Teams {
+		id, restored, err := insertOrFind(tx,
+			"INSERT INTO teams (id, name, description, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING RETURNING id",
+			[]interface{}{team.ID, team.Name, team.Description, team.CreatedAt},
+			"SELECT id FROM teams WHERE id = $1 OR name = $2 ORDER BY id = $1 DESC LIMIT 1", team.ID, team.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore team %q: %w", team.Name, err)
+		}
+		teamIDs[team.ID] = id
+		tally("teams", restored)
+		if !restored {
+			continue
+		}
+		for _, memberID := range team.MemberIDs {
+			if mapped, ok := memberIDs[memberID]; ok {
+				memberID = mapped
+			}
+			_, err := tx.Exec("INSERT INTO team_memberships (team_id, member_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, memberID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore members of team %q: %w", team.Name, err)
+			}
+		}
+	}
+
+	for _, rule := range backup.AlertRules {
+		var exists bool
+		err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM alert_rules WHERE id = $1 OR name = $2 OR external_id = $3)",
+			rule.ID, rule.Name, rule.ExternalID,
+		).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check alert rule %q: %w", rule.Name, err)
+		}
+		if exists {
+			tally("alert_rules", false)
+			continue
+		}
+
+		// Evaluation starts over; incidents are not part of a backup
+		rule.State = "ok"
+		rule.ConsecutiveBreaches = 0
+		rule.LastTriggered = nil
+		rule.LastNotified = nil
+		rule.IncidentID = nil
+		rule.PeakValues = nil
+		rule.Scope.ProjectID = remapID(projectIDs, rule.Scope.ProjectID)
+		rule.Notifications = remapTeamTargets(teamIDs, rule.Notifications)
+		if err := db.insertAlertRule(tx, &rule); err != nil {
+			return nil, fmt.Errorf("failed to restore alert rule %q: %w", rule.Name, err)
+		}
+		tally("alert_rules", true)
+	}
+
+	for _, integration := range backup.Integrations {
+		configJSON, err := json.Marshal(integration.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal integration config: %w", err)
+		}
+		secret, err := db.encryptOptional(integration.Secret)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO integrations (id, name, kind, config, secret, enabled, external_id, version, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT DO NOTHING
+		`, integration.ID, integration.Name, integration.Kind, configJSON, secret,
+			integration.Enabled, integration.ExternalID, integration.Version, integration.CreatedAt, integration.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore integration %q: %w", integration.Name, err)
+		}
+		n, _ := res.RowsAffected()
+		tally("integrations", n > 0)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// insertOrFind runs an INSERT ... ON CONFLICT DO NOTHING RETURNING id, and
+// when it conflicts, finds the ID of the row that is already there
+func insertOrFind(tx *sql.Tx, insert string, args []interface{}, find string, findArgs ...interface{}) (uuid.UUID, bool, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(insert, args...).Scan(&id)
+	if err == nil {
+		return id, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return id, false, err
+	}
+	err = tx.QueryRow(find, findArgs...).Scan(&id)
+	return id, false, err
+}
+
+func remapID(ids map[uuid.UUID]uuid.UUID, id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	if mapped, ok := ids[*id]; ok {
+		return &mapped
+	}
+	return id
+}
+
+// remapTeamTargets points team:<id> notification targets at the teams that
+// stand in for the backed up ones
+func remapTeamTargets(teamIDs map[uuid.UUID]uuid.UUID, targets []string) []string {
+	remapped := make([]string, len(targets))
+	for i, target := range targets {
+		remapped[i] = target
+		raw, isTeam := strings.CutPrefix(target, "team:")
+		if !isTeam {
+			continue
+		}
+		if id, err := uuid.Parse(raw); err == nil {
+			if mapped, ok := teamIDs[id]; ok {
+				remapped[i] = "team:" + mapped.String()
+			}
+		}
+	}
+	return remapped
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// GetOrgUsage returns per-organization usage: events accepted and dropped on
+// days in [from, to), and the storage and seats they hold now. orgID limits
+// it to one organization.
+func (db *DB) GetOrgUsage(orgID *uuid.UUID, from, to time.Time) ([]models.OrgUsage, error) {
+	whereClause := ""
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+	if orgID != nil {
+		args = append(args, *orgID)
+		whereClause = "WHERE o.id = $3"
+	}
+
+	query := fmt.Sprintf(`
+		WITH org_projects AS (
+			SELECT id, org_id FROM projects WHERE org_id IS NOT NULL
+		)
+		SELECT o.id, o.name,
+			COALESCE((
+				SELECT SUM(u.accepted) FROM project_usage u
+				JOIN org_projects p ON p.id = u.project_id
+				WHERE p.org_id = o.id AND u.day >= $1 AND u.day < $2
+			), 0) AS events_ingested,
+			COALESCE((
+				SELECT SUM(u.dropped) FROM project_usage u
+				JOIN org_projects p ON p.id = u.project_id
+				WHERE p.org_id = o.id AND u.day >= $1 AND u.day < $2
+			), 0) AS events_dropped,
+			COALESCE((
+				SELECT SUM(pg_column_size(e.*)) FROM errors e
+				JOIN org_projects p ON p.id = e.project_id
+				WHERE p.org_id = o.id
+			), 0) + COALESCE((
+				SELECT SUM(d.size) FROM debug_files d
+				JOIN org_projects p ON p.id = d.project_id
+				WHERE p.org_id = o.id
+			), 0) + COALESCE((
+				SELECT SUM(m.size) FROM minidumps m
+				JOIN org_projects p ON p.id = m.project_id
+				WHERE p.org_id = o.id
+			), 0) AS storage_bytes,
+			(
+				SELECT COUNT(*) FROM team_members t
+				WHERE t.org_id = o.id AND t.status = 'active'
+			) AS seats
+		FROM organizations o
+		%s
+		ORDER BY o.name, o.id
+	`, whereClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []models.OrgUsage{}
+	for rows.Next() {
+		record := models.OrgUsage{PeriodStart: from, PeriodEnd: to}
+		err := rows.Scan(&record.OrgID, &record.OrgName, &record.EventsIngested, &record.EventsDropped, &record.StorageBytes, &record.Seats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization usage: %w", err)
+		}
+		usage = append(usage, record)
+	}
+
+	return usage, nil
+}
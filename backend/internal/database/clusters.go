@@ -0,0 +1,158 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+// GetErrorGroupTexts returns the latest event of the unresolved error groups
+// seen since the given time, the limit groups with the most events first
+func (db *DB) GetErrorGroupTexts(since time.Time, limit int) ([]models.ErrorGroupText, error) {
+	rows, err := db.Query(`
+		WITH groups AS (
+			SELECT fingerprint, COUNT(*) AS event_count, MAX(timestamp) AS last_seen
+			FROM errors
+			WHERE deleted_at IS NULL AND duplicate_of IS NULL AND resolved = false AND fingerprint IS NOT NULL
+			  AND timestamp >= $1
+			GROUP BY fingerprint
+			ORDER BY event_count DESC
+			LIMIT $2
+		)
+		SELECT g.fingerprint, latest.project_id, latest.message, latest.level, latest.source, g.event_count, g.last_seen
+		FROM groups g
+		CROSS JOIN LATERAL (
+			SELECT project_id, message, level, source FROM errors
+			WHERE fingerprint = g.fingerprint AND deleted_at IS NULL
+			ORDER BY timestamp DESC
+			LIMIT 1
+		) latest
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.ErrorGroupText{}
+	for rows.Next() {
+		var group models.ErrorGroupText
+		err := rows.Scan(&group.Fingerprint, &group.ProjectID, &group.Message, &group.Level,
+			&group.Source, &group.EventCount, &group.LastSeen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// ReplaceErrorClusters swaps the stored clusters for the result of a new
+// clustering run
+func (db *DB) ReplaceErrorClusters(clusters []models.ErrorCluster) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM error_clusters"); err != nil {
+		return fmt.Errorf("failed to clear error clusters: %w", err)
+	}
+	for _, cluster := range clusters {
+		_, err := tx.Exec(`
+			INSERT INTO error_clusters (id, project_id, similarity, event_count, computed_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, cluster.ID, cluster.ProjectID, cluster.Similarity, cluster.EventCount, cluster.ComputedAt)
+		if err != nil {
+			return fmt.Errorf("failed to store error cluster: %w", err)
+		}
+		for _, group := range cluster.Groups {
+			_, err := tx.Exec(`
+				INSERT INTO error_cluster_groups (cluster_id, fingerprint, message, level, source, event_count, last_seen, similarity)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			`, cluster.ID, group.Fingerprint, group.Message, group.Level, group.Source, group.EventCount, group.LastSeen, group.Similarity)
+			if err != nil {
+				return fmt.Errorf("failed to store error cluster group: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetErrorClusters returns a page of the stored clusters, those with the
+// most events first, and how many there are
+func (db *DB) GetErrorClusters(limit, offset int, projectID *uuid.UUID) ([]models.ErrorCluster, int, error) {
+	whereClause := ""
+	args := []interface{}{}
+	if projectID != nil {
+		whereClause = "WHERE project_id = $1"
+		args = append(args, *projectID)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM error_clusters "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count error clusters: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, project_id, similarity, event_count, computed_at
+		FROM error_clusters %s
+		ORDER BY event_count DESC, id
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)+1, len(args)+2)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query error clusters: %w", err)
+	}
+	defer rows.Close()
+
+	clusters := []models.ErrorCluster{}
+	index := map[string]int{}
+	ids := []string{}
+	for rows.Next() {
+		cluster := models.ErrorCluster{Groups: []models.ErrorClusterGroup{}}
+		if err := rows.Scan(&cluster.ID, &cluster.ProjectID, &cluster.Similarity, &cluster.EventCount, &cluster.ComputedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan error cluster: %w", err)
+		}
+		index[cluster.ID] = len(clusters)
+		ids = append(ids, cluster.ID)
+		clusters = append(clusters, cluster)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return clusters, total, nil
+	}
+
+	groupRows, err := db.Query(`
+		SELECT cluster_id, fingerprint, message, level, source, event_count, last_seen, similarity
+		FROM error_cluster_groups
+		WHERE cluster_id = ANY($1)
+		ORDER BY similarity DESC, event_count DESC
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query error cluster groups: %w", err)
+	}
+	defer groupRows.Close()
+
+	for groupRows.Next() {
+		var clusterID string
+		var group models.ErrorClusterGroup
+		err := groupRows.Scan(&clusterID, &group.Fingerprint, &group.Message, &group.Level,
+			&group.Source, &group.EventCount, &group.LastSeen, &group.Similarity)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan error cluster group: %w", err)
+		}
+		i := index[clusterID]
+		clusters[i].Groups = append(clusters[i].Groups, group)
+	}
+
+	return clusters, total, groupRows.Err()
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Context schema methods
+func (db *DB) GetContextSchemas() ([]models.ContextSchema, error) {
+	rows, err := db.Query("SELECT project_id, schema, updated_at FROM project_context_schemas")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query context schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []models.ContextSchema{}
+	for rows.Next() {
+		schema, err := scanContextSchema(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan context schema: %w", err)
+		}
+		schemas = append(schemas, *schema)
+	}
+
+	return schemas, nil
+}
+
+func (db *DB) GetContextSchema(projectID uuid.UUID) (*models.ContextSchema, error) {
+	row := db.QueryRow("SELECT project_id, schema, updated_at FROM project_context_schemas WHERE project_id = $1", projectID)
+
+	schema, err := scanContextSchema(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("context schema not found")
+		}
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (db *DB) UpsertContextSchema(schema *models.ContextSchema) error {
+	schemaJSON, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal context schema: %w", err)
+	}
+
+	query := `
+		INSERT INTO project_context_schemas (project_id, schema, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (project_id) DO UPDATE SET schema = EXCLUDED.schema, updated_at = EXCLUDED.updated_at
+	`
+	_, err = db.Exec(query, schema.ProjectID, schemaJSON, schema.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteContextSchema(projectID uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM project_context_schemas WHERE project_id = $1", projectID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("context schema not found")
+	}
+	return nil
+}
+
+// GetContextConformance counts a project's validated events per source since
+// the given time, nonconforming sources first, with each source's most common
+// violations
+func (db *DB) GetContextConformance(projectID uuid.UUID, since time.Time, topViolations int) ([]models.SourceConformance, error) {
+	query := `
+		SELECT source, COUNT(*), COUNT(*) FILTER (WHERE cardinality(schema_violations) > 0)
+		FROM errors
+		WHERE project_id = $1 AND schema_violations IS NOT NULL AND deleted_at IS NULL AND timestamp >= $2
+		GROUP BY source
+		ORDER BY 3 DESC, source ASC
+	`
+	rows, err := db.Query(query, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query context conformance: %w", err)
+	}
+
+	sources := []models.SourceConformance{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var source models.SourceConformance
+		if err := rows.Scan(&source.Source, &source.Events, &source.Nonconforming); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan context conformance: %w", err)
+		}
+		source.ConformanceRate = float64(source.Events-source.Nonconforming) / float64(source.Events) * 100
+		source.TopViolations = []models.ViolationCount{}
+		index[source.Source] = len(sources)
+		sources = append(sources, source)
+	}
+	rows.Close()
+
+	violationsQuery := `
+		SELECT source, violation, count FROM (
+			SELECT source, violation, COUNT(*) AS count,
+				ROW_NUMBER() OVER (PARTITION BY source ORDER BY COUNT(*) DESC, violation ASC) AS rank
+			FROM errors, unnest(schema_violations) AS violation
+			WHERE project_id = $1 AND deleted_at IS NULL AND timestamp >= $2
+			GROUP BY source, violation
+		) ranked
+		WHERE rank <= $3
+		ORDER BY source, count DESC, violation ASC
+	`
+	rows, err = db.Query(violationsQuery, projectID, since, topViolations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query context schema violations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source string
+		var violation models.ViolationCount
+		if err := rows.Scan(&source, &violation.Violation, &violation.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan context schema violation: %w", err)
+		}
+		if i, ok := index[source]; ok {
+			sources[i].TopViolations = append(sources[i].TopViolations, violation)
+		}
+	}
+
+	return sources, nil
+}
+
+func scanContextSchema(row rowScanner) (*models.ContextSchema, error) {
+	var schema models.ContextSchema
+	var schemaJSON []byte
+
+	if err := row.Scan(&schema.ProjectID, &schemaJSON, &schema.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(schemaJSON, &schema.Schema); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal context schema: %w", err)
+	}
+
+	return &schema, nil
+}
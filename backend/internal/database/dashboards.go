@@ -0,0 +1,190 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const dashboardColumns = "id, name, COALESCE(description, ''), widgets, created_at, updated_at"
+
+func scanDashboard(row rowScanner) (*models.Dashboard, error) {
+	var dashboard models.Dashboard
+	var widgetsJSON []byte
+
+	err := row.Scan(
+		&dashboard.ID, &dashboard.Name, &dashboard.Description, &widgetsJSON,
+		&dashboard.CreatedAt, &dashboard.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(widgetsJSON, &dashboard.Widgets); err != nil || dashboard.Widgets == nil {
+		dashboard.Widgets = []models.DashboardWidget{}
+	}
+
+	return &dashboard, nil
+}
+
+// Dashboard methods
+func (db *DB) GetDashboards() ([]models.Dashboard, error) {
+	query := fmt.Sprintf("SELECT %s FROM dashboards ORDER BY name ASC", dashboardColumns)
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboards: %w", err)
+	}
+	defer rows.Close()
+
+	dashboards := []models.Dashboard{}
+	for rows.Next() {
+		dashboard, err := scanDashboard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard: %w", err)
+		}
+		dashboards = append(dashboards, *dashboard)
+	}
+
+	return dashboards, nil
+}
+
+func (db *DB) GetDashboardByID(id uuid.UUID) (*models.Dashboard, error) {
+	query := fmt.Sprintf("SELECT %s FROM dashboards WHERE id = $1", dashboardColumns)
+
+	dashboard, err := scanDashboard(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dashboard not found")
+		}
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+
+	return dashboard, nil
+}
+
+func (db *DB) CreateDashboard(dashboard *models.Dashboard) error {
+	query := `
+		INSERT INTO dashboards (id, name, description, widgets, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal widgets: %w", err)
+	}
+
+	_, err = db.Exec(query,
+		dashboard.ID, dashboard.Name, dashboard.Description, widgetsJSON,
+		dashboard.CreatedAt, dashboard.UpdatedAt,
+	)
+
+	return err
+}
+
+func (db *DB) UpdateDashboard(dashboard *models.Dashboard) error {
+	query := `
+		UPDATE dashboards SET name = $2, description = $3, widgets = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal widgets: %w", err)
+	}
+
+	_, err = db.Exec(query, dashboard.ID, dashboard.Name, dashboard.Description, widgetsJSON, dashboard.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteDashboard(id uuid.UUID) error {
+	_, err := db.Exec("DELETE FROM dashboards WHERE id = $1", id)
+	return err
+}
+
+// dashboardTrendBuckets maps trend widget intervals to date_trunc units
+var dashboardTrendBuckets = map[string]string{
+	"minute": "minute",
+	"hour":   "hour",
+	"day":    "day",
+}
+
+// GetScopedTrend returns error counts per interval for the errors in the scope
+// during the trailing window
+func (db *DB) GetScopedTrend(scope models.AlertScope, window time.Duration, interval string) ([]models.TrendDataPoint, error) {
+	bucket, ok := dashboardTrendBuckets[interval]
+	if !ok {
+		return nil, fmt.Errorf("unknown trend interval: %s", interval)
+	}
+
+	scopeClause, scopeArgs := buildAlertScope(scope, 2)
+	args := append([]interface{}{window.Seconds()}, scopeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT
+			date_trunc('%s', timestamp) AS bucket,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE resolved = true),
+			COUNT(*) FILTER (WHERE level = 'error')
+		FROM errors
+		WHERE timestamp >= NOW() - make_interval(secs => $1) AND deleted_at IS NULL%s
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, bucket, scopeClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trend: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.TrendDataPoint{}
+	for rows.Next() {
+		var point models.TrendDataPoint
+		if err := rows.Scan(&point.Timestamp, &point.ErrorCount, &point.ResolvedCount, &point.CriticalCount); err != nil {
+			return nil, fmt.Errorf("failed to scan trend: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// GetTopErrorGroups returns the most frequent unresolved error groups in the
+// scope during the trailing window
+func (db *DB) GetTopErrorGroups(scope models.AlertScope, window time.Duration, limit int) ([]models.ServiceErrorGroup, error) {
+	scopeClause, scopeArgs := buildAlertScope(scope, 3)
+	args := append([]interface{}{window.Seconds(), limit}, scopeArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT fingerprint, MAX(message), MAX(level), COUNT(*), MAX(timestamp)
+		FROM errors
+		WHERE resolved = false AND fingerprint IS NOT NULL AND deleted_at IS NULL
+		  AND timestamp >= NOW() - make_interval(secs => $1)%s
+		GROUP BY fingerprint
+		ORDER BY COUNT(*) DESC
+		LIMIT $2
+	`, scopeClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top errors: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.ServiceErrorGroup{}
+	for rows.Next() {
+		var group models.ServiceErrorGroup
+		if err := rows.Scan(&group.Fingerprint, &group.Message, &group.Level, &group.Count, &group.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan top error: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
@@ -1,152 +1,542 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
+	"error-logs/internal/encryption"
 	"error-logs/internal/models"
+	"error-logs/internal/retry"
 )
 
 type DB struct {
 	*sql.DB
+	slow    *slowQueryLog
+	retries *retry.Counter
+	ctx     context.Context // set by WithContext
+
+	keyring            *encryption.Keyring
+	encryptIPAddresses bool
+}
+
+// PoolOptions configures the connection pool and query logging
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration // zero keeps idle connections until ConnMaxLifetime
+
+	// Queries running longer than this are logged; zero disables the log
+	SlowQueryThreshold time.Duration
 }
 
-func Connect(databaseURL string) (*DB, error) {
+func Connect(databaseURL string, opts PoolOptions) (*DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	return &DB{
+		DB:      db,
+		slow:    &slowQueryLog{threshold: opts.SlowQueryThreshold},
+		retries: &retry.Counter{},
+	}, nil
+}
 
-	return &DB{db}, nil
+// errorColumns lists the errors table columns in the order scanError expects
+const errorColumns = `id, timestamp, level, message, stack_trace, context, source,
+	environment, user_agent, ip_address, url, fingerprint, resolved,
+	count, first_seen, last_seen, created_at, updated_at,
+	release, resolved_in_release, regressed, project_id, assigned_to, assigned_team,
+	indexed_context, trace_id, resolved_by, resolved_at, resolution_note, deleted_at,
+	region, deployment, duplicate_of, schema_violations, auto_resolved, state`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (db *DB) scanError(row rowScanner) (*models.Error, error) {
+	var e models.Error
+	var contextJSON, indexedContextJSON []byte
+
+	err := row.Scan(
+		&e.ID, &e.Timestamp, &e.Level, &e.Message, &e.StackTrace,
+		&contextJSON, &e.Source, &e.Environment, &e.UserAgent,
+		&e.IPAddress, &e.URL, &e.Fingerprint, &e.Resolved,
+		&e.Count, &e.FirstSeen, &e.LastSeen, &e.CreatedAt, &e.UpdatedAt,
+		&e.Release, &e.ResolvedInRelease, &e.Regressed, &e.ProjectID, &e.AssignedTo, &e.AssignedTeam,
+		&indexedContextJSON, &e.TraceID, &e.ResolvedBy, &e.ResolvedAt, &e.ResolutionNote,
+		&e.DeletedAt, &e.Region, &e.Deployment, &e.DuplicateOf, pq.Array(&e.SchemaViolations),
+		&e.AutoResolved, &e.State,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(contextJSON, &e.Context); err != nil {
+		e.Context = make(map[string]interface{})
+	}
+
+	if err := json.Unmarshal(indexedContextJSON, &e.IndexedContext); err != nil {
+		e.IndexedContext = make(map[string]string)
+	}
+
+	if e.IPAddress, err = db.decryptOptional(e.IPAddress); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
 }
 
 func (db *DB) CreateError(error *models.Error) error {
-	query := `
-		INSERT INTO errors (
-			id, timestamp, level, message, stack_trace, context, source, 
-			environment, user_agent, ip_address, url, fingerprint, resolved, 
-			count, first_seen, last_seen, created_at, updated_at
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
-		)`
+	query := fmt.Sprintf(`
+		INSERT INTO errors (%s) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36
+		)`, errorColumns)
 
 	contextJSON, err := json.Marshal(error.Context)
 	if err != nil {
 		return fmt.Errorf("failed to marshal context: %w", err)
 	}
 
+	if error.IndexedContext == nil {
+		error.IndexedContext = map[string]string{}
+	}
+	indexedContextJSON, err := json.Marshal(error.IndexedContext)
+	if err != nil {
+		return fmt.Errorf("failed to marshal indexed context: %w", err)
+	}
+
+	ipAddress, err := db.encryptIPAddress(error.IPAddress)
+	if err != nil {
+		return err
+	}
+
+	state := error.State
+	if state == "" {
+		state = "new"
+	}
+
 	_, err = db.Exec(query,
 		error.ID, error.Timestamp, error.Level, error.Message, error.StackTrace,
 		contextJSON, error.Source, error.Environment, error.UserAgent,
-		error.IPAddress, error.URL, error.Fingerprint, error.Resolved,
+		ipAddress, error.URL, error.Fingerprint, error.Resolved,
 		error.Count, error.FirstSeen, error.LastSeen, error.CreatedAt, error.UpdatedAt,
+		error.Release, error.ResolvedInRelease, error.Regressed, error.ProjectID, error.AssignedTo,
+		error.AssignedTeam, indexedContextJSON, error.TraceID, error.ResolvedBy, error.ResolvedAt,
+		error.ResolutionNote, error.DeletedAt, error.Region, error.Deployment, error.DuplicateOf,
+		pq.Array(error.SchemaViolations), error.AutoResolved, state,
 	)
 
 	return err
 }
 
-func (db *DB) GetErrors(limit, offset int, level, source string) ([]models.Error, int, error) {
-	var errors []models.Error
-	var total int
-
-	// Build WHERE clause
-	whereClause := "WHERE 1=1"
+// buildErrorFilter turns an ErrorFilter into a WHERE clause, its arguments,
+// and the next free placeholder index. Errors in the trash never match.
+func buildErrorFilter(filter models.ErrorFilter) (string, []interface{}, int) {
+	whereClause := "WHERE deleted_at IS NULL"
 	args := []interface{}{}
 	argIndex := 1
 
-	if level != "" {
+	if filter.Level != "" {
 		whereClause += fmt.Sprintf(" AND level = $%d", argIndex)
-		args = append(args, level)
+		args = append(args, filter.Level)
 		argIndex++
 	}
 
-	if source != "" {
+	if filter.Source != "" {
 		whereClause += fmt.Sprintf(" AND source = $%d", argIndex)
-		args = append(args, source)
+		args = append(args, filter.Source)
 		argIndex++
 	}
 
+	if filter.Environment != "" {
+		whereClause += fmt.Sprintf(" AND environment = $%d", argIndex)
+		args = append(args, filter.Environment)
+		argIndex++
+	}
+
+	if filter.Region != "" {
+		whereClause += fmt.Sprintf(" AND region = $%d", argIndex)
+		args = append(args, filter.Region)
+		argIndex++
+	}
+
+	if filter.Deployment != "" {
+		whereClause += fmt.Sprintf(" AND deployment = $%d", argIndex)
+		args = append(args, filter.Deployment)
+		argIndex++
+	}
+
+	switch filter.Status {
+	case "resolved":
+		whereClause += " AND resolved = true"
+	case "unresolved":
+		whereClause += " AND resolved = false"
+	}
+
+	if filter.State != "" {
+		whereClause += fmt.Sprintf(" AND state = $%d", argIndex)
+		args = append(args, filter.State)
+		argIndex++
+	}
+
+	// Containment on the GIN-indexed column avoids scanning the raw context
+	if len(filter.Context) > 0 {
+		contextJSON, _ := json.Marshal(filter.Context)
+		whereClause += fmt.Sprintf(" AND indexed_context @> $%d", argIndex)
+		args = append(args, contextJSON)
+		argIndex++
+	}
+
+	if filter.WatchedBy != nil {
+		whereClause += fmt.Sprintf(" AND fingerprint IN (SELECT fingerprint FROM error_watches WHERE member_id = $%d)", argIndex)
+		args = append(args, *filter.WatchedBy)
+		argIndex++
+	}
+
+	if filter.Search != "" {
+		whereClause += fmt.Sprintf(" AND message ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Search+"%")
+		argIndex++
+	}
+
+	if filter.Message != "" {
+		condition, value := messageCondition(filter.Message, filter.MessageMatch, argIndex)
+		if filter.MessageNegate {
+			condition = "NOT (" + condition + ")"
+		}
+		whereClause += " AND " + condition
+		args = append(args, value)
+		argIndex++
+	}
+
+	for _, term := range filter.Query {
+		condition, value := searchTermCondition(term, argIndex)
+		if condition == "" {
+			continue
+		}
+		whereClause += " AND " + condition
+		if value != nil {
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	if filter.OrgID != nil {
+		whereClause += fmt.Sprintf(" AND project_id IN (SELECT id FROM projects WHERE org_id = $%d)", argIndex)
+		args = append(args, *filter.OrgID)
+		argIndex++
+	}
+
+	return whereClause, args, argIndex
+}
+
+// messageCondition matches the message as a case-insensitive substring, an
+// exact string or a regular expression
+func messageCondition(message, match string, argIndex int) (string, interface{}) {
+	switch match {
+	case "exact":
+		return fmt.Sprintf("message = $%d", argIndex), message
+	case "regex":
+		return fmt.Sprintf("message ~ $%d", argIndex), message
+	}
+	return fmt.Sprintf("message ILIKE $%d", argIndex), "%" + message + "%"
+}
+
+// searchTermColumns maps query fields to the columns they match exactly
+var searchTermColumns = map[string]string{
+	"level":       "level",
+	"source":      "source",
+	"environment": "environment",
+	"release":     "release",
+	"region":      "region",
+	"deployment":  "deployment",
+	"url":         "url",
+	"fingerprint": "fingerprint",
+	"trace_id":    "trace_id",
+}
+
+// searchTermCondition returns the SQL for a query term, with its value as
+// parameter argIndex. Negated terms keep rows where the column is NULL.
+func searchTermCondition(term models.SearchTerm, argIndex int) (string, interface{}) {
+	not := ""
+	if term.Negate {
+		not = "NOT "
+	}
+	switch term.Field {
+	case "", "message":
+		return fmt.Sprintf("message %sILIKE $%d", not, argIndex), "%" + term.Value + "%"
+	case "resolved":
+		return fmt.Sprintf("resolved = %t", !term.Negate), nil
+	}
+	if key, ok := strings.CutPrefix(term.Field, "context."); ok {
+		contextJSON, _ := json.Marshal(map[string]string{key: term.Value})
+		if term.Negate {
+			return fmt.Sprintf("NOT COALESCE(indexed_context @> $%d, false)", argIndex), contextJSON
+		}
+		return fmt.Sprintf("indexed_context @> $%d", argIndex), contextJSON
+	}
+	if column, ok := searchTermColumns[term.Field]; ok {
+		if term.Negate {
+			return fmt.Sprintf("%s IS DISTINCT FROM $%d", column, argIndex), term.Value
+		}
+		return fmt.Sprintf("%s = $%d", column, argIndex), term.Value
+	}
+	return "", nil
+}
+
+// MessageRegexTimeout bounds the error list queries of a message regex
+// filter, so a slow pattern can't tie up the database
+const MessageRegexTimeout = 5 * time.Second
+
+// errorQuerier runs the error list queries on the pool or in a transaction
+type errorQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (db *DB) GetErrors(limit, offset int, filter models.ErrorFilter) ([]models.Error, int, error) {
+	if filter.MessageMatch != "regex" {
+		return db.getErrors(db, limit, offset, filter)
+	}
+
+	// SET LOCAL only lasts until the transaction ends
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", MessageRegexTimeout.Milliseconds())); err != nil {
+		return nil, 0, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	return db.getErrors(tx, limit, offset, filter)
+}
+
+func (db *DB) getErrors(q errorQuerier, limit, offset int, filter models.ErrorFilter) ([]models.Error, int, error) {
+	var errors []models.Error
+	var total int
+
+	// Build WHERE clause
+	whereClause, args, argIndex := buildErrorFilter(filter)
+
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM errors %s", whereClause)
-	err := db.QueryRow(countQuery, args...).Scan(&total)
+	err := q.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
 
 	// Get errors
+	orderBy := "timestamp DESC"
+	if filter.Sort == "impact" {
+		// Groups without a score (resolved or quiet for a week) go last
+		orderBy = "(SELECT score FROM error_group_impact i WHERE i.fingerprint = errors.fingerprint) DESC NULLS LAST, timestamp DESC"
+	}
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, level, message, stack_trace, context, source, 
-			   environment, user_agent, ip_address, url, fingerprint, resolved, 
-			   count, first_seen, last_seen, created_at, updated_at
+		SELECT %s
 		FROM errors %s
-		ORDER BY timestamp DESC
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
+	`, errorColumns, whereClause, orderBy, argIndex, argIndex+1)
 
 	args = append(args, limit, offset)
 
-	rows, err := db.Query(query, args...)
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query errors: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var e models.Error
-		var contextJSON []byte
-
-		err := rows.Scan(
-			&e.ID, &e.Timestamp, &e.Level, &e.Message, &e.StackTrace,
-			&contextJSON, &e.Source, &e.Environment, &e.UserAgent,
-			&e.IPAddress, &e.URL, &e.Fingerprint, &e.Resolved,
-			&e.Count, &e.FirstSeen, &e.LastSeen, &e.CreatedAt, &e.UpdatedAt,
-		)
+		e, err := db.scanError(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan error: %w", err)
 		}
 
-		if err := json.Unmarshal(contextJSON, &e.Context); err != nil {
-			e.Context = make(map[string]interface{})
+		errors = append(errors, *e)
+	}
+
+	return errors, total, nil
+}
+
+// GetErrorUpdates returns errors created or updated after the (updatedAt, id)
+// position, oldest first. Rows newer than the returned watermark (NOW() minus
+// settle) are left out, so writes still committing with an earlier timestamp
+// are not skipped by a cursor that moved past them.
+func (db *DB) GetErrorUpdates(filter models.ErrorFilter, updatedAt time.Time, id uuid.UUID, settle time.Duration, limit int) ([]models.Error, time.Time, error) {
+	var watermark time.Time
+	if err := db.QueryRow("SELECT NOW() - make_interval(secs => $1)", settle.Seconds()).Scan(&watermark); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get watermark: %w", err)
+	}
+
+	whereClause, args, argIndex := buildErrorFilter(filter)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM errors %s AND (updated_at, id) > ($%d, $%d) AND updated_at <= $%d
+		ORDER BY updated_at ASC, id ASC
+		LIMIT $%d
+	`, errorColumns, whereClause, argIndex, argIndex+1, argIndex+2, argIndex+3)
+
+	args = append(args, updatedAt, id, watermark, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to query error updates: %w", err)
+	}
+	defer rows.Close()
+
+	errors := []models.Error{}
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to scan error: %w", err)
 		}
+		errors = append(errors, *e)
+	}
+
+	return errors, watermark, nil
+}
+
+// GetErrorsByTraceID returns every error sharing a correlation ID, oldest
+// first, limited to one organization when orgID is set
+func (db *DB) GetErrorsByTraceID(orgID *uuid.UUID, traceID string, limit int) ([]models.Error, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM errors
+		WHERE %s AND trace_id = $2 AND deleted_at IS NULL
+		ORDER BY timestamp ASC
+		LIMIT $3
+	`, errorColumns, inOrg(1))
 
-		errors = append(errors, e)
+	rows, err := db.Query(query, orgID, traceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors by trace: %w", err)
 	}
+	defer rows.Close()
 
-	return errors, total, nil
+	errors := []models.Error{}
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		errors = append(errors, *e)
+	}
+
+	return errors, nil
 }
 
-func (db *DB) GetErrorByID(id uuid.UUID) (*models.Error, error) {
+// FindTraceDuplicate returns the earliest event since the given time with the
+// same fingerprint and trace ID from a different source, or nil if there is none
+func (db *DB) FindTraceDuplicate(fingerprint, traceID, source string, since time.Time) (*uuid.UUID, error) {
 	query := `
-		SELECT id, timestamp, level, message, stack_trace, context, source, 
-			   environment, user_agent, ip_address, url, fingerprint, resolved, 
-			   count, first_seen, last_seen, created_at, updated_at
-		FROM errors WHERE id = $1
+		SELECT id FROM errors
+		WHERE trace_id = $1 AND fingerprint = $2 AND source <> $3
+		  AND duplicate_of IS NULL AND deleted_at IS NULL AND timestamp >= $4
+		ORDER BY timestamp ASC
+		LIMIT 1
 	`
 
-	var e models.Error
-	var contextJSON []byte
+	var id uuid.UUID
+	err := db.QueryRow(query, traceID, fingerprint, source, since).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trace duplicate: %w", err)
+	}
+	return &id, nil
+}
 
-	err := db.QueryRow(query, id).Scan(
-		&e.ID, &e.Timestamp, &e.Level, &e.Message, &e.StackTrace,
-		&contextJSON, &e.Source, &e.Environment, &e.UserAgent,
-		&e.IPAddress, &e.URL, &e.Fingerprint, &e.Resolved,
-		&e.Count, &e.FirstSeen, &e.LastSeen, &e.CreatedAt, &e.UpdatedAt,
-	)
+// GetErrorFacets counts matching errors per level, source, environment, region,
+// and status in a single pass using grouping sets. Errors without a region are
+// left out of the region facet.
+func (db *DB) GetErrorFacets(filter models.ErrorFilter) (*models.ErrorFacetsResponse, error) {
+	whereClause, args, _ := buildErrorFilter(filter)
+
+	query := fmt.Sprintf(`
+		SELECT
+			GROUPING(level) = 0 AS by_level,
+			GROUPING(source) = 0 AS by_source,
+			GROUPING(environment) = 0 AS by_environment,
+			GROUPING(region) = 0 AS by_region,
+			COALESCE(level, ''), COALESCE(source, ''), COALESCE(environment, ''),
+			COALESCE(region, ''), COALESCE(resolved, false), COUNT(*)
+		FROM errors %s
+		GROUP BY GROUPING SETS ((level), (source), (environment), (region), (resolved))
+		ORDER BY COUNT(*) DESC
+	`, whereClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := &models.ErrorFacetsResponse{
+		Level:       []models.FacetCount{},
+		Source:      []models.FacetCount{},
+		Environment: []models.FacetCount{},
+		Region:      []models.FacetCount{},
+		Status:      []models.FacetCount{},
+	}
+
+	for rows.Next() {
+		var byLevel, bySource, byEnvironment, byRegion, resolved bool
+		var level, source, environment, region string
+		var count int
+
+		err := rows.Scan(&byLevel, &bySource, &byEnvironment, &byRegion, &level, &source, &environment, &region, &resolved, &count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error facet: %w", err)
+		}
+
+		switch {
+		case byLevel:
+			facets.Level = append(facets.Level, models.FacetCount{Value: level, Count: count})
+		case bySource:
+			facets.Source = append(facets.Source, models.FacetCount{Value: source, Count: count})
+		case byEnvironment:
+			facets.Environment = append(facets.Environment, models.FacetCount{Value: environment, Count: count})
+		case byRegion:
+			if region != "" {
+				facets.Region = append(facets.Region, models.FacetCount{Value: region, Count: count})
+			}
+		default:
+			status := "unresolved"
+			if resolved {
+				status = "resolved"
+			}
+			facets.Status = append(facets.Status, models.FacetCount{Value: status, Count: count})
+		}
+	}
+
+	return facets, nil
+}
 
+func (db *DB) GetErrorByID(id uuid.UUID) (*models.Error, error) {
+	query := fmt.Sprintf("SELECT %s FROM errors WHERE id = $1 AND deleted_at IS NULL", errorColumns)
+
+	e, err := db.scanError(db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("error not found")
@@ -154,86 +544,525 @@ func (db *DB) GetErrorByID(id uuid.UUID) (*models.Error, error) {
 		return nil, fmt.Errorf("failed to get error: %w", err)
 	}
 
-	if err := json.Unmarshal(contextJSON, &e.Context); err != nil {
-		e.Context = make(map[string]interface{})
+	return e, nil
+}
+
+// GetErrorOrgID returns the organization of the project an error was
+// reported to, nil when the project has none. Errors in the trash are found
+// too.
+func (db *DB) GetErrorOrgID(id uuid.UUID) (*uuid.UUID, error) {
+	var orgID *uuid.UUID
+	err := db.QueryRow(`
+		SELECT p.org_id FROM errors e LEFT JOIN projects p ON p.id = e.project_id
+		WHERE e.id = $1
+	`, id).Scan(&orgID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("error not found")
+		}
+		return nil, fmt.Errorf("failed to get error organization: %w", err)
 	}
+	return orgID, nil
+}
 
-	return &e, nil
+// GetErrorsByIDs returns the listed errors in the order of ids, skipping
+// any that no longer exist or are in the trash
+func (db *DB) GetErrorsByIDs(ids []uuid.UUID) ([]models.Error, error) {
+	if len(ids) == 0 {
+		return []models.Error{}, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM errors WHERE id = ANY($1) AND deleted_at IS NULL", errorColumns)
+	rows, err := db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]models.Error, len(ids))
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		byID[e.ID] = *e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query errors: %w", err)
+	}
+
+	errors := make([]models.Error, 0, len(byID))
+	for _, id := range ids {
+		if e, ok := byID[id]; ok {
+			errors = append(errors, e)
+		}
+	}
+	return errors, nil
 }
 
-func (db *DB) ResolveError(id uuid.UUID) error {
-	query := "UPDATE errors SET resolved = true, updated_at = NOW() WHERE id = $1"
-	_, err := db.Exec(query, id)
-	return err
+// ResolveErrorInRelease resolves every event sharing the error's fingerprint
+// until an event from the given release or newer arrives. The error must
+// still be in state from, and the rest of the group is only resolved in the
+// groupFrom states. With orgID set, only the group's events in that
+// organization are resolved.
+func (db *DB) ResolveErrorInRelease(id uuid.UUID, from string, groupFrom []string, orgID *uuid.UUID, release, resolvedBy string, note *string) error {
+	query := fmt.Sprintf(`
+		UPDATE errors SET resolved = true, state = 'resolved', resolved_in_release = $2, regressed = false, auto_resolved = false,
+			resolved_by = $3, resolved_at = NOW(), resolution_note = $4, updated_at = NOW()
+		WHERE deleted_at IS NULL AND ((id = $1 AND state = $6) OR (fingerprint IS NOT NULL AND state = ANY($7) AND %s AND fingerprint = (
+			SELECT fingerprint FROM errors WHERE id = $1 AND state = $6 AND deleted_at IS NULL
+		)))
+	`, inOrg(5))
+	return db.execErrorUpdate(query, id, release, resolvedBy, note, orgID, from, pq.Array(groupFrom))
+}
+
+// UnresolveError reopens an event that is still in state from and clears its
+// resolution. If it was resolved in a release, the rest of its group is
+// reopened too, only in orgID's organization when it is set.
+func (db *DB) UnresolveError(id uuid.UUID, from string, orgID *uuid.UUID) error {
+	query := fmt.Sprintf(`
+		UPDATE errors SET resolved = false, state = 'new', resolved_in_release = NULL, regressed = false, auto_resolved = false,
+			resolved_by = NULL, resolved_at = NULL, resolution_note = NULL, updated_at = NOW()
+		WHERE deleted_at IS NULL AND ((id = $1 AND state = $3) OR (resolved_in_release IS NOT NULL AND %s AND fingerprint = (
+			SELECT fingerprint FROM errors WHERE id = $1 AND state = $3 AND resolved_in_release IS NOT NULL AND deleted_at IS NULL
+		)))
+	`, inOrg(2))
+	return db.execErrorUpdate(query, id, orgID, from)
+}
+
+// SetErrorState moves an error from one lifecycle state to another. Errors
+// that are resolved or ignored count as resolved, keeping who closed them and
+// why; other states clear the resolution. The update only applies while the
+// error is still in state from, and reports "error not found" otherwise.
+func (db *DB) SetErrorState(id uuid.UUID, from, to string, closed bool, actor string, note *string) error {
+	query := `
+		UPDATE errors SET state = $3, resolved = $4, regressed = false, auto_resolved = false, resolved_in_release = NULL,
+			resolved_by = CASE WHEN $4 THEN $5 END,
+			resolved_at = CASE WHEN $4 THEN NOW() END,
+			resolution_note = CASE WHEN $4 THEN $6 END,
+			updated_at = NOW()
+		WHERE id = $1 AND state = $2 AND deleted_at IS NULL
+	`
+	return db.execErrorUpdate(query, id, from, to, closed, actor, note)
+}
+
+// execErrorUpdate runs an update keyed on an error ID ($1) and reports a
+// missing error as "error not found"
+func (db *DB) execErrorUpdate(query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("error not found")
+	}
+	return nil
 }
 
-func (db *DB) DeleteError(id uuid.UUID) error {
-	query := "DELETE FROM errors WHERE id = $1"
-	_, err := db.Exec(query, id)
+// GetPendingReleaseResolution returns the release a fingerprint is resolved in, if any
+func (db *DB) GetPendingReleaseResolution(fingerprint string) (*string, error) {
+	query := `
+		SELECT resolved_in_release FROM errors
+		WHERE fingerprint = $1 AND resolved = true AND resolved_in_release IS NOT NULL AND deleted_at IS NULL
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var release string
+	err := db.QueryRow(query, fingerprint).Scan(&release)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get release resolution: %w", err)
+	}
+
+	return &release, nil
+}
+
+// GetGroupAssignee reports whether an error group already exists for the
+// fingerprint and which member and team it is currently assigned to
+func (db *DB) GetGroupAssignee(fingerprint string) (bool, *uuid.UUID, *uuid.UUID, error) {
+	query := `
+		SELECT assigned_to, assigned_team FROM errors
+		WHERE fingerprint = $1 AND deleted_at IS NULL
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`
+
+	var assignee, team *uuid.UUID
+	err := db.QueryRow(query, fingerprint).Scan(&assignee, &team)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil, nil, nil
+		}
+		return false, nil, nil, fmt.Errorf("failed to get group assignee: %w", err)
+	}
+
+	return true, assignee, team, nil
+}
+
+// ReopenRegression reopens all events of a fingerprint that were resolved in a release
+func (db *DB) ReopenRegression(fingerprint string) error {
+	query := `
+		UPDATE errors SET resolved = false, state = 'regressed', regressed = true, resolved_in_release = NULL,
+			resolved_by = NULL, resolved_at = NULL, resolution_note = NULL, updated_at = NOW()
+		WHERE fingerprint = $1 AND resolved_in_release IS NOT NULL AND deleted_at IS NULL
+	`
+	_, err := db.Exec(query, fingerprint)
 	return err
 }
 
-func (db *DB) GetStats() (*models.StatsResponse, error) {
-	stats := &models.StatsResponse{}
+// TrashError moves an error to the trash; it is hidden everywhere until
+// restored or purged
+func (db *DB) TrashError(id uuid.UUID) error {
+	query := "UPDATE errors SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL"
+	return db.execErrorUpdate(query, id)
+}
+
+func (db *DB) RestoreError(id uuid.UUID) error {
+	query := "UPDATE errors SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL"
+	return db.execErrorUpdate(query, id)
+}
+
+// DeleteTrashedError permanently removes an error that is in the trash
+func (db *DB) DeleteTrashedError(id uuid.UUID) error {
+	return db.execErrorUpdate("DELETE FROM errors WHERE id = $1 AND deleted_at IS NOT NULL", id)
+}
+
+// inOrg keeps errors of projects in the organization passed as placeholder
+// arg, or every error when it is NULL
+func inOrg(arg int) string {
+	return fmt.Sprintf("($%d::uuid IS NULL OR project_id IN (SELECT id FROM projects WHERE org_id = $%d))", arg, arg)
+}
+
+// GetTrashedErrors lists the trash, most recently deleted first, limited to
+// one organization when orgID is set
+func (db *DB) GetTrashedErrors(orgID *uuid.UUID, limit, offset int) ([]models.Error, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM errors WHERE deleted_at IS NOT NULL AND "+inOrg(1), orgID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count trashed errors: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM errors
+		WHERE deleted_at IS NOT NULL AND %s
+		ORDER BY deleted_at DESC
+		LIMIT $2 OFFSET $3
+	`, errorColumns, inOrg(1))
+
+	rows, err := db.Query(query, orgID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query trashed errors: %w", err)
+	}
+	defer rows.Close()
+
+	errors := []models.Error{}
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan error: %w", err)
+		}
+		errors = append(errors, *e)
+	}
+
+	return errors, total, nil
+}
+
+// PurgeTrashedErrors permanently removes errors trashed before the cutoff
+func (db *DB) PurgeTrashedErrors(cutoff time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM errors WHERE deleted_at IS NOT NULL AND deleted_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trashed errors: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetStats aggregates error counts, optionally scoped to a project, environment
+// and source. Errors today are counted from midnight in the IANA timezone.
+func (db *DB) GetStats(filter models.StatsFilter, timezone string) (*models.StatsResponse, error) {
+	stats := &models.StatsResponse{Timezone: timezone}
+	whereClause, args := buildStatsFilter(filter)
 
 	// Get total errors count
-	err := db.QueryRow("SELECT COUNT(*) FROM errors").Scan(&stats.TotalErrors)
+	err := db.QueryRow("SELECT COUNT(*) FROM errors "+whereClause, args...).Scan(&stats.TotalErrors)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total errors: %w", err)
 	}
 
-	// Get resolved errors count
-	err = db.QueryRow("SELECT COUNT(*) FROM errors WHERE resolved = true").Scan(&stats.ResolvedErrors)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get resolved errors: %w", err)
+	// Count errors per lifecycle state; ignored errors are not counted as resolved
+	if stats.States, err = db.getStateCounts(whereClause, args); err != nil {
+		return nil, err
 	}
+	stats.ResolvedErrors = stats.States["resolved"]
 
-	// Get errors today count - using a more compatible date calculation
-	err = db.QueryRow("SELECT COUNT(*) FROM errors WHERE DATE(timestamp) = CURRENT_DATE").Scan(&stats.ErrorsToday)
+	// Get errors today count - a range from local midnight keeps the timestamp index usable
+	todayClause := fmt.Sprintf(" AND timestamp >= date_trunc('day', NOW() AT TIME ZONE $%d) AT TIME ZONE $%d", len(args)+1, len(args)+1)
+	err = db.QueryRow("SELECT COUNT(*) FROM errors "+whereClause+todayClause, append(args, timezone)...).Scan(&stats.ErrorsToday)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get errors today: %w", err)
 	}
 
 	// Get errors this week count - using a more compatible date calculation
-	err = db.QueryRow("SELECT COUNT(*) FROM errors WHERE timestamp >= NOW() - INTERVAL '7 days'").Scan(&stats.ErrorsThisWeek)
+	err = db.QueryRow("SELECT COUNT(*) FROM errors "+whereClause+" AND timestamp >= NOW() - INTERVAL '7 days'", args...).Scan(&stats.ErrorsThisWeek)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get errors this week: %w", err)
 	}
 
-	// Get errors this month count - using a more compatible date calculation
-	err = db.QueryRow("SELECT COUNT(*) FROM errors WHERE timestamp >= NOW() - INTERVAL '30 days'").Scan(&stats.ErrorsThisMonth)
+	// Get errors this month count - using a more compatible date calculation
+	err = db.QueryRow("SELECT COUNT(*) FROM errors "+whereClause+" AND timestamp >= NOW() - INTERVAL '30 days'", args...).Scan(&stats.ErrorsThisMonth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get errors this month: %w", err)
+	}
+
+	// Suppressed events of the groups the filter matches
+	suppressedQuery := fmt.Sprintf(`
+		SELECT COALESCE(SUM(suppressed_count) FILTER (WHERE day > CURRENT_DATE - 7), 0),
+			   COALESCE(SUM(suppressed_count), 0)
+		FROM error_group_suppressed
+		WHERE day > CURRENT_DATE - 30 AND fingerprint IN (SELECT fingerprint FROM errors %s)
+	`, whereClause)
+	err = db.QueryRow(suppressedQuery, args...).Scan(&stats.SuppressedThisWeek, &stats.SuppressedThisMonth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suppressed events: %w", err)
+	}
+
+	// Calculate error rate for last 24 hours (errors per hour)
+	var errors24h int
+	err = db.QueryRow("SELECT COUNT(*) FROM errors "+whereClause+" AND timestamp >= NOW() - INTERVAL '24 hours'", args...).Scan(&errors24h)
+	if err == nil {
+		stats.ErrorRate24h = float64(errors24h) / 24.0
+	}
+
+	// Calculate resolution rate
+	if stats.TotalErrors > 0 {
+		stats.ResolutionRate = (float64(stats.ResolvedErrors) / float64(stats.TotalErrors)) * 100
+	}
+
+	// Calculate average resolution time (mock for now)
+	stats.AvgResolutionTime = "2h 15m"
+
+	if err := db.getErrorRates(stats, whereClause, args); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getStateCounts counts errors per lifecycle state, including states with none
+func (db *DB) getStateCounts(whereClause string, args []interface{}) (map[string]int, error) {
+	rows, err := db.Query("SELECT state, COUNT(*) FROM errors "+whereClause+" GROUP BY state", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(models.ErrorStates))
+	for _, state := range models.ErrorStates {
+		counts[state] = 0
+	}
+	for rows.Next() {
+		var state string
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan state count: %w", err)
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// getErrorRates fills the 24 hour rate stats. Errors are rolled up per minute
+// over every minute of the window, quiet minutes counting as zero, before
+// taking percentiles.
+func (db *DB) getErrorRates(stats *models.StatsResponse, whereClause string, args []interface{}) error {
+	whereClause += " AND timestamp >= NOW() - INTERVAL '24 hours'"
+
+	percentileQuery := fmt.Sprintf(`
+		WITH minutes AS (
+			SELECT date_trunc('minute', timestamp) AS bucket, COUNT(*) AS errors
+			FROM errors %s
+			GROUP BY bucket
+		)
+		SELECT
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY COALESCE(m.errors, 0)),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY COALESCE(m.errors, 0))
+		FROM generate_series(
+			date_trunc('minute', NOW() - INTERVAL '24 hours') + INTERVAL '1 minute',
+			date_trunc('minute', NOW()),
+			INTERVAL '1 minute'
+		) AS s(bucket)
+		LEFT JOIN minutes m ON m.bucket = s.bucket
+	`, whereClause)
+
+	err := db.QueryRow(percentileQuery, args...).Scan(&stats.ErrorsPerMinuteP50, &stats.ErrorsPerMinuteP95)
+	if err != nil {
+		return fmt.Errorf("failed to get error rate percentiles: %w", err)
+	}
+
+	busiestQuery := fmt.Sprintf(`
+		SELECT date_trunc('hour', timestamp) AS bucket, COUNT(*)
+		FROM errors %s
+		GROUP BY bucket
+		ORDER BY COUNT(*) DESC, bucket DESC
+		LIMIT 1
+	`, whereClause)
+
+	var busiest models.BusiestHour
+	err = db.QueryRow(busiestQuery, args...).Scan(&busiest.Hour, &busiest.Count)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get busiest hour: %w", err)
+	}
+	if err == nil {
+		stats.BusiestHour = &busiest
+	}
+
+	// A source needs two failures for there to be a time between them
+	mtbfQuery := fmt.Sprintf(`
+		SELECT source, COUNT(*),
+			EXTRACT(EPOCH FROM MAX(timestamp) - MIN(timestamp)) / (COUNT(*) - 1)
+		FROM errors %s
+		GROUP BY source
+		HAVING COUNT(*) > 1
+		ORDER BY 3 ASC, source ASC
+	`, whereClause)
+
+	rows, err := db.Query(mtbfQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query mtbf: %w", err)
+	}
+	defer rows.Close()
+
+	stats.MTBFBySource = []models.SourceMTBF{}
+	for rows.Next() {
+		var mtbf models.SourceMTBF
+		if err := rows.Scan(&mtbf.Source, &mtbf.Failures, &mtbf.MTBFSeconds); err != nil {
+			return fmt.Errorf("failed to scan mtbf: %w", err)
+		}
+		stats.MTBFBySource = append(stats.MTBFBySource, mtbf)
+	}
+
+	return nil
+}
+
+// buildStatsFilter turns a StatsFilter into a WHERE clause and its arguments.
+// Events linked as duplicates of another source's event are only counted when
+// stats are scoped to a source.
+func buildStatsFilter(filter models.StatsFilter) (string, []interface{}) {
+	whereClause := "WHERE deleted_at IS NULL"
+	args := []interface{}{}
+
+	if filter.Source == "" {
+		whereClause += " AND duplicate_of IS NULL"
+	}
+
+	if filter.ProjectID != nil {
+		args = append(args, *filter.ProjectID)
+		whereClause += fmt.Sprintf(" AND project_id = $%d", len(args))
+	}
+
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		whereClause += fmt.Sprintf(" AND environment = $%d", len(args))
+	}
+
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		whereClause += fmt.Sprintf(" AND source = $%d", len(args))
+	}
+
+	if filter.Region != "" {
+		args = append(args, filter.Region)
+		whereClause += fmt.Sprintf(" AND region = $%d", len(args))
+	}
+
+	if filter.Deployment != "" {
+		args = append(args, filter.Deployment)
+		whereClause += fmt.Sprintf(" AND deployment = $%d", len(args))
+	}
+
+	if filter.OrgID != nil {
+		args = append(args, *filter.OrgID)
+		whereClause += fmt.Sprintf(" AND project_id IN (SELECT id FROM projects WHERE org_id = $%d)", len(args))
+	}
+
+	return whereClause, args
+}
+
+// heatmapPeriods maps each heatmap period to the range it covers
+var heatmapPeriods = map[string]string{
+	"week":    "7 days",
+	"month":   "30 days",
+	"quarter": "90 days",
+	"year":    "1 year",
+}
+
+// ValidHeatmapPeriod reports whether GetErrorHeatmap supports the period
+func ValidHeatmapPeriod(period string) bool {
+	_, ok := heatmapPeriods[period]
+	return ok
+}
+
+// GetErrorHeatmap counts errors over the period by ISO day of week and hour,
+// both taken in the given IANA timezone
+func (db *DB) GetErrorHeatmap(filter models.StatsFilter, period, timezone string) (*models.HeatmapResponse, error) {
+	interval, ok := heatmapPeriods[period]
+	if !ok {
+		return nil, fmt.Errorf("unsupported heatmap period: %s", period)
+	}
+
+	whereClause, args := buildStatsFilter(filter)
+	args = append(args, timezone)
+	tz := len(args)
+
+	query := fmt.Sprintf(`
+		SELECT
+			EXTRACT(ISODOW FROM timestamp AT TIME ZONE $%d)::int AS day,
+			EXTRACT(HOUR FROM timestamp AT TIME ZONE $%d)::int AS hour,
+			COUNT(*)
+		FROM errors %s AND timestamp >= NOW() - INTERVAL '%s'
+		GROUP BY day, hour
+	`, tz, tz, whereClause, interval)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get errors this month: %w", err)
+		return nil, fmt.Errorf("failed to query heatmap: %w", err)
 	}
+	defer rows.Close()
 
-	// Calculate error rate for last 24 hours (errors per hour)
-	var errors24h int
-	err = db.QueryRow("SELECT COUNT(*) FROM errors WHERE timestamp >= NOW() - INTERVAL '24 hours'").Scan(&errors24h)
-	if err == nil {
-		stats.ErrorRate24h = float64(errors24h) / 24.0
+	heatmap := &models.HeatmapResponse{
+		Period:   period,
+		Timezone: timezone,
+		Days:     []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
 	}
-
-	// Calculate resolution rate
-	if stats.TotalErrors > 0 {
-		stats.ResolutionRate = (float64(stats.ResolvedErrors) / float64(stats.TotalErrors)) * 100
+	for rows.Next() {
+		var day, hour, count int
+		if err := rows.Scan(&day, &hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan heatmap: %w", err)
+		}
+		heatmap.Counts[day-1][hour] = count
+		heatmap.Total += count
+		if count > heatmap.Max {
+			heatmap.Max = count
+		}
 	}
 
-	// Calculate average resolution time (mock for now)
-	stats.AvgResolutionTime = "2h 15m"
-
-	return stats, nil
+	return heatmap, nil
 }
 
 func (db *DB) ValidateAPIKey(keyHash string) (*models.APIKey, error) {
 	query := `
-		SELECT id, key_hash, name, project_id, active, created_at, last_used
+		SELECT id, key_hash, name, permissions, project_id, org_id, active, created_at, last_used,
+			   key_type, allowed_origins, signing_secret
 		FROM api_keys WHERE key_hash = $1 AND active = true
 	`
 
 	var apiKey models.APIKey
+	var permissionsJSON, originsJSON []byte
 	err := db.QueryRow(query, keyHash).Scan(
-		&apiKey.ID, &apiKey.KeyHash, &apiKey.Name, &apiKey.ProjectID,
-		&apiKey.Active, &apiKey.CreatedAt, &apiKey.LastUsed,
+		&apiKey.ID, &apiKey.KeyHash, &apiKey.Name, &permissionsJSON, &apiKey.ProjectID,
+		&apiKey.OrgID, &apiKey.Active, &apiKey.CreatedAt, &apiKey.LastUsed,
+		&apiKey.KeyType, &originsJSON, &apiKey.SigningSecret,
 	)
 
 	if err != nil {
@@ -243,6 +1072,17 @@ func (db *DB) ValidateAPIKey(keyHash string) (*models.APIKey, error) {
 		return nil, fmt.Errorf("failed to validate API key: %w", err)
 	}
 
+	if err := json.Unmarshal(permissionsJSON, &apiKey.Permissions); err != nil {
+		apiKey.Permissions = []string{}
+	}
+	if err := json.Unmarshal(originsJSON, &apiKey.AllowedOrigins); err != nil {
+		apiKey.AllowedOrigins = []string{}
+	}
+	apiKey.SignedRequests = apiKey.SigningSecret != nil
+	if apiKey.SigningSecret, err = db.decryptOptional(apiKey.SigningSecret); err != nil {
+		return nil, err
+	}
+
 	// Update last used timestamp
 	updateQuery := "UPDATE api_keys SET last_used = NOW() WHERE id = $1"
 	db.Exec(updateQuery, apiKey.ID)
@@ -251,7 +1091,15 @@ func (db *DB) ValidateAPIKey(keyHash string) (*models.APIKey, error) {
 }
 
 // Analytics methods
-func (db *DB) GetTrends(period, groupBy string) (*models.TrendResponse, error) {
+
+// GetTrends buckets errors over the period, with buckets starting on the
+// hour, day or month in the IANA timezone
+func (db *DB) GetTrends(period, groupBy string, filter models.StatsFilter, timezone string) (*models.TrendResponse, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", timezone)
+	}
+
 	var timeFormat string
 
 	// Determine time format based on groupBy
@@ -269,23 +1117,24 @@ func (db *DB) GetTrends(period, groupBy string) (*models.TrendResponse, error) {
 	}
 
 	// Determine the time range based on period
-	var whereClause string
+	whereClause, args := buildStatsFilter(filter)
 	switch period {
 	case "day":
-		whereClause = "WHERE timestamp >= NOW() - INTERVAL '24 hours'"
+		whereClause += " AND timestamp >= NOW() - INTERVAL '24 hours'"
 	case "week":
-		whereClause = "WHERE timestamp >= NOW() - INTERVAL '7 days'"
+		whereClause += " AND timestamp >= NOW() - INTERVAL '7 days'"
 	case "month":
-		whereClause = "WHERE timestamp >= NOW() - INTERVAL '30 days'"
+		whereClause += " AND timestamp >= NOW() - INTERVAL '30 days'"
 	case "year":
-		whereClause = "WHERE timestamp >= NOW() - INTERVAL '1 year'"
+		whereClause += " AND timestamp >= NOW() - INTERVAL '1 year'"
 	default:
-		whereClause = "WHERE timestamp >= NOW() - INTERVAL '7 days'"
+		whereClause += " AND timestamp >= NOW() - INTERVAL '7 days'"
 	}
 
+	args = append(args, timezone)
 	query := fmt.Sprintf(`
 		SELECT 
-			TO_CHAR(timestamp, '%s') as time_period,
+			TO_CHAR(timestamp AT TIME ZONE $%d, '%s') as time_period,
 			COUNT(*) as error_count,
 			COUNT(CASE WHEN resolved = true THEN 1 END) as resolved_count,
 			COUNT(CASE WHEN level = 'error' THEN 1 END) as critical_count
@@ -293,9 +1142,9 @@ func (db *DB) GetTrends(period, groupBy string) (*models.TrendResponse, error) {
 		%s
 		GROUP BY time_period
 		ORDER BY time_period ASC
-	`, timeFormat, whereClause)
+	`, len(args), timeFormat, whereClause)
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query trends: %w", err)
 	}
@@ -315,15 +1164,15 @@ func (db *DB) GetTrends(period, groupBy string) (*models.TrendResponse, error) {
 		var timestamp time.Time
 		switch groupBy {
 		case "hour":
-			timestamp, _ = time.Parse("2006-01-02 15:04:05", timePeriod)
+			timestamp, _ = time.ParseInLocation("2006-01-02 15:04:05", timePeriod, loc)
 		case "day":
-			timestamp, _ = time.Parse("2006-01-02", timePeriod)
+			timestamp, _ = time.ParseInLocation("2006-01-02", timePeriod, loc)
 		case "week":
-			timestamp, _ = time.Parse("2006-02", timePeriod) // Simplified for week
+			timestamp, _ = time.ParseInLocation("2006-02", timePeriod, loc) // Simplified for week
 		case "month":
-			timestamp, _ = time.Parse("2006-01", timePeriod)
+			timestamp, _ = time.ParseInLocation("2006-01", timePeriod, loc)
 		default:
-			timestamp, _ = time.Parse("2006-01-02", timePeriod)
+			timestamp, _ = time.ParseInLocation("2006-01-02", timePeriod, loc)
 		}
 
 		dataPoints = append(dataPoints, models.TrendDataPoint{
@@ -336,17 +1185,52 @@ func (db *DB) GetTrends(period, groupBy string) (*models.TrendResponse, error) {
 
 	return &models.TrendResponse{
 		Period:     period,
+		Timezone:   timezone,
 		DataPoints: dataPoints,
 	}, nil
 }
 
 // Alert Rule methods
+
+// alertRuleColumns lists the alert_rules columns in the order scanAlertRule expects
+const alertRuleColumns = `id, name, condition, threshold, time_window, enabled,
+	notifications, scope, last_triggered, renotify_interval, consecutive_evaluations,
+	recovery_threshold, state, consecutive_breaches, last_notified, incident_id, peak_values,
+	runbook_url, runbook_notes, created_at, updated_at, severity, external_id, version`
+
+func (db *DB) scanAlertRule(row rowScanner) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var notificationsJSON, scopeJSON, peakJSON []byte
+	var runbookURL *string
+	var runbookNotes string
+
+	err := row.Scan(
+		&rule.ID, &rule.Name, &rule.Condition, &rule.Threshold,
+		&rule.TimeWindow, &rule.Enabled, &notificationsJSON, &scopeJSON,
+		&rule.LastTriggered, &rule.RenotifyInterval, &rule.ConsecutiveEvaluations,
+		&rule.RecoveryThreshold, &rule.State, &rule.ConsecutiveBreaches, &rule.LastNotified,
+		&rule.IncidentID, &peakJSON, &runbookURL, &runbookNotes, &rule.CreatedAt, &rule.UpdatedAt,
+		&rule.Severity, &rule.ExternalID, &rule.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rule.Runbook = newRunbook(runbookURL, runbookNotes)
+
+	if err := json.Unmarshal(notificationsJSON, &rule.Notifications); err != nil {
+		rule.Notifications = []string{}
+	}
+	if rule.Notifications, err = db.decryptNotifications(rule.Notifications); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(scopeJSON, &rule.Scope)
+	json.Unmarshal(peakJSON, &rule.PeakValues)
+
+	return &rule, nil
+}
+
 func (db *DB) GetAlertRules() ([]models.AlertRule, error) {
-	query := `
-		SELECT id, name, condition, threshold, time_window, enabled, 
-			   notifications, last_triggered, created_at, updated_at
-		FROM alert_rules ORDER BY created_at DESC
-	`
+	query := fmt.Sprintf("SELECT %s FROM alert_rules ORDER BY created_at DESC", alertRuleColumns)
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -356,66 +1240,137 @@ func (db *DB) GetAlertRules() ([]models.AlertRule, error) {
 
 	var rules []models.AlertRule
 	for rows.Next() {
-		var rule models.AlertRule
-		var notificationsJSON []byte
-
-		err := rows.Scan(
-			&rule.ID, &rule.Name, &rule.Condition, &rule.Threshold,
-			&rule.TimeWindow, &rule.Enabled, &notificationsJSON,
-			&rule.LastTriggered, &rule.CreatedAt, &rule.UpdatedAt,
-		)
+		rule, err := db.scanAlertRule(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
 		}
 
-		if err := json.Unmarshal(notificationsJSON, &rule.Notifications); err != nil {
-			rule.Notifications = []string{}
-		}
-
-		rules = append(rules, rule)
+		rules = append(rules, *rule)
 	}
 
 	return rules, nil
 }
 
+// ListAlertRules returns a page of the alert rules matching filter, newest
+// first, and how many match in total
+func (db *DB) ListAlertRules(limit, offset int, filter models.AlertRuleFilter) ([]models.AlertRule, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.Enabled != nil {
+		conditions = append(conditions, fmt.Sprintf("enabled = $%d", argIndex))
+		args = append(args, *filter.Enabled)
+		argIndex++
+	}
+	if filter.State != "" {
+		conditions = append(conditions, fmt.Sprintf("state = $%d", argIndex))
+		args = append(args, filter.State)
+		argIndex++
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
+		args = append(args, "%"+filter.Search+"%")
+		argIndex++
+	}
+	if filter.ExternalID != "" {
+		conditions = append(conditions, fmt.Sprintf("external_id = $%d", argIndex))
+		args = append(args, filter.ExternalID)
+		argIndex++
+	}
+	if filter.OrgID != nil {
+		conditions = append(conditions, fmt.Sprintf("scope->>'project_id' IN (SELECT id::text FROM projects WHERE org_id = $%d)", argIndex))
+		args = append(args, *filter.OrgID)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM alert_rules "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count alert rules: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM alert_rules %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, alertRuleColumns, whereClause, argIndex, argIndex+1)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		rule, err := db.scanAlertRule(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, total, nil
+}
+
+// execer runs statements on the pool or in a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func (db *DB) CreateAlertRule(rule *models.AlertRule) error {
-	query := `
-		INSERT INTO alert_rules (
-			id, name, condition, threshold, time_window, enabled,
-			notifications, last_triggered, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
+	return db.insertAlertRule(db, rule)
+}
+
+func (db *DB) insertAlertRule(q execer, rule *models.AlertRule) error {
+	query := fmt.Sprintf(`
+		INSERT INTO alert_rules (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+	`, alertRuleColumns)
 
-	notificationsJSON, err := json.Marshal(rule.Notifications)
+	notifications, err := db.encryptNotifications(rule.Notifications)
+	if err != nil {
+		return err
+	}
+	notificationsJSON, err := json.Marshal(notifications)
 	if err != nil {
 		return fmt.Errorf("failed to marshal notifications: %w", err)
 	}
 
-	_, err = db.Exec(query,
+	scopeJSON, err := json.Marshal(rule.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scope: %w", err)
+	}
+
+	peakJSON, err := json.Marshal(rule.PeakValues)
+	if err != nil {
+		return fmt.Errorf("failed to marshal peak values: %w", err)
+	}
+
+	runbookURL, runbookNotes := splitRunbook(rule.Runbook)
+
+	_, err = q.Exec(query,
 		rule.ID, rule.Name, rule.Condition, rule.Threshold,
-		rule.TimeWindow, rule.Enabled, notificationsJSON,
-		rule.LastTriggered, rule.CreatedAt, rule.UpdatedAt,
+		rule.TimeWindow, rule.Enabled, notificationsJSON, scopeJSON,
+		rule.LastTriggered, rule.RenotifyInterval, rule.ConsecutiveEvaluations,
+		rule.RecoveryThreshold, rule.State, rule.ConsecutiveBreaches, rule.LastNotified,
+		rule.IncidentID, peakJSON, runbookURL, runbookNotes, rule.CreatedAt, rule.UpdatedAt,
+		rule.Severity, rule.ExternalID, rule.Version,
 	)
 
 	return err
 }
 
 func (db *DB) GetAlertRuleByID(id uuid.UUID) (*models.AlertRule, error) {
-	query := `
-		SELECT id, name, condition, threshold, time_window, enabled,
-			   notifications, last_triggered, created_at, updated_at
-		FROM alert_rules WHERE id = $1
-	`
-
-	var rule models.AlertRule
-	var notificationsJSON []byte
-
-	err := db.QueryRow(query, id).Scan(
-		&rule.ID, &rule.Name, &rule.Condition, &rule.Threshold,
-		&rule.TimeWindow, &rule.Enabled, &notificationsJSON,
-		&rule.LastTriggered, &rule.CreatedAt, &rule.UpdatedAt,
-	)
+	query := fmt.Sprintf("SELECT %s FROM alert_rules WHERE id = $1", alertRuleColumns)
 
+	rule, err := db.scanAlertRule(db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("alert rule not found")
@@ -423,81 +1378,172 @@ func (db *DB) GetAlertRuleByID(id uuid.UUID) (*models.AlertRule, error) {
 		return nil, fmt.Errorf("failed to get alert rule: %w", err)
 	}
 
-	if err := json.Unmarshal(notificationsJSON, &rule.Notifications); err != nil {
-		rule.Notifications = []string{}
-	}
+	return rule, nil
+}
 
-	return &rule, nil
+// UpdateAlertRule saves a rule's configuration and bumps its version. With
+// ifVersion set, the rule is only saved if it is still at that version.
+func (db *DB) UpdateAlertRule(rule *models.AlertRule, ifVersion int) error {
+	return db.updateAlertRule(db, rule, ifVersion)
 }
 
-func (db *DB) UpdateAlertRule(rule *models.AlertRule) error {
+func (db *DB) updateAlertRule(q execer, rule *models.AlertRule, ifVersion int) error {
 	query := `
 		UPDATE alert_rules SET 
 			name = $2, condition = $3, threshold = $4, time_window = $5,
-			enabled = $6, notifications = $7, updated_at = $8
-		WHERE id = $1
+			enabled = $6, notifications = $7, scope = $8, renotify_interval = $9,
+			consecutive_evaluations = $10, recovery_threshold = $11, runbook_url = $12,
+			runbook_notes = $13, updated_at = $14, severity = $15, external_id = $16,
+			version = version + 1
+		WHERE id = $1 AND ($17 = 0 OR version = $17)
+		RETURNING version
 	`
 
-	notificationsJSON, err := json.Marshal(rule.Notifications)
+	notifications, err := db.encryptNotifications(rule.Notifications)
+	if err != nil {
+		return err
+	}
+	notificationsJSON, err := json.Marshal(notifications)
 	if err != nil {
 		return fmt.Errorf("failed to marshal notifications: %w", err)
 	}
 
-	_, err = db.Exec(query,
-		rule.ID, rule.Name, rule.Condition, rule.Threshold,
-		rule.TimeWindow, rule.Enabled, notificationsJSON, rule.UpdatedAt,
-	)
+	scopeJSON, err := json.Marshal(rule.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scope: %w", err)
+	}
+
+	runbookURL, runbookNotes := splitRunbook(rule.Runbook)
 
+	err = q.QueryRow(query,
+		rule.ID, rule.Name, rule.Condition, rule.Threshold,
+		rule.TimeWindow, rule.Enabled, notificationsJSON, scopeJSON,
+		rule.RenotifyInterval, rule.ConsecutiveEvaluations, rule.RecoveryThreshold,
+		runbookURL, runbookNotes, rule.UpdatedAt, rule.Severity, rule.ExternalID, ifVersion,
+	).Scan(&rule.Version)
+	if err == sql.ErrNoRows {
+		return db.missedVersioned("SELECT EXISTS (SELECT 1 FROM alert_rules WHERE id = $1)", rule.ID, "alert rule not found")
+	}
 	return err
 }
 
-func (db *DB) DeleteAlertRule(id uuid.UUID) error {
-	query := "DELETE FROM alert_rules WHERE id = $1"
-	_, err := db.Exec(query, id)
+// UpdateAlertRuleState persists the evaluator's firing state for a rule
+func (db *DB) UpdateAlertRuleState(rule *models.AlertRule) error {
+	query := `
+		UPDATE alert_rules SET
+			state = $2, consecutive_breaches = $3, last_triggered = $4, last_notified = $5,
+			incident_id = $6, peak_values = $7
+		WHERE id = $1
+	`
+
+	peakJSON, err := json.Marshal(rule.PeakValues)
+	if err != nil {
+		return fmt.Errorf("failed to marshal peak values: %w", err)
+	}
+
+	_, err = db.Exec(query,
+		rule.ID, rule.State, rule.ConsecutiveBreaches, rule.LastTriggered, rule.LastNotified,
+		rule.IncidentID, peakJSON,
+	)
 	return err
 }
 
+// DeleteAlertRule deletes a rule; with ifVersion set, only if it is still at
+// that version
+func (db *DB) DeleteAlertRule(id uuid.UUID, ifVersion int) error {
+	result, err := db.Exec("DELETE FROM alert_rules WHERE id = $1 AND ($2 = 0 OR version = $2)", id, ifVersion)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.missedVersioned("SELECT EXISTS (SELECT 1 FROM alert_rules WHERE id = $1)", id, "alert rule not found")
+	}
+	return nil
+}
+
 // Incident methods
-func (db *DB) GetIncidents() ([]models.Incident, error) {
-	query := `
-		SELECT id, title, severity, status, description, assigned_to, created_at, updated_at
-		FROM incidents ORDER BY created_at DESC
-	`
 
-	rows, err := db.Query(query)
+// GetIncidents returns a page of the incidents matching filter, newest first,
+// and how many match in total
+func (db *DB) GetIncidents(limit, offset int, filter models.IncidentFilter) ([]models.Incident, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.Status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, filter.Status)
+		argIndex++
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, fmt.Sprintf("severity = $%d", argIndex))
+		args = append(args, filter.Severity)
+		argIndex++
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("title ILIKE $%d", argIndex))
+		args = append(args, "%"+filter.Search+"%")
+		argIndex++
+	}
+	if filter.OrgID != nil {
+		conditions = append(conditions, fmt.Sprintf("org_id = $%d", argIndex))
+		args = append(args, *filter.OrgID)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM incidents "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, title, severity, status, description, assigned_to, assigned_team, org_id, created_at, updated_at
+		FROM incidents %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query incidents: %w", err)
+		return nil, 0, fmt.Errorf("failed to query incidents: %w", err)
 	}
 	defer rows.Close()
 
-	var incidents []models.Incident
+	incidents := []models.Incident{}
 	for rows.Next() {
 		var incident models.Incident
 
 		err := rows.Scan(
 			&incident.ID, &incident.Title, &incident.Severity, &incident.Status,
-			&incident.Description, &incident.AssignedTo, &incident.CreatedAt, &incident.UpdatedAt,
+			&incident.Description, &incident.AssignedTo, &incident.AssignedTeam,
+			&incident.OrgID, &incident.CreatedAt, &incident.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan incident: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan incident: %w", err)
 		}
 
 		incidents = append(incidents, incident)
 	}
 
-	return incidents, nil
+	return incidents, total, nil
 }
 
 func (db *DB) CreateIncident(incident *models.Incident) error {
 	query := `
 		INSERT INTO incidents (
-			id, title, severity, status, description, assigned_to, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			id, title, severity, status, description, assigned_to, assigned_team, org_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	_, err := db.Exec(query,
 		incident.ID, incident.Title, incident.Severity, incident.Status,
-		incident.Description, incident.AssignedTo, incident.CreatedAt, incident.UpdatedAt,
+		incident.Description, incident.AssignedTo, incident.AssignedTeam,
+		incident.OrgID, incident.CreatedAt, incident.UpdatedAt,
 	)
 
 	return err
@@ -505,7 +1551,7 @@ func (db *DB) CreateIncident(incident *models.Incident) error {
 
 func (db *DB) GetIncidentByID(id uuid.UUID) (*models.Incident, error) {
 	query := `
-		SELECT id, title, severity, status, description, assigned_to, created_at, updated_at
+		SELECT id, title, severity, status, description, assigned_to, assigned_team, org_id, created_at, updated_at
 		FROM incidents WHERE id = $1
 	`
 
@@ -513,7 +1559,8 @@ func (db *DB) GetIncidentByID(id uuid.UUID) (*models.Incident, error) {
 
 	err := db.QueryRow(query, id).Scan(
 		&incident.ID, &incident.Title, &incident.Severity, &incident.Status,
-		&incident.Description, &incident.AssignedTo, &incident.CreatedAt, &incident.UpdatedAt,
+		&incident.Description, &incident.AssignedTo, &incident.AssignedTeam,
+		&incident.OrgID, &incident.CreatedAt, &incident.UpdatedAt,
 	)
 
 	if err != nil {
@@ -530,26 +1577,66 @@ func (db *DB) UpdateIncident(incident *models.Incident) error {
 	query := `
 		UPDATE incidents SET 
 			title = $2, severity = $3, status = $4, description = $5,
-			assigned_to = $6, updated_at = $7
+			assigned_to = $6, assigned_team = $7, updated_at = $8
 		WHERE id = $1
 	`
 
 	_, err := db.Exec(query,
 		incident.ID, incident.Title, incident.Severity, incident.Status,
-		incident.Description, incident.AssignedTo, incident.UpdatedAt,
+		incident.Description, incident.AssignedTo, incident.AssignedTeam, incident.UpdatedAt,
 	)
 
 	return err
 }
 
 // API Key methods
-func (db *DB) GetAPIKeys() ([]models.APIKey, error) {
-	query := `
-		SELECT id, key_hash, name, permissions, project_id, active, expires_at, created_at, last_used
-		FROM api_keys WHERE active = true ORDER BY created_at DESC
-	`
 
-	rows, err := db.Query(query)
+// apiKeyColumns lists the api_keys columns in the order scanAPIKey expects
+const apiKeyColumns = `id, key_hash, name, permissions, project_id, org_id, active, expires_at, created_at, last_used,
+	key_type, allowed_origins, signing_secret IS NOT NULL, external_id, version`
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	var permissionsJSON, originsJSON []byte
+
+	err := row.Scan(
+		&apiKey.ID, &apiKey.KeyHash, &apiKey.Name, &permissionsJSON,
+		&apiKey.ProjectID, &apiKey.OrgID, &apiKey.Active, &apiKey.ExpiresAt,
+		&apiKey.CreatedAt, &apiKey.LastUsed, &apiKey.KeyType, &originsJSON,
+		&apiKey.SignedRequests, &apiKey.ExternalID, &apiKey.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(permissionsJSON, &apiKey.Permissions); err != nil {
+		apiKey.Permissions = []string{}
+	}
+	if err := json.Unmarshal(originsJSON, &apiKey.AllowedOrigins); err != nil {
+		apiKey.AllowedOrigins = []string{}
+	}
+
+	// Generate key preview (show first 4 and last 4 characters)
+	if len(apiKey.KeyHash) >= 8 {
+		prefix := "sk_"
+		if apiKey.KeyType == "public" {
+			prefix = "pk_"
+		}
+		apiKey.KeyPreview = prefix + "****" + apiKey.KeyHash[len(apiKey.KeyHash)-4:]
+	}
+
+	return &apiKey, nil
+}
+
+// GetAPIKeys lists active keys, limited to one organization when orgID is set
+func (db *DB) GetAPIKeys(orgID *uuid.UUID) ([]models.APIKey, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM api_keys WHERE active = true AND ($1::uuid IS NULL OR org_id = $1)
+		ORDER BY created_at DESC
+	`, apiKeyColumns)
+
+	rows, err := db.Query(query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query API keys: %w", err)
 	}
@@ -557,38 +1644,36 @@ func (db *DB) GetAPIKeys() ([]models.APIKey, error) {
 
 	var apiKeys []models.APIKey
 	for rows.Next() {
-		var apiKey models.APIKey
-		var permissionsJSON []byte
-
-		err := rows.Scan(
-			&apiKey.ID, &apiKey.KeyHash, &apiKey.Name, &permissionsJSON,
-			&apiKey.ProjectID, &apiKey.Active, &apiKey.ExpiresAt,
-			&apiKey.CreatedAt, &apiKey.LastUsed,
-		)
+		apiKey, err := scanAPIKey(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
 		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
 
-		if err := json.Unmarshal(permissionsJSON, &apiKey.Permissions); err != nil {
-			apiKey.Permissions = []string{}
-		}
+	return apiKeys, nil
+}
 
-		// Generate key preview (show first 4 and last 4 characters)
-		if len(apiKey.KeyHash) >= 8 {
-			apiKey.KeyPreview = "sk_****" + apiKey.KeyHash[len(apiKey.KeyHash)-4:]
-		}
+// GetAPIKey returns an active key
+func (db *DB) GetAPIKey(id uuid.UUID) (*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE id = $1 AND active = true", apiKeyColumns)
 
-		apiKeys = append(apiKeys, apiKey)
+	apiKey, err := scanAPIKey(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("API key not found")
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
 	}
-
-	return apiKeys, nil
+	return apiKey, nil
 }
 
 func (db *DB) CreateAPIKey(apiKey *models.APIKey) error {
 	query := `
 		INSERT INTO api_keys (
-			id, key_hash, name, permissions, project_id, active, expires_at, created_at, last_used
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			id, key_hash, name, permissions, project_id, org_id, active, expires_at, created_at, last_used,
+			key_type, allowed_origins, external_id, version
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	permissionsJSON, err := json.Marshal(apiKey.Permissions)
@@ -596,29 +1681,125 @@ func (db *DB) CreateAPIKey(apiKey *models.APIKey) error {
 		return fmt.Errorf("failed to marshal permissions: %w", err)
 	}
 
+	originsJSON, err := json.Marshal(apiKey.AllowedOrigins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed origins: %w", err)
+	}
+
 	_, err = db.Exec(query,
 		apiKey.ID, apiKey.KeyHash, apiKey.Name, permissionsJSON,
-		apiKey.ProjectID, apiKey.Active, apiKey.ExpiresAt,
-		apiKey.CreatedAt, apiKey.LastUsed,
+		apiKey.ProjectID, apiKey.OrgID, apiKey.Active, apiKey.ExpiresAt,
+		apiKey.CreatedAt, apiKey.LastUsed, apiKey.KeyType, originsJSON,
+		apiKey.ExternalID, apiKey.Version,
 	)
 
 	return err
 }
 
-func (db *DB) DeleteAPIKey(id uuid.UUID) error {
-	query := "UPDATE api_keys SET active = false WHERE id = $1"
-	_, err := db.Exec(query, id)
+// activeAPIKeyExists takes the key's ID as $1
+const activeAPIKeyExists = "SELECT EXISTS (SELECT 1 FROM api_keys WHERE id = $1 AND active = true)"
+
+// UpdateAPIKey saves a key's name, permissions, expiry, allowed origins and
+// external ID and bumps its version. With ifVersion set, the key is only
+// saved if it is still at that version.
+func (db *DB) UpdateAPIKey(apiKey *models.APIKey, ifVersion int) error {
+	permissionsJSON, err := json.Marshal(apiKey.Permissions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permissions: %w", err)
+	}
+	originsJSON, err := json.Marshal(apiKey.AllowedOrigins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed origins: %w", err)
+	}
+
+	err = db.QueryRow(`
+		UPDATE api_keys SET
+			name = $2, permissions = $3, expires_at = $4, allowed_origins = $5, external_id = $6,
+			version = version + 1
+		WHERE id = $1 AND active = true AND ($7 = 0 OR version = $7)
+		RETURNING version
+	`, apiKey.ID, apiKey.Name, permissionsJSON, apiKey.ExpiresAt, originsJSON, apiKey.ExternalID, ifVersion,
+	).Scan(&apiKey.Version)
+	if err == sql.ErrNoRows {
+		return db.missedVersioned(activeAPIKeyExists, apiKey.ID, "API key not found")
+	}
 	return err
 }
 
+// GetAPIKeyType returns whether an active key is secret or public
+func (db *DB) GetAPIKeyType(id uuid.UUID) (string, error) {
+	var keyType string
+	err := db.QueryRow("SELECT key_type FROM api_keys WHERE id = $1 AND active = true", id).Scan(&keyType)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("API key not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get API key: %w", err)
+	}
+	return keyType, nil
+}
+
+// GetAPIKeyOrgID returns the organization an active key belongs to; nil for
+// deployment-wide keys
+func (db *DB) GetAPIKeyOrgID(id uuid.UUID) (*uuid.UUID, error) {
+	var orgID *uuid.UUID
+	err := db.QueryRow("SELECT org_id FROM api_keys WHERE id = $1 AND active = true", id).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return orgID, nil
+}
+
+// SetAPIKeySigningSecret sets the key's request signing secret; nil stops
+// requiring signatures
+func (db *DB) SetAPIKeySigningSecret(id uuid.UUID, secret *string) error {
+	secret, err := db.encryptOptional(secret)
+	if err != nil {
+		return err
+	}
+	result, err := db.Exec("UPDATE api_keys SET signing_secret = $2, version = version + 1 WHERE id = $1 AND active = true", id, secret)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("API key not found")
+	}
+	return nil
+}
+
+// DeleteAPIKey deactivates a key; with ifVersion set, only if it is still at
+// that version
+func (db *DB) DeleteAPIKey(id uuid.UUID, ifVersion int) error {
+	result, err := db.Exec(`
+		UPDATE api_keys SET active = false, version = version + 1
+		WHERE id = $1 AND active = true AND ($2 = 0 OR version = $2)
+	`, id, ifVersion)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.missedVersioned(activeAPIKeyExists, id, "API key not found")
+	}
+	return nil
+}
+
 // Team Member methods
 func (db *DB) GetTeamMembers() ([]models.TeamMember, error) {
+	return db.GetOrgTeamMembers(nil)
+}
+
+// GetOrgTeamMembers lists members, limited to one organization when orgID is set
+func (db *DB) GetOrgTeamMembers(orgID *uuid.UUID) ([]models.TeamMember, error) {
 	query := `
-		SELECT id, name, email, role, status, last_active, created_at
-		FROM team_members ORDER BY created_at DESC
+		SELECT id, name, email, role, status, org_id, last_active, created_at
+		FROM team_members WHERE $1::uuid IS NULL OR org_id = $1
+		ORDER BY created_at DESC
 	`
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query team members: %w", err)
 	}
@@ -630,7 +1811,7 @@ func (db *DB) GetTeamMembers() ([]models.TeamMember, error) {
 
 		err := rows.Scan(
 			&member.ID, &member.Name, &member.Email, &member.Role,
-			&member.Status, &member.LastActive, &member.CreatedAt,
+			&member.Status, &member.OrgID, &member.LastActive, &member.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan team member: %w", err)
@@ -645,14 +1826,37 @@ func (db *DB) GetTeamMembers() ([]models.TeamMember, error) {
 func (db *DB) CreateTeamMember(member *models.TeamMember) error {
 	query := `
 		INSERT INTO team_members (
-			id, name, email, role, status, last_active, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			id, name, email, role, status, org_id, last_active, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
 	_, err := db.Exec(query,
 		member.ID, member.Name, member.Email, member.Role,
-		member.Status, member.LastActive, member.CreatedAt,
+		member.Status, member.OrgID, member.LastActive, member.CreatedAt,
 	)
 
 	return err
 }
+
+func (db *DB) GetTeamMemberByID(id uuid.UUID) (*models.TeamMember, error) {
+	query := `
+		SELECT id, name, email, role, status, org_id, last_active, created_at
+		FROM team_members WHERE id = $1
+	`
+
+	var member models.TeamMember
+
+	err := db.QueryRow(query, id).Scan(
+		&member.ID, &member.Name, &member.Email, &member.Role,
+		&member.Status, &member.OrgID, &member.LastActive, &member.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("team member not found")
+		}
+		return nil, fmt.Errorf("failed to get team member: %w", err)
+	}
+
+	return &member, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const debugFileColumns = "id, project_id, kind, release, build_id, name, size, checksum, created_at"
+
+func scanDebugFile(row rowScanner) (*models.DebugFile, error) {
+	var file models.DebugFile
+	err := row.Scan(
+		&file.ID, &file.ProjectID, &file.Kind, &file.Release, &file.BuildID,
+		&file.Name, &file.Size, &file.Checksum, &file.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (db *DB) queryDebugFiles(query string, args ...interface{}) ([]models.DebugFile, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query debug files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []models.DebugFile{}
+	for rows.Next() {
+		file, err := scanDebugFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan debug file: %w", err)
+		}
+		files = append(files, *file)
+	}
+
+	return files, nil
+}
+
+// Debug file methods
+func (db *DB) CreateDebugFile(file *models.DebugFile) error {
+	query := fmt.Sprintf(`
+		INSERT INTO debug_files (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, debugFileColumns)
+
+	_, err := db.Exec(query,
+		file.ID, file.ProjectID, file.Kind, file.Release, file.BuildID,
+		file.Name, file.Size, file.Checksum, file.CreatedAt,
+	)
+
+	return err
+}
+
+func (db *DB) GetDebugFiles(release string) ([]models.DebugFile, error) {
+	query := fmt.Sprintf("SELECT %s FROM debug_files", debugFileColumns)
+	args := []interface{}{}
+
+	if release != "" {
+		query += " WHERE release = $1"
+		args = append(args, release)
+	}
+	query += " ORDER BY created_at DESC"
+
+	return db.queryDebugFiles(query, args...)
+}
+
+// FindDebugFiles returns the debug files of a kind uploaded for a release,
+// newest first. Project-scoped lookups include files uploaded without a project.
+func (db *DB) FindDebugFiles(projectID *uuid.UUID, kind, release string) ([]models.DebugFile, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM debug_files
+		WHERE kind = $1 AND release = $2 AND (project_id IS NULL OR project_id = $3)
+		ORDER BY created_at DESC
+	`, debugFileColumns)
+
+	return db.queryDebugFiles(query, kind, release, projectID)
+}
+
+func (db *DB) GetDebugFileByID(id uuid.UUID) (*models.DebugFile, error) {
+	query := fmt.Sprintf("SELECT %s FROM debug_files WHERE id = $1", debugFileColumns)
+
+	file, err := scanDebugFile(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("debug file not found")
+		}
+		return nil, fmt.Errorf("failed to get debug file: %w", err)
+	}
+
+	return file, nil
+}
+
+func (db *DB) DeleteDebugFile(id uuid.UUID) error {
+	_, err := db.Exec("DELETE FROM debug_files WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,365 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"error-logs/internal/encryption"
+	"error-logs/internal/models"
+)
+
+// webhookPrefix marks alert rule notification targets whose URL is encrypted
+const webhookPrefix = "webhook:"
+
+// UseEncryption encrypts API key signing secrets, webhook URLs, event sink
+// and integration secrets with keyring from now on, and client IP addresses of errors if
+// encryptIPAddresses is set. Values are decrypted transparently when read;
+// values written before encryption was enabled are read as they are.
+func (db *DB) UseEncryption(keyring *encryption.Keyring, encryptIPAddresses bool) {
+	db.keyring = keyring
+	db.encryptIPAddresses = encryptIPAddresses && keyring.Enabled()
+}
+
+func (db *DB) encryptOptional(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encrypted, err := db.keyring.Encrypt(*value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+	return &encrypted, nil
+}
+
+func (db *DB) decryptOptional(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	decrypted, err := db.keyring.Decrypt(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &decrypted, nil
+}
+
+func (db *DB) encryptIPAddress(ip *string) (*string, error) {
+	if !db.encryptIPAddresses {
+		return ip, nil
+	}
+	return db.encryptOptional(ip)
+}
+
+// encryptNotifications encrypts the URL of each webhook notification target
+func (db *DB) encryptNotifications(notifications []string) ([]string, error) {
+	encrypted := make([]string, len(notifications))
+	for i, target := range notifications {
+		url, isWebhook := strings.CutPrefix(target, webhookPrefix)
+		if !isWebhook || encryption.IsEncrypted(url) {
+			encrypted[i] = target
+			continue
+		}
+		sealed, err := db.keyring.Encrypt(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt webhook URL: %w", err)
+		}
+		encrypted[i] = webhookPrefix + sealed
+	}
+	return encrypted, nil
+}
+
+func (db *DB) decryptNotifications(notifications []string) ([]string, error) {
+	for i, target := range notifications {
+		url, isWebhook := strings.CutPrefix(target, webhookPrefix)
+		if !isWebhook {
+			continue
+		}
+		opened, err := db.keyring.Decrypt(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt webhook URL: %w", err)
+		}
+		notifications[i] = webhookPrefix + opened
+	}
+	return notifications, nil
+}
+
+// rotationBatchSize bounds how many error rows are re-encrypted per query
+const rotationBatchSize = 1000
+
+// RotationResult counts the rows re-encrypted by RotateEncryption
+type RotationResult struct {
+	APIKeys               int
+	AlertRules            int
+	NotificationSchedules int
+	EventSinks            int
+	Integrations          int
+	Errors                int
+}
+
+// RotateEncryption re-encrypts every encrypted value with the current key and
+// encrypts values stored before encryption was enabled. Error IP addresses
+// are decrypted back to plaintext when IP encryption is off. Once it
+// completes, keys other than the current one can be removed.
+func (db *DB) RotateEncryption() (*RotationResult, error) {
+	if !db.keyring.Enabled() {
+		return nil, fmt.Errorf("no encryption keys are configured")
+	}
+
+	var result RotationResult
+	var err error
+	if result.APIKeys, err = db.rotateSigningSecrets(); err != nil {
+		return &result, err
+	}
+	if result.AlertRules, err = db.rotateWebhookURLs(); err != nil {
+		return &result, err
+	}
+	if result.NotificationSchedules, err = db.rotateScheduleWebhooks(); err != nil {
+		return &result, err
+	}
+	if result.EventSinks, err = db.rotateSinkSecrets(); err != nil {
+		return &result, err
+	}
+	if result.Integrations, err = db.rotateIntegrationSecrets(); err != nil {
+		return &result, err
+	}
+	if result.Errors, err = db.rotateIPAddresses(); err != nil {
+		return &result, err
+	}
+	return &result, nil
+}
+
+func (db *DB) rotateSigningSecrets() (int, error) {
+	rows, err := db.Query(`
+		SELECT id, signing_secret FROM api_keys
+		WHERE signing_secret IS NOT NULL AND signing_secret NOT LIKE $1
+	`, db.keyring.CurrentPrefix()+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query signing secrets: %w", err)
+	}
+	pending := make(map[string]string)
+	for rows.Next() {
+		var id, secret string
+		if err := rows.Scan(&id, &secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan signing secret: %w", err)
+		}
+		pending[id] = secret
+	}
+	rows.Close()
+
+	for id, secret := range pending {
+		plaintext, err := db.keyring.Decrypt(secret)
+		if err != nil {
+			return 0, fmt.Errorf("API key %s: %w", id, err)
+		}
+		sealed, err := db.keyring.Encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec("UPDATE api_keys SET signing_secret = $2 WHERE id = $1", id, sealed); err != nil {
+			return 0, fmt.Errorf("failed to update API key %s: %w", id, err)
+		}
+	}
+	return len(pending), nil
+}
+
+func (db *DB) rotateSinkSecrets() (int, error) {
+	rows, err := db.Query(`
+		SELECT id, secret FROM event_sinks
+		WHERE secret IS NOT NULL AND secret NOT LIKE $1
+	`, db.keyring.CurrentPrefix()+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sink secrets: %w", err)
+	}
+	pending := make(map[string]string)
+	for rows.Next() {
+		var id, secret string
+		if err := rows.Scan(&id, &secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan sink secret: %w", err)
+		}
+		pending[id] = secret
+	}
+	rows.Close()
+
+	for id, secret := range pending {
+		plaintext, err := db.keyring.Decrypt(secret)
+		if err != nil {
+			return 0, fmt.Errorf("event sink %s: %w", id, err)
+		}
+		sealed, err := db.keyring.Encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec("UPDATE event_sinks SET secret = $2 WHERE id = $1", id, sealed); err != nil {
+			return 0, fmt.Errorf("failed to update event sink %s: %w", id, err)
+		}
+	}
+	return len(pending), nil
+}
+
+func (db *DB) rotateIntegrationSecrets() (int, error) {
+	rows, err := db.Query(`
+		SELECT id, secret FROM integrations
+		WHERE secret IS NOT NULL AND secret NOT LIKE $1
+	`, db.keyring.CurrentPrefix()+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query integration secrets: %w", err)
+	}
+	pending := make(map[string]string)
+	for rows.Next() {
+		var id, secret string
+		if err := rows.Scan(&id, &secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan integration secret: %w", err)
+		}
+		pending[id] = secret
+	}
+	rows.Close()
+
+	for id, secret := range pending {
+		plaintext, err := db.keyring.Decrypt(secret)
+		if err != nil {
+			return 0, fmt.Errorf("integration %s: %w", id, err)
+		}
+		sealed, err := db.keyring.Encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec("UPDATE integrations SET secret = $2 WHERE id = $1", id, sealed); err != nil {
+			return 0, fmt.Errorf("failed to update integration %s: %w", id, err)
+		}
+	}
+	return len(pending), nil
+}
+
+func (db *DB) rotateWebhookURLs() (int, error) {
+	rows, err := db.Query(`SELECT id, notifications FROM alert_rules WHERE notifications::text LIKE '%webhook:%'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	stored := make(map[string][]string)
+	for rows.Next() {
+		var id string
+		var notificationsJSON []byte
+		if err := rows.Scan(&id, &notificationsJSON); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		var notifications []string
+		json.Unmarshal(notificationsJSON, &notifications)
+		stored[id] = notifications
+	}
+	rows.Close()
+
+	current := webhookPrefix + db.keyring.CurrentPrefix()
+	rotated := 0
+	for id, notifications := range stored {
+		stale := false
+		for _, target := range notifications {
+			if strings.HasPrefix(target, webhookPrefix) && !strings.HasPrefix(target, current) {
+				stale = true
+			}
+		}
+		if !stale {
+			continue
+		}
+
+		plaintext, err := db.decryptNotifications(notifications)
+		if err != nil {
+			return rotated, fmt.Errorf("alert rule %s: %w", id, err)
+		}
+		sealed, err := db.encryptNotifications(plaintext)
+		if err != nil {
+			return rotated, err
+		}
+		notificationsJSON, err := json.Marshal(sealed)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to marshal notifications: %w", err)
+		}
+		if _, err := db.Exec("UPDATE alert_rules SET notifications = $2 WHERE id = $1", id, notificationsJSON); err != nil {
+			return rotated, fmt.Errorf("failed to update alert rule %s: %w", id, err)
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+func (db *DB) rotateScheduleWebhooks() (int, error) {
+	current := webhookPrefix + db.keyring.CurrentPrefix() + "%"
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT %s FROM notification_schedules
+		WHERE (target LIKE 'webhook:%%' AND target NOT LIKE $1) OR (fallback LIKE 'webhook:%%' AND fallback NOT LIKE $1)
+	`, notificationScheduleColumns), current)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query notification schedules: %w", err)
+	}
+	pending := []*models.NotificationSchedule{}
+	for rows.Next() {
+		schedule, err := db.scanNotificationSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan notification schedule: %w", err)
+		}
+		pending = append(pending, schedule)
+	}
+	rows.Close()
+
+	for i, schedule := range pending {
+		target, fallback, err := db.encryptScheduleTargets(schedule)
+		if err != nil {
+			return i, err
+		}
+		if _, err := db.Exec("UPDATE notification_schedules SET target = $2, fallback = $3 WHERE id = $1", schedule.ID, target, fallback); err != nil {
+			return i, fmt.Errorf("failed to update notification schedule %s: %w", schedule.ID, err)
+		}
+	}
+	return len(pending), nil
+}
+
+func (db *DB) rotateIPAddresses() (int, error) {
+	// Rows that are not yet in their target form: encrypted with the current
+	// key when IP encryption is on, plaintext when it is off
+	query := `SELECT id, ip_address FROM errors WHERE ip_address IS NOT NULL AND ip_address NOT LIKE $1 LIMIT $2`
+	pattern := db.keyring.CurrentPrefix() + "%"
+	if !db.encryptIPAddresses {
+		query = `SELECT id, ip_address FROM errors WHERE ip_address LIKE $1 LIMIT $2`
+		pattern = "enc:%"
+	}
+
+	rotated := 0
+	for {
+		rows, err := db.Query(query, pattern, rotationBatchSize)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to query IP addresses: %w", err)
+		}
+		batch := make(map[string]string)
+		for rows.Next() {
+			var id, ip string
+			if err := rows.Scan(&id, &ip); err != nil {
+				rows.Close()
+				return rotated, fmt.Errorf("failed to scan IP address: %w", err)
+			}
+			batch[id] = ip
+		}
+		rows.Close()
+		if len(batch) == 0 {
+			return rotated, nil
+		}
+
+		for id, ip := range batch {
+			plaintext, err := db.keyring.Decrypt(ip)
+			if err != nil {
+				return rotated, fmt.Errorf("error %s: %w", id, err)
+			}
+			value, err := db.encryptIPAddress(&plaintext)
+			if err != nil {
+				return rotated, err
+			}
+			if _, err := db.Exec("UPDATE errors SET ip_address = $2 WHERE id = $1", id, *value); err != nil {
+				return rotated, fmt.Errorf("failed to update error %s: %w", id, err)
+			}
+			rotated++
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+const eventSinkColumns = "id, name, kind, config, secret, levels, sources, enabled, last_delivered_at, last_error, created_at, updated_at"
+
+func (db *DB) scanEventSink(row rowScanner) (*models.EventSink, error) {
+	var sink models.EventSink
+	var configJSON []byte
+	err := row.Scan(
+		&sink.ID, &sink.Name, &sink.Kind, &configJSON, &sink.Secret,
+		pq.Array(&sink.Levels), pq.Array(&sink.Sources), &sink.Enabled,
+		&sink.LastDeliveredAt, &sink.LastError, &sink.CreatedAt, &sink.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(configJSON, &sink.Config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sink config: %w", err)
+	}
+	if sink.Secret, err = db.decryptOptional(sink.Secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt sink secret: %w", err)
+	}
+	sink.HasSecret = sink.Secret != nil
+	if sink.Levels == nil {
+		sink.Levels = []string{}
+	}
+	if sink.Sources == nil {
+		sink.Sources = []string{}
+	}
+	return &sink, nil
+}
+
+// Event sink methods
+func (db *DB) GetEventSinks() ([]models.EventSink, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM event_sinks ORDER BY name, id", eventSinkColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event sinks: %w", err)
+	}
+	defer rows.Close()
+
+	sinks := []models.EventSink{}
+	for rows.Next() {
+		sink, err := db.scanEventSink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event sink: %w", err)
+		}
+		sinks = append(sinks, *sink)
+	}
+
+	return sinks, nil
+}
+
+func (db *DB) GetEventSink(id uuid.UUID) (*models.EventSink, error) {
+	sink, err := db.scanEventSink(db.QueryRow(fmt.Sprintf("SELECT %s FROM event_sinks WHERE id = $1", eventSinkColumns), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("event sink not found")
+		}
+		return nil, fmt.Errorf("failed to get event sink: %w", err)
+	}
+	return sink, nil
+}
+
+func (db *DB) CreateEventSink(sink *models.EventSink) error {
+	configJSON, err := json.Marshal(sink.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sink config: %w", err)
+	}
+	secret, err := db.encryptOptional(sink.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO event_sinks (id, name, kind, config, secret, levels, sources, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, sink.ID, sink.Name, sink.Kind, configJSON, secret,
+		pq.Array(nilIfEmpty(sink.Levels)), pq.Array(nilIfEmpty(sink.Sources)), sink.Enabled, sink.CreatedAt, sink.UpdatedAt)
+	return err
+}
+
+func (db *DB) UpdateEventSink(sink *models.EventSink) error {
+	configJSON, err := json.Marshal(sink.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sink config: %w", err)
+	}
+	secret, err := db.encryptOptional(sink.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE event_sinks SET
+			name = $2, config = $3, secret = $4, levels = $5, sources = $6,
+			enabled = $7, updated_at = $8
+		WHERE id = $1
+	`, sink.ID, sink.Name, configJSON, secret,
+		pq.Array(nilIfEmpty(sink.Levels)), pq.Array(nilIfEmpty(sink.Sources)), sink.Enabled, sink.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteEventSink(id uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM event_sinks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("event sink not found")
+	}
+	return nil
+}
+
+// RecordEventSinkDelivery stores the outcome of a delivery attempt. A nil
+// deliveryErr marks a successful delivery and clears the last error.
+func (db *DB) RecordEventSinkDelivery(id uuid.UUID, at time.Time, deliveryErr error) error {
+	if deliveryErr != nil {
+		_, err := db.Exec("UPDATE event_sinks SET last_error = $2 WHERE id = $1", id, deliveryErr.Error())
+		return err
+	}
+	_, err := db.Exec("UPDATE event_sinks SET last_delivered_at = $2, last_error = NULL WHERE id = $1", id, at)
+	return err
+}
+
+func nilIfEmpty(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const exportJobColumns = `id, format, destination, filter, from_time, to_time, status,
+	project_id, total_rows, exported_rows, size_bytes, s3_key, error, created_by,
+	created_at, updated_at, completed_at, expires_at`
+
+// Export job methods
+func (db *DB) CreateExportJob(job *models.ExportJob) error {
+	filterJSON, err := json.Marshal(job.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal filter: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO export_jobs (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`, exportJobColumns)
+
+	_, err = db.Exec(query,
+		job.ID, job.Format, job.Destination, filterJSON, job.From, job.To, job.Status,
+		job.ProjectID, job.TotalRows, job.ExportedRows, job.SizeBytes, job.S3Key, job.Error, job.CreatedBy,
+		job.CreatedAt, job.UpdatedAt, job.CompletedAt, job.ExpiresAt,
+	)
+	return err
+}
+
+// UpdateExportJob persists a job's status and progress
+func (db *DB) UpdateExportJob(job *models.ExportJob) error {
+	query := `
+		UPDATE export_jobs SET status = $2, total_rows = $3, exported_rows = $4, size_bytes = $5,
+			s3_key = $6, error = $7, updated_at = $8, completed_at = $9, expires_at = $10
+		WHERE id = $1
+	`
+	_, err := db.Exec(query,
+		job.ID, job.Status, job.TotalRows, job.ExportedRows, job.SizeBytes,
+		job.S3Key, job.Error, job.UpdatedAt, job.CompletedAt, job.ExpiresAt,
+	)
+	return err
+}
+
+// TouchExportJob records that a pending or running job is still being worked on
+func (db *DB) TouchExportJob(id uuid.UUID) error {
+	_, err := db.Exec(`
+		UPDATE export_jobs SET updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'running')
+	`, id)
+	return err
+}
+
+// FailStaleExportJobs fails the pending and running jobs not updated since
+// cutoff, as the instance working on them has stopped
+func (db *DB) FailStaleExportJobs(cutoff time.Time, message string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE export_jobs SET status = 'failed', error = $2, updated_at = NOW(), completed_at = NOW()
+		WHERE status IN ('pending', 'running') AND updated_at < $1
+	`, cutoff, message)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale export jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (db *DB) GetExportJob(id uuid.UUID) (*models.ExportJob, error) {
+	query := fmt.Sprintf("SELECT %s FROM export_jobs WHERE id = $1", exportJobColumns)
+
+	job, err := scanExportJob(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("export job not found")
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetExpiredExportJobs returns completed jobs whose files expired before cutoff
+func (db *DB) GetExpiredExportJobs(cutoff time.Time) ([]models.ExportJob, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM export_jobs
+		WHERE status = 'completed' AND expires_at < $1
+		ORDER BY expires_at
+	`, exportJobColumns)
+
+	rows, err := db.Query(query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.ExportJob
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanExportJob(row rowScanner) (*models.ExportJob, error) {
+	var job models.ExportJob
+	var filterJSON []byte
+	err := row.Scan(
+		&job.ID, &job.Format, &job.Destination, &filterJSON, &job.From, &job.To, &job.Status,
+		&job.ProjectID, &job.TotalRows, &job.ExportedRows, &job.SizeBytes, &job.S3Key, &job.Error, &job.CreatedBy,
+		&job.CreatedAt, &job.UpdatedAt, &job.CompletedAt, &job.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(filterJSON, &job.Filter)
+	return &job, nil
+}
+
+// exportFilter adds the export's time range to the error list filter
+func exportFilter(filter models.ErrorFilter, from, to *time.Time) (string, []interface{}, int) {
+	whereClause, args, argIndex := buildErrorFilter(filter)
+	if from != nil {
+		whereClause += fmt.Sprintf(" AND timestamp >= $%d", argIndex)
+		args = append(args, *from)
+		argIndex++
+	}
+	if to != nil {
+		whereClause += fmt.Sprintf(" AND timestamp < $%d", argIndex)
+		args = append(args, *to)
+		argIndex++
+	}
+	return whereClause, args, argIndex
+}
+
+// CountErrorsForExport counts the errors an export will write
+func (db *DB) CountErrorsForExport(filter models.ErrorFilter, from, to *time.Time) (int, error) {
+	whereClause, args, _ := exportFilter(filter, from, to)
+
+	var total int
+	err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM errors %s", whereClause), args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count errors: %w", err)
+	}
+	return total, nil
+}
+
+// GetErrorsForExport returns the next page of errors after the (timestamp, id)
+// position, oldest first, so an export pages through a large table without
+// OFFSET scans. A nil afterID starts from the beginning.
+func (db *DB) GetErrorsForExport(filter models.ErrorFilter, from, to *time.Time, afterTimestamp time.Time, afterID *uuid.UUID, limit int) ([]models.Error, error) {
+	whereClause, args, argIndex := exportFilter(filter, from, to)
+	if afterID != nil {
+		whereClause += fmt.Sprintf(" AND (timestamp, id) > ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, afterTimestamp, *afterID)
+		argIndex += 2
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM errors %s
+		ORDER BY timestamp, id
+		LIMIT $%d
+	`, errorColumns, whereClause, argIndex)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errors []models.Error
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		errors = append(errors, *e)
+	}
+
+	return errors, rows.Err()
+}
+
+// GetGroupContextKeys returns the top-level context keys used by any
+// occurrence of a group, sorted
+func (db *DB) GetGroupContextKeys(fingerprint string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT jsonb_object_keys(context) AS key
+		FROM errors
+		WHERE fingerprint = $1 AND deleted_at IS NULL AND jsonb_typeof(context) = 'object'
+		ORDER BY key
+	`, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query context keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan context key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetGroupEventsForExport returns the next page of a group's occurrences
+// after the (timestamp, id) position, oldest first. A nil afterID starts from
+// the beginning.
+func (db *DB) GetGroupEventsForExport(fingerprint string, afterTimestamp time.Time, afterID *uuid.UUID, limit int) ([]models.Error, error) {
+	whereClause := "WHERE fingerprint = $1 AND deleted_at IS NULL"
+	args := []interface{}{fingerprint}
+	if afterID != nil {
+		whereClause += " AND (timestamp, id) > ($2, $3)"
+		args = append(args, afterTimestamp, *afterID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM errors %s
+		ORDER BY timestamp, id
+		LIMIT $%d
+	`, errorColumns, whereClause, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errors []models.Error
+	for rows.Next() {
+		e, err := db.scanError(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		errors = append(errors, *e)
+	}
+
+	return errors, rows.Err()
+}
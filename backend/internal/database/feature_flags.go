@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Feature flag methods
+func (db *DB) GetFeatureFlags() ([]models.FeatureFlag, error) {
+	rows, err := db.Query("SELECT key, COALESCE(description, ''), enabled, updated_at FROM feature_flags ORDER BY key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.FeatureFlag{}
+	for rows.Next() {
+		var flag models.FeatureFlag
+		if err := rows.Scan(&flag.Key, &flag.Description, &flag.Enabled, &flag.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
+
+func (db *DB) UpsertFeatureFlag(flag *models.FeatureFlag) error {
+	_, err := db.Exec(`
+		INSERT INTO feature_flags (key, description, enabled, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			description = EXCLUDED.description,
+			enabled = EXCLUDED.enabled,
+			updated_at = EXCLUDED.updated_at
+	`, flag.Key, flag.Description, flag.Enabled, flag.UpdatedAt)
+	return err
+}
+
+// DeleteFeatureFlag removes a flag and all of its overrides
+func (db *DB) DeleteFeatureFlag(key string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM feature_flag_overrides WHERE flag_key = $1", key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM feature_flags WHERE key = $1", key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) GetFeatureFlagOverrides() ([]models.FeatureFlagOverride, error) {
+	rows, err := db.Query(`
+		SELECT flag_key, scope, scope_id, enabled, updated_at
+		FROM feature_flag_overrides ORDER BY flag_key, scope, scope_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flag overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.FeatureFlagOverride{}
+	for rows.Next() {
+		var override models.FeatureFlagOverride
+		if err := rows.Scan(&override.FlagKey, &override.Scope, &override.ScopeID, &override.Enabled, &override.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag override: %w", err)
+		}
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
+func (db *DB) UpsertFeatureFlagOverride(override *models.FeatureFlagOverride) error {
+	_, err := db.Exec(`
+		INSERT INTO feature_flag_overrides (flag_key, scope, scope_id, enabled, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (flag_key, scope, scope_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			updated_at = EXCLUDED.updated_at
+	`, override.FlagKey, override.Scope, override.ScopeID, override.Enabled, override.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteFeatureFlagOverride(key, scope string, scopeID uuid.UUID) error {
+	result, err := db.Exec(
+		"DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND scope = $2 AND scope_id = $3",
+		key, scope, scopeID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("feature flag override not found")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// RecordFirstSeen stores the event as the first occurrence of its group in its
+// environment, and reports whether it was; later occurrences leave the row as is
+func (db *DB) RecordFirstSeen(event *models.FirstSeenEvent) (bool, error) {
+	query := `
+		INSERT INTO error_first_seen (fingerprint, environment, error_id, project_id, message, level, source, first_seen)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (fingerprint, environment) DO NOTHING
+	`
+
+	result, err := db.Exec(query,
+		event.Fingerprint, event.Environment, event.ErrorID, event.ProjectID,
+		event.Message, event.Level, event.Source, event.FirstSeen,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record first seen: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// RecordEarliestFirstSeen records an imported event as the first of its group
+// in its environment unless an earlier event is already recorded
+func (db *DB) RecordEarliestFirstSeen(event *models.FirstSeenEvent) error {
+	query := `
+		INSERT INTO error_first_seen (fingerprint, environment, error_id, project_id, message, level, source, first_seen)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (fingerprint, environment) DO UPDATE SET
+			error_id = EXCLUDED.error_id, project_id = EXCLUDED.project_id, message = EXCLUDED.message,
+			level = EXCLUDED.level, source = EXCLUDED.source, first_seen = EXCLUDED.first_seen
+		WHERE error_first_seen.first_seen > EXCLUDED.first_seen
+	`
+
+	_, err := db.Exec(query,
+		event.Fingerprint, event.Environment, event.ErrorID, event.ProjectID,
+		event.Message, event.Level, event.Source, event.FirstSeen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record first seen: %w", err)
+	}
+	return nil
+}
+
+// GetFirstSeenEvents lists error groups first seen since the given time,
+// newest first, optionally in one environment
+func (db *DB) GetFirstSeenEvents(environment string, since time.Time, limit int) ([]models.FirstSeenEvent, error) {
+	query := `
+		SELECT fingerprint, environment, error_id, project_id, message, level, source, first_seen
+		FROM error_first_seen
+		WHERE first_seen >= $1 AND ($2 = '' OR environment = $2)
+		ORDER BY first_seen DESC
+		LIMIT $3
+	`
+
+	rows, err := db.Query(query, since, environment, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query first seen events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.FirstSeenEvent{}
+	for rows.Next() {
+		var event models.FirstSeenEvent
+		err := rows.Scan(
+			&event.Fingerprint, &event.Environment, &event.ErrorID, &event.ProjectID,
+			&event.Message, &event.Level, &event.Source, &event.FirstSeen,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan first seen event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
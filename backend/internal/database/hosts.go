@@ -0,0 +1,223 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// hostColumns lists the hosts columns in the order scanHost expects. Hosts
+// that checked in since the time in the given placeholder are up.
+func hostColumns(upSinceArg int) string {
+	return fmt.Sprintf(`hostname, environment, agent_version,
+	CASE WHEN last_seen >= $%d THEN 'up' ELSE 'down' END,
+	cpu_usage_percent, memory_usage_percent, disk_usage_percent, bytes_in, bytes_out,
+	active_connections, requests_per_minute, first_seen, last_seen`, upSinceArg)
+}
+
+func scanHost(row interface{ Scan(...interface{}) error }, host *models.Host) error {
+	return row.Scan(
+		&host.Hostname, &host.Environment, &host.AgentVersion, &host.Status,
+		&host.CPUUsagePercent, &host.MemoryUsagePercent, &host.DiskUsagePercent,
+		&host.NetworkIO.BytesIn, &host.NetworkIO.BytesOut,
+		&host.ActiveConnections, &host.RequestsPerMinute, &host.FirstSeen, &host.LastSeen,
+	)
+}
+
+// RecordHostCheckIn stores a host agent's check-in as the host's current
+// metrics and as a sample of its history
+func (db *DB) RecordHostCheckIn(req *models.HostCheckInRequest, at time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO hosts (
+			hostname, environment, agent_version, cpu_usage_percent, memory_usage_percent,
+			disk_usage_percent, bytes_in, bytes_out, active_connections, requests_per_minute,
+			first_seen, last_seen
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+		ON CONFLICT (hostname) DO UPDATE SET
+			environment = EXCLUDED.environment, agent_version = EXCLUDED.agent_version,
+			cpu_usage_percent = EXCLUDED.cpu_usage_percent, memory_usage_percent = EXCLUDED.memory_usage_percent,
+			disk_usage_percent = EXCLUDED.disk_usage_percent, bytes_in = EXCLUDED.bytes_in,
+			bytes_out = EXCLUDED.bytes_out, active_connections = EXCLUDED.active_connections,
+			requests_per_minute = EXCLUDED.requests_per_minute,
+			last_seen = GREATEST(hosts.last_seen, EXCLUDED.last_seen)
+	`,
+		req.Hostname, req.Environment, req.AgentVersion, req.CPUUsagePercent, req.MemoryUsagePercent,
+		req.DiskUsagePercent, req.NetworkIO.BytesIn, req.NetworkIO.BytesOut, req.ActiveConnections,
+		req.RequestsPerMinute, at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record host %s: %w", req.Hostname, err)
+	}
+
+	// Two check-ins in the same instant keep the first sample
+	_, err = tx.Exec(`
+		INSERT INTO host_samples (
+			hostname, timestamp, cpu_usage_percent, memory_usage_percent, disk_usage_percent,
+			bytes_in, bytes_out, active_connections, requests_per_minute
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (hostname, timestamp) DO NOTHING
+	`,
+		req.Hostname, at, req.CPUUsagePercent, req.MemoryUsagePercent, req.DiskUsagePercent,
+		req.NetworkIO.BytesIn, req.NetworkIO.BytesOut, req.ActiveConnections, req.RequestsPerMinute,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record host sample for %s: %w", req.Hostname, err)
+	}
+
+	return tx.Commit()
+}
+
+// GetHosts returns a page of the hosts matching filter, by hostname, and how
+// many match in total. Hosts that checked in since upSince are up.
+func (db *DB) GetHosts(limit, offset int, filter models.HostFilter, upSince time.Time) ([]models.Host, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.Environment != "" {
+		conditions = append(conditions, fmt.Sprintf("environment = $%d", argIndex))
+		args = append(args, filter.Environment)
+		argIndex++
+	}
+	switch filter.Status {
+	case "up":
+		conditions = append(conditions, fmt.Sprintf("last_seen >= $%d", argIndex))
+		args = append(args, upSince)
+		argIndex++
+	case "down":
+		conditions = append(conditions, fmt.Sprintf("last_seen < $%d", argIndex))
+		args = append(args, upSince)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM hosts "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count hosts: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM hosts %s
+		ORDER BY hostname
+		LIMIT $%d OFFSET $%d
+	`, hostColumns(argIndex), whereClause, argIndex+1, argIndex+2)
+
+	rows, err := db.Query(query, append(args, upSince, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query hosts: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := []models.Host{}
+	for rows.Next() {
+		var host models.Host
+		if err := scanHost(rows, &host); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan host: %w", err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, total, rows.Err()
+}
+
+func (db *DB) GetHost(hostname string, upSince time.Time) (*models.Host, error) {
+	var host models.Host
+	err := scanHost(db.QueryRow("SELECT "+hostColumns(2)+" FROM hosts WHERE hostname = $1", hostname, upSince), &host)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("host not found")
+		}
+		return nil, fmt.Errorf("failed to get host: %w", err)
+	}
+	return &host, nil
+}
+
+// GetHostSamples returns the check-ins of a host since the given time, oldest first
+func (db *DB) GetHostSamples(hostname string, since time.Time) ([]models.HostSample, error) {
+	rows, err := db.Query(`
+		SELECT timestamp, cpu_usage_percent, memory_usage_percent, disk_usage_percent,
+			bytes_in, bytes_out, active_connections, requests_per_minute
+		FROM host_samples
+		WHERE hostname = $1 AND timestamp >= $2
+		ORDER BY timestamp
+	`, hostname, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query host samples: %w", err)
+	}
+	defer rows.Close()
+
+	samples := []models.HostSample{}
+	for rows.Next() {
+		var sample models.HostSample
+		err := rows.Scan(
+			&sample.Timestamp, &sample.CPUUsagePercent, &sample.MemoryUsagePercent, &sample.DiskUsagePercent,
+			&sample.NetworkIO.BytesIn, &sample.NetworkIO.BytesOut, &sample.ActiveConnections, &sample.RequestsPerMinute,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan host sample: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// DeleteHost forgets a host and its history, e.g. once it is decommissioned
+func (db *DB) DeleteHost(hostname string) error {
+	result, err := db.Exec("DELETE FROM hosts WHERE hostname = $1", hostname)
+	if err != nil {
+		return fmt.Errorf("failed to delete host: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("host not found")
+	}
+	return nil
+}
+
+// GetHostTotals combines the last check-in of every host that is up: usage
+// percentages are averaged, traffic and connections are summed
+func (db *DB) GetHostTotals(upSince time.Time) (*models.SystemMetrics, error) {
+	metrics := &models.SystemMetrics{Hosts: &models.HostSummary{}}
+	err := db.QueryRow(`
+		SELECT
+			COALESCE(AVG(cpu_usage_percent) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(AVG(memory_usage_percent) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(AVG(disk_usage_percent) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(SUM(bytes_in) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(SUM(bytes_out) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(SUM(active_connections) FILTER (WHERE last_seen >= $1), 0),
+			COALESCE(SUM(requests_per_minute) FILTER (WHERE last_seen >= $1), 0),
+			COUNT(*), COUNT(*) FILTER (WHERE last_seen >= $1)
+		FROM hosts
+	`, upSince).Scan(
+		&metrics.CPUUsagePercent, &metrics.MemoryUsagePercent, &metrics.DiskUsagePercent,
+		&metrics.NetworkIO.BytesIn, &metrics.NetworkIO.BytesOut,
+		&metrics.ActiveConnections, &metrics.RequestsPerMinute,
+		&metrics.Hosts.Total, &metrics.Hosts.Up,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host totals: %w", err)
+	}
+	metrics.Hosts.Down = metrics.Hosts.Total - metrics.Hosts.Up
+	return metrics, nil
+}
+
+func (db *DB) DeleteHostSamplesBefore(cutoff time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM host_samples WHERE timestamp < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete host samples: %w", err)
+	}
+	return result.RowsAffected()
+}
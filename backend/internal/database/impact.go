@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// impactScoreQuery scores every unresolved group seen in the last week as
+//
+//	level weight × (1 + ln(1 + events in 24h) + 2·ln(1 + users in 24h))
+//	  × trend × recency
+//
+// Level weight is 3 for error, 2 for warning and 1 otherwise. Trend compares
+// the last 24 hours with the 24 before, clamped to 0.5–3. Recency decays by e
+// for every day since the group was last seen. Users are counted from the
+// context user_id, or user.id.
+const impactScoreQuery = `
+	WITH groups AS (
+		SELECT fingerprint,
+			MAX(timestamp) AS last_seen,
+			COUNT(*) FILTER (WHERE timestamp >= $1::timestamptz - INTERVAL '24 hours') AS events_24h,
+			COUNT(*) FILTER (WHERE timestamp < $1::timestamptz - INTERVAL '24 hours' AND timestamp >= $1::timestamptz - INTERVAL '48 hours') AS events_prev_24h,
+			COUNT(DISTINCT COALESCE(context->>'user_id', context#>>'{user,id}'))
+				FILTER (WHERE timestamp >= $1::timestamptz - INTERVAL '24 hours') AS users_24h,
+			MAX(CASE level WHEN 'error' THEN 3 WHEN 'warning' THEN 2 ELSE 1 END) AS level_weight
+		FROM errors
+		WHERE deleted_at IS NULL AND duplicate_of IS NULL AND resolved = false AND fingerprint IS NOT NULL
+		  AND timestamp >= $1::timestamptz - INTERVAL '7 days'
+		GROUP BY fingerprint
+	)
+	INSERT INTO error_group_impact (fingerprint, score, events_24h, users_24h, computed_at)
+	SELECT fingerprint,
+		level_weight * (1 + LN(1 + events_24h) + 2 * LN(1 + users_24h))
+			* (1 + LEAST(GREATEST((events_24h - events_prev_24h)::float8 / GREATEST(events_prev_24h, 1), -0.5), 2))
+			* EXP(-EXTRACT(EPOCH FROM $1::timestamptz - last_seen) / 86400.0),
+		events_24h, users_24h, $1::timestamptz
+	FROM groups
+	ON CONFLICT (fingerprint) DO UPDATE SET
+		score = EXCLUDED.score, events_24h = EXCLUDED.events_24h,
+		users_24h = EXCLUDED.users_24h, computed_at = EXCLUDED.computed_at
+`
+
+// RefreshImpactScores recomputes the impact score of every active error group
+// and drops the scores of groups that were resolved or went quiet
+func (db *DB) RefreshImpactScores(now time.Time) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin impact refresh: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(impactScoreQuery, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute impact scores: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM error_group_impact WHERE computed_at < $1", now); err != nil {
+		return 0, fmt.Errorf("failed to drop stale impact scores: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit impact scores: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetErrorGroupImpacts returns the impact scores of the given groups, leaving
+// out groups without one
+func (db *DB) GetErrorGroupImpacts(fingerprints []string) (map[string]float64, error) {
+	scores := make(map[string]float64)
+	if len(fingerprints) == 0 {
+		return scores, nil
+	}
+
+	rows, err := db.Query("SELECT fingerprint, score FROM error_group_impact WHERE fingerprint = ANY($1)", pq.Array(fingerprints))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impact scores: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint string
+		var score float64
+		if err := rows.Scan(&fingerprint, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan impact score: %w", err)
+		}
+		scores[fingerprint] = score
+	}
+
+	return scores, nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const importJobColumns = `id, format, status, project_id, total_groups, total_events,
+	processed_events, imported_events, failed_events, error, created_by,
+	created_at, updated_at, completed_at`
+
+// Import job methods
+func (db *DB) CreateImportJob(job *models.ImportJob) error {
+	query := fmt.Sprintf(`
+		INSERT INTO import_jobs (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, importJobColumns)
+
+	_, err := db.Exec(query,
+		job.ID, job.Format, job.Status, job.ProjectID, job.TotalGroups, job.TotalEvents,
+		job.ProcessedEvents, job.ImportedEvents, job.FailedEvents, job.Error, job.CreatedBy,
+		job.CreatedAt, job.UpdatedAt, job.CompletedAt,
+	)
+	return err
+}
+
+// UpdateImportJob persists a job's status and progress
+func (db *DB) UpdateImportJob(job *models.ImportJob) error {
+	query := `
+		UPDATE import_jobs SET status = $2, processed_events = $3, imported_events = $4,
+			failed_events = $5, error = $6, updated_at = $7, completed_at = $8
+		WHERE id = $1
+	`
+	_, err := db.Exec(query,
+		job.ID, job.Status, job.ProcessedEvents, job.ImportedEvents,
+		job.FailedEvents, job.Error, job.UpdatedAt, job.CompletedAt,
+	)
+	return err
+}
+
+// TouchImportJob records that a pending or running job is still being worked on
+func (db *DB) TouchImportJob(id uuid.UUID) error {
+	_, err := db.Exec(`
+		UPDATE import_jobs SET updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'running')
+	`, id)
+	return err
+}
+
+// FailStaleImportJobs fails the pending and running jobs not updated since
+// cutoff, as the instance working on them has stopped
+func (db *DB) FailStaleImportJobs(cutoff time.Time, message string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE import_jobs SET status = 'failed', error = $2, updated_at = NOW(), completed_at = NOW()
+		WHERE status IN ('pending', 'running') AND updated_at < $1
+	`, cutoff, message)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale import jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (db *DB) GetImportJob(id uuid.UUID) (*models.ImportJob, error) {
+	query := fmt.Sprintf("SELECT %s FROM import_jobs WHERE id = $1", importJobColumns)
+
+	var job models.ImportJob
+	err := db.QueryRow(query, id).Scan(
+		&job.ID, &job.Format, &job.Status, &job.ProjectID, &job.TotalGroups, &job.TotalEvents,
+		&job.ProcessedEvents, &job.ImportedEvents, &job.FailedEvents, &job.Error, &job.CreatedBy,
+		&job.CreatedAt, &job.UpdatedAt, &job.CompletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import job not found")
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	return &job, nil
+}
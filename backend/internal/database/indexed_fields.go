@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+
+	"error-logs/internal/models"
+)
+
+// Indexed context field methods
+func (db *DB) GetIndexedContextFields() ([]models.IndexedContextField, error) {
+	query := "SELECT key, created_at FROM indexed_context_fields ORDER BY key ASC"
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexed context fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []models.IndexedContextField{}
+	for rows.Next() {
+		var field models.IndexedContextField
+		if err := rows.Scan(&field.Key, &field.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan indexed context field: %w", err)
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func (db *DB) CreateIndexedContextField(field *models.IndexedContextField) error {
+	query := "INSERT INTO indexed_context_fields (key, created_at) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING"
+	_, err := db.Exec(query, field.Key, field.CreatedAt)
+	return err
+}
+
+func (db *DB) DeleteIndexedContextField(key string) error {
+	query := "DELETE FROM indexed_context_fields WHERE key = $1"
+	_, err := db.Exec(query, key)
+	return err
+}
+
+// BackfillIndexedContextField copies an existing context key into
+// indexed_context for rows ingested before the key was designated
+func (db *DB) BackfillIndexedContextField(key string) (int64, error) {
+	query := `
+		UPDATE errors SET indexed_context = COALESCE(indexed_context, '{}'::jsonb) || jsonb_build_object($1::text, context->>$1)
+		WHERE context ? $1 AND jsonb_typeof(context->$1) <> 'null'
+	`
+
+	result, err := db.Exec(query, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill indexed context field: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RemoveIndexedContextField drops a key from every row's indexed_context
+func (db *DB) RemoveIndexedContextField(key string) error {
+	query := "UPDATE errors SET indexed_context = indexed_context - $1::text WHERE indexed_context ? $1"
+	_, err := db.Exec(query, key)
+	return err
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+func (db *DB) GetProjectIDs() ([]uuid.UUID, error) {
+	rows, err := db.Query("SELECT id FROM projects ORDER BY created_at ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ComputeWeeklyInsights builds a project's insights for the week starting at
+// weekStart, comparing counts against the week before. limit caps each list.
+func (db *DB) ComputeWeeklyInsights(projectID uuid.UUID, weekStart time.Time, limit int) (*models.WeeklyInsights, error) {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	previousStart := weekStart.AddDate(0, 0, -7)
+
+	insights := &models.WeeklyInsights{
+		ProjectID: projectID,
+		WeekStart: weekStart,
+		WeekEnd:   weekEnd,
+	}
+
+	totalsQuery := `
+		SELECT
+			COUNT(*) FILTER (WHERE timestamp >= $2),
+			COUNT(*) FILTER (WHERE timestamp < $2),
+			COUNT(DISTINCT fingerprint) FILTER (WHERE timestamp >= $2 AND regressed = true)
+		FROM errors
+		WHERE project_id = $1 AND deleted_at IS NULL AND duplicate_of IS NULL AND timestamp >= $3 AND timestamp < $4
+	`
+	err := db.QueryRow(totalsQuery, projectID, weekStart, previousStart, weekEnd).Scan(
+		&insights.TotalErrors, &insights.PreviousTotalErrors, &insights.RegressionCount,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekly totals: %w", err)
+	}
+
+	// A group is new when it was first seen in any environment during the week
+	newQuery := `
+		WITH first AS (
+			SELECT fingerprint, MIN(first_seen) AS first_seen
+			FROM error_first_seen
+			WHERE project_id = $1
+			GROUP BY fingerprint
+			HAVING MIN(first_seen) >= $2 AND MIN(first_seen) < $3
+		)
+		SELECT f.fingerprint, MAX(e.message), MAX(e.source), COUNT(e.id), f.first_seen,
+			COUNT(*) OVER ()
+		FROM first f
+		LEFT JOIN errors e ON e.fingerprint = f.fingerprint AND e.project_id = $1
+			AND e.deleted_at IS NULL AND e.duplicate_of IS NULL AND e.timestamp >= $2 AND e.timestamp < $3
+		GROUP BY f.fingerprint, f.first_seen
+		ORDER BY COUNT(e.id) DESC, f.first_seen ASC
+		LIMIT $4
+	`
+	rows, err := db.Query(newQuery, projectID, weekStart, weekEnd, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query new errors: %w", err)
+	}
+	insights.NewErrors = []models.InsightErrorGroup{}
+	for rows.Next() {
+		var group models.InsightErrorGroup
+		var message, source sql.NullString
+		if err := rows.Scan(&group.Fingerprint, &message, &source, &group.Count, &group.FirstSeen, &insights.NewErrorCount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan new error: %w", err)
+		}
+		group.Message = message.String
+		group.Source = source.String
+		insights.NewErrors = append(insights.NewErrors, group)
+	}
+	rows.Close()
+
+	if insights.MoversUp, err = db.getInsightMovers(projectID, previousStart, weekStart, weekEnd, "DESC", limit); err != nil {
+		return nil, err
+	}
+	if insights.MoversDown, err = db.getInsightMovers(projectID, previousStart, weekStart, weekEnd, "ASC", limit); err != nil {
+		return nil, err
+	}
+
+	slowestQuery := `
+		SELECT fingerprint, MAX(message), MAX(source), MIN(timestamp), MAX(resolved_at),
+			(ARRAY_AGG(resolved_by ORDER BY resolved_at DESC NULLS LAST))[1],
+			EXTRACT(EPOCH FROM MAX(resolved_at) - MIN(timestamp))
+		FROM errors
+		WHERE project_id = $1 AND deleted_at IS NULL AND fingerprint IS NOT NULL
+		  AND fingerprint IN (
+			SELECT fingerprint FROM errors
+			WHERE project_id = $1 AND resolved_at >= $2 AND resolved_at < $3 AND deleted_at IS NULL
+		  )
+		GROUP BY fingerprint
+		HAVING bool_and(resolved)
+		ORDER BY 7 DESC
+		LIMIT $4
+	`
+	rows, err = db.Query(slowestQuery, projectID, weekStart, weekEnd, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slowest resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	insights.SlowestResolving = []models.InsightResolution{}
+	for rows.Next() {
+		var resolution models.InsightResolution
+		err := rows.Scan(
+			&resolution.Fingerprint, &resolution.Message, &resolution.Source, &resolution.FirstSeen,
+			&resolution.ResolvedAt, &resolution.ResolvedBy, &resolution.ResolutionSeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan resolution: %w", err)
+		}
+		insights.SlowestResolving = append(insights.SlowestResolving, resolution)
+	}
+
+	return insights, nil
+}
+
+// getInsightMovers returns the groups whose count changed most between the
+// previous week and this one: order DESC for increases, ASC for decreases
+func (db *DB) getInsightMovers(projectID uuid.UUID, previousStart, weekStart, weekEnd time.Time, order string, limit int) ([]models.InsightMover, error) {
+	direction := "> 0"
+	if order == "ASC" {
+		direction = "< 0"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT fingerprint, MAX(message), MAX(source),
+			COUNT(*) FILTER (WHERE timestamp >= $3) AS current,
+			COUNT(*) FILTER (WHERE timestamp < $3) AS previous
+		FROM errors
+		WHERE project_id = $1 AND deleted_at IS NULL AND duplicate_of IS NULL AND fingerprint IS NOT NULL
+		  AND timestamp >= $2 AND timestamp < $4
+		GROUP BY fingerprint
+		HAVING COUNT(*) FILTER (WHERE timestamp >= $3) - COUNT(*) FILTER (WHERE timestamp < $3) %s
+		ORDER BY COUNT(*) FILTER (WHERE timestamp >= $3) - COUNT(*) FILTER (WHERE timestamp < $3) %s, fingerprint ASC
+		LIMIT $5
+	`, direction, order)
+
+	rows, err := db.Query(query, projectID, previousStart, weekStart, weekEnd, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movers: %w", err)
+	}
+	defer rows.Close()
+
+	movers := []models.InsightMover{}
+	for rows.Next() {
+		var mover models.InsightMover
+		if err := rows.Scan(&mover.Fingerprint, &mover.Message, &mover.Source, &mover.Count, &mover.PreviousCount); err != nil {
+			return nil, fmt.Errorf("failed to scan mover: %w", err)
+		}
+		mover.Change = mover.Count - mover.PreviousCount
+		movers = append(movers, mover)
+	}
+
+	return movers, nil
+}
+
+func (db *DB) SaveWeeklyInsights(insights *models.WeeklyInsights) error {
+	query := `
+		INSERT INTO weekly_insights (project_id, week_start, insights, generated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, week_start) DO UPDATE SET
+			insights = EXCLUDED.insights, generated_at = EXCLUDED.generated_at
+	`
+
+	insightsJSON, err := json.Marshal(insights)
+	if err != nil {
+		return fmt.Errorf("failed to marshal insights: %w", err)
+	}
+
+	_, err = db.Exec(query, insights.ProjectID, insights.WeekStart, insightsJSON, insights.GeneratedAt)
+	return err
+}
+
+// GetWeeklyInsights returns the stored insights for the week, or nil when
+// they have not been generated
+func (db *DB) GetWeeklyInsights(projectID uuid.UUID, weekStart time.Time) (*models.WeeklyInsights, error) {
+	var insightsJSON []byte
+	err := db.QueryRow(
+		"SELECT insights FROM weekly_insights WHERE project_id = $1 AND week_start = $2",
+		projectID, weekStart,
+	).Scan(&insightsJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get weekly insights: %w", err)
+	}
+
+	var insights models.WeeklyInsights
+	if err := json.Unmarshal(insightsJSON, &insights); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal weekly insights: %w", err)
+	}
+	return &insights, nil
+}
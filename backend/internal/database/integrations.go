@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const integrationColumns = "id, name, kind, config, secret, enabled, external_id, version, created_at, updated_at"
+
+const integrationExists = "SELECT EXISTS (SELECT 1 FROM integrations WHERE id = $1)"
+
+func (db *DB) scanIntegration(row rowScanner) (*models.Integration, error) {
+	var integration models.Integration
+	var configJSON []byte
+	err := row.Scan(
+		&integration.ID, &integration.Name, &integration.Kind, &configJSON, &integration.Secret,
+		&integration.Enabled, &integration.ExternalID, &integration.Version,
+		&integration.CreatedAt, &integration.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(configJSON, &integration.Config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal integration config: %w", err)
+	}
+	if integration.Secret, err = db.decryptOptional(integration.Secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt integration secret: %w", err)
+	}
+	integration.HasSecret = integration.Secret != nil
+	if integration.Config == nil {
+		integration.Config = map[string]interface{}{}
+	}
+	integration.Status = "configured"
+	if !integration.Enabled {
+		integration.Status = "disabled"
+	}
+	return &integration, nil
+}
+
+// Integration methods
+func (db *DB) GetIntegrations() ([]models.Integration, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM integrations ORDER BY name, id", integrationColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrations: %w", err)
+	}
+	defer rows.Close()
+
+	integrations := []models.Integration{}
+	for rows.Next() {
+		integration, err := db.scanIntegration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integration: %w", err)
+		}
+		integrations = append(integrations, *integration)
+	}
+
+	return integrations, nil
+}
+
+func (db *DB) GetIntegration(id uuid.UUID) (*models.Integration, error) {
+	integration, err := db.scanIntegration(db.QueryRow(fmt.Sprintf("SELECT %s FROM integrations WHERE id = $1", integrationColumns), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("integration not found")
+		}
+		return nil, fmt.Errorf("failed to get integration: %w", err)
+	}
+	return integration, nil
+}
+
+func (db *DB) CreateIntegration(integration *models.Integration) error {
+	configJSON, err := json.Marshal(integration.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal integration config: %w", err)
+	}
+	secret, err := db.encryptOptional(integration.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO integrations (id, name, kind, config, secret, enabled, external_id, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, integration.ID, integration.Name, integration.Kind, configJSON, secret,
+		integration.Enabled, integration.ExternalID, integration.Version, integration.CreatedAt, integration.UpdatedAt)
+	return err
+}
+
+// UpdateIntegration stores the integration and bumps its version; with
+// ifVersion set, only if it is still at that version
+func (db *DB) UpdateIntegration(integration *models.Integration, ifVersion int) error {
+	configJSON, err := json.Marshal(integration.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal integration config: %w", err)
+	}
+	secret, err := db.encryptOptional(integration.Secret)
+	if err != nil {
+		return err
+	}
+
+	err = db.QueryRow(`
+		UPDATE integrations SET
+			name = $2, config = $3, secret = $4, enabled = $5, external_id = $6,
+			updated_at = $7, version = version + 1
+		WHERE id = $1 AND ($8 = 0 OR version = $8)
+		RETURNING version
+	`, integration.ID, integration.Name, configJSON, secret, integration.Enabled,
+		integration.ExternalID, integration.UpdatedAt, ifVersion).Scan(&integration.Version)
+	if err == sql.ErrNoRows {
+		return db.missedVersioned(integrationExists, integration.ID, "integration not found")
+	}
+	return err
+}
+
+// DeleteIntegration deletes an integration; with ifVersion set, only if it is
+// still at that version
+func (db *DB) DeleteIntegration(id uuid.UUID, ifVersion int) error {
+	result, err := db.Exec("DELETE FROM integrations WHERE id = $1 AND ($2 = 0 OR version = $2)", id, ifVersion)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.missedVersioned(integrationExists, id, "integration not found")
+	}
+	return nil
+}
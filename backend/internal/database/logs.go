@@ -0,0 +1,152 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// InsertLogEvents stores a batch of log events in one transaction
+func (db *DB) InsertLogEvents(events []models.LogEvent) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO log_events (
+			timestamp, level, message, source, environment,
+			context, trace_id, project_id, sample_rate
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare log insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		contextJSON, err := json.Marshal(event.Context)
+		if err != nil {
+			return fmt.Errorf("failed to marshal log context: %w", err)
+		}
+
+		_, err = stmt.Exec(
+			event.Timestamp, event.Level, event.Message, event.Source, event.Environment,
+			contextJSON, event.TraceID, event.ProjectID, event.SampleRate,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert log event: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func buildLogFilter(filter models.LogFilter) (string, []interface{}, int) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if len(filter.Levels) > 0 {
+		placeholders := make([]string, len(filter.Levels))
+		for i, level := range filter.Levels {
+			placeholders[i] = fmt.Sprintf("$%d", argIndex)
+			args = append(args, level)
+			argIndex++
+		}
+		conditions = append(conditions, fmt.Sprintf("level IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if filter.Source != "" {
+		conditions = append(conditions, fmt.Sprintf("source = $%d", argIndex))
+		args = append(args, filter.Source)
+		argIndex++
+	}
+	if filter.Environment != "" {
+		conditions = append(conditions, fmt.Sprintf("environment = $%d", argIndex))
+		args = append(args, filter.Environment)
+		argIndex++
+	}
+	if filter.TraceID != "" {
+		conditions = append(conditions, fmt.Sprintf("trace_id = $%d", argIndex))
+		args = append(args, filter.TraceID)
+		argIndex++
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("message ILIKE $%d", argIndex))
+		args = append(args, "%"+filter.Search+"%")
+		argIndex++
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argIndex))
+		args = append(args, *filter.Since)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return whereClause, args, argIndex
+}
+
+func (db *DB) GetLogEvents(limit, offset int, filter models.LogFilter) ([]models.LogEvent, int, error) {
+	whereClause, args, argIndex := buildLogFilter(filter)
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM log_events %s", whereClause)
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count log events: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, level, message, COALESCE(source, ''), COALESCE(environment, ''),
+			   context, trace_id, project_id, sample_rate
+		FROM log_events %s
+		ORDER BY timestamp DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query log events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.LogEvent{}
+	for rows.Next() {
+		var event models.LogEvent
+		var contextJSON []byte
+
+		err := rows.Scan(
+			&event.ID, &event.Timestamp, &event.Level, &event.Message, &event.Source,
+			&event.Environment, &contextJSON, &event.TraceID, &event.ProjectID, &event.SampleRate,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan log event: %w", err)
+		}
+
+		if err := json.Unmarshal(contextJSON, &event.Context); err != nil {
+			event.Context = make(map[string]interface{})
+		}
+
+		events = append(events, event)
+	}
+
+	return events, total, nil
+}
+
+// DeleteLogEventsBefore prunes log events older than the cutoff
+func (db *DB) DeleteLogEventsBefore(cutoff time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM log_events WHERE timestamp < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete log events: %w", err)
+	}
+	return result.RowsAffected()
+}
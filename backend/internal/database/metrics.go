@@ -0,0 +1,117 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// metricResolutions maps each rollup resolution to its bucket size
+var metricResolutions = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+}
+
+// RecordMetricSamples folds samples into the minute and hour rollups in one transaction
+func (db *DB) RecordMetricSamples(samples []models.MetricSample) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO metric_rollups (
+			name, metric_type, tags, resolution, bucket,
+			sample_count, value_sum, value_min, value_max
+		) VALUES ($1, $2, $3, $4, $5, 1, $6, $6, $6)
+		ON CONFLICT (name, tags, resolution, bucket) DO UPDATE SET
+			sample_count = metric_rollups.sample_count + 1,
+			value_sum = metric_rollups.value_sum + EXCLUDED.value_sum,
+			value_min = LEAST(metric_rollups.value_min, EXCLUDED.value_min),
+			value_max = GREATEST(metric_rollups.value_max, EXCLUDED.value_max)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare metric rollup: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		tagsJSON, err := json.Marshal(sample.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metric tags: %w", err)
+		}
+		if sample.Tags == nil {
+			tagsJSON = []byte("{}")
+		}
+
+		for resolution, size := range metricResolutions {
+			bucket := sample.Timestamp.UTC().Truncate(size)
+			if _, err := stmt.Exec(sample.Name, sample.Type, tagsJSON, resolution, bucket, sample.Value); err != nil {
+				return fmt.Errorf("failed to record metric %s: %w", sample.Name, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetMetricSeries returns the rollup points for a metric since the given time,
+// merging every tag set that contains the requested tags
+func (db *DB) GetMetricSeries(name, resolution string, tags map[string]string, since time.Time) (*models.MetricSeriesResponse, error) {
+	if _, ok := metricResolutions[resolution]; !ok {
+		return nil, fmt.Errorf("unknown metric resolution: %s", resolution)
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metric tags: %w", err)
+	}
+	if tags == nil {
+		tagsJSON = []byte("{}")
+	}
+
+	query := `
+		SELECT bucket, MAX(metric_type), SUM(sample_count), SUM(value_sum),
+			   MIN(value_min), MAX(value_max)
+		FROM metric_rollups
+		WHERE name = $1 AND resolution = $2 AND bucket >= $3 AND tags @> $4
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+
+	rows, err := db.Query(query, name, resolution, since, tagsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metric series: %w", err)
+	}
+	defer rows.Close()
+
+	series := &models.MetricSeriesResponse{
+		Name:       name,
+		Resolution: resolution,
+		Tags:       tags,
+		Points:     []models.MetricPoint{},
+	}
+
+	for rows.Next() {
+		var point models.MetricPoint
+		var metricType string
+
+		err := rows.Scan(&point.Timestamp, &metricType, &point.Count, &point.Sum, &point.Min, &point.Max)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan metric point: %w", err)
+		}
+
+		series.Type = metricType
+		point.Value = point.Sum
+		if metricType == "gauge" && point.Count > 0 {
+			point.Value = point.Sum / float64(point.Count)
+		}
+
+		series.Points = append(series.Points, point)
+	}
+
+	return series, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const minidumpColumns = "id, project_id, error_id, fingerprint, release, os, arch, size, checksum, created_at"
+
+func scanMinidump(row rowScanner) (*models.Minidump, error) {
+	var dump models.Minidump
+	err := row.Scan(
+		&dump.ID, &dump.ProjectID, &dump.ErrorID, &dump.Fingerprint, &dump.Release,
+		&dump.OS, &dump.Arch, &dump.Size, &dump.Checksum, &dump.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &dump, nil
+}
+
+// Minidump methods
+func (db *DB) CreateMinidump(dump *models.Minidump) error {
+	query := fmt.Sprintf(`
+		INSERT INTO minidumps (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, minidumpColumns)
+
+	_, err := db.Exec(query,
+		dump.ID, dump.ProjectID, dump.ErrorID, dump.Fingerprint, dump.Release,
+		dump.OS, dump.Arch, dump.Size, dump.Checksum, dump.CreatedAt,
+	)
+
+	return err
+}
+
+// GetMinidumps lists minidumps newest first, optionally for one error group
+func (db *DB) GetMinidumps(fingerprint string, limit int) ([]models.Minidump, error) {
+	query := fmt.Sprintf("SELECT %s FROM minidumps", minidumpColumns)
+	args := []interface{}{}
+
+	if fingerprint != "" {
+		query += " WHERE fingerprint = $1"
+		args = append(args, fingerprint)
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT %d", limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minidumps: %w", err)
+	}
+	defer rows.Close()
+
+	dumps := []models.Minidump{}
+	for rows.Next() {
+		dump, err := scanMinidump(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan minidump: %w", err)
+		}
+		dumps = append(dumps, *dump)
+	}
+
+	return dumps, nil
+}
+
+func (db *DB) GetMinidumpByID(id uuid.UUID) (*models.Minidump, error) {
+	query := fmt.Sprintf("SELECT %s FROM minidumps WHERE id = $1", minidumpColumns)
+
+	dump, err := scanMinidump(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("minidump not found")
+		}
+		return nil, fmt.Errorf("failed to get minidump: %w", err)
+	}
+
+	return dump, nil
+}
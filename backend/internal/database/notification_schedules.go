@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+const notificationScheduleColumns = "id, target, timezone, quiet_start, quiet_end, quiet_days, fallback, critical_override, created_at, updated_at"
+
+func (db *DB) scanNotificationSchedule(row rowScanner) (*models.NotificationSchedule, error) {
+	var schedule models.NotificationSchedule
+	err := row.Scan(
+		&schedule.ID, &schedule.Target, &schedule.Timezone, &schedule.QuietStart, &schedule.QuietEnd,
+		pq.Array(&schedule.QuietDays), &schedule.Fallback, &schedule.CriticalOverride,
+		&schedule.CreatedAt, &schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.QuietDays == nil {
+		schedule.QuietDays = []string{}
+	}
+	if err := db.decryptScheduleTargets(&schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// decryptScheduleTargets opens webhook URLs in the target and fallback
+func (db *DB) decryptScheduleTargets(schedule *models.NotificationSchedule) error {
+	targets := []string{schedule.Target}
+	if schedule.Fallback != nil {
+		targets = append(targets, *schedule.Fallback)
+	}
+	targets, err := db.decryptNotifications(targets)
+	if err != nil {
+		return err
+	}
+	schedule.Target = targets[0]
+	if schedule.Fallback != nil {
+		schedule.Fallback = &targets[1]
+	}
+	return nil
+}
+
+// encryptScheduleTargets returns the target and fallback as stored
+func (db *DB) encryptScheduleTargets(schedule *models.NotificationSchedule) (string, *string, error) {
+	targets := []string{schedule.Target}
+	if schedule.Fallback != nil {
+		targets = append(targets, *schedule.Fallback)
+	}
+	targets, err := db.encryptNotifications(targets)
+	if err != nil {
+		return "", nil, err
+	}
+	var fallback *string
+	if schedule.Fallback != nil {
+		fallback = &targets[1]
+	}
+	return targets[0], fallback, nil
+}
+
+// Notification schedule methods
+func (db *DB) GetNotificationSchedules() ([]models.NotificationSchedule, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM notification_schedules ORDER BY created_at, id", notificationScheduleColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.NotificationSchedule{}
+	for rows.Next() {
+		schedule, err := db.scanNotificationSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, nil
+}
+
+func (db *DB) GetNotificationSchedule(id uuid.UUID) (*models.NotificationSchedule, error) {
+	query := fmt.Sprintf("SELECT %s FROM notification_schedules WHERE id = $1", notificationScheduleColumns)
+	schedule, err := db.scanNotificationSchedule(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification schedule not found")
+		}
+		return nil, fmt.Errorf("failed to get notification schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+func (db *DB) CreateNotificationSchedule(schedule *models.NotificationSchedule) error {
+	target, fallback, err := db.encryptScheduleTargets(schedule)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO notification_schedules (id, target, timezone, quiet_start, quiet_end, quiet_days, fallback, critical_override, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, schedule.ID, target, schedule.Timezone, schedule.QuietStart, schedule.QuietEnd,
+		pq.Array(nilIfEmpty(schedule.QuietDays)), fallback, schedule.CriticalOverride, schedule.CreatedAt, schedule.UpdatedAt)
+	return err
+}
+
+func (db *DB) UpdateNotificationSchedule(schedule *models.NotificationSchedule) error {
+	target, fallback, err := db.encryptScheduleTargets(schedule)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE notification_schedules SET
+			target = $2, timezone = $3, quiet_start = $4, quiet_end = $5, quiet_days = $6,
+			fallback = $7, critical_override = $8, updated_at = $9
+		WHERE id = $1
+	`, schedule.ID, target, schedule.Timezone, schedule.QuietStart, schedule.QuietEnd,
+		pq.Array(nilIfEmpty(schedule.QuietDays)), fallback, schedule.CriticalOverride, schedule.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteNotificationSchedule(id uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM notification_schedules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("notification schedule not found")
+	}
+	return nil
+}
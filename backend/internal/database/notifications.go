@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+const notificationColumns = "id, member_id, type, subject, body, rule_id, error_id, fingerprint, read_at, created_at"
+
+// CreateNotifications adds entries to members' inboxes in one transaction
+func (db *DB) CreateNotifications(notifications []models.Notification) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO notifications (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, notificationColumns))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, n := range notifications {
+		_, err := stmt.Exec(
+			n.ID, n.MemberID, n.Type, n.Subject, n.Body,
+			n.RuleID, n.ErrorID, n.Fingerprint, n.ReadAt, n.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create notification: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetNotifications returns a page of a member's notifications matching
+// filter, newest first, and how many match in total
+func (db *DB) GetNotifications(memberID uuid.UUID, limit, offset int, filter models.NotificationFilter) ([]models.Notification, int, error) {
+	whereClause, args := notificationConditions(memberID, filter)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notifications "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM notifications %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, notificationColumns, whereClause, len(args)+1, len(args)+2)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(
+			&n.ID, &n.MemberID, &n.Type, &n.Subject, &n.Body,
+			&n.RuleID, &n.ErrorID, &n.Fingerprint, &n.ReadAt, &n.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+func notificationConditions(memberID uuid.UUID, filter models.NotificationFilter) (string, []interface{}) {
+	whereClause := "WHERE member_id = $1"
+	args := []interface{}{memberID}
+	if filter.Unread {
+		whereClause += " AND read_at IS NULL"
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		whereClause += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	return whereClause, args
+}
+
+// CountUnreadNotifications returns how many of a member's notifications are
+// unread
+func (db *DB) CountUnreadNotifications(memberID uuid.UUID) (int, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM notifications WHERE member_id = $1 AND read_at IS NULL", memberID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationsRead marks the given notifications of a member as read,
+// or all of them matching filter when ids is empty. It returns how many were
+// unread.
+func (db *DB) MarkNotificationsRead(memberID uuid.UUID, ids []uuid.UUID, filter models.NotificationFilter, readAt time.Time) (int64, error) {
+	filter.Unread = true
+	whereClause, args := notificationConditions(memberID, filter)
+	if len(ids) > 0 {
+		args = append(args, pq.Array(ids))
+		whereClause += fmt.Sprintf(" AND id = ANY($%d)", len(args))
+	}
+	args = append(args, readAt)
+
+	result, err := db.Exec(fmt.Sprintf("UPDATE notifications SET read_at = $%d %s", len(args), whereClause), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetNotification returns one of a member's notifications
+func (db *DB) GetNotification(memberID, id uuid.UUID) (*models.Notification, error) {
+	var n models.Notification
+	err := db.QueryRow(
+		fmt.Sprintf("SELECT %s FROM notifications WHERE id = $1 AND member_id = $2", notificationColumns),
+		id, memberID,
+	).Scan(
+		&n.ID, &n.MemberID, &n.Type, &n.Subject, &n.Body,
+		&n.RuleID, &n.ErrorID, &n.Fingerprint, &n.ReadAt, &n.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// DeleteNotificationsBefore removes notifications created before cutoff
+func (db *DB) DeleteNotificationsBefore(cutoff time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM notifications WHERE created_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,265 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const organizationColumns = "id, name, slug, settings, created_at"
+
+// Organization methods
+func (db *DB) GetOrganizations() ([]models.Organization, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM organizations ORDER BY name ASC", organizationColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, *org)
+	}
+
+	return orgs, nil
+}
+
+func (db *DB) GetOrganization(id uuid.UUID) (*models.Organization, error) {
+	return db.getOrganization("id", id)
+}
+
+func (db *DB) GetOrganizationBySlug(slug string) (*models.Organization, error) {
+	return db.getOrganization("slug", slug)
+}
+
+func (db *DB) getOrganization(column string, value interface{}) (*models.Organization, error) {
+	row := db.QueryRow(fmt.Sprintf("SELECT %s FROM organizations WHERE %s = $1", organizationColumns, column), value)
+
+	org, err := scanOrganization(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
+
+func (db *DB) CreateOrganization(org *models.Organization) error {
+	settingsJSON, err := json.Marshal(org.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	_, err = db.Exec(
+		fmt.Sprintf("INSERT INTO organizations (%s) VALUES ($1, $2, $3, $4, $5)", organizationColumns),
+		org.ID, org.Name, org.Slug, settingsJSON, org.CreatedAt,
+	)
+	return err
+}
+
+func (db *DB) UpdateOrganization(org *models.Organization) error {
+	settingsJSON, err := json.Marshal(org.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	result, err := db.Exec("UPDATE organizations SET name = $2, settings = $3 WHERE id = $1", org.ID, org.Name, settingsJSON)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("organization not found")
+	}
+	return nil
+}
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	var org models.Organization
+	var settingsJSON []byte
+
+	if err := row.Scan(&org.ID, &org.Name, &org.Slug, &settingsJSON, &org.CreatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(settingsJSON, &org.Settings)
+
+	return &org, nil
+}
+
+// Project methods
+
+// projectColumns lists the projects columns in the order scanProject expects
+const projectColumns = "id, org_id, name, slug, external_id, version, created_at"
+
+func scanProject(row rowScanner) (*models.Project, error) {
+	var project models.Project
+	err := row.Scan(&project.ID, &project.OrgID, &project.Name, &project.Slug,
+		&project.ExternalID, &project.Version, &project.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetProjects lists projects, limited to one organization when orgID is set
+func (db *DB) GetProjects(orgID *uuid.UUID) ([]models.Project, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM projects WHERE $1::uuid IS NULL OR org_id = $1
+		ORDER BY name ASC
+	`, projectColumns), orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, *project)
+	}
+
+	return projects, nil
+}
+
+func (db *DB) GetProject(id uuid.UUID) (*models.Project, error) {
+	project, err := scanProject(db.QueryRow(fmt.Sprintf("SELECT %s FROM projects WHERE id = $1", projectColumns), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("project not found")
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return project, nil
+}
+
+// ProjectSlugExists reports whether any project already uses slug
+func (db *DB) ProjectSlugExists(slug string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM projects WHERE slug = $1)", slug).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check project slug: %w", err)
+	}
+	return exists, nil
+}
+
+func (db *DB) CreateProject(project *models.Project) error {
+	_, err := db.Exec(
+		"INSERT INTO projects (id, org_id, name, slug, external_id, version, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		project.ID, project.OrgID, project.Name, project.Slug, project.ExternalID, project.Version, project.CreatedAt,
+	)
+	return err
+}
+
+// projectExists takes the project's ID as $1
+const projectExists = "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1)"
+
+// UpdateProject saves a project's name, slug and external ID and bumps its
+// version. With ifVersion set, the project is only saved if it is still at
+// that version.
+func (db *DB) UpdateProject(project *models.Project, ifVersion int) error {
+	err := db.QueryRow(`
+		UPDATE projects SET name = $2, slug = $3, external_id = $4, version = version + 1
+		WHERE id = $1 AND ($5 = 0 OR version = $5)
+		RETURNING version
+	`, project.ID, project.Name, project.Slug, project.ExternalID, ifVersion).Scan(&project.Version)
+	if err == sql.ErrNoRows {
+		return db.missedVersioned(projectExists, project.ID, "project not found")
+	}
+	return err
+}
+
+// ProjectHasAPIKeys reports whether any active API key belongs to the project
+func (db *DB) ProjectHasAPIKeys(id uuid.UUID) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM api_keys WHERE project_id = $1 AND active = true)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check project API keys: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteProject deletes a project and its settings; with ifVersion set, only
+// if it is still at that version. Projects that have received errors are
+// still referenced and can't be deleted.
+func (db *DB) DeleteProject(id uuid.UUID, ifVersion int) error {
+	result, err := db.Exec("DELETE FROM projects WHERE id = $1 AND ($2 = 0 OR version = $2)", id, ifVersion)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.missedVersioned(projectExists, id, "project not found")
+	}
+	return nil
+}
+
+// Organization quota methods
+func (db *DB) GetOrgQuota(orgID uuid.UUID) (*models.OrgQuota, error) {
+	query := `
+		SELECT org_id, daily_soft_limit, daily_hard_limit,
+			   monthly_soft_limit, monthly_hard_limit, updated_at
+		FROM organization_quotas WHERE org_id = $1
+	`
+
+	var quota models.OrgQuota
+	err := db.QueryRow(query, orgID).Scan(
+		&quota.OrgID, &quota.DailySoftLimit, &quota.DailyHardLimit,
+		&quota.MonthlySoftLimit, &quota.MonthlyHardLimit, &quota.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// No quota configured means unlimited
+			return &models.OrgQuota{OrgID: orgID}, nil
+		}
+		return nil, fmt.Errorf("failed to get organization quota: %w", err)
+	}
+
+	return &quota, nil
+}
+
+// GetProjectOrgQuota returns the quota of the organization a project belongs
+// to, or nil when the project has no organization
+func (db *DB) GetProjectOrgQuota(projectID uuid.UUID) (*models.OrgQuota, error) {
+	var orgID *uuid.UUID
+	err := db.QueryRow("SELECT org_id FROM projects WHERE id = $1", projectID).Scan(&orgID)
+	if err == sql.ErrNoRows || (err == nil && orgID == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project organization: %w", err)
+	}
+	return db.GetOrgQuota(*orgID)
+}
+
+func (db *DB) UpsertOrgQuota(quota *models.OrgQuota) error {
+	query := `
+		INSERT INTO organization_quotas (
+			org_id, daily_soft_limit, daily_hard_limit,
+			monthly_soft_limit, monthly_hard_limit, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (org_id) DO UPDATE SET
+			daily_soft_limit = EXCLUDED.daily_soft_limit,
+			daily_hard_limit = EXCLUDED.daily_hard_limit,
+			monthly_soft_limit = EXCLUDED.monthly_soft_limit,
+			monthly_hard_limit = EXCLUDED.monthly_hard_limit,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := db.Exec(query,
+		quota.OrgID, quota.DailySoftLimit, quota.DailyHardLimit,
+		quota.MonthlySoftLimit, quota.MonthlyHardLimit, quota.UpdatedAt,
+	)
+
+	return err
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const ownershipRuleColumns = "id, project_id, match_type, pattern, owner_type, owner_id, priority, created_at"
+
+// Ownership rule methods
+func (db *DB) GetOwnershipRules(projectID *uuid.UUID) ([]models.OwnershipRule, error) {
+	query := fmt.Sprintf("SELECT %s FROM ownership_rules", ownershipRuleColumns)
+	args := []interface{}{}
+
+	// Project-scoped lookups include global rules
+	if projectID != nil {
+		query += " WHERE project_id = $1 OR project_id IS NULL"
+		args = append(args, *projectID)
+	}
+	query += " ORDER BY priority DESC, created_at ASC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ownership rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.OwnershipRule{}
+	for rows.Next() {
+		var rule models.OwnershipRule
+
+		err := rows.Scan(
+			&rule.ID, &rule.ProjectID, &rule.MatchType, &rule.Pattern,
+			&rule.OwnerType, &rule.OwnerID, &rule.Priority, &rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ownership rule: %w", err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (db *DB) GetOwnershipRuleByID(id uuid.UUID) (*models.OwnershipRule, error) {
+	query := fmt.Sprintf("SELECT %s FROM ownership_rules WHERE id = $1", ownershipRuleColumns)
+
+	var rule models.OwnershipRule
+	err := db.QueryRow(query, id).Scan(
+		&rule.ID, &rule.ProjectID, &rule.MatchType, &rule.Pattern,
+		&rule.OwnerType, &rule.OwnerID, &rule.Priority, &rule.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("ownership rule not found")
+		}
+		return nil, fmt.Errorf("failed to get ownership rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+func (db *DB) CreateOwnershipRule(rule *models.OwnershipRule) error {
+	query := fmt.Sprintf(`
+		INSERT INTO ownership_rules (%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, ownershipRuleColumns)
+
+	_, err := db.Exec(query,
+		rule.ID, rule.ProjectID, rule.MatchType, rule.Pattern,
+		rule.OwnerType, rule.OwnerID, rule.Priority, rule.CreatedAt,
+	)
+
+	return err
+}
+
+func (db *DB) UpdateOwnershipRule(rule *models.OwnershipRule) error {
+	query := `
+		UPDATE ownership_rules SET
+			project_id = $2, match_type = $3, pattern = $4, owner_type = $5,
+			owner_id = $6, priority = $7
+		WHERE id = $1
+	`
+
+	_, err := db.Exec(query,
+		rule.ID, rule.ProjectID, rule.MatchType, rule.Pattern,
+		rule.OwnerType, rule.OwnerID, rule.Priority,
+	)
+
+	return err
+}
+
+func (db *DB) DeleteOwnershipRule(id uuid.UUID) error {
+	query := "DELETE FROM ownership_rules WHERE id = $1"
+	_, err := db.Exec(query, id)
+	return err
+}
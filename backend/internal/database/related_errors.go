@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// AttachRelatedErrors records the error groups with the most events in the
+// scope between since and until as possibly related to an incident, and
+// returns how many were attached
+func (db *DB) AttachRelatedErrors(incidentID uuid.UUID, scope models.AlertScope, since, until time.Time, limit int) (int64, error) {
+	scopeClause, scopeArgs := buildAlertScope(scope, 5)
+	args := append([]interface{}{incidentID, since, until, limit}, scopeArgs...)
+
+	query := fmt.Sprintf(`
+		INSERT INTO incident_related_errors (
+			incident_id, fingerprint, message, level, source, event_count, first_seen, last_seen
+		)
+		SELECT $1, fingerprint, MAX(message), MAX(level), MAX(source), COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM errors
+		WHERE fingerprint IS NOT NULL AND deleted_at IS NULL
+		  AND timestamp >= $2 AND timestamp <= $3%s
+		GROUP BY fingerprint
+		ORDER BY COUNT(*) DESC
+		LIMIT $4
+		ON CONFLICT (incident_id, fingerprint) DO NOTHING
+	`, scopeClause)
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach related errors: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetIncidentRelatedErrors returns the error groups attached to an incident,
+// most events first
+func (db *DB) GetIncidentRelatedErrors(incidentID uuid.UUID) ([]models.IncidentRelatedError, error) {
+	rows, err := db.Query(`
+		SELECT fingerprint, message, level, source, event_count, first_seen, last_seen
+		FROM incident_related_errors
+		WHERE incident_id = $1
+		ORDER BY event_count DESC, fingerprint
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related errors: %w", err)
+	}
+	defer rows.Close()
+
+	related := []models.IncidentRelatedError{}
+	for rows.Next() {
+		var group models.IncidentRelatedError
+		err := rows.Scan(
+			&group.Fingerprint, &group.Message, &group.Level, &group.Source,
+			&group.EventCount, &group.FirstSeen, &group.LastSeen,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan related error: %w", err)
+		}
+		related = append(related, group)
+	}
+	return related, rows.Err()
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// ComputeReport summarises the errors and incidents between start and end,
+// comparing the total with the period from previousStart. Daily counts are
+// bucketed in start's location.
+func (db *DB) ComputeReport(previousStart, start, end time.Time, limit int) (*models.Report, error) {
+	loc := start.Location()
+	report := &models.Report{
+		Start:    start,
+		End:      end,
+		Timezone: loc.String(),
+	}
+
+	totalsQuery := `
+		SELECT
+			COUNT(*) FILTER (WHERE timestamp >= $2),
+			COUNT(*) FILTER (WHERE timestamp < $2),
+			COUNT(*) FILTER (WHERE timestamp >= $2 AND resolved = true),
+			COUNT(*) FILTER (WHERE timestamp >= $2 AND level = 'error')
+		FROM errors
+		WHERE deleted_at IS NULL AND duplicate_of IS NULL AND timestamp >= $1 AND timestamp < $3
+	`
+	err := db.QueryRow(totalsQuery, previousStart, start, end).Scan(
+		&report.TotalErrors, &report.PreviousTotalErrors, &report.ResolvedErrors, &report.CriticalErrors,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report totals: %w", err)
+	}
+
+	// A group is new when it was first seen in any environment during the period
+	newQuery := `
+		SELECT COUNT(*) FROM (
+			SELECT fingerprint FROM error_first_seen
+			GROUP BY fingerprint
+			HAVING MIN(first_seen) >= $1 AND MIN(first_seen) < $2
+		) AS first
+	`
+	if err := db.QueryRow(newQuery, start, end).Scan(&report.NewErrorGroups); err != nil {
+		return nil, fmt.Errorf("failed to count new error groups: %w", err)
+	}
+
+	if report.Daily, err = db.getReportDaily(start, end); err != nil {
+		return nil, err
+	}
+
+	topQuery := `
+		SELECT fingerprint, MAX(message), MAX(source), COUNT(*), MIN(timestamp)
+		FROM errors
+		WHERE deleted_at IS NULL AND duplicate_of IS NULL AND fingerprint IS NOT NULL
+		  AND timestamp >= $1 AND timestamp < $2
+		GROUP BY fingerprint
+		ORDER BY COUNT(*) DESC, fingerprint ASC
+		LIMIT $3
+	`
+	rows, err := db.Query(topQuery, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top errors: %w", err)
+	}
+	report.TopErrors = []models.InsightErrorGroup{}
+	for rows.Next() {
+		var group models.InsightErrorGroup
+		if err := rows.Scan(&group.Fingerprint, &group.Message, &group.Source, &group.Count, &group.FirstSeen); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan top error: %w", err)
+		}
+		group.FirstSeen = group.FirstSeen.In(loc)
+		report.TopErrors = append(report.TopErrors, group)
+	}
+	rows.Close()
+
+	incidentsQuery := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status IN ('resolved', 'closed'))
+		FROM incidents
+		WHERE created_at >= $1 AND created_at < $2
+	`
+	if err := db.QueryRow(incidentsQuery, start, end).Scan(&report.IncidentsOpened, &report.IncidentsResolved); err != nil {
+		return nil, fmt.Errorf("failed to count report incidents: %w", err)
+	}
+
+	return report, nil
+}
+
+// getReportDaily counts errors per local day, listing quiet days as zero
+func (db *DB) getReportDaily(start, end time.Time) ([]models.TrendDataPoint, error) {
+	loc := start.Location()
+
+	query := `
+		SELECT (timestamp AT TIME ZONE $3)::date AS day,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE resolved = true),
+			COUNT(*) FILTER (WHERE level = 'error')
+		FROM errors
+		WHERE deleted_at IS NULL AND duplicate_of IS NULL AND timestamp >= $1 AND timestamp < $2
+		GROUP BY day
+	`
+	rows, err := db.Query(query, start, end, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report days: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]models.TrendDataPoint)
+	for rows.Next() {
+		var day time.Time
+		var point models.TrendDataPoint
+		if err := rows.Scan(&day, &point.ErrorCount, &point.ResolvedCount, &point.CriticalCount); err != nil {
+			return nil, fmt.Errorf("failed to scan report day: %w", err)
+		}
+		counts[day.Format("2006-01-02")] = point
+	}
+
+	daily := []models.TrendDataPoint{}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		point := counts[day.Format("2006-01-02")]
+		point.Timestamp = day
+		daily = append(daily, point)
+	}
+
+	return daily, nil
+}
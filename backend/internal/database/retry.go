@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+
+	"error-logs/internal/retry"
+)
+
+// isSerializationFailure reports whether Postgres aborted the statement
+// because of a concurrent transaction. The statement was rolled back, so it
+// is always safe to run again.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "40" // serialization_failure, deadlock_detected
+}
+
+// isConnectionFailure reports whether the connection to Postgres broke or
+// could not be made
+func isConnectionFailure(err error) bool {
+	// A cancelled or expired context also reads as a net.Error timeout
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P03": // admin_shutdown, cannot_connect_now
+			return true
+		}
+		return pqErr.Code.Class() == "08" // connection_exception
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsUnavailable reports whether err means the database could not be reached,
+// as opposed to rejecting the statement
+func IsUnavailable(err error) bool {
+	return isConnectionFailure(err)
+}
+
+// IsStatementTimeout reports whether Postgres cancelled the statement because
+// it ran past statement_timeout
+func IsStatementTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014" // query_canceled
+}
+
+// IsUniqueViolation reports whether a write was refused by a unique constraint
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" // unique_violation
+}
+
+// IsForeignKeyViolation reports whether a write was refused because other
+// rows still reference the row
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" // foreign_key_violation
+}
+
+// isTransientRead is used for queries, which have no effect to repeat
+func isTransientRead(err error) bool {
+	return isSerializationFailure(err) || isConnectionFailure(err)
+}
+
+// writeStatement matches statements that change data, such as
+// UPDATE ... RETURNING or a data-modifying WITH, run through Query or
+// QueryRow. SELECT ... FOR UPDATE matches too, which only costs it a retry.
+var writeStatement = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|TRUNCATE)\b`)
+
+// transientFor picks the failures a statement may be retried on. Writes are
+// treated like Exec: after a broken connection they may have been committed.
+func transientFor(query string) func(error) bool {
+	if writeStatement.MatchString(query) {
+		return isSerializationFailure
+	}
+	return isTransientRead
+}
+
+// RetryCount is the number of queries retried since the process started
+func (db *DB) RetryCount() int64 {
+	return db.retries.Retries()
+}
+
+// RetryExhaustedCount is the number of queries that failed after every retry
+func (db *DB) RetryExhaustedCount() int64 {
+	return db.retries.Exhausted()
+}
+
+// WithContext returns a handle whose statements and transactions run under
+// ctx: they are cancelled, and not retried, once ctx is done. Request paths
+// use it so a query stops when the request's time budget is spent.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	bound := *db
+	bound.ctx = ctx
+	return &bound
+}
+
+// context is the context statements run under; background work has none
+func (db *DB) context() context.Context {
+	if db.ctx == nil {
+		return context.Background()
+	}
+	return db.ctx
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.slow.observe(query, args, time.Now())
+	ctx := db.context()
+	var rows *sql.Rows
+	err := retry.Do(ctx, retry.DefaultPolicy, db.retries, transientFor(query), func() error {
+		var err error
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer db.slow.observe(query, args, time.Now())
+	ctx := db.context()
+	var row *sql.Row
+	retry.Do(ctx, retry.DefaultPolicy, db.retries, transientFor(query), func() error {
+		row = db.DB.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// Exec only retries serialization failures: after a broken connection the
+// statement may already have been committed.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer db.slow.observe(query, args, time.Now())
+	ctx := db.context()
+	var result sql.Result
+	err := retry.Do(ctx, retry.DefaultPolicy, db.retries, isSerializationFailure, func() error {
+		var err error
+		result, err = db.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Begin starts a transaction under the handle's context, which rolls it
+// back if the context ends first
+func (db *DB) Begin() (*sql.Tx, error) {
+	return db.DB.BeginTx(db.context(), nil)
+}
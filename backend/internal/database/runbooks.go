@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+// newRunbook builds a runbook from its columns, or nil when both are empty
+func newRunbook(url *string, notes string) *models.Runbook {
+	if (url == nil || *url == "") && notes == "" {
+		return nil
+	}
+	return &models.Runbook{URL: url, Notes: notes}
+}
+
+func splitRunbook(runbook *models.Runbook) (*string, string) {
+	if runbook == nil {
+		return nil, ""
+	}
+	return runbook.URL, runbook.Notes
+}
+
+// Error group runbook methods
+func (db *DB) GetErrorGroupRunbook(fingerprint string) (*models.Runbook, error) {
+	query := "SELECT runbook_url, runbook_notes FROM error_group_runbooks WHERE fingerprint = $1"
+
+	var url *string
+	var notes string
+	if err := db.QueryRow(query, fingerprint).Scan(&url, &notes); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get error group runbook: %w", err)
+	}
+
+	return newRunbook(url, notes), nil
+}
+
+// GetErrorGroupRunbooks returns the runbooks of the given groups keyed by fingerprint
+func (db *DB) GetErrorGroupRunbooks(fingerprints []string) (map[string]*models.Runbook, error) {
+	runbooks := make(map[string]*models.Runbook)
+	if len(fingerprints) == 0 {
+		return runbooks, nil
+	}
+
+	query := "SELECT fingerprint, runbook_url, runbook_notes FROM error_group_runbooks WHERE fingerprint = ANY($1)"
+
+	rows, err := db.Query(query, pq.Array(fingerprints))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error group runbooks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint string
+		var url *string
+		var notes string
+		if err := rows.Scan(&fingerprint, &url, &notes); err != nil {
+			return nil, fmt.Errorf("failed to scan error group runbook: %w", err)
+		}
+		runbooks[fingerprint] = newRunbook(url, notes)
+	}
+
+	return runbooks, nil
+}
+
+func (db *DB) SetErrorGroupRunbook(fingerprint string, runbook *models.Runbook) error {
+	query := `
+		INSERT INTO error_group_runbooks (fingerprint, runbook_url, runbook_notes, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (fingerprint) DO UPDATE SET
+			runbook_url = EXCLUDED.runbook_url,
+			runbook_notes = EXCLUDED.runbook_notes,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	url, notes := splitRunbook(runbook)
+	_, err := db.Exec(query, fingerprint, url, notes, time.Now().UTC())
+	return err
+}
+
+func (db *DB) DeleteErrorGroupRunbook(fingerprint string) error {
+	_, err := db.Exec("DELETE FROM error_group_runbooks WHERE fingerprint = $1", fingerprint)
+	return err
+}
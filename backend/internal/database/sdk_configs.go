@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const sdkConfigColumns = `project_id, sample_rate, level_sample_rates, allowed_levels,
+	scrub_rules, flush_interval_seconds, updated_at`
+
+// SDK config methods
+func (db *DB) GetSDKConfigs() ([]models.SDKConfig, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM sdk_configs", sdkConfigColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SDK configs: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.SDKConfig{}
+	for rows.Next() {
+		config, err := scanSDKConfig(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan SDK config: %w", err)
+		}
+		configs = append(configs, *config)
+	}
+
+	return configs, nil
+}
+
+func (db *DB) GetSDKConfig(projectID uuid.UUID) (*models.SDKConfig, error) {
+	row := db.QueryRow(fmt.Sprintf("SELECT %s FROM sdk_configs WHERE project_id = $1", sdkConfigColumns), projectID)
+
+	config, err := scanSDKConfig(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("SDK config not found")
+		}
+		return nil, err
+	}
+	return config, nil
+}
+
+func (db *DB) UpsertSDKConfig(config *models.SDKConfig) error {
+	levelRatesJSON, _ := json.Marshal(config.LevelSampleRates)
+	levelsJSON, _ := json.Marshal(config.AllowedLevels)
+	rulesJSON, _ := json.Marshal(config.ScrubRules)
+
+	query := fmt.Sprintf(`
+		INSERT INTO sdk_configs (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (project_id) DO UPDATE SET
+			sample_rate = EXCLUDED.sample_rate,
+			level_sample_rates = EXCLUDED.level_sample_rates,
+			allowed_levels = EXCLUDED.allowed_levels,
+			scrub_rules = EXCLUDED.scrub_rules,
+			flush_interval_seconds = EXCLUDED.flush_interval_seconds,
+			updated_at = EXCLUDED.updated_at
+	`, sdkConfigColumns)
+	_, err := db.Exec(query, config.ProjectID, config.SampleRate, levelRatesJSON, levelsJSON,
+		rulesJSON, config.FlushIntervalSeconds, config.UpdatedAt)
+	return err
+}
+
+func (db *DB) DeleteSDKConfig(projectID uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM sdk_configs WHERE project_id = $1", projectID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("SDK config not found")
+	}
+	return nil
+}
+
+func scanSDKConfig(row rowScanner) (*models.SDKConfig, error) {
+	var config models.SDKConfig
+	var levelRatesJSON, levelsJSON, rulesJSON []byte
+
+	err := row.Scan(&config.ProjectID, &config.SampleRate, &levelRatesJSON, &levelsJSON,
+		&rulesJSON, &config.FlushIntervalSeconds, &config.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(levelRatesJSON, &config.LevelSampleRates)
+	json.Unmarshal(levelsJSON, &config.AllowedLevels)
+	json.Unmarshal(rulesJSON, &config.ScrubRules)
+
+	return &config, nil
+}
@@ -0,0 +1,409 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const serviceColumns = "id, name, COALESCE(description, ''), owner_team, repository_url, runbook_url, created_at, updated_at"
+
+func scanService(row rowScanner) (*models.Service, error) {
+	var service models.Service
+	err := row.Scan(
+		&service.ID, &service.Name, &service.Description, &service.OwnerTeam,
+		&service.RepositoryURL, &service.RunbookURL, &service.CreatedAt, &service.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+// Service catalog methods
+func (db *DB) GetServices() ([]models.Service, error) {
+	query := fmt.Sprintf("SELECT %s FROM services ORDER BY name ASC", serviceColumns)
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query services: %w", err)
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		service, err := scanService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service: %w", err)
+		}
+		services = append(services, *service)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dependencies, err := db.getServiceDependencies("")
+	if err != nil {
+		return nil, err
+	}
+	for i := range services {
+		services[i].Dependencies = dependencies[services[i].ID]
+		if services[i].Dependencies == nil {
+			services[i].Dependencies = []string{}
+		}
+	}
+
+	return services, nil
+}
+
+// getServiceDependencies returns what each service depends on, by name.
+// where narrows the services, e.g. to one ID given as $1.
+func (db *DB) getServiceDependencies(where string, args ...interface{}) (map[uuid.UUID][]string, error) {
+	query := "SELECT service_id, depends_on FROM service_dependencies"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY depends_on ASC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	dependencies := map[uuid.UUID][]string{}
+	for rows.Next() {
+		var serviceID uuid.UUID
+		var dependsOn string
+		if err := rows.Scan(&serviceID, &dependsOn); err != nil {
+			return nil, fmt.Errorf("failed to scan service dependency: %w", err)
+		}
+		dependencies[serviceID] = append(dependencies[serviceID], dependsOn)
+	}
+
+	return dependencies, rows.Err()
+}
+
+func (db *DB) getService(where string, arg interface{}) (*models.Service, error) {
+	query := fmt.Sprintf("SELECT %s FROM services WHERE %s = $1", serviceColumns, where)
+
+	service, err := scanService(db.QueryRow(query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("service not found")
+		}
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	dependencies, err := db.getServiceDependencies("service_id = $1", service.ID)
+	if err != nil {
+		return nil, err
+	}
+	service.Dependencies = dependencies[service.ID]
+	if service.Dependencies == nil {
+		service.Dependencies = []string{}
+	}
+
+	return service, nil
+}
+
+func (db *DB) GetServiceByID(id uuid.UUID) (*models.Service, error) {
+	return db.getService("id", id)
+}
+
+func (db *DB) GetServiceByName(name string) (*models.Service, error) {
+	return db.getService("name", name)
+}
+
+func (db *DB) CreateService(service *models.Service) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO services (id, name, description, owner_team, repository_url, runbook_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err = tx.Exec(query,
+		service.ID, service.Name, service.Description, service.OwnerTeam,
+		service.RepositoryURL, service.RunbookURL, service.CreatedAt, service.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setServiceDependencies(tx, service); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateService saves a service and its dependencies. Services that depend on
+// it by its old name follow a rename.
+func (db *DB) UpdateService(service *models.Service, oldName string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE services SET
+			name = $2, description = $3, owner_team = $4, repository_url = $5,
+			runbook_url = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	_, err = tx.Exec(query,
+		service.ID, service.Name, service.Description, service.OwnerTeam,
+		service.RepositoryURL, service.RunbookURL, service.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if oldName != service.Name {
+		// A service may already depend on both names
+		_, err := tx.Exec(`
+			UPDATE service_dependencies d SET depends_on = $2
+			WHERE depends_on = $1 AND NOT EXISTS (
+				SELECT 1 FROM service_dependencies o WHERE o.service_id = d.service_id AND o.depends_on = $2
+			)
+		`, oldName, service.Name)
+		if err != nil {
+			return fmt.Errorf("failed to rename service dependencies: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM service_dependencies WHERE depends_on = $1", oldName); err != nil {
+			return fmt.Errorf("failed to rename service dependencies: %w", err)
+		}
+	}
+	if err := setServiceDependencies(tx, service); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setServiceDependencies replaces what a service depends on
+func setServiceDependencies(tx *sql.Tx, service *models.Service) error {
+	if _, err := tx.Exec("DELETE FROM service_dependencies WHERE service_id = $1", service.ID); err != nil {
+		return fmt.Errorf("failed to set service dependencies: %w", err)
+	}
+	for _, dependsOn := range service.Dependencies {
+		_, err := tx.Exec("INSERT INTO service_dependencies (service_id, depends_on) VALUES ($1, $2)", service.ID, dependsOn)
+		if err != nil {
+			return fmt.Errorf("failed to set service dependencies: %w", err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) DeleteService(id uuid.UUID) error {
+	_, err := db.Exec("DELETE FROM services WHERE id = $1", id)
+	return err
+}
+
+// GetUnregisteredSources returns error sources seen since the given time that
+// have no catalog entry
+func (db *DB) GetUnregisteredSources(since time.Time) ([]string, error) {
+	query := `
+		SELECT DISTINCT e.source FROM errors e
+		WHERE e.timestamp >= $1 AND e.deleted_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM services s WHERE s.name = e.source)
+		ORDER BY e.source ASC
+	`
+
+	rows, err := db.Query(query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unregistered sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []string{}
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, fmt.Errorf("failed to scan source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func (db *DB) GetServiceErrorStats(source string) (*models.ServiceErrorStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE timestamp >= NOW() - INTERVAL '24 hours'),
+			COUNT(*) FILTER (WHERE timestamp >= NOW() - INTERVAL '7 days'),
+			COUNT(DISTINCT fingerprint) FILTER (WHERE resolved = false),
+			MAX(timestamp)
+		FROM errors WHERE source = $1 AND deleted_at IS NULL
+	`
+
+	var stats models.ServiceErrorStats
+	err := db.QueryRow(query, source).Scan(
+		&stats.ErrorsLast24h, &stats.ErrorsLast7d, &stats.UnresolvedGroups, &stats.LastSeen,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service error stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// GetServiceTrend returns hourly error counts for a source over the last 24 hours
+func (db *DB) GetServiceTrend(source string) ([]models.TrendDataPoint, error) {
+	query := `
+		SELECT
+			date_trunc('hour', timestamp) AS bucket,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE resolved = true),
+			COUNT(*) FILTER (WHERE level = 'error')
+		FROM errors
+		WHERE source = $1 AND deleted_at IS NULL AND timestamp >= NOW() - INTERVAL '24 hours'
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+
+	rows, err := db.Query(query, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service trend: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.TrendDataPoint{}
+	for rows.Next() {
+		var point models.TrendDataPoint
+		if err := rows.Scan(&point.Timestamp, &point.ErrorCount, &point.ResolvedCount, &point.CriticalCount); err != nil {
+			return nil, fmt.Errorf("failed to scan service trend: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// GetServiceTopErrors returns the most frequent unresolved error groups of a
+// source over the last 24 hours
+func (db *DB) GetServiceTopErrors(source string, limit int) ([]models.ServiceErrorGroup, error) {
+	query := `
+		SELECT fingerprint, MAX(message), MAX(level), COUNT(*), MAX(timestamp)
+		FROM errors
+		WHERE source = $1 AND resolved = false AND fingerprint IS NOT NULL AND deleted_at IS NULL
+		  AND timestamp >= NOW() - INTERVAL '24 hours'
+		GROUP BY fingerprint
+		ORDER BY COUNT(*) DESC
+		LIMIT $2
+	`
+
+	rows, err := db.Query(query, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service top errors: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.ServiceErrorGroup{}
+	for rows.Next() {
+		var group models.ServiceErrorGroup
+		if err := rows.Scan(&group.Fingerprint, &group.Message, &group.Level, &group.Count, &group.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan service top error: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// GetOpenIncidentsForSource returns unresolved incidents opened by alert rules
+// scoped to the source
+func (db *DB) GetOpenIncidentsForSource(source string) ([]models.Incident, error) {
+	query := `
+		SELECT i.id, i.title, i.severity, i.status, COALESCE(i.description, ''),
+			   i.assigned_to, i.assigned_team, i.created_at, i.updated_at
+		FROM incidents i
+		JOIN alert_rules r ON r.incident_id = i.id
+		WHERE r.scope->>'source' = $1 AND i.status NOT IN ('resolved', 'closed')
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := db.Query(query, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []models.Incident{}
+	for rows.Next() {
+		var incident models.Incident
+		err := rows.Scan(
+			&incident.ID, &incident.Title, &incident.Severity, &incident.Status,
+			&incident.Description, &incident.AssignedTo, &incident.AssignedTeam,
+			&incident.CreatedAt, &incident.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, nil
+}
+
+// GetErrorCountsBySource returns the number of errors each source reported
+// since the given time
+func (db *DB) GetErrorCountsBySource(since time.Time) (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT source, COUNT(*) FROM errors
+		WHERE timestamp >= $1 AND deleted_at IS NULL
+		GROUP BY source
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var source string
+		var count int
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan error count: %w", err)
+		}
+		counts[source] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetOpenIncidentCountsBySource returns the number of unresolved incidents
+// opened by alert rules scoped to each source
+func (db *DB) GetOpenIncidentCountsBySource() (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT r.scope->>'source', COUNT(DISTINCT i.id)
+		FROM incidents i
+		JOIN alert_rules r ON r.incident_id = i.id
+		WHERE r.scope->>'source' <> '' AND i.status NOT IN ('resolved', 'closed')
+		GROUP BY r.scope->>'source'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service incidents: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var source string
+		var count int
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan incident count: %w", err)
+		}
+		counts[source] = count
+	}
+	return counts, rows.Err()
+}
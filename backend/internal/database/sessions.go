@@ -0,0 +1,120 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// UpsertSessions records session start and end pings. A session keeps the
+// first terminal status it reports; later "ok" pings do not reopen it.
+func (db *DB) UpsertSessions(updates []models.SessionUpdate, projectID *uuid.UUID) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO sessions (
+			session_id, project_id, distinct_id, release, environment,
+			status, errors, started_at, ended_at
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (session_id) DO UPDATE SET
+			distinct_id = COALESCE(sessions.distinct_id, EXCLUDED.distinct_id),
+			status = CASE WHEN sessions.status = 'ok' THEN EXCLUDED.status ELSE sessions.status END,
+			errors = GREATEST(sessions.errors, EXCLUDED.errors),
+			ended_at = COALESCE(EXCLUDED.ended_at, sessions.ended_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare session upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, update := range updates {
+		var endedAt *time.Time
+		if update.Status != "ok" {
+			endedAt = update.Timestamp
+		}
+
+		_, err := stmt.Exec(
+			update.SessionID, projectID, update.DistinctID, update.Release, update.Environment,
+			update.Status, update.Errors, update.Started, endedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert session %s: %w", update.SessionID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionHealth computes crash-free session and user rates per release and
+// environment for sessions started since the given time
+func (db *DB) GetSessionHealth(release, environment string, since time.Time) ([]models.SessionHealth, error) {
+	whereClause := "WHERE started_at >= $1"
+	args := []interface{}{since}
+	argIndex := 2
+
+	if release != "" {
+		whereClause += fmt.Sprintf(" AND release = $%d", argIndex)
+		args = append(args, release)
+		argIndex++
+	}
+	if environment != "" {
+		whereClause += fmt.Sprintf(" AND environment = $%d", argIndex)
+		args = append(args, environment)
+		argIndex++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT release, environment,
+			   COUNT(*),
+			   COUNT(*) FILTER (WHERE status = 'crashed'),
+			   COUNT(*) FILTER (WHERE status = 'abnormal'),
+			   COUNT(*) FILTER (WHERE errors > 0),
+			   COUNT(DISTINCT distinct_id),
+			   COUNT(DISTINCT distinct_id) FILTER (WHERE status = 'crashed')
+		FROM sessions %s
+		GROUP BY release, environment
+		ORDER BY MAX(started_at) DESC
+	`, whereClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session health: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.SessionHealth{}
+	for rows.Next() {
+		var health models.SessionHealth
+
+		err := rows.Scan(
+			&health.Release, &health.Environment, &health.TotalSessions,
+			&health.CrashedSessions, &health.AbnormalSessions, &health.ErroredSessions,
+			&health.TotalUsers, &health.CrashedUsers,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session health: %w", err)
+		}
+
+		health.CrashFreeSessionsRate = crashFreeRate(health.TotalSessions, health.CrashedSessions)
+		health.CrashFreeUsersRate = crashFreeRate(health.TotalUsers, health.CrashedUsers)
+
+		results = append(results, health)
+	}
+
+	return results, nil
+}
+
+// crashFreeRate returns the crash-free percentage, or 100 with no data
+func crashFreeRate(total, crashed int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(total-crashed) / float64(total) * 100
+}
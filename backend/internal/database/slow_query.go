@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// slowQueryLog tracks queries that take longer than threshold. A zero
+// threshold disables logging.
+type slowQueryLog struct {
+	threshold time.Duration
+	count     atomic.Int64
+}
+
+// observe logs the query when it ran for longer than the threshold. Only
+// the types of its parameters are logged, as their values may be personal
+// data or secrets.
+func (l *slowQueryLog) observe(query string, args []interface{}, start time.Time) {
+	if l.threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < l.threshold {
+		return
+	}
+	l.count.Add(1)
+	log.Printf("SLOW QUERY: %dms, query: %s, args: %s",
+		elapsed.Milliseconds(), strings.Join(strings.Fields(query), " "), argTypes(args))
+}
+
+// argTypes describes query parameters by count and type, e.g.
+// "3 [uuid.UUID string int]"
+func argTypes(args []interface{}) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("%T", arg)
+	}
+	return fmt.Sprintf("%d [%s]", len(args), strings.Join(types, " "))
+}
+
+// SlowQueryCount is the number of slow queries since the process started
+func (db *DB) SlowQueryCount() int64 {
+	return db.slow.count.Load()
+}
+
+// SlowQueryThreshold is the duration above which queries are logged
+func (db *DB) SlowQueryThreshold() time.Duration {
+	return db.slow.threshold
+}
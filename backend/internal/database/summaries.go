@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+// GetErrorSummary returns the stored summary of an error group, or nil when
+// none has been generated
+func (db *DB) GetErrorSummary(fingerprint string) (*models.ErrorSummary, error) {
+	summary := models.ErrorSummary{Fingerprint: fingerprint}
+	err := db.QueryRow(`
+		SELECT summary, hypotheses, model, generated_at
+		FROM error_summaries WHERE fingerprint = $1
+	`, fingerprint).Scan(&summary.Summary, pq.Array(&summary.Hypotheses), &summary.Model, &summary.GeneratedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get error summary: %w", err)
+	}
+	if summary.Hypotheses == nil {
+		summary.Hypotheses = []string{}
+	}
+	return &summary, nil
+}
+
+// SaveErrorSummary stores the summary of an error group, replacing any
+// earlier one
+func (db *DB) SaveErrorSummary(summary *models.ErrorSummary) error {
+	_, err := db.Exec(`
+		INSERT INTO error_summaries (fingerprint, summary, hypotheses, model, generated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (fingerprint) DO UPDATE SET
+			summary = EXCLUDED.summary,
+			hypotheses = EXCLUDED.hypotheses,
+			model = EXCLUDED.model,
+			generated_at = EXCLUDED.generated_at
+	`, summary.Fingerprint, summary.Summary, pq.Array(summary.Hypotheses), summary.Model, summary.GeneratedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save error summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Team methods
+func (db *DB) GetTeams() ([]models.Team, error) {
+	query := `
+		SELECT id, name, COALESCE(description, ''), created_at
+		FROM teams ORDER BY name ASC
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var team models.Team
+
+		err := rows.Scan(&team.ID, &team.Name, &team.Description, &team.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+
+		teams = append(teams, team)
+	}
+
+	for i := range teams {
+		members, err := db.GetTeamMembersByTeam(teams[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		teams[i].Members = members
+	}
+
+	return teams, nil
+}
+
+func (db *DB) GetTeamByID(id uuid.UUID) (*models.Team, error) {
+	query := `
+		SELECT id, name, COALESCE(description, ''), created_at
+		FROM teams WHERE id = $1
+	`
+
+	var team models.Team
+	err := db.QueryRow(query, id).Scan(&team.ID, &team.Name, &team.Description, &team.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("team not found")
+		}
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+
+	members, err := db.GetTeamMembersByTeam(team.ID)
+	if err != nil {
+		return nil, err
+	}
+	team.Members = members
+
+	return &team, nil
+}
+
+func (db *DB) CreateTeam(team *models.Team, memberIDs []uuid.UUID) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO teams (id, name, description, created_at) VALUES ($1, $2, $3, $4)",
+		team.ID, team.Name, team.Description, team.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create team: %w", err)
+	}
+
+	for _, memberID := range memberIDs {
+		_, err = tx.Exec(
+			"INSERT INTO team_memberships (team_id, member_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			team.ID, memberID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add team member: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) UpdateTeam(team *models.Team) error {
+	query := "UPDATE teams SET name = $2, description = $3 WHERE id = $1"
+	_, err := db.Exec(query, team.ID, team.Name, team.Description)
+	return err
+}
+
+func (db *DB) DeleteTeam(id uuid.UUID) error {
+	query := "DELETE FROM teams WHERE id = $1"
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// Team membership methods
+func (db *DB) GetTeamMembersByTeam(teamID uuid.UUID) ([]models.TeamMember, error) {
+	query := `
+		SELECT m.id, m.name, m.email, m.role, m.status, m.last_active, m.created_at
+		FROM team_members m
+		JOIN team_memberships tm ON tm.member_id = m.id
+		WHERE tm.team_id = $1
+		ORDER BY m.name ASC
+	`
+
+	rows, err := db.Query(query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team membership: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var member models.TeamMember
+
+		err := rows.Scan(
+			&member.ID, &member.Name, &member.Email, &member.Role,
+			&member.Status, &member.LastActive, &member.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (db *DB) AddTeamMembership(teamID, memberID uuid.UUID) error {
+	query := "INSERT INTO team_memberships (team_id, member_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := db.Exec(query, teamID, memberID)
+	return err
+}
+
+func (db *DB) RemoveTeamMembership(teamID, memberID uuid.UUID) error {
+	query := "DELETE FROM team_memberships WHERE team_id = $1 AND member_id = $2"
+	_, err := db.Exec(query, teamID, memberID)
+	return err
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const thresholdSubscriptionColumns = "id, member_id, fingerprint, threshold, time_window, state, last_triggered, created_at"
+
+// Threshold subscription methods
+func (db *DB) CreateThresholdSubscription(sub *models.ThresholdSubscription) error {
+	query := fmt.Sprintf(`
+		INSERT INTO threshold_subscriptions (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, thresholdSubscriptionColumns)
+
+	_, err := db.Exec(query,
+		sub.ID, sub.MemberID, sub.Fingerprint, sub.Threshold,
+		sub.TimeWindow, sub.State, sub.LastTriggered, sub.CreatedAt,
+	)
+	return err
+}
+
+// GetThresholdSubscriptions returns every subscription for the evaluator
+func (db *DB) GetThresholdSubscriptions() ([]models.ThresholdSubscription, error) {
+	query := fmt.Sprintf("SELECT %s FROM threshold_subscriptions ORDER BY fingerprint", thresholdSubscriptionColumns)
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query threshold subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanThresholdSubscriptions(rows)
+}
+
+// ListThresholdSubscriptions returns a page of the subscriptions matching
+// filter, newest first, and how many match in total
+func (db *DB) ListThresholdSubscriptions(limit, offset int, filter models.ThresholdSubscriptionFilter) ([]models.ThresholdSubscription, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.MemberID != nil {
+		conditions = append(conditions, fmt.Sprintf("member_id = $%d", argIndex))
+		args = append(args, *filter.MemberID)
+		argIndex++
+	}
+	if filter.Fingerprint != "" {
+		conditions = append(conditions, fmt.Sprintf("fingerprint = $%d", argIndex))
+		args = append(args, filter.Fingerprint)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM threshold_subscriptions "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count threshold subscriptions: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM threshold_subscriptions %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, thresholdSubscriptionColumns, whereClause, argIndex, argIndex+1)
+
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query threshold subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs, err := scanThresholdSubscriptions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return subs, total, nil
+}
+
+// UpdateThresholdSubscriptionState persists the evaluator's firing state
+func (db *DB) UpdateThresholdSubscriptionState(sub *models.ThresholdSubscription) error {
+	_, err := db.Exec(
+		"UPDATE threshold_subscriptions SET state = $2, last_triggered = $3 WHERE id = $1",
+		sub.ID, sub.State, sub.LastTriggered,
+	)
+	return err
+}
+
+func (db *DB) DeleteThresholdSubscription(id uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM threshold_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("threshold subscription not found")
+	}
+	return nil
+}
+
+func scanThresholdSubscriptions(rows *sql.Rows) ([]models.ThresholdSubscription, error) {
+	subs := []models.ThresholdSubscription{}
+	for rows.Next() {
+		var sub models.ThresholdSubscription
+
+		err := rows.Scan(
+			&sub.ID, &sub.MemberID, &sub.Fingerprint, &sub.Threshold,
+			&sub.TimeWindow, &sub.State, &sub.LastTriggered, &sub.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan threshold subscription: %w", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"error-logs/internal/models"
+)
+
+// Throttle period methods
+func (db *DB) CreateThrottlePeriod(period *models.ThrottlePeriod) error {
+	query := `
+		INSERT INTO throttle_periods (
+			id, kind, key, suppressed_count, started_at, last_seen_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := db.Exec(query,
+		period.ID, period.Kind, period.Key, period.SuppressedCount,
+		period.StartedAt, period.LastSeenAt,
+	)
+
+	return err
+}
+
+func (db *DB) UpdateThrottlePeriod(id uuid.UUID, suppressedCount int, lastSeenAt time.Time) error {
+	query := "UPDATE throttle_periods SET suppressed_count = $2, last_seen_at = $3 WHERE id = $1"
+	_, err := db.Exec(query, id, suppressedCount, lastSeenAt)
+	return err
+}
+
+func (db *DB) GetThrottlePeriods(limit int) ([]models.ThrottlePeriod, error) {
+	query := `
+		SELECT id, kind, key, suppressed_count, started_at, last_seen_at,
+			   last_seen_at >= NOW() - INTERVAL '2 minutes' AS active
+		FROM throttle_periods
+		ORDER BY started_at DESC
+		LIMIT $1
+	`
+
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query throttle periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.ThrottlePeriod{}
+	for rows.Next() {
+		var period models.ThrottlePeriod
+
+		err := rows.Scan(
+			&period.ID, &period.Kind, &period.Key, &period.SuppressedCount,
+			&period.StartedAt, &period.LastSeenAt, &period.Active,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan throttle period: %w", err)
+		}
+
+		periods = append(periods, period)
+	}
+
+	return periods, nil
+}
+
+// AddSuppressedEvents adds to the per-day counts of events spike protection
+// dropped from each group
+func (db *DB) AddSuppressedEvents(counts []models.SuppressedEvents) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, count := range counts {
+		_, err := tx.Exec(`
+			INSERT INTO error_group_suppressed (fingerprint, day, suppressed_count)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (fingerprint, day) DO UPDATE SET
+				suppressed_count = error_group_suppressed.suppressed_count + EXCLUDED.suppressed_count
+		`, count.Fingerprint, count.Day.Format("2006-01-02"), count.Count)
+		if err != nil {
+			return fmt.Errorf("failed to add suppressed events: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSuppressedCounts returns how many events spike protection dropped from
+// each of the given groups, keyed by fingerprint
+func (db *DB) GetSuppressedCounts(fingerprints []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(fingerprints) == 0 {
+		return counts, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT fingerprint, SUM(suppressed_count) FROM error_group_suppressed
+		WHERE fingerprint = ANY($1)
+		GROUP BY fingerprint
+	`, pq.Array(fingerprints))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suppressed events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint string
+		var count int
+		if err := rows.Scan(&fingerprint, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan suppressed events: %w", err)
+		}
+		counts[fingerprint] = count
+	}
+	return counts, rows.Err()
+}
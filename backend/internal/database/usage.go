@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Quota methods
+func (db *DB) GetProjectQuota(projectID uuid.UUID) (*models.ProjectQuota, error) {
+	query := `
+		SELECT project_id, daily_soft_limit, daily_hard_limit,
+			   monthly_soft_limit, monthly_hard_limit, updated_at
+		FROM project_quotas WHERE project_id = $1
+	`
+
+	var quota models.ProjectQuota
+	err := db.QueryRow(query, projectID).Scan(
+		&quota.ProjectID, &quota.DailySoftLimit, &quota.DailyHardLimit,
+		&quota.MonthlySoftLimit, &quota.MonthlyHardLimit, &quota.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// No quota configured means unlimited
+			return &models.ProjectQuota{ProjectID: projectID}, nil
+		}
+		return nil, fmt.Errorf("failed to get project quota: %w", err)
+	}
+
+	return &quota, nil
+}
+
+func (db *DB) UpsertProjectQuota(quota *models.ProjectQuota) error {
+	query := `
+		INSERT INTO project_quotas (
+			project_id, daily_soft_limit, daily_hard_limit,
+			monthly_soft_limit, monthly_hard_limit, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (project_id) DO UPDATE SET
+			daily_soft_limit = EXCLUDED.daily_soft_limit,
+			daily_hard_limit = EXCLUDED.daily_hard_limit,
+			monthly_soft_limit = EXCLUDED.monthly_soft_limit,
+			monthly_hard_limit = EXCLUDED.monthly_hard_limit,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := db.Exec(query,
+		quota.ProjectID, quota.DailySoftLimit, quota.DailyHardLimit,
+		quota.MonthlySoftLimit, quota.MonthlyHardLimit, quota.UpdatedAt,
+	)
+
+	return err
+}
+
+// Usage methods
+
+// AddUsage adds to the per-day counts of accepted and dropped events of each
+// project
+func (db *DB) AddUsage(counts []models.UsageCount) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, count := range counts {
+		_, err := tx.Exec(`
+			INSERT INTO project_usage (project_id, day, accepted, dropped)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (project_id, day) DO UPDATE SET
+				accepted = project_usage.accepted + EXCLUDED.accepted,
+				dropped = project_usage.dropped + EXCLUDED.dropped
+		`, count.ProjectID, count.Day.Format("2006-01-02"), count.Accepted, count.Dropped)
+		if err != nil {
+			return fmt.Errorf("failed to add usage: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUsage returns usage per project and period, optionally limited to one
+// project and to the projects of one organization
+func (db *DB) GetUsage(projectID, orgID *uuid.UUID, groupBy string, since time.Time) ([]models.UsageRecord, error) {
+	timeFormat := "YYYY-MM-DD"
+	if groupBy == "month" {
+		timeFormat = "YYYY-MM"
+	}
+
+	whereClause := "WHERE day >= $1"
+	args := []interface{}{since.Format("2006-01-02")}
+
+	if projectID != nil {
+		args = append(args, *projectID)
+		whereClause += fmt.Sprintf(" AND project_id = $%d", len(args))
+	}
+	if orgID != nil {
+		args = append(args, *orgID)
+		whereClause += fmt.Sprintf(" AND project_id IN (SELECT id FROM projects WHERE org_id = $%d)", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT project_id, TO_CHAR(day, '%s') AS period,
+			   SUM(accepted) AS accepted, SUM(dropped) AS dropped
+		FROM project_usage
+		%s
+		GROUP BY project_id, period
+		ORDER BY period ASC, project_id
+	`, timeFormat, whereClause)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []models.UsageRecord{}
+	for rows.Next() {
+		var record models.UsageRecord
+
+		err := rows.Scan(&record.ProjectID, &record.Period, &record.Accepted, &record.Dropped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+
+		usage = append(usage, record)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrVersionMismatch is returned by versioned updates and deletes when the row
+// changed since the version the caller read
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// missedVersioned explains a versioned UPDATE or DELETE that matched no row:
+// the row is gone, reported as notFound, or its version moved on. existsQuery
+// takes the row's ID as $1.
+func (db *DB) missedVersioned(existsQuery string, id uuid.UUID, notFound string) error {
+	var exists bool
+	if err := db.QueryRow(existsQuery, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check version: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return errors.New(notFound)
+}
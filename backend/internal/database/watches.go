@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Error watch methods
+func (db *DB) WatchErrorGroup(fingerprint string, memberID uuid.UUID) error {
+	query := "INSERT INTO error_watches (fingerprint, member_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := db.Exec(query, fingerprint, memberID)
+	return err
+}
+
+func (db *DB) UnwatchErrorGroup(fingerprint string, memberID uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM error_watches WHERE fingerprint = $1 AND member_id = $2", fingerprint, memberID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("watch not found")
+	}
+	return nil
+}
+
+// GetErrorGroupWatchers returns the team members watching a group, by name
+func (db *DB) GetErrorGroupWatchers(fingerprint string) ([]models.TeamMember, error) {
+	query := `
+		SELECT m.id, m.name, m.email, m.role, m.status, m.last_active, m.created_at
+		FROM team_members m
+		JOIN error_watches w ON w.member_id = m.id
+		WHERE w.fingerprint = $1
+		ORDER BY m.name ASC
+	`
+
+	rows, err := db.Query(query, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error watchers: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var member models.TeamMember
+
+		err := rows.Scan(
+			&member.ID, &member.Name, &member.Email, &member.Role,
+			&member.Status, &member.LastActive, &member.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error watcher: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,122 @@
+// Package encryption encrypts sensitive column values with AES-GCM before
+// they are stored. Encrypted values carry the ID of their key, so keys can be
+// rotated: new values use the current key while older keys stay available
+// for decryption until every value has been re-encrypted.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// prefix marks encrypted values: enc:v1:<key id>:<base64 nonce and ciphertext>.
+// Values without it are plaintext written before encryption was enabled.
+const prefix = "enc:v1:"
+
+// Keyring holds the encryption keys by ID. A nil Keyring stores values as
+// plaintext.
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// ParseKeyring reads keys from "id:base64key" entries. The first entry is
+// the current key; 32-byte keys select AES-256. No entries returns nil.
+func ParseKeyring(entries []string) (*Keyring, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	keyring := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for i, entry := range entries {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("encryption key %d must be id:base64key", i+1)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+
+		keyring.keys[id] = aead
+		if i == 0 {
+			keyring.currentID = id
+		}
+	}
+	return keyring, nil
+}
+
+// Enabled reports whether new values are encrypted
+func (k *Keyring) Enabled() bool {
+	return k != nil
+}
+
+// CurrentPrefix is the prefix of values encrypted with the current key, for
+// finding values that still need rotating
+func (k *Keyring) CurrentPrefix() string {
+	return prefix + k.currentID + ":"
+}
+
+// Encrypt seals plaintext with the current key. Without a keyring it returns
+// plaintext unchanged.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	aead := k.keys[k.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return k.CurrentPrefix() + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value written by Encrypt with whichever key sealed it.
+// Plaintext values are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	if k == nil {
+		return "", fmt.Errorf("value is encrypted with key %q but no encryption keys are configured", id)
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key %q", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether value was written by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
@@ -0,0 +1,158 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey1 = "k1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	testKey2 = "k2:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func testKeyring(t *testing.T, entries ...string) *Keyring {
+	t.Helper()
+	keyring, err := ParseKeyring(entries)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	return keyring
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	keyring := testKeyring(t, testKey1)
+	const secret = "https://hooks.example.com/T000/B000/XXXX"
+
+	sealed, err := keyring.Encrypt(secret)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(sealed) || !strings.HasPrefix(sealed, keyring.CurrentPrefix()) || strings.Contains(sealed, secret) {
+		t.Errorf("Encrypt = %q, want the secret sealed under k1", sealed)
+	}
+	if again, _ := keyring.Encrypt(secret); again == sealed {
+		t.Error("Encrypt reused a nonce")
+	}
+
+	opened, err := keyring.Decrypt(sealed)
+	if err != nil || opened != secret {
+		t.Errorf("Decrypt = %q, %v, want %q", opened, err, secret)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := testKeyring(t, testKey1)
+	sealed, err := old.Encrypt("signing secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := testKeyring(t, testKey2, testKey1)
+	if opened, err := rotated.Decrypt(sealed); err != nil || opened != "signing secret" {
+		t.Errorf("Decrypt with the old key second = %q, %v", opened, err)
+	}
+	if strings.HasPrefix(sealed, rotated.CurrentPrefix()) {
+		t.Error("a value sealed under k1 counts as current after rotating to k2")
+	}
+
+	resealed, err := rotated.Encrypt("signing secret")
+	if err != nil || !strings.HasPrefix(resealed, "enc:v1:k2:") {
+		t.Errorf("Encrypt after rotation = %q, %v, want it sealed under k2", resealed, err)
+	}
+	if _, err := old.Decrypt(resealed); err == nil {
+		t.Error("a keyring without k2 decrypted a value sealed under it")
+	}
+}
+
+func TestKeyringRejectsWrongKey(t *testing.T) {
+	sealed, err := testKeyring(t, testKey1).Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	// Same ID, different key material
+	impostor := testKeyring(t, "k1:"+strings.TrimPrefix(testKey2, "k2:"))
+	if opened, err := impostor.Decrypt(sealed); err == nil {
+		t.Errorf("Decrypt with the wrong key = %q, want an error", opened)
+	}
+}
+
+func TestKeyringRejectsTamperedValues(t *testing.T) {
+	keyring := testKeyring(t, testKey1)
+	sealed, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, keyring.CurrentPrefix()))
+	if err != nil {
+		t.Fatalf("decode sealed value: %v", err)
+	}
+	raw[len(raw)-1] ^= 0x01
+	flipped := keyring.CurrentPrefix() + base64.StdEncoding.EncodeToString(raw)
+
+	for _, value := range []string{
+		flipped,
+		sealed[:len(sealed)-8],
+		keyring.CurrentPrefix() + "not base64!",
+		keyring.CurrentPrefix() + base64.StdEncoding.EncodeToString([]byte("short")),
+		"enc:v1:no-separator",
+	} {
+		if opened, err := keyring.Decrypt(value); err == nil {
+			t.Errorf("Decrypt(%q) = %q, want an error", value, opened)
+		}
+	}
+}
+
+func TestKeyringPlaintextPassthrough(t *testing.T) {
+	var disabled *Keyring
+	if disabled.Enabled() {
+		t.Error("a nil keyring is enabled")
+	}
+	if stored, err := disabled.Encrypt("203.0.113.5"); err != nil || stored != "203.0.113.5" {
+		t.Errorf("Encrypt without keys = %q, %v, want the plaintext", stored, err)
+	}
+
+	// Values written before encryption was enabled stay readable
+	for _, keyring := range []*Keyring{disabled, testKeyring(t, testKey1)} {
+		if opened, err := keyring.Decrypt("203.0.113.5"); err != nil || opened != "203.0.113.5" {
+			t.Errorf("Decrypt(plaintext) = %q, %v, want it unchanged", opened, err)
+		}
+	}
+
+	sealed, err := testKeyring(t, testKey1).Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := disabled.Decrypt(sealed); err == nil {
+		t.Error("a nil keyring returned an encrypted value instead of failing")
+	}
+}
+
+func TestKeyringUnknownKey(t *testing.T) {
+	sealed, err := testKeyring(t, testKey2).Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	_, err = testKeyring(t, testKey1).Decrypt(sealed)
+	if err == nil || !strings.Contains(err.Error(), `unknown encryption key "k2"`) {
+		t.Errorf("Decrypt = %v, want an unknown key error", err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	if keyring, err := ParseKeyring(nil); keyring != nil || err != nil {
+		t.Errorf("ParseKeyring(nil) = %v, %v, want no keyring", keyring, err)
+	}
+
+	for _, entries := range [][]string{
+		{"no-separator"},
+		{":" + strings.TrimPrefix(testKey1, "k1:")},
+		{"k1:not base64!"},
+		{"k1:" + base64.StdEncoding.EncodeToString([]byte("15 bytes long!!"))},
+		{testKey1, testKey1},
+	} {
+		if _, err := ParseKeyring(entries); err == nil {
+			t.Errorf("ParseKeyring(%q) accepted invalid keys", entries)
+		}
+	}
+}
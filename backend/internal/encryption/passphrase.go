@@ -0,0 +1,103 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// sealedFormat names the envelope written by SealWithPassphrase
+	sealedFormat = "error-logs-sealed/v1"
+	// passphraseIterations is the PBKDF2-SHA256 work factor for new envelopes
+	passphraseIterations = 600000
+	// MinPassphraseLength is the shortest passphrase SealWithPassphrase accepts
+	MinPassphraseLength = 12
+)
+
+// ErrWrongPassphrase is returned when an envelope can't be opened, because
+// the passphrase is wrong or the envelope was altered
+var ErrWrongPassphrase = errors.New("wrong passphrase or damaged data")
+
+// sealed is the JSON envelope of data sealed with a passphrase. It carries
+// everything needed to open it except the passphrase, so it can be opened
+// on another deployment with other encryption keys.
+type sealed struct {
+	Format     string `json:"format"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// SealWithPassphrase encrypts plaintext with AES-256-GCM under a key derived
+// from passphrase, independently of the deployment's encryption keys
+func SealWithPassphrase(plaintext []byte, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+
+	envelope := sealed{
+		Format:     sealedFormat,
+		KDF:        "pbkdf2-sha256",
+		Iterations: passphraseIterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(envelope.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := passphraseAEAD(passphrase, envelope.Salt, envelope.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, []byte(sealedFormat))
+
+	return json.Marshal(envelope)
+}
+
+// OpenWithPassphrase decrypts an envelope written by SealWithPassphrase
+func OpenWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	var envelope sealed
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Format != sealedFormat {
+		return nil, fmt.Errorf("not an encrypted backup")
+	}
+	// The work factor is bounded so a crafted envelope can't tie up the server
+	if envelope.KDF != "pbkdf2-sha256" || envelope.Iterations < 1 || envelope.Iterations > 10*passphraseIterations {
+		return nil, fmt.Errorf("unsupported key derivation %q", envelope.KDF)
+	}
+
+	aead, err := passphraseAEAD(passphrase, envelope.Salt, envelope.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, []byte(sealedFormat))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func passphraseAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
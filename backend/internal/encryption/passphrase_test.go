@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+func TestSealWithPassphrase(t *testing.T) {
+	plaintext := []byte(`{"api_keys":[{"name":"CI"}]}`)
+	data, err := SealWithPassphrase(plaintext, testPassphrase)
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+	if bytes.Contains(data, plaintext) {
+		t.Error("the envelope contains the plaintext")
+	}
+
+	opened, err := OpenWithPassphrase(data, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenWithPassphrase: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("OpenWithPassphrase = %q, want %q", opened, plaintext)
+	}
+
+	// A fresh salt and nonce for every envelope
+	again, _ := SealWithPassphrase(plaintext, testPassphrase)
+	if bytes.Equal(data, again) {
+		t.Error("sealing twice gave the same envelope")
+	}
+}
+
+func TestSealWithPassphraseRejectsShortPassphrases(t *testing.T) {
+	if _, err := SealWithPassphrase([]byte("x"), "short"); err == nil {
+		t.Error("SealWithPassphrase accepted a 5 character passphrase")
+	}
+}
+
+func TestOpenWithPassphraseRejectsWrongPassphrases(t *testing.T) {
+	data, err := SealWithPassphrase([]byte("secret"), testPassphrase)
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+	if _, err := OpenWithPassphrase(data, testPassphrase+"!"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("err = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestOpenWithPassphraseRejectsTamperedEnvelopes(t *testing.T) {
+	data, err := SealWithPassphrase([]byte("secret"), testPassphrase)
+	if err != nil {
+		t.Fatalf("SealWithPassphrase: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(e *sealed)
+		wantErr error
+	}{
+		{"flipped ciphertext bit", func(e *sealed) { e.Ciphertext[0] ^= 1 }, ErrWrongPassphrase},
+		{"truncated ciphertext", func(e *sealed) { e.Ciphertext = e.Ciphertext[:len(e.Ciphertext)-1] }, ErrWrongPassphrase},
+		{"other nonce", func(e *sealed) { e.Nonce[0] ^= 1 }, ErrWrongPassphrase},
+		{"short nonce", func(e *sealed) { e.Nonce = e.Nonce[:4] }, ErrWrongPassphrase},
+		{"other salt", func(e *sealed) { e.Salt[0] ^= 1 }, ErrWrongPassphrase},
+		{"unknown format", func(e *sealed) { e.Format = "error-logs-sealed/v0" }, nil},
+		{"unknown KDF", func(e *sealed) { e.KDF = "md5" }, nil},
+		{"excessive work factor", func(e *sealed) { e.Iterations = 100 * passphraseIterations }, nil},
+	}
+
+	for _, tt := range tests {
+		var envelope sealed
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			t.Fatalf("unmarshal envelope: %v", err)
+		}
+		tt.modify(&envelope)
+		tampered, _ := json.Marshal(envelope)
+
+		_, err := OpenWithPassphrase(tampered, testPassphrase)
+		if err == nil {
+			t.Errorf("%s: OpenWithPassphrase succeeded", tt.name)
+		} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if _, err := OpenWithPassphrase([]byte("not json"), testPassphrase); err == nil {
+		t.Error("OpenWithPassphrase accepted a file that is not an envelope")
+	}
+}
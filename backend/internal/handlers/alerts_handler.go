@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 
 	"error-logs/internal/models"
 	"error-logs/internal/services"
@@ -22,19 +29,40 @@ func NewAlertsHandler(alertsService *services.AlertsService) *AlertsHandler {
 }
 
 func (h *AlertsHandler) GetAlertRules(w http.ResponseWriter, r *http.Request) {
-	rules, err := h.alertsService.GetAlertRules(r.Context())
+	limit, offset := parsePage(r, 50, 500)
+
+	query := r.URL.Query()
+	filter := models.AlertRuleFilter{
+		State:      query.Get("state"),
+		Search:     query.Get("search"),
+		ExternalID: query.Get("external_id"),
+		OrgID:      requestOrgID(r),
+	}
+	if enabledStr := query.Get("enabled"); enabledStr != "" {
+		enabled, err := strconv.ParseBool(enabledStr)
+		if err != nil {
+			writeErrorResponse(w, "enabled must be true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Enabled = &enabled
+	}
+
+	rules, total, err := h.alertsService.GetAlertRules(r.Context(), limit, offset, filter)
 	if err != nil {
 		writeErrorResponse(w, "Failed to get alert rules", http.StatusInternalServerError)
 		return
 	}
 
-	writeSuccessResponse(w, map[string]interface{}{"rules": rules})
+	writeSuccessResponse(w, map[string]interface{}{
+		"rules":      rules,
+		"pagination": newPagination(r, total, limit, offset),
+	})
 }
 
 func (h *AlertsHandler) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateAlertRuleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -47,17 +75,51 @@ func (h *AlertsHandler) CreateAlertRule(w http.ResponseWriter, r *http.Request)
 		writeErrorResponse(w, "Condition is required", http.StatusBadRequest)
 		return
 	}
+	if !validNotificationTargets(req.Notifications) {
+		writeErrorResponse(w, "Team notification targets must be team:<uuid>", http.StatusBadRequest)
+		return
+	}
 
-	rule, err := h.alertsService.CreateAlertRule(r.Context(), &req)
+	rule, err := h.alertsService.CreateAlertRule(r.Context(), &req, requestOrgID(r))
+	if errors.Is(err, services.ErrInvalidAlertCondition) || errors.Is(err, services.ErrInvalidRunbook) ||
+		errors.Is(err, services.ErrInvalidAlertSeverity) || errors.Is(err, services.ErrInvalidAlertScope) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to create alert rule", http.StatusInternalServerError)
 		return
 	}
 
+	setVersionETag(w, rule.Version)
 	w.WriteHeader(http.StatusCreated)
 	writeSuccessResponse(w, rule)
 }
 
+func (h *AlertsHandler) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid alert rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.alertsService.GetAlertRule(r.Context(), id, requestOrgID(r))
+	if err != nil {
+		if err.Error() == "alert rule not found" {
+			writeErrorResponse(w, "Alert rule not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	setVersionETag(w, rule.Version)
+	writeSuccessResponse(w, rule)
+}
+
 func (h *AlertsHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -68,16 +130,39 @@ func (h *AlertsHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Request)
 
 	var req models.CreateAlertRuleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	rule, err := h.alertsService.UpdateAlertRule(r.Context(), id, &req)
+	if !validNotificationTargets(req.Notifications) {
+		writeErrorResponse(w, "Team notification targets must be team:<uuid>", http.StatusBadRequest)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
 	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.alertsService.UpdateAlertRule(r.Context(), id, &req, requestOrgID(r), ifVersion)
+	if errors.Is(err, services.ErrInvalidAlertCondition) || errors.Is(err, services.ErrInvalidRunbook) ||
+		errors.Is(err, services.ErrInvalidAlertSeverity) || errors.Is(err, services.ErrInvalidAlertScope) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil {
+		if err.Error() == "alert rule not found" {
+			writeErrorResponse(w, "Alert rule not found", http.StatusNotFound)
+			return
+		}
 		writeErrorResponse(w, "Failed to update alert rule", http.StatusInternalServerError)
 		return
 	}
 
+	setVersionETag(w, rule.Version)
 	writeSuccessResponse(w, rule)
 }
 
@@ -89,8 +174,21 @@ func (h *AlertsHandler) DeleteAlertRule(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.alertsService.DeleteAlertRule(r.Context(), id)
+	ifVersion, err := ifMatchVersion(r)
 	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.alertsService.DeleteAlertRule(r.Context(), id, requestOrgID(r), ifVersion)
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil {
+		if err.Error() == "alert rule not found" {
+			writeErrorResponse(w, "Alert rule not found", http.StatusNotFound)
+			return
+		}
 		writeErrorResponse(w, "Failed to delete alert rule", http.StatusInternalServerError)
 		return
 	}
@@ -98,20 +196,109 @@ func (h *AlertsHandler) DeleteAlertRule(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ApplyAlertRules makes the stored alert rules match a JSON or YAML document
+// of all of them, so they can be kept in version control and applied by CI
+func (h *AlertsHandler) ApplyAlertRules(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			writeErrorResponse(w, "dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	doc, err := decodeAlertRuleDocument(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeDecodeError(w, err)
+			return
+		}
+		writeErrorResponse(w, "Invalid rule document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, rule := range doc.Rules {
+		if !validNotificationTargets(rule.Notifications) {
+			writeErrorResponse(w, "Team notification targets must be team:<uuid> (rule "+strconv.Quote(rule.Name)+")", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.alertsService.ApplyAlertRules(r.Context(), doc, dryRun)
+	if errors.Is(err, services.ErrInvalidAlertRuleDocument) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to apply alert rules", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, result)
+}
+
+// decodeAlertRuleDocument reads a rule document as YAML when the request says
+// so and as JSON otherwise. Unknown fields are refused, so a misspelled field
+// fails in CI instead of being dropped.
+func decodeAlertRuleDocument(r *http.Request) (*models.AlertRuleDocument, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		// YAML is converted to JSON so both formats share the JSON field names
+		var value interface{}
+		if err := yaml.Unmarshal(body, &value); err != nil {
+			return nil, err
+		}
+		if body, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc models.AlertRuleDocument
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 func (h *AlertsHandler) GetIncidents(w http.ResponseWriter, r *http.Request) {
-	incidents, err := h.alertsService.GetIncidents(r.Context())
+	limit, offset := parsePage(r, 50, 500)
+
+	query := r.URL.Query()
+	filter := models.IncidentFilter{
+		Status:   query.Get("status"),
+		Severity: query.Get("severity"),
+		Search:   query.Get("search"),
+		OrgID:    requestOrgID(r),
+	}
+
+	incidents, total, err := h.alertsService.GetIncidents(r.Context(), limit, offset, filter)
 	if err != nil {
 		writeErrorResponse(w, "Failed to get incidents", http.StatusInternalServerError)
 		return
 	}
 
-	writeSuccessResponse(w, map[string]interface{}{"incidents": incidents})
+	writeSuccessResponse(w, map[string]interface{}{
+		"incidents":  incidents,
+		"pagination": newPagination(r, total, limit, offset),
+	})
 }
 
 func (h *AlertsHandler) CreateIncident(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateIncidentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -124,7 +311,7 @@ func (h *AlertsHandler) CreateIncident(w http.ResponseWriter, r *http.Request) {
 		req.Severity = "medium"
 	}
 
-	incident, err := h.alertsService.CreateIncident(r.Context(), &req)
+	incident, err := h.alertsService.CreateIncident(r.Context(), &req, requestOrgID(r))
 	if err != nil {
 		writeErrorResponse(w, "Failed to create incident", http.StatusInternalServerError)
 		return
@@ -144,15 +331,262 @@ func (h *AlertsHandler) UpdateIncident(w http.ResponseWriter, r *http.Request) {
 
 	var req models.CreateIncidentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	incident, err := h.alertsService.UpdateIncident(r.Context(), id, &req)
+	incident, err := h.alertsService.UpdateIncident(r.Context(), id, &req, requestOrgID(r))
 	if err != nil {
+		if err.Error() == "incident not found" {
+			writeErrorResponse(w, "Incident not found", http.StatusNotFound)
+			return
+		}
 		writeErrorResponse(w, "Failed to update incident", http.StatusInternalServerError)
 		return
 	}
 
 	writeSuccessResponse(w, incident)
 }
+
+func (h *AlertsHandler) GetIncidentRelatedErrors(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid incident ID", http.StatusBadRequest)
+		return
+	}
+
+	related, err := h.alertsService.GetIncidentRelatedErrors(r.Context(), id, requestOrgID(r))
+	if err != nil {
+		if err.Error() == "incident not found" {
+			writeErrorResponse(w, "Incident not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get related errors", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"related_errors": related,
+	})
+}
+
+// validNotificationTargets checks that team targets ("team:<uuid>") carry a valid team ID
+func validNotificationTargets(targets []string) bool {
+	for _, target := range targets {
+		if teamID, ok := strings.CutPrefix(target, "team:"); ok {
+			if _, err := uuid.Parse(teamID); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (h *AlertsHandler) GetThresholdSubscriptions(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePage(r, 50, 500)
+
+	query := r.URL.Query()
+	filter := models.ThresholdSubscriptionFilter{
+		Fingerprint: query.Get("fingerprint"),
+	}
+	if memberStr := query.Get("member_id"); memberStr != "" {
+		memberID, err := uuid.Parse(memberStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid member_id", http.StatusBadRequest)
+			return
+		}
+		filter.MemberID = &memberID
+	}
+
+	subs, total, err := h.alertsService.GetThresholdSubscriptions(r.Context(), limit, offset, filter)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get threshold subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"subscriptions": subs,
+		"pagination":    newPagination(r, total, limit, offset),
+	})
+}
+
+func (h *AlertsHandler) CreateThresholdSubscription(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateThresholdSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.MemberID == uuid.Nil {
+		writeErrorResponse(w, "member_id is required", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.alertsService.CreateThresholdSubscription(r.Context(), &req)
+	if errors.Is(err, services.ErrInvalidSubscription) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "team member not found" {
+			writeErrorResponse(w, "Team member not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to create threshold subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, sub)
+}
+
+func (h *AlertsHandler) DeleteThresholdSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertsService.DeleteThresholdSubscription(r.Context(), id); err != nil {
+		if err.Error() == "threshold subscription not found" {
+			writeErrorResponse(w, "Threshold subscription not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete threshold subscription", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AlertsHandler) GetNotificationSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.alertsService.GetNotificationSchedules(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get notification schedules", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"schedules": schedules})
+}
+
+func (h *AlertsHandler) CreateNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateNotificationScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	schedule, err := h.alertsService.CreateNotificationSchedule(r.Context(), &req)
+	if err != nil {
+		writeScheduleError(w, err, "Failed to create notification schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, schedule)
+}
+
+func (h *AlertsHandler) UpdateNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateNotificationScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	schedule, err := h.alertsService.UpdateNotificationSchedule(r.Context(), id, &req)
+	if err != nil {
+		writeScheduleError(w, err, "Failed to update notification schedule")
+		return
+	}
+
+	writeSuccessResponse(w, schedule)
+}
+
+func (h *AlertsHandler) DeleteNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertsService.DeleteNotificationSchedule(r.Context(), id); err != nil {
+		writeScheduleError(w, err, "Failed to delete notification schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeScheduleError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSchedule):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == "notification schedule not found":
+		writeErrorResponse(w, "Notification schedule not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, message, http.StatusInternalServerError)
+	}
+}
+
+// GetAlertTemplates lists the recommended rules, with thresholds suggested
+// for the scope in the query
+func (h *AlertsHandler) GetAlertTemplates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	scope := models.AlertScope{
+		Source:      query.Get("source"),
+		Environment: query.Get("environment"),
+		Level:       query.Get("level"),
+	}
+	if projectStr := query.Get("project_id"); projectStr != "" {
+		projectID, err := uuid.Parse(projectStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid project_id", http.StatusBadRequest)
+			return
+		}
+		scope.ProjectID = &projectID
+	}
+
+	templates, err := h.alertsService.GetAlertTemplates(r.Context(), scope)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get alert templates", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"templates": templates})
+}
+
+func (h *AlertsHandler) CreateAlertRuleFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRuleFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if !validNotificationTargets(req.Notifications) {
+		writeErrorResponse(w, "Team notification targets must be team:<uuid>", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.alertsService.CreateAlertRuleFromTemplate(r.Context(), chi.URLParam(r, "id"), &req, requestOrgID(r))
+	if errors.Is(err, services.ErrInvalidAlertCondition) || errors.Is(err, services.ErrInvalidRunbook) ||
+		errors.Is(err, services.ErrInvalidAlertSeverity) || errors.Is(err, services.ErrInvalidAlertScope) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && err.Error() == "alert template not found" {
+		writeErrorResponse(w, "Alert template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to create alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, rule)
+}
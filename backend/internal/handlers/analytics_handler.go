@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"time"
+
+	"github.com/google/uuid"
 
 	"error-logs/internal/models"
 	"error-logs/internal/services"
@@ -29,25 +34,131 @@ func (h *AnalyticsHandler) GetTrends(w http.ResponseWriter, r *http.Request) {
 		groupBy = "day"
 	}
 
-	trends, err := h.analyticsService.GetTrends(r.Context(), period, groupBy)
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	etag := ""
+	if generation, err := h.analyticsService.TrendsCacheGeneration(r.Context()); err != nil {
+		log.Printf("Failed to get cache generation: %v", err)
+	} else {
+		etag = responseETag(r, generation, trendsETagPeriod)
+	}
+	if notModified(w, r, etag) {
+		return
+	}
+
+	trends, err := h.analyticsService.GetTrends(r.Context(), period, groupBy, filter, r.URL.Query().Get("tz"))
+	if errors.Is(err, services.ErrInvalidTimezone) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to get trends", http.StatusInternalServerError)
 		return
 	}
 
+	setETag(w, etag)
 	writeSuccessResponse(w, trends)
 }
 
+func (h *AnalyticsHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	period := query.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	timezone := query.Get("tz")
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	etag := h.etag(r, heatmapETagPeriod)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	heatmap, err := h.analyticsService.GetHeatmap(r.Context(), filter, period, timezone)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidHeatmap) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeErrorResponse(w, "Failed to get heatmap", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, etag)
+	writeSuccessResponse(w, heatmap)
+}
+
+func (h *AnalyticsHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	projectID, err := uuid.Parse(query.Get("project"))
+	if err != nil {
+		writeErrorResponse(w, "project must be a project ID", http.StatusBadRequest)
+		return
+	}
+
+	var week *time.Time
+	if weekStr := query.Get("week"); weekStr != "" {
+		t, err := time.Parse("2006-01-02", weekStr)
+		if err != nil {
+			writeErrorResponse(w, "week must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		week = &t
+	}
+
+	insights, err := h.analyticsService.GetInsights(r.Context(), projectID, week, query.Get("tz"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInsightsWeek) || errors.Is(err, services.ErrInvalidTimezone) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeErrorResponse(w, "Failed to get insights", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, insights)
+}
+
 func (h *AnalyticsHandler) GetPerformanceMetrics(w http.ResponseWriter, r *http.Request) {
+	etag := h.etag(r, performanceETagPeriod)
+	if notModified(w, r, etag) {
+		return
+	}
+
 	metrics, err := h.analyticsService.GetPerformanceMetrics(r.Context())
 	if err != nil {
 		writeErrorResponse(w, "Failed to get performance metrics", http.StatusInternalServerError)
 		return
 	}
 
+	setETag(w, etag)
 	writeSuccessResponse(w, metrics)
 }
 
+// etag returns the response ETag, or "" when the cache generation is unavailable
+func (h *AnalyticsHandler) etag(r *http.Request, period time.Duration) string {
+	generation, err := h.analyticsService.CacheGeneration(r.Context())
+	if err != nil {
+		log.Printf("Failed to get cache generation: %v", err)
+		return ""
+	}
+	return responseETag(r, generation, period)
+}
+
 // Helper functions
 func writeSuccessResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -58,12 +169,21 @@ func writeSuccessResponse(w http.ResponseWriter, data interface{}) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// writeErrorResponse answers with the generic code for the status; use
+// writeError where clients need to tell failures with the same status apart
 func writeErrorResponse(w http.ResponseWriter, message string, statusCode int) {
+	writeError(w, statusErrorCode(statusCode), message, statusCode)
+}
+
+func writeError(w http.ResponseWriter, code, message string, statusCode int, details ...models.FieldError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	response := models.APIResponse{
-		Error:  message,
-		Status: "error",
+		Error:     message,
+		Status:    "error",
+		Code:      code,
+		Details:   details,
+		RequestID: w.Header().Get(requestIDHeader),
 	}
 	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type AnnotationHandler struct {
+	annotationService *services.AnnotationService
+}
+
+func NewAnnotationHandler(annotationService *services.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{
+		annotationService: annotationService,
+	}
+}
+
+func (h *AnnotationHandler) GetAnnotations(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePage(r, 50, 500)
+
+	query := r.URL.Query()
+	filter := models.AnnotationFilter{
+		Type: query.Get("type"),
+	}
+	if projectStr := query.Get("project_id"); projectStr != "" {
+		projectID, err := uuid.Parse(projectStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid project_id", http.StatusBadRequest)
+			return
+		}
+		filter.ProjectID = &projectID
+	}
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			writeErrorResponse(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.Since = &since
+	}
+	if untilStr := query.Get("until"); untilStr != "" {
+		until, err := time.Parse(time.RFC3339, untilStr)
+		if err != nil {
+			writeErrorResponse(w, "until must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.Until = &until
+	}
+
+	annotations, total, err := h.annotationService.GetAnnotations(r.Context(), limit, offset, filter)
+	if err != nil {
+		writeAnnotationError(w, err, "Failed to get annotations")
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"annotations": annotations,
+		"pagination":  newPagination(r, total, limit, offset),
+	})
+}
+
+func (h *AnnotationHandler) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	annotation, err := h.annotationService.CreateAnnotation(r.Context(), &req, requestActor(r, ""))
+	if err != nil {
+		writeAnnotationError(w, err, "Failed to create annotation")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, annotation)
+}
+
+func (h *AnnotationHandler) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid annotation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.annotationService.DeleteAnnotation(r.Context(), id, requestActor(r, "")); err != nil {
+		writeAnnotationError(w, err, "Failed to delete annotation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordDeploy is the deploy webhook for CI: it annotates trend charts with
+// the release that went out
+func (h *AnnotationHandler) RecordDeploy(w http.ResponseWriter, r *http.Request) {
+	var req models.DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	annotation, err := h.annotationService.RecordDeploy(r.Context(), &req, requestActor(r, ""))
+	if err != nil {
+		writeAnnotationError(w, err, "Failed to record deploy")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, annotation)
+}
+
+func writeAnnotationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAnnotation):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == "project not found":
+		writeErrorResponse(w, "Project not found", http.StatusNotFound)
+	case err.Error() == "annotation not found":
+		writeErrorResponse(w, "Annotation not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+const (
+	// maxResolutionNoteLength bounds resolve and unresolve notes
+	maxResolutionNoteLength = 2000
+
+	// maxActorLength matches audit_log.actor and errors.resolved_by
+	maxActorLength = 255
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, offset := parsePage(r, 50, 500)
+
+	filter := models.AuditLogFilter{
+		Action:     query.Get("action"),
+		Actor:      query.Get("actor"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	if wantsNDJSON(r) {
+		h.streamAuditLog(w, r, filter)
+		return
+	}
+
+	response, err := h.auditService.GetAuditLog(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+	response.Pagination = newPagination(r, response.Total, limit, offset)
+
+	writeSuccessResponse(w, response)
+}
+
+// streamAuditLog writes every matching entry as a line of JSON, newest first,
+// ignoring limit and offset
+func (h *AuditHandler) streamAuditLog(w http.ResponseWriter, r *http.Request, filter models.AuditLogFilter) {
+	stream := newNDJSONStream(w)
+	err := h.auditService.StreamAuditLog(r.Context(), filter, func(entry *models.AuditLogEntry) error {
+		return stream.Write(entry)
+	})
+	if err == nil {
+		stream.Close()
+		return
+	}
+	if stream.Started() {
+		log.Printf("Audit log stream failed: %v", err)
+		stream.Fail("Failed to get audit log")
+		return
+	}
+	writeErrorResponse(w, "Failed to get audit log", http.StatusInternalServerError)
+}
+
+// requestActor identifies who made a change: the name given in the request,
+// or else the API key that authenticated it
+func requestActor(r *http.Request, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		return "api_key:" + key.Name
+	}
+	return "unknown"
+}
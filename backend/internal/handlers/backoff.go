@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"error-logs/internal/models"
+)
+
+// clientBackoffHeader is the backoff handshake. An SDK that sends it, with any
+// value, gets the current hint on every ingestion response, including
+// "retry-after=0; sample-rate=1" once load is back to normal so it knows when
+// to recover. Refused and throttled requests carry the hint in their body
+// either way.
+const clientBackoffHeader = "X-Client-Backoff"
+
+// noBackoff is the hint while ingestion is not shedding load
+var noBackoff = func() models.Backoff {
+	rate := 1.0
+	return models.Backoff{SampleRate: &rate}
+}()
+
+// writeShed refuses an ingestion request to shed load, telling the client
+// when to retry and how much to send meanwhile
+func writeShed(w http.ResponseWriter, r *http.Request, code, message string, statusCode int, backoff models.Backoff) {
+	w.Header().Set("Retry-After", strconv.Itoa(backoff.RetryAfter))
+	setClientBackoff(w, r, &backoff)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(models.APIResponse{
+		Error:     message,
+		Status:    "error",
+		Code:      code,
+		RequestID: w.Header().Get(requestIDHeader),
+		Backoff:   &backoff,
+	})
+}
+
+// writeThrottled accepts an event that spike protection sampled out
+func writeThrottled(w http.ResponseWriter, r *http.Request, backoff models.Backoff) {
+	setClientBackoff(w, r, &backoff)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.APIResponse{
+		Data:    map[string]string{"status": "throttled"},
+		Status:  "success",
+		Backoff: &backoff,
+	})
+}
+
+// setClientBackoff sends the hint to SDKs that asked for it; nil means there
+// is no load to shed
+func setClientBackoff(w http.ResponseWriter, r *http.Request, backoff *models.Backoff) {
+	if r.Header.Get(clientBackoffHeader) == "" {
+		return
+	}
+	if backoff == nil {
+		backoff = &noBackoff
+	}
+
+	value := fmt.Sprintf("retry-after=%d", backoff.RetryAfter)
+	if backoff.SampleRate != nil {
+		value += "; sample-rate=" + strconv.FormatFloat(*backoff.SampleRate, 'f', -1, 64)
+	}
+	w.Header().Set(clientBackoffHeader, value)
+}
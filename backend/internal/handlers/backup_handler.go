@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+// BackupHandler saves and restores the deployment's configuration. Backups
+// hold every organization's settings and secrets, so only deployment admins
+// may use it.
+type BackupHandler struct {
+	backupService *services.BackupService
+}
+
+func NewBackupHandler(backupService *services.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// CreateBackup downloads the configuration sealed with the passphrase in the
+// body. The file is the backup itself, not wrapped in a response envelope.
+func (h *BackupHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.CreateBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	sealed, err := h.backupService.CreateBackup(r.Context(), req.Passphrase, requestActor(r, ""))
+	if err != nil {
+		writeBackupError(w, err, "Failed to create backup")
+		return
+	}
+
+	fileName := fmt.Sprintf("error-logs-backup-%s.json", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Write(sealed)
+}
+
+func (h *BackupHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.RestoreBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if len(req.Backup) == 0 {
+		writeErrorResponse(w, "backup is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.backupService.RestoreBackup(r.Context(), req.Backup, req.Passphrase, requestActor(r, ""))
+	if err != nil {
+		writeBackupError(w, err, "Failed to restore backup")
+		return
+	}
+
+	writeSuccessResponse(w, result)
+}
+
+func writeBackupError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, services.ErrInvalidBackup) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeErrorResponse(w, message, http.StatusInternalServerError)
+}
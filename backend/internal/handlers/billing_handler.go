@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type BillingHandler struct {
+	billingService *services.BillingService
+}
+
+func NewBillingHandler(billingService *services.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+	}
+}
+
+// GetOrgUsage returns per-organization usage for metering. The range defaults
+// to the current UTC month up to today.
+func (h *BillingHandler) GetOrgUsage(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+	query := r.URL.Query()
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, param := range []struct {
+		name string
+		date *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if dateStr := query.Get(param.name); dateStr != "" {
+			t, err := time.Parse("2006-01-02", dateStr)
+			if err != nil {
+				writeErrorResponse(w, param.name+" must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+				return
+			}
+			*param.date = t
+		}
+	}
+
+	var orgID *uuid.UUID
+	if orgStr := query.Get("org_id"); orgStr != "" {
+		id, err := uuid.Parse(orgStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+		orgID = &id
+	}
+
+	usage, err := h.billingService.GetOrgUsage(r.Context(), orgID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUsageRange) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else if err.Error() == "organization not found" {
+			writeErrorResponse(w, "Organization not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get usage", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"organizations": usage,
+	})
+}
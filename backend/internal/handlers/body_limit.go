@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BodyLimits are the request body caps, in bytes, applied by BodyLimitMiddleware
+type BodyLimits struct {
+	Default int64 // any route not listed below
+	Event   int64 // single error events
+	Batch   int64 // log, metric and session batches
+	Import  int64 // exports from other error trackers, and backups
+
+	// Minidump and DebugFile are the upload caps the minidump and debug
+	// file handlers enforce themselves; SignatureMiddleware applies them
+	// when it reads a signed upload
+	Minidump  int64
+	DebugFile int64
+}
+
+// bodyLimitFor picks the cap for a request. Upload endpoints return 0 and
+// enforce their own (larger or format-specific) limits in the handler.
+func (l BodyLimits) bodyLimitFor(r *http.Request) int64 {
+	if r.Method != http.MethodPost {
+		return l.Default
+	}
+	if route, ok := ingestionRoutes[r.URL.Path]; ok {
+		switch route.body {
+		case eventBody:
+			return l.Event
+		case batchBody:
+			return l.Batch
+		case uploadBody:
+			return 0
+		}
+		return l.Default
+	}
+	switch r.URL.Path {
+	case "/api/import", "/api/admin/restore":
+		return l.Import
+	case "/api/debug-files", "/api/debug-files/":
+		return 0
+	}
+	return l.Default
+}
+
+// uploadLimitFor is the cap of an upload endpoint, for which bodyLimitFor
+// returns 0
+func (l BodyLimits) uploadLimitFor(r *http.Request) int64 {
+	switch r.URL.Path {
+	case "/api/minidump":
+		return l.Minidump
+	case "/api/csp-reports":
+		return maxCSPReportBytes
+	}
+	return l.DebugFile
+}
+
+// BodyLimitMiddleware caps request bodies so an oversized payload is rejected
+// with 413 instead of being read into memory. Requests that declare a larger
+// Content-Length are refused before the body is touched.
+func BodyLimitMiddleware(limits BodyLimits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			maxBytes := limits.bodyLimitFor(r)
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeDecodeError reports a failed JSON body decode, as 413 when the body
+// hit its size limit and 400 otherwise
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	writeError(w, codeInvalidJSON, "Invalid JSON", http.StatusBadRequest)
+}
+
+// decodeBatch streams the array under field of a JSON object body, calling
+// each to decode every element in turn. It stops after maxItems+1 elements so
+// an oversized batch is left for the service to reject without the rest of
+// the body being read. Other fields of the object are skipped.
+func decodeBatch(body io.Reader, field string, maxItems int, each func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := token.(string); key != field {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		token, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			continue // null
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("%s must be an array", field)
+		}
+		for n := 0; dec.More(); n++ {
+			if n > maxItems {
+				return nil
+			}
+			if err := each(dec); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token()
+	return err
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q", want)
+	}
+	return nil
+}
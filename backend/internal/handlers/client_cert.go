@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// clientCertEndpoints are the ingestion endpoints that require a client
+// certificate when mutual TLS is enabled
+var clientCertEndpoints = ingestionPaths(func(route ingestionRoute) bool { return route.clientCert })
+
+// ClientCertMiddleware requires a verified TLS client certificate for
+// ingestion requests made with secret API keys. Public keys are used from
+// browsers and client apps, which cannot hold a certificate, and pass
+// through. The server only verifies certificates against TLS_CLIENT_CA_FILE;
+// this middleware decides where one is required.
+func ClientCertMiddleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimSuffix(r.URL.Path, "/")
+			if r.Method != http.MethodPost || !clientCertEndpoints[path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if key := apiKeyFromContext(r.Context()); key != nil && key.KeyType == "public" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				writeError(w, codeClientCertRequired, "Client certificate required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
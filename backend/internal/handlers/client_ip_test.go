@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{"direct client", "203.0.113.5:4000", nil, "", "203.0.113.5"},
+		{"direct client forging headers", "203.0.113.5:4000", []string{"1.2.3.4"}, "5.6.7.8", "203.0.113.5"},
+		{"trusted proxy", "10.1.2.3:4000", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"trusted single address", "192.0.2.7:4000", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"spoofed hop before the proxy", "10.1.2.3:4000", []string{"1.2.3.4, 198.51.100.9"}, "", "198.51.100.9"},
+		{"chain of trusted proxies", "10.1.2.3:4000", []string{"198.51.100.9, 10.9.9.9", "10.8.8.8"}, "", "198.51.100.9"},
+		{"only proxies", "10.1.2.3:4000", []string{"10.9.9.9"}, "", "10.9.9.9"},
+		{"garbage hop", "10.1.2.3:4000", []string{"not-an-ip"}, "", "10.1.2.3"},
+		{"X-Real-IP from a trusted proxy", "10.1.2.3:4000", nil, "198.51.100.9", "198.51.100.9"},
+		{"IPv6 proxy", "[2001:db8::1]:4000", []string{"2001:db9::5"}, "", "2001:db9::5"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/errors", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, value := range tt.xff {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if tt.xRealIP != "" {
+			r.Header.Set("X-Real-IP", tt.xRealIP)
+		}
+		if got := clientIP(r, trusted); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/errors", nil)
+	r.RemoteAddr = "203.0.113.5:4000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := clientIP(r, nil); got != "203.0.113.5" {
+		t.Errorf("clientIP = %q, want the peer", got)
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := ParseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) accepted an invalid entry", entry)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type ClusteringHandler struct {
+	clusteringService *services.ClusteringService
+}
+
+func NewClusteringHandler(clusteringService *services.ClusteringService) *ClusteringHandler {
+	return &ClusteringHandler{clusteringService: clusteringService}
+}
+
+// GetClusters lists error groups that look like the same root cause
+func (h *ClusteringHandler) GetClusters(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePage(r, 20, 100)
+
+	var projectID *uuid.UUID
+	if project := r.URL.Query().Get("project"); project != "" {
+		id, err := uuid.Parse(project)
+		if err != nil {
+			writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+		projectID = &id
+	}
+
+	response, err := h.clusteringService.GetClusters(r.Context(), limit, offset, projectID)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get error clusters", http.StatusInternalServerError)
+		return
+	}
+	response.Pagination = newPagination(r, response.Total, limit, offset)
+
+	writeSuccessResponse(w, response)
+}
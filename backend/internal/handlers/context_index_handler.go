@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+var contextKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,100}$`)
+
+type ContextIndexHandler struct {
+	contextIndexService *services.ContextIndexService
+}
+
+func NewContextIndexHandler(contextIndexService *services.ContextIndexService) *ContextIndexHandler {
+	return &ContextIndexHandler{
+		contextIndexService: contextIndexService,
+	}
+}
+
+func (h *ContextIndexHandler) GetIndexedFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.contextIndexService.GetIndexedFields(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get indexed fields", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"fields": fields})
+}
+
+func (h *ContextIndexHandler) CreateIndexedField(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateIndexedContextFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if !contextKeyPattern.MatchString(req.Key) {
+		writeErrorResponse(w, "Key must be 1-100 letters, digits, '_' or '-'", http.StatusBadRequest)
+		return
+	}
+
+	field, err := h.contextIndexService.AddIndexedField(r.Context(), req.Key)
+	if err != nil {
+		writeErrorResponse(w, "Failed to create indexed field", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, field)
+}
+
+func (h *ContextIndexHandler) DeleteIndexedField(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	if !contextKeyPattern.MatchString(key) {
+		writeErrorResponse(w, "Invalid key", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.contextIndexService.RemoveIndexedField(r.Context(), key); err != nil {
+		writeErrorResponse(w, "Failed to delete indexed field", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type ContextSchemaHandler struct {
+	contextSchemaService *services.ContextSchemaService
+}
+
+func NewContextSchemaHandler(contextSchemaService *services.ContextSchemaService) *ContextSchemaHandler {
+	return &ContextSchemaHandler{
+		contextSchemaService: contextSchemaService,
+	}
+}
+
+func (h *ContextSchemaHandler) GetContextSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := h.contextSchemaService.GetSchema(r.Context(), projectID)
+	if err != nil {
+		if err.Error() == "context schema not found" {
+			writeErrorResponse(w, "Context schema not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get context schema", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, schema)
+}
+
+func (h *ContextSchemaHandler) UpdateContextSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateContextSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	schema, err := h.contextSchemaService.SetSchema(r.Context(), projectID, &req)
+	if errors.Is(err, services.ErrInvalidContextSchema) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to update context schema", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, schema)
+}
+
+func (h *ContextSchemaHandler) DeleteContextSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.contextSchemaService.DeleteSchema(r.Context(), projectID); err != nil {
+		if err.Error() == "context schema not found" {
+			writeErrorResponse(w, "Context schema not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to delete context schema", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ContextSchemaHandler) GetConformance(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	days := 7 // default
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 90 {
+			days = d
+		}
+	}
+
+	report, err := h.contextSchemaService.GetConformance(r.Context(), projectID, days)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get context conformance", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, report)
+}
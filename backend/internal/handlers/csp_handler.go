@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"error-logs/internal/services"
+)
+
+// maxCSPReportBytes caps report bodies; report-to batches several violations
+const maxCSPReportBytes = 256 << 10
+
+type CSPHandler struct {
+	cspService   *services.CSPService
+	quotaService *services.QuotaService
+}
+
+func NewCSPHandler(cspService *services.CSPService, quotaService *services.QuotaService) *CSPHandler {
+	return &CSPHandler{
+		cspService:   cspService,
+		quotaService: quotaService,
+	}
+}
+
+// IngestReports accepts CSP violation reports sent by browsers through
+// report-uri (application/csp-report) or report-to (application/reports+json)
+func (h *CSPHandler) IngestReports(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Report too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Failed to read report", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	key := apiKeyFromContext(r.Context())
+	accepted, err := h.cspService.IngestReports(r.Context(), body, key,
+		query.Get("environment"), query.Get("release"), r.Header.Get("User-Agent"), getClientIP(r))
+	if errors.Is(err, services.ErrInvalidCSPReport) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *key.ProjectID))
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record CSP reports", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
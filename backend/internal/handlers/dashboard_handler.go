@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type DashboardHandler struct {
+	dashboardService *services.DashboardService
+}
+
+func NewDashboardHandler(dashboardService *services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{
+		dashboardService: dashboardService,
+	}
+}
+
+func (h *DashboardHandler) GetDashboards(w http.ResponseWriter, r *http.Request) {
+	dashboards, err := h.dashboardService.GetDashboards(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get dashboards", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, dashboards)
+}
+
+func (h *DashboardHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid dashboard ID", http.StatusBadRequest)
+		return
+	}
+
+	dashboard, err := h.dashboardService.GetDashboard(r.Context(), id)
+	if err != nil {
+		if err.Error() == "dashboard not found" {
+			writeErrorResponse(w, "Dashboard not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get dashboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, dashboard)
+}
+
+func (h *DashboardHandler) CreateDashboard(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDashboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	dashboard, err := h.dashboardService.CreateDashboard(r.Context(), &req)
+	if errors.Is(err, services.ErrInvalidDashboard) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to create dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, dashboard)
+}
+
+func (h *DashboardHandler) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid dashboard ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateDashboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	dashboard, err := h.dashboardService.UpdateDashboard(r.Context(), id, &req)
+	if errors.Is(err, services.ErrInvalidDashboard) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "dashboard not found" {
+			writeErrorResponse(w, "Dashboard not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to update dashboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, dashboard)
+}
+
+func (h *DashboardHandler) UpdateLayout(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid dashboard ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateDashboardLayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	dashboard, err := h.dashboardService.UpdateLayout(r.Context(), id, &req)
+	if errors.Is(err, services.ErrInvalidDashboard) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "dashboard not found" {
+			writeErrorResponse(w, "Dashboard not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to update dashboard layout", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, dashboard)
+}
+
+func (h *DashboardHandler) DeleteDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid dashboard ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dashboardService.DeleteDashboard(r.Context(), id); err != nil {
+		if err.Error() == "dashboard not found" {
+			writeErrorResponse(w, "Dashboard not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete dashboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DashboardHandler) GetDashboardData(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid dashboard ID", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.dashboardService.GetDashboardData(r.Context(), id)
+	if err != nil {
+		if err.Error() == "dashboard not found" {
+			writeErrorResponse(w, "Dashboard not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get dashboard data", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, data)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type DebugFileHandler struct {
+	symbolicationService *services.SymbolicationService
+	maxUploadBytes       int64
+}
+
+func NewDebugFileHandler(symbolicationService *services.SymbolicationService, maxUploadMB int) *DebugFileHandler {
+	return &DebugFileHandler{
+		symbolicationService: symbolicationService,
+		maxUploadBytes:       int64(maxUploadMB) << 20,
+	}
+}
+
+func (h *DebugFileHandler) GetDebugFiles(w http.ResponseWriter, r *http.Request) {
+	files, err := h.symbolicationService.GetDebugFiles(r.Context(), r.URL.Query().Get("release"))
+	if err != nil {
+		writeErrorResponse(w, "Failed to get debug files", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"debug_files": files})
+}
+
+// UploadDebugFile accepts a multipart form with "file", "kind", "release", and
+// for dSYMs the binary "name" (defaults to the uploaded file name)
+func (h *DebugFileHandler) UploadDebugFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Debug file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "A multipart \"file\" field is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	name := r.FormValue("name")
+	if name == "" {
+		name = header.Filename
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	debugFile, err := h.symbolicationService.UploadDebugFile(r.Context(), r.FormValue("kind"), r.FormValue("release"), name, projectID, file)
+	if errors.Is(err, services.ErrInvalidDebugFile) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to store debug file", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, debugFile)
+}
+
+func (h *DebugFileHandler) DeleteDebugFile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid debug file ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.symbolicationService.DeleteDebugFile(r.Context(), id)
+	if err != nil {
+		if err.Error() == "debug file not found" {
+			writeErrorResponse(w, "Debug file not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete debug file", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestIDHeader echoes the ID chi's RequestID middleware gave the request,
+// which is also logged, so a failed call can be traced in the server logs
+const requestIDHeader = "X-Request-ID"
+
+// Error codes for failures that share a status but need different handling by
+// clients. Other failures carry the generic code for their status.
+const (
+	codeInvalidJSON            = "invalid_json"
+	codeValidationFailed       = "validation_failed"
+	codeInvalidLevel           = "invalid_level"
+	codeFieldNotIndexed        = "field_not_indexed"
+	codeAPIKeyRequired         = "api_key_required"
+	codeInvalidAPIKey          = "invalid_api_key"
+	codeSecretKeyInQuery       = "secret_key_in_query"
+	codeInvalidSignature       = "invalid_signature"
+	codeClientCertRequired     = "client_certificate_required"
+	codePublicKeyNotAllowed    = "public_key_not_allowed"
+	codeOrgKeyNotAllowed       = "org_key_not_allowed"
+	codePermissionNotGrantable = "permission_not_grantable"
+	codeOriginNotAllowed       = "origin_not_allowed"
+	codeRateLimited            = "rate_limited"
+	codeQuotaExceeded          = "quota_exceeded"
+	codeBodyTooLarge           = "body_too_large"
+	codeReadOnly               = "read_only"
+	codeIngestionUnavailable   = "ingestion_unavailable"
+	codeSummariesDisabled      = "summaries_disabled"
+)
+
+// Codes for rejected request fields
+const (
+	fieldRequired = "required"
+	fieldTooLong  = "too_long"
+	fieldInvalid  = "invalid"
+)
+
+// statusErrorCode is the generic code for an HTTP error status
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusGone:
+		return "gone"
+	case http.StatusPreconditionFailed:
+		return "precondition_failed"
+	case http.StatusRequestEntityTooLarge:
+		return codeBodyTooLarge
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	}
+	if statusCode >= 500 {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// RequestIDHeaderMiddleware returns the request ID in the X-Request-ID response
+// header. It must run after middleware.RequestID.
+func RequestIDHeaderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(requestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
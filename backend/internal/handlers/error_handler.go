@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -19,22 +23,49 @@ import (
 )
 
 type ErrorHandler struct {
-	errorService *services.ErrorService
+	errorService     *services.ErrorService
+	quotaService     *services.QuotaService
+	publicKeyService *services.PublicKeyService
 }
 
-func NewErrorHandler(errorService *services.ErrorService) *ErrorHandler {
+func NewErrorHandler(errorService *services.ErrorService, quotaService *services.QuotaService, publicKeyService *services.PublicKeyService) *ErrorHandler {
 	return &ErrorHandler{
-		errorService: errorService,
+		errorService:     errorService,
+		quotaService:     quotaService,
+		publicKeyService: publicKeyService,
 	}
 }
 
-// APIKeyMiddleware validates API keys
-func APIKeyMiddleware(db *database.DB) func(next http.Handler) http.Handler {
+type contextKey string
+
+const apiKeyContextKey contextKey = "api_key"
+
+// apiKeyFromContext returns the API key that authenticated the request, if any
+func apiKeyFromContext(ctx context.Context) *models.APIKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return apiKey
+}
+
+// APIKeyMiddleware validates API keys. Keys validated earlier are remembered
+// in known, so events can still be accepted into the queue while the database
+// is unavailable.
+func APIKeyMiddleware(db *database.DB, known *KnownAPIKeys) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			apiKey := r.Header.Get("X-API-Key")
 			if apiKey == "" {
-				http.Error(w, "API key required", http.StatusUnauthorized)
+				// Log drains cannot set headers, but can put credentials in
+				// the drain URL, which arrive as basic auth
+				_, apiKey, _ = r.BasicAuth()
+			}
+			fromQuery := false
+			if apiKey == "" {
+				// Browser SDKs may pass their public key in the URL
+				apiKey = r.URL.Query().Get("api_key")
+				fromQuery = true
+			}
+			if apiKey == "" {
+				writeError(w, codeAPIKeyRequired, "API key required", http.StatusUnauthorized)
 				return
 			}
 
@@ -42,81 +73,317 @@ func APIKeyMiddleware(db *database.DB) func(next http.Handler) http.Handler {
 			hash := sha256.Sum256([]byte(apiKey))
 			keyHash := fmt.Sprintf("%x", hash)
 
-			_, err := db.ValidateAPIKey(keyHash)
+			key, err := db.ValidateAPIKey(keyHash)
+			if err != nil && database.IsUnavailable(err) {
+				cached, ok := known.load(keyHash)
+				if !ok {
+					writeErrorResponse(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				key, err = cached, nil
+			}
 			if err != nil {
-				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				known.delete(keyHash)
+				writeError(w, codeInvalidAPIKey, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			known.store(keyHash, key)
+			if fromQuery && key.KeyType != "public" {
+				writeError(w, codeSecretKeyInQuery, "Secret API keys must be sent in the X-API-Key header", http.StatusUnauthorized)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// Match the errors.region and errors.deployment columns
+const (
+	maxRegionLength     = 50
+	maxDeploymentLength = 100
+)
+
+// validateEvent lists every field of an error event that cannot be stored
+func validateEvent(req *models.CreateErrorRequest) []models.FieldError {
+	var details []models.FieldError
+	if req.Message == "" {
+		details = append(details, models.FieldError{Field: "message", Code: fieldRequired, Message: "Message is required"})
+	}
+	if req.Region != nil && len(*req.Region) > maxRegionLength {
+		details = append(details, models.FieldError{Field: "region", Code: fieldTooLong,
+			Message: fmt.Sprintf("Region must be at most %d characters", maxRegionLength)})
+	}
+	if req.Deployment != nil && len(*req.Deployment) > maxDeploymentLength {
+		details = append(details, models.FieldError{Field: "deployment", Code: fieldTooLong,
+			Message: fmt.Sprintf("Deployment must be at most %d characters", maxDeploymentLength)})
+	}
+	return details
+}
+
 func (h *ErrorHandler) CreateError(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateErrorRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	// Validate required fields
-	if req.Message == "" {
-		writeErrorResponse(w, "Message is required", http.StatusBadRequest)
-		return
-	}
 	if req.Level == "" {
 		req.Level = "error"
 	}
 	if req.Source == "" {
 		req.Source = "unknown"
 	}
+	if !slices.Contains(services.EventLevels, req.Level) {
+		writeError(w, codeInvalidLevel, "level must be one of "+strings.Join(services.EventLevels, ", "), http.StatusBadRequest,
+			models.FieldError{Field: "level", Code: fieldInvalid, Message: "unknown level " + strconv.Quote(req.Level)})
+		return
+	}
+	if details := validateEvent(&req); len(details) > 0 {
+		writeError(w, codeValidationFailed, details[0].Message, http.StatusBadRequest, details...)
+		return
+	}
 
 	// Extract client info
 	userAgent := r.Header.Get("User-Agent")
 	ipAddress := getClientIP(r)
 
-	error, err := h.errorService.CreateError(r.Context(), &req, userAgent, ipAddress)
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+
+		// Drop bot traffic and browser noise from public keys without telling the sender
+		if key.KeyType == "public" {
+			if reason := h.publicKeyService.FilterReason(&req, userAgent); reason != "" {
+				log.Printf("EVENT FILTERED: public key %s from %s - %s", key.ID, ipAddress, reason)
+				w.WriteHeader(http.StatusAccepted)
+				writeSuccessResponse(w, map[string]string{"status": "filtered"})
+				return
+			}
+		}
+	}
+
+	// Enforce project quotas before accepting the event
+	if projectID != nil {
+		warn, err := h.quotaService.RecordEvent(r.Context(), *projectID)
+		if err == services.ErrQuotaExceeded {
+			writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+			return
+		}
+		if warn {
+			w.Header().Set("X-Quota-Warning", "soft limit reached")
+		}
+	}
+
+	error, err := h.errorService.CreateError(r.Context(), &req, projectID, userAgent, ipAddress)
+	if err == services.ErrEventThrottled {
+		// Accepted but sampled out by spike protection
+		writeThrottled(w, r, h.errorService.ThrottledBackoff())
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to create error", http.StatusInternalServerError)
 		return
 	}
+	backoff := h.errorService.QueueBackoff()
+	if backoff != nil {
+		w.Header().Set("X-Queue-Warning", "ingestion queue nearly full")
+	}
+	setClientBackoff(w, r, backoff)
 
 	w.WriteHeader(http.StatusCreated)
 	writeSuccessResponse(w, error)
 }
 
 func (h *ErrorHandler) GetErrors(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	level := r.URL.Query().Get("level")
-	source := r.URL.Query().Get("source")
+	limit, offset := parsePage(r, 50, 100)
 
-	limit := 50 // default
-	offset := 0 // default
+	fields, err := parseFields(r, errorFields)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	filter := parseErrorFilter(r)
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "", "newest":
+	case "impact":
+		filter.Sort = sort
+	default:
+		writeErrorResponse(w, "sort must be newest or impact", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("watched") == "true" {
+		memberID, err := uuid.Parse(r.URL.Query().Get("member_id"))
+		if err != nil {
+			writeErrorResponse(w, "watched=true requires a valid member_id", http.StatusBadRequest)
+			return
 		}
+		filter.WatchedBy = &memberID
 	}
-
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+	if err := services.CheckErrorState(filter.State); err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Message = r.URL.Query().Get("message")
+	filter.MessageMatch = r.URL.Query().Get("message_match")
+	filter.MessageNegate = r.URL.Query().Get("message_negate") == "true"
+	if query := r.URL.Query().Get("query"); query != "" {
+		if filter.Query, err = services.ParseSearchQuery(query); err != nil {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 	}
 
-	response, err := h.errorService.GetErrors(r.Context(), limit, offset, level, source)
+	etag := h.etag(r, 0)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	response, err := h.errorService.GetErrors(r.Context(), limit, offset, filter)
+	if errors.Is(err, services.ErrFieldNotIndexed) {
+		writeError(w, codeFieldNotIndexed, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidMessageFilter) || errors.Is(err, services.ErrMessageRegexTimeout) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to get errors", http.StatusInternalServerError)
 		return
 	}
+	response.Query = filter.Query
+	response.Pagination = newPagination(r, response.Total, limit, offset)
+
+	if fields == nil {
+		setETag(w, etag)
+		writeSuccessResponse(w, response)
+		return
+	}
+
+	sparse, err := selectFields(response.Errors, fields)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get errors", http.StatusInternalServerError)
+		return
+	}
+	setETag(w, etag)
+	writeSuccessResponse(w, sparseErrorList{
+		Errors:     sparse,
+		Total:      response.Total,
+		Page:       response.Page,
+		Limit:      response.Limit,
+		Degraded:   response.Degraded,
+		Query:      response.Query,
+		Pagination: response.Pagination,
+	})
+}
+
+// GetErrorUpdates returns errors created or updated after the since cursor,
+// long-polling for up to wait seconds when there are none
+func (h *ErrorHandler) GetErrorUpdates(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	var wait time.Duration
+	if waitStr := r.URL.Query().Get("wait"); waitStr != "" {
+		seconds, err := strconv.Atoi(waitStr)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > services.MaxErrorUpdatesWait {
+			writeErrorResponse(w, "wait must be between 0 and 30 seconds", http.StatusBadRequest)
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	response, err := h.errorService.GetErrorUpdates(r.Context(), r.URL.Query().Get("since"), parseErrorFilter(r), limit, wait)
+	if errors.Is(err, services.ErrFieldNotIndexed) {
+		writeError(w, codeFieldNotIndexed, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidCursor) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get error updates", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
+
+func (h *ErrorHandler) GetErrorFacets(w http.ResponseWriter, r *http.Request) {
+	facets, err := h.errorService.GetErrorFacets(r.Context(), parseErrorFilter(r))
+	if errors.Is(err, services.ErrFieldNotIndexed) {
+		writeError(w, codeFieldNotIndexed, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get error facets", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, facets)
+}
+
+func (h *ErrorHandler) GetErrorsByTrace(w http.ResponseWriter, r *http.Request) {
+	traceID := chi.URLParam(r, "traceId")
+	if traceID == "" || len(traceID) > 128 {
+		writeErrorResponse(w, "Invalid trace ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.errorService.GetErrorsByTraceID(r.Context(), traceID, requestOrgID(r))
+	if err != nil {
+		writeErrorResponse(w, "Failed to get errors for trace", http.StatusInternalServerError)
+		return
+	}
 
 	writeSuccessResponse(w, response)
 }
 
+// parseErrorFilter reads the list filters shared by the error endpoints.
+// Organization-scoped keys only see the errors of their organization.
+func parseErrorFilter(r *http.Request) models.ErrorFilter {
+	query := r.URL.Query()
+	filter := models.ErrorFilter{
+		Level:       query.Get("level"),
+		Source:      query.Get("source"),
+		Environment: query.Get("environment"),
+		Status:      query.Get("status"),
+		State:       query.Get("state"),
+		Region:      query.Get("region"),
+		Deployment:  query.Get("deployment"),
+		Search:      query.Get("search"),
+		Fuzzy:       query.Get("fuzzy") == "true",
+		OrgID:       requestOrgID(r),
+	}
+
+	// context.<key>=<value> filters on indexed context fields
+	for param, values := range query {
+		if key, ok := strings.CutPrefix(param, "context."); ok && key != "" && len(values) > 0 {
+			if filter.Context == nil {
+				filter.Context = make(map[string]string)
+			}
+			filter.Context[key] = values[0]
+		}
+	}
+
+	return filter
+}
+
 func (h *ErrorHandler) GetError(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -146,15 +413,90 @@ func (h *ErrorHandler) ResolveError(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.errorService.ResolveError(r.Context(), id)
+	// The body is optional; an empty body resolves immediately
+	var req models.ResolveErrorRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeDecodeError(w, err)
+			return
+		}
+	}
+
+	if len(req.Note) > maxResolutionNoteLength || len(req.ResolvedBy) > maxActorLength {
+		writeErrorResponse(w, "Note or resolved_by is too long", http.StatusBadRequest)
+		return
+	}
+	actor := requestActor(r, req.ResolvedBy)
+
+	switch req.Mode {
+	case "", "now":
+		err = h.errorService.ResolveError(r.Context(), id, actor, req.Note)
+	case "in_release":
+		if req.Release == "" {
+			writeErrorResponse(w, "Release is required", http.StatusBadRequest)
+			return
+		}
+		err = h.errorService.ResolveErrorInRelease(r.Context(), id, req.Release, requestOrgID(r), actor, req.Note)
+	default:
+		writeErrorResponse(w, "Invalid resolve mode", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		writeErrorResponse(w, "Failed to resolve error", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, services.ErrInvalidStateTransition):
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		case err.Error() == "error not found":
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		default:
+			writeErrorResponse(w, "Failed to resolve error", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	if req.Mode == "in_release" {
+		writeSuccessResponse(w, map[string]string{"status": "resolved", "resolved_in_release": req.Release})
+		return
+	}
 	writeSuccessResponse(w, map[string]string{"status": "resolved"})
 }
 
+func (h *ErrorHandler) UnresolveError(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional
+	var req models.UnresolveErrorRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeDecodeError(w, err)
+			return
+		}
+	}
+	if len(req.Note) > maxResolutionNoteLength || len(req.UnresolvedBy) > maxActorLength {
+		writeErrorResponse(w, "Note or unresolved_by is too long", http.StatusBadRequest)
+		return
+	}
+
+	err = h.errorService.UnresolveError(r.Context(), id, requestOrgID(r), requestActor(r, req.UnresolvedBy), req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidStateTransition):
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		case err.Error() == "error not found":
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		default:
+			writeErrorResponse(w, "Failed to unresolve error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, map[string]string{"status": "unresolved"})
+}
+
 func (h *ErrorHandler) DeleteError(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -163,17 +505,302 @@ func (h *ErrorHandler) DeleteError(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.errorService.DeleteError(r.Context(), id)
+	err = h.errorService.DeleteError(r.Context(), id, requestActor(r, ""))
+	if err != nil {
+		if err.Error() == "error not found" {
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ErrorHandler) GetFirstSeen(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	days := 7
+	if daysStr := query.Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > 90 {
+			writeErrorResponse(w, "days must be between 1 and 90", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+	limit := 50
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	response, err := h.errorService.GetFirstSeen(r.Context(), query.Get("environment"), time.Duration(days)*24*time.Hour, limit)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get new errors", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
+
+func (h *ErrorHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePage(r, 50, 100)
+
+	response, err := h.errorService.GetTrash(r.Context(), limit, offset, requestOrgID(r))
+	if err != nil {
+		writeErrorResponse(w, "Failed to get trash", http.StatusInternalServerError)
+		return
+	}
+	response.Pagination = newPagination(r, response.Total, limit, offset)
+
+	writeSuccessResponse(w, response)
+}
+
+func (h *ErrorHandler) RestoreError(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.errorService.RestoreError(r.Context(), id, requestActor(r, ""))
+	if err != nil {
+		if err.Error() == "error not found" {
+			writeErrorResponse(w, "Error not found in trash", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to restore error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, map[string]string{"message": "Error restored successfully"})
+}
+
+func (h *ErrorHandler) PurgeError(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.errorService.PurgeError(r.Context(), id, requestActor(r, ""))
+	if err != nil {
+		if err.Error() == "error not found" {
+			writeErrorResponse(w, "Error not found in trash", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ErrorHandler) SetErrorRunbook(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.Runbook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	runbook, err := h.errorService.SetErrorRunbook(r.Context(), id, &req)
+	if err != nil {
+		if err.Error() == "error not found" {
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		} else if errors.Is(err, services.ErrInvalidRunbook) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeErrorResponse(w, "Failed to set runbook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, runbook)
+}
+
+func (h *ErrorHandler) DeleteErrorRunbook(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.errorService.DeleteErrorRunbook(r.Context(), id)
+	if err != nil {
+		if err.Error() == "error not found" {
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		} else if errors.Is(err, services.ErrInvalidRunbook) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeErrorResponse(w, "Failed to delete runbook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ErrorHandler) GetAutoResolvePolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.errorService.GetAutoResolvePolicy(r.Context(), projectID)
+	if err != nil {
+		writeAutoResolveError(w, err, "Failed to get auto-resolve policy")
+		return
+	}
+
+	writeSuccessResponse(w, policy)
+}
+
+func (h *ErrorHandler) UpdateAutoResolvePolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
 	if err != nil {
-		writeErrorResponse(w, "Failed to delete error", http.StatusInternalServerError)
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateAutoResolvePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	policy, err := h.errorService.SetAutoResolvePolicy(r.Context(), projectID, &req, requestActor(r, ""))
+	if err != nil {
+		writeAutoResolveError(w, err, "Failed to update auto-resolve policy")
+		return
+	}
+
+	writeSuccessResponse(w, policy)
+}
+
+func (h *ErrorHandler) DeleteAutoResolvePolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.errorService.DeleteAutoResolvePolicy(r.Context(), projectID, requestActor(r, "")); err != nil {
+		writeAutoResolveError(w, err, "Failed to delete auto-resolve policy")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func writeAutoResolveError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAutoResolvePolicy):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == "auto-resolve policy not found":
+		writeErrorResponse(w, "Auto-resolve policy not found", http.StatusNotFound)
+	case err.Error() == "project not found":
+		writeErrorResponse(w, "Project not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// ChangeErrorState moves an error to another lifecycle state
+func (h *ErrorHandler) ChangeErrorState(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ChangeErrorStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if len(req.Note) > maxResolutionNoteLength || len(req.ChangedBy) > maxActorLength {
+		writeErrorResponse(w, "Note or changed_by is too long", http.StatusBadRequest)
+		return
+	}
+
+	error, err := h.errorService.ChangeErrorState(r.Context(), id, &req, requestActor(r, req.ChangedBy))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidErrorState):
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidStateTransition):
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		case err.Error() == "error not found":
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		default:
+			writeErrorResponse(w, "Failed to change error state", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, error)
+}
+
+// GetErrorStates lists the lifecycle states and the transitions allowed
+// between them
+func (h *ErrorHandler) GetErrorStates(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponse(w, map[string]interface{}{
+		"states":      models.ErrorStates,
+		"transitions": services.ErrorStateTransitions,
+	})
+}
+
+// parseStatsFilter reads the project, environment, source, region and
+// deployment scope shared by stats and analytics endpoints, within the
+// organization of an organization-scoped key
+func parseStatsFilter(r *http.Request) (models.StatsFilter, error) {
+	query := r.URL.Query()
+	filter := models.StatsFilter{
+		Environment: query.Get("environment"),
+		Source:      query.Get("source"),
+		Region:      query.Get("region"),
+		Deployment:  query.Get("deployment"),
+		OrgID:       requestOrgID(r),
+	}
+	if project := query.Get("project"); project != "" {
+		projectID, err := uuid.Parse(project)
+		if err != nil {
+			return filter, err
+		}
+		filter.ProjectID = &projectID
+	}
+	return filter, nil
+}
+
 func (h *ErrorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.errorService.GetStats(r.Context())
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	etag := h.etag(r, statsETagPeriod)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	stats, err := h.errorService.GetStats(r.Context(), filter, r.URL.Query().Get("tz"))
+	if errors.Is(err, services.ErrInvalidTimezone) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to get stats: %v", err)
 		writeErrorResponse(w, "Failed to get stats", http.StatusInternalServerError)
@@ -187,30 +814,16 @@ func (h *ErrorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Returning stats to client: %+v", stats)
+	setETag(w, etag)
 	writeSuccessResponse(w, stats)
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// Fall back to RemoteAddr, but strip the port
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+// etag returns the response ETag, or "" when the cache generation is unavailable
+func (h *ErrorHandler) etag(r *http.Request, period time.Duration) string {
+	generation, err := h.errorService.CacheGeneration(r.Context())
 	if err != nil {
-		// If we can't split host:port, return as-is (might be just an IP)
-		return r.RemoteAddr
+		log.Printf("Failed to get cache generation: %v", err)
+		return ""
 	}
-	return host
+	return responseETag(r, generation, period)
 }
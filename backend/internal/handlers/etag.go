@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"error-logs/internal/services"
+)
+
+// Responses over trailing time windows change as time passes even without new
+// errors, so their ETags also roll over once per period. The periods match
+// the cache TTLs of the services behind them.
+const (
+	statsETagPeriod       = 5 * time.Minute
+	trendsETagPeriod      = 5 * time.Minute
+	heatmapETagPeriod     = 15 * time.Minute
+	performanceETagPeriod = time.Minute
+)
+
+// responseETag derives a weak ETag from the request URL and the cache
+// generation of the data behind it
+func responseETag(r *http.Request, generation int64, period time.Duration) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%d", r.URL.RequestURI(), generation)
+	if period > 0 {
+		fmt.Fprintf(hash, "|%d", time.Now().Unix()/int64(period.Seconds()))
+	}
+	return fmt.Sprintf(`W/"%x"`, hash.Sum(nil)[:12])
+}
+
+// notModified reports whether the request's If-None-Match already lists the
+// ETag, in which case it writes 304 Not Modified
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			setETag(w, etag)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// setETag marks the response as revalidatable; skipped when no ETag could be derived
+func setETag(w http.ResponseWriter, etag string) {
+	if etag == "" {
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+}
+
+// setVersionETag returns a managed resource's version as a strong ETag, which
+// If-Match takes back on updates and deletes
+func setVersionETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion reads the version an update or delete is conditional on from
+// If-Match. It is 0, matching any version, when the header is absent or *.
+func ifMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("If-Match must be an ETag returned by this API")
+	}
+	return version, nil
+}
+
+// writeVersionedError writes the responses shared by managed resources and
+// reports whether err was one of them
+func writeVersionedError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrVersionMismatch):
+		writeErrorResponse(w, "The resource changed since it was read; fetch it again and retry with its new ETag", http.StatusPreconditionFailed)
+	case errors.Is(err, services.ErrAlreadyExists):
+		writeErrorResponse(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidExternalID):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
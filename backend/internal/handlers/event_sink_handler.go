@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+// EventSinkHandler manages where error events are forwarded. Sinks receive
+// events from every organization, so only deployment admins manage them.
+type EventSinkHandler struct {
+	forwardingService *services.ForwardingService
+}
+
+func NewEventSinkHandler(forwardingService *services.ForwardingService) *EventSinkHandler {
+	return &EventSinkHandler{
+		forwardingService: forwardingService,
+	}
+}
+
+func (h *EventSinkHandler) GetEventSinks(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	sinks, err := h.forwardingService.GetSinks(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get event sinks", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"sinks": sinks})
+}
+
+func (h *EventSinkHandler) GetEventSink(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid sink ID", http.StatusBadRequest)
+		return
+	}
+
+	sink, err := h.forwardingService.GetSink(r.Context(), id)
+	if err != nil {
+		writeEventSinkError(w, err, "Failed to get event sink")
+		return
+	}
+
+	writeSuccessResponse(w, sink)
+}
+
+func (h *EventSinkHandler) CreateEventSink(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.CreateEventSinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	sink, err := h.forwardingService.CreateSink(r.Context(), &req, requestActor(r, ""))
+	if err != nil {
+		writeEventSinkError(w, err, "Failed to create event sink")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, sink)
+}
+
+func (h *EventSinkHandler) UpdateEventSink(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid sink ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateEventSinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	sink, err := h.forwardingService.UpdateSink(r.Context(), id, &req, requestActor(r, ""))
+	if err != nil {
+		writeEventSinkError(w, err, "Failed to update event sink")
+		return
+	}
+
+	writeSuccessResponse(w, sink)
+}
+
+func (h *EventSinkHandler) DeleteEventSink(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid sink ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.forwardingService.DeleteSink(r.Context(), id, requestActor(r, "")); err != nil {
+		writeEventSinkError(w, err, "Failed to delete event sink")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeEventSinkError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidEventSink):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == "event sink not found":
+		writeErrorResponse(w, "Event sink not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// CreateExport starts an export of the errors matching the filter, answering
+// 202 with the job to poll
+func (h *ExportHandler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	job, err := h.exportService.CreateExport(r.Context(), &req, projectID, requestActor(r, ""))
+	if errors.Is(err, services.ErrFieldNotIndexed) {
+		writeError(w, codeFieldNotIndexed, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidExport) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to start export", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, job)
+}
+
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid export job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.exportService.GetExport(r.Context(), id)
+	if err != nil {
+		if err.Error() == "export job not found" {
+			writeErrorResponse(w, "Export job not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get export job", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, job)
+}
+
+// DownloadExport serves a finished export to anyone holding its signed link;
+// it is mounted outside /api so links work without an API key
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid export job ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	job, file, err := h.exportService.OpenExportDownload(r.Context(), id, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDownloadLink):
+			writeErrorResponse(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrExportExpired):
+			writeErrorResponse(w, err.Error(), http.StatusGone)
+		case err.Error() == "export job not found":
+			writeErrorResponse(w, "Export not found", http.StatusNotFound)
+		default:
+			writeErrorResponse(w, "Failed to get export", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", services.ExportContentType(job))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ExportFileName(job)))
+	w.Header().Set("Content-Length", strconv.FormatInt(job.SizeBytes, 10))
+	io.Copy(w, file)
+}
+
+// ExportErrorEvents downloads every occurrence of an error's group as CSV.
+// It streams in the request instead of starting an export job.
+func (h *ExportHandler) ExportErrorEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	if wantsNDJSON(r) {
+		h.streamErrorEvents(w, r, id)
+		return
+	}
+
+	started := false
+	err = h.exportService.WriteGroupEvents(r.Context(), id, requestActor(r, ""), func(fileName string) io.Writer {
+		started = true
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		return w
+	})
+	if err == nil {
+		return
+	}
+	if started {
+		// The status is sent; the client sees a truncated file
+		log.Printf("Error event export of %s failed: %v", id, err)
+		return
+	}
+	if err.Error() == "error not found" {
+		writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		return
+	}
+	writeErrorResponse(w, "Failed to export error events", http.StatusInternalServerError)
+}
+
+// streamErrorEvents writes the occurrences of an error's group as lines of
+// JSON, oldest first
+func (h *ExportHandler) streamErrorEvents(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	stream := newNDJSONStream(w)
+	err := h.exportService.StreamGroupEvents(r.Context(), id, requestActor(r, ""), func(e *models.Error) error {
+		return stream.Write(e)
+	})
+	if err == nil {
+		stream.Close()
+		return
+	}
+	if stream.Started() {
+		log.Printf("Error event stream of %s failed: %v", id, err)
+		stream.Fail("Failed to export error events")
+		return
+	}
+	if err.Error() == "error not found" {
+		writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		return
+	}
+	writeErrorResponse(w, "Failed to export error events", http.StatusInternalServerError)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+const featureFlagsHeader = "X-Feature-Flags"
+
+type featureFlagsContextKey struct{}
+
+// FeatureFlagMiddleware resolves the flags for the API key's organization and
+// project, lists the enabled ones in X-Feature-Flags and makes them available
+// to handlers through featureFlagsFromContext.
+func FeatureFlagMiddleware(flags *services.FeatureFlagService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var orgID, projectID *uuid.UUID
+			if key := apiKeyFromContext(r.Context()); key != nil {
+				orgID, projectID = key.OrgID, key.ProjectID
+			}
+
+			resolved := flags.Resolve(orgID, projectID)
+			enabled := make([]string, 0, len(resolved))
+			for key, on := range resolved {
+				if on {
+					enabled = append(enabled, key)
+				}
+			}
+			sort.Strings(enabled)
+			w.Header().Set(featureFlagsHeader, strings.Join(enabled, ","))
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), featureFlagsContextKey{}, resolved)))
+		})
+	}
+}
+
+// featureFlagsFromContext returns whether each flag is on for the request's
+// API key
+func featureFlagsFromContext(ctx context.Context) map[string]bool {
+	resolved, _ := ctx.Value(featureFlagsContextKey{}).(map[string]bool)
+	if resolved == nil {
+		return map[string]bool{}
+	}
+	return resolved
+}
+
+type FeatureFlagHandler struct {
+	featureFlagService *services.FeatureFlagService
+}
+
+func NewFeatureFlagHandler(featureFlagService *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		featureFlagService: featureFlagService,
+	}
+}
+
+// GetFeatures returns every flag and whether it is on for the API key
+func (h *FeatureFlagHandler) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponse(w, map[string]interface{}{"features": featureFlagsFromContext(r.Context())})
+}
+
+func (h *FeatureFlagHandler) GetFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	flags, err := h.featureFlagService.ListFlags(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get feature flags", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"flags": flags})
+}
+
+func (h *FeatureFlagHandler) UpdateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.UpdateFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	flag, err := h.featureFlagService.UpdateFlag(r.Context(), chi.URLParam(r, "key"), &req, requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidFeatureFlag) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to update feature flag", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, flag)
+}
+
+func (h *FeatureFlagHandler) DeleteFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	if err := h.featureFlagService.DeleteFlag(r.Context(), chi.URLParam(r, "key"), requestActor(r, "")); err != nil {
+		if err.Error() == "feature flag not found" {
+			writeErrorResponse(w, "Feature flag not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to delete feature flag", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FeatureFlagHandler) SetFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.SetFeatureFlagOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	override, err := h.featureFlagService.SetOverride(r.Context(), chi.URLParam(r, "key"), &req, requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidFeatureFlag) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "feature flag not found" {
+			writeErrorResponse(w, "Feature flag not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to set feature flag override", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, override)
+}
+
+func (h *FeatureFlagHandler) DeleteFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	scopeID, err := uuid.Parse(chi.URLParam(r, "scopeId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid scope ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.featureFlagService.RemoveOverride(r.Context(), chi.URLParam(r, "key"), chi.URLParam(r, "scope"), scopeID, requestActor(r, ""))
+	if err != nil {
+		if err.Error() == "feature flag override not found" {
+			writeErrorResponse(w, "Feature flag override not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to delete feature flag override", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isDeploymentAdmin reports whether a key is a deployment-wide secret key
+// with the admin permission
+func isDeploymentAdmin(key *models.APIKey) bool {
+	return key != nil && key.KeyType != "public" && key.OrgID == nil && slices.Contains(key.Permissions, "admin")
+}
+
+// requireDeploymentAdmin allows deployment-wide admin keys, for settings that
+// span organizations such as feature flags
+func requireDeploymentAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !isDeploymentAdmin(apiKeyFromContext(r.Context())) {
+		writeErrorResponse(w, "Deployment-wide admin key required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
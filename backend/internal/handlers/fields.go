@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"error-logs/internal/models"
+)
+
+// errorFields are the fields of an error that can be requested with ?fields=
+var errorFields = jsonFieldNames(reflect.TypeOf(models.Error{}))
+
+// sparseErrorList is an ErrorListResponse carrying only the requested fields
+type sparseErrorList struct {
+	Errors     []map[string]json.RawMessage `json:"errors"`
+	Total      int                          `json:"total"`
+	Page       int                          `json:"page"`
+	Limit      int                          `json:"limit"`
+	Degraded   bool                         `json:"degraded,omitempty"`
+	Query      []models.SearchTerm          `json:"query,omitempty"`
+	Pagination *models.Pagination           `json:"pagination,omitempty"`
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFields reads a comma-separated ?fields= list, or nil when absent. The
+// id is always included so clients can still address each item.
+func parseFields(r *http.Request, allowed map[string]bool) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	fields := []string{"id"}
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || field == "id" {
+			continue
+		}
+		if !allowed[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// selectFields drops every field not listed from each error
+func selectFields(errors []models.Error, fields []string) ([]map[string]json.RawMessage, error) {
+	result := make([]map[string]json.RawMessage, len(errors))
+	for i, e := range errors {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				selected[field] = value
+			}
+		}
+		result[i] = selected
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type GelfHandler struct {
+	gelfService  *services.GelfService
+	quotaService *services.QuotaService
+}
+
+func NewGelfHandler(gelfService *services.GelfService, quotaService *services.QuotaService) *GelfHandler {
+	return &GelfHandler{
+		gelfService:  gelfService,
+		quotaService: quotaService,
+	}
+}
+
+// Ingest accepts one GELF message, plain or gzip/zlib compressed, like
+// Graylog's GELF HTTP input
+func (h *GelfHandler) Ingest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Failed to read message", http.StatusBadRequest)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	recorded, err := h.gelfService.Ingest(r.Context(), body, projectID, getClientIP(r))
+	if errors.Is(err, services.ErrInvalidGELF) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record GELF message", http.StatusInternalServerError)
+		return
+	}
+
+	accepted := 0
+	if recorded {
+		accepted = 1
+	}
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type HerokuHandler struct {
+	herokuService *services.HerokuService
+	quotaService  *services.QuotaService
+}
+
+func NewHerokuHandler(herokuService *services.HerokuService, quotaService *services.QuotaService) *HerokuHandler {
+	return &HerokuHandler{
+		herokuService: herokuService,
+		quotaService:  quotaService,
+	}
+}
+
+// IngestLogs accepts a Heroku HTTPS log drain delivery
+func (h *HerokuHandler) IngestLogs(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Failed to read logs", http.StatusBadRequest)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	query := r.URL.Query()
+	accepted, err := h.herokuService.IngestLogs(r.Context(), body, projectID,
+		query.Get("app"), query.Get("environment"), r.Header.Get("Logplex-Drain-Token"), getClientIP(r))
+	if errors.Is(err, services.ErrInvalidHerokuLogs) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record Heroku logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type HostHandler struct {
+	hostService *services.HostService
+}
+
+func NewHostHandler(hostService *services.HostService) *HostHandler {
+	return &HostHandler{
+		hostService: hostService,
+	}
+}
+
+func (h *HostHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	var req models.HostCheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	err := h.hostService.CheckIn(r.Context(), &req)
+	if errors.Is(err, services.ErrInvalidHost) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]string{"hostname": req.Hostname})
+}
+
+func (h *HostHandler) GetHosts(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePage(r, 50, 500)
+
+	query := r.URL.Query()
+	filter := models.HostFilter{
+		Environment: query.Get("environment"),
+		Status:      query.Get("status"),
+	}
+
+	hosts, total, err := h.hostService.GetHosts(r.Context(), limit, offset, filter)
+	if errors.Is(err, services.ErrInvalidHost) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get hosts", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"hosts":      hosts,
+		"pagination": newPagination(r, total, limit, offset),
+	})
+}
+
+func (h *HostHandler) GetHost(w http.ResponseWriter, r *http.Request) {
+	period := time.Hour // default
+	if periodStr := r.URL.Query().Get("period"); periodStr != "" {
+		d, err := time.ParseDuration(periodStr)
+		if err != nil || d <= 0 || d > 7*24*time.Hour {
+			writeErrorResponse(w, "Invalid period", http.StatusBadRequest)
+			return
+		}
+		period = d
+	}
+
+	host, err := h.hostService.GetHost(r.Context(), chi.URLParam(r, "hostname"), period)
+	if err != nil {
+		if err.Error() == "host not found" {
+			writeErrorResponse(w, "Host not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get host", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, host)
+}
+
+func (h *HostHandler) DeleteHost(w http.ResponseWriter, r *http.Request) {
+	if err := h.hostService.DeleteHost(r.Context(), chi.URLParam(r, "hostname")); err != nil {
+		if err.Error() == "host not found" {
+			writeErrorResponse(w, "Host not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to delete host", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// StartImport accepts a Sentry or Rollbar export and imports it in the
+// background, answering 202 with the job to poll
+func (h *ImportHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Export too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Failed to read export", http.StatusBadRequest)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	query := r.URL.Query()
+	job, err := h.importService.StartImport(r.Context(), query.Get("format"), body, projectID, query.Get("source"), requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidImport) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to start import", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, job)
+}
+
+func (h *ImportHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid import job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.importService.GetImportJob(r.Context(), id)
+	if err != nil {
+		if err.Error() == "import job not found" {
+			writeErrorResponse(w, "Import job not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get import job", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, job)
+}
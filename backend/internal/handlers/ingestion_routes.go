@@ -0,0 +1,61 @@
+package handlers
+
+// ingestionBody is how the body of an ingestion request is capped
+type ingestionBody int
+
+const (
+	defaultBody ingestionBody = iota // BodyLimits.Default
+	eventBody                        // BodyLimits.Event
+	batchBody                        // BodyLimits.Batch
+	uploadBody                       // the handler enforces its own cap
+)
+
+// ingestionRoute describes a POST endpoint that takes events from SDKs,
+// agents and log drains
+type ingestionRoute struct {
+	body ingestionBody
+	// clientCert requires a client certificate from secret keys when mutual
+	// TLS is enabled
+	clientCert bool
+	// public opens the route to public keys, guarded as it describes
+	public *publicEndpoint
+	// upload gets the Long request timeout rather than Ingest
+	upload bool
+}
+
+// ingestionRoutes are every endpoint that ingests events. They are all open
+// to organization-scoped keys, whose events land in the key's project. The
+// middlewares that treat ingestion differently derive their lists from here,
+// so a new endpoint is added once.
+var ingestionRoutes = map[string]ingestionRoute{
+	"/api/errors": {body: eventBody, clientCert: true,
+		public: &publicEndpoint{checkOrigin: true, maxBytes: maxPublicEventBytes}},
+	"/api/gelf":               {body: eventBody, clientCert: true},
+	"/api/logs":               {body: batchBody, clientCert: true},
+	"/api/metrics":            {body: batchBody, clientCert: true},
+	"/api/sessions":           {body: batchBody, clientCert: true},
+	"/api/kubernetes/events":  {body: batchBody, clientCert: true},
+	"/api/serverless/lambda":  {body: batchBody, clientCert: true},
+	"/api/serverless/vercel":  {body: batchBody, clientCert: true},
+	"/api/serverless/netlify": {body: batchBody, clientCert: true},
+	"/api/hosts/check-in":     {body: defaultBody, clientCert: true},
+	// Heroku's log drains cannot present a client certificate
+	"/api/heroku/logs": {body: batchBody},
+	// Browsers send CSP reports without a client certificate, and the
+	// reports are checked against their document URL instead of the Origin
+	"/api/csp-reports": {body: uploadBody, public: &publicEndpoint{maxBytes: maxPublicEventBytes}},
+	// Native crash reporters have no origin and upload large files
+	"/api/minidump": {body: uploadBody, clientCert: true, public: &publicEndpoint{}, upload: true},
+}
+
+// ingestionPaths returns the paths of the ingestion routes for which keep
+// reports true
+func ingestionPaths(keep func(route ingestionRoute) bool) map[string]bool {
+	paths := map[string]bool{}
+	for path, route := range ingestionRoutes {
+		if keep(route) {
+			paths[path] = true
+		}
+	}
+	return paths
+}
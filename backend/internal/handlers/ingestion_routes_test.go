@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIngestionRouteLists(t *testing.T) {
+	for path := range ingestionRoutes {
+		if !orgKeyIngestion[path] {
+			t.Errorf("%s is not open to organization-scoped keys", path)
+		}
+	}
+	for _, path := range []string{"/api/heroku/logs", "/api/csp-reports"} {
+		if clientCertEndpoints[path] {
+			t.Errorf("%s requires a client certificate its senders cannot present", path)
+		}
+	}
+	if !clientCertEndpoints["/api/errors"] || !clientCertEndpoints["/api/minidump"] {
+		t.Error("SDK ingestion does not require a client certificate")
+	}
+	if _, ok := publicEndpoints["/api/logs"]; ok {
+		t.Error("/api/logs is open to public keys")
+	}
+	if endpoint, ok := publicEndpoints["/api/errors"]; !ok || !endpoint.checkOrigin {
+		t.Errorf("publicEndpoints[/api/errors] = %+v, %t, want an origin-checked endpoint", endpoint, ok)
+	}
+}
+
+func TestIngestionLimits(t *testing.T) {
+	limits := BodyLimits{Default: 1, Event: 2, Batch: 3, Import: 4}
+	timeouts := RequestTimeouts{Default: time.Second, Ingest: 2 * time.Second, Long: 3 * time.Second}
+
+	tests := []struct {
+		path    string
+		body    int64
+		timeout time.Duration
+	}{
+		{"/api/errors", 2, 2 * time.Second},
+		{"/api/heroku/logs", 3, 2 * time.Second},
+		{"/api/hosts/check-in", 1, 2 * time.Second},
+		{"/api/csp-reports", 0, 2 * time.Second},
+		{"/api/minidump", 0, 3 * time.Second},
+		{"/api/import", 4, 3 * time.Second},
+		{"/api/alerts/rules", 1, time.Second},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", tt.path, nil)
+		if got := limits.bodyLimitFor(r); got != tt.body {
+			t.Errorf("bodyLimitFor(%s) = %d, want %d", tt.path, got, tt.body)
+		}
+		if got := timeouts.timeoutFor(r); got != tt.timeout {
+			t.Errorf("timeoutFor(%s) = %v, want %v", tt.path, got, tt.timeout)
+		}
+	}
+}
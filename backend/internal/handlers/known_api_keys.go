@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// knownAPIKeyTTL bounds how long a key validated earlier is still accepted
+// while the database is unavailable. A key revoked on another instance stops
+// working within it.
+const knownAPIKeyTTL = 5 * time.Minute
+
+// KnownAPIKeys remembers the keys APIKeyMiddleware validated recently, so
+// events can still be accepted into the queue while the database is
+// unavailable
+type KnownAPIKeys struct {
+	keys sync.Map // key hash -> knownAPIKey
+}
+
+type knownAPIKey struct {
+	key         *models.APIKey
+	validatedAt time.Time
+}
+
+func NewKnownAPIKeys() *KnownAPIKeys {
+	return &KnownAPIKeys{}
+}
+
+// load returns the key with the hash if it was validated within
+// knownAPIKeyTTL
+func (k *KnownAPIKeys) load(keyHash string) (*models.APIKey, bool) {
+	value, ok := k.keys.Load(keyHash)
+	if !ok {
+		return nil, false
+	}
+	known := value.(knownAPIKey)
+	if time.Since(known.validatedAt) >= knownAPIKeyTTL {
+		k.keys.CompareAndDelete(keyHash, value)
+		return nil, false
+	}
+	return known.key, true
+}
+
+func (k *KnownAPIKeys) store(keyHash string, key *models.APIKey) {
+	k.keys.Store(keyHash, knownAPIKey{key: key, validatedAt: time.Now()})
+}
+
+func (k *KnownAPIKeys) delete(keyHash string) {
+	k.keys.Delete(keyHash)
+}
+
+// Forget drops a revoked key, so it is refused at once even while the
+// database is unavailable
+func (k *KnownAPIKeys) Forget(id uuid.UUID) {
+	k.keys.Range(func(keyHash, value any) bool {
+		if value.(knownAPIKey).key.ID == id {
+			k.keys.Delete(keyHash)
+		}
+		return true
+	})
+}
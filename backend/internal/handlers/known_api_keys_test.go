@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+func TestKnownAPIKeysExpire(t *testing.T) {
+	known := NewKnownAPIKeys()
+	key := &models.APIKey{ID: uuid.New()}
+
+	known.store("fresh", key)
+	if got, ok := known.load("fresh"); !ok || got != key {
+		t.Errorf("load(fresh) = %v, %t, want the stored key", got, ok)
+	}
+
+	known.keys.Store("stale", knownAPIKey{key: key, validatedAt: time.Now().Add(-knownAPIKeyTTL)})
+	if _, ok := known.load("stale"); ok {
+		t.Error("load(stale) returned a key validated longer than the TTL ago")
+	}
+	if _, ok := known.keys.Load("stale"); ok {
+		t.Error("expired key was not dropped")
+	}
+}
+
+func TestKnownAPIKeysForget(t *testing.T) {
+	known := NewKnownAPIKeys()
+	revoked, other := &models.APIKey{ID: uuid.New()}, &models.APIKey{ID: uuid.New()}
+	known.store("revoked", revoked)
+	known.store("other", other)
+
+	known.Forget(revoked.ID)
+	if _, ok := known.load("revoked"); ok {
+		t.Error("revoked key is still known")
+	}
+	if _, ok := known.load("other"); !ok {
+		t.Error("Forget dropped another key")
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type KubernetesHandler struct {
+	kubernetesService *services.KubernetesService
+	quotaService      *services.QuotaService
+}
+
+func NewKubernetesHandler(kubernetesService *services.KubernetesService, quotaService *services.QuotaService) *KubernetesHandler {
+	return &KubernetesHandler{
+		kubernetesService: kubernetesService,
+		quotaService:      quotaService,
+	}
+}
+
+// IngestEvents accepts Kubernetes events posted by an in-cluster watcher
+func (h *KubernetesHandler) IngestEvents(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Failed to read events", http.StatusBadRequest)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	query := r.URL.Query()
+	accepted, err := h.kubernetesService.IngestEvents(r.Context(), body, projectID,
+		query.Get("environment"), query.Get("cluster"), r.Header.Get("User-Agent"), getClientIP(r))
+	if errors.Is(err, services.ErrInvalidKubernetesEvent) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record Kubernetes events", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type LogHandler struct {
+	logService *services.LogService
+}
+
+func NewLogHandler(logService *services.LogService) *LogHandler {
+	return &LogHandler{
+		logService: logService,
+	}
+}
+
+func (h *LogHandler) IngestLogs(w http.ResponseWriter, r *http.Request) {
+	var req models.IngestLogsRequest
+	err := decodeBatch(r.Body, "logs", services.MaxLogBatch, func(dec *json.Decoder) error {
+		var item models.CreateLogRequest
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		req.Logs = append(req.Logs, item)
+		return nil
+	})
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	response, err := h.logService.IngestLogs(r.Context(), &req, projectID)
+	if errors.Is(err, services.ErrInvalidLog) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to store logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, response)
+}
+
+func (h *LogHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 100 // default
+	offset := 0  // default
+
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	filter := models.LogFilter{
+		Source:      query.Get("source"),
+		Environment: query.Get("environment"),
+		TraceID:     query.Get("trace_id"),
+		Search:      query.Get("search"),
+	}
+
+	// level=a,b selects exact levels; min_level selects that level and above
+	if levels := query.Get("level"); levels != "" {
+		filter.Levels = strings.Split(levels, ",")
+	} else if minLevel := query.Get("min_level"); minLevel != "" {
+		filter.Levels = services.LogLevelsAtOrAbove(minLevel)
+		if filter.Levels == nil {
+			writeErrorResponse(w, "Invalid min_level", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid since timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.Since = &since
+	}
+
+	response, err := h.logService.GetLogs(r.Context(), limit, offset, filter)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get logs", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type MetricsHandler struct {
+	metricsService *services.MetricsService
+}
+
+func NewMetricsHandler(metricsService *services.MetricsService) *MetricsHandler {
+	return &MetricsHandler{
+		metricsService: metricsService,
+	}
+}
+
+func (h *MetricsHandler) IngestMetrics(w http.ResponseWriter, r *http.Request) {
+	var req models.IngestMetricsRequest
+	err := decodeBatch(r.Body, "metrics", services.MaxMetricBatch, func(dec *json.Decoder) error {
+		var item models.MetricSample
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		req.Metrics = append(req.Metrics, item)
+		return nil
+	})
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	accepted, err := h.metricsService.IngestMetrics(r.Context(), &req)
+	if errors.Is(err, services.ErrInvalidMetric) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record metrics", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
+
+func (h *MetricsHandler) GetMetricSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	resolution := query.Get("resolution")
+	if resolution == "" {
+		resolution = "minute"
+	}
+
+	period := 24 * time.Hour // default
+	if periodStr := query.Get("period"); periodStr != "" {
+		d, err := time.ParseDuration(periodStr)
+		if err != nil || d <= 0 || d > 90*24*time.Hour {
+			writeErrorResponse(w, "Invalid period", http.StatusBadRequest)
+			return
+		}
+		period = d
+	}
+
+	// tag.<key>=<value> narrows the series to matching tag sets
+	var tags map[string]string
+	for param, values := range query {
+		if key, ok := strings.CutPrefix(param, "tag."); ok && key != "" && len(values) > 0 {
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			tags[key] = values[0]
+		}
+	}
+
+	series, err := h.metricsService.GetMetricSeries(r.Context(), query.Get("name"), resolution, tags, period)
+	if errors.Is(err, services.ErrInvalidMetric) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get metrics", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, series)
+}
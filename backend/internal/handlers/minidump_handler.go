@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type MinidumpHandler struct {
+	minidumpService *services.MinidumpService
+	quotaService    *services.QuotaService
+	maxUploadBytes  int64
+}
+
+func NewMinidumpHandler(minidumpService *services.MinidumpService, quotaService *services.QuotaService, maxUploadMB int) *MinidumpHandler {
+	return &MinidumpHandler{
+		minidumpService: minidumpService,
+		quotaService:    quotaService,
+		maxUploadBytes:  int64(maxUploadMB) << 20,
+	}
+}
+
+// UploadMinidump accepts the multipart upload sent by Crashpad and Breakpad:
+// the dump in "upload_file_minidump" (or "file") and annotations as the other
+// form fields. Gzip-encoded request bodies are accepted.
+func (h *MinidumpHandler) UploadMinidump(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		body, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeErrorResponse(w, "Invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+		r.Body = io.NopCloser(io.LimitReader(body, h.maxUploadBytes))
+		r.Header.Del("Content-Encoding")
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Minidump too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeErrorResponse(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("upload_file_minidump")
+	if err != nil {
+		file, _, err = r.FormFile("file")
+	}
+	if err != nil {
+		writeErrorResponse(w, "A multipart \"upload_file_minidump\" field is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	annotations := make(map[string]string)
+	for key, values := range r.MultipartForm.Value {
+		if len(values) > 0 {
+			annotations[key] = values[0]
+		}
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	minidump, err := h.minidumpService.IngestMinidump(r.Context(), file, annotations, projectID, r.Header.Get("User-Agent"), getClientIP(r))
+	if errors.Is(err, services.ErrInvalidMinidump) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to store minidump", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, minidump)
+}
+
+func (h *MinidumpHandler) GetMinidumps(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+
+	minidumps, err := h.minidumpService.GetMinidumps(r.Context(), r.URL.Query().Get("fingerprint"), limit)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get minidumps", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"minidumps": minidumps})
+}
+
+func (h *MinidumpHandler) DownloadMinidump(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid minidump ID", http.StatusBadRequest)
+		return
+	}
+
+	minidump, file, err := h.minidumpService.OpenMinidump(r.Context(), id)
+	if err != nil {
+		if err.Error() == "minidump not found" {
+			writeErrorResponse(w, "Minidump not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get minidump", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", minidump.ID.String()+".dmp"))
+	w.Header().Set("Content-Length", strconv.FormatInt(minidump.Size, 10))
+	io.Copy(w, file)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"error-logs/internal/services"
 )
@@ -50,3 +51,20 @@ func (h *MonitoringHandler) GetUptime(w http.ResponseWriter, r *http.Request) {
 
 	writeSuccessResponse(w, uptime)
 }
+
+func (h *MonitoringHandler) GetThrottlePeriods(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 200 {
+			limit = l
+		}
+	}
+
+	periods, err := h.monitoringService.GetThrottlePeriods(r.Context(), limit)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get throttle periods", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"throttle_periods": periods})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"error-logs/internal/models"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	// ndjsonFlushRows is how many rows are written between flushes, so slow
+	// pages still reach the client as they are read
+	ndjsonFlushRows = 100
+)
+
+// wantsNDJSON reports whether a list should be streamed as newline-delimited
+// JSON, asked for with format=ndjson or Accept: application/x-ndjson
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjsonStream writes one JSON value per line. The status is sent with the
+// first row, so failures before then can still be answered normally.
+type ndjsonStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	rows    int
+}
+
+func newNDJSONStream(w http.ResponseWriter) *ndjsonStream {
+	return &ndjsonStream{w: w, encoder: json.NewEncoder(w)}
+}
+
+// Started reports whether anything was written
+func (s *ndjsonStream) Started() bool {
+	return s.rows > 0
+}
+
+func (s *ndjsonStream) Write(v interface{}) error {
+	if s.rows == 0 {
+		s.w.Header().Set("Content-Type", ndjsonContentType)
+		s.w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%ndjsonFlushRows == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// Close ends a complete stream. An empty list is an empty 200 response.
+func (s *ndjsonStream) Close() {
+	if s.rows == 0 {
+		s.w.Header().Set("Content-Type", ndjsonContentType)
+		s.w.WriteHeader(http.StatusOK)
+		return
+	}
+	s.flush()
+}
+
+// Fail ends a stream that broke after it started with an error line, which
+// clients tell from rows by its "status": "error", since the status code is
+// already sent
+func (s *ndjsonStream) Fail(message string) {
+	s.encoder.Encode(models.APIResponse{
+		Error:     message,
+		Status:    "error",
+		Code:      statusErrorCode(http.StatusInternalServerError),
+		RequestID: s.w.Header().Get(requestIDHeader),
+	})
+	s.flush()
+}
+
+// flush sends buffered rows; a client that went away fails the next write
+func (s *ndjsonStream) flush() {
+	http.NewResponseController(s.w).Flush()
+}
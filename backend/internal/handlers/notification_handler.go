@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+	"error-logs/internal/websocket"
+)
+
+// maxNotificationStreamBytes caps a message from an inbox stream client,
+// which is not expected to send any
+const maxNotificationStreamBytes = 1 << 10
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications lists a team member's inbox, newest first
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	memberID, ok := parseMemberID(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := parsePage(r, 50, 200)
+
+	notifications, total, unread, err := h.notificationService.GetNotifications(r.Context(), memberID, limit, offset, parseNotificationFilter(r))
+	if err != nil {
+		writeNotificationError(w, err, "Failed to get notifications")
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"notifications": notifications,
+		"unread":        unread,
+		"pagination":    newPagination(r, total, limit, offset),
+	})
+}
+
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	memberID, ok := parseMemberID(w, r)
+	if !ok {
+		return
+	}
+
+	unread, err := h.notificationService.UnreadCount(r.Context(), memberID)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to count notifications")
+		return
+	}
+
+	writeSuccessResponse(w, map[string]int{"unread": unread})
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+	memberID, ok := parseMemberID(w, r)
+	if !ok {
+		return
+	}
+
+	notification, err := h.notificationService.MarkRead(r.Context(), memberID, id)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to mark notification read")
+		return
+	}
+
+	writeSuccessResponse(w, notification)
+}
+
+// MarkAllRead marks every unread notification matching the filter read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	memberID, ok := parseMemberID(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.notificationService.MarkAllRead(r.Context(), memberID, parseNotificationFilter(r))
+	if err != nil {
+		writeNotificationError(w, err, "Failed to mark notifications read")
+		return
+	}
+
+	writeSuccessResponse(w, map[string]int64{"updated": updated})
+}
+
+// StreamNotifications pushes a member's new notifications and unread count
+// over a WebSocket
+func (h *NotificationHandler) StreamNotifications(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		writeErrorResponse(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	memberID, ok := parseMemberID(w, r)
+	if !ok {
+		return
+	}
+	unread, err := h.notificationService.UnreadCount(r.Context(), memberID)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to count notifications")
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, maxNotificationStreamBytes)
+	if err != nil {
+		log.Printf("Notification stream upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close(websocket.CloseNormal, "")
+
+	stream := h.notificationService.Subscribe(memberID)
+	defer h.notificationService.Unsubscribe(memberID, stream)
+
+	// Client messages are ignored; reading notices when the client leaves
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(message *models.NotificationMessage) bool {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Failed to marshal notification message: %v", err)
+			return true
+		}
+		return conn.WriteText(data) == nil
+	}
+
+	if !send(&models.NotificationMessage{Type: "status", MemberID: memberID, Unread: unread}) {
+		return
+	}
+
+	ticker := time.NewTicker(tailPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			conn.Close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case message := <-stream:
+			if !send(message) {
+				return
+			}
+		case <-ticker.C:
+			if conn.Ping() != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseMemberID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	memberID, err := uuid.Parse(r.URL.Query().Get("member_id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid member_id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return memberID, true
+}
+
+func parseNotificationFilter(r *http.Request) models.NotificationFilter {
+	query := r.URL.Query()
+	return models.NotificationFilter{
+		Unread: query.Get("unread") == "true",
+		Type:   query.Get("type"),
+	}
+}
+
+func writeNotificationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNotificationFilter):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == "team member not found":
+		writeErrorResponse(w, "Team member not found", http.StatusNotFound)
+	case err.Error() == "notification not found":
+		writeErrorResponse(w, "Notification not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+// orgKeyIngestion are the POST endpoints open to organization-scoped keys.
+// They only add events, which land in the key's project.
+var orgKeyIngestion = ingestionPaths(func(ingestionRoute) bool { return true })
+
+// orgKeyReads are the GET endpoints open to organization-scoped keys; they
+// resolve settings for the key itself
+var orgKeyReads = map[string]bool{
+	"/api/sdk/config": true,
+	"/api/features":   true,
+}
+
+// orgKeyErrorReads are the GET endpoints that list errors and stats. Their
+// filters keep the errors of projects in the key's organization.
+var orgKeyErrorReads = map[string]bool{
+	"/api/errors":         true,
+	"/api/errors/facets":  true,
+	"/api/errors/updates": true,
+	"/api/errors/trash":   true,
+	"/api/stats":          true,
+}
+
+// orgKeyErrorActions are the calls on one error open to organization-scoped
+// keys, by method and the rest of the path after /api/errors/{id}
+var orgKeyErrorActions = map[string]bool{
+	"GET":            true,
+	"DELETE":         true,
+	"PUT /resolve":   true,
+	"PUT /unresolve": true,
+	"PUT /state":     true,
+	"PUT /restore":   true,
+}
+
+// orgKeyRoutes are the route trees that limit what they read and change to
+// the key's organization
+var orgKeyRoutes = []string{
+	"/api/orgs",
+	"/api/projects",
+	"/api/settings/api-keys",
+	"/api/settings/team",
+	"/api/settings/usage",
+	"/api/settings/quotas",
+	"/api/alerts/rules",
+	"/api/alerts/templates",
+	"/api/alerts/incidents",
+}
+
+// orgKeyBlocked are the paths under orgKeyRoutes that act on every
+// organization at once
+var orgKeyBlocked = map[string]bool{
+	"/api/alerts/rules/bulk": true,
+}
+
+// orgKeyAllowed reports whether an organization-scoped key may make the
+// request without further checks
+func orgKeyAllowed(r *http.Request) bool {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPost:
+		if orgKeyIngestion[path] {
+			return true
+		}
+	case http.MethodGet:
+		if orgKeyReads[path] || orgKeyErrorReads[path] || strings.HasPrefix(path, "/api/errors/by-trace/") {
+			return true
+		}
+	}
+	if orgKeyBlocked[path] {
+		return false
+	}
+	for _, route := range orgKeyRoutes {
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// orgKeyErrorID returns the error a request open to organization-scoped keys
+// acts on, if it acts on one
+func orgKeyErrorID(r *http.Request) (uuid.UUID, bool) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if rest, ok := strings.CutPrefix(path, "/api/errors/trash/"); ok && r.Method == http.MethodDelete {
+		id, err := uuid.Parse(rest)
+		return id, err == nil
+	}
+
+	rest, ok := strings.CutPrefix(path, "/api/errors/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	idStr, action, _ := strings.Cut(rest, "/")
+	if action != "" {
+		action = " /" + action
+	}
+	if !orgKeyErrorActions[r.Method+action] {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(idStr)
+	return id, err == nil
+}
+
+// OrgKeyMiddleware keeps organization-scoped API keys to the endpoints that
+// are limited to their organization, plus ingestion. Calls on a single error
+// answer 404 unless the error was reported to a project of the key's
+// organization. Exports, dashboards, the audit log and the other
+// deployment-wide data need a deployment-wide key. Keys without an
+// organization pass through.
+func OrgKeyMiddleware(errorService *services.ErrorService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromContext(r.Context())
+			if key == nil || key.OrgID == nil || orgKeyAllowed(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if id, ok := orgKeyErrorID(r); ok {
+				orgID, err := errorService.ErrorOrgID(r.Context(), id)
+				if err != nil && err.Error() != "error not found" {
+					writeErrorResponse(w, "Failed to get error", http.StatusInternalServerError)
+					return
+				}
+				if err != nil || orgID == nil || *orgID != *key.OrgID {
+					writeErrorResponse(w, "Error not found", http.StatusNotFound)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writeError(w, codeOrgKeyNotAllowed, "Organization API keys cannot call this endpoint", http.StatusForbidden)
+		})
+	}
+}
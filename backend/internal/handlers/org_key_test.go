@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrgKeyAllowed(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{"POST", "/api/errors", true},
+		{"GET", "/api/errors", true},
+		{"GET", "/api/errors/facets", true},
+		{"GET", "/api/errors/by-trace/abc123", true},
+		{"GET", "/api/stats", true},
+		{"GET", "/api/alerts/rules", true},
+		{"PUT", "/api/alerts/rules/9b2f1c4e-8a55-4c1e-9d4b-3f0e1a2b3c4d", true},
+		{"PUT", "/api/alerts/rules/bulk", false},
+		{"POST", "/api/alerts/incidents/", true},
+		{"GET", "/api/alerts/subscriptions", false},
+		{"GET", "/api/errors/tail", false},
+		{"GET", "/api/analytics/trends", false},
+		{"POST", "/api/exports", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := orgKeyAllowed(r); got != tt.want {
+			t.Errorf("orgKeyAllowed(%s %s) = %t, want %t", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOrgKeyErrorID(t *testing.T) {
+	const id = "9b2f1c4e-8a55-4c1e-9d4b-3f0e1a2b3c4d"
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{"GET", "/api/errors/" + id, true},
+		{"DELETE", "/api/errors/" + id, true},
+		{"PUT", "/api/errors/" + id + "/resolve", true},
+		{"PUT", "/api/errors/" + id + "/state/", true},
+		{"DELETE", "/api/errors/trash/" + id, true},
+		{"PUT", "/api/errors/" + id + "/runbook", false},
+		{"POST", "/api/errors/" + id + "/summarize", false},
+		{"GET", "/api/errors/" + id + "/events/export", false},
+		{"GET", "/api/errors/states", false},
+		{"GET", "/api/errors/trash/" + id, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		got, ok := orgKeyErrorID(r)
+		if ok != tt.want || (ok && got.String() != id) {
+			t.Errorf("orgKeyErrorID(%s %s) = %s, %t, want %t", tt.method, tt.path, got, ok, tt.want)
+		}
+	}
+}
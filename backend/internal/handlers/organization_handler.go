@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+	quotaService        *services.QuotaService
+}
+
+func NewOrganizationHandler(organizationService *services.OrganizationService, quotaService *services.QuotaService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		quotaService:        quotaService,
+	}
+}
+
+// requestOrgID is the organization the request's API key is scoped to; nil
+// for deployment-wide keys
+func requestOrgID(r *http.Request) *uuid.UUID {
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		return key.OrgID
+	}
+	return nil
+}
+
+// GetOrganizations returns the org switcher: every organization the key can
+// see, with its projects
+func (h *OrganizationHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgID := requestOrgID(r)
+
+	orgs, err := h.organizationService.GetSwitcher(r.Context(), orgID)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get organizations", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{
+		"organizations":  orgs,
+		"current_org_id": orgID,
+	})
+}
+
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	org, err := h.organizationService.CreateOrganization(r.Context(), &req, requestOrgID(r), requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, org)
+}
+
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.organizationService.GetOrganization(r.Context(), id, requestOrgID(r))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			writeErrorResponse(w, "Organization not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to get organization", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, org)
+}
+
+func (h *OrganizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	org, err := h.organizationService.UpdateOrganization(r.Context(), id, &req, requestOrgID(r), requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "organization not found" {
+			writeErrorResponse(w, "Organization not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to update organization", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, org)
+}
+
+func (h *OrganizationHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	project, err := h.organizationService.CreateProject(r.Context(), id, &req, requestOrgID(r), requestActor(r, ""))
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil {
+		if err.Error() == "organization not found" {
+			writeErrorResponse(w, "Organization not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to create project", http.StatusInternalServerError)
+		return
+	}
+
+	setVersionETag(w, project.Version)
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, project)
+}
+
+// GetProjects lists the projects the key can see, across organizations for
+// deployment-wide keys
+func (h *OrganizationHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.organizationService.GetProjects(r.Context(), requestOrgID(r), r.URL.Query().Get("external_id"))
+	if err != nil {
+		writeErrorResponse(w, "Failed to get projects", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"projects": projects})
+}
+
+func (h *OrganizationHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.organizationService.GetProject(r.Context(), id, requestOrgID(r))
+	if err != nil {
+		writeProjectError(w, err, "Failed to get project")
+		return
+	}
+
+	setVersionETag(w, project.Version)
+	writeSuccessResponse(w, project)
+}
+
+func (h *OrganizationHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.organizationService.UpdateProject(r.Context(), id, &req, requestOrgID(r), ifVersion, requestActor(r, ""))
+	if err != nil {
+		writeProjectError(w, err, "Failed to update project")
+		return
+	}
+
+	setVersionETag(w, project.Version)
+	writeSuccessResponse(w, project)
+}
+
+func (h *OrganizationHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.organizationService.DeleteProject(r.Context(), id, requestOrgID(r), ifVersion, requestActor(r, "")); err != nil {
+		writeProjectError(w, err, "Failed to delete project")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeProjectError(w http.ResponseWriter, err error, failure string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrganization):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrProjectInUse):
+		writeErrorResponse(w, err.Error(), http.StatusConflict)
+	case writeVersionedError(w, err):
+	case err.Error() == "project not found":
+		writeErrorResponse(w, "Project not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, failure, http.StatusInternalServerError)
+	}
+}
+
+func (h *OrganizationHandler) GetOrgQuota(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.visibleOrgID(w, r)
+	if !ok {
+		return
+	}
+
+	quota, err := h.quotaService.GetOrgQuota(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get quota", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, quota)
+}
+
+func (h *OrganizationHandler) UpdateOrgQuota(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.visibleOrgID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.DailySoftLimit < 0 || req.DailyHardLimit < 0 || req.MonthlySoftLimit < 0 || req.MonthlyHardLimit < 0 {
+		writeErrorResponse(w, "Limits must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	quota, err := h.quotaService.UpdateOrgQuota(r.Context(), id, &req)
+	if err != nil {
+		writeErrorResponse(w, "Failed to update quota", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, quota)
+}
+
+// visibleOrgID parses the {id} URL parameter and checks the organization
+// exists and the key may see it, writing the error response when not
+func (h *OrganizationHandler) visibleOrgID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid organization ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	if _, err := h.organizationService.GetOrganization(r.Context(), id, requestOrgID(r)); err != nil {
+		if err.Error() == "organization not found" {
+			writeErrorResponse(w, "Organization not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get organization", http.StatusInternalServerError)
+		}
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type OwnershipHandler struct {
+	ownershipService *services.OwnershipService
+}
+
+func NewOwnershipHandler(ownershipService *services.OwnershipService) *OwnershipHandler {
+	return &OwnershipHandler{
+		ownershipService: ownershipService,
+	}
+}
+
+func (h *OwnershipHandler) GetOwnershipRules(w http.ResponseWriter, r *http.Request) {
+	var projectID *uuid.UUID
+	if projectStr := r.URL.Query().Get("project_id"); projectStr != "" {
+		id, err := uuid.Parse(projectStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+		projectID = &id
+	}
+
+	rules, err := h.ownershipService.GetOwnershipRules(r.Context(), projectID)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get ownership rules", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"rules": rules})
+}
+
+func (h *OwnershipHandler) CreateOwnershipRule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOwnershipRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if msg := validateOwnershipRule(&req); msg != "" {
+		writeErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.ownershipService.CreateOwnershipRule(r.Context(), &req)
+	if err != nil {
+		writeErrorResponse(w, "Failed to create ownership rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, rule)
+}
+
+func (h *OwnershipHandler) UpdateOwnershipRule(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid ownership rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateOwnershipRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if msg := validateOwnershipRule(&req); msg != "" {
+		writeErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.ownershipService.UpdateOwnershipRule(r.Context(), id, &req)
+	if err != nil {
+		if err.Error() == "ownership rule not found" {
+			writeErrorResponse(w, "Ownership rule not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to update ownership rule", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, rule)
+}
+
+func (h *OwnershipHandler) DeleteOwnershipRule(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid ownership rule ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ownershipService.DeleteOwnershipRule(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, "Failed to delete ownership rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateOwnershipRule returns a client-facing message for invalid rules
+func validateOwnershipRule(req *models.CreateOwnershipRuleRequest) string {
+	switch req.MatchType {
+	case "source", "url", "path":
+	default:
+		return "Match type must be one of: source, url, path"
+	}
+	if req.Pattern == "" {
+		return "Pattern is required"
+	}
+	switch req.OwnerType {
+	case "":
+		req.OwnerType = "member"
+	case "member", "team":
+	default:
+		return "Owner type must be one of: member, team"
+	}
+	if req.OwnerID == uuid.Nil {
+		return "Owner ID is required"
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"error-logs/internal/models"
+)
+
+// parsePage reads the limit and offset query parameters of a list request.
+// page may be given instead of offset. Missing or out of range values fall
+// back to the defaults.
+func parsePage(r *http.Request, defaultLimit, maxLimit int) (limit, offset int) {
+	query := r.URL.Query()
+
+	limit = defaultLimit
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= maxLimit {
+		limit = l
+	}
+
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	} else if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		offset = (p - 1) * limit
+	}
+	return limit, offset
+}
+
+// newPagination describes the page at limit and offset in a list of total
+// items. The links keep the request's other query parameters.
+func newPagination(r *http.Request, total, limit, offset int) *models.Pagination {
+	pagination := &models.Pagination{
+		Total:  total,
+		Page:   (offset / limit) + 1,
+		Limit:  limit,
+		Offset: offset,
+		Links: models.PaginationLinks{
+			Self: pageLink(r, limit, offset),
+		},
+	}
+	if offset+limit < total {
+		pagination.Links.Next = pageLink(r, limit, offset+limit)
+	}
+	if offset > 0 {
+		pagination.Links.Prev = pageLink(r, limit, max(offset-limit, 0))
+	}
+	return pagination
+}
+
+func pageLink(r *http.Request, limit, offset int) string {
+	query := r.URL.Query()
+	query.Del("page")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// pageBounds returns the slice bounds of the page at limit and offset in a
+// list of n items held in memory
+func pageBounds(n, limit, offset int) (start, end int) {
+	return min(offset, n), min(offset+limit, n)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"error-logs/internal/services"
+)
+
+// maxPublicEventBytes caps request bodies sent with public keys
+const maxPublicEventBytes = 64 << 10
+
+// publicEndpoint describes how a POST endpoint open to public keys is guarded
+type publicEndpoint struct {
+	checkOrigin bool  // CSP reports are checked against their document URL instead
+	maxBytes    int64 // 0 leaves the limit to the handler
+}
+
+// publicReads are the GET endpoints open to public keys. They return nothing
+// about stored errors, so origins and rate limits are not checked.
+var publicReads = map[string]bool{
+	"/api/sdk/config": true,
+}
+
+// publicEndpoints are the ingestion endpoints open to public keys
+var publicEndpoints = publicIngestion()
+
+func publicIngestion() map[string]publicEndpoint {
+	endpoints := map[string]publicEndpoint{}
+	for path, route := range ingestionRoutes {
+		if route.public != nil {
+			endpoints[path] = *route.public
+		}
+	}
+	return endpoints
+}
+
+// PublicKeyMiddleware restricts public (browser and client app) API keys to
+// the reads in publicReads and the ingestion endpoints in publicEndpoints.
+// Ingestion must come from an allowed origin and stay within the per-IP rate
+// limit, reported in X-RateLimit-* headers. Secret keys pass through.
+func PublicKeyMiddleware(publicKeyService *services.PublicKeyService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromContext(r.Context())
+			if key == nil || key.KeyType != "public" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method == http.MethodGet && publicReads[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			endpoint, ok := publicEndpoints[r.URL.Path]
+			if r.Method != http.MethodPost || !ok {
+				writeError(w, codePublicKeyNotAllowed, "Public API keys can only submit errors", http.StatusForbidden)
+				return
+			}
+
+			if endpoint.checkOrigin {
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					origin = r.Header.Get("Referer")
+				}
+				if !publicKeyService.OriginAllowed(key, origin) {
+					writeError(w, codeOriginNotAllowed, "Origin not allowed", http.StatusForbidden)
+					return
+				}
+			}
+
+			allowed, rateLimit := publicKeyService.AllowRequest(r.Context(), key.ID, getClientIP(r))
+			setRateLimitHeaders(w, rateLimit)
+			if !allowed {
+				writeShed(w, r, codeRateLimited, "Rate limit exceeded", http.StatusTooManyRequests, services.RateLimitBackoff(rateLimit))
+				return
+			}
+
+			if endpoint.maxBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, endpoint.maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
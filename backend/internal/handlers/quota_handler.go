@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type QuotaHandler struct {
+	quotaService *services.QuotaService
+}
+
+func NewQuotaHandler(quotaService *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		quotaService: quotaService,
+	}
+}
+
+func (h *QuotaHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "month" {
+		groupBy = "day"
+	}
+
+	days := 30 // default
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 366 {
+			days = d
+		}
+	}
+
+	var projectID *uuid.UUID
+	if projectStr := r.URL.Query().Get("project_id"); projectStr != "" {
+		id, err := uuid.Parse(projectStr)
+		if err != nil {
+			writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+		projectID = &id
+	}
+
+	usage, err := h.quotaService.GetUsage(r.Context(), projectID, requestOrgID(r), groupBy, days)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get usage", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, usage)
+}
+
+func (h *QuotaHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	quota, err := h.quotaService.GetQuota(r.Context(), projectID, requestOrgID(r))
+	if err != nil && err.Error() == "project not found" {
+		writeErrorResponse(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get quota", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, quota)
+}
+
+func (h *QuotaHandler) UpdateQuota(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	// Validate limits
+	if req.DailySoftLimit < 0 || req.DailyHardLimit < 0 || req.MonthlySoftLimit < 0 || req.MonthlyHardLimit < 0 {
+		writeErrorResponse(w, "Limits must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	quota, err := h.quotaService.UpdateQuota(r.Context(), projectID, &req, requestOrgID(r))
+	if err != nil && err.Error() == "project not found" {
+		writeErrorResponse(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to update quota", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, quota)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"error-logs/internal/services"
+)
+
+// setRateLimitHeaders tells the client where it stands in its rate limit
+// window, so SDKs can slow down before they are refused
+func setRateLimitHeaders(w http.ResponseWriter, status *services.RateLimitStatus) {
+	if status == nil {
+		return
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+}
+
+// QuotaHeaderMiddleware adds X-Quota-Remaining to responses for API keys whose
+// project has a hard quota. The header is filled in when the response is
+// written, so it already counts the events the request itself sent.
+func QuotaHeaderMiddleware(quotaService *services.QuotaService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromContext(r.Context())
+			if key == nil || key.ProjectID == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(&quotaHeaderWriter{ResponseWriter: w, set: func() {
+				if remaining, ok := quotaService.Remaining(r.Context(), *key.ProjectID); ok {
+					w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+				}
+			}}, r)
+		})
+	}
+}
+
+// quotaHeaderWriter runs set just before the response headers are sent
+type quotaHeaderWriter struct {
+	http.ResponseWriter
+	set  func()
+	done bool
+}
+
+func (w *quotaHeaderWriter) WriteHeader(statusCode int) {
+	if !w.done {
+		w.done = true
+		w.set()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *quotaHeaderWriter) Write(b []byte) (int, error) {
+	if !w.done {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// hijack the connection for a WebSocket
+func (w *quotaHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
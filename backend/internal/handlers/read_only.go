@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+// readOnlyAllowedWrites are the writes accepted in read-only mode. Error
+// events, CSP reports, Kubernetes events and serverless and Heroku errors are
+// queued in Redis and stored once it is turned off.
+var readOnlyAllowedWrites = map[string]bool{
+	"POST /api/errors":             true,
+	"POST /api/csp-reports":        true,
+	"POST /api/kubernetes/events":  true,
+	"POST /api/serverless/lambda":  true,
+	"POST /api/serverless/vercel":  true,
+	"POST /api/serverless/netlify": true,
+	"POST /api/heroku/logs":        true,
+	"PUT /api/admin/read-only":     true,
+}
+
+// ReadOnlyMiddleware rejects writes with 503 while read-only mode is on
+func ReadOnlyMiddleware(readOnlyService *services.ReadOnlyService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if readOnlyAllowedWrites[r.Method+" "+strings.TrimSuffix(r.URL.Path, "/")] || !readOnlyService.Enabled(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Retry-After", "60")
+			writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		})
+	}
+}
+
+type ReadOnlyHandler struct {
+	readOnlyService *services.ReadOnlyService
+}
+
+func NewReadOnlyHandler(readOnlyService *services.ReadOnlyService) *ReadOnlyHandler {
+	return &ReadOnlyHandler{
+		readOnlyService: readOnlyService,
+	}
+}
+
+func (h *ReadOnlyHandler) GetReadOnlyMode(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponse(w, h.readOnlyService.Status(r.Context()))
+}
+
+// SetReadOnlyMode needs a deployment-wide admin key, since the mode applies
+// to every organization. The actor is always the key, never a name from the
+// request, so the change cannot be attributed to someone else.
+func (h *ReadOnlyHandler) SetReadOnlyMode(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.SetReadOnlyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if !req.Enabled && h.readOnlyService.Status(r.Context()).Forced {
+		writeErrorResponse(w, "Read-only mode is forced by READ_ONLY_MODE", http.StatusConflict)
+		return
+	}
+
+	status, err := h.readOnlyService.SetMode(r.Context(), req.Enabled, req.Reason, requestActor(r, ""))
+	if err != nil {
+		writeErrorResponse(w, "Failed to set read-only mode", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, status)
+}
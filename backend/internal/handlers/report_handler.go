@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"error-logs/internal/services"
+)
+
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// GetReport renders the weekly or monthly report as HTML, or as a PDF or
+// JSON with format=pdf or format=json
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" && format != "json" {
+		writeErrorResponse(w, "format must be html, pdf or json", http.StatusBadRequest)
+		return
+	}
+
+	var date *time.Time
+	if dateStr := query.Get("date"); dateStr != "" {
+		t, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			writeErrorResponse(w, "date must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		date = &t
+	}
+
+	report, err := h.reportService.GetReport(r.Context(), chi.URLParam(r, "period"), date, query.Get("tz"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReport) || errors.Is(err, services.ErrInvalidTimezone) {
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeErrorResponse(w, "Failed to generate report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if format == "json" {
+		writeSuccessResponse(w, report)
+		return
+	}
+
+	if format == "pdf" {
+		pdf, err := services.RenderReportPDF(report)
+		if err != nil {
+			writeErrorResponse(w, "Failed to render report", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, services.ReportPDFFilename(report)))
+		w.Write(pdf)
+		return
+	}
+
+	html, err := services.RenderReportHTML(report)
+	if err != nil {
+		writeErrorResponse(w, "Failed to render report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+}
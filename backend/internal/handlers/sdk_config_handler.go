@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type SDKConfigHandler struct {
+	sdkConfigService *services.SDKConfigService
+}
+
+func NewSDKConfigHandler(sdkConfigService *services.SDKConfigService) *SDKConfigHandler {
+	return &SDKConfigHandler{
+		sdkConfigService: sdkConfigService,
+	}
+}
+
+// GetSDKConfig returns the config for the calling key's project. Public keys
+// may call it too. The ETag lets SDKs poll for changes cheaply.
+func (h *SDKConfigHandler) GetSDKConfig(w http.ResponseWriter, r *http.Request) {
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	config := h.sdkConfigService.ConfigFor(r.Context(), projectID)
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get SDK config", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`W/"%x"`, sum[:12])
+	if notModified(w, r, etag) {
+		return
+	}
+
+	setETag(w, etag)
+	writeSuccessResponse(w, config)
+}
+
+func (h *SDKConfigHandler) GetProjectSDKConfig(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	config, err := h.sdkConfigService.GetConfig(r.Context(), projectID)
+	if err != nil {
+		writeErrorResponse(w, "Failed to get SDK config", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, config)
+}
+
+func (h *SDKConfigHandler) UpdateProjectSDKConfig(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateSDKConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	config, err := h.sdkConfigService.UpdateConfig(r.Context(), projectID, &req)
+	if errors.Is(err, services.ErrInvalidSDKConfig) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to update SDK config", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, config)
+}
+
+func (h *SDKConfigHandler) DeleteProjectSDKConfig(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "projectId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sdkConfigService.DeleteConfig(r.Context(), projectID); err != nil {
+		if err.Error() == "SDK config not found" {
+			writeErrorResponse(w, "SDK config not found", http.StatusNotFound)
+			return
+		}
+		writeErrorResponse(w, "Failed to delete SDK config", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
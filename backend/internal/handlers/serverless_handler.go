@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type ServerlessHandler struct {
+	serverlessService *services.ServerlessService
+	quotaService      *services.QuotaService
+	vercelVerifyToken string
+}
+
+func NewServerlessHandler(serverlessService *services.ServerlessService, quotaService *services.QuotaService, vercelVerifyToken string) *ServerlessHandler {
+	return &ServerlessHandler{
+		serverlessService: serverlessService,
+		quotaService:      quotaService,
+		vercelVerifyToken: vercelVerifyToken,
+	}
+}
+
+type serverlessIngestFunc func(ctx context.Context, body []byte, projectID *uuid.UUID, environment, userAgent, ipAddress string) (int, error)
+
+// IngestLambda accepts a CloudWatch Logs subscription payload of Lambda
+// function logs
+func (h *ServerlessHandler) IngestLambda(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+	h.ingest(w, r, body, h.serverlessService.IngestLambda)
+}
+
+// IngestVercel accepts a Vercel log drain delivery. Vercel checks a new
+// drain's URL by looking for the x-vercel-verify header on the response.
+func (h *ServerlessHandler) IngestVercel(w http.ResponseWriter, r *http.Request) {
+	if h.vercelVerifyToken != "" {
+		w.Header().Set("x-vercel-verify", h.vercelVerifyToken)
+	}
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+	if err := h.serverlessService.VerifyVercelSignature(body, r.Header.Get("x-vercel-signature")); err != nil {
+		writeError(w, codeInvalidSignature, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.ingest(w, r, body, h.serverlessService.IngestVercel)
+}
+
+// IngestNetlify accepts a Netlify log drain delivery
+func (h *ServerlessHandler) IngestNetlify(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+	h.ingest(w, r, body, h.serverlessService.IngestNetlify)
+}
+
+func (h *ServerlessHandler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		writeErrorResponse(w, "Failed to read logs", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+func (h *ServerlessHandler) ingest(w http.ResponseWriter, r *http.Request, body []byte, ingest serverlessIngestFunc) {
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	accepted, err := ingest(r.Context(), body, projectID, r.URL.Query().Get("environment"), r.Header.Get("User-Agent"), getClientIP(r))
+	if errors.Is(err, services.ErrInvalidServerlessLogs) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == services.ErrQuotaExceeded {
+		writeShed(w, r, codeQuotaExceeded, "Quota exceeded", http.StatusTooManyRequests, h.quotaService.QuotaBackoff(r.Context(), *projectID))
+		return
+	}
+	if err == services.ErrReadOnly {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, codeReadOnly, "The API is in read-only mode for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if err == services.ErrIngestUnavailable {
+		writeShed(w, r, codeIngestionUnavailable, "Ingestion is temporarily unavailable, retry later", http.StatusServiceUnavailable, services.UnavailableBackoff())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record serverless errors", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
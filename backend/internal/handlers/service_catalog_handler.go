@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type ServiceCatalogHandler struct {
+	serviceCatalogService *services.ServiceCatalogService
+}
+
+func NewServiceCatalogHandler(serviceCatalogService *services.ServiceCatalogService) *ServiceCatalogHandler {
+	return &ServiceCatalogHandler{
+		serviceCatalogService: serviceCatalogService,
+	}
+}
+
+func (h *ServiceCatalogHandler) GetServices(w http.ResponseWriter, r *http.Request) {
+	response, err := h.serviceCatalogService.GetServices(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get services", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
+
+func (h *ServiceCatalogHandler) GetDependencyGraph(w http.ResponseWriter, r *http.Request) {
+	graph, err := h.serviceCatalogService.GetDependencyGraph(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get service graph", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, graph)
+}
+
+func (h *ServiceCatalogHandler) GetService(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	service, err := h.serviceCatalogService.GetService(r.Context(), id)
+	if err != nil {
+		if err.Error() == "service not found" {
+			writeErrorResponse(w, "Service not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get service", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, service)
+}
+
+func (h *ServiceCatalogHandler) CreateService(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	service, err := h.serviceCatalogService.CreateService(r.Context(), &req)
+	if errors.Is(err, services.ErrInvalidService) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to create service", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, service)
+}
+
+func (h *ServiceCatalogHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	service, err := h.serviceCatalogService.UpdateService(r.Context(), id, &req)
+	if errors.Is(err, services.ErrInvalidService) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if err.Error() == "service not found" {
+			writeErrorResponse(w, "Service not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to update service", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, service)
+}
+
+func (h *ServiceCatalogHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceCatalogService.DeleteService(r.Context(), id); err != nil {
+		if err.Error() == "service not found" {
+			writeErrorResponse(w, "Service not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to delete service", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ServiceCatalogHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	dashboard, err := h.serviceCatalogService.GetDashboard(r.Context(), id)
+	if err != nil {
+		if err.Error() == "service not found" {
+			writeErrorResponse(w, "Service not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get service dashboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, dashboard)
+}
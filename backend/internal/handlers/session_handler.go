@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+func (h *SessionHandler) RecordSessions(w http.ResponseWriter, r *http.Request) {
+	var req models.IngestSessionsRequest
+	err := decodeBatch(r.Body, "sessions", services.MaxSessionBatch, func(dec *json.Decoder) error {
+		var item models.SessionUpdate
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		req.Sessions = append(req.Sessions, item)
+		return nil
+	})
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var projectID *uuid.UUID
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		projectID = key.ProjectID
+	}
+
+	accepted, err := h.sessionService.RecordSessions(r.Context(), &req, projectID)
+	if errors.Is(err, services.ErrInvalidSession) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to record sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeSuccessResponse(w, map[string]int{"accepted": accepted})
+}
+
+func (h *SessionHandler) GetSessionHealth(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	health, err := h.sessionService.GetSessionHealth(r.Context(), query.Get("release"), query.Get("environment"), query.Get("period"))
+	if err != nil {
+		writeErrorResponse(w, "Failed to get session health", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, health)
+}
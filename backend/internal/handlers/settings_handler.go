@@ -5,8 +5,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -17,28 +19,54 @@ import (
 
 type SettingsHandler struct {
 	settingsService *services.SettingsService
+	knownAPIKeys    *KnownAPIKeys
 }
 
-func NewSettingsHandler(settingsService *services.SettingsService) *SettingsHandler {
+func NewSettingsHandler(settingsService *services.SettingsService, knownAPIKeys *KnownAPIKeys) *SettingsHandler {
 	return &SettingsHandler{
 		settingsService: settingsService,
+		knownAPIKeys:    knownAPIKeys,
 	}
 }
 
 func (h *SettingsHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
-	apiKeys, err := h.settingsService.GetAPIKeys(r.Context())
+	limit, offset := parsePage(r, 50, 500)
+
+	apiKeys, err := h.settingsService.GetAPIKeys(r.Context(), requestOrgID(r), r.URL.Query().Get("external_id"))
 	if err != nil {
 		writeErrorResponse(w, "Failed to get API keys", http.StatusInternalServerError)
 		return
 	}
 
-	writeSuccessResponse(w, map[string]interface{}{"api_keys": apiKeys})
+	start, end := pageBounds(len(apiKeys), limit, offset)
+	writeSuccessResponse(w, map[string]interface{}{
+		"api_keys":   apiKeys[start:end],
+		"pagination": newPagination(r, len(apiKeys), limit, offset),
+	})
+}
+
+// checkGrantedPermissions rejects permissions the caller's own key does not
+// have, so no key can mint a more powerful one. Only deployment-wide admin
+// keys may grant admin.
+func checkGrantedPermissions(w http.ResponseWriter, r *http.Request, permissions []string) bool {
+	key := apiKeyFromContext(r.Context())
+	for _, permission := range permissions {
+		if key == nil || !slices.Contains(key.Permissions, permission) {
+			writeError(w, codePermissionNotGrantable, fmt.Sprintf("The API key can't grant the %q permission, which it doesn't have", permission), http.StatusForbidden)
+			return false
+		}
+		if permission == "admin" && !isDeploymentAdmin(key) {
+			writeError(w, codePermissionNotGrantable, "Only deployment-wide admin keys can grant the admin permission", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
 }
 
 func (h *SettingsHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateAPIKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -50,6 +78,27 @@ func (h *SettingsHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	if len(req.Permissions) == 0 {
 		req.Permissions = []string{"read"}
 	}
+	if req.KeyType == "" {
+		req.KeyType = "secret"
+	}
+	if req.KeyType != "secret" && req.KeyType != "public" {
+		writeErrorResponse(w, "Key type must be secret or public", http.StatusBadRequest)
+		return
+	}
+	if req.KeyType == "public" {
+		// Public keys can only ingest errors
+		req.Permissions = []string{"write"}
+		if len(req.AllowedOrigins) == 0 {
+			writeErrorResponse(w, "Public keys require at least one allowed origin", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.AllowedOrigins == nil {
+		req.AllowedOrigins = []string{}
+	}
+	if !checkGrantedPermissions(w, r, req.Permissions) {
+		return
+	}
 
 	// Generate API key
 	keyBytes := make([]byte, 32)
@@ -58,10 +107,21 @@ func (h *SettingsHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKey := "sk_" + hex.EncodeToString(keyBytes)
+	prefix := "sk_"
+	if req.KeyType == "public" {
+		prefix = "pk_"
+	}
+	apiKey := prefix + hex.EncodeToString(keyBytes)
 	keyHash := fmt.Sprintf("%x", sha256.Sum256([]byte(apiKey)))
 
-	key, err := h.settingsService.CreateAPIKey(r.Context(), &req, keyHash)
+	key, err := h.settingsService.CreateAPIKey(r.Context(), &req, keyHash, requestOrgID(r))
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to create API key", http.StatusInternalServerError)
 		return
@@ -75,12 +135,84 @@ func (h *SettingsHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		"permissions": key.Permissions,
 		"expires_at":  key.ExpiresAt,
 		"created_at":  key.CreatedAt,
+
+		"project_id":      key.ProjectID,
+		"org_id":          key.OrgID,
+		"key_type":        key.KeyType,
+		"allowed_origins": key.AllowedOrigins,
+		"external_id":     key.ExternalID,
+		"version":         key.Version,
 	}
 
+	setVersionETag(w, key.Version)
 	w.WriteHeader(http.StatusCreated)
 	writeSuccessResponse(w, response)
 }
 
+func (h *SettingsHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.settingsService.GetAPIKey(r.Context(), id, requestOrgID(r))
+	if err != nil && err.Error() == "API key not found" {
+		writeErrorResponse(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to get API key", http.StatusInternalServerError)
+		return
+	}
+
+	setVersionETag(w, key.Version)
+	writeSuccessResponse(w, key)
+}
+
+func (h *SettingsHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Permissions != nil && !checkGrantedPermissions(w, r, *req.Permissions) {
+		return
+	}
+
+	key, err := h.settingsService.UpdateAPIKey(r.Context(), id, &req, requestOrgID(r), ifVersion)
+	if errors.Is(err, services.ErrInvalidAPIKeyChange) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil && err.Error() == "API key not found" {
+		writeErrorResponse(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to update API key", http.StatusInternalServerError)
+		return
+	}
+	h.knownAPIKeys.Forget(id)
+
+	setVersionETag(w, key.Version)
+	writeSuccessResponse(w, key)
+}
+
 func (h *SettingsHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -89,29 +221,95 @@ func (h *SettingsHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.settingsService.DeleteAPIKey(r.Context(), id)
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.settingsService.DeleteAPIKey(r.Context(), id, requestOrgID(r), ifVersion)
+	if writeVersionedError(w, err) {
+		return
+	}
+	if err != nil && err.Error() == "API key not found" {
+		writeErrorResponse(w, "API key not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to delete API key", http.StatusInternalServerError)
 		return
 	}
+	h.knownAPIKeys.Forget(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EnableRequestSigning requires signed requests for a key and returns the new
+// signing secret. This is the only time the secret is shown.
+func (h *SettingsHandler) EnableRequestSigning(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := h.settingsService.EnableRequestSigning(r.Context(), id, requestOrgID(r))
+	if errors.Is(err, services.ErrPublicKeySigning) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && err.Error() == "API key not found" {
+		writeErrorResponse(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to enable request signing", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, models.RequestSigningResponse{SigningSecret: secret})
+}
+
+func (h *SettingsHandler) DisableRequestSigning(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.settingsService.DisableRequestSigning(r.Context(), id, requestOrgID(r))
+	if err != nil && err.Error() == "API key not found" {
+		writeErrorResponse(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, "Failed to disable request signing", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *SettingsHandler) GetTeamMembers(w http.ResponseWriter, r *http.Request) {
-	members, err := h.settingsService.GetTeamMembers(r.Context())
+	limit, offset := parsePage(r, 50, 500)
+
+	members, err := h.settingsService.GetTeamMembers(r.Context(), requestOrgID(r))
 	if err != nil {
 		writeErrorResponse(w, "Failed to get team members", http.StatusInternalServerError)
 		return
 	}
 
-	writeSuccessResponse(w, map[string]interface{}{"members": members})
+	start, end := pageBounds(len(members), limit, offset)
+	writeSuccessResponse(w, map[string]interface{}{
+		"members":    members[start:end],
+		"pagination": newPagination(r, len(members), limit, offset),
+	})
 }
 
 func (h *SettingsHandler) InviteTeamMember(w http.ResponseWriter, r *http.Request) {
 	var req models.InviteTeamMemberRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -120,11 +318,17 @@ func (h *SettingsHandler) InviteTeamMember(w http.ResponseWriter, r *http.Reques
 		writeErrorResponse(w, "Email is required", http.StatusBadRequest)
 		return
 	}
-	if req.Role == "" {
-		req.Role = "viewer"
+	if req.Role != "" && !slices.Contains(services.MemberRoles, req.Role) {
+		writeErrorResponse(w, "Role must be one of owner, admin, developer or viewer", http.StatusBadRequest)
+		return
 	}
 
-	member, err := h.settingsService.InviteTeamMember(r.Context(), &req)
+	// An empty role takes the organization's default_role, or viewer
+	member, err := h.settingsService.InviteTeamMember(r.Context(), &req, requestOrgID(r))
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, "Failed to invite team member", http.StatusInternalServerError)
 		return
@@ -135,7 +339,7 @@ func (h *SettingsHandler) InviteTeamMember(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *SettingsHandler) GetIntegrations(w http.ResponseWriter, r *http.Request) {
-	integrations, err := h.settingsService.GetIntegrations(r.Context())
+	integrations, err := h.settingsService.GetIntegrations(r.Context(), r.URL.Query().Get("external_id"))
 	if err != nil {
 		writeErrorResponse(w, "Failed to get integrations", http.StatusInternalServerError)
 		return
@@ -143,3 +347,270 @@ func (h *SettingsHandler) GetIntegrations(w http.ResponseWriter, r *http.Request
 
 	writeSuccessResponse(w, map[string]interface{}{"integrations": integrations})
 }
+
+func (h *SettingsHandler) GetIntegration(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid integration ID", http.StatusBadRequest)
+		return
+	}
+
+	integration, err := h.settingsService.GetIntegration(r.Context(), id)
+	if err != nil {
+		writeIntegrationError(w, err, "Failed to get integration")
+		return
+	}
+
+	setVersionETag(w, integration.Version)
+	writeSuccessResponse(w, integration)
+}
+
+// CreateIntegration adds an integration. Integrations are shared by every
+// organization, so only deployment admins change them.
+func (h *SettingsHandler) CreateIntegration(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	var req models.CreateIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	integration, err := h.settingsService.CreateIntegration(r.Context(), &req)
+	if err != nil {
+		writeIntegrationError(w, err, "Failed to create integration")
+		return
+	}
+
+	setVersionETag(w, integration.Version)
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, integration)
+}
+
+func (h *SettingsHandler) UpdateIntegration(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid integration ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	integration, err := h.settingsService.UpdateIntegration(r.Context(), id, &req, ifVersion)
+	if err != nil {
+		writeIntegrationError(w, err, "Failed to update integration")
+		return
+	}
+
+	setVersionETag(w, integration.Version)
+	writeSuccessResponse(w, integration)
+}
+
+func (h *SettingsHandler) DeleteIntegration(w http.ResponseWriter, r *http.Request) {
+	if !requireDeploymentAdmin(w, r) {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid integration ID", http.StatusBadRequest)
+		return
+	}
+	ifVersion, err := ifMatchVersion(r)
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.settingsService.DeleteIntegration(r.Context(), id, ifVersion); err != nil {
+		writeIntegrationError(w, err, "Failed to delete integration")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeIntegrationError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidIntegration):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case writeVersionedError(w, err):
+	case err.Error() == "integration not found":
+		writeErrorResponse(w, "Integration not found", http.StatusNotFound)
+	default:
+		writeErrorResponse(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *SettingsHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.settingsService.GetTeams(r.Context())
+	if err != nil {
+		writeErrorResponse(w, "Failed to get teams", http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccessResponse(w, map[string]interface{}{"teams": teams})
+}
+
+func (h *SettingsHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	team, err := h.settingsService.GetTeam(r.Context(), id)
+	if err != nil {
+		if err.Error() == "team not found" {
+			writeErrorResponse(w, "Team not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to get team", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, team)
+}
+
+func (h *SettingsHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	// Validate required fields
+	if req.Name == "" {
+		writeErrorResponse(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	team, err := h.settingsService.CreateTeam(r.Context(), &req)
+	if err != nil {
+		writeErrorResponse(w, "Failed to create team", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeSuccessResponse(w, team)
+}
+
+func (h *SettingsHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.Name == "" {
+		writeErrorResponse(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	team, err := h.settingsService.UpdateTeam(r.Context(), id, &req)
+	if err != nil {
+		if err.Error() == "team not found" {
+			writeErrorResponse(w, "Team not found", http.StatusNotFound)
+		} else {
+			writeErrorResponse(w, "Failed to update team", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, team)
+}
+
+func (h *SettingsHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.settingsService.DeleteTeam(r.Context(), id)
+	if err != nil {
+		writeErrorResponse(w, "Failed to delete team", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SettingsHandler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeErrorResponse(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.MemberID == uuid.Nil {
+		writeErrorResponse(w, "Member ID is required", http.StatusBadRequest)
+		return
+	}
+
+	team, err := h.settingsService.AddTeamMember(r.Context(), id, req.MemberID, requestOrgID(r))
+	if err != nil {
+		switch err.Error() {
+		case "team not found", "team member not found":
+			writeErrorResponse(w, "Team or member not found", http.StatusNotFound)
+		default:
+			writeErrorResponse(w, "Failed to add team member", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, team)
+}
+
+func (h *SettingsHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "memberId"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid member ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.settingsService.RemoveTeamMember(r.Context(), id, memberID)
+	if err != nil {
+		writeErrorResponse(w, "Failed to remove team member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
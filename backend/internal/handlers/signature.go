@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"error-logs/internal/services"
+)
+
+// SignatureMiddleware verifies the X-Signature header on requests made with
+// API keys that require signed requests. The body is read in full to check
+// it, within the limits set by BodyLimitMiddleware or, on upload endpoints,
+// the upload's own cap, and handed on unchanged.
+func SignatureMiddleware(signingService *services.RequestSigningService, limits BodyLimits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromContext(r.Context())
+			if key == nil || key.SigningSecret == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if limits.bodyLimitFor(r) == 0 {
+				maxBytes := limits.uploadLimitFor(r)
+				if r.ContentLength > maxBytes {
+					writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeErrorResponse(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				writeErrorResponse(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			err = signingService.Verify(r.Context(), *key.SigningSecret,
+				r.Header.Get("X-Signature-Timestamp"), r.Header.Get("X-Signature"),
+				r.Method, r.URL.RequestURI(), body)
+			if errors.Is(err, services.ErrInvalidSignature) {
+				writeError(w, codeInvalidSignature, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				writeErrorResponse(w, "Failed to verify signature", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
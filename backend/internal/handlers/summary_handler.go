@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/services"
+)
+
+type SummaryHandler struct {
+	summaryService *services.SummaryService
+}
+
+func NewSummaryHandler(summaryService *services.SummaryService) *SummaryHandler {
+	return &SummaryHandler{summaryService: summaryService}
+}
+
+// Summarize returns a generated explanation of the error's group, from the
+// cache unless ?refresh=true
+func (h *SummaryHandler) Summarize(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.summaryService.Summarize(r.Context(), id, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		switch {
+		case err.Error() == "error not found":
+			writeErrorResponse(w, "Error not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrSummariesDisabled):
+			writeError(w, codeSummariesDisabled, "Error summaries are not configured", http.StatusServiceUnavailable)
+		case errors.Is(err, services.ErrSummaryFailed):
+			writeErrorResponse(w, err.Error(), http.StatusBadGateway)
+		default:
+			writeErrorResponse(w, "Failed to summarize error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeSuccessResponse(w, summary)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+	"error-logs/internal/websocket"
+)
+
+const (
+	// tailPingInterval keeps idle live tail connections open through proxies
+	tailPingInterval = 30 * time.Second
+	// maxTailCommandBytes caps a live tail client message
+	maxTailCommandBytes = 16 << 10
+)
+
+type TailHandler struct {
+	tailService *services.TailService
+}
+
+func NewTailHandler(tailService *services.TailService) *TailHandler {
+	return &TailHandler{
+		tailService: tailService,
+	}
+}
+
+// tailRequest is a client message as read off the connection
+type tailRequest struct {
+	command models.TailCommand
+	err     error
+}
+
+// TailErrors streams stored events matching the query's filter over a
+// WebSocket. The client may change the filter and pause or resume the
+// stream with TailCommand messages; everything is written from this
+// goroutine so held events are sent before newer ones.
+func (h *TailHandler) TailErrors(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		writeErrorResponse(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	filter := parseErrorFilter(r)
+
+	conn, err := websocket.Upgrade(w, r, maxTailCommandBytes)
+	if err != nil {
+		log.Printf("Live tail upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close(websocket.CloseNormal, "")
+
+	subscription := h.tailService.Subscribe(filter)
+	defer h.tailService.Unsubscribe(subscription)
+
+	requests := make(chan tailRequest)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var request tailRequest
+			if err := json.Unmarshal(data, &request.command); err != nil {
+				request.err = fmt.Errorf("%w: %v", services.ErrInvalidTailCommand, err)
+			}
+			select {
+			case requests <- request:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	send := func(message models.TailMessage) bool {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Failed to marshal tail message: %v", err)
+			return true
+		}
+		return conn.WriteText(data) == nil
+	}
+	sendDropped := func() bool {
+		if dropped := subscription.TakeDropped(); dropped > 0 {
+			return send(models.TailMessage{Type: "dropped", Dropped: dropped})
+		}
+		return true
+	}
+
+	if !send(subscription.Status()) {
+		return
+	}
+
+	ticker := time.NewTicker(tailPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			conn.Close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case event := <-subscription.Events():
+			if !send(models.TailMessage{Type: "event", Error: event}) || !sendDropped() {
+				return
+			}
+		case request := <-requests:
+			if request.err == nil {
+				var held []*models.Error
+				held, request.err = subscription.Apply(request.command)
+				for _, event := range held {
+					if !send(models.TailMessage{Type: "event", Error: event}) {
+						return
+					}
+				}
+			}
+			if request.err != nil {
+				if !send(models.TailMessage{Type: "error", Message: request.err.Error()}) {
+					return
+				}
+				continue
+			}
+			if !send(subscription.Status()) || !sendDropped() {
+				return
+			}
+		case <-ticker.C:
+			if !sendDropped() || conn.Ping() != nil {
+				return
+			}
+		}
+	}
+}
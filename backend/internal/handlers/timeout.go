@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestTimeouts are the per-route budgets applied by TimeoutMiddleware.
+// Live tail and inbox stream connections stay open as long as the client
+// wants.
+type RequestTimeouts struct {
+	Default time.Duration // any route not listed below
+	Ingest  time.Duration // SDK event ingestion, which should fail fast
+	Long    time.Duration // uploads, downloads, long-polling, NDJSON streams and error summaries
+}
+
+func (t RequestTimeouts) timeoutFor(r *http.Request) time.Duration {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if r.Method == http.MethodPost {
+		if route, ok := ingestionRoutes[path]; ok {
+			if route.upload {
+				return t.Long
+			}
+			return t.Ingest
+		}
+		switch path {
+		case "/api/debug-files", "/api/import":
+			return t.Long
+		}
+	}
+	if path == "/api/errors/tail" || path == "/api/notifications/stream" {
+		return 0
+	}
+	if path == "/api/audit-log" && wantsNDJSON(r) {
+		return t.Long
+	}
+	if path == "/api/errors/updates" ||
+		(r.Method == http.MethodPost && strings.HasPrefix(path, "/api/errors/") && strings.HasSuffix(path, "/summarize")) ||
+		(strings.HasPrefix(path, "/api/errors/") && strings.HasSuffix(path, "/events/export")) ||
+		(strings.HasPrefix(path, "/api/minidumps/") && strings.HasSuffix(path, "/download")) ||
+		(strings.HasPrefix(path, "/exports/") && strings.HasSuffix(path, "/download")) {
+		return t.Long
+	}
+	return t.Default
+}
+
+// TimeoutMiddleware cancels the request context once the route's budget is
+// spent and answers 504 if the handler gave up because of it
+func TimeoutMiddleware(timeouts RequestTimeouts) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeouts.timeoutFor(r)
+			if timeout == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			middleware.Timeout(timeout)(next).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/services"
+)
+
+type WatchHandler struct {
+	watchService *services.WatchService
+}
+
+func NewWatchHandler(watchService *services.WatchService) *WatchHandler {
+	return &WatchHandler{
+		watchService: watchService,
+	}
+}
+
+func (h *WatchHandler) WatchError(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.WatchErrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.MemberID == uuid.Nil {
+		writeErrorResponse(w, "member_id is required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.watchService.Watch(r.Context(), id, req.MemberID, requestActor(r, ""))
+	if err != nil {
+		writeWatchError(w, err, "Failed to watch error")
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
+
+func (h *WatchHandler) UnwatchError(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := uuid.Parse(r.URL.Query().Get("member_id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid member_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchService.Unwatch(r.Context(), id, memberID, requestActor(r, "")); err != nil {
+		writeWatchError(w, err, "Failed to unwatch error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WatchHandler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorResponse(w, "Invalid error ID", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.watchService.GetWatchers(r.Context(), id)
+	if err != nil {
+		writeWatchError(w, err, "Failed to get watchers")
+		return
+	}
+
+	writeSuccessResponse(w, response)
+}
+
+func writeWatchError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "error not found":
+		writeErrorResponse(w, "Error not found", http.StatusNotFound)
+	case err.Error() == "team member not found":
+		writeErrorResponse(w, "Team member not found", http.StatusNotFound)
+	case err.Error() == "watch not found":
+		writeErrorResponse(w, "Watch not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNoErrorGroup):
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
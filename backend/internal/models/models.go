@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +26,57 @@ type Error struct {
 	LastSeen    time.Time              `json:"last_seen" db:"last_seen"`
 	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+
+	Release           *string `json:"release" db:"release"`
+	ResolvedInRelease *string `json:"resolved_in_release" db:"resolved_in_release"`
+	Regressed         bool    `json:"regressed" db:"regressed"`
+
+	ProjectID    *uuid.UUID `json:"project_id" db:"project_id"`
+	AssignedTo   *uuid.UUID `json:"assigned_to" db:"assigned_to"`
+	AssignedTeam *uuid.UUID `json:"assigned_team" db:"assigned_team"`
+
+	IndexedContext map[string]string `json:"-" db:"indexed_context"`
+	TraceID        *string           `json:"trace_id" db:"trace_id"`
+
+	ResolvedBy     *string    `json:"resolved_by" db:"resolved_by"`
+	ResolvedAt     *time.Time `json:"resolved_at" db:"resolved_at"`
+	ResolutionNote *string    `json:"resolution_note" db:"resolution_note"`
+
+	// State is where the error is in triage; see ErrorStates. Resolved is
+	// true while it is resolved or ignored.
+	State string `json:"state" db:"state"`
+
+	// Set when the project's auto-resolve policy resolved the error
+	AutoResolved bool `json:"auto_resolved" db:"auto_resolved"`
+
+	// Set while the error is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	Region     *string `json:"region" db:"region"`
+	Deployment *string `json:"deployment" db:"deployment"`
+
+	// DuplicateOf links an event to the same error reported by another source
+	// for the same trace; duplicates are left out of stats
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty" db:"duplicate_of"`
+
+	// SchemaViolations lists where the context breaks the project's context
+	// schema: nil when the project has none, empty when the context conforms
+	SchemaViolations []string `json:"schema_violations" db:"schema_violations"`
+
+	// Runbook attached to the error group, if any
+	Runbook *Runbook `json:"runbook,omitempty" db:"-"`
+
+	// Impact score of the error group, set while it is unresolved and active
+	ImpactScore *float64 `json:"impact_score,omitempty" db:"-"`
+
+	// Events of the group that spike protection dropped while sampling
+	SuppressedCount int `json:"suppressed_count" db:"-"`
+}
+
+// Runbook points responders at remediation steps for an alert rule or error group
+type Runbook struct {
+	URL   *string `json:"url"`
+	Notes string  `json:"notes"`
 }
 
 type CreateErrorRequest struct {
@@ -35,6 +87,109 @@ type CreateErrorRequest struct {
 	Source      string                 `json:"source"`
 	Environment *string                `json:"environment"`
 	URL         *string                `json:"url"`
+	Release     *string                `json:"release"`
+	Region      *string                `json:"region"`
+	Deployment  *string                `json:"deployment"`
+}
+
+type ResolveErrorRequest struct {
+	Mode       string `json:"mode"` // "now" (default) or "in_release"
+	Release    string `json:"release"`
+	Note       string `json:"note"`
+	ResolvedBy string `json:"resolved_by"` // defaults to the API key
+}
+
+type UnresolveErrorRequest struct {
+	Note         string `json:"note"`
+	UnresolvedBy string `json:"unresolved_by"` // defaults to the API key
+}
+
+// ErrorStates are the lifecycle states of an error, in triage order
+var ErrorStates = []string{"new", "acknowledged", "in_progress", "resolved", "ignored", "regressed"}
+
+// ChangeErrorStateRequest moves an error to another lifecycle state
+type ChangeErrorStateRequest struct {
+	State     string `json:"state"`
+	Note      string `json:"note"`
+	ChangedBy string `json:"changed_by"` // defaults to the API key
+}
+
+// ErrorFilter holds the filters accepted by the error list and facet endpoints
+type ErrorFilter struct {
+	Level       string `json:"level,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Status      string `json:"status,omitempty"` // resolved, unresolved
+	State       string `json:"state,omitempty"`  // one of ErrorStates
+	Region      string `json:"region,omitempty"`
+	Deployment  string `json:"deployment,omitempty"`
+
+	// Context matches indexed context fields exactly, e.g. context.customer_id=123
+	Context map[string]string `json:"context,omitempty"`
+
+	// WatchedBy keeps only the groups this team member watches
+	WatchedBy *uuid.UUID `json:"watched_by,omitempty"`
+
+	// Sort orders the list: "newest" (default) or "impact"
+	Sort string `json:"sort,omitempty"`
+
+	// Search matches text in the message, case-insensitively. With OpenSearch
+	// it also matches stack traces and URLs, and Fuzzy tolerates typos.
+	Search string `json:"search,omitempty"`
+	Fuzzy  bool   `json:"fuzzy,omitempty"`
+
+	// Message matches the message by MessageMatch: "substring" (default,
+	// case-insensitive), "exact" or "regex". MessageNegate keeps the errors
+	// that don't match instead.
+	Message       string `json:"message,omitempty"`
+	MessageMatch  string `json:"message_match,omitempty"`
+	MessageNegate bool   `json:"message_negate,omitempty"`
+
+	// Query holds the parsed terms of a query string, all of which must match
+	Query []SearchTerm `json:"query,omitempty"`
+
+	// OrgID keeps the errors reported to projects of this organization
+	OrgID *uuid.UUID `json:"org_id,omitempty"`
+}
+
+// SearchTerm is one term of an error query such as level:error or -"timeout".
+// Field is empty for text in the message, "resolved" for the resolved flag,
+// or context.<key> for an indexed context field.
+type SearchTerm struct {
+	Field  string `json:"field,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Negate bool   `json:"negate,omitempty"`
+}
+
+// StatsFilter scopes GET /api/stats; empty fields match everything
+type StatsFilter struct {
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Environment string     `json:"environment,omitempty"`
+	Source      string     `json:"source,omitempty"`
+	Region      string     `json:"region,omitempty"`
+	Deployment  string     `json:"deployment,omitempty"`
+
+	// OrgID keeps the errors reported to projects of this organization
+	OrgID *uuid.UUID `json:"org_id,omitempty"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type ErrorFacetsResponse struct {
+	Level       []FacetCount `json:"level"`
+	Source      []FacetCount `json:"source"`
+	Environment []FacetCount `json:"environment"`
+	Region      []FacetCount `json:"region"`
+	Status      []FacetCount `json:"status"`
+}
+
+type TraceErrorsResponse struct {
+	TraceID string   `json:"trace_id"`
+	Sources []string `json:"sources"`
+	Errors  []Error  `json:"errors"`
 }
 
 type ErrorListResponse struct {
@@ -42,6 +197,96 @@ type ErrorListResponse struct {
 	Total  int     `json:"total"`
 	Page   int     `json:"page"`
 	Limit  int     `json:"limit"`
+
+	// Degraded is set when the database was unavailable and the list was
+	// served from the most recently received events instead
+	Degraded bool `json:"degraded,omitempty"`
+
+	// Query is how the query parameter was understood
+	Query []SearchTerm `json:"query,omitempty"`
+
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes one page of a list response
+type Pagination struct {
+	Total  int             `json:"total"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+	Links  PaginationLinks `json:"links"`
+}
+
+// PaginationLinks are relative URLs of the neighbouring pages. Next and Prev
+// are left out at the ends of the list.
+type PaginationLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// ErrorUpdatesResponse is a page of errors created or updated after a cursor.
+// Pass Cursor as since to get the next page.
+type ErrorUpdatesResponse struct {
+	Errors  []Error `json:"errors"`
+	Cursor  string  `json:"cursor"`
+	HasMore bool    `json:"has_more"`
+}
+
+// ErrorGroupText is the latest event of an unresolved error group, read by
+// the clustering job to compare groups
+type ErrorGroupText struct {
+	Fingerprint string
+	ProjectID   *uuid.UUID
+	Message     string
+	Level       string
+	Source      string
+	EventCount  int
+	LastSeen    time.Time
+}
+
+// ErrorCluster is a set of error groups whose messages are so alike that
+// they likely share a root cause. Its ID is the fingerprint of the group
+// with the most events.
+type ErrorCluster struct {
+	ID         string              `json:"id"`
+	ProjectID  *uuid.UUID          `json:"project_id,omitempty"`
+	Similarity float64             `json:"similarity"` // of the least similar group to the first
+	EventCount int                 `json:"event_count"`
+	Groups     []ErrorClusterGroup `json:"groups"`
+	ComputedAt time.Time           `json:"computed_at"`
+}
+
+// ErrorClusterGroup is a member of a cluster, as of the last clustering run
+type ErrorClusterGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Level       string    `json:"level"`
+	Source      string    `json:"source"`
+	EventCount  int       `json:"event_count"`
+	LastSeen    time.Time `json:"last_seen"`
+	Similarity  float64   `json:"similarity"` // to the cluster's first group
+}
+
+type ErrorClustersResponse struct {
+	Clusters []ErrorCluster `json:"clusters"`
+	Total    int            `json:"total"`
+	// Enabled is false when the clustering job is turned off
+	Enabled    bool        `json:"enabled"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// ErrorSummary is a plain-language explanation of an error group written by
+// a language model. It is generated text and may be wrong.
+type ErrorSummary struct {
+	Fingerprint string    `json:"fingerprint"`
+	Summary     string    `json:"summary"`
+	Hypotheses  []string  `json:"hypotheses"` // likely causes, most likely first
+	Generated   bool      `json:"generated"`  // always true, so clients label it
+	Disclaimer  string    `json:"disclaimer"`
+	Model       string    `json:"model"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Cached      bool      `json:"cached"`
 }
 
 type StatsResponse struct {
@@ -53,6 +298,32 @@ type StatsResponse struct {
 	ErrorRate24h      float64 `json:"error_rate_24h"`
 	ResolutionRate    float64 `json:"resolution_rate"`
 	AvgResolutionTime string  `json:"avg_resolution_time"`
+	Timezone          string  `json:"timezone"` // where errors_today starts at midnight
+
+	// Events of the matching groups dropped by spike protection's sampling
+	SuppressedThisWeek  int `json:"suppressed_this_week"`
+	SuppressedThisMonth int `json:"suppressed_this_month"`
+
+	// States counts errors per lifecycle state
+	States map[string]int `json:"states"`
+
+	// Rates over the last 24 hours, from per-minute and per-hour counts
+	ErrorsPerMinuteP50 float64      `json:"errors_per_minute_p50"`
+	ErrorsPerMinuteP95 float64      `json:"errors_per_minute_p95"`
+	BusiestHour        *BusiestHour `json:"busiest_hour"`
+	MTBFBySource       []SourceMTBF `json:"mtbf_by_source"`
+}
+
+type BusiestHour struct {
+	Hour  time.Time `json:"hour"`
+	Count int       `json:"count"`
+}
+
+// SourceMTBF is the mean time between failures of a source
+type SourceMTBF struct {
+	Source      string  `json:"source"`
+	Failures    int     `json:"failures"`
+	MTBFSeconds float64 `json:"mtbf_seconds"`
 }
 
 // Analytics models
@@ -65,7 +336,67 @@ type TrendDataPoint struct {
 
 type TrendResponse struct {
 	Period     string           `json:"period"`
+	Timezone   string           `json:"timezone"` // buckets start at midnight (or the hour) here
 	DataPoints []TrendDataPoint `json:"data_points"`
+
+	// Annotations in the period that apply to the trend's scope, oldest first
+	Annotations []Annotation `json:"annotations"`
+}
+
+// Annotation marks an event on trend charts, such as a deploy. Empty
+// ProjectID, Environment and Source apply to all.
+type Annotation struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ProjectID   *uuid.UUID `json:"project_id" db:"project_id"`
+	Environment *string    `json:"environment" db:"environment"`
+	Source      *string    `json:"source" db:"source"`
+	Type        string     `json:"type" db:"type"` // deploy, incident, config-change
+	Text        string     `json:"text" db:"text"`
+	URL         *string    `json:"url" db:"url"`
+	IncidentID  *uuid.UUID `json:"incident_id,omitempty" db:"incident_id"`
+	CreatedBy   string     `json:"created_by" db:"created_by"`
+	Timestamp   time.Time  `json:"timestamp" db:"timestamp"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateAnnotationRequest struct {
+	ProjectID   *uuid.UUID `json:"project_id"`
+	Environment *string    `json:"environment"`
+	Source      *string    `json:"source"`
+	Type        string     `json:"type"`
+	Text        string     `json:"text"`
+	URL         *string    `json:"url"`
+	Timestamp   *time.Time `json:"timestamp"` // default now
+}
+
+// DeployRequest is sent by CI when a release goes out
+type DeployRequest struct {
+	ProjectID   *uuid.UUID `json:"project_id"`
+	Environment *string    `json:"environment"`
+	Source      *string    `json:"source"`
+	Release     string     `json:"release"`
+	Description string     `json:"description"`
+	URL         *string    `json:"url"`
+	Timestamp   *time.Time `json:"timestamp"` // default now
+}
+
+// AnnotationFilter narrows the annotation list; zero values match all
+type AnnotationFilter struct {
+	ProjectID *uuid.UUID
+	Type      string
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// HeatmapResponse counts errors by day of week and hour of day. Counts[0] is
+// Monday and Counts[d][0] is midnight to 1am in the requested timezone.
+type HeatmapResponse struct {
+	Period   string     `json:"period"`
+	Timezone string     `json:"timezone"`
+	Days     []string   `json:"days"`
+	Counts   [7][24]int `json:"counts"`
+	Max      int        `json:"max"`
+	Total    int        `json:"total"`
 }
 
 type PerformanceMetrics struct {
@@ -92,15 +423,139 @@ type ServicesResponse struct {
 }
 
 type SystemMetrics struct {
-	CPUUsagePercent    float64 `json:"cpu_usage_percent"`
-	MemoryUsagePercent float64 `json:"memory_usage_percent"`
-	DiskUsagePercent   float64 `json:"disk_usage_percent"`
-	NetworkIO          struct {
-		BytesIn  int64 `json:"bytes_in"`
-		BytesOut int64 `json:"bytes_out"`
-	} `json:"network_io"`
-	ActiveConnections int `json:"active_connections"`
-	RequestsPerMinute int `json:"requests_per_minute"`
+	CPUUsagePercent    float64          `json:"cpu_usage_percent"`
+	MemoryUsagePercent float64          `json:"memory_usage_percent"`
+	DiskUsagePercent   float64          `json:"disk_usage_percent"`
+	NetworkIO          NetworkIO        `json:"network_io"`
+	ActiveConnections  int              `json:"active_connections"`
+	RequestsPerMinute  int              `json:"requests_per_minute"`
+	Hosts              *HostSummary     `json:"hosts"`
+	Database           *DatabaseMetrics `json:"database"`
+	Redis              *RedisMetrics    `json:"redis"`
+	Instance           *InstanceMetrics `json:"instance"`
+}
+
+type NetworkIO struct {
+	BytesIn  int64 `json:"bytes_in"`
+	BytesOut int64 `json:"bytes_out"`
+}
+
+// HostSummary counts the hosts with a reporting agent
+type HostSummary struct {
+	Total int `json:"total"`
+	Up    int `json:"up"`
+	Down  int `json:"down"`
+}
+
+// HostCheckInRequest is what a host agent reports on each check-in. Network
+// bytes are counted since the agent's previous check-in.
+type HostCheckInRequest struct {
+	Hostname           string    `json:"hostname"`
+	Environment        string    `json:"environment"`
+	AgentVersion       string    `json:"agent_version"`
+	CPUUsagePercent    float64   `json:"cpu_usage_percent"`
+	MemoryUsagePercent float64   `json:"memory_usage_percent"`
+	DiskUsagePercent   float64   `json:"disk_usage_percent"`
+	NetworkIO          NetworkIO `json:"network_io"`
+	ActiveConnections  int       `json:"active_connections"`
+	RequestsPerMinute  int       `json:"requests_per_minute"`
+}
+
+// Host is a host with a reporting agent and the metrics of its last check-in
+type Host struct {
+	Hostname           string    `json:"hostname"`
+	Environment        string    `json:"environment"`
+	AgentVersion       string    `json:"agent_version"`
+	Status             string    `json:"status"` // up, down
+	CPUUsagePercent    float64   `json:"cpu_usage_percent"`
+	MemoryUsagePercent float64   `json:"memory_usage_percent"`
+	DiskUsagePercent   float64   `json:"disk_usage_percent"`
+	NetworkIO          NetworkIO `json:"network_io"`
+	ActiveConnections  int       `json:"active_connections"`
+	RequestsPerMinute  int       `json:"requests_per_minute"`
+	FirstSeen          time.Time `json:"first_seen"`
+	LastSeen           time.Time `json:"last_seen"`
+}
+
+// HostFilter narrows the host list; empty fields match everything
+type HostFilter struct {
+	Environment string
+	Status      string // up, down
+}
+
+// HostSample is the metrics of one check-in
+type HostSample struct {
+	Timestamp          time.Time `json:"timestamp"`
+	CPUUsagePercent    float64   `json:"cpu_usage_percent"`
+	MemoryUsagePercent float64   `json:"memory_usage_percent"`
+	DiskUsagePercent   float64   `json:"disk_usage_percent"`
+	NetworkIO          NetworkIO `json:"network_io"`
+	ActiveConnections  int       `json:"active_connections"`
+	RequestsPerMinute  int       `json:"requests_per_minute"`
+}
+
+// HostDetail is a host with its check-ins over a period, oldest first
+type HostDetail struct {
+	Host    Host         `json:"host"`
+	Samples []HostSample `json:"samples"`
+}
+
+// InstanceMetrics identify the API process that answered
+type InstanceMetrics struct {
+	ID     string `json:"id"`
+	Leader bool   `json:"leader"` // runs the periodic background jobs
+}
+
+// DatabaseMetrics are counters kept by this API process
+type DatabaseMetrics struct {
+	SlowQueries          int64       `json:"slow_queries"`
+	SlowQueryThresholdMS int64       `json:"slow_query_threshold_ms"`
+	Retries              int64       `json:"retries"`
+	RetriesExhausted     int64       `json:"retries_exhausted"`
+	Pool                 PoolMetrics `json:"pool"`
+}
+
+// RedisMetrics are counters kept by this API process
+type RedisMetrics struct {
+	Retries          int64              `json:"retries"`
+	RetriesExhausted int64              `json:"retries_exhausted"`
+	QueueLength      int64              `json:"queue_length"` // errors waiting to be stored
+	Memory           *RedisMemoryReport `json:"memory"`       // nil until the leader has checked
+}
+
+// RedisMemoryReport is the leader's last check of Redis memory
+type RedisMemoryReport struct {
+	UsedBytes        int64                 `json:"used_bytes"`
+	MaxBytes         int64                 `json:"max_bytes"` // 0 when Redis has no maxmemory
+	EvictionPolicy   string                `json:"eviction_policy"`
+	QueueEvictable   bool                  `json:"queue_evictable"` // the policy may evict the error queue
+	CacheBytes       int64                 `json:"cache_bytes"`
+	CacheBudgetBytes int64                 `json:"cache_budget_bytes"` // 0 is unlimited
+	Families         []RedisKeyFamilyUsage `json:"families"`
+	Dropped          []string              `json:"dropped"` // cache families dropped to stay within the budget
+	CheckedAt        time.Time             `json:"checked_at"`
+}
+
+// RedisKeyFamilyUsage is the size of one kind of key
+type RedisKeyFamilyUsage struct {
+	Name        string `json:"name"`
+	Cache       bool   `json:"cache"`
+	Keys        int64  `json:"keys"`
+	Bytes       int64  `json:"bytes"`        // estimated from a sample of the keys
+	ExpiryFixed int64  `json:"expiry_fixed"` // cache keys found without an expiry and given one
+}
+
+// PoolMetrics mirror database/sql DBStats for the connection pool
+type PoolMetrics struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
 }
 
 type UptimeData struct {
@@ -121,36 +576,166 @@ type AlertRule struct {
 	TimeWindow    string     `json:"time_window" db:"time_window"`
 	Enabled       bool       `json:"enabled" db:"enabled"`
 	Notifications []string   `json:"notifications" db:"notifications"`
+	Scope         AlertScope `json:"scope" db:"scope"`
 	LastTriggered *time.Time `json:"last_triggered" db:"last_triggered"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Severity is given to the incidents the rule opens; critical rules may
+	// notify during quiet hours
+	Severity string `json:"severity" db:"severity"` // low, medium, high, critical
+
+	// Flap suppression
+	RenotifyInterval       string `json:"renotify_interval" db:"renotify_interval"`
+	ConsecutiveEvaluations int    `json:"consecutive_evaluations" db:"consecutive_evaluations"`
+	RecoveryThreshold      *int   `json:"recovery_threshold" db:"recovery_threshold"`
+
+	// Evaluator state
+	State               string     `json:"state" db:"state"` // ok, pending, firing
+	ConsecutiveBreaches int        `json:"consecutive_breaches" db:"consecutive_breaches"`
+	LastNotified        *time.Time `json:"last_notified" db:"last_notified"`
+
+	// Incident opened by the current firing and the highest metric values seen during it
+	IncidentID *uuid.UUID         `json:"incident_id" db:"incident_id"`
+	PeakValues map[string]float64 `json:"peak_values" db:"peak_values"`
+
+	Runbook *Runbook `json:"runbook" db:"-"` // runbook_url, runbook_notes
+
+	// ExternalID is chosen by the caller, e.g. a Terraform resource address.
+	// Version is bumped by every configuration change and returned as the ETag.
+	ExternalID *string `json:"external_id" db:"external_id"`
+	Version    int     `json:"version" db:"version"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AlertScope narrows the errors an alert rule counts; empty fields match everything
+type AlertScope struct {
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Source      string     `json:"source,omitempty"`
+	Environment string     `json:"environment,omitempty"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	Level       string     `json:"level,omitempty"`
 }
 
 type CreateAlertRuleRequest struct {
-	Name          string   `json:"name"`
-	Condition     string   `json:"condition"`
-	Threshold     int      `json:"threshold"`
-	TimeWindow    string   `json:"time_window"`
-	Notifications []string `json:"notifications"`
-	Enabled       bool     `json:"enabled"`
+	Name          string     `json:"name"`
+	Condition     string     `json:"condition"`
+	Threshold     int        `json:"threshold"`
+	TimeWindow    string     `json:"time_window"`
+	Notifications []string   `json:"notifications"`
+	Scope         AlertScope `json:"scope"`
+	Enabled       bool       `json:"enabled"`
+	Severity      string     `json:"severity"` // default high
+
+	RenotifyInterval       string `json:"renotify_interval"`
+	ConsecutiveEvaluations int    `json:"consecutive_evaluations"`
+	RecoveryThreshold      *int   `json:"recovery_threshold"`
+
+	Runbook *Runbook `json:"runbook"`
+
+	ExternalID *string `json:"external_id"` // "" removes it on update
+}
+
+// AlertRuleDocument declares every alert rule, for keeping them in version
+// control. Rules are matched to the stored ones by name.
+type AlertRuleDocument struct {
+	Rules []CreateAlertRuleRequest `json:"rules"`
+}
+
+// AlertRulesApplyResult lists what applying an AlertRuleDocument changed, or
+// would change on a dry run, by rule name
+type AlertRulesApplyResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Created   []string          `json:"created"`
+	Updated   []AlertRuleChange `json:"updated"`
+	Deleted   []string          `json:"deleted"`
+	Unchanged []string          `json:"unchanged"`
+}
+
+// AlertRuleChange names an updated rule and the fields that changed
+type AlertRuleChange struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+// AlertTemplate is a recommended alert rule. Threshold and TimeWindow are
+// suggested from the scope's recent errors when it has any.
+type AlertTemplate struct {
+	ID                     string     `json:"id"`
+	Name                   string     `json:"name"`
+	Description            string     `json:"description"`
+	Condition              string     `json:"condition"`
+	Threshold              int        `json:"threshold"`
+	TimeWindow             string     `json:"time_window"`
+	Scope                  AlertScope `json:"scope"`
+	Severity               string     `json:"severity"`
+	ConsecutiveEvaluations int        `json:"consecutive_evaluations"`
+	RenotifyInterval       string     `json:"renotify_interval,omitempty"`
+	Basis                  string     `json:"basis"` // history, default
+}
+
+// CreateRuleFromTemplateRequest creates an alert rule from a template. Empty
+// fields take the template's values; scope fields are added to its scope.
+type CreateRuleFromTemplateRequest struct {
+	Name          string     `json:"name"`
+	Scope         AlertScope `json:"scope"`
+	Notifications []string   `json:"notifications"`
+	Threshold     *int       `json:"threshold"`
+	TimeWindow    string     `json:"time_window"`
+	Severity      string     `json:"severity"`
+	Enabled       *bool      `json:"enabled"` // default true
+	Runbook       *Runbook   `json:"runbook"`
 }
 
 type Incident struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	Title       string     `json:"title" db:"title"`
-	Severity    string     `json:"severity" db:"severity"`
-	Status      string     `json:"status" db:"status"`
-	Description string     `json:"description" db:"description"`
-	AssignedTo  *uuid.UUID `json:"assigned_to" db:"assigned_to"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Title        string     `json:"title" db:"title"`
+	Severity     string     `json:"severity" db:"severity"`
+	Status       string     `json:"status" db:"status"`
+	Description  string     `json:"description" db:"description"`
+	AssignedTo   *uuid.UUID `json:"assigned_to" db:"assigned_to"`
+	AssignedTeam *uuid.UUID `json:"assigned_team" db:"assigned_team"`
+	OrgID        *uuid.UUID `json:"org_id,omitempty" db:"org_id"` // NULL for deployment-wide incidents
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IncidentRelatedError is an error group that was active in the scope and
+// window of the alert rule that opened an incident, attached to it when the
+// rule fired as a possible cause
+type IncidentRelatedError struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Level       string    `json:"level"`
+	Source      string    `json:"source"`
+	EventCount  int       `json:"event_count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// AlertRuleFilter narrows the alert rule list; empty fields match everything
+type AlertRuleFilter struct {
+	Enabled    *bool
+	State      string // ok, pending, firing
+	Search     string // case-insensitive match on the name
+	ExternalID string
+	OrgID      *uuid.UUID // rules scoped to a project of this organization
+}
+
+// IncidentFilter narrows the incident list; empty fields match everything
+type IncidentFilter struct {
+	Status   string
+	Severity string
+	Search   string     // case-insensitive match on the title
+	OrgID    *uuid.UUID // incidents of this organization
 }
 
 type CreateIncidentRequest struct {
-	Title       string     `json:"title"`
-	Severity    string     `json:"severity"`
-	Description string     `json:"description"`
-	AssignedTo  *uuid.UUID `json:"assigned_to"`
+	Title        string     `json:"title"`
+	Severity     string     `json:"severity"`
+	Description  string     `json:"description"`
+	AssignedTo   *uuid.UUID `json:"assigned_to"`
+	AssignedTeam *uuid.UUID `json:"assigned_team"`
 }
 
 // Settings models
@@ -161,16 +746,47 @@ type APIKey struct {
 	KeyPreview  string     `json:"key_preview" db:"-"`
 	Permissions []string   `json:"permissions" db:"permissions"`
 	ProjectID   *uuid.UUID `json:"project_id" db:"project_id"`
+	OrgID       *uuid.UUID `json:"org_id" db:"org_id"` // nil for deployment-wide keys
 	Active      bool       `json:"active" db:"active"`
 	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	LastUsed    *time.Time `json:"last_used" db:"last_used"`
+
+	KeyType        string   `json:"key_type" db:"key_type"` // secret, public
+	AllowedOrigins []string `json:"allowed_origins" db:"allowed_origins"`
+
+	SigningSecret  *string `json:"-" db:"signing_secret"`
+	SignedRequests bool    `json:"signed_requests" db:"-"` // requests must be signed with SigningSecret
+
+	ExternalID *string `json:"external_id" db:"external_id"`
+	Version    int     `json:"version" db:"version"`
+}
+
+type RequestSigningResponse struct {
+	SigningSecret string `json:"signing_secret"`
 }
 
 type CreateAPIKeyRequest struct {
 	Name        string     `json:"name"`
 	Permissions []string   `json:"permissions"`
 	ExpiresAt   *time.Time `json:"expires_at"`
+
+	ProjectID      *uuid.UUID `json:"project_id"`
+	OrgID          *uuid.UUID `json:"org_id"` // only honored for deployment-wide callers
+	KeyType        string     `json:"key_type"`
+	AllowedOrigins []string   `json:"allowed_origins"`
+
+	ExternalID *string `json:"external_id"`
+}
+
+// UpdateAPIKeyRequest changes the given fields of a key. The key itself, its
+// type, project and organization can't be changed.
+type UpdateAPIKeyRequest struct {
+	Name           *string    `json:"name"`
+	Permissions    *[]string  `json:"permissions"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	AllowedOrigins *[]string  `json:"allowed_origins"`
+	ExternalID     *string    `json:"external_id"` // "" removes it
 }
 
 type TeamMember struct {
@@ -179,20 +795,1043 @@ type TeamMember struct {
 	Email      string     `json:"email" db:"email"`
 	Role       string     `json:"role" db:"role"`
 	Status     string     `json:"status" db:"status"`
+	OrgID      *uuid.UUID `json:"org_id" db:"org_id"`
 	LastActive *time.Time `json:"last_active" db:"last_active"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 }
 
 type InviteTeamMemberRequest struct {
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Email string     `json:"email"`
+	Role  string     `json:"role"`
+	OrgID *uuid.UUID `json:"org_id"` // only honored for deployment-wide callers
+}
+
+type Team struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Description string       `json:"description" db:"description"`
+	Members     []TeamMember `json:"members" db:"-"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+type CreateTeamRequest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	MemberIDs   []uuid.UUID `json:"member_ids"`
+}
+
+type AddTeamMemberRequest struct {
+	MemberID uuid.UUID `json:"member_id"`
 }
 
+type IndexedContextField struct {
+	Key       string    `json:"key" db:"key"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateIndexedContextFieldRequest struct {
+	Key string `json:"key"`
+}
+
+// Integration is a configured connection to a notification service
 type Integration struct {
-	Name     string                 `json:"name"`
-	Status   string                 `json:"status"`
-	Config   map[string]interface{} `json:"config"`
-	LastTest *time.Time             `json:"last_test"`
+	ID         uuid.UUID              `json:"id" db:"id"`
+	Name       string                 `json:"name" db:"name"`
+	Kind       string                 `json:"kind" db:"kind"` // slack, email, webhook, pagerduty, opsgenie
+	Config     map[string]interface{} `json:"config" db:"config"`
+	Secret     *string                `json:"-" db:"secret"`
+	HasSecret  bool                   `json:"has_secret" db:"-"`
+	Enabled    bool                   `json:"enabled" db:"enabled"`
+	Status     string                 `json:"status" db:"-"` // configured, disabled
+	ExternalID *string                `json:"external_id" db:"external_id"`
+	Version    int                    `json:"version" db:"version"`
+	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+type CreateIntegrationRequest struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	Config     map[string]interface{} `json:"config"`
+	Secret     *string                `json:"secret"`
+	Enabled    *bool                  `json:"enabled"` // default true
+	ExternalID *string                `json:"external_id"`
+}
+
+type UpdateIntegrationRequest struct {
+	Name       *string                 `json:"name"`
+	Config     *map[string]interface{} `json:"config"`
+	Secret     *string                 `json:"secret"` // "" removes it
+	Enabled    *bool                   `json:"enabled"`
+	ExternalID *string                 `json:"external_id"` // "" removes it
+}
+
+// ConfigBackup is the configuration saved by a backup. It is only ever
+// written encrypted, so it carries the secrets the API never returns.
+type ConfigBackup struct {
+	FormatVersion int                 `json:"format_version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Organizations []Organization      `json:"organizations"`
+	Projects      []Project           `json:"projects"`
+	APIKeys       []APIKeyBackup      `json:"api_keys"`
+	AlertRules    []AlertRule         `json:"alert_rules"`
+	Integrations  []IntegrationBackup `json:"integrations"`
+	TeamMembers   []TeamMember        `json:"team_members"`
+	Teams         []TeamBackup        `json:"teams"`
+}
+
+// APIKeyBackup keeps the hash of a key, so restored keys keep working, but
+// never the key itself
+type APIKeyBackup struct {
+	APIKey
+	KeyHash       string  `json:"key_hash"`
+	SigningSecret *string `json:"signing_secret"`
+}
+
+type IntegrationBackup struct {
+	Integration
+	Secret *string `json:"secret"`
+}
+
+type TeamBackup struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	MemberIDs   []uuid.UUID `json:"member_ids"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type CreateBackupRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+type RestoreBackupRequest struct {
+	Passphrase string          `json:"passphrase"`
+	Backup     json.RawMessage `json:"backup"` // as returned by POST /api/admin/backup
+}
+
+// BackupRestoreResult counts the restored items per kind. Items already
+// present, by ID or by a unique name, are skipped and left as they are.
+type BackupRestoreResult struct {
+	Restored map[string]int `json:"restored"`
+	Skipped  map[string]int `json:"skipped"`
+}
+
+// Quota models
+type ProjectQuota struct {
+	ProjectID        uuid.UUID `json:"project_id" db:"project_id"`
+	DailySoftLimit   int       `json:"daily_soft_limit" db:"daily_soft_limit"`
+	DailyHardLimit   int       `json:"daily_hard_limit" db:"daily_hard_limit"`
+	MonthlySoftLimit int       `json:"monthly_soft_limit" db:"monthly_soft_limit"`
+	MonthlyHardLimit int       `json:"monthly_hard_limit" db:"monthly_hard_limit"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateQuotaRequest struct {
+	DailySoftLimit   int `json:"daily_soft_limit"`
+	DailyHardLimit   int `json:"daily_hard_limit"`
+	MonthlySoftLimit int `json:"monthly_soft_limit"`
+	MonthlyHardLimit int `json:"monthly_hard_limit"`
+}
+
+// ContextSchema is the JSON schema a project's event context is validated against
+type ContextSchema struct {
+	ProjectID uuid.UUID              `json:"project_id" db:"project_id"`
+	Schema    map[string]interface{} `json:"schema" db:"schema"`
+	UpdatedAt time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateContextSchemaRequest struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// AutoResolvePolicy resolves a project's error groups once they have had no
+// events for Days
+type AutoResolvePolicy struct {
+	ProjectID uuid.UUID `json:"project_id" db:"project_id"`
+	Days      int       `json:"days" db:"days"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateAutoResolvePolicyRequest struct {
+	Days int `json:"days"`
+}
+
+// SourceConformance counts how many of a source's validated events broke the
+// context schema, with the most common violations
+type SourceConformance struct {
+	Source          string           `json:"source"`
+	Events          int              `json:"events"`
+	Nonconforming   int              `json:"nonconforming"`
+	ConformanceRate float64          `json:"conformance_rate"`
+	TopViolations   []ViolationCount `json:"top_violations"`
+}
+
+type ViolationCount struct {
+	Violation string `json:"violation"`
+	Count     int    `json:"count"`
+}
+
+type ContextConformanceReport struct {
+	ProjectID uuid.UUID           `json:"project_id"`
+	Since     time.Time           `json:"since"`
+	Sources   []SourceConformance `json:"sources"`
+}
+
+type UsageRecord struct {
+	ProjectID uuid.UUID `json:"project_id" db:"project_id"`
+	Period    string    `json:"period" db:"day"`
+	Accepted  int       `json:"accepted" db:"accepted"`
+	Dropped   int       `json:"dropped" db:"dropped"`
+}
+
+type UsageResponse struct {
+	GroupBy string        `json:"group_by"`
+	Usage   []UsageRecord `json:"usage"`
+}
+
+// Spike protection models
+type ThrottlePeriod struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	Kind            string    `json:"kind" db:"kind"`
+	Key             string    `json:"key" db:"key"`
+	SuppressedCount int       `json:"suppressed_count" db:"suppressed_count"`
+	StartedAt       time.Time `json:"started_at" db:"started_at"`
+	LastSeenAt      time.Time `json:"last_seen_at" db:"last_seen_at"`
+	Active          bool      `json:"active" db:"-"`
+}
+
+// SuppressedEvents is how many of a group's events spike protection dropped
+// on one UTC day
+type SuppressedEvents struct {
+	Fingerprint string
+	Day         time.Time
+	Count       int64
+}
+
+// UsageCount is how many of a project's events were accepted and dropped on
+// one UTC day
+type UsageCount struct {
+	ProjectID uuid.UUID
+	Day       time.Time
+	Accepted  int64
+	Dropped   int64
+}
+
+// Ownership models
+type OwnershipRule struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ProjectID *uuid.UUID `json:"project_id" db:"project_id"`
+	MatchType string     `json:"match_type" db:"match_type"`
+	Pattern   string     `json:"pattern" db:"pattern"`
+	OwnerType string     `json:"owner_type" db:"owner_type"`
+	OwnerID   uuid.UUID  `json:"owner_id" db:"owner_id"`
+	Priority  int        `json:"priority" db:"priority"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateOwnershipRuleRequest struct {
+	ProjectID *uuid.UUID `json:"project_id"`
+	MatchType string     `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	OwnerType string     `json:"owner_type"`
+	OwnerID   uuid.UUID  `json:"owner_id"`
+	Priority  int        `json:"priority"`
+}
+
+// Custom metric models
+type MetricSample struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"` // counter, gauge
+	Value     float64           `json:"value"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+}
+
+type IngestMetricsRequest struct {
+	Metrics []MetricSample `json:"metrics"`
+}
+
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"` // sum for counters, average for gauges
+	Count     int64     `json:"count"`
+	Sum       float64   `json:"sum"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+}
+
+type MetricSeriesResponse struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Resolution string            `json:"resolution"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Points     []MetricPoint     `json:"points"`
+}
+
+// Log event models
+type LogEvent struct {
+	ID          int64                  `json:"id" db:"id"`
+	Timestamp   time.Time              `json:"timestamp" db:"timestamp"`
+	Level       string                 `json:"level" db:"level"`
+	Message     string                 `json:"message" db:"message"`
+	Source      string                 `json:"source" db:"source"`
+	Environment string                 `json:"environment" db:"environment"`
+	Context     map[string]interface{} `json:"context" db:"context"`
+	TraceID     *string                `json:"trace_id" db:"trace_id"`
+	ProjectID   *uuid.UUID             `json:"project_id" db:"project_id"`
+	SampleRate  int                    `json:"sample_rate" db:"sample_rate"`
+}
+
+type CreateLogRequest struct {
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Source      string                 `json:"source"`
+	Environment string                 `json:"environment"`
+	Context     map[string]interface{} `json:"context"`
+	TraceID     string                 `json:"trace_id"`
+	Timestamp   *time.Time             `json:"timestamp"`
+}
+
+type IngestLogsRequest struct {
+	Logs []CreateLogRequest `json:"logs"`
+}
+
+type IngestLogsResponse struct {
+	Accepted int `json:"accepted"`
+	Sampled  int `json:"sampled"` // dropped by level sampling
+}
+
+type LogFilter struct {
+	Levels      []string
+	Source      string
+	Environment string
+	TraceID     string
+	Search      string
+	Since       *time.Time
+}
+
+type LogListResponse struct {
+	Logs  []LogEvent `json:"logs"`
+	Total int        `json:"total"`
+	Page  int        `json:"page"`
+	Limit int        `json:"limit"`
+}
+
+// Session models
+type SessionUpdate struct {
+	SessionID   string     `json:"session_id"`
+	DistinctID  string     `json:"distinct_id"`
+	Release     string     `json:"release"`
+	Environment string     `json:"environment"`
+	Status      string     `json:"status"` // ok, exited, crashed, abnormal
+	Errors      int        `json:"errors"`
+	Started     *time.Time `json:"started"`
+	Timestamp   *time.Time `json:"timestamp"`
+}
+
+type IngestSessionsRequest struct {
+	Sessions []SessionUpdate `json:"sessions"`
+}
+
+type SessionHealth struct {
+	Release               string  `json:"release"`
+	Environment           string  `json:"environment"`
+	TotalSessions         int     `json:"total_sessions"`
+	CrashedSessions       int     `json:"crashed_sessions"`
+	AbnormalSessions      int     `json:"abnormal_sessions"`
+	ErroredSessions       int     `json:"errored_sessions"`
+	CrashFreeSessionsRate float64 `json:"crash_free_sessions_rate"`
+	TotalUsers            int     `json:"total_users"`
+	CrashedUsers          int     `json:"crashed_users"`
+	CrashFreeUsersRate    float64 `json:"crash_free_users_rate"`
+}
+
+type SessionHealthResponse struct {
+	Period  string          `json:"period"`
+	Results []SessionHealth `json:"results"`
+}
+
+// Debug file models
+type DebugFile struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ProjectID *uuid.UUID `json:"project_id" db:"project_id"`
+	Kind      string     `json:"kind" db:"kind"`
+	Release   string     `json:"release" db:"release"`
+	BuildID   *string    `json:"build_id" db:"build_id"`
+	Name      string     `json:"name" db:"name"`
+	Size      int64      `json:"size" db:"size"`
+	Checksum  string     `json:"checksum" db:"checksum"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Minidump is a stored native crash dump and the event created from it
+type Minidump struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ProjectID   *uuid.UUID `json:"project_id" db:"project_id"`
+	ErrorID     *uuid.UUID `json:"error_id" db:"error_id"`
+	Fingerprint string     `json:"fingerprint" db:"fingerprint"`
+	Release     *string    `json:"release" db:"release"`
+	OS          string     `json:"os" db:"os"`
+	Arch        string     `json:"arch" db:"arch"`
+	Size        int64      `json:"size" db:"size"`
+	Checksum    string     `json:"checksum" db:"checksum"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Service catalog models
+
+// Service is a catalog entry for an error source
+type Service struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"` // matches errors.source
+	Description   string     `json:"description" db:"description"`
+	OwnerTeam     *uuid.UUID `json:"owner_team" db:"owner_team"`
+	RepositoryURL *string    `json:"repository_url" db:"repository_url"`
+	RunbookURL    *string    `json:"runbook_url" db:"runbook_url"`
+	// Names of the catalog services and other components, e.g. a database,
+	// this service needs to work
+	Dependencies []string  `json:"dependencies"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateServiceRequest struct {
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	OwnerTeam     *uuid.UUID `json:"owner_team"`
+	RepositoryURL *string    `json:"repository_url"`
+	RunbookURL    *string    `json:"runbook_url"`
+	// nil keeps the dependencies on update
+	Dependencies []string `json:"dependencies"`
+}
+
+// ServiceGraph is the dependency graph of the catalog with the health of each
+// node
+type ServiceGraph struct {
+	Nodes []ServiceGraphNode `json:"nodes"`
+	Edges []ServiceGraphEdge `json:"edges"`
+}
+
+type ServiceGraphNode struct {
+	Name      string     `json:"name"`
+	ServiceID *uuid.UUID `json:"service_id"` // nil for components outside the catalog
+	// failing with open incidents, healthy without them, unknown for a
+	// component outside the catalog that sent no errors in the last hour
+	Status         string `json:"status"`
+	OpenIncidents  int    `json:"open_incidents"`
+	ErrorsLastHour int    `json:"errors_last_hour"`
+	// Failing nodes this one depends on, directly or through others
+	ImpactedBy []string `json:"impacted_by"`
+}
+
+// ServiceGraphEdge says From depends on To
+type ServiceGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ServiceListResponse struct {
+	Services []Service `json:"services"`
+	// Sources seen in the last 30 days that are not in the catalog yet
+	UnregisteredSources []string `json:"unregistered_sources"`
+}
+
+type ServiceErrorStats struct {
+	ErrorsLast24h    int        `json:"errors_last_24h"`
+	ErrorsLast7d     int        `json:"errors_last_7d"`
+	UnresolvedGroups int        `json:"unresolved_groups"`
+	LastSeen         *time.Time `json:"last_seen"`
+}
+
+type ServiceErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Level       string    `json:"level"`
+	Count       int       `json:"count"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ServiceDashboard aggregates everything known about one service
+type ServiceDashboard struct {
+	Service       Service             `json:"service"`
+	Owner         *Team               `json:"owner"`
+	Stats         ServiceErrorStats   `json:"stats"`
+	Trend         []TrendDataPoint    `json:"trend"` // hourly, last 24 hours
+	TopErrors     []ServiceErrorGroup `json:"top_errors"`
+	Monitors      []AlertRule         `json:"monitors"`
+	OpenIncidents []Incident          `json:"open_incidents"`
+}
+
+// Dashboards
+
+// WidgetLayout places a widget on the dashboard grid
+type WidgetLayout struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// DashboardWidget is one panel of a dashboard. Which fields apply depends on
+// the type: stat uses Metric, trend uses Interval, top_errors uses Limit and
+// monitor_status uses RuleIDs.
+type DashboardWidget struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"` // stat, trend, top_errors, monitor_status
+	Title      string       `json:"title"`
+	Metric     string       `json:"metric,omitempty"`
+	Scope      AlertScope   `json:"scope"`
+	TimeWindow string       `json:"time_window,omitempty"`
+	Interval   string       `json:"interval,omitempty"` // minute, hour or day
+	Limit      int          `json:"limit,omitempty"`
+	RuleIDs    []uuid.UUID  `json:"rule_ids,omitempty"`
+	Layout     WidgetLayout `json:"layout"`
+}
+
+type Dashboard struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	Name        string            `json:"name" db:"name"`
+	Description string            `json:"description" db:"description"`
+	Widgets     []DashboardWidget `json:"widgets" db:"widgets"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+type CreateDashboardRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Widgets     []DashboardWidget `json:"widgets"`
+}
+
+// UpdateDashboardLayoutRequest moves and resizes widgets, keyed by widget ID
+type UpdateDashboardLayoutRequest struct {
+	Layout map[string]WidgetLayout `json:"layout"`
+}
+
+// MonitorStatus is the state of one alert rule on a monitor_status widget
+type MonitorStatus struct {
+	RuleID        uuid.UUID  `json:"rule_id"`
+	Name          string     `json:"name"`
+	Enabled       bool       `json:"enabled"`
+	State         string     `json:"state"`
+	LastTriggered *time.Time `json:"last_triggered"`
+	IncidentID    *uuid.UUID `json:"incident_id,omitempty"`
+}
+
+// DashboardWidgetData is the computed content of one widget. A widget whose
+// query fails carries Error instead, so the rest of the dashboard still renders.
+type DashboardWidgetData struct {
+	WidgetID  string              `json:"widget_id"`
+	Type      string              `json:"type"`
+	Value     *float64            `json:"value,omitempty"`
+	Trend     []TrendDataPoint    `json:"trend,omitempty"`
+	TopErrors []ServiceErrorGroup `json:"top_errors,omitempty"`
+	Monitors  []MonitorStatus     `json:"monitors,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type DashboardDataResponse struct {
+	Dashboard   Dashboard             `json:"dashboard"`
+	Widgets     []DashboardWidgetData `json:"widgets"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// Audit log
+
+type AuditLogEntry struct {
+	ID         uuid.UUID              `json:"id" db:"id"`
+	Action     string                 `json:"action" db:"action"`
+	Actor      string                 `json:"actor" db:"actor"`
+	TargetType string                 `json:"target_type" db:"target_type"`
+	TargetID   string                 `json:"target_id" db:"target_id"`
+	Details    map[string]interface{} `json:"details" db:"details"`
+	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+}
+
+// AuditLogFilter narrows the audit log; empty fields match everything
+type AuditLogFilter struct {
+	Action     string
+	Actor      string
+	TargetType string
+	TargetID   string
+}
+
+type AuditLogResponse struct {
+	Entries []AuditLogEntry `json:"entries"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// FirstSeenEvent is the first occurrence of an error group in an environment
+type FirstSeenEvent struct {
+	Fingerprint string     `json:"fingerprint" db:"fingerprint"`
+	Environment string     `json:"environment" db:"environment"`
+	ErrorID     uuid.UUID  `json:"error_id" db:"error_id"`
+	ProjectID   *uuid.UUID `json:"project_id" db:"project_id"`
+	Message     string     `json:"message" db:"message"`
+	Level       string     `json:"level" db:"level"`
+	Source      string     `json:"source" db:"source"`
+	FirstSeen   time.Time  `json:"first_seen" db:"first_seen"`
+}
+
+type FirstSeenResponse struct {
+	Events []FirstSeenEvent `json:"events"`
+}
+
+// WeeklyInsights summarizes a project's errors over a week starting Monday 00:00 UTC
+type WeeklyInsights struct {
+	ProjectID           uuid.UUID           `json:"project_id"`
+	WeekStart           time.Time           `json:"week_start"`
+	WeekEnd             time.Time           `json:"week_end"`
+	Timezone            string              `json:"timezone"` // the week runs Monday to Monday here
+	TotalErrors         int                 `json:"total_errors"`
+	PreviousTotalErrors int                 `json:"previous_total_errors"`
+	NewErrorCount       int                 `json:"new_error_count"`
+	NewErrors           []InsightErrorGroup `json:"new_errors"`
+	MoversUp            []InsightMover      `json:"movers_up"`
+	MoversDown          []InsightMover      `json:"movers_down"`
+	SlowestResolving    []InsightResolution `json:"slowest_resolving"`
+	RegressionCount     int                 `json:"regression_count"`
+	GeneratedAt         time.Time           `json:"generated_at"`
+}
+
+type InsightErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Source      string    `json:"source"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+}
+
+// InsightMover is an error group whose weekly count changed most
+type InsightMover struct {
+	Fingerprint   string `json:"fingerprint"`
+	Message       string `json:"message"`
+	Source        string `json:"source"`
+	Count         int    `json:"count"`
+	PreviousCount int    `json:"previous_count"`
+	Change        int    `json:"change"`
+}
+
+// InsightResolution is an error group resolved during the week, timed from its first event
+type InsightResolution struct {
+	Fingerprint       string    `json:"fingerprint"`
+	Message           string    `json:"message"`
+	Source            string    `json:"source"`
+	FirstSeen         time.Time `json:"first_seen"`
+	ResolvedAt        time.Time `json:"resolved_at"`
+	ResolvedBy        *string   `json:"resolved_by"`
+	ResolutionSeconds float64   `json:"resolution_seconds"`
+}
+
+// Read-only mode models
+type ReadOnlyStatus struct {
+	Enabled bool       `json:"enabled"`
+	Forced  bool       `json:"forced"` // set by READ_ONLY_MODE, cannot be turned off through the API
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+type SetReadOnlyRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// Watch list models
+type WatchErrorRequest struct {
+	MemberID uuid.UUID `json:"member_id"`
+}
+
+// ErrorWatchersResponse lists the team members watching an error group
+type ErrorWatchersResponse struct {
+	Fingerprint string       `json:"fingerprint"`
+	Watchers    []TeamMember `json:"watchers"`
+}
+
+// NotificationSchedule sets quiet hours for one alert notification target.
+// During quiet hours, notifications to the target go to Fallback instead, or
+// nowhere, unless the rule is critical and CriticalOverride is set.
+type NotificationSchedule struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	Target           string    `json:"target" db:"target"`
+	Timezone         string    `json:"timezone" db:"timezone"`
+	QuietStart       string    `json:"quiet_start" db:"quiet_start"` // HH:MM, "" for no daily quiet hours
+	QuietEnd         string    `json:"quiet_end" db:"quiet_end"`
+	QuietDays        []string  `json:"quiet_days" db:"quiet_days"` // mon..sun, quiet all day
+	Fallback         *string   `json:"fallback" db:"fallback"`
+	CriticalOverride bool      `json:"critical_override" db:"critical_override"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateNotificationScheduleRequest struct {
+	Target           string   `json:"target"`
+	Timezone         string   `json:"timezone"`
+	QuietStart       string   `json:"quiet_start"`
+	QuietEnd         string   `json:"quiet_end"`
+	QuietDays        []string `json:"quiet_days"`
+	Fallback         *string  `json:"fallback"`
+	CriticalOverride *bool    `json:"critical_override"` // default true
+}
+
+// Notification is an entry in a team member's in-app inbox. It is recorded
+// alongside every email the member is sent about alerts, threshold
+// subscriptions, watched errors and ownership assignments.
+type Notification struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	MemberID    uuid.UUID  `json:"member_id" db:"member_id"`
+	Type        string     `json:"type" db:"type"` // alert, threshold, watch, ownership
+	Subject     string     `json:"subject" db:"subject"`
+	Body        string     `json:"body" db:"body"`
+	RuleID      *uuid.UUID `json:"rule_id,omitempty" db:"rule_id"`
+	ErrorID     *uuid.UUID `json:"error_id,omitempty" db:"error_id"`
+	Fingerprint *string    `json:"fingerprint,omitempty" db:"fingerprint"`
+	ReadAt      *time.Time `json:"read_at" db:"read_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NotificationFilter narrows a member's inbox; zero values match all
+type NotificationFilter struct {
+	Unread bool
+	Type   string
+}
+
+// NotificationMessage is sent to an inbox stream client. "notification"
+// carries a new notification, "read" follows notifications being marked
+// read, and "status" opens the stream. Each has the member's unread count.
+type NotificationMessage struct {
+	Type         string        `json:"type"`
+	MemberID     uuid.UUID     `json:"member_id"`
+	Notification *Notification `json:"notification,omitempty"`
+	Unread       int           `json:"unread"`
+}
+
+// ThresholdSubscription is a team member's personal alert on one error group.
+// The member is emailed when the group's events in the window exceed the
+// threshold. It is evaluated apart from team-wide alert rules.
+type ThresholdSubscription struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	MemberID      uuid.UUID  `json:"member_id" db:"member_id"`
+	Fingerprint   string     `json:"fingerprint" db:"fingerprint"`
+	Threshold     int        `json:"threshold" db:"threshold"`
+	TimeWindow    string     `json:"time_window" db:"time_window"`
+	State         string     `json:"state" db:"state"` // ok, firing
+	LastTriggered *time.Time `json:"last_triggered" db:"last_triggered"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateThresholdSubscriptionRequest struct {
+	MemberID    uuid.UUID `json:"member_id"`
+	Fingerprint string    `json:"fingerprint"`
+	Threshold   int       `json:"threshold"`
+	TimeWindow  string    `json:"time_window"`
+}
+
+// ThresholdSubscriptionFilter narrows the subscription list; zero values match all
+type ThresholdSubscriptionFilter struct {
+	MemberID    *uuid.UUID
+	Fingerprint string
+}
+
+// Report summarises a week or month of errors for stakeholders who do not
+// use the dashboard
+type Report struct {
+	Period              string              `json:"period"` // weekly, monthly
+	Start               time.Time           `json:"start"`
+	End                 time.Time           `json:"end"`
+	Timezone            string              `json:"timezone"`
+	TotalErrors         int                 `json:"total_errors"`
+	PreviousTotalErrors int                 `json:"previous_total_errors"`
+	ResolvedErrors      int                 `json:"resolved_errors"`
+	CriticalErrors      int                 `json:"critical_errors"`
+	NewErrorGroups      int                 `json:"new_error_groups"`
+	Daily               []TrendDataPoint    `json:"daily"`
+	TopErrors           []InsightErrorGroup `json:"top_errors"`
+	IncidentsOpened     int                 `json:"incidents_opened"`
+	IncidentsResolved   int                 `json:"incidents_resolved"` // of those opened in the period
+	GeneratedAt         time.Time           `json:"generated_at"`
+}
+
+// ImportJob tracks the import of another error tracker's export. Progress is
+// counted in events; an issue exported without events counts as one.
+type ImportJob struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Format          string     `json:"format" db:"format"` // sentry, rollbar
+	Status          string     `json:"status" db:"status"` // pending, running, completed, failed
+	ProjectID       *uuid.UUID `json:"project_id" db:"project_id"`
+	TotalGroups     int        `json:"total_groups" db:"total_groups"`
+	TotalEvents     int        `json:"total_events" db:"total_events"`
+	ProcessedEvents int        `json:"processed_events" db:"processed_events"`
+	ImportedEvents  int        `json:"imported_events" db:"imported_events"`
+	FailedEvents    int        `json:"failed_events" db:"failed_events"`
+	Error           *string    `json:"error" db:"error"`
+	CreatedBy       string     `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at" db:"completed_at"`
+
+	// Progress is the percentage of events processed
+	Progress float64 `json:"progress" db:"-"`
+}
+
+// ExportJob tracks an export of errors to a file, written in the background so
+// large exports do not hold a request open
+type ExportJob struct {
+	ID           uuid.UUID   `json:"id" db:"id"`
+	Format       string      `json:"format" db:"format"`           // csv, json
+	Destination  string      `json:"destination" db:"destination"` // download, s3
+	Filter       ErrorFilter `json:"filter" db:"filter"`
+	From         *time.Time  `json:"from" db:"from_time"`
+	To           *time.Time  `json:"to" db:"to_time"`
+	Status       string      `json:"status" db:"status"` // pending, running, completed, failed, expired
+	ProjectID    *uuid.UUID  `json:"project_id" db:"project_id"`
+	TotalRows    int         `json:"total_rows" db:"total_rows"`
+	ExportedRows int         `json:"exported_rows" db:"exported_rows"`
+	SizeBytes    int64       `json:"size_bytes" db:"size_bytes"`
+	S3Key        *string     `json:"s3_key,omitempty" db:"s3_key"`
+	Error        *string     `json:"error" db:"error"`
+	CreatedBy    string      `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+	CompletedAt  *time.Time  `json:"completed_at" db:"completed_at"`
+	ExpiresAt    *time.Time  `json:"expires_at" db:"expires_at"`
+
+	// Progress is the percentage of rows written
+	Progress float64 `json:"progress" db:"-"`
+	// DownloadURL is a signed link to the file, set once the job completes
+	DownloadURL *string `json:"download_url,omitempty" db:"-"`
+}
+
+type CreateExportRequest struct {
+	Format      string      `json:"format"`
+	Destination string      `json:"destination"`
+	Filter      ErrorFilter `json:"filter"`
+	From        *time.Time  `json:"from,omitempty"`
+	To          *time.Time  `json:"to,omitempty"`
+}
+
+// SDKConfig is the behavior SDKs fetch at startup, so it can be changed for
+// a project's deployed clients without redeploying them
+type SDKConfig struct {
+	ProjectID *uuid.UUID `json:"project_id" db:"project_id"`
+	// SampleRate is the fraction of error events to send, from 0 to 1
+	SampleRate float64 `json:"sample_rate" db:"sample_rate"`
+	// LevelSampleRates overrides SampleRate for single levels
+	LevelSampleRates     map[string]float64 `json:"level_sample_rates" db:"level_sample_rates"`
+	AllowedLevels        []string           `json:"allowed_levels" db:"allowed_levels"`
+	ScrubRules           []ScrubRule        `json:"scrub_rules" db:"scrub_rules"`
+	FlushIntervalSeconds int                `json:"flush_interval_seconds" db:"flush_interval_seconds"`
+	// UpdatedAt is nil while the project uses the defaults
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ScrubRule tells SDKs to redact data before sending it: the context field
+// named Field, values matching Pattern, or both
+type ScrubRule struct {
+	Field       string `json:"field,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+	Replacement string `json:"replacement"`
+}
+
+type UpdateSDKConfigRequest struct {
+	SampleRate           *float64           `json:"sample_rate"`
+	LevelSampleRates     map[string]float64 `json:"level_sample_rates"`
+	AllowedLevels        []string           `json:"allowed_levels"`
+	ScrubRules           []ScrubRule        `json:"scrub_rules"`
+	FlushIntervalSeconds *int               `json:"flush_interval_seconds"`
+}
+
+// Organization models
+type Organization struct {
+	ID        uuid.UUID   `json:"id" db:"id"`
+	Name      string      `json:"name" db:"name"`
+	Slug      string      `json:"slug" db:"slug"`
+	Settings  OrgSettings `json:"settings" db:"settings"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+}
+
+// OrgSettings apply to every project and member of an organization
+type OrgSettings struct {
+	DefaultRole         string   `json:"default_role,omitempty"`          // role given to invites that don't name one
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty"` // empty allows any domain
+}
+
+type Project struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	OrgID      *uuid.UUID `json:"org_id" db:"org_id"`
+	Name       string     `json:"name" db:"name"`
+	Slug       string     `json:"slug" db:"slug"`
+	ExternalID *string    `json:"external_id" db:"external_id"`
+	Version    int        `json:"version" db:"version"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateOrganizationRequest struct {
+	Name     string      `json:"name"`
+	Slug     string      `json:"slug"`
+	Settings OrgSettings `json:"settings"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name     *string      `json:"name"`
+	Settings *OrgSettings `json:"settings"`
+}
+
+type CreateProjectRequest struct {
+	Name       string  `json:"name"`
+	Slug       string  `json:"slug"`
+	ExternalID *string `json:"external_id"`
+}
+
+type UpdateProjectRequest struct {
+	Name       *string `json:"name"`
+	Slug       *string `json:"slug"`
+	ExternalID *string `json:"external_id"` // "" removes it
+}
+
+// OrgSwitcherEntry is an organization the caller can switch to, with its projects
+type OrgSwitcherEntry struct {
+	Organization
+	Projects []Project `json:"projects"`
+	Current  bool      `json:"current"` // the organization of the calling API key
+}
+
+// OrgQuota limits ingestion across all of an organization's projects
+type OrgQuota struct {
+	OrgID            uuid.UUID `json:"org_id" db:"org_id"`
+	DailySoftLimit   int       `json:"daily_soft_limit" db:"daily_soft_limit"`
+	DailyHardLimit   int       `json:"daily_hard_limit" db:"daily_hard_limit"`
+	MonthlySoftLimit int       `json:"monthly_soft_limit" db:"monthly_soft_limit"`
+	MonthlyHardLimit int       `json:"monthly_hard_limit" db:"monthly_hard_limit"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FeatureFlag gates a capability. Enabled is the default for organizations
+// and projects without an override.
+type FeatureFlag struct {
+	Key         string                `json:"key" db:"key"`
+	Description string                `json:"description" db:"description"`
+	Enabled     bool                  `json:"enabled" db:"enabled"`
+	Builtin     bool                  `json:"builtin" db:"-"` // known to the server; can't be deleted
+	Overrides   []FeatureFlagOverride `json:"overrides" db:"-"`
+	UpdatedAt   *time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// FeatureFlagOverride turns a flag on or off for one organization or project
+type FeatureFlagOverride struct {
+	FlagKey   string    `json:"-" db:"flag_key"`
+	Scope     string    `json:"scope" db:"scope"` // org, project
+	ScopeID   uuid.UUID `json:"scope_id" db:"scope_id"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateFeatureFlagRequest struct {
+	Description *string `json:"description"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+type SetFeatureFlagOverrideRequest struct {
+	Scope   string    `json:"scope"`
+	ScopeID uuid.UUID `json:"scope_id"`
+	Enabled bool      `json:"enabled"`
+}
+
+// OrgUsage is what an organization consumed in a metering period. Events
+// are counted for the period; storage and seats are as of now.
+type OrgUsage struct {
+	OrgID          uuid.UUID `json:"org_id" db:"org_id"`
+	OrgName        string    `json:"org_name" db:"org_name"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	EventsIngested int64     `json:"events_ingested" db:"events_ingested"`
+	EventsDropped  int64     `json:"events_dropped" db:"events_dropped"`
+	StorageBytes   int64     `json:"storage_bytes" db:"storage_bytes"` // events, debug files and minidumps
+	Seats          int       `json:"seats" db:"seats"`                 // active team members
+}
+
+// UsageWebhookPayload is posted to the billing webhook once per metering period
+type UsageWebhookPayload struct {
+	ID            string     `json:"id"` // the same for redeliveries of a period
+	Type          string     `json:"type"`
+	PeriodStart   time.Time  `json:"period_start"`
+	PeriodEnd     time.Time  `json:"period_end"`
+	Organizations []OrgUsage `json:"organizations"`
+	SentAt        time.Time  `json:"sent_at"`
+}
+
+// EventSink is an external destination that accepted error events are
+// forwarded to as they are stored
+type EventSink struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	Name      string          `json:"name" db:"name"`
+	Kind      string          `json:"kind" db:"kind"` // http, kafka, s3
+	Config    EventSinkConfig `json:"config" db:"config"`
+	Secret    *string         `json:"-" db:"secret"`
+	HasSecret bool            `json:"has_secret" db:"-"`
+	Levels    []string        `json:"levels" db:"levels"`   // empty forwards every level
+	Sources   []string        `json:"sources" db:"sources"` // empty forwards every source
+	Enabled   bool            `json:"enabled" db:"enabled"`
+
+	Queued          int64      `json:"queued" db:"-"` // events waiting to be delivered
+	LastDeliveredAt *time.Time `json:"last_delivered_at" db:"last_delivered_at"`
+	LastError       *string    `json:"last_error" db:"last_error"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EventSinkConfig is where a sink delivers to; which fields apply depends on
+// the kind
+type EventSinkConfig struct {
+	URL      string `json:"url,omitempty"`      // http: endpoint; kafka: Kafka REST Proxy base URL
+	Topic    string `json:"topic,omitempty"`    // kafka
+	Bucket   string `json:"bucket,omitempty"`   // s3
+	Region   string `json:"region,omitempty"`   // s3
+	Endpoint string `json:"endpoint,omitempty"` // s3: S3-compatible endpoint; empty means AWS
+	Prefix   string `json:"prefix,omitempty"`   // s3: key prefix
+}
+
+type CreateEventSinkRequest struct {
+	Name    string          `json:"name"`
+	Kind    string          `json:"kind"`
+	Config  EventSinkConfig `json:"config"`
+	Secret  *string         `json:"secret"`
+	Levels  []string        `json:"levels"`
+	Sources []string        `json:"sources"`
+	Enabled *bool           `json:"enabled"` // default true
+}
+
+type UpdateEventSinkRequest struct {
+	Name    *string          `json:"name"`
+	Config  *EventSinkConfig `json:"config"`
+	Secret  *string          `json:"secret"` // "" removes it
+	Levels  *[]string        `json:"levels"`
+	Sources *[]string        `json:"sources"`
+	Enabled *bool            `json:"enabled"`
+}
+
+// TailCommand is sent by a live tail client: "filter" replaces the filter,
+// "pause" holds matching events and "resume" sends them
+type TailCommand struct {
+	Type   string       `json:"type"`
+	Filter *ErrorFilter `json:"filter,omitempty"`
+}
+
+// TailMessage is sent to a live tail client. "event" carries a stored error,
+// "status" the current filter and state, "dropped" how many events were lost
+// since the last such message, and "error" a rejected command.
+type TailMessage struct {
+	Type    string       `json:"type"`
+	Error   *Error       `json:"error,omitempty"`
+	Filter  *ErrorFilter `json:"filter,omitempty"`
+	Paused  *bool        `json:"paused,omitempty"`
+	Dropped int          `json:"dropped,omitempty"`
+	Message string       `json:"message,omitempty"`
 }
 
 // Response wrapper types
@@ -200,4 +1839,30 @@ type APIResponse struct {
 	Data   interface{} `json:"data,omitempty"`
 	Error  string      `json:"error,omitempty"`
 	Status string      `json:"status"`
+
+	// Set on errors: a machine-readable code, the request fields at fault,
+	// and the ID to quote when reporting the failure
+	Code      string       `json:"code,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+
+	// Backoff is set when ingestion refused or sampled out the request to
+	// shed load
+	Backoff *Backoff `json:"backoff,omitempty"`
+}
+
+// Backoff tells an SDK how to ease off while ingestion is shedding load
+type Backoff struct {
+	// RetryAfter is how many seconds to wait before sending again
+	RetryAfter int `json:"retry_after"`
+	// SampleRate is the fraction of events to send until then, if the SDK
+	// should keep sending at all
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+}
+
+// FieldError explains why one request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
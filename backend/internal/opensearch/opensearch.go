@@ -0,0 +1,191 @@
+// Package opensearch indexes and searches documents in one OpenSearch (or
+// Elasticsearch) index over the REST API.
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to one index
+type Client struct {
+	baseURL  *url.URL
+	index    string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient returns a client for the index. username and password are sent
+// as basic auth when set.
+func NewClient(rawURL, index, username, password string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(rawURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OpenSearch URL %q", rawURL)
+	}
+	if index == "" {
+		return nil, fmt.Errorf("an OpenSearch index name is required")
+	}
+
+	return &Client{
+		baseURL:  u,
+		index:    index,
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// BulkAction is one operation of a bulk request: "index" replaces the
+// document with Doc, "delete" removes it
+type BulkAction struct {
+	Op  string
+	ID  string
+	Doc interface{}
+}
+
+// EnsureIndex creates the index with the given settings and mappings unless
+// it already exists
+func (c *Client) EnsureIndex(ctx context.Context, definition interface{}) error {
+	resp, err := c.do(ctx, http.MethodHead, "/"+c.index, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, err := json.Marshal(definition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index definition: %w", err)
+	}
+	return c.expectOK(c.do(ctx, http.MethodPut, "/"+c.index, "application/json", body))
+}
+
+// Bulk applies the actions in one request. It fails if any action failed.
+func (c *Client) Bulk(ctx context.Context, actions []BulkAction) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, action := range actions {
+		meta := map[string]map[string]string{action.Op: {"_id": action.ID}}
+		if err := encoder.Encode(meta); err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if action.Op != "delete" {
+			if err := encoder.Encode(action.Doc); err != nil {
+				return fmt.Errorf("failed to encode document %s: %w", action.ID, err)
+			}
+		}
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/"+c.index+"/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return statusError(resp)
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for op, outcome := range item {
+			// Deleting a document that was never indexed is fine
+			if outcome.Error != nil && !(op == "delete" && outcome.Status == http.StatusNotFound) {
+				return fmt.Errorf("bulk %s failed: %s", op, outcome.Error.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateByQuery runs a painless script on every document matching query
+func (c *Client) UpdateByQuery(ctx context.Context, query interface{}, script string, params map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":  query,
+		"script": map[string]interface{}{"source": script, "params": params},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+	return c.expectOK(c.do(ctx, http.MethodPost, "/"+c.index+"/_update_by_query?conflicts=proceed", "application/json", body))
+}
+
+// Search runs a search request and decodes the response into out
+func (c *Client) Search(ctx context.Context, request interface{}, out interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/"+c.index+"/_search", "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return statusError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach OpenSearch: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) expectOK(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+func statusError(resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("OpenSearch returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const UsageReportedPrefix = "usage_reported:"
+
+func usageReportedKey(start time.Time) string {
+	return UsageReportedPrefix + start.Format("2006-01-02")
+}
+
+// ClaimUsageDelivery reports whether the usage of the day starting at start
+// has yet to be posted to the billing webhook, so a new leader does not post
+// it again
+func (c *Client) ClaimUsageDelivery(ctx context.Context, start time.Time, ttl time.Duration) (bool, error) {
+	fresh, err := c.SetNX(ctx, usageReportedKey(start), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim usage delivery: %w", err)
+	}
+	return fresh, nil
+}
+
+// ReleaseUsageDelivery gives up a claim after a failed delivery so it is
+// retried
+func (c *Client) ReleaseUsageDelivery(ctx context.Context, start time.Time) error {
+	if err := c.Del(ctx, usageReportedKey(start)).Err(); err != nil {
+		return fmt.Errorf("failed to release usage delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Cache generations count invalidations of a cache. Responses derive their
+// ETags from them, so a client's copy stays valid until the next invalidation.
+const CacheGenerationPrefix = "cache_generation:"
+
+// ErrorsCacheGeneration covers error lists, stats and analytics built from errors
+const ErrorsCacheGeneration = "errors"
+
+// AnnotationsCacheGeneration covers trend responses, which carry annotations
+const AnnotationsCacheGeneration = "annotations"
+
+func (c *Client) IncrementCacheGeneration(ctx context.Context, name string) error {
+	if err := c.Incr(ctx, CacheGenerationPrefix+name).Err(); err != nil {
+		return fmt.Errorf("failed to increment cache generation: %w", err)
+	}
+	return nil
+}
+
+// GetCacheGeneration returns the current generation, zero if it was never incremented
+func (c *Client) GetCacheGeneration(ctx context.Context, name string) (int64, error) {
+	generation, err := c.Get(ctx, CacheGenerationPrefix+name).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cache generation: %w", err)
+	}
+	return generation, nil
+}
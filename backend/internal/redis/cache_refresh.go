@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CacheAccessPrefix keys hourly sorted sets counting reads of cached queries.
+// Members describe the query, so it can be recomputed without a request.
+const CacheAccessPrefix = "cache_access:"
+
+// maxTrackedCacheEntries bounds each hourly set; the least read entries are
+// dropped first
+const maxTrackedCacheEntries = 1000
+
+func cacheAccessKey(now time.Time) string {
+	return CacheAccessPrefix + strconv.FormatInt(now.Unix()/3600, 10)
+}
+
+// RecordCacheAccess counts a read of the cached query described by entry
+func (c *Client) RecordCacheAccess(ctx context.Context, entry string, now time.Time) error {
+	key := cacheAccessKey(now)
+
+	pipe := c.Pipeline()
+	pipe.ZIncrBy(ctx, key, 1, entry)
+	pipe.ZRemRangeByRank(ctx, key, 0, -maxTrackedCacheEntries-1)
+	pipe.Expire(ctx, key, 2*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record cache access: %w", err)
+	}
+	return nil
+}
+
+// GetPopularCacheEntries returns up to limit entries read at least minReads
+// times in the current and previous hour, most read first
+func (c *Client) GetPopularCacheEntries(ctx context.Context, now time.Time, limit int, minReads float64) ([]string, error) {
+	reads := map[string]float64{}
+	for _, key := range []string{cacheAccessKey(now.Add(-time.Hour)), cacheAccessKey(now)} {
+		entries, err := c.ZRevRangeWithScores(ctx, key, 0, maxTrackedCacheEntries-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cache accesses: %w", err)
+		}
+		for _, entry := range entries {
+			if member, ok := entry.Member.(string); ok {
+				reads[member] += entry.Score
+			}
+		}
+	}
+
+	var popular []string
+	for entry, count := range reads {
+		if count >= minReads {
+			popular = append(popular, entry)
+		}
+	}
+	sort.Slice(popular, func(i, j int) bool {
+		if reads[popular[i]] != reads[popular[j]] {
+			return reads[popular[i]] > reads[popular[j]]
+		}
+		return popular[i] < popular[j]
+	})
+	if len(popular) > limit {
+		popular = popular[:limit]
+	}
+	return popular, nil
+}
+
+// GetStatsCacheTTL returns how long the cached stats under key have left, or
+// zero if they aren't cached
+func (c *Client) GetStatsCacheTTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.cacheTTL(ctx, statsKey(key))
+}
+
+// GetTrendsCacheTTL returns how long the cached trends under key have left,
+// or zero if they aren't cached
+func (c *Client) GetTrendsCacheTTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.cacheTTL(ctx, TrendsCachePrefix+key)
+}
+
+func (c *Client) cacheTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cache TTL: %w", err)
+	}
+	// PTTL answers negative durations for missing keys and keys without expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const ForwardQueuePrefix = "forward_queue:"
+
+func forwardQueueKey(sinkID uuid.UUID) string {
+	return ForwardQueuePrefix + sinkID.String()
+}
+
+// QueueForwardEvent appends an event to a sink's delivery queue. The queue
+// keeps at most maxLength events; the oldest are dropped beyond that.
+func (c *Client) QueueForwardEvent(ctx context.Context, sinkID uuid.UUID, event []byte, maxLength int64) error {
+	key := forwardQueueKey(sinkID)
+	pipe := c.Pipeline()
+	pipe.RPush(ctx, key, event)
+	pipe.LTrim(ctx, key, -maxLength, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to queue forwarded event: %w", err)
+	}
+	return nil
+}
+
+// PeekForwardEvents returns up to n of a sink's oldest queued events without
+// removing them, so they are kept if delivery fails
+func (c *Client) PeekForwardEvents(ctx context.Context, sinkID uuid.UUID, n int) ([]string, error) {
+	events, err := c.LRange(ctx, forwardQueueKey(sinkID), 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forwarded events: %w", err)
+	}
+	return events, nil
+}
+
+// AckForwardEvents removes a sink's n oldest events after they were delivered
+func (c *Client) AckForwardEvents(ctx context.Context, sinkID uuid.UUID, n int) error {
+	if err := c.LTrim(ctx, forwardQueueKey(sinkID), int64(n), -1).Err(); err != nil {
+		return fmt.Errorf("failed to remove forwarded events: %w", err)
+	}
+	return nil
+}
+
+// ForwardQueueLength is the number of events waiting for delivery to a sink
+func (c *Client) ForwardQueueLength(ctx context.Context, sinkID uuid.UUID) (int64, error) {
+	return c.LLen(ctx, forwardQueueKey(sinkID)).Result()
+}
+
+// DeleteForwardQueue drops every event waiting for a deleted sink
+func (c *Client) DeleteForwardQueue(ctx context.Context, sinkID uuid.UUID) error {
+	return c.Del(ctx, forwardQueueKey(sinkID)).Err()
+}
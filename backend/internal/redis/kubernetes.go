@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const KubernetesEventPrefix = "k8s_event:"
+
+// ClaimKubernetesEvent reports whether an occurrence of a Kubernetes event,
+// identified by its UID and count, is seen for the first time within ttl.
+// Watchers send every event again when they restart.
+func (c *Client) ClaimKubernetesEvent(ctx context.Context, uid string, count int, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("%s%s:%d", KubernetesEventPrefix, uid, count)
+	fresh, err := c.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim kubernetes event: %w", err)
+	}
+	return fresh, nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const LockPrefix = "lock:"
+
+// acquireLockScript takes the lock when it is free and extends it when owner
+// already holds it, so an owner whose renewal failed, or whose reply was
+// lost, does not lock itself out
+var acquireLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseLockScript deletes the lock only while owner still holds it
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock takes the named lock for owner unless another owner holds it,
+// and extends it for another ttl when owner holds it already. The lock lapses
+// after ttl unless it is acquired again.
+func (c *Client) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLockScript.Run(ctx, c.Client, []string{LockPrefix + name}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseLock gives up a lock held by owner so another owner can take it
+// without waiting for it to lapse
+func (c *Client) ReleaseLock(ctx context.Context, name, owner string) error {
+	if err := releaseLockScript.Run(ctx, c.Client, []string{LockPrefix + name}, owner).Err(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
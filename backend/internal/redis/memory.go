@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"error-logs/internal/models"
+)
+
+// MemoryReportKey holds the last memory check, written by the leader and
+// read by every instance
+const MemoryReportKey = "redis_memory_report"
+
+// KeyFamily groups keys of one kind for memory accounting. Cache families
+// hold data that is recomputed on a miss, so they can be dropped to stay
+// within the cache budget; the others are never touched.
+type KeyFamily struct {
+	Name     string
+	Patterns []string
+	Cache    bool
+	// TTL is given to cache keys found without an expiry, which a
+	// volatile-* eviction policy would otherwise never evict
+	TTL time.Duration
+}
+
+// KeyFamilies are the key families that can grow with traffic
+var KeyFamilies = []KeyFamily{
+	{Name: "queue", Patterns: []string{ErrorQueueKey}},
+	{Name: "forward_queues", Patterns: []string{ForwardQueuePrefix + "*"}},
+	{Name: "recent_errors", Patterns: []string{RecentErrorsKey}},
+	{Name: "kubernetes_events", Patterns: []string{KubernetesEventPrefix + "*"}},
+	{Name: "error_lists", Patterns: []string{ErrorCachePrefix + "*", CacheKeysSetKey}, Cache: true, TTL: 2 * time.Minute},
+	{Name: "stats", Patterns: []string{StatsCacheKey, StatsCacheKey + ":*"}, Cache: true, TTL: 5 * time.Minute},
+	{Name: "trends", Patterns: []string{TrendsCachePrefix + "*"}, Cache: true, TTL: 5 * time.Minute},
+	{Name: "heatmaps", Patterns: []string{HeatmapCachePrefix + "*"}, Cache: true, TTL: 15 * time.Minute},
+	{Name: "monitoring", Patterns: []string{PerformanceMetricsCacheKey, ServiceHealthCacheKey, SystemMetricsCacheKey, UptimeCacheKey}, Cache: true, TTL: time.Minute},
+	{Name: "cache_access", Patterns: []string{CacheAccessPrefix + "*"}, Cache: true, TTL: 2 * time.Hour},
+}
+
+// memorySampleSize is how many keys of a family are measured with MEMORY
+// USAGE; the family's size is extrapolated from them
+const memorySampleSize = 50
+
+// MeasureKeyFamily counts the keys of a family and estimates their size.
+// Cache keys without an expiry are given the family's TTL.
+func (c *Client) MeasureKeyFamily(ctx context.Context, family KeyFamily) (*models.RedisKeyFamilyUsage, error) {
+	usage := &models.RedisKeyFamilyUsage{Name: family.Name, Cache: family.Cache}
+	var sampled, sampledBytes int64
+
+	err := c.scanKeyFamily(ctx, family, func(keys []string) error {
+		pipe := c.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		sizes := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			if family.Cache {
+				ttls[i] = pipe.PTTL(ctx, key)
+			}
+			if sampled+int64(i) < memorySampleSize {
+				sizes[i] = pipe.MemoryUsage(ctx, key)
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return err
+		}
+
+		for i, key := range keys {
+			if sizes[i] != nil {
+				// Nil for keys that expired since the scan
+				if size, err := sizes[i].Result(); err == nil {
+					sampled++
+					sampledBytes += size
+				}
+			}
+			// -1 is a key without an expiry, -2 one that is gone
+			if ttls[i] != nil && ttls[i].Val() == -1 {
+				if err := c.Expire(ctx, key, family.TTL).Err(); err != nil {
+					return err
+				}
+				usage.ExpiryFixed++
+			}
+		}
+		usage.Keys += int64(len(keys))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s keys: %w", family.Name, err)
+	}
+
+	if sampled > 0 {
+		usage.Bytes = sampledBytes * usage.Keys / sampled
+	}
+	return usage, nil
+}
+
+// DropKeyFamily deletes every key of a cache family
+func (c *Client) DropKeyFamily(ctx context.Context, family KeyFamily) error {
+	if !family.Cache {
+		return fmt.Errorf("%s keys are not a cache", family.Name)
+	}
+	err := c.scanKeyFamily(ctx, family, func(keys []string) error {
+		return c.Unlink(ctx, keys...).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop %s keys: %w", family.Name, err)
+	}
+	return nil
+}
+
+// scanKeyFamily passes the existing keys of a family to fn in batches.
+// Patterns without a wildcard name a single key.
+func (c *Client) scanKeyFamily(ctx context.Context, family KeyFamily, fn func(keys []string) error) error {
+	for _, pattern := range family.Patterns {
+		if !strings.Contains(pattern, "*") {
+			exists, err := c.Exists(ctx, pattern).Result()
+			if err != nil {
+				return err
+			}
+			if exists > 0 {
+				if err := fn([]string{pattern}); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		var cursor uint64
+		for {
+			keys, next, err := c.Scan(ctx, cursor, pattern, 500).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// MemoryInfo returns the memory Redis uses, its maxmemory limit (0 when
+// unlimited) and its eviction policy
+func (c *Client) MemoryInfo(ctx context.Context) (int64, int64, string, error) {
+	info, err := c.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to get memory info: %w", err)
+	}
+
+	var used, max int64
+	var policy string
+	for _, line := range strings.Split(info, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "used_memory":
+			used, _ = strconv.ParseInt(value, 10, 64)
+		case "maxmemory":
+			max, _ = strconv.ParseInt(value, 10, 64)
+		case "maxmemory_policy":
+			policy = value
+		}
+	}
+	return used, max, policy, nil
+}
+
+func (c *Client) SaveMemoryReport(ctx context.Context, report *models.RedisMemoryReport, ttl time.Duration) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory report: %w", err)
+	}
+	if err := c.Set(ctx, MemoryReportKey, reportJSON, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save memory report: %w", err)
+	}
+	return nil
+}
+
+// GetMemoryReport returns the last memory check, nil if there was none
+// recently
+func (c *Client) GetMemoryReport(ctx context.Context) (*models.RedisMemoryReport, error) {
+	result, err := c.Get(ctx, MemoryReportKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory report: %w", err)
+	}
+
+	var report models.RedisMemoryReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal memory report: %w", err)
+	}
+	return &report, nil
+}
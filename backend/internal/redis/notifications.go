@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"error-logs/internal/models"
+)
+
+// NotificationChannel carries inbox changes to the stream clients of every
+// instance. Like TailChannel, delivery is at most once.
+const NotificationChannel = "notifications"
+
+// PublishNotification announces a new notification, or read notifications,
+// to the member's stream clients
+func (c *Client) PublishNotification(ctx context.Context, message *models.NotificationMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	if err := c.Publish(ctx, NotificationChannel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish notification: %w", err)
+	}
+	return nil
+}
+
+// SubscribeNotifications delivers messages published on NotificationChannel
+// until ctx is done, then closes the returned channel
+func (c *Client) SubscribeNotifications(ctx context.Context) (<-chan *models.NotificationMessage, error) {
+	pubsub := c.Subscribe(ctx, NotificationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to notifications: %w", err)
+	}
+
+	messages := make(chan *models.NotificationMessage)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+
+		received := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload, ok := <-received:
+				if !ok {
+					return
+				}
+				var message models.NotificationMessage
+				if err := json.Unmarshal([]byte(payload.Payload), &message); err != nil {
+					log.Printf("Failed to decode notification: %v", err)
+					continue
+				}
+				select {
+				case messages <- &message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	NotificationCountPrefix  = "notify_count:"
+	NotificationDigestPrefix = "notify_digest:"
+	NotificationDigestsKey   = "notify_digests"
+)
+
+// NotificationDigest sums up the notifications held for one rule and channel
+type NotificationDigest struct {
+	Count   int
+	Since   time.Time
+	Samples []string // subjects of the latest held notifications
+}
+
+// CountNotification counts a notification to a rule's channel in the minute
+// and returns how many were counted in it so far
+func (c *Client) CountNotification(ctx context.Context, key string, minute time.Time) (int64, error) {
+	countKey := fmt.Sprintf("%s%s:%d", NotificationCountPrefix, key, minute.Unix())
+	pipe := c.TxPipeline()
+	count := pipe.Incr(ctx, countKey)
+	pipe.Expire(ctx, countKey, 2*time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to count notification: %w", err)
+	}
+	return count.Val(), nil
+}
+
+// HoldNotification adds a notification to the digest of a rule's channel,
+// keeping the subjects of the latest samples. A new digest is due at due.
+func (c *Client) HoldNotification(ctx context.Context, key, subject string, due time.Time, samples int) error {
+	digestKey := NotificationDigestPrefix + key
+	samplesKey := digestKey + ":samples"
+	// Digests are normally taken when due; the expiry only cleans up after a
+	// sender that never came
+	ttl := time.Until(due) + time.Hour
+
+	pipe := c.TxPipeline()
+	pipe.HIncrBy(ctx, digestKey, "count", 1)
+	pipe.HSetNX(ctx, digestKey, "since", time.Now().UTC().Format(time.RFC3339Nano))
+	pipe.RPush(ctx, samplesKey, subject)
+	pipe.LTrim(ctx, samplesKey, int64(-samples), -1)
+	pipe.Expire(ctx, digestKey, ttl)
+	pipe.Expire(ctx, samplesKey, ttl)
+	pipe.ZAddNX(ctx, NotificationDigestsKey, &redis.Z{Score: float64(due.Unix()), Member: key})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to hold notification: %w", err)
+	}
+	return nil
+}
+
+// DueNotificationDigests returns the keys of the digests due by now
+func (c *Client) DueNotificationDigests(ctx context.Context, now time.Time) ([]string, error) {
+	keys, err := c.ZRangeByScore(ctx, NotificationDigestsKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due notification digests: %w", err)
+	}
+	return keys, nil
+}
+
+// TakeNotificationDigest removes a digest and returns what it held. It
+// returns nil if the digest was already taken or has expired.
+func (c *Client) TakeNotificationDigest(ctx context.Context, key string) (*NotificationDigest, error) {
+	digestKey := NotificationDigestPrefix + key
+	samplesKey := digestKey + ":samples"
+
+	pipe := c.TxPipeline()
+	removed := pipe.ZRem(ctx, NotificationDigestsKey, key)
+	fields := pipe.HGetAll(ctx, digestKey)
+	samples := pipe.LRange(ctx, samplesKey, 0, -1)
+	pipe.Del(ctx, digestKey, samplesKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to take notification digest: %w", err)
+	}
+
+	count, _ := strconv.Atoi(fields.Val()["count"])
+	if removed.Val() == 0 || count == 0 {
+		return nil, nil
+	}
+	since, _ := time.Parse(time.RFC3339Nano, fields.Val()["since"])
+	return &NotificationDigest{Count: count, Since: since, Samples: samples.Val()}, nil
+}
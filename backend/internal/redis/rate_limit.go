@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const RateLimitPrefix = "rate_limit:"
+
+// IncrementRateLimit counts a request for key in the current minute bucket
+// and returns the count so far
+func (c *Client) IncrementRateLimit(ctx context.Context, key string, now time.Time) (int64, error) {
+	bucketKey := fmt.Sprintf("%s%s:%d", RateLimitPrefix, key, now.Unix()/60)
+
+	pipe := c.Pipeline()
+	incr := pipe.Incr(ctx, bucketKey)
+	pipe.Expire(ctx, bucketKey, 2*time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment rate limit: %w", err)
+	}
+
+	return incr.Val(), nil
+}
@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+
+	"error-logs/internal/models"
+)
+
+const ReadOnlyModeKey = "read_only_mode"
+
+// GetReadOnlyMode returns the shared read-only flag, off when it was never set
+func (c *Client) GetReadOnlyMode(ctx context.Context) (models.ReadOnlyStatus, error) {
+	var status models.ReadOnlyStatus
+	data, err := c.Get(ctx, ReadOnlyModeKey).Result()
+	if err == redis.Nil {
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("failed to get read-only mode: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return status, fmt.Errorf("failed to unmarshal read-only mode: %w", err)
+	}
+	return status, nil
+}
+
+func (c *Client) SetReadOnlyMode(ctx context.Context, status models.ReadOnlyStatus) error {
+	if !status.Enabled {
+		if err := c.Del(ctx, ReadOnlyModeKey).Err(); err != nil {
+			return fmt.Errorf("failed to clear read-only mode: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal read-only mode: %w", err)
+	}
+	if err := c.Set(ctx, ReadOnlyModeKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to set read-only mode: %w", err)
+	}
+	return nil
+}
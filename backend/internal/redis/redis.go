@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,10 +11,12 @@ import (
 	"github.com/go-redis/redis/v8"
 
 	"error-logs/internal/models"
+	"error-logs/internal/retry"
 )
 
 type Client struct {
 	*redis.Client
+	retries *retry.Counter
 }
 
 func NewClient(redisURL string) (*Client, error) {
@@ -22,6 +25,8 @@ func NewClient(redisURL string) (*Client, error) {
 		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
 	}
 
+	// Client retries idempotent commands itself and counts the retries
+	opt.MaxRetries = -1
 	rdb := redis.NewClient(opt)
 
 	// Test connection
@@ -29,10 +34,11 @@ func NewClient(redisURL string) (*Client, error) {
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &Client{rdb}, nil
+	return &Client{Client: rdb, retries: &retry.Counter{}}, nil
 }
 
 const (
@@ -41,6 +47,7 @@ const (
 	ErrorCachePrefix           = "error_cache:"
 	StatsCacheKey              = "stats_cache"
 	TrendsCachePrefix          = "trends_cache:"
+	HeatmapCachePrefix         = "heatmap_cache:"
 	PerformanceMetricsCacheKey = "performance_metrics_cache"
 	ServiceHealthCacheKey      = "service_health_cache"
 	SystemMetricsCacheKey      = "system_metrics_cache"
@@ -48,18 +55,44 @@ const (
 	CacheKeysSetKey            = "cache_keys_set"
 )
 
-func (c *Client) QueueError(ctx context.Context, error *models.Error) error {
+// ErrQueueFull is returned by QueueError when the queue is at its maximum length
+var ErrQueueFull = errors.New("error queue is full")
+
+// queueErrorScript pushes onto the queue unless it already holds ARGV[2]
+// entries (0 means unbounded) and returns the new length, or -1 when full
+var queueErrorScript = redis.NewScript(`
+local max = tonumber(ARGV[2])
+if max > 0 and redis.call("LLEN", KEYS[1]) >= max then
+	return -1
+end
+local length = redis.call("LPUSH", KEYS[1], ARGV[1])
+redis.call("LPUSH", KEYS[2], ARGV[1])
+redis.call("LTRIM", KEYS[2], 0, 99)
+return length
+`)
+
+// QueueError adds an event to the processing queue and the recent-errors
+// list. It returns the queue length, or ErrQueueFull when the queue already
+// holds maxLength events.
+func (c *Client) QueueError(ctx context.Context, error *models.Error, maxLength int64) (int64, error) {
 	errorJSON, err := json.Marshal(error)
 	if err != nil {
-		return fmt.Errorf("failed to marshal error: %w", err)
+		return 0, fmt.Errorf("failed to marshal error: %w", err)
 	}
 
-	pipe := c.Pipeline()
-	pipe.LPush(ctx, ErrorQueueKey, errorJSON)
-	pipe.LPush(ctx, RecentErrorsKey, errorJSON)
-	pipe.LTrim(ctx, RecentErrorsKey, 0, 99)
-	_, err = pipe.Exec(ctx)
-	return err
+	length, err := queueErrorScript.Run(ctx, c.Client, []string{ErrorQueueKey, RecentErrorsKey}, errorJSON, maxLength).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if length < 0 {
+		return maxLength, ErrQueueFull
+	}
+	return length, nil
+}
+
+// QueueLength is the number of events waiting to be written to the database
+func (c *Client) QueueLength(ctx context.Context) (int64, error) {
+	return c.LLen(ctx, ErrorQueueKey).Result()
 }
 
 func (c *Client) DequeueError(ctx context.Context) (*models.Error, error) {
@@ -78,6 +111,16 @@ func (c *Client) DequeueError(ctx context.Context) (*models.Error, error) {
 	return &error, nil
 }
 
+// RequeueError puts an error back at the head of the queue, to be dequeued
+// next, after it could not be processed
+func (c *Client) RequeueError(ctx context.Context, error *models.Error) error {
+	errorJSON, err := json.Marshal(error)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error: %w", err)
+	}
+	return c.RPush(ctx, ErrorQueueKey, errorJSON).Err()
+}
+
 func (c *Client) GetRecentErrors(ctx context.Context, limit int) ([]models.Error, error) {
 	results, err := c.LRange(ctx, RecentErrorsKey, 0, int64(limit-1)).Result()
 	if err != nil {
@@ -95,10 +138,16 @@ func (c *Client) GetRecentErrors(ctx context.Context, limit int) ([]models.Error
 	return errors, nil
 }
 
-func (c *Client) CacheErrorList(ctx context.Context, key string, errors []models.Error, ttl time.Duration) error {
+// cachedErrorList is a page of errors with the total of the list it came from
+type cachedErrorList struct {
+	Errors []models.Error `json:"errors"`
+	Total  int            `json:"total"`
+}
+
+func (c *Client) CacheErrorList(ctx context.Context, key string, errors []models.Error, total int, ttl time.Duration) error {
 	start := time.Now()
 
-	errorsJSON, err := json.Marshal(errors)
+	errorsJSON, err := json.Marshal(cachedErrorList{Errors: errors, Total: total})
 	if err != nil {
 		log.Printf("REDIS MARSHAL ERROR: Error list - key: %s, error: %v", key, err)
 		return fmt.Errorf("failed to marshal errors: %w", err)
@@ -108,6 +157,8 @@ func (c *Client) CacheErrorList(ctx context.Context, key string, errors []models
 	pipe := c.Pipeline()
 	pipe.Set(ctx, fullKey, errorsJSON, ttl)
 	pipe.SAdd(ctx, CacheKeysSetKey, fullKey)
+	// Lives as long as the newest list, rather than growing forever
+	pipe.Expire(ctx, CacheKeysSetKey, ttl)
 	_, err = pipe.Exec(ctx)
 
 	if err != nil {
@@ -119,7 +170,9 @@ func (c *Client) CacheErrorList(ctx context.Context, key string, errors []models
 	return nil
 }
 
-func (c *Client) GetCachedErrorList(ctx context.Context, key string) ([]models.Error, error) {
+// GetCachedErrorList returns a cached page of errors and the total of the
+// list, or nil errors on a miss
+func (c *Client) GetCachedErrorList(ctx context.Context, key string) ([]models.Error, int, error) {
 	start := time.Now()
 	fullKey := ErrorCachePrefix + key
 
@@ -127,23 +180,76 @@ func (c *Client) GetCachedErrorList(ctx context.Context, key string) ([]models.E
 	if err != nil {
 		if err == redis.Nil {
 			log.Printf("REDIS CACHE MISS: Error list - key: %s, duration: %v", key, time.Since(start))
-			return nil, nil
+			return nil, 0, nil
 		}
 		log.Printf("REDIS ERROR: GetCachedErrorList - key: %s, error: %v, duration: %v", key, err, time.Since(start))
-		return nil, fmt.Errorf("failed to get cached errors: %w", err)
+		return nil, 0, fmt.Errorf("failed to get cached errors: %w", err)
 	}
 
-	var errors []models.Error
-	if err := json.Unmarshal([]byte(result), &errors); err != nil {
+	var list cachedErrorList
+	if err := json.Unmarshal([]byte(result), &list); err != nil {
 		log.Printf("REDIS UNMARSHAL ERROR: Error list - key: %s, error: %v", key, err)
-		return nil, fmt.Errorf("failed to unmarshal cached errors: %w", err)
+		return nil, 0, fmt.Errorf("failed to unmarshal cached errors: %w", err)
 	}
 
-	log.Printf("REDIS CACHE HIT: Error list - key: %s, count: %d, duration: %v", key, len(errors), time.Since(start))
-	return errors, nil
+	log.Printf("REDIS CACHE HIT: Error list - key: %s, count: %d, duration: %v", key, len(list.Errors), time.Since(start))
+	return list.Errors, list.Total, nil
 }
 
-func (c *Client) CacheStats(ctx context.Context, stats *models.StatsResponse) error {
+func (c *Client) CacheErrorFacets(ctx context.Context, key string, facets *models.ErrorFacetsResponse, ttl time.Duration) error {
+	start := time.Now()
+
+	facetsJSON, err := json.Marshal(facets)
+	if err != nil {
+		log.Printf("REDIS MARSHAL ERROR: Error facets - key: %s, error: %v", key, err)
+		return fmt.Errorf("failed to marshal error facets: %w", err)
+	}
+
+	// Stored under the error cache prefix so new errors invalidate it
+	fullKey := ErrorCachePrefix + key
+	err = c.Set(ctx, fullKey, facetsJSON, ttl).Err()
+	if err != nil {
+		log.Printf("REDIS WRITE ERROR: Error facets - key: %s, error: %v, duration: %v", key, err, time.Since(start))
+		return err
+	}
+
+	log.Printf("REDIS CACHE WRITE: Error facets - key: %s, ttl: %v, duration: %v", key, ttl, time.Since(start))
+	return nil
+}
+
+func (c *Client) GetCachedErrorFacets(ctx context.Context, key string) (*models.ErrorFacetsResponse, error) {
+	start := time.Now()
+	fullKey := ErrorCachePrefix + key
+
+	result, err := c.Get(ctx, fullKey).Result()
+	if err != nil {
+		if err == redis.Nil {
+			log.Printf("REDIS CACHE MISS: Error facets - key: %s, duration: %v", key, time.Since(start))
+			return nil, nil
+		}
+		log.Printf("REDIS ERROR: GetCachedErrorFacets - key: %s, error: %v, duration: %v", key, err, time.Since(start))
+		return nil, fmt.Errorf("failed to get cached error facets: %w", err)
+	}
+
+	var facets models.ErrorFacetsResponse
+	if err := json.Unmarshal([]byte(result), &facets); err != nil {
+		log.Printf("REDIS UNMARSHAL ERROR: Error facets - key: %s, error: %v", key, err)
+		return nil, fmt.Errorf("failed to unmarshal cached error facets: %w", err)
+	}
+
+	log.Printf("REDIS CACHE HIT: Error facets - key: %s, duration: %v", key, time.Since(start))
+	return &facets, nil
+}
+
+// statsKey scopes the stats cache; the unscoped stats keep the bare key
+func statsKey(key string) string {
+	if key == "" {
+		return StatsCacheKey
+	}
+	return StatsCacheKey + ":" + key
+}
+
+func (c *Client) CacheStats(ctx context.Context, key string, stats *models.StatsResponse) error {
 	start := time.Now()
 
 	statsJSON, err := json.Marshal(stats)
@@ -152,26 +258,26 @@ func (c *Client) CacheStats(ctx context.Context, stats *models.StatsResponse) er
 		return fmt.Errorf("failed to marshal stats: %w", err)
 	}
 
-	err = c.Set(ctx, StatsCacheKey, statsJSON, 5*time.Minute).Err()
+	err = c.Set(ctx, statsKey(key), statsJSON, 5*time.Minute).Err()
 	if err != nil {
-		log.Printf("REDIS WRITE ERROR: Stats - error: %v, duration: %v", err, time.Since(start))
+		log.Printf("REDIS WRITE ERROR: Stats - key: %s, error: %v, duration: %v", key, err, time.Since(start))
 		return err
 	}
 
-	log.Printf("REDIS CACHE WRITE: Stats - ttl: 5m, duration: %v", time.Since(start))
+	log.Printf("REDIS CACHE WRITE: Stats - key: %s, ttl: 5m, duration: %v", key, time.Since(start))
 	return nil
 }
 
-func (c *Client) GetCachedStats(ctx context.Context) (*models.StatsResponse, error) {
+func (c *Client) GetCachedStats(ctx context.Context, key string) (*models.StatsResponse, error) {
 	start := time.Now()
 
-	result, err := c.Get(ctx, StatsCacheKey).Result()
+	result, err := c.Get(ctx, statsKey(key)).Result()
 	if err != nil {
 		if err == redis.Nil {
-			log.Printf("REDIS CACHE MISS: Stats - duration: %v", time.Since(start))
+			log.Printf("REDIS CACHE MISS: Stats - key: %s, duration: %v", key, time.Since(start))
 			return nil, nil
 		}
-		log.Printf("REDIS ERROR: GetCachedStats - error: %v, duration: %v", err, time.Since(start))
+		log.Printf("REDIS ERROR: GetCachedStats - key: %s, error: %v, duration: %v", key, err, time.Since(start))
 		return nil, fmt.Errorf("failed to get cached stats: %w", err)
 	}
 
@@ -181,7 +287,7 @@ func (c *Client) GetCachedStats(ctx context.Context) (*models.StatsResponse, err
 		return nil, fmt.Errorf("failed to unmarshal cached stats: %w", err)
 	}
 
-	log.Printf("REDIS CACHE HIT: Stats - duration: %v", time.Since(start))
+	log.Printf("REDIS CACHE HIT: Stats - key: %s, duration: %v", key, time.Since(start))
 	return &stats, nil
 }
 
@@ -211,13 +317,20 @@ func (c *Client) InvalidateErrorCache(ctx context.Context) error {
 func (c *Client) InvalidateStatsCache(ctx context.Context) error {
 	start := time.Now()
 
-	err := c.Del(ctx, StatsCacheKey).Err()
+	keys, err := c.Keys(ctx, StatsCacheKey+":*").Result()
+	if err != nil {
+		log.Printf("REDIS INVALIDATE ERROR: Stats cache - failed to get keys: %v", err)
+		return err
+	}
+	keys = append(keys, StatsCacheKey)
+
+	err = c.Del(ctx, keys...).Err()
 	if err != nil {
 		log.Printf("REDIS INVALIDATE ERROR: Stats cache - error: %v", err)
 		return err
 	}
 
-	log.Printf("REDIS CACHE INVALIDATE: Stats cache - duration: %v", time.Since(start))
+	log.Printf("REDIS CACHE INVALIDATE: Stats cache - keys: %d, duration: %v", len(keys), time.Since(start))
 	return nil
 }
 
@@ -229,7 +342,12 @@ func (c *Client) InvalidateAllCache(ctx context.Context) error {
 		return err
 	}
 
-	err := c.InvalidateStatsCache(ctx)
+	if err := c.InvalidateStatsCache(ctx); err != nil {
+		return err
+	}
+
+	// Bumped after the keys are gone so a new ETag never maps to stale cached data
+	err := c.IncrementCacheGeneration(ctx, ErrorsCacheGeneration)
 	log.Printf("REDIS CACHE INVALIDATE: Full cache invalidation completed, duration: %v", time.Since(start))
 	return err
 }
@@ -279,6 +397,50 @@ func (c *Client) GetCachedTrends(ctx context.Context, key string) (*models.Trend
 	return &trends, nil
 }
 
+func (c *Client) CacheHeatmap(ctx context.Context, key string, heatmap *models.HeatmapResponse, ttl time.Duration) error {
+	start := time.Now()
+
+	heatmapJSON, err := json.Marshal(heatmap)
+	if err != nil {
+		log.Printf("REDIS MARSHAL ERROR: Heatmap - key: %s, error: %v", key, err)
+		return fmt.Errorf("failed to marshal heatmap: %w", err)
+	}
+
+	fullKey := HeatmapCachePrefix + key
+	err = c.Set(ctx, fullKey, heatmapJSON, ttl).Err()
+	if err != nil {
+		log.Printf("REDIS WRITE ERROR: Heatmap - key: %s, error: %v, duration: %v", key, err, time.Since(start))
+		return err
+	}
+
+	log.Printf("REDIS CACHE WRITE: Heatmap - key: %s, ttl: %v, duration: %v", key, ttl, time.Since(start))
+	return nil
+}
+
+func (c *Client) GetCachedHeatmap(ctx context.Context, key string) (*models.HeatmapResponse, error) {
+	start := time.Now()
+	fullKey := HeatmapCachePrefix + key
+
+	result, err := c.Get(ctx, fullKey).Result()
+	if err != nil {
+		if err == redis.Nil {
+			log.Printf("REDIS CACHE MISS: Heatmap - key: %s, duration: %v", key, time.Since(start))
+			return nil, nil
+		}
+		log.Printf("REDIS ERROR: GetCachedHeatmap - key: %s, error: %v, duration: %v", key, err, time.Since(start))
+		return nil, fmt.Errorf("failed to get cached heatmap: %w", err)
+	}
+
+	var heatmap models.HeatmapResponse
+	if err := json.Unmarshal([]byte(result), &heatmap); err != nil {
+		log.Printf("REDIS UNMARSHAL ERROR: Heatmap - key: %s, error: %v", key, err)
+		return nil, fmt.Errorf("failed to unmarshal cached heatmap: %w", err)
+	}
+
+	log.Printf("REDIS CACHE HIT: Heatmap - key: %s, duration: %v", key, time.Since(start))
+	return &heatmap, nil
+}
+
 func (c *Client) CachePerformanceMetrics(ctx context.Context, key string, metrics *models.PerformanceMetrics, ttl time.Duration) error {
 	start := time.Now()
 
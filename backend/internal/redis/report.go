@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const ReportSentPrefix = "report_sent:"
+
+// ClaimReportDelivery reports whether the scheduled report for a period has
+// yet to be emailed, so a new leader does not send it again
+func (c *Client) ClaimReportDelivery(ctx context.Context, period string, start time.Time, ttl time.Duration) (bool, error) {
+	key := ReportSentPrefix + period + ":" + start.Format(time.RFC3339)
+	fresh, err := c.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim report delivery: %w", err)
+	}
+	return fresh, nil
+}
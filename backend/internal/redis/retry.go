@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"error-logs/internal/retry"
+)
+
+// isTransient reports whether a command failed because Redis was briefly
+// unreachable, slow, or still loading its dataset
+func isTransient(err error) bool {
+	if err == redis.Nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "LOADING ") || strings.HasPrefix(msg, "TRYAGAIN ")
+}
+
+// RetryCount is the number of commands retried since the process started
+func (c *Client) RetryCount() int64 {
+	return c.retries.Retries()
+}
+
+// RetryExhaustedCount is the number of commands that failed after every retry
+func (c *Client) RetryExhaustedCount() int64 {
+	return c.retries.Exhausted()
+}
+
+func (c *Client) retry(ctx context.Context, cmd func() error) {
+	retry.Do(ctx, retry.DefaultPolicy, c.retries, isTransient, cmd)
+}
+
+// The commands below are idempotent and retried on transient errors. Others,
+// such as Incr and pipelines, are sent once: a timed out command may still
+// have been applied.
+
+func (c *Client) Get(ctx context.Context, key string) *redis.StringCmd {
+	var cmd *redis.StringCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.Get(ctx, key)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *Client) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	var cmd *redis.SliceCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.MGet(ctx, keys...)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *Client) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	var cmd *redis.StatusCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.Set(ctx, key, value, expiration)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *Client) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var cmd *redis.IntCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.Del(ctx, keys...)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *Client) Keys(ctx context.Context, pattern string) *redis.StringSliceCmd {
+	var cmd *redis.StringSliceCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.Keys(ctx, pattern)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	var cmd *redis.StringSliceCmd
+	c.retry(ctx, func() error {
+		cmd = c.Client.LRange(ctx, key, start, stop)
+		return cmd.Err()
+	})
+	return cmd
+}
@@ -0,0 +1,19 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const SignaturePrefix = "signature:"
+
+// ClaimSignature records a request signature and reports whether it was
+// seen for the first time within ttl
+func (c *Client) ClaimSignature(ctx context.Context, signature string, ttl time.Duration) (bool, error) {
+	fresh, err := c.SetNX(ctx, SignaturePrefix+signature, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record signature: %w", err)
+	}
+	return fresh, nil
+}
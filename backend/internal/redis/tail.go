@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"error-logs/internal/models"
+)
+
+// TailChannel carries stored events to the live tail subscribers of every
+// instance. Delivery is at most once: events published while an instance is
+// disconnected are not replayed.
+const TailChannel = "error_tail"
+
+// PublishTailEvent announces a stored event to live tail subscribers
+func (c *Client) PublishTailEvent(ctx context.Context, event *models.Error) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tail event: %w", err)
+	}
+	if err := c.Publish(ctx, TailChannel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish tail event: %w", err)
+	}
+	return nil
+}
+
+// SubscribeTail delivers events published on TailChannel until ctx is done,
+// then closes the returned channel. The subscription reconnects by itself
+// after connection errors.
+func (c *Client) SubscribeTail(ctx context.Context) (<-chan *models.Error, error) {
+	pubsub := c.Subscribe(ctx, TailChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to tail events: %w", err)
+	}
+
+	events := make(chan *models.Error)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event models.Error
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					log.Printf("Failed to decode tail event: %v", err)
+					continue
+				}
+				select {
+				case events <- &event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
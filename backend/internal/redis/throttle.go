@@ -0,0 +1,157 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const (
+	SpikeCounterPrefix       = "spike_counter:"
+	ThrottlePeriodPrefix     = "throttle_period:"
+	ThrottleSuppressedPrefix = "throttle_suppressed:"
+	// SuppressedEventsKey counts suppressed events per group and UTC day until
+	// they are written to the database
+	SuppressedEventsKey = "suppressed_events"
+)
+
+// throttleWindow is how long a throttle period stays open after the last spike event
+const throttleWindow = 2 * time.Minute
+
+// IncrementSpikeCounter counts an event for kind/key in the current minute bucket
+func (c *Client) IncrementSpikeCounter(ctx context.Context, kind, key string, now time.Time) (int64, error) {
+	bucketKey := fmt.Sprintf("%s%s:%s:%d", SpikeCounterPrefix, kind, key, now.Unix()/60)
+
+	pipe := c.Pipeline()
+	incr := pipe.Incr(ctx, bucketKey)
+	pipe.Expire(ctx, bucketKey, throttleWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment spike counter: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// GetActiveThrottlePeriod returns the open throttle period for kind/key, if any
+func (c *Client) GetActiveThrottlePeriod(ctx context.Context, kind, key string) (*uuid.UUID, error) {
+	result, err := c.Get(ctx, ThrottlePeriodPrefix+kind+":"+key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get throttle period: %w", err)
+	}
+
+	id, err := uuid.Parse(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse throttle period ID: %w", err)
+	}
+	return &id, nil
+}
+
+// ClaimThrottlePeriod opens a throttle period for kind/key with the given ID,
+// unless another event, possibly on another instance, opened one first. It
+// returns the open period's ID and whether it is the one given.
+func (c *Client) ClaimThrottlePeriod(ctx context.Context, kind, key string, id uuid.UUID) (uuid.UUID, bool, error) {
+	periodKey := ThrottlePeriodPrefix + kind + ":" + key
+	claimed, err := c.SetNX(ctx, periodKey, id.String(), throttleWindow).Result()
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to claim throttle period: %w", err)
+	}
+	if claimed {
+		return id, true, nil
+	}
+
+	existing, err := c.GetActiveThrottlePeriod(ctx, kind, key)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if existing == nil {
+		// The period closed in between; the next event opens a new one
+		return uuid.Nil, false, fmt.Errorf("throttle period for %s %s closed while claiming it", kind, key)
+	}
+	return *existing, false, nil
+}
+
+// TouchThrottlePeriod marks the period as active and counts a suppressed event
+func (c *Client) TouchThrottlePeriod(ctx context.Context, kind, key string, id uuid.UUID, suppressed bool) (int64, error) {
+	periodKey := ThrottlePeriodPrefix + kind + ":" + key
+	suppressedKey := ThrottleSuppressedPrefix + id.String()
+
+	pipe := c.Pipeline()
+	pipe.Set(ctx, periodKey, id.String(), throttleWindow)
+	var count *redis.IntCmd
+	if suppressed {
+		count = pipe.Incr(ctx, suppressedKey)
+	} else {
+		count = pipe.IncrBy(ctx, suppressedKey, 0)
+	}
+	pipe.Expire(ctx, suppressedKey, 2*throttleWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to update throttle period: %w", err)
+	}
+
+	return count.Val(), nil
+}
+
+// CountSuppressedEvent counts an event of the group that spike protection
+// dropped
+func (c *Client) CountSuppressedEvent(ctx context.Context, fingerprint string, now time.Time) error {
+	field := fingerprint + ":" + now.Format("2006-01-02")
+	if err := c.HIncrBy(ctx, SuppressedEventsKey, field, 1).Err(); err != nil {
+		return fmt.Errorf("failed to count suppressed event: %w", err)
+	}
+	return nil
+}
+
+// takeSuppressedEventsScript reads and clears the counts in one step, so no
+// event counted in between is lost
+var takeSuppressedEventsScript = redis.NewScript(`
+local counts = redis.call("HGETALL", KEYS[1])
+redis.call("DEL", KEYS[1])
+return counts
+`)
+
+// TakeSuppressedEvents returns the suppressed events counted since the last
+// call and clears them
+func (c *Client) TakeSuppressedEvents(ctx context.Context) ([]models.SuppressedEvents, error) {
+	values, err := takeSuppressedEventsScript.Run(ctx, c.Client, []string{SuppressedEventsKey}).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take suppressed events: %w", err)
+	}
+
+	counts := []models.SuppressedEvents{}
+	for i := 0; i+1 < len(values); i += 2 {
+		separator := strings.LastIndex(values[i], ":")
+		if separator < 0 {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", values[i][separator+1:])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(values[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		counts = append(counts, models.SuppressedEvents{Fingerprint: values[i][:separator], Day: day, Count: count})
+	}
+	return counts, nil
+}
+
+// RestoreSuppressedEvents adds counts back after they could not be stored
+func (c *Client) RestoreSuppressedEvents(ctx context.Context, counts []models.SuppressedEvents) error {
+	pipe := c.Pipeline()
+	for _, count := range counts {
+		pipe.HIncrBy(ctx, SuppressedEventsKey, count.Fingerprint+":"+count.Day.Format("2006-01-02"), count.Count)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,203 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const (
+	UsageDailyPrefix   = "usage_daily:"
+	UsageMonthlyPrefix = "usage_monthly:"
+	// PendingUsageKey counts accepted and dropped events per project and UTC
+	// day until they are moved to the database
+	PendingUsageKey = "usage_pending"
+)
+
+// Project and organization IDs are both UUIDs, so they share one key space
+func usageDailyKey(id uuid.UUID, now time.Time) string {
+	return UsageDailyPrefix + id.String() + ":" + now.Format("2006-01-02")
+}
+
+func usageMonthlyKey(id uuid.UUID, now time.Time) string {
+	return UsageMonthlyPrefix + id.String() + ":" + now.Format("2006-01")
+}
+
+// GetUsageCounters returns the accepted event counts of a project or
+// organization for the current day and month
+func (c *Client) GetUsageCounters(ctx context.Context, id uuid.UUID, now time.Time) (int, int, error) {
+	results, err := c.MGet(ctx, usageDailyKey(id, now), usageMonthlyKey(id, now)).Result()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get usage counters: %w", err)
+	}
+
+	counts := make([]int, 2)
+	for i, result := range results {
+		if str, ok := result.(string); ok {
+			fmt.Sscanf(str, "%d", &counts[i])
+		}
+	}
+
+	return counts[0], counts[1], nil
+}
+
+// UsageLimit is a project or organization an event counts against, with its
+// hard limits; a zero limit is unlimited
+type UsageLimit struct {
+	ID      uuid.UUID
+	Daily   int
+	Monthly int
+}
+
+// reserveUsageScript counts an event against every project or organization
+// in KEYS (a daily and a monthly counter each) and takes it back from all of
+// them when one goes over its limit, so concurrent events cannot overshoot a
+// hard limit between reading and incrementing the counters. It returns the
+// 1-based position of the first limit hit, 0 when none was, followed by the
+// counts before this event.
+var reserveUsageScript = redis.NewScript(`
+local blocked = 0
+local result = {0}
+for i = 1, #KEYS / 2 do
+	local daily = redis.call("INCR", KEYS[2*i-1])
+	redis.call("EXPIRE", KEYS[2*i-1], ARGV[1])
+	local monthly = redis.call("INCR", KEYS[2*i])
+	redis.call("EXPIRE", KEYS[2*i], ARGV[2])
+	table.insert(result, daily - 1)
+	table.insert(result, monthly - 1)
+
+	local dailyLimit = tonumber(ARGV[2*i+1])
+	local monthlyLimit = tonumber(ARGV[2*i+2])
+	if blocked == 0 and ((dailyLimit > 0 and daily > dailyLimit) or (monthlyLimit > 0 and monthly > monthlyLimit)) then
+		blocked = i
+	end
+end
+if blocked > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call("DECR", key)
+	end
+end
+result[1] = blocked
+return result
+`)
+
+// ReserveUsage counts an accepted event against the current day and month of
+// every limit unless that puts one of them over its hard limit, in which case
+// nothing is counted. It returns the index of the limit that was hit, or -1,
+// and the daily and monthly counts of each before this event.
+func (c *Client) ReserveUsage(ctx context.Context, now time.Time, limits []UsageLimit) (int, [][2]int, error) {
+	keys := make([]string, 0, 2*len(limits))
+	args := []interface{}{int((48 * time.Hour).Seconds()), int((32 * 24 * time.Hour).Seconds())}
+	for _, limit := range limits {
+		keys = append(keys, usageDailyKey(limit.ID, now), usageMonthlyKey(limit.ID, now))
+		args = append(args, limit.Daily, limit.Monthly)
+	}
+
+	values, err := reserveUsageScript.Run(ctx, c.Client, keys, args...).Int64Slice()
+	if err != nil {
+		return -1, nil, fmt.Errorf("failed to reserve usage: %w", err)
+	}
+	if len(values) != 1+2*len(limits) {
+		return -1, nil, fmt.Errorf("failed to reserve usage: unexpected reply of %d values", len(values))
+	}
+
+	counts := make([][2]int, len(limits))
+	for i := range counts {
+		counts[i] = [2]int{int(values[1+2*i]), int(values[2+2*i])}
+	}
+	return int(values[0]) - 1, counts, nil
+}
+
+// CountUsage adds an accepted or dropped event of the project to the usage
+// waiting to be stored in the database
+func (c *Client) CountUsage(ctx context.Context, projectID uuid.UUID, now time.Time, accepted bool) error {
+	outcome := "dropped"
+	if accepted {
+		outcome = "accepted"
+	}
+	field := projectID.String() + ":" + now.Format("2006-01-02") + ":" + outcome
+	if err := c.HIncrBy(ctx, PendingUsageKey, field, 1).Err(); err != nil {
+		return fmt.Errorf("failed to count usage: %w", err)
+	}
+	return nil
+}
+
+// takePendingUsageScript reads and clears the pending usage in one step, so
+// no event counted in between is lost
+var takePendingUsageScript = redis.NewScript(`
+local counts = redis.call("HGETALL", KEYS[1])
+redis.call("DEL", KEYS[1])
+return counts
+`)
+
+// TakePendingUsage returns the usage counted since the last call, per project
+// and UTC day, and clears it
+func (c *Client) TakePendingUsage(ctx context.Context) ([]models.UsageCount, error) {
+	values, err := takePendingUsageScript.Run(ctx, c.Client, []string{PendingUsageKey}).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take pending usage: %w", err)
+	}
+
+	type projectDay struct {
+		projectID uuid.UUID
+		day       time.Time
+	}
+	index := map[projectDay]int{}
+	counts := []models.UsageCount{}
+	for i := 0; i+1 < len(values); i += 2 {
+		parts := strings.Split(values[i], ":")
+		if len(parts) != 3 {
+			continue
+		}
+		projectID, err := uuid.Parse(parts[0])
+		if err != nil {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", parts[1])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(values[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		key := projectDay{projectID, day}
+		j, ok := index[key]
+		if !ok {
+			j = len(counts)
+			index[key] = j
+			counts = append(counts, models.UsageCount{ProjectID: projectID, Day: day})
+		}
+		switch parts[2] {
+		case "accepted":
+			counts[j].Accepted += count
+		case "dropped":
+			counts[j].Dropped += count
+		}
+	}
+	return counts, nil
+}
+
+// RestorePendingUsage adds usage back after it could not be stored
+func (c *Client) RestorePendingUsage(ctx context.Context, counts []models.UsageCount) error {
+	pipe := c.Pipeline()
+	for _, count := range counts {
+		prefix := count.ProjectID.String() + ":" + count.Day.Format("2006-01-02") + ":"
+		if count.Accepted > 0 {
+			pipe.HIncrBy(ctx, PendingUsageKey, prefix+"accepted", count.Accepted)
+		}
+		if count.Dropped > 0 {
+			pipe.HIncrBy(ctx, PendingUsageKey, prefix+"dropped", count.Dropped)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
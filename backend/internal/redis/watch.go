@@ -0,0 +1,19 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const WatchNotifiedPrefix = "watch_notified:"
+
+// ClaimWatchNotification reports whether watchers of a group may be told
+// about a new event, at most once per ttl
+func (c *Client) ClaimWatchNotification(ctx context.Context, fingerprint string, ttl time.Duration) (bool, error) {
+	fresh, err := c.SetNX(ctx, WatchNotifiedPrefix+fingerprint, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim watch notification: %w", err)
+	}
+	return fresh, nil
+}
@@ -0,0 +1,96 @@
+// Package retry runs operations again after transient failures, waiting a
+// jittered, exponentially growing delay between attempts.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Policy controls how many attempts Do makes and how long it waits between them
+type Policy struct {
+	Attempts  int           // total attempts, including the first
+	BaseDelay time.Duration // upper bound of the first wait, doubled after each retry
+	MaxDelay  time.Duration // upper bound of any wait
+}
+
+// DefaultPolicy suits single queries and cache commands: up to two retries
+// within about a quarter of a second
+var DefaultPolicy = Policy{
+	Attempts:  3,
+	BaseDelay: 50 * time.Millisecond,
+	MaxDelay:  200 * time.Millisecond,
+}
+
+// Counter counts retries, and operations that still failed after all attempts
+type Counter struct {
+	retries   atomic.Int64
+	exhausted atomic.Int64
+}
+
+func (c *Counter) Retries() int64 {
+	return c.retries.Load()
+}
+
+func (c *Counter) Exhausted() int64 {
+	return c.exhausted.Load()
+}
+
+// Do calls fn until it succeeds, fails with an error transient does not
+// accept, runs out of attempts, or ctx is done. It returns fn's last error.
+func Do(ctx context.Context, policy Policy, counter *Counter, transient func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !transient(err) {
+			return err
+		}
+		if attempt >= policy.Attempts {
+			counter.exhausted.Add(1)
+			return err
+		}
+
+		counter.retries.Add(1)
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// delay picks a random wait up to the backoff for attempt ("full jitter"),
+// so clients failing together do not retry together
+func (p Policy) delay(attempt int) time.Duration {
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff))) + 1
+}
+
+// Wait calls fn until it succeeds or ctx is done, backing off between
+// attempts as Do does but without an attempt limit. It returns fn's last
+// error when ctx ends first.
+func Wait(ctx context.Context, policy Policy, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
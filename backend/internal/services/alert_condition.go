@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"error-logs/internal/database"
+)
+
+// conditionNode is a parsed alert condition: either a comparison of a metric
+// against a value, or a boolean combination of child conditions
+type conditionNode struct {
+	Op       string // "and", "or", "not", or "cmp"
+	Children []*conditionNode
+
+	Metric      string
+	Comparator  string
+	Value       float64
+	UsesRuleMax bool // compares against the rule's threshold field
+}
+
+// metrics returns every metric referenced by the condition
+func (n *conditionNode) metrics() []string {
+	if n.Op == "cmp" {
+		return []string{n.Metric}
+	}
+	var result []string
+	for _, child := range n.Children {
+		result = append(result, child.metrics()...)
+	}
+	return dedupeStrings(result)
+}
+
+func (n *conditionNode) eval(values map[string]float64, threshold float64) bool {
+	switch n.Op {
+	case "and":
+		for _, child := range n.Children {
+			if !child.eval(values, threshold) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, child := range n.Children {
+			if child.eval(values, threshold) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !n.Children[0].eval(values, threshold)
+	}
+
+	target := n.Value
+	if n.UsesRuleMax {
+		target = threshold
+	}
+	value := values[n.Metric]
+
+	switch n.Comparator {
+	case ">=":
+		return value >= target
+	case "<":
+		return value < target
+	case "<=":
+		return value <= target
+	case "=", "==":
+		return value == target
+	default:
+		return value > target
+	}
+}
+
+func (n *conditionNode) String() string {
+	switch n.Op {
+	case "and", "or":
+		parts := make([]string, len(n.Children))
+		for i, child := range n.Children {
+			parts[i] = child.String()
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(n.Op)+" ") + ")"
+	case "not":
+		return "NOT " + n.Children[0].String()
+	}
+	if n.UsesRuleMax {
+		return fmt.Sprintf("%s %s threshold", n.Metric, n.Comparator)
+	}
+	return fmt.Sprintf("%s %s %v", n.Metric, n.Comparator, n.Value)
+}
+
+// NewErrorCondition marks rules that notify as soon as an error group is first
+// seen in the rule's scope, instead of being evaluated on a schedule
+const NewErrorCondition = "new_error"
+
+func isNewErrorCondition(condition string) bool {
+	return strings.EqualFold(strings.TrimSpace(condition), NewErrorCondition)
+}
+
+// validateAlertCondition accepts NewErrorCondition and any condition the
+// evaluator can parse
+func validateAlertCondition(condition string) error {
+	if isNewErrorCondition(condition) {
+		return nil
+	}
+	_, err := parseAlertCondition(condition)
+	return err
+}
+
+// parseAlertCondition parses conditions such as
+// "error_count > threshold in time_window" or
+// "error_rate > 5 AND (critical_count >= 10 OR NOT unresolved_count < 3)".
+// "no_events" is shorthand for "event_count = 0".
+// Anything after a trailing "in" is descriptive and ignored.
+func parseAlertCondition(condition string) (*conditionNode, error) {
+	p := &conditionParser{tokens: tokenizeCondition(condition)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty alert condition")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" && !strings.EqualFold(tok, "in") {
+		return nil, fmt.Errorf("unexpected %q in alert condition", tok)
+	}
+	return node, nil
+}
+
+type conditionParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *conditionParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *conditionParser) parseOr() (*conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	node := &conditionNode{Op: "or", Children: []*conditionNode{left}}
+	for strings.EqualFold(p.peek(), "or") || p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, right)
+	}
+	if len(node.Children) == 1 {
+		return left, nil
+	}
+	return node, nil
+}
+
+func (p *conditionParser) parseAnd() (*conditionNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	node := &conditionNode{Op: "and", Children: []*conditionNode{left}}
+	for strings.EqualFold(p.peek(), "and") || p.peek() == "&&" {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, right)
+	}
+	if len(node.Children) == 1 {
+		return left, nil
+	}
+	return node, nil
+}
+
+func (p *conditionParser) parseFactor() (*conditionNode, error) {
+	tok := p.next()
+	switch {
+	case strings.EqualFold(tok, "not") || tok == "!":
+		child, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNode{Op: "not", Children: []*conditionNode{child}}, nil
+	case tok == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in alert condition")
+		}
+		return node, nil
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of alert condition")
+	case strings.EqualFold(tok, "no_events"):
+		// Inactivity: nothing at all received in the scope during the window
+		return &conditionNode{Op: "cmp", Metric: "event_count", Comparator: "=", Value: 0}, nil
+	}
+
+	// Custom metric names keep their case; built-in metrics are case-insensitive
+	metric := tok
+	if !strings.HasPrefix(metric, database.CustomMetricPrefix) {
+		metric = strings.ToLower(tok)
+	}
+	if !database.IsAlertMetric(metric) {
+		return nil, fmt.Errorf("unknown alert metric: %s", tok)
+	}
+
+	node := &conditionNode{Op: "cmp", Metric: metric, Comparator: ">", UsesRuleMax: true}
+	switch p.peek() {
+	case ">", ">=", "<", "<=", "=", "==":
+		node.Comparator = p.next()
+	default:
+		// A bare metric compares "> threshold"
+		return node, nil
+	}
+
+	value := p.next()
+	if strings.EqualFold(value, "threshold") {
+		return node, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q in alert condition", value)
+	}
+	node.Value = number
+	node.UsesRuleMax = false
+	return node, nil
+}
+
+func tokenizeCondition(condition string) []string {
+	var tokens []string
+	runes := []rune(condition)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case strings.ContainsRune("<>=!&|", r):
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune("<>=&|", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()<>=!&|", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+	return tokens
+}
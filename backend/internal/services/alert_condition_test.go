@@ -0,0 +1,96 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAlertCondition(t *testing.T) {
+	tests := []struct {
+		condition   string
+		want        string
+		wantMetrics []string
+	}{
+		{"error_count > threshold in time_window", "error_count > threshold", []string{"error_count"}},
+		{"error_count", "error_count > threshold", []string{"error_count"}},
+		{"ERROR_RATE >= 2.5", "error_rate >= 2.5", []string{"error_rate"}},
+		{"no_events", "event_count = 0", []string{"event_count"}},
+		{"metric:Checkout.Failed>10", "metric:Checkout.Failed > 10", []string{"metric:Checkout.Failed"}},
+		{"error_rate > 5 AND (critical_count >= 10 OR NOT unresolved_count < 3)",
+			"(error_rate > 5 AND (critical_count >= 10 OR NOT unresolved_count < 3))",
+			[]string{"error_rate", "critical_count", "unresolved_count"}},
+		{"error_count > 1 || error_count <= 0 && error_rate == 1",
+			"(error_count > 1 OR (error_count <= 0 AND error_rate == 1))", []string{"error_count", "error_rate"}},
+		{"!(error_count > 1)", "NOT error_count > 1", []string{"error_count"}},
+	}
+
+	for _, tt := range tests {
+		node, err := parseAlertCondition(tt.condition)
+		if err != nil {
+			t.Errorf("parseAlertCondition(%q): %v", tt.condition, err)
+			continue
+		}
+		if got := node.String(); got != tt.want {
+			t.Errorf("parseAlertCondition(%q) = %s, want %s", tt.condition, got, tt.want)
+		}
+		if got := node.metrics(); !reflect.DeepEqual(got, tt.wantMetrics) {
+			t.Errorf("parseAlertCondition(%q).metrics() = %v, want %v", tt.condition, got, tt.wantMetrics)
+		}
+	}
+}
+
+func TestParseAlertConditionRejectsInvalidConditions(t *testing.T) {
+	for _, condition := range []string{
+		"",
+		"   ",
+		"unknown_metric > 5",
+		"metric: > 5",
+		"error_count > lots",
+		"error_count > 5 AND",
+		"(error_count > 5",
+		"error_count > 5)",
+		"error_count > 5 error_rate > 1",
+	} {
+		if _, err := parseAlertCondition(condition); err == nil {
+			t.Errorf("parseAlertCondition(%q) accepted an invalid condition", condition)
+		}
+	}
+}
+
+func TestValidateAlertCondition(t *testing.T) {
+	if err := validateAlertCondition(" New_Error "); err != nil {
+		t.Errorf("validateAlertCondition(new_error): %v", err)
+	}
+	if err := validateAlertCondition("new_error > 1"); err == nil {
+		t.Error("validateAlertCondition accepted new_error as a metric")
+	}
+}
+
+func TestAlertConditionEval(t *testing.T) {
+	tests := []struct {
+		condition string
+		values    map[string]float64
+		threshold float64
+		want      bool
+	}{
+		{"error_count > threshold", map[string]float64{"error_count": 11}, 10, true},
+		{"error_count > threshold", map[string]float64{"error_count": 10}, 10, false},
+		{"error_count >= threshold", map[string]float64{"error_count": 10}, 10, true},
+		{"error_rate < 1", map[string]float64{"error_rate": 0.5}, 0, true},
+		{"no_events", map[string]float64{}, 10, true},
+		{"no_events", map[string]float64{"event_count": 1}, 10, false},
+		{"error_rate > 5 AND critical_count >= 10", map[string]float64{"error_rate": 6, "critical_count": 9}, 0, false},
+		{"error_rate > 5 OR critical_count >= 10", map[string]float64{"error_rate": 6, "critical_count": 9}, 0, true},
+		{"NOT unresolved_count < 3", map[string]float64{"unresolved_count": 3}, 0, true},
+	}
+
+	for _, tt := range tests {
+		node, err := parseAlertCondition(tt.condition)
+		if err != nil {
+			t.Fatalf("parseAlertCondition(%q): %v", tt.condition, err)
+		}
+		if got := node.eval(tt.values, tt.threshold); got != tt.want {
+			t.Errorf("%q with %v and threshold %v = %v, want %v", tt.condition, tt.values, tt.threshold, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// alertEvaluationInterval is how often enabled alert rules are checked
+const alertEvaluationInterval = time.Minute
+
+// maxRelatedErrors is how many error groups are attached to an incident
+// opened by an alert rule
+const maxRelatedErrors = 10
+
+// ErrInvalidAlertCondition is returned for conditions the evaluator cannot run
+var ErrInvalidAlertCondition = errors.New("invalid alert condition")
+
+// parseTimeWindow accepts Go durations plus a "d" suffix for days, defaulting to 5m
+func parseTimeWindow(window string) time.Duration {
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
+	}
+	if d, err := time.ParseDuration(window); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// StartAlertEvaluator periodically evaluates enabled alert rules and threshold
+// subscriptions until ctx is done
+func (s *AlertsService) StartAlertEvaluator(ctx context.Context) {
+	log.Println("Starting alert evaluator...")
+
+	ticker := time.NewTicker(alertEvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Alert evaluator stopped")
+			return
+		case <-ticker.C:
+			s.evaluateAlertRules(ctx)
+			s.evaluateThresholdSubscriptions(ctx)
+		}
+	}
+}
+
+func (s *AlertsService) evaluateAlertRules(ctx context.Context) {
+	rules, err := s.db.GetAlertRules()
+	if err != nil {
+		log.Printf("Failed to load alert rules: %v", err)
+		return
+	}
+
+	for i := range rules {
+		// New error rules are notified on ingestion by NotifyFirstSeen
+		if !rules[i].Enabled || isNewErrorCondition(rules[i].Condition) {
+			continue
+		}
+		if err := s.evaluateAlertRule(ctx, &rules[i]); err != nil {
+			log.Printf("Failed to evaluate alert rule %s: %v", rules[i].ID, err)
+		}
+	}
+}
+
+func (s *AlertsService) evaluateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	condition, err := parseAlertCondition(rule.Condition)
+	if err != nil {
+		return err
+	}
+
+	window := parseTimeWindow(rule.TimeWindow)
+
+	// All metrics come from a single query so composite conditions see one snapshot
+	values, err := s.db.EvaluateAlertMetrics(condition.metrics(), rule.Scope, window)
+	if err != nil {
+		return err
+	}
+
+	// While firing, "threshold" means the recovery threshold so the rule only
+	// clears once the metric has dropped well below the trigger point
+	threshold := float64(rule.Threshold)
+	if rule.State == "firing" && rule.RecoveryThreshold != nil {
+		threshold = float64(*rule.RecoveryThreshold)
+	}
+
+	now := time.Now().UTC()
+	summary := fmt.Sprintf("%s matched in the last %s (values: %s)", condition, window, formatMetricValues(values))
+
+	if !condition.eval(values, threshold) {
+		if rule.State == "firing" {
+			log.Printf("ALERT CLEARED: rule %s (%s), values: %v", rule.ID, rule.Name, values)
+			s.resolveAlert(rule, now)
+		}
+		rule.State = "ok"
+		rule.ConsecutiveBreaches = 0
+		return s.db.UpdateAlertRuleState(rule)
+	}
+
+	rule.ConsecutiveBreaches++
+	if rule.State == "firing" {
+		recordPeakValues(rule, values)
+	}
+
+	switch {
+	case rule.State == "firing":
+		// Already notified; only repeat once the re-notify interval has passed
+		if interval := parseRenotifyInterval(rule.RenotifyInterval); interval > 0 &&
+			(rule.LastNotified == nil || now.Sub(*rule.LastNotified) >= interval) {
+			log.Printf("ALERT STILL FIRING: rule %s (%s), values: %v", rule.ID, rule.Name, values)
+			rule.LastNotified = &now
+			s.notifyAlert(rule, "[Alert] Still firing: "+rule.Name, summary, nil, now)
+		}
+	case rule.ConsecutiveBreaches >= max(rule.ConsecutiveEvaluations, 1):
+		log.Printf("ALERT TRIGGERED: rule %s (%s) - %s, values: %v", rule.ID, rule.Name, condition, values)
+		rule.State = "firing"
+		rule.LastTriggered = &now
+		rule.LastNotified = &now
+		rule.PeakValues = nil
+		recordPeakValues(rule, values)
+		s.triggerAlert(rule, summary, now)
+	default:
+		rule.State = "pending"
+	}
+
+	return s.db.UpdateAlertRuleState(rule)
+}
+
+// recordPeakValues keeps the highest value seen for each metric while the rule fires
+func recordPeakValues(rule *models.AlertRule, values map[string]float64) {
+	if rule.PeakValues == nil {
+		rule.PeakValues = make(map[string]float64, len(values))
+	}
+	for metric, value := range values {
+		if peak, ok := rule.PeakValues[metric]; !ok || value > peak {
+			rule.PeakValues[metric] = value
+		}
+	}
+}
+
+// parseRenotifyInterval returns zero (never re-notify) for an empty or invalid interval
+func parseRenotifyInterval(interval string) time.Duration {
+	if interval == "" {
+		return 0
+	}
+	return parseTimeWindow(interval)
+}
+
+func formatMetricValues(values map[string]float64) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, values[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *AlertsService) triggerAlert(rule *models.AlertRule, summary string, now time.Time) {
+	incident := &models.Incident{
+		ID:          uuid.New(),
+		Title:       "Alert: " + rule.Name,
+		Severity:    rule.Severity,
+		Status:      "open",
+		Description: summary,
+		OrgID:       s.ruleOrgID(rule),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.db.CreateIncident(incident); err != nil {
+		log.Printf("Failed to create incident for alert rule %s: %v", rule.ID, err)
+	} else {
+		s.annotate.AnnotateIncident(incident, rule.Scope)
+		s.attachRelatedErrors(rule, incident, now)
+	}
+
+	rule.IncidentID = &incident.ID
+	s.notifyAlert(rule, "[Alert] "+rule.Name, summary, &incident.ID, now)
+}
+
+// ruleOrgID is the organization of the project a rule watches, which the
+// incidents it opens belong to. Rules on every project open deployment-wide
+// incidents.
+func (s *AlertsService) ruleOrgID(rule *models.AlertRule) *uuid.UUID {
+	if rule.Scope.ProjectID == nil {
+		return nil
+	}
+	project, err := s.db.GetProject(*rule.Scope.ProjectID)
+	if err != nil {
+		log.Printf("Failed to get the project of alert rule %s: %v", rule.ID, err)
+		return nil
+	}
+	return project.OrgID
+}
+
+// attachRelatedErrors attaches the busiest error groups of the rule's
+// source during its window to the incident it opened. The rule's fingerprint
+// and level are left out of the scope, so a rule on one group or on errors
+// only also points at the warnings and other groups that came with it.
+func (s *AlertsService) attachRelatedErrors(rule *models.AlertRule, incident *models.Incident, now time.Time) {
+	scope := rule.Scope
+	scope.Fingerprint = ""
+	scope.Level = ""
+
+	since := now.Add(-parseTimeWindow(rule.TimeWindow))
+	attached, err := s.db.AttachRelatedErrors(incident.ID, scope, since, now, maxRelatedErrors)
+	if err != nil {
+		log.Printf("Failed to attach related errors to incident %s: %v", incident.ID, err)
+		return
+	}
+	if attached > 0 {
+		log.Printf("ALERT CORRELATED: %d error groups attached to incident %s", attached, incident.ID)
+	}
+}
+
+// resolveAlert closes the incident opened when the rule fired and sends a
+// resolved notification to the same targets
+func (s *AlertsService) resolveAlert(rule *models.AlertRule, now time.Time) {
+	var duration time.Duration
+	if rule.LastTriggered != nil {
+		duration = now.Sub(*rule.LastTriggered).Round(time.Second)
+	}
+
+	if rule.IncidentID != nil {
+		incident, err := s.db.GetIncidentByID(*rule.IncidentID)
+		if err != nil {
+			log.Printf("Failed to load incident %s for alert rule %s: %v", *rule.IncidentID, rule.ID, err)
+		} else if incident.Status != "resolved" && incident.Status != "closed" {
+			incident.Status = "resolved"
+			incident.UpdatedAt = now
+			if err := s.db.UpdateIncident(incident); err != nil {
+				log.Printf("Failed to resolve incident %s for alert rule %s: %v", incident.ID, rule.ID, err)
+			}
+		}
+	}
+
+	peak := formatMetricValues(rule.PeakValues)
+	subject := "[Resolved] " + rule.Name
+	runbook := s.runbookFor(rule)
+	body := fmt.Sprintf("Alert rule %q has recovered after %s.\n\nPeak values: %s\n", rule.Name, duration, peak) + formatRunbook(runbook)
+	s.notify(rule, subject, body, map[string]interface{}{
+		"rule_id":      rule.ID,
+		"rule_name":    rule.Name,
+		"status":       "resolved",
+		"triggered_at": rule.LastTriggered,
+		"resolved_at":  now,
+		"duration":     duration.String(),
+		"peak_values":  rule.PeakValues,
+		"incident_id":  rule.IncidentID,
+		"runbook":      runbook,
+	})
+
+	rule.IncidentID = nil
+	rule.PeakValues = nil
+}
+
+func (s *AlertsService) notifyAlert(rule *models.AlertRule, subject, summary string, incidentID *uuid.UUID, now time.Time) {
+	runbook := s.runbookFor(rule)
+	body := fmt.Sprintf("Alert rule %q is firing.\n\n%s\n", rule.Name, summary) + formatRunbook(runbook)
+	s.notify(rule, subject, body, map[string]interface{}{
+		"rule_id":      rule.ID,
+		"rule_name":    rule.Name,
+		"status":       "firing",
+		"summary":      summary,
+		"triggered_at": rule.LastTriggered,
+		"notified_at":  now,
+		"incident_id":  incidentID,
+		"runbook":      runbook,
+	})
+}
+
+// runbookFor falls back to the runbook of the error group or service the rule
+// is scoped to when the rule has none of its own
+func (s *AlertsService) runbookFor(rule *models.AlertRule) *models.Runbook {
+	return resolveRunbook(s.db, rule.Runbook, rule.Scope.Fingerprint, rule.Scope.Source)
+}
+
+// notify delivers a rule's notification to each of its targets, except
+// those in a notification storm, where it is held for a digest. Targets in
+// their quiet hours are routed by their schedule.
+func (s *AlertsService) notify(rule *models.AlertRule, subject, body string, payload map[string]interface{}) {
+	targets := make([]string, 0, len(rule.Notifications))
+	for _, target := range rule.Notifications {
+		if !s.batcher.Hold(rule.ID, target, subject) {
+			targets = append(targets, target)
+		}
+	}
+	s.deliver(rule, s.route(targets, rule.Severity, time.Now()), subject, body, payload)
+}
+
+// deliver sends a rule's notification to each target: "email" (owners and
+// admins), "email:<address>", "team:<id>", and "webhook:<url>". Team members
+// who are emailed also get it in their inbox.
+func (s *AlertsService) deliver(rule *models.AlertRule, targets []string, subject, body string, payload map[string]interface{}) {
+	emails := []string{}
+	memberIDs := []uuid.UUID{}
+	addresses := []string{}
+
+	for _, target := range targets {
+		kind, value, _ := strings.Cut(target, ":")
+		switch kind {
+		case "email":
+			if value != "" {
+				emails = append(emails, value)
+				addresses = append(addresses, value)
+				continue
+			}
+			members, err := s.db.GetTeamMembers()
+			if err != nil {
+				log.Printf("Failed to load team members for notification: %v", err)
+				continue
+			}
+			for _, member := range members {
+				if member.Role == "owner" || member.Role == "admin" {
+					emails = append(emails, member.Email)
+					memberIDs = append(memberIDs, member.ID)
+				}
+			}
+		case "team":
+			teamID, err := uuid.Parse(value)
+			if err != nil {
+				continue
+			}
+			members, err := s.db.GetTeamMembersByTeam(teamID)
+			if err != nil {
+				log.Printf("Failed to load team %s for notification: %v", teamID, err)
+				continue
+			}
+			for _, member := range members {
+				emails = append(emails, member.Email)
+				memberIDs = append(memberIDs, member.ID)
+			}
+		case "webhook":
+			url := value
+			go func() {
+				if err := s.notifier.SendWebhook(url, payload); err != nil {
+					log.Printf("Failed to send webhook notification: %v", err)
+				}
+			}()
+		default:
+			log.Printf("NOTIFICATION SKIPPED: channel %q is not configured", target)
+		}
+	}
+
+	if len(emails) > 0 {
+		go func() {
+			if err := s.notifier.SendEmail(dedupeStrings(emails), subject, body); err != nil {
+				log.Printf("Failed to send email notification: %v", err)
+			}
+		}()
+	}
+
+	// Addresses of team members reach their inbox too
+	if len(addresses) > 0 {
+		members, err := s.db.GetTeamMembers()
+		if err != nil {
+			log.Printf("Failed to load team members for notification: %v", err)
+		}
+		for _, member := range members {
+			for _, address := range addresses {
+				if strings.EqualFold(member.Email, address) {
+					memberIDs = append(memberIDs, member.ID)
+				}
+			}
+		}
+	}
+	ruleID := rule.ID
+	s.inbox.Record(memberIDs, models.Notification{
+		Type:    "alert",
+		Subject: subject,
+		Body:    body,
+		RuleID:  &ruleID,
+	})
+}
+
+func dedupeStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidAlertRuleDocument is returned for a rule document that can't be
+// applied
+var ErrInvalidAlertRuleDocument = errors.New("invalid alert rule document")
+
+// maxAlertRulesPerDocument bounds the rules one document may declare
+const maxAlertRulesPerDocument = 500
+
+// ApplyAlertRules makes the stored alert rules match doc: rules it names are
+// created or updated, and stored rules it doesn't name are deleted. Applying
+// the same document again changes nothing. Updated rules keep their ID and
+// evaluator state. With dryRun nothing is written and the result shows what
+// would change.
+func (s *AlertsService) ApplyAlertRules(ctx context.Context, doc *models.AlertRuleDocument, dryRun bool) (*models.AlertRulesApplyResult, error) {
+	if len(doc.Rules) > maxAlertRulesPerDocument {
+		return nil, fmt.Errorf("%w: at most %d rules may be declared", ErrInvalidAlertRuleDocument, maxAlertRulesPerDocument)
+	}
+
+	stored, err := s.db.GetAlertRules()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.AlertRule, len(stored))
+	for i := range stored {
+		if _, ok := byName[stored[i].Name]; ok {
+			return nil, fmt.Errorf("%w: more than one stored rule is named %q; rename or delete them first", ErrInvalidAlertRuleDocument, stored[i].Name)
+		}
+		byName[stored[i].Name] = &stored[i]
+	}
+
+	result := &models.AlertRulesApplyResult{
+		DryRun:    dryRun,
+		Created:   []string{},
+		Updated:   []models.AlertRuleChange{},
+		Deleted:   []string{},
+		Unchanged: []string{},
+	}
+	var created, updated []models.AlertRule
+	declared := make(map[string]bool, len(doc.Rules))
+	now := time.Now().UTC()
+
+	for i := range doc.Rules {
+		req := &doc.Rules[i]
+		if req.Name == "" {
+			return nil, fmt.Errorf("%w: rules[%d] has no name", ErrInvalidAlertRuleDocument, i)
+		}
+		if declared[req.Name] {
+			return nil, fmt.Errorf("%w: rule %q is declared more than once", ErrInvalidAlertRuleDocument, req.Name)
+		}
+		declared[req.Name] = true
+		if req.Condition == "" {
+			return nil, fmt.Errorf("%w: rule %q has no condition", ErrInvalidAlertRuleDocument, req.Name)
+		}
+
+		existing, ok := byName[req.Name]
+		if !ok {
+			rule := models.AlertRule{ID: uuid.New(), State: "ok", Version: 1, CreatedAt: now, UpdatedAt: now}
+			if err := applyAlertRuleRequest(&rule, req); err != nil {
+				return nil, fmt.Errorf("%w: rule %q: %v", ErrInvalidAlertRuleDocument, req.Name, err)
+			}
+			created = append(created, rule)
+			result.Created = append(result.Created, req.Name)
+			continue
+		}
+
+		rule := *existing
+		if err := applyAlertRuleRequest(&rule, req); err != nil {
+			return nil, fmt.Errorf("%w: rule %q: %v", ErrInvalidAlertRuleDocument, req.Name, err)
+		}
+		fields := alertRuleChangedFields(existing, &rule)
+		if len(fields) == 0 {
+			result.Unchanged = append(result.Unchanged, req.Name)
+			continue
+		}
+		rule.UpdatedAt = now
+		updated = append(updated, rule)
+		result.Updated = append(result.Updated, models.AlertRuleChange{Name: req.Name, Fields: fields})
+	}
+
+	var deleted []uuid.UUID
+	for _, rule := range stored {
+		if !declared[rule.Name] {
+			deleted = append(deleted, rule.ID)
+			result.Deleted = append(result.Deleted, rule.Name)
+		}
+	}
+
+	if dryRun || len(created)+len(updated)+len(deleted) == 0 {
+		return result, nil
+	}
+	if err := s.db.ApplyAlertRules(created, updated, deleted); err != nil {
+		return nil, externalIDConflict(err, "alert rule", nil)
+	}
+	return result, nil
+}
+
+// alertRuleChangedFields names the configured fields that differ between two
+// versions of a rule, as they are named in the API
+func alertRuleChangedFields(old, new *models.AlertRule) []string {
+	fields := []struct {
+		name string
+		same bool
+	}{
+		{"condition", old.Condition == new.Condition},
+		{"threshold", old.Threshold == new.Threshold},
+		{"time_window", old.TimeWindow == new.TimeWindow},
+		{"enabled", old.Enabled == new.Enabled},
+		{"notifications", slices.Equal(old.Notifications, new.Notifications)},
+		{"scope", reflect.DeepEqual(old.Scope, new.Scope)},
+		{"severity", old.Severity == new.Severity},
+		{"renotify_interval", old.RenotifyInterval == new.RenotifyInterval},
+		{"consecutive_evaluations", old.ConsecutiveEvaluations == new.ConsecutiveEvaluations},
+		{"recovery_threshold", reflect.DeepEqual(old.RecoveryThreshold, new.RecoveryThreshold)},
+		{"runbook", reflect.DeepEqual(old.Runbook, new.Runbook)},
+		{"external_id", reflect.DeepEqual(old.ExternalID, new.ExternalID)},
+	}
+
+	changed := []string{}
+	for _, field := range fields {
+		if !field.same {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+const (
+	// alertTemplateHistory is how far back thresholds are suggested from
+	alertTemplateHistory = 7 * 24 * time.Hour
+	// alertTemplateBucket is the interval errors are counted in for suggestions
+	alertTemplateBucket = 5 * time.Minute
+	// sloFastBurnRate spends 2% of a 30-day error budget in one hour
+	sloFastBurnRate = 14.4
+)
+
+// alertTemplates are the recommended rules, with the values used when the
+// scope has no recent errors to suggest from
+var alertTemplates = []models.AlertTemplate{
+	{
+		ID:                     "error-spike",
+		Name:                   "Error spike",
+		Description:            "Fires when the errors in 5 minutes reach twice the busiest 5 minutes of the past week.",
+		Condition:              "error_count > threshold",
+		Threshold:              50,
+		TimeWindow:             "5m",
+		Severity:               "high",
+		ConsecutiveEvaluations: 2,
+	},
+	{
+		ID:                     "new-error-in-production",
+		Name:                   "New error in production",
+		Description:            "Notifies as soon as an error group is seen for the first time in production.",
+		Condition:              NewErrorCondition,
+		Scope:                  models.AlertScope{Environment: "production"},
+		Severity:               "medium",
+		ConsecutiveEvaluations: 1,
+	},
+	{
+		ID:                     "no-events",
+		Name:                   "No events received",
+		Description:            "Fires when nothing arrives for twice the longest quiet stretch of the past week, which usually means the SDK or the service is down. Scope it to a project or source.",
+		Condition:              "no_events",
+		TimeWindow:             "15m",
+		Severity:               "high",
+		ConsecutiveEvaluations: 1,
+	},
+	{
+		ID:                     "slo-burn",
+		Name:                   "Error budget burn",
+		Description:            "Fires when the hourly error rate runs at 14.4 times the past week's average, which spends 2% of a 30-day error budget in an hour.",
+		Condition:              "error_rate > threshold",
+		Threshold:              10,
+		TimeWindow:             "1h",
+		Severity:               "critical",
+		ConsecutiveEvaluations: 1,
+		RenotifyInterval:       "1h",
+	},
+}
+
+// GetAlertTemplates returns the recommended rules with thresholds suggested
+// for the scope
+func (s *AlertsService) GetAlertTemplates(ctx context.Context, scope models.AlertScope) ([]models.AlertTemplate, error) {
+	counts, err := s.db.GetErrorCountBuckets(scope, alertTemplateBucket, time.Now().UTC().Add(-alertTemplateHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]models.AlertTemplate, len(alertTemplates))
+	for i, template := range alertTemplates {
+		template.Scope = mergeAlertScope(template.Scope, scope)
+		suggestThresholds(&template, counts)
+		templates[i] = template
+	}
+	return templates, nil
+}
+
+// CreateAlertRuleFromTemplate creates a rule from a template, with thresholds
+// suggested from the history of the rule's scope unless the request sets them.
+// Callers scoped to an organization must scope the rule to one of its projects.
+func (s *AlertsService) CreateAlertRuleFromTemplate(ctx context.Context, id string, req *models.CreateRuleFromTemplateRequest, callerOrg *uuid.UUID) (*models.AlertRule, error) {
+	i := slices.IndexFunc(alertTemplates, func(t models.AlertTemplate) bool { return t.ID == id })
+	if i < 0 {
+		return nil, fmt.Errorf("alert template not found")
+	}
+	template := alertTemplates[i]
+	template.Scope = mergeAlertScope(template.Scope, req.Scope)
+	if err := s.checkAlertScope(template.Scope, callerOrg); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.db.GetErrorCountBuckets(template.Scope, alertTemplateBucket, time.Now().UTC().Add(-alertTemplateHistory))
+	if err != nil {
+		log.Printf("Failed to load error history for alert template %s, using defaults: %v", id, err)
+	}
+	suggestThresholds(&template, counts)
+
+	rule := models.CreateAlertRuleRequest{
+		Name:                   req.Name,
+		Condition:              template.Condition,
+		Threshold:              template.Threshold,
+		TimeWindow:             template.TimeWindow,
+		Notifications:          req.Notifications,
+		Scope:                  template.Scope,
+		Enabled:                req.Enabled == nil || *req.Enabled,
+		Severity:               template.Severity,
+		RenotifyInterval:       template.RenotifyInterval,
+		ConsecutiveEvaluations: template.ConsecutiveEvaluations,
+		Runbook:                req.Runbook,
+	}
+	if rule.Name == "" {
+		rule.Name = template.Name
+		if template.Scope.Source != "" {
+			rule.Name += " in " + template.Scope.Source
+		}
+	}
+	if req.Threshold != nil {
+		rule.Threshold = *req.Threshold
+	}
+	if req.TimeWindow != "" {
+		rule.TimeWindow = req.TimeWindow
+	}
+	if req.Severity != "" {
+		rule.Severity = req.Severity
+	}
+	if rule.Notifications == nil {
+		rule.Notifications = []string{}
+	}
+
+	return s.CreateAlertRule(ctx, &rule, callerOrg)
+}
+
+// mergeAlertScope adds the set fields of scope to base
+func mergeAlertScope(base, scope models.AlertScope) models.AlertScope {
+	if scope.ProjectID != nil {
+		base.ProjectID = scope.ProjectID
+	}
+	if scope.Source != "" {
+		base.Source = scope.Source
+	}
+	if scope.Environment != "" {
+		base.Environment = scope.Environment
+	}
+	if scope.Fingerprint != "" {
+		base.Fingerprint = scope.Fingerprint
+	}
+	if scope.Level != "" {
+		base.Level = scope.Level
+	}
+	return base
+}
+
+// suggestThresholds replaces a template's defaults with values derived from
+// the error counts per alertTemplateBucket. Without any errors in the history
+// the defaults stay.
+func suggestThresholds(template *models.AlertTemplate, counts []int) {
+	template.Basis = "default"
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return
+	}
+
+	switch template.ID {
+	case "error-spike":
+		sorted := slices.Clone(counts)
+		slices.Sort(sorted)
+		p99 := sorted[int(float64(len(sorted)-1)*0.99)]
+		template.Threshold = max(10, 2*p99)
+	case "no-events":
+		longest, run := 0, 0
+		for _, count := range counts {
+			if count == 0 {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+		window := time.Duration(2*(longest+1)) * alertTemplateBucket
+		template.TimeWindow = formatAlertWindow(min(max(window, 15*time.Minute), 24*time.Hour))
+	case "slo-burn":
+		perMinute := float64(total) / (float64(len(counts)) * alertTemplateBucket.Minutes())
+		template.Threshold = max(1, int(math.Ceil(sloFastBurnRate*perMinute)))
+	default:
+		return
+	}
+	template.Basis = "history"
+}
+
+// formatAlertWindow writes a window the way parseTimeWindow reads it
+func formatAlertWindow(window time.Duration) string {
+	if window%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(window.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(window.Minutes()))
+}
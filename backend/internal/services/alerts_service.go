@@ -2,59 +2,174 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
-	"error-logs/internal/database"
 	"error-logs/internal/models"
-	"error-logs/internal/redis"
 )
 
+// ErrInvalidAlertSeverity is returned for an alert rule severity that is not
+// one of AlertSeverities
+var ErrInvalidAlertSeverity = errors.New("invalid alert severity")
+
+// ErrInvalidAlertScope is returned for an alert rule of an
+// organization-scoped caller that is not scoped to one of its projects
+var ErrInvalidAlertScope = errors.New("invalid alert scope")
+
+// AlertSeverities are the severities of alert rules and incidents, least
+// severe first
+var AlertSeverities = []string{"low", "medium", "high", "critical"}
+
 type AlertsService struct {
-	db    *database.DB
-	redis *redis.Client
+	db       AlertStore
+	notifier *Notifier
+	batcher  *NotificationBatcher
+	inbox    *NotificationService
+	annotate *AnnotationService
+
+	schedulesMu       sync.RWMutex
+	schedules         []models.NotificationSchedule
+	schedulesLoadedAt time.Time
 }
 
-func NewAlertsService(db *database.DB, redis *redis.Client) *AlertsService {
+func NewAlertsService(db AlertStore, notifier *Notifier, batcher *NotificationBatcher, inbox *NotificationService, annotate *AnnotationService) *AlertsService {
 	return &AlertsService{
-		db:    db,
-		redis: redis,
+		db:       db,
+		notifier: notifier,
+		batcher:  batcher,
+		inbox:    inbox,
+		annotate: annotate,
 	}
 }
 
-func (s *AlertsService) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
-	return s.db.GetAlertRules()
+// GetAlertRules returns a page of the rules matching filter and how many match
+func (s *AlertsService) GetAlertRules(ctx context.Context, limit, offset int, filter models.AlertRuleFilter) ([]models.AlertRule, int, error) {
+	return s.db.ListAlertRules(limit, offset, filter)
 }
 
-func (s *AlertsService) CreateAlertRule(ctx context.Context, req *models.CreateAlertRuleRequest) (*models.AlertRule, error) {
+// scopeInOrg reports whether a caller scoped to callerOrg may see and change
+// rules with the scope: only those of one of its organization's projects.
+// Rules without a project watch every project.
+func (s *AlertsService) scopeInOrg(scope models.AlertScope, callerOrg *uuid.UUID) (bool, error) {
+	if callerOrg == nil {
+		return true, nil
+	}
+	if scope.ProjectID == nil {
+		return false, nil
+	}
+	project, err := s.db.GetProject(*scope.ProjectID)
+	if err != nil {
+		if err.Error() == "project not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return projectVisible(project, callerOrg), nil
+}
+
+// checkAlertScope refuses a rule scope outside callerOrg
+func (s *AlertsService) checkAlertScope(scope models.AlertScope, callerOrg *uuid.UUID) error {
+	ok, err := s.scopeInOrg(scope, callerOrg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: organization API keys must scope rules to a project of their organization", ErrInvalidAlertScope)
+	}
+	return nil
+}
+
+// CreateAlertRule adds a rule. Callers scoped to an organization can only
+// add rules on its projects.
+func (s *AlertsService) CreateAlertRule(ctx context.Context, req *models.CreateAlertRuleRequest, callerOrg *uuid.UUID) (*models.AlertRule, error) {
+	if err := s.checkAlertScope(req.Scope, callerOrg); err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC()
 
 	rule := &models.AlertRule{
 		ID:            uuid.New(),
-		Name:          req.Name,
-		Condition:     req.Condition,
-		Threshold:     req.Threshold,
-		TimeWindow:    req.TimeWindow,
-		Enabled:       req.Enabled,
-		Notifications: req.Notifications,
 		LastTriggered: nil,
-		CreatedAt:     now,
-		UpdatedAt:     now,
+		State:         "ok",
+		Version:       1,
+
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := applyAlertRuleRequest(rule, req); err != nil {
+		return nil, err
 	}
 
 	if err := s.db.CreateAlertRule(rule); err != nil {
-		return nil, err
+		return nil, externalIDConflict(err, "alert rule", rule.ExternalID)
 	}
 
 	return rule, nil
 }
 
-func (s *AlertsService) UpdateAlertRule(ctx context.Context, id uuid.UUID, req *models.CreateAlertRuleRequest) (*models.AlertRule, error) {
+// GetAlertRule returns a rule, treating ones outside the caller's
+// organization as missing
+func (s *AlertsService) GetAlertRule(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID) (*models.AlertRule, error) {
 	rule, err := s.db.GetAlertRuleByID(id)
 	if err != nil {
 		return nil, err
 	}
+	ok, err := s.scopeInOrg(rule.Scope, callerOrg)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("alert rule not found")
+	}
+	return rule, nil
+}
+
+// UpdateAlertRule replaces a rule's configuration. With ifVersion set, the
+// rule is only updated if it is still at that version.
+func (s *AlertsService) UpdateAlertRule(ctx context.Context, id uuid.UUID, req *models.CreateAlertRuleRequest, callerOrg *uuid.UUID, ifVersion int) (*models.AlertRule, error) {
+	rule, err := s.GetAlertRule(ctx, id, callerOrg)
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != 0 && rule.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+	if err := s.checkAlertScope(req.Scope, callerOrg); err != nil {
+		return nil, err
+	}
+
+	if err := applyAlertRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateAlertRule(rule, ifVersion); err != nil {
+		return nil, externalIDConflict(err, "alert rule", rule.ExternalID)
+	}
+
+	return rule, nil
+}
+
+// applyAlertRuleRequest validates req and copies it onto rule, leaving the
+// rule's identity and evaluator state alone
+func applyAlertRuleRequest(rule *models.AlertRule, req *models.CreateAlertRuleRequest) error {
+	if err := validateAlertCondition(req.Condition); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAlertCondition, err)
+	}
+	runbook, err := normalizeRunbook(req.Runbook)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRunbook, err)
+	}
+	severity, err := normalizeAlertSeverity(req.Severity)
+	if err != nil {
+		return err
+	}
 
 	rule.Name = req.Name
 	rule.Condition = req.Condition
@@ -62,46 +177,95 @@ func (s *AlertsService) UpdateAlertRule(ctx context.Context, id uuid.UUID, req *
 	rule.TimeWindow = req.TimeWindow
 	rule.Enabled = req.Enabled
 	rule.Notifications = req.Notifications
-	rule.UpdatedAt = time.Now().UTC()
+	if rule.Notifications == nil {
+		rule.Notifications = []string{}
+	}
+	rule.Scope = req.Scope
+	rule.Severity = severity
+	rule.Runbook = runbook
+	rule.RenotifyInterval = req.RenotifyInterval
+	rule.ConsecutiveEvaluations = max(req.ConsecutiveEvaluations, 1)
+	rule.RecoveryThreshold = req.RecoveryThreshold
+	return applyExternalID(&rule.ExternalID, req.ExternalID)
+}
 
-	if err := s.db.UpdateAlertRule(rule); err != nil {
-		return nil, err
+// normalizeAlertSeverity defaults an empty severity to high
+func normalizeAlertSeverity(severity string) (string, error) {
+	if severity == "" {
+		return "high", nil
+	}
+	if !slices.Contains(AlertSeverities, severity) {
+		return "", fmt.Errorf("%w: severity must be one of %s", ErrInvalidAlertSeverity, strings.Join(AlertSeverities, ", "))
 	}
+	return severity, nil
+}
 
-	return rule, nil
+// DeleteAlertRule deletes a rule; with ifVersion set, only if it is still at
+// that version
+func (s *AlertsService) DeleteAlertRule(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID, ifVersion int) error {
+	if callerOrg != nil {
+		if _, err := s.GetAlertRule(ctx, id, callerOrg); err != nil {
+			return err
+		}
+	}
+	return s.db.DeleteAlertRule(id, ifVersion)
 }
 
-func (s *AlertsService) DeleteAlertRule(ctx context.Context, id uuid.UUID) error {
-	return s.db.DeleteAlertRule(id)
+// GetIncidents returns a page of the incidents matching filter and how many match
+func (s *AlertsService) GetIncidents(ctx context.Context, limit, offset int, filter models.IncidentFilter) ([]models.Incident, int, error) {
+	return s.db.GetIncidents(limit, offset, filter)
 }
 
-func (s *AlertsService) GetIncidents(ctx context.Context) ([]models.Incident, error) {
-	return s.db.GetIncidents()
+// getIncident returns an incident, treating ones outside the caller's
+// organization as missing
+func (s *AlertsService) getIncident(id uuid.UUID, callerOrg *uuid.UUID) (*models.Incident, error) {
+	incident, err := s.db.GetIncidentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if callerOrg != nil && (incident.OrgID == nil || *incident.OrgID != *callerOrg) {
+		return nil, fmt.Errorf("incident not found")
+	}
+	return incident, nil
 }
 
-func (s *AlertsService) CreateIncident(ctx context.Context, req *models.CreateIncidentRequest) (*models.Incident, error) {
+// CreateIncident opens an incident by hand. Incidents opened by
+// organization-scoped callers belong to their organization.
+func (s *AlertsService) CreateIncident(ctx context.Context, req *models.CreateIncidentRequest, callerOrg *uuid.UUID) (*models.Incident, error) {
 	now := time.Now().UTC()
 
 	incident := &models.Incident{
-		ID:          uuid.New(),
-		Title:       req.Title,
-		Severity:    req.Severity,
-		Status:      "open",
-		Description: req.Description,
-		AssignedTo:  req.AssignedTo,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:           uuid.New(),
+		Title:        req.Title,
+		Severity:     req.Severity,
+		Status:       "open",
+		Description:  req.Description,
+		AssignedTo:   req.AssignedTo,
+		AssignedTeam: req.AssignedTeam,
+		OrgID:        callerOrg,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 
 	if err := s.db.CreateIncident(incident); err != nil {
 		return nil, err
 	}
+	s.annotate.AnnotateIncident(incident, models.AlertScope{})
 
 	return incident, nil
 }
 
-func (s *AlertsService) UpdateIncident(ctx context.Context, id uuid.UUID, req *models.CreateIncidentRequest) (*models.Incident, error) {
-	incident, err := s.db.GetIncidentByID(id)
+// GetIncidentRelatedErrors returns the error groups attached to an incident
+// as possibly related when its alert rule fired
+func (s *AlertsService) GetIncidentRelatedErrors(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID) ([]models.IncidentRelatedError, error) {
+	if _, err := s.getIncident(id, callerOrg); err != nil {
+		return nil, err
+	}
+	return s.db.GetIncidentRelatedErrors(id)
+}
+
+func (s *AlertsService) UpdateIncident(ctx context.Context, id uuid.UUID, req *models.CreateIncidentRequest, callerOrg *uuid.UUID) (*models.Incident, error) {
+	incident, err := s.getIncident(id, callerOrg)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +274,7 @@ func (s *AlertsService) UpdateIncident(ctx context.Context, id uuid.UUID, req *m
 	incident.Severity = req.Severity
 	incident.Description = req.Description
 	incident.AssignedTo = req.AssignedTo
+	incident.AssignedTeam = req.AssignedTeam
 	incident.UpdatedAt = time.Now().UTC()
 
 	if err := s.db.UpdateIncident(incident); err != nil {
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"time"
@@ -11,6 +13,12 @@ import (
 	"error-logs/internal/redis"
 )
 
+// ErrInvalidHeatmap is returned for an unsupported heatmap period or timezone
+var ErrInvalidHeatmap = errors.New("invalid heatmap request")
+
+// trendsCacheTTL is how long computed trends are cached
+const trendsCacheTTL = 5 * time.Minute
+
 type AnalyticsService struct {
 	db    *database.DB
 	redis *redis.Client
@@ -23,18 +31,45 @@ func NewAnalyticsService(db *database.DB, redis *redis.Client) *AnalyticsService
 	}
 }
 
-func (s *AnalyticsService) GetTrends(ctx context.Context, period, groupBy string) (*models.TrendResponse, error) {
-	cacheKey := "trends_" + period + "_" + groupBy
+// CacheGeneration changes whenever errors are written, resolved or deleted
+func (s *AnalyticsService) CacheGeneration(ctx context.Context) (int64, error) {
+	return s.redis.GetCacheGeneration(ctx, redis.ErrorsCacheGeneration)
+}
+
+// TrendsCacheGeneration also changes when annotations do, which trend
+// responses carry. Both generations only grow, so their sum does too.
+func (s *AnalyticsService) TrendsCacheGeneration(ctx context.Context) (int64, error) {
+	errors, err := s.redis.GetCacheGeneration(ctx, redis.ErrorsCacheGeneration)
+	if err != nil {
+		return 0, err
+	}
+	annotations, err := s.redis.GetCacheGeneration(ctx, redis.AnnotationsCacheGeneration)
+	if err != nil {
+		return 0, err
+	}
+	return errors + annotations, nil
+}
+
+// GetTrends buckets errors over the period by groupBy, with day and month
+// buckets starting at midnight in timezone, an IANA name or "" for UTC
+func (s *AnalyticsService) GetTrends(ctx context.Context, period, groupBy string, filter models.StatsFilter, timezone string) (*models.TrendResponse, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := trendsCacheKey(period, groupBy, filter, loc)
+	recordCacheAccess(s.redis, cachedQuery{Kind: "trends", Filter: filter, Timezone: timezone, Period: period, GroupBy: groupBy})
 
 	// Try to get from cache first
 	if cachedTrends, err := s.redis.GetCachedTrends(ctx, cacheKey); err == nil && cachedTrends != nil {
 		log.Printf("CACHE HIT: GetTrends - key: %s", cacheKey)
-		return cachedTrends, nil
+		return s.annotateTrends(cachedTrends, period, filter)
 	}
 
 	log.Printf("CACHE MISS: GetTrends - key: %s, fetching from database", cacheKey)
 
-	trends, err := s.db.GetTrends(period, groupBy)
+	trends, err := s.db.WithContext(ctx).GetTrends(period, groupBy, filter, loc.String())
 	if err != nil {
 		return nil, err
 	}
@@ -42,14 +77,102 @@ func (s *AnalyticsService) GetTrends(ctx context.Context, period, groupBy string
 	// Cache the result
 	go func() {
 		cacheCtx := context.Background()
-		if err := s.redis.CacheTrends(cacheCtx, cacheKey, trends, 5*time.Minute); err != nil {
+		if err := s.redis.CacheTrends(cacheCtx, cacheKey, trends, trendsCacheTTL); err != nil {
 			log.Printf("Failed to cache trends: %v", err)
 		} else {
 			log.Printf("CACHE WRITE: GetTrends - key: %s", cacheKey)
 		}
 	}()
 
-	return trends, nil
+	return s.annotateTrends(trends, period, filter)
+}
+
+// RefreshTrends recomputes a trend and replaces the cached copy, whether or
+// not it has expired
+func (s *AnalyticsService) RefreshTrends(ctx context.Context, period, groupBy string, filter models.StatsFilter, timezone string) error {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return err
+	}
+	trends, err := s.db.GetTrends(period, groupBy, filter, loc.String())
+	if err != nil {
+		return err
+	}
+	return s.redis.CacheTrends(ctx, trendsCacheKey(period, groupBy, filter, loc), trends, trendsCacheTTL)
+}
+
+// trendsCacheKey is the cache key of a trend without its annotations
+func trendsCacheKey(period, groupBy string, filter models.StatsFilter, loc *time.Location) string {
+	key := "trends_" + period + "_" + groupBy
+	if scope := statsCacheKey(filter); scope != "" {
+		key += "_" + scope
+	}
+	if loc != time.UTC {
+		key += "_tz=" + loc.String()
+	}
+	return key
+}
+
+// annotateTrends returns a copy of trends with the period's annotations.
+// They are not cached with the trend, so new ones show up right away.
+func (s *AnalyticsService) annotateTrends(trends *models.TrendResponse, period string, filter models.StatsFilter) (*models.TrendResponse, error) {
+	annotations, err := s.db.GetTrendAnnotations(filter, time.Now().UTC().Add(-trendPeriodDuration(period)))
+	if err != nil {
+		return nil, err
+	}
+	annotated := *trends
+	annotated.Annotations = annotations
+	return &annotated, nil
+}
+
+// trendPeriodDuration matches the ranges of database.GetTrends
+func trendPeriodDuration(period string) time.Duration {
+	switch period {
+	case "day":
+		return 24 * time.Hour
+	case "month":
+		return 30 * 24 * time.Hour
+	case "year":
+		return 365 * 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
+
+// GetHeatmap counts errors by day of week and hour of day over the period
+func (s *AnalyticsService) GetHeatmap(ctx context.Context, filter models.StatsFilter, period, timezone string) (*models.HeatmapResponse, error) {
+	if !database.ValidHeatmapPeriod(period) {
+		return nil, fmt.Errorf("%w: period must be week, month, quarter or year", ErrInvalidHeatmap)
+	}
+	// Postgres and Go share the IANA database, so a zone Go knows is one Postgres accepts
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidHeatmap, timezone)
+	}
+
+	cacheKey := fmt.Sprintf("%s_%s_%s", period, timezone, statsCacheKey(filter))
+
+	if cachedHeatmap, err := s.redis.GetCachedHeatmap(ctx, cacheKey); err == nil && cachedHeatmap != nil {
+		log.Printf("CACHE HIT: GetHeatmap - key: %s", cacheKey)
+		return cachedHeatmap, nil
+	}
+
+	log.Printf("CACHE MISS: GetHeatmap - key: %s, fetching from database", cacheKey)
+
+	heatmap, err := s.db.WithContext(ctx).GetErrorHeatmap(filter, period, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		cacheCtx := context.Background()
+		if err := s.redis.CacheHeatmap(cacheCtx, cacheKey, heatmap, 15*time.Minute); err != nil {
+			log.Printf("Failed to cache heatmap: %v", err)
+		} else {
+			log.Printf("CACHE WRITE: GetHeatmap - key: %s", cacheKey)
+		}
+	}()
+
+	return heatmap, nil
 }
 
 func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context) (*models.PerformanceMetrics, error) {
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidAnnotation is returned when an annotation fails validation
+var ErrInvalidAnnotation = errors.New("invalid annotation")
+
+// AnnotationTypes are the accepted annotation types
+var AnnotationTypes = []string{"deploy", "incident", "config-change"}
+
+// maxAnnotationTextLength keeps chart markers readable
+const maxAnnotationTextLength = 500
+
+// AnnotationService records the events shown as markers on trend charts.
+// Deploys are reported by CI and incidents annotate themselves.
+type AnnotationService struct {
+	db    *database.DB
+	redis *redis.Client
+	audit *AuditService
+}
+
+func NewAnnotationService(db *database.DB, redis *redis.Client, audit *AuditService) *AnnotationService {
+	return &AnnotationService{
+		db:    db,
+		redis: redis,
+		audit: audit,
+	}
+}
+
+func (s *AnnotationService) GetAnnotations(ctx context.Context, limit, offset int, filter models.AnnotationFilter) ([]models.Annotation, int, error) {
+	if filter.Type != "" && !slices.Contains(AnnotationTypes, filter.Type) {
+		return nil, 0, fmt.Errorf("%w: type must be deploy, incident or config-change", ErrInvalidAnnotation)
+	}
+	return s.db.GetAnnotations(limit, offset, filter)
+}
+
+func (s *AnnotationService) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest, actor string) (*models.Annotation, error) {
+	if !slices.Contains(AnnotationTypes, req.Type) {
+		return nil, fmt.Errorf("%w: type must be deploy, incident or config-change", ErrInvalidAnnotation)
+	}
+
+	annotation := &models.Annotation{
+		ProjectID:   req.ProjectID,
+		Environment: nonEmpty(req.Environment),
+		Source:      nonEmpty(req.Source),
+		Type:        req.Type,
+		Text:        strings.TrimSpace(req.Text),
+		URL:         nonEmpty(req.URL),
+		CreatedBy:   actor,
+	}
+	if req.Timestamp != nil {
+		annotation.Timestamp = req.Timestamp.UTC()
+	}
+	if err := s.create(annotation); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record("annotation.created", actor, "annotation", annotation.ID.String(), map[string]interface{}{
+		"type": annotation.Type,
+		"text": annotation.Text,
+	})
+	return annotation, nil
+}
+
+// RecordDeploy annotates a deploy reported by CI
+func (s *AnnotationService) RecordDeploy(ctx context.Context, req *models.DeployRequest, actor string) (*models.Annotation, error) {
+	release := strings.TrimSpace(req.Release)
+	if release == "" {
+		return nil, fmt.Errorf("%w: release is required", ErrInvalidAnnotation)
+	}
+
+	text := "Deployed " + release
+	if req.Source != nil && *req.Source != "" {
+		text += " of " + *req.Source
+	}
+	if req.Environment != nil && *req.Environment != "" {
+		text += " to " + *req.Environment
+	}
+	if description := strings.TrimSpace(req.Description); description != "" {
+		text += ": " + description
+	}
+
+	annotation := &models.Annotation{
+		ProjectID:   req.ProjectID,
+		Environment: nonEmpty(req.Environment),
+		Source:      nonEmpty(req.Source),
+		Type:        "deploy",
+		Text:        truncate(text, maxAnnotationTextLength-3),
+		URL:         nonEmpty(req.URL),
+		CreatedBy:   actor,
+	}
+	if req.Timestamp != nil {
+		annotation.Timestamp = req.Timestamp.UTC()
+	}
+	if err := s.create(annotation); err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// AnnotateIncident marks when an incident was opened. Failures are logged;
+// the incident stands regardless.
+func (s *AnnotationService) AnnotateIncident(incident *models.Incident, scope models.AlertScope) {
+	annotation := &models.Annotation{
+		ProjectID:  scope.ProjectID,
+		Type:       "incident",
+		Text:       truncate(fmt.Sprintf("Incident opened (%s): %s", incident.Severity, incident.Title), maxAnnotationTextLength-3),
+		IncidentID: &incident.ID,
+		CreatedBy:  "system",
+		Timestamp:  incident.CreatedAt,
+	}
+	if scope.Environment != "" {
+		annotation.Environment = &scope.Environment
+	}
+	if scope.Source != "" {
+		annotation.Source = &scope.Source
+	}
+	if err := s.create(annotation); err != nil {
+		log.Printf("Failed to annotate incident %s: %v", incident.ID, err)
+	}
+}
+
+// create validates and stores an annotation, then invalidates trend ETags
+func (s *AnnotationService) create(annotation *models.Annotation) error {
+	if annotation.Text == "" {
+		return fmt.Errorf("%w: text is required", ErrInvalidAnnotation)
+	}
+	if len(annotation.Text) > maxAnnotationTextLength {
+		return fmt.Errorf("%w: text must be at most %d characters", ErrInvalidAnnotation, maxAnnotationTextLength)
+	}
+	if (annotation.Environment != nil && len(*annotation.Environment) > 50) || (annotation.Source != nil && len(*annotation.Source) > 50) {
+		return fmt.Errorf("%w: environment and source must be at most 50 characters", ErrInvalidAnnotation)
+	}
+	if annotation.URL != nil && !strings.HasPrefix(*annotation.URL, "http://") && !strings.HasPrefix(*annotation.URL, "https://") {
+		return fmt.Errorf("%w: url must be http(s)", ErrInvalidAnnotation)
+	}
+	if annotation.ProjectID != nil {
+		if _, err := s.db.GetProject(*annotation.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	annotation.ID = uuid.New()
+	annotation.CreatedAt = now
+	if annotation.Timestamp.IsZero() {
+		annotation.Timestamp = now
+	}
+
+	if err := s.db.CreateAnnotation(annotation); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *AnnotationService) DeleteAnnotation(ctx context.Context, id uuid.UUID, actor string) error {
+	if err := s.db.DeleteAnnotation(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	s.audit.Record("annotation.deleted", actor, "annotation", id.String(), nil)
+	return nil
+}
+
+func (s *AnnotationService) invalidate() {
+	if err := s.redis.IncrementCacheGeneration(context.Background(), redis.AnnotationsCacheGeneration); err != nil {
+		log.Printf("Failed to invalidate annotations: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// SystemActor is the actor of changes made by the server itself
+const SystemActor = "system"
+
+type AuditService struct {
+	db    *database.DB
+	redis *redis.Client
+}
+
+func NewAuditService(db *database.DB, redis *redis.Client) *AuditService {
+	return &AuditService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// Record appends an entry to the audit log. Failures are logged rather than
+// returned so auditing never fails the change it describes.
+func (s *AuditService) Record(action, actor, targetType, targetID string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	entry := &models.AuditLogEntry{
+		ID:         uuid.New(),
+		Action:     action,
+		Actor:      actor,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := s.db.CreateAuditLogEntry(entry); err != nil {
+		log.Printf("Failed to record audit log entry %s on %s %s: %v", action, targetType, targetID, err)
+	}
+}
+
+func (s *AuditService) GetAuditLog(ctx context.Context, filter models.AuditLogFilter, limit, offset int) (*models.AuditLogResponse, error) {
+	entries, total, err := s.db.GetAuditLog(filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditLogResponse{
+		Entries: entries,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
+
+// StreamAuditLog passes every matching entry to fn, newest first. Entries are
+// read a page at a time, so no more than one page is held in memory.
+func (s *AuditService) StreamAuditLog(ctx context.Context, filter models.AuditLogFilter, fn func(entry *models.AuditLogEntry) error) error {
+	var beforeCreatedAt time.Time
+	var beforeID *uuid.UUID
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := s.db.GetAuditLogBefore(filter, beforeCreatedAt, beforeID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := fn(&entries[i]); err != nil {
+				return err
+			}
+		}
+		if len(entries) < exportBatchSize {
+			return nil
+		}
+
+		last := entries[len(entries)-1]
+		beforeCreatedAt, beforeID = last.CreatedAt, &last.ID
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidAutoResolvePolicy is returned for policies that can't be saved
+var ErrInvalidAutoResolvePolicy = errors.New("invalid auto-resolve policy")
+
+const (
+	// autoResolveInterval is how often stale groups are looked for
+	autoResolveInterval = time.Hour
+	// maxAutoResolveDays is the longest quiet period a policy may wait for
+	maxAutoResolveDays = 365
+)
+
+func (s *ErrorService) GetAutoResolvePolicy(ctx context.Context, projectID uuid.UUID) (*models.AutoResolvePolicy, error) {
+	return s.db.GetAutoResolvePolicy(projectID)
+}
+
+func (s *ErrorService) SetAutoResolvePolicy(ctx context.Context, projectID uuid.UUID, req *models.UpdateAutoResolvePolicyRequest, actor string) (*models.AutoResolvePolicy, error) {
+	if req.Days < 1 || req.Days > maxAutoResolveDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidAutoResolvePolicy, maxAutoResolveDays)
+	}
+
+	policy := &models.AutoResolvePolicy{
+		ProjectID: projectID,
+		Days:      req.Days,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.db.UpsertAutoResolvePolicy(policy); err != nil {
+		return nil, err
+	}
+	s.audit.Record("auto_resolve.updated", actor, "project", projectID.String(), map[string]interface{}{"days": policy.Days})
+	return policy, nil
+}
+
+func (s *ErrorService) DeleteAutoResolvePolicy(ctx context.Context, projectID uuid.UUID, actor string) error {
+	if err := s.db.DeleteAutoResolvePolicy(projectID); err != nil {
+		return err
+	}
+	s.audit.Record("auto_resolve.deleted", actor, "project", projectID.String(), nil)
+	return nil
+}
+
+// StartAutoResolver resolves the error groups of projects with an
+// auto-resolve policy once they have gone quiet, until ctx is done
+func (s *ErrorService) StartAutoResolver(ctx context.Context) {
+	log.Println("Starting auto-resolver...")
+
+	ticker := time.NewTicker(autoResolveInterval)
+	defer ticker.Stop()
+
+	for {
+		s.autoResolve(time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			log.Println("Auto-resolver stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ErrorService) autoResolve(now time.Time) {
+	policies, err := s.db.GetAutoResolvePolicies()
+	if err != nil {
+		log.Printf("Failed to load auto-resolve policies: %v", err)
+		return
+	}
+
+	resolved := false
+	for _, policy := range policies {
+		note := fmt.Sprintf("Auto-resolved after %d days without events", policy.Days)
+		ids, fingerprints, err := s.db.AutoResolveStaleErrors(policy.ProjectID, now.AddDate(0, 0, -policy.Days), note)
+		if err != nil {
+			log.Printf("Failed to auto-resolve errors of project %s: %v", policy.ProjectID, err)
+			continue
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		resolved = true
+
+		log.Printf("AUTO-RESOLVED: %d errors in %d groups of project %s", len(ids), len(fingerprints), policy.ProjectID)
+		go func() {
+			for _, fingerprint := range fingerprints {
+				s.search.ResolveGroup(fingerprint)
+			}
+		}()
+		s.audit.Record("error.auto_resolved", SystemActor, "project", policy.ProjectID.String(), map[string]interface{}{
+			"days":   policy.Days,
+			"groups": len(fingerprints),
+			"errors": len(ids),
+		})
+	}
+
+	if resolved {
+		log.Println("CACHE INVALIDATION: auto-resolve - invalidating all caches")
+		if err := s.cache.InvalidateAllCache(context.Background()); err != nil {
+			log.Printf("Failed to invalidate caches: %v", err)
+		}
+	}
+}
+
+// applyAutoResolution reopens the event's group as a regression when it was
+// auto-resolved
+func (s *ErrorService) applyAutoResolution(error *models.Error) {
+	if error.Fingerprint == nil {
+		return
+	}
+
+	reopened, err := s.db.ReopenAutoResolved(*error.Fingerprint)
+	if err != nil {
+		log.Printf("Failed to check auto-resolution: %v", err)
+		return
+	}
+	if !reopened {
+		return
+	}
+
+	log.Printf("REGRESSION: fingerprint %s seen again after it was auto-resolved", *error.Fingerprint)
+	go s.search.ReopenGroup(*error.Fingerprint)
+	s.audit.Record("error.regressed", SystemActor, "error_group", *error.Fingerprint, map[string]interface{}{
+		"auto_resolved": true,
+	})
+	error.Regressed = true
+}
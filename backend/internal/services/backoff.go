@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// Backoff hints tell SDKs how to ease off while ingestion sheds load, so an
+// overload recovers instead of being prolonged by blind retries.
+
+// minQueueSampleRate is the least SDKs are asked to send while the queue fills
+const minQueueSampleRate = 0.1
+
+// ingestUnavailableRetry is how long SDKs wait when events can be neither
+// queued nor stored
+const ingestUnavailableRetry = 30 * time.Second
+
+// ThrottledBackoff is the hint for an event dropped by spike protection: send
+// the share spike protection keeps until the per-minute counters roll over
+func (s *ErrorService) ThrottledBackoff() models.Backoff {
+	return models.Backoff{
+		RetryAfter: secondsUntil(time.Now().UTC().Truncate(time.Minute).Add(time.Minute)),
+		SampleRate: sampleRate(s.throttle.SampleRate()),
+	}
+}
+
+// QueueBackoff is the hint while the queue is nearly full, or nil when it is
+// not. The suggested rate falls from 1 at the warning level to
+// minQueueSampleRate when the queue is full.
+func (s *ErrorService) QueueBackoff() *models.Backoff {
+	if !s.QueueNearlyFull() {
+		return nil
+	}
+	fill := float64(s.queueLength.Load()) / float64(s.maxQueueLength)
+	headroom := (1 - fill) / (1 - queueWarnPercent/100.0)
+	return &models.Backoff{SampleRate: sampleRate(max(min(headroom, 1), minQueueSampleRate))}
+}
+
+// UnavailableBackoff is the hint when an event could not be accepted at all
+func UnavailableBackoff() models.Backoff {
+	return models.Backoff{RetryAfter: int(ingestUnavailableRetry.Seconds())}
+}
+
+// QuotaBackoff is the hint for a project that hit its hard quota, or whose
+// organization did: stop sending until the next UTC day, or the next month
+// when a monthly limit is the one used up
+func (s *QuotaService) QuotaBackoff(ctx context.Context, projectID uuid.UUID) models.Backoff {
+	now := time.Now().UTC()
+	reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+
+	limits := s.projectQuotas(projectID)
+	if _, monthly, err := s.redis.GetUsageCounters(ctx, projectID, now); err == nil && exceeds(monthly, limits.project.MonthlyHardLimit) {
+		reset = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if _, monthly := s.orgUsage(ctx, limits.org, now); limits.org != nil && exceeds(monthly, limits.org.MonthlyHardLimit) {
+		reset = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return models.Backoff{RetryAfter: secondsUntil(reset), SampleRate: sampleRate(0)}
+}
+
+// RateLimitBackoff is the hint for a client over its rate limit: wait for the
+// window to end, then send no more than the limit allows at the current pace
+func RateLimitBackoff(status *RateLimitStatus) models.Backoff {
+	if status == nil {
+		return models.Backoff{RetryAfter: 60}
+	}
+	return models.Backoff{
+		RetryAfter: secondsUntil(status.Reset),
+		SampleRate: sampleRate(min(float64(status.Limit)/float64(max(status.Used, 1)), 1)),
+	}
+}
+
+func secondsUntil(t time.Time) int {
+	return max(int(math.Ceil(time.Until(t).Seconds())), 1)
+}
+
+func sampleRate(rate float64) *float64 {
+	rate = math.Round(rate*1000) / 1000
+	return &rate
+}
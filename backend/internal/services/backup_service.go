@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"error-logs/internal/database"
+	"error-logs/internal/encryption"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidBackup is returned for a backup that can't be created or
+// restored: a short or wrong passphrase, or a file that is not a backup
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// BackupService saves the deployment's configuration to an encrypted backup
+// and restores it, for disaster recovery and for cloning environments.
+// Backups are sealed with a passphrase rather than ENCRYPTION_KEYS, so they
+// can be restored into a deployment with other keys.
+type BackupService struct {
+	db    *database.DB
+	audit *AuditService
+}
+
+func NewBackupService(db *database.DB, audit *AuditService) *BackupService {
+	return &BackupService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// CreateBackup returns the configuration sealed with passphrase
+func (s *BackupService) CreateBackup(ctx context.Context, passphrase, actor string) ([]byte, error) {
+	if len(passphrase) < encryption.MinPassphraseLength {
+		return nil, fmt.Errorf("%w: passphrase must be at least %d characters", ErrInvalidBackup, encryption.MinPassphraseLength)
+	}
+
+	backup, err := s.db.GetConfigBackup()
+	if err != nil {
+		return nil, err
+	}
+	backup.CreatedAt = time.Now().UTC()
+
+	plaintext, err := json.Marshal(backup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup: %w", err)
+	}
+	sealed, err := encryption.SealWithPassphrase(plaintext, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record("backup.created", actor, "system", "backup", backupCounts(backup))
+	return sealed, nil
+}
+
+// RestoreBackup opens a backup with passphrase and adds its configuration.
+// Items that already exist are skipped, so a backup can be restored again.
+func (s *BackupService) RestoreBackup(ctx context.Context, sealed []byte, passphrase, actor string) (*models.BackupRestoreResult, error) {
+	plaintext, err := encryption.OpenWithPassphrase(sealed, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	var backup models.ConfigBackup
+	if err := json.Unmarshal(plaintext, &backup); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if backup.FormatVersion < 1 || backup.FormatVersion > database.BackupFormatVersion {
+		return nil, fmt.Errorf("%w: format version %d is not supported by this server", ErrInvalidBackup, backup.FormatVersion)
+	}
+
+	result, err := s.db.RestoreConfigBackup(&backup)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"created_at": backup.CreatedAt,
+		"restored":   result.Restored,
+		"skipped":    result.Skipped,
+	}
+	s.audit.Record("backup.restored", actor, "system", "backup", details)
+	return result, nil
+}
+
+func backupCounts(backup *models.ConfigBackup) map[string]interface{} {
+	return map[string]interface{}{
+		"organizations": len(backup.Organizations),
+		"projects":      len(backup.Projects),
+		"api_keys":      len(backup.APIKeys),
+		"alert_rules":   len(backup.AlertRules),
+		"integrations":  len(backup.Integrations),
+		"team_members":  len(backup.TeamMembers),
+		"teams":         len(backup.Teams),
+	}
+}
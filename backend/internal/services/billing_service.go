@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidUsageRange is returned for usage ranges that end before they start
+var ErrInvalidUsageRange = errors.New("invalid usage range")
+
+// usageReporterInterval is how often the reporter checks for ended days
+const usageReporterInterval = time.Hour
+
+// usageBackfillDays is how many ended days are posted if they were missed,
+// e.g. while the webhook was failing or no replica was running
+const usageBackfillDays = 7
+
+// usageDeliveryTTL keeps a delivery claim past the backfill window
+const usageDeliveryTTL = 2 * usageBackfillDays * 24 * time.Hour
+
+// BillingService meters what each organization uses, for operators who bill
+// for it. Usage of each UTC day is posted to the billing webhook once the day
+// has ended.
+type BillingService struct {
+	db    *database.DB
+	redis *redis.Client
+
+	webhookURL    string
+	webhookSecret string
+	client        *http.Client
+}
+
+func NewBillingService(db *database.DB, redis *redis.Client, webhookURL, webhookSecret string) *BillingService {
+	return &BillingService{
+		db:            db,
+		redis:         redis,
+		webhookURL:    webhookURL,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetOrgUsage returns the usage of every organization, or of one, for the
+// days from from up to and including to
+func (s *BillingService) GetOrgUsage(ctx context.Context, orgID *uuid.UUID, from, to time.Time) ([]models.OrgUsage, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidUsageRange)
+	}
+	if orgID != nil {
+		if _, err := s.db.GetOrganization(*orgID); err != nil {
+			return nil, err
+		}
+	}
+	return s.db.GetOrgUsage(orgID, from, to.AddDate(0, 0, 1))
+}
+
+// StartUsageReporter posts each ended day's usage to the billing webhook,
+// until ctx is done
+func (s *BillingService) StartUsageReporter(ctx context.Context) {
+	if s.webhookURL == "" {
+		log.Println("Usage reporter disabled: no BILLING_WEBHOOK_URL configured")
+		return
+	}
+	log.Println("Starting usage reporter...")
+
+	ticker := time.NewTicker(usageReporterInterval)
+	defer ticker.Stop()
+
+	for {
+		s.reportEndedDays(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Usage reporter stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BillingService) reportEndedDays(ctx context.Context) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Oldest first, so the receiver sees days in order
+	for i := usageBackfillDays; i >= 1; i-- {
+		start := today.AddDate(0, 0, -i)
+
+		fresh, err := s.redis.ClaimUsageDelivery(ctx, start, usageDeliveryTTL)
+		if err != nil {
+			log.Printf("Failed to claim usage delivery: %v", err)
+			return
+		}
+		if !fresh {
+			continue
+		}
+
+		if err := s.reportDay(ctx, start, now); err != nil {
+			log.Printf("Failed to report usage for %s: %v", start.Format("2006-01-02"), err)
+			if err := s.redis.ReleaseUsageDelivery(ctx, start); err != nil {
+				log.Printf("Failed to release usage delivery: %v", err)
+			}
+			// Later days wait, so none overtakes this one
+			return
+		}
+		log.Printf("USAGE REPORTED: %s", start.Format("2006-01-02"))
+	}
+}
+
+func (s *BillingService) reportDay(ctx context.Context, start, now time.Time) error {
+	end := start.AddDate(0, 0, 1)
+	usage, err := s.db.GetOrgUsage(nil, start, end)
+	if err != nil {
+		return err
+	}
+
+	return s.postWebhook(ctx, &models.UsageWebhookPayload{
+		ID:            "usage-" + start.Format("2006-01-02"),
+		Type:          "usage.reported",
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		Organizations: usage,
+		SentAt:        now,
+	})
+}
+
+// postWebhook sends the payload, signed like API requests (see SignRequest)
+// when BILLING_WEBHOOK_SECRET is set
+func (s *BillingService) postWebhook(ctx context.Context, payload *models.UsageWebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create usage webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.webhookSecret != "" {
+		u, err := url.Parse(s.webhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid billing webhook URL: %w", err)
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		req.Header.Set("X-Signature", SignRequest(s.webhookSecret, timestamp, http.MethodPost, u.RequestURI(), body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send usage webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("usage webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
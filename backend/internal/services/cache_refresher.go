@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+const (
+	// maxRefreshedQueries is how many of the most read queries are kept warm
+	maxRefreshedQueries = 20
+	// minQueryReads is how often a query must have been read in the last
+	// one to two hours to be kept warm
+	minQueryReads = 3
+)
+
+// cachedQuery describes a cached stats or trends query, so it can be
+// recomputed without the request that read it
+type cachedQuery struct {
+	Kind     string             `json:"kind"`
+	Filter   models.StatsFilter `json:"filter"`
+	Timezone string             `json:"timezone,omitempty"`
+	Period   string             `json:"period,omitempty"`
+	GroupBy  string             `json:"group_by,omitempty"`
+}
+
+type cacheAccessRecorder interface {
+	RecordCacheAccess(ctx context.Context, entry string, now time.Time) error
+}
+
+// recordCacheAccess counts a read of query in the background
+func recordCacheAccess(recorder cacheAccessRecorder, query cachedQuery) {
+	entry, err := json.Marshal(query)
+	if err != nil {
+		return
+	}
+	go func() {
+		if err := recorder.RecordCacheAccess(context.Background(), string(entry), time.Now().UTC()); err != nil {
+			log.Printf("Failed to record cache access: %v", err)
+		}
+	}()
+}
+
+// CacheRefresher recomputes the most read stats and trends shortly before
+// their cache entries expire, so dashboards polling them never wait for a
+// cold query
+type CacheRefresher struct {
+	errors    *ErrorService
+	analytics *AnalyticsService
+	redis     *redis.Client
+	interval  time.Duration
+}
+
+func NewCacheRefresher(errors *ErrorService, analytics *AnalyticsService, redis *redis.Client, interval time.Duration) *CacheRefresher {
+	return &CacheRefresher{
+		errors:    errors,
+		analytics: analytics,
+		redis:     redis,
+		interval:  interval,
+	}
+}
+
+// Start refreshes popular cache entries every interval until ctx is done
+func (r *CacheRefresher) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+	log.Println("Starting cache refresher...")
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Cache refresher stopped")
+			return
+		case <-ticker.C:
+			r.refresh(ctx, time.Now().UTC())
+		}
+	}
+}
+
+// refresh recomputes the popular entries that expire before the next two
+// ticks. Entries that are already gone, e.g. stats flushed by new errors,
+// are left to be computed on their next read.
+func (r *CacheRefresher) refresh(ctx context.Context, now time.Time) {
+	entries, err := r.redis.GetPopularCacheEntries(ctx, now, maxRefreshedQueries, minQueryReads)
+	if err != nil {
+		log.Printf("Failed to load popular cache entries: %v", err)
+		return
+	}
+
+	refreshed := 0
+	for _, entry := range entries {
+		var query cachedQuery
+		if err := json.Unmarshal([]byte(entry), &query); err != nil {
+			continue
+		}
+		loc, err := loadTimezone(query.Timezone)
+		if err != nil {
+			continue
+		}
+
+		var ttl time.Duration
+		switch query.Kind {
+		case "stats":
+			ttl, err = r.redis.GetStatsCacheTTL(ctx, statsCacheKeyIn(query.Filter, loc))
+		case "trends":
+			ttl, err = r.redis.GetTrendsCacheTTL(ctx, trendsCacheKey(query.Period, query.GroupBy, query.Filter, loc))
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to check cache entry %s: %v", entry, err)
+			continue
+		}
+		if ttl == 0 || ttl > 2*r.interval {
+			continue
+		}
+
+		switch query.Kind {
+		case "stats":
+			err = r.errors.RefreshStats(ctx, query.Filter, query.Timezone)
+		case "trends":
+			err = r.analytics.RefreshTrends(ctx, query.Period, query.GroupBy, query.Filter, query.Timezone)
+		}
+		if err != nil {
+			log.Printf("Failed to refresh cache entry %s: %v", entry, err)
+			continue
+		}
+		refreshed++
+	}
+
+	if refreshed > 0 {
+		log.Printf("CACHE REFRESH: recomputed %d popular entries before they expired", refreshed)
+	}
+}
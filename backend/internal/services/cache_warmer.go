@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// cacheWarmTimeout bounds one round of warming, so a slow database can't
+// pile rounds up behind each other
+const cacheWarmTimeout = time.Minute
+
+// CacheWarmer fills the caches the dashboard reads first, so its first load
+// after a restart or a cache flush doesn't send a burst of cold queries to
+// Postgres
+type CacheWarmer struct {
+	errors    *ErrorService
+	analytics *AnalyticsService
+	interval  time.Duration
+}
+
+func NewCacheWarmer(errors *ErrorService, analytics *AnalyticsService, interval time.Duration) *CacheWarmer {
+	return &CacheWarmer{
+		errors:    errors,
+		analytics: analytics,
+		interval:  interval,
+	}
+}
+
+// Start warms the caches right away, then checks the cache generation every
+// interval and warms them again if they were flushed since, until ctx is
+// done. Flushes happen on every stored event, so checking on a timer rather
+// than after each flush keeps a busy instance to one round per interval.
+func (w *CacheWarmer) Start(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	log.Println("Starting cache warmer...")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	warmed := int64(-1)
+	for {
+		generation, err := w.errors.CacheGeneration(ctx)
+		if err != nil {
+			log.Printf("Failed to read cache generation: %v", err)
+		} else if generation != warmed {
+			if w.warm(ctx) {
+				warmed = generation
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Cache warmer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// warm loads the dashboard's default views the way its requests do, which
+// caches them on a miss. It reports whether all of them loaded.
+func (w *CacheWarmer) warm(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, cacheWarmTimeout)
+	defer cancel()
+
+	start := time.Now()
+	ok := true
+	if _, err := w.errors.GetStats(ctx, models.StatsFilter{}, ""); err != nil {
+		log.Printf("Failed to warm stats cache: %v", err)
+		ok = false
+	}
+	if _, err := w.errors.GetErrors(ctx, 50, 0, models.ErrorFilter{}); err != nil {
+		log.Printf("Failed to warm error list cache: %v", err)
+		ok = false
+	}
+	if _, err := w.analytics.GetTrends(ctx, "week", "day", models.StatsFilter{}, ""); err != nil {
+		log.Printf("Failed to warm trends cache: %v", err)
+		ok = false
+	}
+	if ok {
+		log.Printf("CACHE WARM: stats, recent errors and trends loaded in %v", time.Since(start))
+	}
+	return ok
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"hash/fnv"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Groups are compared with MinHash signatures of their messages, and
+// locality-sensitive hashing picks the pairs worth comparing: two groups are
+// compared only when their signatures agree in all rows of at least one
+// band. With 16 bands of 4 rows, pairs at 0.7 similarity are compared 99%
+// of the time and pairs at 0.3 about 12% of the time.
+const (
+	minHashBands   = 16
+	minHashRows    = 4
+	minHashLength  = minHashBands * minHashRows
+	minHashSeedMix = 0x9e3779b97f4a7c15
+)
+
+// Variable parts of messages that would keep alike messages apart
+var (
+	clusterUUID   = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	clusterHex    = regexp.MustCompile(`\b(?:0x[0-9a-f]+|[0-9a-f]{8,})\b`)
+	clusterNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	clusterQuoted = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	clusterWord   = regexp.MustCompile(`[a-z_<>][a-z0-9_<>]*`)
+)
+
+// clusterShingles returns the hashed words and word pairs of a message,
+// after replacing IDs, numbers and quoted values with placeholders
+func clusterShingles(message string) map[uint64]bool {
+	text := strings.ToLower(message)
+	text = clusterUUID.ReplaceAllString(text, "<uuid>")
+	text = clusterHex.ReplaceAllString(text, "<hex>")
+	text = clusterQuoted.ReplaceAllString(text, "<str>")
+	text = clusterNumber.ReplaceAllString(text, "<n>")
+	words := clusterWord.FindAllString(text, -1)
+
+	shingles := make(map[uint64]bool, 2*len(words))
+	for i, word := range words {
+		shingles[hashShingle(word)] = true
+		if i > 0 {
+			shingles[hashShingle(words[i-1]+" "+word)] = true
+		}
+	}
+	return shingles
+}
+
+func hashShingle(shingle string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(shingle))
+	return h.Sum64()
+}
+
+// mixHash is splitmix64's finalizer, which turns one hash of a shingle into
+// the many independent ones a MinHash signature needs
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func minHashSignature(shingles map[uint64]bool) [minHashLength]uint64 {
+	var signature [minHashLength]uint64
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for shingle := range shingles {
+		for i := range signature {
+			if h := mixHash(shingle ^ (uint64(i+1) * minHashSeedMix)); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// jaccard is the share of shingles two messages have in common
+func jaccard(a, b map[uint64]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for shingle := range a {
+		if b[shingle] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// clusterItem is one error group being clustered
+type clusterItem struct {
+	key       string // groups are only clustered with others under the same key
+	shingles  map[uint64]bool
+	signature [minHashLength]uint64
+}
+
+// clusterBucket holds the items whose signatures agree in one band
+type clusterBucket struct {
+	key  string
+	rows [minHashRows]uint64
+}
+
+// clusterBySimilarity returns the sets of items that are linked through
+// pairs at least minSimilarity alike, largest first. Items alone are left out.
+func clusterBySimilarity(items []clusterItem, minSimilarity float64) [][]int {
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	compared := map[[2]int]bool{}
+	for band := 0; band < minHashBands; band++ {
+		buckets := map[clusterBucket][]int{}
+		for i, item := range items {
+			if len(item.shingles) == 0 {
+				continue
+			}
+			bucket := clusterBucket{key: item.key}
+			copy(bucket.rows[:], item.signature[band*minHashRows:(band+1)*minHashRows])
+			buckets[bucket] = append(buckets[bucket], i)
+		}
+
+		for _, members := range buckets {
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					pair := [2]int{members[x], members[y]}
+					if compared[pair] {
+						continue
+					}
+					compared[pair] = true
+					if find(pair[0]) != find(pair[1]) && jaccard(items[pair[0]].shingles, items[pair[1]].shingles) >= minSimilarity {
+						parent[find(pair[0])] = find(pair[1])
+					}
+				}
+			}
+		}
+	}
+
+	byRoot := map[int][]int{}
+	for i := range items {
+		root := find(i)
+		byRoot[root] = append(byRoot[root], i)
+	}
+	clusters := [][]int{}
+	for _, members := range byRoot {
+		if len(members) > 1 {
+			clusters = append(clusters, members)
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i]) != len(clusters[j]) {
+			return len(clusters[i]) > len(clusters[j])
+		}
+		return clusters[i][0] < clusters[j][0]
+	})
+	return clusters
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+const (
+	// clusterWindow is how recently a group must have been seen to be clustered
+	clusterWindow = 7 * 24 * time.Hour
+	// maxClusteredGroups caps a run to the groups with the most events
+	maxClusteredGroups = 5000
+	// defaultClusterSimilarity is used when the configured percentage is out of range
+	defaultClusterSimilarity = 70
+)
+
+// ClusteringService suggests error groups that look like the same root
+// cause: groups whose messages are alike once IDs and numbers are set aside,
+// such as one connection failure reported from many code paths. A periodic
+// job computes the clusters; it is off unless an interval is configured.
+type ClusteringService struct {
+	db            *database.DB
+	interval      time.Duration
+	minSimilarity float64
+}
+
+func NewClusteringService(db *database.DB, intervalMinutes, similarityPercent int) *ClusteringService {
+	if similarityPercent < 1 || similarityPercent > 100 {
+		similarityPercent = defaultClusterSimilarity
+	}
+	return &ClusteringService{
+		db:            db,
+		interval:      time.Duration(intervalMinutes) * time.Minute,
+		minSimilarity: float64(similarityPercent) / 100,
+	}
+}
+
+// Enabled reports whether the clustering job runs
+func (s *ClusteringService) Enabled() bool {
+	return s.interval > 0
+}
+
+// GetClusters returns a page of the clusters found by the last run, those
+// with the most events first
+func (s *ClusteringService) GetClusters(ctx context.Context, limit, offset int, projectID *uuid.UUID) (*models.ErrorClustersResponse, error) {
+	clusters, total, err := s.db.GetErrorClusters(limit, offset, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ErrorClustersResponse{Clusters: clusters, Total: total, Enabled: s.Enabled()}, nil
+}
+
+// StartClusterer periodically recomputes the clusters until ctx is done
+func (s *ClusteringService) StartClusterer(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+	log.Println("Starting error clusterer...")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if clusters, err := s.cluster(time.Now().UTC()); err != nil {
+			log.Printf("Failed to cluster error groups: %v", err)
+		} else {
+			log.Printf("ERROR CLUSTERS: found %d clusters", clusters)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Error clusterer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cluster groups the unresolved error groups seen in the last week by the
+// similarity of their messages, within each project, and stores the result
+func (s *ClusteringService) cluster(now time.Time) (int, error) {
+	groups, err := s.db.GetErrorGroupTexts(now.Add(-clusterWindow), maxClusteredGroups)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]clusterItem, len(groups))
+	for i, group := range groups {
+		shingles := clusterShingles(group.Message)
+		items[i] = clusterItem{shingles: shingles, signature: minHashSignature(shingles)}
+		if group.ProjectID != nil {
+			items[i].key = group.ProjectID.String()
+		}
+	}
+
+	clusters := []models.ErrorCluster{}
+	for _, members := range clusterBySimilarity(items, s.minSimilarity) {
+		// The group with the most events leads the cluster and names it
+		sort.Slice(members, func(i, j int) bool {
+			return groups[members[i]].EventCount > groups[members[j]].EventCount
+		})
+		lead := members[0]
+
+		cluster := models.ErrorCluster{
+			ID:         groups[lead].Fingerprint,
+			ProjectID:  groups[lead].ProjectID,
+			Similarity: 1,
+			ComputedAt: now,
+		}
+		for _, member := range members {
+			group := groups[member]
+			similarity := jaccard(items[lead].shingles, items[member].shingles)
+			if similarity < cluster.Similarity {
+				cluster.Similarity = similarity
+			}
+			cluster.EventCount += group.EventCount
+			cluster.Groups = append(cluster.Groups, models.ErrorClusterGroup{
+				Fingerprint: group.Fingerprint,
+				Message:     group.Message,
+				Level:       group.Level,
+				Source:      group.Source,
+				EventCount:  group.EventCount,
+				LastSeen:    group.LastSeen,
+				Similarity:  similarity,
+			})
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	if err := s.db.ReplaceErrorClusters(clusters); err != nil {
+		return 0, err
+	}
+	return len(clusters), nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrFieldNotIndexed is returned when a query filters on a context key that
+// has not been designated as indexed
+var ErrFieldNotIndexed = errors.New("context field is not indexed")
+
+// indexedFieldsRefresh bounds how stale the in-memory field list may get
+const indexedFieldsRefresh = time.Minute
+
+type ContextIndexService struct {
+	db    *database.DB
+	redis *redis.Client
+
+	mu       sync.RWMutex
+	fields   map[string]bool
+	loadedAt time.Time
+}
+
+func NewContextIndexService(db *database.DB, redis *redis.Client) *ContextIndexService {
+	return &ContextIndexService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (s *ContextIndexService) GetIndexedFields(ctx context.Context) ([]models.IndexedContextField, error) {
+	return s.db.GetIndexedContextFields()
+}
+
+func (s *ContextIndexService) AddIndexedField(ctx context.Context, key string) (*models.IndexedContextField, error) {
+	field := &models.IndexedContextField{
+		Key:       key,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.db.CreateIndexedContextField(field); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	// Backfill existing rows in the background; this can touch many rows
+	go func() {
+		start := time.Now()
+		rows, err := s.db.BackfillIndexedContextField(key)
+		if err != nil {
+			log.Printf("Failed to backfill indexed context field %s: %v", key, err)
+			return
+		}
+		log.Printf("INDEXED FIELD BACKFILL: key: %s, rows: %d, duration: %v", key, rows, time.Since(start))
+	}()
+
+	return field, nil
+}
+
+func (s *ContextIndexService) RemoveIndexedField(ctx context.Context, key string) error {
+	if err := s.db.DeleteIndexedContextField(key); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	go func() {
+		if err := s.db.RemoveIndexedContextField(key); err != nil {
+			log.Printf("Failed to remove indexed context field %s: %v", key, err)
+		}
+	}()
+
+	return nil
+}
+
+// Extract copies the designated context keys of an event into IndexedContext
+func (s *ContextIndexService) Extract(error *models.Error) {
+	fields := s.indexedFields()
+	if len(fields) == 0 || len(error.Context) == 0 {
+		return
+	}
+
+	indexed := make(map[string]string)
+	for key := range fields {
+		value, ok := error.Context[key]
+		if !ok || value == nil {
+			continue
+		}
+		indexed[key] = stringifyContextValue(value)
+	}
+	error.IndexedContext = indexed
+}
+
+// CheckIndexed rejects filters on context keys that are not indexed
+func (s *ContextIndexService) CheckIndexed(filter map[string]string) error {
+	if len(filter) == 0 {
+		return nil
+	}
+
+	fields := s.indexedFields()
+	for key := range filter {
+		if !fields[key] {
+			return fmt.Errorf("%w: %s", ErrFieldNotIndexed, key)
+		}
+	}
+	return nil
+}
+
+func (s *ContextIndexService) indexedFields() map[string]bool {
+	s.mu.RLock()
+	if s.fields != nil && time.Since(s.loadedAt) < indexedFieldsRefresh {
+		defer s.mu.RUnlock()
+		return s.fields
+	}
+	s.mu.RUnlock()
+
+	list, err := s.db.GetIndexedContextFields()
+	if err != nil {
+		log.Printf("Failed to load indexed context fields: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.fields
+	}
+
+	fields := make(map[string]bool, len(list))
+	for _, field := range list {
+		fields[field.Key] = true
+	}
+
+	s.mu.Lock()
+	s.fields = fields
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return fields
+}
+
+func (s *ContextIndexService) invalidate() {
+	s.mu.Lock()
+	s.fields = nil
+	s.mu.Unlock()
+}
+
+// stringifyContextValue renders a context value the same way Postgres'
+// ->> operator does, so backfilled and newly ingested rows match
+func stringifyContextValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		// Objects and arrays come out as JSON, laid out like jsonb's text
+		var sb strings.Builder
+		if err := writeJSONBText(&sb, v); err != nil {
+			return fmt.Sprint(v)
+		}
+		return sb.String()
+	}
+}
+
+// writeJSONBText writes a value as JSON the way Postgres prints jsonb: a
+// space after each comma and colon, and object keys shorter first, then in
+// byte order, as jsonb stores them. Values that are not plain JSON, such as
+// structs set at ingestion, are round-tripped through encoding/json first.
+func writeJSONBText(sb *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		sb.WriteString("null")
+	case string:
+		sb.WriteString(jsonString(v))
+	case float64:
+		sb.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeJSONBText(sb, item); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(jsonString(key))
+			sb.WriteString(": ")
+			if err := writeJSONBText(sb, v[key]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var plain interface{}
+		if err := json.Unmarshal(raw, &plain); err != nil {
+			return err
+		}
+		return writeJSONBText(sb, plain)
+	}
+	return nil
+}
+
+// jsonString quotes a string as JSON without escaping <, > and &, which
+// jsonb prints as they are
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStringifyContextValue(t *testing.T) {
+	// Expected values are what Postgres prints for context->>'key'
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`"checkout"`, `checkout`},
+		{`42`, `42`},
+		{`1.5`, `1.5`},
+		{`true`, `true`},
+		{`[1, "a", null]`, `[1, "a", null]`},
+		{`{"b":1,"a":2}`, `{"a": 2, "b": 1}`},
+		{`{"long":1,"zz":2}`, `{"zz": 2, "long": 1}`},
+		{`{"user":{"id":7,"tags":["x","y"]}}`, `{"user": {"id": 7, "tags": ["x", "y"]}}`},
+		{`{"html":"<a & b>"}`, `{"html": "<a & b>"}`},
+		{`["line\nbreak","quote\""]`, `["line\nbreak", "quote\""]`},
+		{`{}`, `{}`},
+		{`[]`, `[]`},
+	}
+
+	for _, tt := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(tt.json), &value); err != nil {
+			t.Fatalf("invalid test JSON %s: %v", tt.json, err)
+		}
+		if got := stringifyContextValue(value); got != tt.want {
+			t.Errorf("stringifyContextValue(%s) = %s, want %s", tt.json, got, tt.want)
+		}
+	}
+}
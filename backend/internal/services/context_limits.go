@@ -0,0 +1,81 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"unicode/utf8"
+)
+
+// ContextTruncatedKey is added to a context that was cut down to its
+// ContextLimits. It holds how many keys were dropped and which values were
+// shortened.
+const ContextTruncatedKey = "_truncated"
+
+// ContextLimits bounds the context stored with an event, so one service
+// sending whole request objects can't blow up row and cache sizes. A zero
+// limit is not enforced.
+type ContextLimits struct {
+	MaxKeys       int // top-level keys kept, in key order
+	MaxValueBytes int // size of each value, as JSON unless it is a string
+}
+
+// Apply drops the keys past MaxKeys and shortens values over MaxValueBytes,
+// in place. Shortened strings are cut at a character boundary; other values
+// are replaced by the start of their JSON. Only truncated contexts get the
+// ContextTruncatedKey marker, which is not counted against MaxKeys.
+func (l ContextLimits) Apply(fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	dropped := 0
+	if l.MaxKeys > 0 && len(keys) > l.MaxKeys {
+		for _, key := range keys[l.MaxKeys:] {
+			delete(fields, key)
+		}
+		dropped = len(keys) - l.MaxKeys
+		keys = keys[:l.MaxKeys]
+	}
+
+	shortened := []string{}
+	if l.MaxValueBytes > 0 {
+		for _, key := range keys {
+			if value, ok := shortenContextValue(fields[key], l.MaxValueBytes); ok {
+				fields[key] = value
+				shortened = append(shortened, key)
+			}
+		}
+	}
+
+	if dropped == 0 && len(shortened) == 0 {
+		return
+	}
+	fields[ContextTruncatedKey] = map[string]interface{}{
+		"dropped_keys": dropped,
+		"values":       shortened,
+	}
+}
+
+// shortenContextValue returns the value cut to maxBytes, and whether it had
+// to be
+func shortenContextValue(value interface{}, maxBytes int) (string, bool) {
+	s, isString := value.(string)
+	if !isString {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		s = string(encoded)
+	}
+	if len(s) <= maxBytes {
+		return "", false
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaViolations caps the violations recorded on one event
+const maxSchemaViolations = 10
+
+// schemaKeywords are the JSON Schema keywords the validator supports. The
+// annotation keywords are accepted and ignored; anything else is rejected
+// rather than silently not enforced.
+var schemaKeywords = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "enum": true, "pattern": true, "minLength": true, "maxLength": true,
+	"minimum": true, "maximum": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"examples": true, "default": true,
+}
+
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// contextSchema is a compiled subset of JSON Schema, enough to describe the
+// expected shape of event context
+type contextSchema struct {
+	types      []string
+	properties map[string]*contextSchema
+	required   []string
+	additional *bool
+	items      *contextSchema
+	enum       []interface{}
+	pattern    *regexp.Regexp
+	minLength  *int
+	maxLength  *int
+	minimum    *float64
+	maximum    *float64
+}
+
+// compileContextSchema checks a project's schema and prepares it for validation
+func compileContextSchema(schema map[string]interface{}) (*contextSchema, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContextSchema, err)
+	}
+	compiled, err := compileSchemaNode(raw, "context")
+	if err != nil {
+		return nil, err
+	}
+	if len(compiled.types) > 0 && !slices.Equal(compiled.types, []string{"object"}) {
+		return nil, fmt.Errorf("%w: context must have type object", ErrInvalidContextSchema)
+	}
+	return compiled, nil
+}
+
+func compileSchemaNode(raw json.RawMessage, path string) (*contextSchema, error) {
+	var node map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &node); err != nil || node == nil {
+		return nil, fmt.Errorf("%w: the schema for %s must be an object", ErrInvalidContextSchema, path)
+	}
+
+	keywords := make([]string, 0, len(node))
+	for keyword := range node {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			return nil, fmt.Errorf("%w: unsupported keyword %q at %s", ErrInvalidContextSchema, keyword, path)
+		}
+	}
+
+	s := &contextSchema{}
+	decode := func(keyword string, dest interface{}) error {
+		value, ok := node[keyword]
+		if !ok {
+			return nil
+		}
+		if err := json.Unmarshal(value, dest); err != nil {
+			return fmt.Errorf("%w: invalid %s at %s", ErrInvalidContextSchema, keyword, path)
+		}
+		return nil
+	}
+
+	if value, ok := node["type"]; ok {
+		var single string
+		if json.Unmarshal(value, &single) == nil {
+			s.types = []string{single}
+		} else if err := decode("type", &s.types); err != nil {
+			return nil, err
+		}
+		for _, t := range s.types {
+			if !slices.Contains(schemaTypes, t) {
+				return nil, fmt.Errorf("%w: unknown type %q at %s", ErrInvalidContextSchema, t, path)
+			}
+		}
+	}
+
+	var properties map[string]json.RawMessage
+	if err := decode("properties", &properties); err != nil {
+		return nil, err
+	}
+	if len(properties) > 0 {
+		s.properties = make(map[string]*contextSchema, len(properties))
+		for key, value := range properties {
+			property, err := compileSchemaNode(value, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[key] = property
+		}
+	}
+
+	if err := decode("required", &s.required); err != nil {
+		return nil, err
+	}
+	if err := decode("additionalProperties", &s.additional); err != nil {
+		return nil, fmt.Errorf("%w: additionalProperties at %s must be a boolean", ErrInvalidContextSchema, path)
+	}
+
+	if value, ok := node["items"]; ok {
+		items, err := compileSchemaNode(value, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s.items = items
+	}
+
+	if err := decode("enum", &s.enum); err != nil {
+		return nil, err
+	}
+
+	var pattern string
+	if err := decode("pattern", &pattern); err != nil {
+		return nil, err
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid pattern at %s: %v", ErrInvalidContextSchema, path, err)
+		}
+		s.pattern = re
+	}
+
+	for keyword, dest := range map[string]interface{}{
+		"minLength": &s.minLength, "maxLength": &s.maxLength,
+		"minimum": &s.minimum, "maximum": &s.maximum,
+	} {
+		if err := decode(keyword, dest); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// validate appends a violation for each place value breaks the schema. Array
+// elements share one path, so the same mistake in any element reads the same.
+func (s *contextSchema) validate(value interface{}, path string, violations []string) []string {
+	if len(violations) >= maxSchemaViolations {
+		return violations
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return schemaTypeMatches(t, value) }) {
+		return append(violations, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.types, " or "), schemaTypeOf(value)))
+	}
+	if len(s.enum) > 0 && !slices.ContainsFunc(s.enum, func(allowed interface{}) bool { return reflect.DeepEqual(allowed, value) }) {
+		violations = append(violations, fmt.Sprintf("%s: not one of the allowed values", path))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			violations = append(violations, fmt.Sprintf("%s: shorter than %d characters", path, *s.minLength))
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			violations = append(violations, fmt.Sprintf("%s: longer than %d characters", path, *s.maxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			violations = append(violations, fmt.Sprintf("%s: does not match %s", path, s.pattern))
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			violations = append(violations, fmt.Sprintf("%s: less than %v", path, *s.minimum))
+		}
+		if s.maximum != nil && v > *s.maximum {
+			violations = append(violations, fmt.Sprintf("%s: greater than %v", path, *s.maximum))
+		}
+	case map[string]interface{}:
+		for _, key := range s.required {
+			if _, ok := v[key]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s: required field is missing", path, key))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := s.properties[key]; ok {
+				violations = property.validate(v[key], path+"."+key, violations)
+			} else if s.additional != nil && !*s.additional {
+				violations = append(violations, fmt.Sprintf("%s.%s: unexpected field", path, key))
+			}
+		}
+	case []interface{}:
+		if s.items != nil {
+			for _, item := range v {
+				violations = s.items.validate(item, path+"[]", violations)
+			}
+		}
+	}
+
+	if len(violations) > maxSchemaViolations {
+		violations = violations[:maxSchemaViolations]
+	}
+	return violations
+}
+
+func schemaTypeMatches(t string, value interface{}) bool {
+	actual := schemaTypeOf(value)
+	return actual == t || (t == "number" && actual == "integer")
+}
+
+// schemaTypeOf names the JSON type of a decoded value
+func schemaTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidContextSchema is returned for schemas the validator cannot enforce
+var ErrInvalidContextSchema = errors.New("invalid context schema")
+
+// contextSchemasRefresh bounds how stale the in-memory schemas may get
+const contextSchemasRefresh = time.Minute
+
+// conformanceTopViolations caps the violations listed per source
+const conformanceTopViolations = 5
+
+// ContextSchemaService validates event context against each project's JSON
+// schema and reports how well each source conforms
+type ContextSchemaService struct {
+	db *database.DB
+
+	mu       sync.RWMutex
+	schemas  map[uuid.UUID]*contextSchema
+	loadedAt time.Time
+}
+
+func NewContextSchemaService(db *database.DB) *ContextSchemaService {
+	return &ContextSchemaService{
+		db: db,
+	}
+}
+
+func (s *ContextSchemaService) GetSchema(ctx context.Context, projectID uuid.UUID) (*models.ContextSchema, error) {
+	return s.db.GetContextSchema(projectID)
+}
+
+func (s *ContextSchemaService) SetSchema(ctx context.Context, projectID uuid.UUID, req *models.UpdateContextSchemaRequest) (*models.ContextSchema, error) {
+	if req.Schema == nil {
+		return nil, fmt.Errorf("%w: schema is required", ErrInvalidContextSchema)
+	}
+	if _, err := compileContextSchema(req.Schema); err != nil {
+		return nil, err
+	}
+
+	schema := &models.ContextSchema{
+		ProjectID: projectID,
+		Schema:    req.Schema,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.db.UpsertContextSchema(schema); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	return schema, nil
+}
+
+func (s *ContextSchemaService) DeleteSchema(ctx context.Context, projectID uuid.UUID) error {
+	if err := s.db.DeleteContextSchema(projectID); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// GetConformance reports, per source, how many of the project's events in
+// the last days broke its context schema
+func (s *ContextSchemaService) GetConformance(ctx context.Context, projectID uuid.UUID, days int) (*models.ContextConformanceReport, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	sources, err := s.db.GetContextConformance(projectID, since, conformanceTopViolations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ContextConformanceReport{
+		ProjectID: projectID,
+		Since:     since,
+		Sources:   sources,
+	}, nil
+}
+
+// Validate returns where the context breaks the project's schema: nil when
+// the project has no schema, empty when the context conforms
+func (s *ContextSchemaService) Validate(projectID *uuid.UUID, errorContext map[string]interface{}) []string {
+	if projectID == nil {
+		return nil
+	}
+	schema := s.contextSchemas()[*projectID]
+	if schema == nil {
+		return nil
+	}
+
+	// Round-trip through JSON so values set at ingestion, like a parsed Go
+	// stack, are checked as the JSON that gets stored
+	var value interface{} = map[string]interface{}{}
+	if raw, err := json.Marshal(errorContext); err == nil {
+		if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+			value = map[string]interface{}{}
+		}
+	}
+
+	return dedupeStrings(schema.validate(value, "context", []string{}))
+}
+
+func (s *ContextSchemaService) contextSchemas() map[uuid.UUID]*contextSchema {
+	s.mu.RLock()
+	if s.schemas != nil && time.Since(s.loadedAt) < contextSchemasRefresh {
+		defer s.mu.RUnlock()
+		return s.schemas
+	}
+	s.mu.RUnlock()
+
+	list, err := s.db.GetContextSchemas()
+	if err != nil {
+		log.Printf("Failed to load context schemas: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.schemas
+	}
+
+	schemas := make(map[uuid.UUID]*contextSchema, len(list))
+	for _, stored := range list {
+		compiled, err := compileContextSchema(stored.Schema)
+		if err != nil {
+			log.Printf("Skipping context schema of project %s: %v", stored.ProjectID, err)
+			continue
+		}
+		schemas[stored.ProjectID] = compiled
+	}
+
+	s.mu.Lock()
+	s.schemas = schemas
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return schemas
+}
+
+func (s *ContextSchemaService) invalidate() {
+	s.mu.Lock()
+	s.schemas = nil
+	s.mu.Unlock()
+}
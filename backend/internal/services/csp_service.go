@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidCSPReport is returned when a CSP report body cannot be parsed
+var ErrInvalidCSPReport = errors.New("invalid CSP report")
+
+// maxCSPReportBatch caps the number of reports accepted per request
+const maxCSPReportBatch = 100
+
+// cspViolation is a violation normalized from either report format
+type cspViolation struct {
+	DocumentURL        string
+	BlockedURL         string
+	EffectiveDirective string
+	OriginalPolicy     string
+	Disposition        string
+	SourceFile         string
+	LineNumber         int
+	ColumnNumber       int
+	StatusCode         int
+	Sample             string
+	Referrer           string
+}
+
+// report-uri body: {"csp-report": {...}}
+type legacyCSPReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		EffectiveDirective string `json:"effective-directive"`
+		ViolatedDirective  string `json:"violated-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		StatusCode         int    `json:"status-code"`
+		ScriptSample       string `json:"script-sample"`
+		Referrer           string `json:"referrer"`
+	} `json:"csp-report"`
+}
+
+// report-to body: [{"type": "csp-violation", "body": {...}}, ...]
+type reportingAPIReport struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	UserAgent string `json:"user_agent"`
+	Body      struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		OriginalPolicy     string `json:"originalPolicy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		ColumnNumber       int    `json:"columnNumber"`
+		StatusCode         int    `json:"statusCode"`
+		Sample             string `json:"sample"`
+		Referrer           string `json:"referrer"`
+	} `json:"body"`
+}
+
+// CSPService turns Content Security Policy violation reports into error events
+type CSPService struct {
+	errorService     *ErrorService
+	quotaService     *QuotaService
+	publicKeyService *PublicKeyService
+}
+
+func NewCSPService(errorService *ErrorService, quotaService *QuotaService, publicKeyService *PublicKeyService) *CSPService {
+	return &CSPService{
+		errorService:     errorService,
+		quotaService:     quotaService,
+		publicKeyService: publicKeyService,
+	}
+}
+
+// IngestReports parses a report-uri or report-to body and records each
+// violation as an error event with source "csp". Violations caused by browser
+// extensions, and for public keys violations on pages outside the key's
+// allowed origins, are dropped. It returns the number of events recorded.
+func (s *CSPService) IngestReports(ctx context.Context, body []byte, key *models.APIKey, environment, release, userAgent, ipAddress string) (int, error) {
+	violations, err := parseCSPReports(body)
+	if err != nil {
+		return 0, err
+	}
+
+	var projectID *uuid.UUID
+	if key != nil {
+		projectID = key.ProjectID
+	}
+
+	accepted := 0
+	for _, violation := range violations {
+		if violation.fromExtension() {
+			continue
+		}
+		// Browsers do not reliably send Origin with reports, so check the page itself
+		if key != nil && key.KeyType == "public" && !s.publicKeyService.OriginAllowed(key, violation.DocumentURL) {
+			log.Printf("CSP REPORT FILTERED: public key %s from %s - origin %s", key.ID, ipAddress, violation.DocumentURL)
+			continue
+		}
+
+		if projectID != nil {
+			if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+				return accepted, err
+			}
+		}
+
+		req := violation.toErrorRequest()
+		if environment != "" {
+			req.Environment = &environment
+		}
+		if release != "" {
+			req.Release = &release
+		}
+
+		_, err := s.errorService.CreateError(ctx, req, projectID, userAgent, ipAddress)
+		if err == ErrEventThrottled {
+			continue
+		}
+		if err != nil {
+			return accepted, err
+		}
+		accepted++
+	}
+
+	return accepted, nil
+}
+
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: empty body", ErrInvalidCSPReport)
+	}
+
+	var violations []cspViolation
+	if body[0] == '[' {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSPReport, err)
+		}
+		for _, report := range reports {
+			// Reporting API batches may include other report types
+			if report.Type != "csp-violation" {
+				continue
+			}
+			b := report.Body
+			documentURL := b.DocumentURL
+			if documentURL == "" {
+				documentURL = report.URL
+			}
+			violations = append(violations, cspViolation{
+				DocumentURL:        documentURL,
+				BlockedURL:         b.BlockedURL,
+				EffectiveDirective: b.EffectiveDirective,
+				OriginalPolicy:     b.OriginalPolicy,
+				Disposition:        b.Disposition,
+				SourceFile:         b.SourceFile,
+				LineNumber:         b.LineNumber,
+				ColumnNumber:       b.ColumnNumber,
+				StatusCode:         b.StatusCode,
+				Sample:             b.Sample,
+				Referrer:           b.Referrer,
+			})
+		}
+	} else {
+		var report legacyCSPReport
+		if err := json.Unmarshal(body, &report); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSPReport, err)
+		}
+		r := report.Report
+		directive := r.EffectiveDirective
+		if directive == "" {
+			// Older browsers only send the full violated directive, e.g. "script-src 'self'"
+			directive, _, _ = strings.Cut(r.ViolatedDirective, " ")
+		}
+		violations = append(violations, cspViolation{
+			DocumentURL:        r.DocumentURI,
+			BlockedURL:         r.BlockedURI,
+			EffectiveDirective: directive,
+			OriginalPolicy:     r.OriginalPolicy,
+			Disposition:        r.Disposition,
+			SourceFile:         r.SourceFile,
+			LineNumber:         r.LineNumber,
+			ColumnNumber:       r.ColumnNumber,
+			StatusCode:         r.StatusCode,
+			Sample:             r.ScriptSample,
+			Referrer:           r.Referrer,
+		})
+	}
+
+	for _, v := range violations {
+		if v.EffectiveDirective == "" {
+			return nil, fmt.Errorf("%w: missing directive", ErrInvalidCSPReport)
+		}
+	}
+	if len(violations) > maxCSPReportBatch {
+		return nil, fmt.Errorf("%w: at most %d reports per request", ErrInvalidCSPReport, maxCSPReportBatch)
+	}
+
+	return violations, nil
+}
+
+func (v cspViolation) fromExtension() bool {
+	for _, scheme := range extensionSchemes {
+		if strings.HasPrefix(v.BlockedURL, scheme) || strings.HasPrefix(v.SourceFile, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// toErrorRequest builds the error event. The message names the directive and
+// the blocked origin rather than the full URL, so violations group per
+// resource host instead of per query string.
+func (v cspViolation) toErrorRequest() *models.CreateErrorRequest {
+	level := "error"
+	if v.Disposition == "report" {
+		level = "warning"
+	}
+
+	blocked := cspBlockedResource(v.BlockedURL)
+	message := fmt.Sprintf("CSP violation: %s blocked %s", v.EffectiveDirective, blocked)
+
+	details := map[string]interface{}{
+		"csp_directive":   v.EffectiveDirective,
+		"csp_blocked_uri": v.BlockedURL,
+	}
+	if v.Disposition != "" {
+		details["csp_disposition"] = v.Disposition
+	}
+	if v.OriginalPolicy != "" {
+		details["csp_policy"] = v.OriginalPolicy
+	}
+	if v.SourceFile != "" {
+		details["csp_source_file"] = v.SourceFile
+		details["csp_line"] = v.LineNumber
+		details["csp_column"] = v.ColumnNumber
+	}
+	if v.Sample != "" {
+		details["csp_sample"] = v.Sample
+	}
+	if v.StatusCode != 0 {
+		details["csp_status_code"] = v.StatusCode
+	}
+	if v.Referrer != "" {
+		details["csp_referrer"] = v.Referrer
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:   level,
+		Message: message,
+		Context: details,
+		Source:  "csp",
+	}
+	if v.DocumentURL != "" {
+		documentURL := v.DocumentURL
+		req.URL = &documentURL
+	}
+	return req
+}
+
+// cspBlockedResource reduces a blocked URL to its origin; keywords such as
+// "inline" and "eval" are kept as they are
+func cspBlockedResource(blocked string) string {
+	if blocked == "" {
+		return "inline"
+	}
+	parsed, err := url.Parse(blocked)
+	if err != nil || parsed.Host == "" {
+		return blocked
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidDashboard is returned when a dashboard or one of its widgets fails validation
+var ErrInvalidDashboard = errors.New("invalid dashboard")
+
+const (
+	maxDashboardWidgets = 50
+	maxTrendPoints      = 1440
+	maxTopErrorsLimit   = 50
+
+	defaultWidgetTimeWindow = "24h"
+	defaultTopErrorsLimit   = 10
+)
+
+var trendIntervals = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+type DashboardService struct {
+	db    *database.DB
+	redis *redis.Client
+}
+
+func NewDashboardService(db *database.DB, redis *redis.Client) *DashboardService {
+	return &DashboardService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+func (s *DashboardService) GetDashboards(ctx context.Context) ([]models.Dashboard, error) {
+	return s.db.GetDashboards()
+}
+
+func (s *DashboardService) GetDashboard(ctx context.Context, id uuid.UUID) (*models.Dashboard, error) {
+	return s.db.GetDashboardByID(id)
+}
+
+func (s *DashboardService) CreateDashboard(ctx context.Context, req *models.CreateDashboardRequest) (*models.Dashboard, error) {
+	if err := validateDashboard(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	dashboard := &models.Dashboard{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		Widgets:     req.Widgets,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.db.CreateDashboard(dashboard); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+func (s *DashboardService) UpdateDashboard(ctx context.Context, id uuid.UUID, req *models.CreateDashboardRequest) (*models.Dashboard, error) {
+	dashboard, err := s.db.GetDashboardByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateDashboard(req); err != nil {
+		return nil, err
+	}
+
+	dashboard.Name = req.Name
+	dashboard.Description = req.Description
+	dashboard.Widgets = req.Widgets
+	dashboard.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateDashboard(dashboard); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+// UpdateLayout moves and resizes widgets without touching their queries.
+// Widgets missing from the request keep their position.
+func (s *DashboardService) UpdateLayout(ctx context.Context, id uuid.UUID, req *models.UpdateDashboardLayoutRequest) (*models.Dashboard, error) {
+	dashboard, err := s.db.GetDashboardByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(dashboard.Widgets))
+	for _, widget := range dashboard.Widgets {
+		known[widget.ID] = true
+	}
+	for widgetID, layout := range req.Layout {
+		if !known[widgetID] {
+			return nil, fmt.Errorf("%w: unknown widget %q", ErrInvalidDashboard, widgetID)
+		}
+		if err := validateWidgetLayout(&layout); err != nil {
+			return nil, err
+		}
+		req.Layout[widgetID] = layout
+	}
+
+	for i := range dashboard.Widgets {
+		if layout, ok := req.Layout[dashboard.Widgets[i].ID]; ok {
+			dashboard.Widgets[i].Layout = layout
+		}
+	}
+	dashboard.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateDashboard(dashboard); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+func (s *DashboardService) DeleteDashboard(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.GetDashboardByID(id); err != nil {
+		return err
+	}
+	return s.db.DeleteDashboard(id)
+}
+
+// GetDashboardData computes every widget of a dashboard in one call. A failing
+// widget reports its error without failing the others.
+func (s *DashboardService) GetDashboardData(ctx context.Context, id uuid.UUID) (*models.DashboardDataResponse, error) {
+	db := s.db.WithContext(ctx)
+	dashboard, err := db.GetDashboardByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.DashboardDataResponse{
+		Dashboard:   *dashboard,
+		Widgets:     make([]models.DashboardWidgetData, 0, len(dashboard.Widgets)),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	// Monitor widgets share one read of the alert rules
+	var rules []models.AlertRule
+	var rulesErr error
+	rulesLoaded := false
+
+	for _, widget := range dashboard.Widgets {
+		data := models.DashboardWidgetData{WidgetID: widget.ID, Type: widget.Type}
+		window := parseTimeWindow(widget.TimeWindow)
+
+		switch widget.Type {
+		case "stat":
+			var values map[string]float64
+			if values, err = db.EvaluateAlertMetrics([]string{widget.Metric}, widget.Scope, window); err == nil {
+				value := values[widget.Metric]
+				data.Value = &value
+			}
+		case "trend":
+			data.Trend, err = db.GetScopedTrend(widget.Scope, window, widget.Interval)
+		case "top_errors":
+			data.TopErrors, err = db.GetTopErrorGroups(widget.Scope, window, widget.Limit)
+		case "monitor_status":
+			if !rulesLoaded {
+				rules, rulesErr = db.GetAlertRules()
+				rulesLoaded = true
+			}
+			err = rulesErr
+			if err == nil {
+				data.Monitors = monitorStatuses(rules, widget.RuleIDs)
+			}
+		default:
+			err = fmt.Errorf("unknown widget type %q", widget.Type)
+		}
+
+		if err != nil {
+			log.Printf("Failed to compute widget %s on dashboard %s: %v", widget.ID, dashboard.ID, err)
+			data.Error = "failed to load widget data"
+		}
+		response.Widgets = append(response.Widgets, data)
+	}
+
+	return response, nil
+}
+
+// monitorStatuses lists the given rules, or every rule when ruleIDs is empty
+func monitorStatuses(rules []models.AlertRule, ruleIDs []uuid.UUID) []models.MonitorStatus {
+	wanted := make(map[uuid.UUID]bool, len(ruleIDs))
+	for _, id := range ruleIDs {
+		wanted[id] = true
+	}
+
+	statuses := []models.MonitorStatus{}
+	for _, rule := range rules {
+		if len(wanted) > 0 && !wanted[rule.ID] {
+			continue
+		}
+		statuses = append(statuses, models.MonitorStatus{
+			RuleID:        rule.ID,
+			Name:          rule.Name,
+			Enabled:       rule.Enabled,
+			State:         rule.State,
+			LastTriggered: rule.LastTriggered,
+			IncidentID:    rule.IncidentID,
+		})
+	}
+	return statuses
+}
+
+// validateDashboard checks a dashboard and fills in widget defaults
+func validateDashboard(req *models.CreateDashboardRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidDashboard)
+	}
+	if len(req.Name) > 255 {
+		return fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidDashboard)
+	}
+	if len(req.Widgets) > maxDashboardWidgets {
+		return fmt.Errorf("%w: at most %d widgets per dashboard", ErrInvalidDashboard, maxDashboardWidgets)
+	}
+	if req.Widgets == nil {
+		req.Widgets = []models.DashboardWidget{}
+	}
+
+	seen := make(map[string]bool, len(req.Widgets))
+	for i := range req.Widgets {
+		widget := &req.Widgets[i]
+		if widget.ID == "" {
+			widget.ID = uuid.New().String()
+		}
+		if seen[widget.ID] {
+			return fmt.Errorf("%w: duplicate widget id %q", ErrInvalidDashboard, widget.ID)
+		}
+		seen[widget.ID] = true
+
+		if err := validateWidget(widget); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateWidget(widget *models.DashboardWidget) error {
+	widget.Title = strings.TrimSpace(widget.Title)
+	if widget.TimeWindow == "" {
+		widget.TimeWindow = defaultWidgetTimeWindow
+	}
+
+	switch widget.Type {
+	case "stat":
+		if widget.Metric == "" {
+			widget.Metric = "error_count"
+		}
+		if !database.IsAlertMetric(widget.Metric) {
+			return fmt.Errorf("%w: widget %q: unknown metric %q", ErrInvalidDashboard, widget.ID, widget.Metric)
+		}
+	case "trend":
+		if widget.Interval == "" {
+			widget.Interval = "hour"
+		}
+		interval, ok := trendIntervals[widget.Interval]
+		if !ok {
+			return fmt.Errorf("%w: widget %q: interval must be minute, hour or day", ErrInvalidDashboard, widget.ID)
+		}
+		if parseTimeWindow(widget.TimeWindow)/interval > maxTrendPoints {
+			return fmt.Errorf("%w: widget %q: time_window is too long for a %s interval", ErrInvalidDashboard, widget.ID, widget.Interval)
+		}
+	case "top_errors":
+		if widget.Limit == 0 {
+			widget.Limit = defaultTopErrorsLimit
+		}
+		if widget.Limit < 0 || widget.Limit > maxTopErrorsLimit {
+			return fmt.Errorf("%w: widget %q: limit must be between 1 and %d", ErrInvalidDashboard, widget.ID, maxTopErrorsLimit)
+		}
+	case "monitor_status":
+	default:
+		return fmt.Errorf("%w: widget %q: type must be stat, trend, top_errors or monitor_status", ErrInvalidDashboard, widget.ID)
+	}
+
+	return validateWidgetLayout(&widget.Layout)
+}
+
+// validateWidgetLayout rejects negative positions and gives unsized widgets a default size
+func validateWidgetLayout(layout *models.WidgetLayout) error {
+	if layout.X < 0 || layout.Y < 0 || layout.W < 0 || layout.H < 0 {
+		return fmt.Errorf("%w: layout values must not be negative", ErrInvalidDashboard)
+	}
+	if layout.W == 0 {
+		layout.W = 4
+	}
+	if layout.H == 0 {
+		layout.H = 3
+	}
+	return nil
+}
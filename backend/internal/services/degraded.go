@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// recentErrorsLimit matches the length the recent-errors list is trimmed to
+const recentErrorsLimit = 100
+
+// recentErrors serves an error list from the recent-errors list in Redis while
+// the database is unavailable. The list holds the last events as they were
+// received, not the stored groups, so counts are per event and nothing is
+// resolved.
+func (s *ErrorService) recentErrors(ctx context.Context, limit, offset int, filter models.ErrorFilter) (*models.ErrorListResponse, error) {
+	recent, err := s.cache.GetRecentErrors(ctx, recentErrorsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]models.Error, 0, len(recent))
+	for _, e := range recent {
+		if eventMatches(&e, filter) {
+			matched = append(matched, e)
+		}
+	}
+
+	start := min(offset, len(matched))
+	end := min(offset+limit, len(matched))
+	return &models.ErrorListResponse{
+		Errors:   matched[start:end],
+		Total:    len(matched),
+		Page:     (offset / limit) + 1,
+		Limit:    limit,
+		Degraded: true,
+	}, nil
+}
+
+// recentError looks an event up in the recent-errors list while the database
+// is unavailable, returning dbErr when it is not there
+func (s *ErrorService) recentError(ctx context.Context, id uuid.UUID, dbErr error) (*models.Error, error) {
+	recent, err := s.cache.GetRecentErrors(ctx, recentErrorsLimit)
+	if err != nil {
+		return nil, dbErr
+	}
+	for i := range recent {
+		if recent[i].ID == id {
+			return &recent[i], nil
+		}
+	}
+	return nil, dbErr
+}
+
+// eventMatches applies an error list filter to a single event, for lists
+// served without the database and for live tail subscriptions
+func eventMatches(e *models.Error, filter models.ErrorFilter) bool {
+	if filter.Level != "" && e.Level != filter.Level {
+		return false
+	}
+	if filter.Source != "" && e.Source != filter.Source {
+		return false
+	}
+	if filter.Environment != "" && e.Environment != filter.Environment {
+		return false
+	}
+	// Resolution and watches are not known until the event is stored, and
+	// the organization of the event's project needs the database
+	if filter.Status == "resolved" || filter.WatchedBy != nil || (filter.State != "" && filter.State != "new") || filter.OrgID != nil {
+		return false
+	}
+	if filter.Region != "" && (e.Region == nil || *e.Region != filter.Region) {
+		return false
+	}
+	if filter.Deployment != "" && (e.Deployment == nil || *e.Deployment != filter.Deployment) {
+		return false
+	}
+	for key, value := range filter.Context {
+		v, ok := e.Context[key]
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	if filter.Search != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(filter.Search)) {
+		return false
+	}
+	if !messageMatches(e, filter) {
+		return false
+	}
+	for _, term := range filter.Query {
+		if !searchTermMatches(e, term) {
+			return false
+		}
+	}
+	return true
+}
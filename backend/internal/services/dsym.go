@@ -0,0 +1,195 @@
+package services
+
+import (
+	"debug/dwarf"
+	"debug/macho"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// lcUUID is the Mach-O load command carrying the binary's build UUID
+const lcUUID = 0x1b
+
+// Apple crash report frame: "3   MyApp   0x0000000104a8c5e0 0x104a84000 + 34272"
+var appleFramePattern = regexp.MustCompile(`^(\s*\d+\s+)(\S+)(\s+)(0x[0-9a-fA-F]+)\s+(?:0x[0-9a-fA-F]+|\S+)\s*\+\s*(\d+)\s*$`)
+
+type dwarfFunction struct {
+	name      string
+	low, high uint64
+}
+
+type dwarfUnit struct {
+	entry     *dwarf.Entry
+	low, high uint64
+}
+
+// dsymSymbols resolves addresses in one architecture slice of a dSYM
+type dsymSymbols struct {
+	buildID   string
+	textAddr  uint64
+	data      *dwarf.Data
+	functions []dwarfFunction // sorted by low
+	units     []dwarfUnit
+}
+
+// parseDSYM reads the DWARF binary inside a dSYM bundle
+// (Contents/Resources/DWARF/<name>). Fat files use the arm64 slice when present.
+func parseDSYM(r io.ReaderAt) (*dsymSymbols, error) {
+	file, err := openMachO(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := file.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("no DWARF data: %w", err)
+	}
+
+	symbols := &dsymSymbols{data: data}
+	if text := file.Segment("__TEXT"); text != nil {
+		symbols.textAddr = text.Addr
+	}
+	for _, load := range file.Loads {
+		raw := load.Raw()
+		if len(raw) >= 24 && file.ByteOrder.Uint32(raw) == lcUUID {
+			id, _ := uuid.FromBytes(raw[8:24])
+			symbols.buildID = strings.ToUpper(id.String())
+		}
+	}
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DWARF: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			ranges, _ := data.Ranges(entry)
+			for _, rng := range ranges {
+				symbols.units = append(symbols.units, dwarfUnit{entry: entry, low: rng[0], high: rng[1]})
+			}
+		case dwarf.TagSubprogram:
+			name, _ := entry.Val(dwarf.AttrName).(string)
+			if name == "" {
+				name, _ = entry.Val(dwarf.AttrLinkageName).(string)
+			}
+			if name == "" {
+				continue
+			}
+			ranges, _ := data.Ranges(entry)
+			for _, rng := range ranges {
+				symbols.functions = append(symbols.functions, dwarfFunction{name: name, low: rng[0], high: rng[1]})
+			}
+		}
+	}
+
+	if len(symbols.functions) == 0 {
+		return nil, fmt.Errorf("no functions found in DWARF data")
+	}
+
+	sort.Slice(symbols.functions, func(i, j int) bool {
+		return symbols.functions[i].low < symbols.functions[j].low
+	})
+
+	return symbols, nil
+}
+
+func openMachO(r io.ReaderAt) (*macho.File, error) {
+	fat, err := macho.NewFatFile(r)
+	if err == nil {
+		for _, arch := range fat.Arches {
+			if arch.Cpu == macho.CpuArm64 {
+				return arch.File, nil
+			}
+		}
+		return fat.Arches[0].File, nil
+	}
+
+	file, err := macho.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a Mach-O file: %w", err)
+	}
+	return file, nil
+}
+
+// lookup resolves a file-relative address to function, file, and line
+func (d *dsymSymbols) lookup(addr uint64) (string, string, int, bool) {
+	i := sort.Search(len(d.functions), func(i int) bool {
+		return d.functions[i].low > addr
+	}) - 1
+	if i < 0 || addr >= d.functions[i].high {
+		return "", "", 0, false
+	}
+	function := d.functions[i]
+
+	for _, unit := range d.units {
+		if addr < unit.low || addr >= unit.high {
+			continue
+		}
+		lines, err := d.data.LineReader(unit.entry)
+		if err != nil || lines == nil {
+			break
+		}
+		var entry dwarf.LineEntry
+		if err := lines.SeekPC(addr, &entry); err == nil && entry.File != nil {
+			return function.name, path.Base(entry.File.Name), entry.Line, true
+		}
+		break
+	}
+
+	return function.name, "", 0, true
+}
+
+// symbolicate rewrites Apple crash report frames from the named binary,
+// reporting whether anything changed
+func (d *dsymSymbols) symbolicate(stackTrace, binaryName string) (string, bool) {
+	lines := strings.Split(stackTrace, "\n")
+	changed := false
+
+	for i, line := range lines {
+		frame := appleFramePattern.FindStringSubmatch(line)
+		if frame == nil || !strings.EqualFold(frame[2], binaryName) {
+			continue
+		}
+
+		offset, err := strconv.ParseUint(frame[5], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		function, file, lineNumber, ok := d.lookup(d.textAddr + offset)
+		if !ok {
+			continue
+		}
+
+		symbol := function
+		if file != "" {
+			symbol += fmt.Sprintf(" (%s:%d)", file, lineNumber)
+		}
+		lines[i] = frame[1] + frame[2] + frame[3] + frame[4] + " " + symbol
+		changed = true
+	}
+
+	return strings.Join(lines, "\n"), changed
+}
+
+// dsymBinaryName normalizes "MyApp.app.dSYM" or a DWARF path to "MyApp"
+func dsymBinaryName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	for _, suffix := range []string{".dSYM", ".app", ".framework"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// traceDuplicateWindow is how far back an event from another source for the
+// same trace is looked for
+const traceDuplicateWindow = time.Hour
+
+// linkTraceDuplicate links an event to the same error already reported by
+// another source for the same trace, such as a client SDK and the server SDK
+// handling its request, so the error is counted once in stats
+func (s *ErrorService) linkTraceDuplicate(error *models.Error) {
+	if error.TraceID == nil || error.Fingerprint == nil {
+		return
+	}
+
+	original, err := s.db.FindTraceDuplicate(*error.Fingerprint, *error.TraceID, error.Source, error.Timestamp.Add(-traceDuplicateWindow))
+	if err != nil {
+		log.Printf("Failed to check trace duplicates: %v", err)
+		return
+	}
+	if original == nil {
+		return
+	}
+
+	log.Printf("TRACE DUPLICATE: %s event %s linked to %s (trace %s)", error.Source, error.ID, *original, *error.TraceID)
+	error.DuplicateOf = original
+}
@@ -3,8 +3,13 @@ package services
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,21 +19,90 @@ import (
 	"error-logs/internal/redis"
 )
 
+// trashPurgeInterval is how often errors past the trash retention are purged
+const trashPurgeInterval = time.Hour
+
+// ErrIngestUnavailable is returned when an event can be neither queued nor
+// written directly, because the queue is full or Redis is down and the
+// database is unavailable
+var ErrIngestUnavailable = errors.New("event could not be queued or stored")
+
+// queueWarnPercent is how full the queue gets before senders are warned
+const queueWarnPercent = 80
+
+// EventLevels are the levels an error event may have, most severe first
+var EventLevels = []string{"critical", "error", "warning", "info", "debug"}
+
 type ErrorService struct {
-	db    *database.DB
-	redis *redis.Client
+	db           ErrorStore
+	cache        CacheClient
+	queue        Queue
+	throttle     *ThrottleService
+	ownership    *OwnershipService
+	contextIndex *ContextIndexService
+	schemas      *ContextSchemaService
+	symbols      *SymbolicationService
+	audit        *AuditService
+	alerts       *AlertsService
+	readOnly     *ReadOnlyService
+	watches      *WatchService
+	forwarder    *ForwardingService
+	search       *SearchIndexService
+	tail         *TailService
+
+	maxQueueLength int64
+	queueLength    atomic.Int64
+	contextLimits  ContextLimits
+
+	goInAppPrefixes []string
 }
 
-func NewErrorService(db *database.DB, redis *redis.Client) *ErrorService {
+func NewErrorService(db ErrorStore, cache CacheClient, queue Queue, throttle *ThrottleService, ownership *OwnershipService, contextIndex *ContextIndexService, schemas *ContextSchemaService, symbols *SymbolicationService, audit *AuditService, alerts *AlertsService, readOnly *ReadOnlyService, watches *WatchService, forwarder *ForwardingService, search *SearchIndexService, tail *TailService, maxQueueLength int, contextLimits ContextLimits, goInAppPrefixes []string) *ErrorService {
 	return &ErrorService{
-		db:    db,
-		redis: redis,
+		db:           db,
+		cache:        cache,
+		queue:        queue,
+		throttle:     throttle,
+		ownership:    ownership,
+		contextIndex: contextIndex,
+		schemas:      schemas,
+		symbols:      symbols,
+		audit:        audit,
+		alerts:       alerts,
+		readOnly:     readOnly,
+		watches:      watches,
+		forwarder:    forwarder,
+		search:       search,
+		tail:         tail,
+
+		maxQueueLength: int64(maxQueueLength),
+		contextLimits:  contextLimits,
+
+		goInAppPrefixes: goInAppPrefixes,
 	}
 }
 
-func (s *ErrorService) CreateError(ctx context.Context, req *models.CreateErrorRequest, userAgent, ipAddress string) (*models.Error, error) {
+func (s *ErrorService) CreateError(ctx context.Context, req *models.CreateErrorRequest, projectID *uuid.UUID, userAgent, ipAddress string) (*models.Error, error) {
+	// Mobile traces must be symbolicated before they are fingerprinted
+	s.symbols.Symbolicate(req, projectID)
+
+	var fingerprint string
+	if stack, ok := s.parseGoPanic(req); ok {
+		fingerprint = stack.fingerprint(req.Message)
+	} else {
+		fingerprint = generateFingerprint(req.Message, req.StackTrace)
+	}
+
+	return s.recordEvent(ctx, req, fingerprint, projectID, userAgent, ipAddress)
+}
+
+// recordEvent throttles and queues an event whose fingerprint is already known
+func (s *ErrorService) recordEvent(ctx context.Context, req *models.CreateErrorRequest, fingerprint string, projectID *uuid.UUID, userAgent, ipAddress string) (*models.Error, error) {
 	now := time.Now().UTC()
-	fingerprint := generateFingerprint(req.Message, req.StackTrace)
+
+	if !s.throttle.Allow(ctx, fingerprint, req.Source) {
+		return nil, ErrEventThrottled
+	}
 
 	error := &models.Error{
 		ID:          uuid.New(),
@@ -42,6 +116,10 @@ func (s *ErrorService) CreateError(ctx context.Context, req *models.CreateErrorR
 		UserAgent:   &userAgent,
 		IPAddress:   &ipAddress,
 		URL:         req.URL,
+		Release:     req.Release,
+		Region:      nonEmpty(req.Region),
+		Deployment:  nonEmpty(req.Deployment),
+		ProjectID:   projectID,
 		Fingerprint: &fingerprint,
 		Resolved:    false,
 		Count:       1,
@@ -58,39 +136,91 @@ func (s *ErrorService) CreateError(ctx context.Context, req *models.CreateErrorR
 	if error.Context == nil {
 		error.Context = make(map[string]interface{})
 	}
+	// Violations describe the context as sent, before it is truncated
+	error.SchemaViolations = s.schemas.Validate(projectID, error.Context)
+	s.contextLimits.Apply(error.Context)
 
-	if err := s.redis.QueueError(ctx, error); err != nil {
+	length, err := s.queue.QueueError(ctx, error, s.maxQueueLength)
+	if err != nil {
 		log.Printf("Failed to queue error to Redis: %v", err)
+		if err == redis.ErrQueueFull {
+			s.queueLength.Store(length)
+		}
+		if s.readOnly.Enabled(ctx) {
+			return nil, ErrReadOnly
+		}
+		s.prepareForStorage(error)
 		if err := s.db.CreateError(error); err != nil {
+			if database.IsUnavailable(err) {
+				return nil, ErrIngestUnavailable
+			}
 			return nil, err
 		}
+		s.recordFirstSeen(error)
+		go s.watches.NotifyStored(error)
+		go s.forwarder.Forward(error)
+		s.search.Index(error)
+		go s.tail.Publish(error)
 		log.Printf("CACHE INVALIDATION: CreateError (fallback) - invalidating all caches")
-		s.redis.InvalidateAllCache(context.Background())
+		s.cache.InvalidateAllCache(context.Background())
 		return error, nil
 	}
+	s.queueLength.Store(length)
 
 	log.Printf("CACHE INVALIDATION: CreateError - invalidating all caches")
-	s.redis.InvalidateAllCache(context.Background())
+	s.cache.InvalidateAllCache(context.Background())
 	return error, nil
 }
 
-func (s *ErrorService) GetErrors(ctx context.Context, limit, offset int, level, source string) (*models.ErrorListResponse, error) {
-	cacheKey := fmt.Sprintf("list_%d_%d_%s_%s", limit, offset, level, source)
+// store is the error store bound to ctx, so a request's queries stop once
+// its time budget is spent
+func (s *ErrorService) store(ctx context.Context) ErrorStore {
+	return s.db.WithContext(ctx)
+}
+
+func (s *ErrorService) GetErrors(ctx context.Context, limit, offset int, filter models.ErrorFilter) (*models.ErrorListResponse, error) {
+	if err := s.contextIndex.CheckIndexed(filter.Context); err != nil {
+		return nil, err
+	}
+	if err := s.contextIndex.CheckIndexed(searchQueryContext(filter.Query)); err != nil {
+		return nil, err
+	}
+	if err := checkMessageFilter(filter); err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("list_%d_%d_%s", limit, offset, filterCacheKey(filter))
 	start := time.Now()
 
-	if cachedErrors, err := s.redis.GetCachedErrorList(ctx, cacheKey); err == nil && cachedErrors != nil {
+	if cachedErrors, cachedTotal, err := s.cache.GetCachedErrorList(ctx, cacheKey); err == nil && cachedErrors != nil {
 		log.Printf("CACHE HIT: GetErrors - key: %s, duration: %v", cacheKey, time.Since(start))
 		return &models.ErrorListResponse{
 			Errors: cachedErrors,
-			Total:  len(cachedErrors) + offset,
+			Total:  cachedTotal,
 			Page:   (offset / limit) + 1,
 			Limit:  limit,
 		}, nil
 	}
 
 	log.Printf("CACHE MISS: GetErrors - key: %s, fetching from database", cacheKey)
-	errors, total, err := s.db.GetErrors(limit, offset, level, source)
+	errors, total, err := s.listErrors(ctx, limit, offset, filter)
 	if err != nil {
+		if database.IsStatementTimeout(err) && filter.MessageMatch == "regex" {
+			return nil, ErrMessageRegexTimeout
+		}
+		if database.IsUnavailable(err) {
+			log.Printf("DATABASE UNAVAILABLE: GetErrors - serving recent errors")
+			return s.recentErrors(ctx, limit, offset, filter)
+		}
+		return nil, err
+	}
+	if err := s.attachRunbooks(errors); err != nil {
+		return nil, err
+	}
+	if err := s.attachImpactScores(errors); err != nil {
+		return nil, err
+	}
+	if err := s.attachSuppressedCounts(errors); err != nil {
 		return nil, err
 	}
 
@@ -102,7 +232,7 @@ func (s *ErrorService) GetErrors(ctx context.Context, limit, offset int, level,
 			cacheStart := time.Now()
 			// Use background context to avoid cancellation when HTTP request ends
 			cacheCtx := context.Background()
-			if err := s.redis.CacheErrorList(cacheCtx, cacheKey, errors, 2*time.Minute); err != nil {
+			if err := s.cache.CacheErrorList(cacheCtx, cacheKey, errors, total, 2*time.Minute); err != nil {
 				log.Printf("Failed to cache error list: %v", err)
 			} else {
 				log.Printf("CACHE WRITE: GetErrors - key: %s, duration: %v", cacheKey, time.Since(cacheStart))
@@ -118,38 +248,379 @@ func (s *ErrorService) GetErrors(ctx context.Context, limit, offset int, level,
 	}, nil
 }
 
+// listErrors reads a page of errors. Text searches go to OpenSearch when it is
+// enabled, with the events themselves read back from the database; if
+// OpenSearch fails the database answers instead.
+func (s *ErrorService) listErrors(ctx context.Context, limit, offset int, filter models.ErrorFilter) ([]models.Error, int, error) {
+	if s.search.Handles(filter) {
+		ids, total, err := s.search.Search(ctx, filter, limit, offset)
+		if err == nil {
+			errors, err := s.store(ctx).GetErrorsByIDs(ids)
+			return errors, total, err
+		}
+		log.Printf("SEARCH FALLBACK: GetErrors - %v", err)
+	}
+	return s.store(ctx).GetErrors(limit, offset, filter)
+}
+
+func (s *ErrorService) errorFacets(ctx context.Context, filter models.ErrorFilter) (*models.ErrorFacetsResponse, error) {
+	if s.search.Handles(filter) {
+		facets, err := s.search.Facets(ctx, filter)
+		if err == nil {
+			return facets, nil
+		}
+		log.Printf("SEARCH FALLBACK: GetErrorFacets - %v", err)
+	}
+	return s.store(ctx).GetErrorFacets(filter)
+}
+
+// GetErrorsByTraceID returns the events of a trace, only those of callerOrg's
+// projects when it is set
+func (s *ErrorService) GetErrorsByTraceID(ctx context.Context, traceID string, callerOrg *uuid.UUID) (*models.TraceErrorsResponse, error) {
+	errors, err := s.store(ctx).GetErrorsByTraceID(callerOrg, traceID, 500)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []string{}
+	seen := make(map[string]bool)
+	for _, e := range errors {
+		if !seen[e.Source] {
+			seen[e.Source] = true
+			sources = append(sources, e.Source)
+		}
+	}
+
+	return &models.TraceErrorsResponse{
+		TraceID: traceID,
+		Sources: sources,
+		Errors:  errors,
+	}, nil
+}
+
+func (s *ErrorService) GetErrorFacets(ctx context.Context, filter models.ErrorFilter) (*models.ErrorFacetsResponse, error) {
+	if err := s.contextIndex.CheckIndexed(filter.Context); err != nil {
+		return nil, err
+	}
+
+	cacheKey := "facets_" + filterCacheKey(filter)
+
+	if cachedFacets, err := s.cache.GetCachedErrorFacets(ctx, cacheKey); err == nil && cachedFacets != nil {
+		log.Printf("CACHE HIT: GetErrorFacets - key: %s", cacheKey)
+		return cachedFacets, nil
+	}
+
+	log.Printf("CACHE MISS: GetErrorFacets - key: %s, fetching from database", cacheKey)
+	facets, err := s.errorFacets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		cacheCtx := context.Background()
+		if err := s.cache.CacheErrorFacets(cacheCtx, cacheKey, facets, 2*time.Minute); err != nil {
+			log.Printf("Failed to cache error facets: %v", err)
+		}
+	}()
+
+	return facets, nil
+}
+
 func (s *ErrorService) GetErrorByID(ctx context.Context, id uuid.UUID) (*models.Error, error) {
-	return s.db.GetErrorByID(id)
+	store := s.store(ctx)
+	error, err := store.GetErrorByID(id)
+	if err != nil {
+		if database.IsUnavailable(err) {
+			return s.recentError(ctx, id, err)
+		}
+		return nil, err
+	}
+	if error.Fingerprint != nil {
+		if error.Runbook, err = store.GetErrorGroupRunbook(*error.Fingerprint); err != nil {
+			return nil, err
+		}
+		scores, err := store.GetErrorGroupImpacts([]string{*error.Fingerprint})
+		if err != nil {
+			return nil, err
+		}
+		if score, ok := scores[*error.Fingerprint]; ok {
+			error.ImpactScore = &score
+		}
+		suppressed, err := store.GetSuppressedCounts([]string{*error.Fingerprint})
+		if err != nil {
+			return nil, err
+		}
+		error.SuppressedCount = suppressed[*error.Fingerprint]
+	}
+	return error, nil
+}
+
+// SetErrorRunbook attaches a runbook to the group the error belongs to
+func (s *ErrorService) SetErrorRunbook(ctx context.Context, id uuid.UUID, runbook *models.Runbook) (*models.Runbook, error) {
+	fingerprint, err := s.groupFingerprint(id)
+	if err != nil {
+		return nil, err
+	}
+
+	runbook, err = normalizeRunbook(runbook)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRunbook, err)
+	}
+	if runbook == nil {
+		return nil, fmt.Errorf("%w: url or notes is required", ErrInvalidRunbook)
+	}
+
+	if err := s.db.SetErrorGroupRunbook(fingerprint, runbook); err != nil {
+		return nil, err
+	}
+	go s.cache.InvalidateAllCache(context.Background())
+	return runbook, nil
 }
 
-func (s *ErrorService) ResolveError(ctx context.Context, id uuid.UUID) error {
-	if err := s.db.ResolveError(id); err != nil {
+func (s *ErrorService) DeleteErrorRunbook(ctx context.Context, id uuid.UUID) error {
+	fingerprint, err := s.groupFingerprint(id)
+	if err != nil {
 		return err
 	}
+	if err := s.db.DeleteErrorGroupRunbook(fingerprint); err != nil {
+		return err
+	}
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+func (s *ErrorService) groupFingerprint(id uuid.UUID) (string, error) {
+	error, err := s.db.GetErrorByID(id)
+	if err != nil {
+		return "", err
+	}
+	if error.Fingerprint == nil {
+		return "", fmt.Errorf("%w: error has no group", ErrInvalidRunbook)
+	}
+	return *error.Fingerprint, nil
+}
+
+// attachRunbooks sets each error's group runbook
+func (s *ErrorService) attachRunbooks(errors []models.Error) error {
+	fingerprints := []string{}
+	for _, e := range errors {
+		if e.Fingerprint != nil {
+			fingerprints = append(fingerprints, *e.Fingerprint)
+		}
+	}
+
+	runbooks, err := s.db.GetErrorGroupRunbooks(fingerprints)
+	if err != nil {
+		return err
+	}
+	for i := range errors {
+		if errors[i].Fingerprint != nil {
+			errors[i].Runbook = runbooks[*errors[i].Fingerprint]
+		}
+	}
+	return nil
+}
+
+// attachSuppressedCounts sets how many events spike protection dropped from
+// each error's group
+func (s *ErrorService) attachSuppressedCounts(errors []models.Error) error {
+	fingerprints := []string{}
+	for _, e := range errors {
+		if e.Fingerprint != nil {
+			fingerprints = append(fingerprints, *e.Fingerprint)
+		}
+	}
+
+	counts, err := s.db.GetSuppressedCounts(fingerprints)
+	if err != nil {
+		return err
+	}
+	for i := range errors {
+		if errors[i].Fingerprint != nil {
+			errors[i].SuppressedCount = counts[*errors[i].Fingerprint]
+		}
+	}
+	return nil
+}
+
+// ResolveError resolves an event; actor is recorded as resolved_by. Like
+// ChangeErrorState, it refuses states that ErrorStateTransitions doesn't let
+// become resolved.
+func (s *ErrorService) ResolveError(ctx context.Context, id uuid.UUID, actor, note string) error {
+	_, err := s.transitionError(id, "resolved", func(from string) error {
+		return s.db.SetErrorState(id, from, "resolved", true, actor, optionalString(note))
+	})
+	if err != nil {
+		return err
+	}
+	s.search.Refresh(id)
+	s.audit.Record("error.resolved", actor, "error", id.String(), resolutionDetails(note, ""))
 	log.Printf("CACHE INVALIDATION: ResolveError - invalidating all caches for error ID: %s", id)
-	go s.redis.InvalidateAllCache(context.Background())
+	go s.cache.InvalidateAllCache(context.Background())
 	return nil
 }
 
-func (s *ErrorService) DeleteError(ctx context.Context, id uuid.UUID) error {
-	if err := s.db.DeleteError(id); err != nil {
+// ResolveErrorInRelease resolves an event's group until the release ships.
+// The event must be in a state that may become resolved. The rest of the
+// group is resolved where its state allows it, and events already resolved
+// wait for the release too; ignored events are left alone. With callerOrg
+// set, the group is only resolved in that organization.
+func (s *ErrorService) ResolveErrorInRelease(ctx context.Context, id uuid.UUID, release string, callerOrg *uuid.UUID, actor, note string) error {
+	groupFrom := append(statesMovingTo("resolved"), "resolved")
+	_, err := s.transitionError(id, "resolved", func(from string) error {
+		return s.db.ResolveErrorInRelease(id, from, groupFrom, callerOrg, release, actor, optionalString(note))
+	})
+	if err != nil {
 		return err
 	}
+	s.search.Refresh(id)
+	s.audit.Record("error.resolved", actor, "error", id.String(), resolutionDetails(note, release))
+	log.Printf("CACHE INVALIDATION: ResolveErrorInRelease - invalidating all caches for error ID: %s", id)
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+// UnresolveError reopens a resolved or ignored event and clears its
+// resolution. A group resolved in a release is reopened only in callerOrg
+// when it is set.
+func (s *ErrorService) UnresolveError(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID, actor, note string) error {
+	_, err := s.transitionError(id, "new", func(from string) error {
+		return s.db.UnresolveError(id, from, callerOrg)
+	})
+	if err != nil {
+		return err
+	}
+	s.search.Refresh(id)
+	s.audit.Record("error.unresolved", actor, "error", id.String(), resolutionDetails(note, ""))
+	log.Printf("CACHE INVALIDATION: UnresolveError - invalidating all caches for error ID: %s", id)
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+// DeleteError moves an error to the trash, from where it can be restored
+// until it is purged
+func (s *ErrorService) DeleteError(ctx context.Context, id uuid.UUID, actor string) error {
+	if err := s.db.TrashError(id); err != nil {
+		return err
+	}
+	s.search.Remove(id)
+	s.audit.Record("error.deleted", actor, "error", id.String(), nil)
 	log.Printf("CACHE INVALIDATION: DeleteError - invalidating all caches for error ID: %s", id)
-	go s.redis.InvalidateAllCache(context.Background())
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+func (s *ErrorService) RestoreError(ctx context.Context, id uuid.UUID, actor string) error {
+	if err := s.db.RestoreError(id); err != nil {
+		return err
+	}
+	s.search.Refresh(id)
+	s.audit.Record("error.restored", actor, "error", id.String(), nil)
+	log.Printf("CACHE INVALIDATION: RestoreError - invalidating all caches for error ID: %s", id)
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+// ErrorOrgID returns the organization an error belongs to through its
+// project, nil for errors of deployment-wide projects
+func (s *ErrorService) ErrorOrgID(ctx context.Context, id uuid.UUID) (*uuid.UUID, error) {
+	return s.store(ctx).GetErrorOrgID(id)
+}
+
+// PurgeError permanently removes an error from the trash
+func (s *ErrorService) PurgeError(ctx context.Context, id uuid.UUID, actor string) error {
+	if err := s.db.DeleteTrashedError(id); err != nil {
+		return err
+	}
+	s.audit.Record("error.purged", actor, "error", id.String(), nil)
 	return nil
 }
 
-func (s *ErrorService) GetStats(ctx context.Context) (*models.StatsResponse, error) {
+// GetTrash lists the trash, only the errors of callerOrg's projects when it
+// is set
+func (s *ErrorService) GetTrash(ctx context.Context, limit, offset int, callerOrg *uuid.UUID) (*models.ErrorListResponse, error) {
+	errors, total, err := s.store(ctx).GetTrashedErrors(callerOrg, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ErrorListResponse{
+		Errors: errors,
+		Total:  total,
+		Page:   (offset / limit) + 1,
+		Limit:  limit,
+	}, nil
+}
+
+// StartTrashPurger permanently deletes errors that have been in the trash
+// longer than retention, until ctx is done
+func (s *ErrorService) StartTrashPurger(ctx context.Context, retention time.Duration) {
+	log.Println("Starting trash purger...")
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().UTC().Add(-retention)
+		if purged, err := s.db.PurgeTrashedErrors(cutoff); err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("TRASH PURGE: deleted %d errors trashed before %s", purged, cutoff.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func resolutionDetails(note, release string) map[string]interface{} {
+	details := map[string]interface{}{}
+	if note != "" {
+		details["note"] = note
+	}
+	if release != "" {
+		details["release"] = release
+	}
+	return details
+}
+
+func nonEmpty(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// GetStats aggregates error counts for the filter. "Today" starts at midnight
+// in timezone, an IANA name or "" for UTC.
+func (s *ErrorService) GetStats(ctx context.Context, filter models.StatsFilter, timezone string) (*models.StatsResponse, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
+	cacheKey := statsCacheKeyIn(filter, loc)
+	recordCacheAccess(s.cache, cachedQuery{Kind: "stats", Filter: filter, Timezone: timezone})
 
-	if cachedStats, err := s.redis.GetCachedStats(ctx); err == nil && cachedStats != nil {
-		log.Printf("CACHE HIT: GetStats - duration: %v", time.Since(start))
+	if cachedStats, err := s.cache.GetCachedStats(ctx, cacheKey); err == nil && cachedStats != nil {
+		log.Printf("CACHE HIT: GetStats - key: %s, duration: %v", cacheKey, time.Since(start))
 		return cachedStats, nil
 	}
 
-	log.Printf("CACHE MISS: GetStats - fetching from database")
-	stats, err := s.db.GetStats()
+	log.Printf("CACHE MISS: GetStats - key: %s, fetching from database", cacheKey)
+	stats, err := s.store(ctx).GetStats(filter, loc.String())
 	if err != nil {
 		return nil, err
 	}
@@ -161,52 +632,271 @@ func (s *ErrorService) GetStats(ctx context.Context) (*models.StatsResponse, err
 		cacheStart := time.Now()
 		// Use background context to avoid cancellation when HTTP request ends
 		cacheCtx := context.Background()
-		if err := s.redis.CacheStats(cacheCtx, stats); err != nil {
+		if err := s.cache.CacheStats(cacheCtx, cacheKey, stats); err != nil {
 			log.Printf("Failed to cache stats: %v", err)
 		} else {
-			log.Printf("CACHE WRITE: GetStats - duration: %v", time.Since(cacheStart))
+			log.Printf("CACHE WRITE: GetStats - key: %s, duration: %v", cacheKey, time.Since(cacheStart))
 		}
 	}()
 
 	return stats, nil
 }
 
+// RefreshStats recomputes the stats and replaces the cached copy, whether or
+// not it has expired
+func (s *ErrorService) RefreshStats(ctx context.Context, filter models.StatsFilter, timezone string) error {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return err
+	}
+	stats, err := s.db.GetStats(filter, loc.String())
+	if err != nil {
+		return err
+	}
+	return s.cache.CacheStats(ctx, statsCacheKeyIn(filter, loc), stats)
+}
+
+// CacheGeneration changes whenever errors are written, resolved or deleted
+func (s *ErrorService) CacheGeneration(ctx context.Context) (int64, error) {
+	return s.cache.GetCacheGeneration(ctx, redis.ErrorsCacheGeneration)
+}
+
+// QueueNearlyFull reports whether the ingestion queue was close to its
+// maximum length when an event was last queued
+func (s *ErrorService) QueueNearlyFull() bool {
+	return s.maxQueueLength > 0 && s.queueLength.Load()*100 >= s.maxQueueLength*queueWarnPercent
+}
+
+// maxQueueBackoff caps how long the queue processor waits between attempts
+// while Redis or the database is unavailable
+const maxQueueBackoff = 30 * time.Second
+
 func (s *ErrorService) StartQueueProcessor(ctx context.Context) {
 	log.Println("Starting error queue processor...")
 
+	backoff := time.Duration(0)
+	wait := func() {
+		backoff = min(max(2*backoff, time.Second), maxQueueBackoff)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Queue processor stopped")
 			return
 		default:
-			error, err := s.redis.DequeueError(ctx)
+			// Leave events queued until read-only mode is turned off
+			if s.readOnly.Enabled(ctx) {
+				select {
+				case <-ctx.Done():
+				case <-time.After(readOnlyRefresh):
+				}
+				continue
+			}
+
+			error, err := s.queue.DequeueError(ctx)
 			if err != nil {
 				log.Printf("Failed to dequeue error: %v", err)
-				time.Sleep(1 * time.Second)
+				wait()
 				continue
 			}
 
 			if error == nil {
+				backoff = 0
 				continue // No error available
 			}
 
 			if err := s.processError(ctx, error); err != nil {
 				log.Printf("Failed to process error: %v", err)
+				// Keep the event until the database is back instead of dropping it
+				if database.IsUnavailable(err) {
+					if err := s.queue.RequeueError(ctx, error); err != nil {
+						log.Printf("Failed to requeue error: %v", err)
+					}
+					wait()
+				}
+				continue
 			}
+			backoff = 0
 		}
 	}
 }
 
 func (s *ErrorService) processError(ctx context.Context, error *models.Error) error {
+	s.prepareForStorage(error)
+	// Stamp when the event becomes visible so update cursors do not skip queued events
+	error.UpdatedAt = time.Now().UTC()
 	if err := s.db.CreateError(error); err != nil {
 		return err
 	}
+	s.recordFirstSeen(error)
+	go s.watches.NotifyStored(error)
+	go s.forwarder.Forward(error)
+	s.search.Index(error)
+	go s.tail.Publish(error)
 	log.Printf("CACHE INVALIDATION: processError - invalidating all caches for processed error")
-	go s.redis.InvalidateAllCache(context.Background())
+	go s.cache.InvalidateAllCache(context.Background())
+	return nil
+}
+
+// parseGoPanic reduces a Go panic trace to the panicking goroutine without
+// runtime frames and records its parsed frames in the event context
+func (s *ErrorService) parseGoPanic(req *models.CreateErrorRequest) (*goStack, bool) {
+	if req.StackTrace == nil {
+		return nil, false
+	}
+
+	stack, ok := parseGoStack(*req.StackTrace, s.goInAppPrefixes)
+	if !ok {
+		return nil, false
+	}
+
+	trace := stack.render()
+	req.StackTrace = &trace
+	if req.Context == nil {
+		req.Context = make(map[string]interface{})
+	}
+	req.Context["go_stack"] = stack
+
+	return stack, true
+}
+
+// prepareForStorage derives the stored fields of an event right before it is
+// written, both from the queue processor and the direct-write fallback
+func (s *ErrorService) prepareForStorage(error *models.Error) {
+	s.applyReleaseResolution(error)
+	if !error.Resolved && !error.Regressed {
+		s.applyAutoResolution(error)
+	}
+	error.State = initialErrorState(error)
+	s.ownership.AssignOwner(error)
+	s.contextIndex.Extract(error)
+	error.TraceID = extractTraceID(error.Context)
+	s.linkTraceDuplicate(error)
+}
+
+// traceContextKeys are the context keys checked, in order, for a correlation ID
+var traceContextKeys = []string{"trace_id", "traceId", "request_id", "requestId"}
+
+func extractTraceID(errorContext map[string]interface{}) *string {
+	for _, key := range traceContextKeys {
+		value, ok := errorContext[key]
+		if !ok || value == nil {
+			continue
+		}
+		traceID := stringifyContextValue(value)
+		if traceID == "" {
+			continue
+		}
+		if shortened, cut := shortenContextValue(traceID, 128); cut {
+			traceID = shortened
+		}
+		return &traceID
+	}
 	return nil
 }
 
+// applyReleaseResolution keeps an event resolved when its fingerprint was
+// resolved in a newer release, and reopens the group as a regression otherwise
+func (s *ErrorService) applyReleaseResolution(error *models.Error) {
+	if error.Fingerprint == nil {
+		return
+	}
+
+	release, err := s.db.GetPendingReleaseResolution(*error.Fingerprint)
+	if err != nil {
+		log.Printf("Failed to check release resolution: %v", err)
+		return
+	}
+	if release == nil {
+		return
+	}
+
+	if error.Release != nil && compareReleases(*error.Release, *release) >= 0 {
+		log.Printf("REGRESSION: fingerprint %s seen in release %s (resolved in %s)", *error.Fingerprint, *error.Release, *release)
+		if err := s.db.ReopenRegression(*error.Fingerprint); err != nil {
+			log.Printf("Failed to reopen regression: %v", err)
+		} else {
+			go s.search.ReopenGroup(*error.Fingerprint)
+			s.audit.Record("error.regressed", SystemActor, "error_group", *error.Fingerprint, map[string]interface{}{
+				"release":             *error.Release,
+				"resolved_in_release": *release,
+			})
+		}
+		error.Regressed = true
+		return
+	}
+
+	error.Resolved = true
+	error.ResolvedInRelease = release
+}
+
+// filterCacheKey builds a stable cache key fragment from the list filters
+func filterCacheKey(filter models.ErrorFilter) string {
+	key := fmt.Sprintf("%s_%s_%s_%s_%s_%s", filter.Level, filter.Source, filter.Environment, filter.Status, filter.Region, filter.Deployment)
+
+	contextKeys := make([]string, 0, len(filter.Context))
+	for k := range filter.Context {
+		contextKeys = append(contextKeys, k)
+	}
+	sort.Strings(contextKeys)
+	for _, k := range contextKeys {
+		key += "_" + k + "=" + filter.Context[k]
+	}
+	if filter.WatchedBy != nil {
+		key += "_watched=" + filter.WatchedBy.String()
+	}
+	if filter.State != "" {
+		key += "_state=" + filter.State
+	}
+	if filter.Sort != "" {
+		key += "_sort=" + filter.Sort
+	}
+	if filter.Search != "" {
+		key += fmt.Sprintf("_search=%q_fuzzy=%t", filter.Search, filter.Fuzzy)
+	}
+	if filter.Message != "" {
+		key += fmt.Sprintf("_message=%q_%s_%t", filter.Message, filter.MessageMatch, filter.MessageNegate)
+	}
+	for _, term := range filter.Query {
+		key += fmt.Sprintf("_q=%t:%s:%q", term.Negate, term.Field, term.Value)
+	}
+	if filter.OrgID != nil {
+		key += "_org=" + filter.OrgID.String()
+	}
+
+	return key
+}
+
+// statsCacheKey is empty for unscoped stats
+func statsCacheKey(filter models.StatsFilter) string {
+	if filter == (models.StatsFilter{}) {
+		return ""
+	}
+	project := ""
+	if filter.ProjectID != nil {
+		project = filter.ProjectID.String()
+	}
+	key := fmt.Sprintf("%s_%s_%s_%s_%s", project, filter.Environment, filter.Source, filter.Region, filter.Deployment)
+	if filter.OrgID != nil {
+		key += "_org=" + filter.OrgID.String()
+	}
+	return key
+}
+
+// statsCacheKeyIn is the cache key of the stats in a timezone
+func statsCacheKeyIn(filter models.StatsFilter, loc *time.Location) string {
+	key := statsCacheKey(filter)
+	if loc != time.UTC {
+		key += "_tz=" + loc.String()
+	}
+	return key
+}
+
 func generateFingerprint(message string, stackTrace *string) string {
 	data := message
 	if stackTrace != nil {
@@ -215,3 +905,84 @@ func generateFingerprint(message string, stackTrace *string) string {
 	hash := sha256.Sum256([]byte(data))
 	return fmt.Sprintf("%x", hash)[:16]
 }
+
+// compareReleases orders versions such as "1.10.2" or "v2.0.0-rc.1" by
+// semver precedence: numeric parts compare as numbers and missing ones count
+// as 0, so "1.0" equals "1.0.0"; a pre-release sorts before its release; and
+// build metadata after "+" is ignored
+func compareReleases(a, b string) int {
+	coreA, preA := splitRelease(a)
+	coreB, preB := splitRelease(b)
+
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		partA, partB := "0", "0"
+		if i < len(coreA) {
+			partA = coreA[i]
+		}
+		if i < len(coreB) {
+			partB = coreB[i]
+		}
+		if c := compareReleaseIdentifiers(partA, partB); c != 0 {
+			return c
+		}
+	}
+
+	// A release ranks above its pre-releases
+	switch {
+	case preA == nil && preB == nil:
+		return 0
+	case preA == nil:
+		return 1
+	case preB == nil:
+		return -1
+	}
+	for i := 0; i < len(preA) && i < len(preB); i++ {
+		if c := compareReleaseIdentifiers(preA[i], preB[i]); c != 0 {
+			return c
+		}
+	}
+	// With an equal prefix, more identifiers rank higher: rc.1 < rc.1.1
+	switch {
+	case len(preA) < len(preB):
+		return -1
+	case len(preA) > len(preB):
+		return 1
+	}
+	return 0
+}
+
+// splitRelease splits a version into its dotted core and its pre-release
+// identifiers, which are nil for a release
+func splitRelease(version string) (core, prerelease []string) {
+	version = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(version), "v"), "V")
+	version, _, _ = strings.Cut(version, "+")
+	version, pre, hasPre := strings.Cut(version, "-")
+
+	core = strings.Split(version, ".")
+	if hasPre {
+		prerelease = strings.Split(pre, ".")
+	}
+	return core, prerelease
+}
+
+// compareReleaseIdentifiers compares one dot-separated part of two versions:
+// numbers numerically, other parts in ASCII order, and numbers before others
+func compareReleaseIdentifiers(a, b string) int {
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if numA < numB {
+			return -1
+		}
+		if numA > numB {
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,42 @@
+package services
+
+import "testing"
+
+func TestCompareReleases(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1", "1.0.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3+build.7", "1.2.3", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0", "1.0.1", -1},
+		{"2.0.0-rc1", "2.0.0", -1},
+		{"2.0.0", "2.0.0-rc1", 1},
+		{"2.0-beta", "2.0.0", -1},
+		{"2.0.0-rc.2", "2.0.0-rc.10", -1},
+		{"2.0.0-alpha", "2.0.0-alpha.1", -1},
+		{"2.0.0-alpha.1", "2.0.0-alpha.beta", -1},
+		{"2.0.0-alpha.beta", "2.0.0-beta", -1},
+		{"2.0.0-rc.1", "2.0.0-rc.1+build.5", 0},
+		{"2.0.0-rc1", "1.9.9", 1},
+		{"2.0.0-rc1", "2.0.1-rc1", -1},
+		{"2024.05.01", "2024.5.2", -1},
+		{"abc", "abd", -1},
+		{"1.0.0", "1.0.0a", -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareReleases(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareReleases(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := compareReleases(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareReleases(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+var (
+	// ErrInvalidErrorState is returned for a state that is not one of
+	// models.ErrorStates
+	ErrInvalidErrorState = errors.New("invalid error state")
+	// ErrInvalidStateTransition is returned when an error can't move from its
+	// current state to the one asked for
+	ErrInvalidStateTransition = errors.New("invalid state transition")
+)
+
+// ErrorStateTransitions lists the states each state may move to through the
+// API. regressed is only entered by the server, when a resolved group sees
+// new events.
+var ErrorStateTransitions = map[string][]string{
+	"new":          {"acknowledged", "in_progress", "resolved", "ignored"},
+	"acknowledged": {"in_progress", "resolved", "ignored"},
+	"in_progress":  {"acknowledged", "resolved", "ignored"},
+	"resolved":     {"new"},
+	"ignored":      {"new"},
+	"regressed":    {"acknowledged", "in_progress", "resolved", "ignored"},
+}
+
+// closedErrorStates are the states in which an error counts as resolved
+var closedErrorStates = []string{"resolved", "ignored"}
+
+// CheckErrorState validates a state filter
+func CheckErrorState(state string) error {
+	if state != "" && !slices.Contains(models.ErrorStates, state) {
+		return fmt.Errorf("%w: state must be one of %s", ErrInvalidErrorState, strings.Join(models.ErrorStates, ", "))
+	}
+	return nil
+}
+
+// ChangeErrorState moves an error to another lifecycle state if its current
+// state allows it, and returns the updated error
+func (s *ErrorService) ChangeErrorState(ctx context.Context, id uuid.UUID, req *models.ChangeErrorStateRequest, actor string) (*models.Error, error) {
+	if req.State == "" {
+		return nil, fmt.Errorf("%w: state is required", ErrInvalidErrorState)
+	}
+	if err := CheckErrorState(req.State); err != nil {
+		return nil, err
+	}
+
+	closed := slices.Contains(closedErrorStates, req.State)
+	from, err := s.transitionError(id, req.State, func(from string) error {
+		return s.db.SetErrorState(id, from, req.State, closed, actor, optionalString(req.Note))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.search.Refresh(id)
+	details := resolutionDetails(req.Note, "")
+	details["from"] = from
+	details["to"] = req.State
+	s.audit.Record("error.state_changed", actor, "error", id.String(), details)
+	log.Printf("CACHE INVALIDATION: ChangeErrorState - invalidating all caches for error ID: %s", id)
+	go s.cache.InvalidateAllCache(context.Background())
+
+	return s.db.GetErrorByID(id)
+}
+
+// transitionError moves an error from its current state to state to, if
+// ErrorStateTransitions allows it, and returns the state it left. apply
+// writes the change; it must only apply while the error is still in from and
+// report "error not found" otherwise.
+func (s *ErrorService) transitionError(id uuid.UUID, to string, apply func(from string) error) (string, error) {
+	error, err := s.db.GetErrorByID(id)
+	if err != nil {
+		return "", err
+	}
+	from := error.State
+	if !slices.Contains(ErrorStateTransitions[from], to) {
+		return "", fmt.Errorf("%w: %s errors can't become %s", ErrInvalidStateTransition, from, to)
+	}
+
+	if err := apply(from); err != nil {
+		if err.Error() == "error not found" {
+			// Trashed, or changed by someone else since it was read
+			return "", fmt.Errorf("%w: the error was changed, reload it and try again", ErrInvalidStateTransition)
+		}
+		return "", err
+	}
+	return from, nil
+}
+
+// statesMovingTo lists the states that may move to state
+func statesMovingTo(state string) []string {
+	var states []string
+	for _, from := range models.ErrorStates {
+		if slices.Contains(ErrorStateTransitions[from], state) {
+			states = append(states, from)
+		}
+	}
+	return states
+}
+
+// initialErrorState is the state a new event is stored in
+func initialErrorState(error *models.Error) string {
+	switch {
+	case error.Regressed:
+		return "regressed"
+	case error.Resolved:
+		return "resolved"
+	}
+	return "new"
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidCursor is returned for a since value that is neither a cursor nor a timestamp
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	// MaxErrorUpdatesWait caps how long GetErrorUpdates long-polls
+	MaxErrorUpdatesWait = 30 * time.Second
+
+	// errorUpdatesSettle keeps the cursor behind writes that may still be committing
+	errorUpdatesSettle = time.Second
+
+	errorUpdatesPollInterval = time.Second
+)
+
+// GetErrorUpdates returns errors created or updated after since, which is a
+// cursor from a previous call or an RFC 3339 timestamp. An empty since
+// returns no errors and a cursor for the present. With wait > 0 the call
+// long-polls until an update arrives or wait has passed; in between it only
+// reads the Redis cache generation and queries again once errors changed.
+func (s *ErrorService) GetErrorUpdates(ctx context.Context, since string, filter models.ErrorFilter, limit int, wait time.Duration) (*models.ErrorUpdatesResponse, error) {
+	if err := s.contextIndex.CheckIndexed(filter.Context); err != nil {
+		return nil, err
+	}
+
+	updatedAt, id, err := parseErrorCursor(since)
+	if err != nil {
+		return nil, err
+	}
+	if since == "" {
+		// Start from the watermark; there is nothing to return yet
+		_, watermark, err := s.store(ctx).GetErrorUpdates(filter, time.Now(), uuid.Nil, errorUpdatesSettle, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ErrorUpdatesResponse{Errors: []models.Error{}, Cursor: encodeErrorCursor(watermark, uuid.Nil)}, nil
+	}
+
+	deadline := time.Now().Add(min(wait, MaxErrorUpdatesWait))
+	generation, _ := s.CacheGeneration(ctx)
+	// A change is followed by one more query after the settle delay has passed
+	lastChange := time.Now()
+
+	for {
+		if time.Since(lastChange) <= errorUpdatesSettle+errorUpdatesPollInterval {
+			errors, watermark, err := s.store(ctx).GetErrorUpdates(filter, updatedAt, id, errorUpdatesSettle, limit+1)
+			if err != nil {
+				return nil, err
+			}
+
+			response := &models.ErrorUpdatesResponse{Errors: errors}
+			if len(errors) > limit {
+				response.Errors = errors[:limit]
+				response.HasMore = true
+			}
+			if len(response.Errors) > 0 {
+				last := response.Errors[len(response.Errors)-1]
+				updatedAt, id = last.UpdatedAt, last.ID
+			} else {
+				// Everything up to the watermark has been seen
+				updatedAt, id = watermark, uuid.Nil
+			}
+			response.Cursor = encodeErrorCursor(updatedAt, id)
+
+			if len(response.Errors) > 0 || !time.Now().Before(deadline) {
+				if err := s.attachRunbooks(response.Errors); err != nil {
+					return nil, err
+				}
+				return response, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &models.ErrorUpdatesResponse{Errors: []models.Error{}, Cursor: encodeErrorCursor(updatedAt, id)}, nil
+		case <-time.After(errorUpdatesPollInterval):
+		}
+
+		if current, err := s.CacheGeneration(ctx); err != nil || current != generation {
+			generation = current
+			lastChange = time.Now()
+		}
+		if !time.Now().Before(deadline) {
+			// Answer with whatever the final query finds
+			lastChange = time.Now()
+		}
+	}
+}
+
+// encodeErrorCursor encodes an (updated_at, id) position as an opaque string
+func encodeErrorCursor(updatedAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d_%s", updatedAt.UnixMicro(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseErrorCursor(since string) (time.Time, uuid.UUID, error) {
+	if since == "" {
+		return time.Time{}, uuid.Nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		return t, uuid.Nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(since)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	micros, idStr, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return time.UnixMicro(us).UTC(), id, nil
+}
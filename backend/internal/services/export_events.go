@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// groupEventsCSVHeader names the fixed columns of a group's occurrence export.
+// A context.<key> column follows for each context key the group uses.
+var groupEventsCSVHeader = []string{
+	"id", "timestamp", "level", "message", "environment", "release", "url", "user",
+	"user_agent", "ip_address", "trace_id", "region", "deployment",
+}
+
+// WriteGroupEvents writes every occurrence of the error's group as CSV,
+// oldest first. Unlike CreateExport it streams in the request, since one
+// group is small enough to. open is called with the file name once the group
+// is found and returns where to write; errors returned before then mean
+// nothing was written.
+func (s *ExportService) WriteGroupEvents(ctx context.Context, id uuid.UUID, actor string, open func(fileName string) io.Writer) error {
+	event, err := s.db.GetErrorByID(id)
+	if err != nil {
+		return err
+	}
+
+	// An error without a fingerprint is a group of its own
+	var contextKeys []string
+	if event.Fingerprint != nil {
+		if contextKeys, err = s.db.GetGroupContextKeys(*event.Fingerprint); err != nil {
+			return err
+		}
+	} else {
+		for key := range event.Context {
+			contextKeys = append(contextKeys, key)
+		}
+		sort.Strings(contextKeys)
+	}
+
+	group := id.String()
+	if event.Fingerprint != nil {
+		group = *event.Fingerprint
+	}
+	cw := csv.NewWriter(open(fmt.Sprintf("error-%s-events.csv", group)))
+
+	header := append([]string{}, groupEventsCSVHeader...)
+	for _, key := range contextKeys {
+		header = append(header, "context."+key)
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	rows, err := s.eachGroupEvent(ctx, event, func(e *models.Error) error {
+		if err := cw.Write(groupEventCSVRow(e, contextKeys)); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	s.audit.Record("event.events_exported", actor, "error_group", group, map[string]interface{}{
+		"error_id": id,
+		"rows":     rows,
+	})
+	return nil
+}
+
+// StreamGroupEvents passes every occurrence of the error's group to fn,
+// oldest first. Errors returned before fn is first called mean nothing was
+// passed.
+func (s *ExportService) StreamGroupEvents(ctx context.Context, id uuid.UUID, actor string, fn func(e *models.Error) error) error {
+	error, err := s.db.GetErrorByID(id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.eachGroupEvent(ctx, error, fn)
+	if err != nil {
+		return err
+	}
+
+	group := id.String()
+	if error.Fingerprint != nil {
+		group = *error.Fingerprint
+	}
+	s.audit.Record("error.events_exported", actor, "error_group", group, map[string]interface{}{
+		"error_id": id,
+		"rows":     rows,
+		"format":   "ndjson",
+	})
+	return nil
+}
+
+// eachGroupEvent passes the occurrences of the error's group to fn a page at
+// a time, oldest first, and returns how many there were. An error without a
+// fingerprint is a group of its own.
+func (s *ExportService) eachGroupEvent(ctx context.Context, error *models.Error, fn func(e *models.Error) error) (int, error) {
+	if error.Fingerprint == nil {
+		return 1, fn(error)
+	}
+
+	rows := 0
+	var afterTimestamp time.Time
+	var afterID *uuid.UUID
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		events, err := s.db.GetGroupEventsForExport(*error.Fingerprint, afterTimestamp, afterID, exportBatchSize)
+		if err != nil {
+			return rows, err
+		}
+		for i := range events {
+			if err := fn(&events[i]); err != nil {
+				return rows, err
+			}
+		}
+		rows += len(events)
+		if len(events) < exportBatchSize {
+			return rows, nil
+		}
+
+		last := events[len(events)-1]
+		afterTimestamp, afterID = last.Timestamp, &last.ID
+	}
+}
+
+func groupEventCSVRow(e *models.Error, contextKeys []string) []string {
+	row := []string{
+		e.ID.String(),
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Level,
+		e.Message,
+		e.Environment,
+		stringValue(e.Release),
+		stringValue(e.URL),
+		contextUser(e.Context),
+		stringValue(e.UserAgent),
+		stringValue(e.IPAddress),
+		stringValue(e.TraceID),
+		stringValue(e.Region),
+		stringValue(e.Deployment),
+	}
+	for _, key := range contextKeys {
+		row = append(row, contextCSVValue(e.Context[key]))
+	}
+	return row
+}
+
+// contextUser returns the user an event happened to: the context user_id, or
+// user.id, as affected users are counted
+func contextUser(fields map[string]interface{}) string {
+	if user := contextCSVValue(fields["user_id"]); user != "" {
+		return user
+	}
+	if user, ok := fields["user"].(map[string]interface{}); ok {
+		return contextCSVValue(user["id"])
+	}
+	return ""
+}
+
+// contextCSVValue writes strings as they are and other values as JSON
+func contextCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/s3"
+)
+
+var (
+	// ErrInvalidExport is returned for an export request that cannot be run
+	ErrInvalidExport = errors.New("invalid export")
+	// ErrInvalidDownloadLink is returned for a download link that was not
+	// signed by this server
+	ErrInvalidDownloadLink = errors.New("invalid download link")
+	// ErrExportExpired is returned once an export's file has been removed
+	ErrExportExpired = errors.New("export has expired")
+)
+
+const (
+	// exportBatchSize is how many errors are read per query
+	exportBatchSize = 1000
+	// exportPurgeInterval is how often expired export files are removed
+	exportPurgeInterval = time.Hour
+	// maxPresignDuration is the longest an S3 presigned URL may be valid
+	maxPresignDuration = 7 * 24 * time.Hour
+)
+
+// exportCSVHeader names the columns of a CSV export
+var exportCSVHeader = []string{
+	"id", "timestamp", "level", "message", "source", "environment", "release", "url",
+	"fingerprint", "resolved", "count", "first_seen", "last_seen", "region", "deployment",
+	"project_id", "trace_id",
+}
+
+// ExportService writes filtered errors to CSV or JSON files in the background
+// and hands out signed links to them, so large exports do not hold a request
+// open for minutes
+type ExportService struct {
+	db           *database.DB
+	contextIndex *ContextIndexService
+	audit        *AuditService
+	s3           *s3.Client
+	s3Prefix     string
+	storageDir   string
+	retention    time.Duration
+	signingKey   []byte
+}
+
+// NewExportService returns the service. s3Client may be nil when no bucket is
+// configured. Without a signing key, links are signed with a random key and
+// stop working when the process restarts or on other instances.
+func NewExportService(db *database.DB, contextIndex *ContextIndexService, audit *AuditService, s3Client *s3.Client, s3Prefix, storageDir string, retention time.Duration, signingKey string) *ExportService {
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+		log.Println("EXPORT_SIGNING_KEY is not set; export download links will only work on this instance until it restarts")
+	}
+
+	return &ExportService{
+		db:           db,
+		contextIndex: contextIndex,
+		audit:        audit,
+		s3:           s3Client,
+		s3Prefix:     s3Prefix,
+		storageDir:   storageDir,
+		retention:    retention,
+		signingKey:   key,
+	}
+}
+
+// CreateExport validates the request and writes the export in the background.
+// The returned job is pending; its progress and link are read with GetExport.
+func (s *ExportService) CreateExport(ctx context.Context, req *models.CreateExportRequest, projectID *uuid.UUID, actor string) (*models.ExportJob, error) {
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+	if req.Format != "csv" && req.Format != "json" {
+		return nil, fmt.Errorf("%w: format must be csv or json", ErrInvalidExport)
+	}
+	if req.Destination == "" {
+		req.Destination = "download"
+	}
+	switch req.Destination {
+	case "download":
+	case "s3":
+		if s.s3 == nil {
+			return nil, fmt.Errorf("%w: no S3 bucket is configured", ErrInvalidExport)
+		}
+	default:
+		return nil, fmt.Errorf("%w: destination must be download or s3", ErrInvalidExport)
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidExport)
+	}
+	if err := s.contextIndex.CheckIndexed(req.Filter.Context); err != nil {
+		return nil, err
+	}
+	if err := checkMessageFilter(req.Filter); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	// Exports are always oldest first
+	req.Filter.Sort = ""
+
+	total, err := s.db.CountErrorsForExport(req.Filter, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := &models.ExportJob{
+		ID:          uuid.New(),
+		Format:      req.Format,
+		Destination: req.Destination,
+		Filter:      req.Filter,
+		From:        req.From,
+		To:          req.To,
+		Status:      "pending",
+		ProjectID:   projectID,
+		TotalRows:   total,
+		CreatedBy:   actor,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.db.CreateExportJob(job); err != nil {
+		return nil, err
+	}
+	s.audit.Record("export.started", actor, "export_job", job.ID.String(), map[string]interface{}{
+		"format":      job.Format,
+		"destination": job.Destination,
+		"total_rows":  job.TotalRows,
+	})
+
+	go s.runExport(*job)
+
+	return withExportProgress(job), nil
+}
+
+// GetExport returns a job, with a download link once it has completed
+func (s *ExportService) GetExport(ctx context.Context, id uuid.UUID) (*models.ExportJob, error) {
+	job, err := s.db.GetExportJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == "completed" && job.ExpiresAt != nil {
+		var link string
+		if job.Destination == "s3" && job.S3Key != nil && s.s3 != nil {
+			link = s.s3.PresignGet(*job.S3Key, min(time.Until(*job.ExpiresAt), maxPresignDuration))
+		} else if job.Destination == "download" {
+			link = s.downloadPath(job.ID, *job.ExpiresAt)
+		}
+		if link != "" {
+			job.DownloadURL = &link
+		}
+	}
+
+	return withExportProgress(job), nil
+}
+
+// OpenExportDownload checks a download link's signature and returns the job
+// and its file
+func (s *ExportService) OpenExportDownload(ctx context.Context, id uuid.UUID, expires, signature string) (*models.ExportJob, *os.File, error) {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(id, expiresUnix))) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	if time.Now().Unix() >= expiresUnix {
+		return nil, nil, ErrExportExpired
+	}
+
+	job, err := s.db.GetExportJob(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status == "expired" {
+		return nil, nil, ErrExportExpired
+	}
+	if job.Status != "completed" || job.Destination != "download" {
+		return nil, nil, fmt.Errorf("export job not found")
+	}
+
+	f, err := os.Open(s.exportPath(job))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrExportExpired
+		}
+		return nil, nil, err
+	}
+	return job, f, nil
+}
+
+// StartStaleJobReaper fails the exports whose instance stopped before
+// finishing them, until ctx is done
+func (s *ExportService) StartStaleJobReaper(ctx context.Context) {
+	runStaleJobReaper(ctx, "export", s.db.FailStaleExportJobs)
+}
+
+// StartExportPurger removes export files once their links expire, until ctx
+// is done. Objects written to S3 are left to the bucket's lifecycle rules.
+func (s *ExportService) StartExportPurger(ctx context.Context) {
+	log.Println("Starting export purger...")
+
+	ticker := time.NewTicker(exportPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeExpiredExports()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Export purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ExportService) purgeExpiredExports() {
+	jobs, err := s.db.GetExpiredExportJobs(time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to get expired exports: %v", err)
+		return
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Destination == "download" {
+			if err := os.Remove(s.exportPath(job)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove export %s: %v", job.ID, err)
+				continue
+			}
+		}
+		job.Status = "expired"
+		s.updateExportJob(job)
+	}
+	if len(jobs) > 0 {
+		log.Printf("EXPORT PURGE: expired %d exports", len(jobs))
+	}
+}
+
+func withExportProgress(job *models.ExportJob) *models.ExportJob {
+	if job.TotalRows > 0 {
+		job.Progress = min(float64(job.ExportedRows)/float64(job.TotalRows)*100, 100)
+	} else if job.Status == "completed" {
+		job.Progress = 100
+	}
+	return job
+}
+
+func (s *ExportService) runExport(job models.ExportJob) {
+	log.Printf("EXPORT STARTED: job %s, %s to %s, %d rows", job.ID, job.Format, job.Destination, job.TotalRows)
+	job.Status = "running"
+	s.updateExportJob(&job)
+	stopHeartbeat := startHeartbeat(func() error { return s.db.TouchExportJob(job.ID) })
+	defer stopHeartbeat()
+
+	err := s.writeExport(&job)
+	if err == nil && job.Destination == "s3" {
+		err = s.uploadExport(&job)
+	}
+
+	now := time.Now().UTC()
+	if err != nil {
+		message := err.Error()
+		job.Status = "failed"
+		job.Error = &message
+		os.Remove(s.exportPath(&job))
+	} else {
+		expires := now.Add(s.retention)
+		job.Status = "completed"
+		job.ExpiresAt = &expires
+	}
+	job.CompletedAt = &now
+	s.updateExportJob(&job)
+
+	log.Printf("EXPORT %s: job %s, %d rows, %d bytes", strings.ToUpper(job.Status), job.ID, job.ExportedRows, job.SizeBytes)
+}
+
+// writeExport pages through the matching errors and writes them to the job's file
+func (s *ExportService) writeExport(job *models.ExportJob) error {
+	if err := os.MkdirAll(s.storageDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export storage: %w", err)
+	}
+	f, err := os.Create(s.exportPath(job))
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	writer := newExportWriter(job.Format, w)
+	if err := writer.begin(); err != nil {
+		return err
+	}
+
+	var afterTimestamp time.Time
+	var afterID *uuid.UUID
+	for {
+		errors, err := s.db.GetErrorsForExport(job.Filter, job.From, job.To, afterTimestamp, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range errors {
+			if err := writer.write(&errors[i]); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		job.ExportedRows += len(errors)
+		if len(errors) < exportBatchSize {
+			break
+		}
+
+		last := errors[len(errors)-1]
+		afterTimestamp, afterID = last.Timestamp, &last.ID
+		s.updateExportJob(job)
+	}
+
+	if err := writer.end(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	job.SizeBytes = info.Size()
+	// Rows may have arrived since the count
+	job.TotalRows = max(job.TotalRows, job.ExportedRows)
+	return nil
+}
+
+// uploadExport moves a written export to S3
+func (s *ExportService) uploadExport(job *models.ExportJob) error {
+	path := s.exportPath(job)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer f.Close()
+
+	key := s.s3Prefix + filepath.Base(path)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := s.s3.PutObject(ctx, key, f, job.SizeBytes, exportContentType(job.Format)); err != nil {
+		return err
+	}
+	job.S3Key = &key
+	return nil
+}
+
+func (s *ExportService) updateExportJob(job *models.ExportJob) {
+	job.UpdatedAt = time.Now().UTC()
+	if err := s.db.UpdateExportJob(job); err != nil {
+		log.Printf("Failed to update export job %s: %v", job.ID, err)
+	}
+}
+
+func (s *ExportService) exportPath(job *models.ExportJob) string {
+	return filepath.Join(s.storageDir, job.ID.String()+"."+job.Format)
+}
+
+// downloadPath is the signed link to a downloadable export, valid until expires
+func (s *ExportService) downloadPath(id uuid.UUID, expires time.Time) string {
+	return fmt.Sprintf("/exports/%s/download?expires=%d&signature=%s", id, expires.Unix(), s.sign(id, expires.Unix()))
+}
+
+func (s *ExportService) sign(id uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ExportFileName is the name an export is downloaded as
+func ExportFileName(job *models.ExportJob) string {
+	return "errors-" + job.CreatedAt.Format("20060102-150405") + "." + job.Format
+}
+
+func exportContentType(format string) string {
+	if format == "json" {
+		return "application/json"
+	}
+	return "text/csv"
+}
+
+// ExportContentType is the Content-Type an export is served with
+func ExportContentType(job *models.ExportJob) string {
+	return exportContentType(job.Format)
+}
+
+// exportWriter streams errors in one export format
+type exportWriter struct {
+	begin func() error
+	write func(e *models.Error) error
+	end   func() error
+}
+
+func newExportWriter(format string, w io.Writer) *exportWriter {
+	if format == "json" {
+		// A JSON array written one element at a time
+		first := true
+		return &exportWriter{
+			begin: func() error {
+				_, err := io.WriteString(w, "[")
+				return err
+			},
+			write: func(e *models.Error) error {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				if !first {
+					if _, err := io.WriteString(w, ",\n"); err != nil {
+						return err
+					}
+				}
+				first = false
+				_, err = w.Write(data)
+				return err
+			},
+			end: func() error {
+				_, err := io.WriteString(w, "]\n")
+				return err
+			},
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	return &exportWriter{
+		begin: func() error {
+			return cw.Write(exportCSVHeader)
+		},
+		write: func(e *models.Error) error {
+			return cw.Write(exportCSVRow(e))
+		},
+		end: func() error {
+			cw.Flush()
+			return cw.Error()
+		},
+	}
+}
+
+func exportCSVRow(e *models.Error) []string {
+	var projectID string
+	if e.ProjectID != nil {
+		projectID = e.ProjectID.String()
+	}
+	return []string{
+		e.ID.String(),
+		e.Timestamp.UTC().Format(time.RFC3339),
+		e.Level,
+		e.Message,
+		e.Source,
+		e.Environment,
+		stringValue(e.Release),
+		stringValue(e.URL),
+		stringValue(e.Fingerprint),
+		strconv.FormatBool(e.Resolved),
+		strconv.Itoa(e.Count),
+		e.FirstSeen.UTC().Format(time.RFC3339),
+		e.LastSeen.UTC().Format(time.RFC3339),
+		stringValue(e.Region),
+		stringValue(e.Deployment),
+		projectID,
+		stringValue(e.TraceID),
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+
+	"error-logs/internal/database"
+)
+
+var (
+	// ErrAlreadyExists is returned when a resource would take the external ID
+	// or slug of another one
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrInvalidExternalID is returned for an external ID that can't be stored
+	ErrInvalidExternalID = errors.New("invalid external_id")
+	// ErrVersionMismatch is returned when a resource changed since the
+	// version the caller read
+	ErrVersionMismatch = database.ErrVersionMismatch
+)
+
+// maxExternalIDLength matches the external_id columns
+const maxExternalIDLength = 255
+
+// applyExternalID validates the external ID of a request and copies it onto
+// target. nil leaves target alone and "" clears it.
+func applyExternalID(target **string, externalID *string) error {
+	if externalID == nil {
+		return nil
+	}
+	if *externalID == "" {
+		*target = nil
+		return nil
+	}
+	if len(*externalID) > maxExternalIDLength {
+		return fmt.Errorf("%w: external_id must be at most %d characters", ErrInvalidExternalID, maxExternalIDLength)
+	}
+	for _, r := range *externalID {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%w: external_id must not contain spaces or control characters", ErrInvalidExternalID)
+		}
+	}
+	id := *externalID
+	*target = &id
+	return nil
+}
+
+// externalIDConflict turns a unique violation on write into ErrAlreadyExists
+func externalIDConflict(err error, resource string, externalID *string) error {
+	if err == nil || !database.IsUniqueViolation(err) {
+		return err
+	}
+	if externalID == nil {
+		return fmt.Errorf("%w: %s conflicts with an existing one", ErrAlreadyExists, resource)
+	}
+	return fmt.Errorf("%w: a %s with external_id %q already exists", ErrAlreadyExists, resource, *externalID)
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidFeatureFlag is returned for flag changes that can't be applied
+var ErrInvalidFeatureFlag = errors.New("invalid feature flag")
+
+// featureFlagsRefresh bounds how long a flag change takes to reach other
+// replicas
+const featureFlagsRefresh = time.Minute
+
+// Built-in flags gate capabilities of this server
+const (
+	FlagAnomalyDetection  = "anomaly_detection"
+	FlagSymbolication     = "symbolication"
+	FlagClickHouseStorage = "clickhouse_storage"
+)
+
+// builtinFeatureFlags are known without a feature_flags row; a row overrides
+// the default and description
+var builtinFeatureFlags = []models.FeatureFlag{
+	{Key: FlagAnomalyDetection, Description: "Detect unusual error rates per project", Enabled: false},
+	{Key: FlagSymbolication, Description: "Symbolicate mobile stack traces with uploaded debug files", Enabled: true},
+	{Key: FlagClickHouseStorage, Description: "Store events in ClickHouse instead of PostgreSQL", Enabled: false},
+}
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,99}$`)
+
+// FeatureFlagService resolves which flags are on for an organization or
+// project. A project override wins over an organization override, which wins
+// over the flag's default. Flags are resolved from memory since every request
+// asks.
+type FeatureFlagService struct {
+	db    *database.DB
+	audit *AuditService
+
+	mu       sync.RWMutex
+	state    *featureFlagState
+	loadedAt time.Time
+}
+
+type featureFlagState struct {
+	flags       map[string]*models.FeatureFlag // with their overrides
+	overrides   map[flagScope]bool
+	projectOrgs map[uuid.UUID]uuid.UUID
+}
+
+type flagScope struct {
+	key   string
+	scope string
+	id    uuid.UUID
+}
+
+func NewFeatureFlagService(db *database.DB, audit *AuditService) *FeatureFlagService {
+	return &FeatureFlagService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// Enabled reports whether a flag is on for a project, or for an organization
+// when projectID is nil. Unknown flags are off.
+func (s *FeatureFlagService) Enabled(key string, orgID, projectID *uuid.UUID) bool {
+	return s.featureFlags().enabled(key, orgID, projectID)
+}
+
+// Resolve returns every flag and whether it is on for the organization and
+// project
+func (s *FeatureFlagService) Resolve(orgID, projectID *uuid.UUID) map[string]bool {
+	state := s.featureFlags()
+	resolved := make(map[string]bool, len(state.flags))
+	for key := range state.flags {
+		resolved[key] = state.enabled(key, orgID, projectID)
+	}
+	return resolved
+}
+
+// ListFlags returns all flags with their overrides, read from the database so
+// admins see their own changes immediately
+func (s *FeatureFlagService) ListFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]models.FeatureFlag, 0, len(state.flags))
+	for _, flag := range state.flags {
+		flags = append(flags, *flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// UpdateFlag creates a flag or changes its default and description
+func (s *FeatureFlagService) UpdateFlag(ctx context.Context, key string, req *models.UpdateFeatureFlagRequest, actor string) (*models.FeatureFlag, error) {
+	if !flagKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: key must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidFeatureFlag)
+	}
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	flag := &models.FeatureFlag{Key: key, Overrides: []models.FeatureFlagOverride{}}
+	if existing := state.flags[key]; existing != nil {
+		flag = existing
+	}
+
+	if req.Description != nil {
+		flag.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	now := time.Now().UTC()
+	flag.UpdatedAt = &now
+
+	if err := s.db.UpsertFeatureFlag(flag); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	s.audit.Record("feature_flag.updated", actor, "feature_flag", key, map[string]interface{}{"enabled": flag.Enabled})
+	return flag, nil
+}
+
+// DeleteFlag removes a flag and its overrides. Built-in flags return to their
+// default instead.
+func (s *FeatureFlagService) DeleteFlag(ctx context.Context, key, actor string) error {
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	if state.flags[key] == nil {
+		return fmt.Errorf("feature flag not found")
+	}
+
+	if err := s.db.DeleteFeatureFlag(key); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	s.audit.Record("feature_flag.deleted", actor, "feature_flag", key, nil)
+	return nil
+}
+
+// SetOverride turns a flag on or off for one organization or project
+func (s *FeatureFlagService) SetOverride(ctx context.Context, key string, req *models.SetFeatureFlagOverrideRequest, actor string) (*models.FeatureFlagOverride, error) {
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if state.flags[key] == nil {
+		return nil, fmt.Errorf("feature flag not found")
+	}
+
+	switch req.Scope {
+	case "org":
+		if _, err := s.db.GetOrganization(req.ScopeID); err != nil {
+			if err.Error() == "organization not found" {
+				return nil, fmt.Errorf("%w: organization not found", ErrInvalidFeatureFlag)
+			}
+			return nil, err
+		}
+	case "project":
+		if _, err := s.db.GetProject(req.ScopeID); err != nil {
+			if err.Error() == "project not found" {
+				return nil, fmt.Errorf("%w: project not found", ErrInvalidFeatureFlag)
+			}
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: scope must be org or project", ErrInvalidFeatureFlag)
+	}
+
+	override := &models.FeatureFlagOverride{
+		FlagKey:   key,
+		Scope:     req.Scope,
+		ScopeID:   req.ScopeID,
+		Enabled:   req.Enabled,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.db.UpsertFeatureFlagOverride(override); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	s.audit.Record("feature_flag.override_set", actor, "feature_flag", key, map[string]interface{}{
+		"scope":    override.Scope,
+		"scope_id": override.ScopeID,
+		"enabled":  override.Enabled,
+	})
+	return override, nil
+}
+
+// RemoveOverride returns an organization or project to the flag's default
+func (s *FeatureFlagService) RemoveOverride(ctx context.Context, key, scope string, scopeID uuid.UUID, actor string) error {
+	if err := s.db.DeleteFeatureFlagOverride(key, scope, scopeID); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	s.audit.Record("feature_flag.override_removed", actor, "feature_flag", key, map[string]interface{}{
+		"scope":    scope,
+		"scope_id": scopeID,
+	})
+	return nil
+}
+
+func (state *featureFlagState) enabled(key string, orgID, projectID *uuid.UUID) bool {
+	flag := state.flags[key]
+	if flag == nil {
+		return false
+	}
+
+	if projectID != nil {
+		if enabled, ok := state.overrides[flagScope{key, "project", *projectID}]; ok {
+			return enabled
+		}
+		if orgID == nil {
+			if projectOrg, ok := state.projectOrgs[*projectID]; ok {
+				orgID = &projectOrg
+			}
+		}
+	}
+	if orgID != nil {
+		if enabled, ok := state.overrides[flagScope{key, "org", *orgID}]; ok {
+			return enabled
+		}
+	}
+	return flag.Enabled
+}
+
+func (s *FeatureFlagService) featureFlags() *featureFlagState {
+	s.mu.RLock()
+	if s.state != nil && time.Since(s.loadedAt) < featureFlagsRefresh {
+		defer s.mu.RUnlock()
+		return s.state
+	}
+	s.mu.RUnlock()
+
+	state, err := s.load()
+	if err != nil {
+		log.Printf("Failed to load feature flags: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.state != nil {
+			return s.state
+		}
+		// Never loaded: fall back to the built-in defaults
+		return builtinFeatureFlagState()
+	}
+
+	s.mu.Lock()
+	s.state = state
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return state
+}
+
+func (s *FeatureFlagService) load() (*featureFlagState, error) {
+	flags, err := s.db.GetFeatureFlags()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.db.GetFeatureFlagOverrides()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.db.GetProjects(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	state := builtinFeatureFlagState()
+	for i := range flags {
+		flag := &flags[i]
+		flag.Overrides = []models.FeatureFlagOverride{}
+		if builtin := state.flags[flag.Key]; builtin != nil {
+			flag.Builtin = true
+		}
+		state.flags[flag.Key] = flag
+	}
+	for _, override := range overrides {
+		flag := state.flags[override.FlagKey]
+		if flag == nil {
+			continue
+		}
+		flag.Overrides = append(flag.Overrides, override)
+		state.overrides[flagScope{override.FlagKey, override.Scope, override.ScopeID}] = override.Enabled
+	}
+	for _, project := range projects {
+		if project.OrgID != nil {
+			state.projectOrgs[project.ID] = *project.OrgID
+		}
+	}
+	return state, nil
+}
+
+func (s *FeatureFlagService) invalidate() {
+	s.mu.Lock()
+	s.state = nil
+	s.mu.Unlock()
+}
+
+func builtinFeatureFlagState() *featureFlagState {
+	state := &featureFlagState{
+		flags:       make(map[string]*models.FeatureFlag, len(builtinFeatureFlags)),
+		overrides:   make(map[flagScope]bool),
+		projectOrgs: make(map[uuid.UUID]uuid.UUID),
+	}
+	for _, builtin := range builtinFeatureFlags {
+		flag := builtin
+		flag.Builtin = true
+		flag.Overrides = []models.FeatureFlagOverride{}
+		state.flags[flag.Key] = &flag
+	}
+	return state
+}
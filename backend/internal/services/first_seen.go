@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// recordFirstSeen checks whether a stored event is the first of its group in
+// its environment, and if so audits it and notifies the new error alert rules
+func (s *ErrorService) recordFirstSeen(error *models.Error) {
+	if error.Fingerprint == nil {
+		return
+	}
+
+	event := &models.FirstSeenEvent{
+		Fingerprint: *error.Fingerprint,
+		ErrorID:     error.ID,
+		ProjectID:   error.ProjectID,
+		Message:     error.Message,
+		Level:       error.Level,
+		Source:      error.Source,
+		Environment: error.Environment,
+		FirstSeen:   error.Timestamp,
+	}
+
+	isNew, err := s.db.RecordFirstSeen(event)
+	if err != nil {
+		log.Printf("Failed to record first seen: %v", err)
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	log.Printf("FIRST SEEN: group %s in environment %q - %s", event.Fingerprint, event.Environment, truncate(event.Message, 100))
+	s.audit.Record("error.first_seen", SystemActor, "error_group", event.Fingerprint, map[string]interface{}{
+		"environment": event.Environment,
+		"error_id":    event.ErrorID,
+	})
+	go s.alerts.NotifyFirstSeen(event)
+}
+
+// GetFirstSeen lists error groups first seen in the last window
+func (s *ErrorService) GetFirstSeen(ctx context.Context, environment string, window time.Duration, limit int) (*models.FirstSeenResponse, error) {
+	events, err := s.db.GetFirstSeenEvents(environment, time.Now().UTC().Add(-window), limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.FirstSeenResponse{Events: events}, nil
+}
+
+// NotifyFirstSeen notifies every enabled new error rule whose scope matches
+// the event. There is no firing state: each new group is its own notification.
+func (s *AlertsService) NotifyFirstSeen(event *models.FirstSeenEvent) {
+	rules, err := s.db.GetAlertRules()
+	if err != nil {
+		log.Printf("Failed to load alert rules: %v", err)
+		return
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled || !isNewErrorCondition(rule.Condition) || !firstSeenMatchesScope(event, rule.Scope) {
+			continue
+		}
+
+		environment := event.Environment
+		if environment == "" {
+			environment = "(none)"
+		}
+
+		log.Printf("NEW ERROR ALERT: rule %s (%s) - group %s in %s", rule.ID, rule.Name, event.Fingerprint, environment)
+
+		now := time.Now().UTC()
+		runbook := resolveRunbook(s.db, rule.Runbook, event.Fingerprint, event.Source)
+		subject := fmt.Sprintf("[New error] %s: %s", rule.Name, truncate(event.Message, 80))
+		body := fmt.Sprintf(
+			"A new error was seen for the first time in %s.\n\nMessage: %s\nLevel: %s\nSource: %s\nFingerprint: %s\nError ID: %s\nFirst seen: %s\n",
+			environment, event.Message, event.Level, event.Source, event.Fingerprint, event.ErrorID, event.FirstSeen.Format(time.RFC3339),
+		) + formatRunbook(runbook)
+
+		s.notify(rule, subject, body, map[string]interface{}{
+			"rule_id":     rule.ID,
+			"rule_name":   rule.Name,
+			"status":      "new_error",
+			"fingerprint": event.Fingerprint,
+			"environment": event.Environment,
+			"error_id":    event.ErrorID,
+			"message":     event.Message,
+			"level":       event.Level,
+			"source":      event.Source,
+			"first_seen":  event.FirstSeen,
+			"notified_at": now,
+			"runbook":     runbook,
+		})
+
+		rule.LastTriggered = &now
+		rule.LastNotified = &now
+		if err := s.db.UpdateAlertRuleState(rule); err != nil {
+			log.Printf("Failed to update alert rule %s: %v", rule.ID, err)
+		}
+	}
+}
+
+func firstSeenMatchesScope(event *models.FirstSeenEvent, scope models.AlertScope) bool {
+	if scope.ProjectID != nil && (event.ProjectID == nil || *event.ProjectID != *scope.ProjectID) {
+		return false
+	}
+	if scope.Source != "" && scope.Source != event.Source {
+		return false
+	}
+	if scope.Environment != "" && scope.Environment != event.Environment {
+		return false
+	}
+	if scope.Fingerprint != "" && scope.Fingerprint != event.Fingerprint {
+		return false
+	}
+	if scope.Level != "" && scope.Level != event.Level {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/s3"
+)
+
+// deliverHTTP posts the batch as {"events": [...]}, signed like API requests
+// (see SignRequest) when the sink has a secret
+func (s *ForwardingService) deliverHTTP(ctx context.Context, sink *models.EventSink, events []string) error {
+	batch := struct {
+		Events []json.RawMessage `json:"events"`
+	}{Events: rawEvents(events)}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.Config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sink.Secret != nil {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		req.Header.Set("X-Signature", SignRequest(*sink.Secret, timestamp, http.MethodPost, req.URL.RequestURI(), body))
+	}
+
+	return s.send(req)
+}
+
+// deliverKafka produces the batch to a topic through a Kafka REST Proxy (v2
+// API), keyed by fingerprint so each error group stays on one partition. The
+// sink's secret, if any, is sent as basic auth credentials ("user:password").
+func (s *ForwardingService) deliverKafka(ctx context.Context, sink *models.EventSink, events []string) error {
+	type record struct {
+		Key   *string         `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	records := make([]record, len(events))
+	for i, event := range events {
+		var keyed struct {
+			Fingerprint *string `json:"fingerprint"`
+		}
+		json.Unmarshal([]byte(event), &keyed)
+		records[i] = record{Key: keyed.Fingerprint, Value: json.RawMessage(event)}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(sink.Config.URL, "/") + "/topics/" + url.PathEscape(sink.Config.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if sink.Secret != nil {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(*sink.Secret)))
+	}
+
+	return s.send(req)
+}
+
+// deliverS3 writes the batch as one newline-delimited JSON object under
+// prefix/YYYY/MM/DD/HH/, like a Firehose delivery stream
+func (s *ForwardingService) deliverS3(ctx context.Context, sink *models.EventSink, events []string) error {
+	client, err := s3.NewClient(sink.Config.Endpoint, sink.Config.Region, sink.Config.Bucket, s.awsAccessKeyID, s.awsSecretAccessKey)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	for _, event := range events {
+		body.WriteString(event)
+		body.WriteByte('\n')
+	}
+
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s%s/%s-%s.ndjson", sink.Config.Prefix, now.Format("2006/01/02/15"), now.Format("20060102T150405Z"), uuid.NewString())
+	return client.PutObject(ctx, key, &body, int64(body.Len()), "application/x-ndjson")
+}
+
+func (s *ForwardingService) send(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sink returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func rawEvents(events []string) []json.RawMessage {
+	raw := make([]json.RawMessage, len(events))
+	for i, event := range events {
+		raw[i] = json.RawMessage(event)
+	}
+	return raw
+}
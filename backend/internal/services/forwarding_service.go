@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidEventSink is returned when a sink fails validation
+var ErrInvalidEventSink = errors.New("invalid event sink")
+
+const (
+	// forwardInterval is how often the forwarder checks sink queues
+	forwardInterval = 5 * time.Second
+	// forwardBatchSize caps the events sent to a sink in one delivery
+	forwardBatchSize = 500
+	// forwardS3Interval spaces out S3 objects unless a full batch is waiting
+	forwardS3Interval = time.Minute
+	// forwardMaxBackoff caps the wait before retrying a failing sink
+	forwardMaxBackoff = 5 * time.Minute
+	// forwardQueueMax bounds each sink's queue while it is failing; the
+	// oldest events are dropped beyond it
+	forwardQueueMax = 100000
+	// eventSinksRefresh bounds how long a sink change takes to reach other
+	// replicas
+	eventSinksRefresh = time.Minute
+)
+
+var eventSinkKinds = []string{"http", "kafka", "s3"}
+
+// ForwardingService forwards stored error events to external sinks. Every
+// instance queues events in Redis, one queue per sink; the leader delivers
+// them in batches, in order, keeping them queued until a sink accepts them.
+type ForwardingService struct {
+	db    *database.DB
+	redis *redis.Client
+	audit *AuditService
+
+	awsAccessKeyID     string
+	awsSecretAccessKey string
+	client             *http.Client
+
+	mu       sync.RWMutex
+	sinks    []models.EventSink
+	loadedAt time.Time
+}
+
+func NewForwardingService(db *database.DB, redis *redis.Client, audit *AuditService, awsAccessKeyID, awsSecretAccessKey string) *ForwardingService {
+	return &ForwardingService{
+		db:                 db,
+		redis:              redis,
+		audit:              audit,
+		awsAccessKeyID:     awsAccessKeyID,
+		awsSecretAccessKey: awsSecretAccessKey,
+		client:             &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Forward queues a stored event for every enabled sink whose filters match it
+func (s *ForwardingService) Forward(event *models.Error) {
+	var payload []byte
+	for _, sink := range s.enabledSinks() {
+		if !sinkMatches(&sink, event) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("Failed to marshal forwarded event: %v", err)
+				return
+			}
+		}
+		if err := s.redis.QueueForwardEvent(context.Background(), sink.ID, payload, forwardQueueMax); err != nil {
+			log.Printf("Failed to queue event for sink %s: %v", sink.Name, err)
+		}
+	}
+}
+
+func sinkMatches(sink *models.EventSink, event *models.Error) bool {
+	if len(sink.Levels) > 0 && !slices.Contains(sink.Levels, event.Level) {
+		return false
+	}
+	if len(sink.Sources) > 0 && !slices.Contains(sink.Sources, event.Source) {
+		return false
+	}
+	return true
+}
+
+func (s *ForwardingService) GetSinks(ctx context.Context) ([]models.EventSink, error) {
+	sinks, err := s.db.GetEventSinks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range sinks {
+		s.attachQueued(ctx, &sinks[i])
+	}
+	return sinks, nil
+}
+
+func (s *ForwardingService) GetSink(ctx context.Context, id uuid.UUID) (*models.EventSink, error) {
+	sink, err := s.db.GetEventSink(id)
+	if err != nil {
+		return nil, err
+	}
+	s.attachQueued(ctx, sink)
+	return sink, nil
+}
+
+func (s *ForwardingService) attachQueued(ctx context.Context, sink *models.EventSink) {
+	queued, err := s.redis.ForwardQueueLength(ctx, sink.ID)
+	if err != nil {
+		log.Printf("Failed to get queue length of sink %s: %v", sink.Name, err)
+		return
+	}
+	sink.Queued = queued
+}
+
+func (s *ForwardingService) CreateSink(ctx context.Context, req *models.CreateEventSinkRequest, actor string) (*models.EventSink, error) {
+	now := time.Now().UTC()
+	sink := &models.EventSink{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(req.Name),
+		Kind:      req.Kind,
+		Config:    req.Config,
+		Levels:    req.Levels,
+		Sources:   req.Sources,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		sink.Secret = req.Secret
+	}
+	if err := s.validate(sink); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.CreateEventSink(sink); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	s.audit.Record("event_sink.created", actor, "event_sink", sink.ID.String(), map[string]interface{}{"name": sink.Name, "kind": sink.Kind})
+	return sink, nil
+}
+
+func (s *ForwardingService) UpdateSink(ctx context.Context, id uuid.UUID, req *models.UpdateEventSinkRequest, actor string) (*models.EventSink, error) {
+	sink, err := s.db.GetEventSink(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		sink.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Config != nil {
+		sink.Config = *req.Config
+	}
+	if req.Secret != nil {
+		sink.Secret = nil
+		if *req.Secret != "" {
+			sink.Secret = req.Secret
+		}
+	}
+	if req.Levels != nil {
+		sink.Levels = *req.Levels
+	}
+	if req.Sources != nil {
+		sink.Sources = *req.Sources
+	}
+	if req.Enabled != nil {
+		sink.Enabled = *req.Enabled
+	}
+	if err := s.validate(sink); err != nil {
+		return nil, err
+	}
+	sink.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateEventSink(sink); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	s.attachQueued(ctx, sink)
+
+	s.audit.Record("event_sink.updated", actor, "event_sink", sink.ID.String(), map[string]interface{}{"name": sink.Name, "enabled": sink.Enabled})
+	return sink, nil
+}
+
+// DeleteSink removes a sink and drops the events still queued for it
+func (s *ForwardingService) DeleteSink(ctx context.Context, id uuid.UUID, actor string) error {
+	if err := s.db.DeleteEventSink(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	if err := s.redis.DeleteForwardQueue(ctx, id); err != nil {
+		log.Printf("Failed to delete queue of sink %s: %v", id, err)
+	}
+
+	s.audit.Record("event_sink.deleted", actor, "event_sink", id.String(), nil)
+	return nil
+}
+
+func (s *ForwardingService) validate(sink *models.EventSink) error {
+	if sink.Name == "" || len(sink.Name) > 100 {
+		return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidEventSink)
+	}
+	for _, level := range sink.Levels {
+		if !slices.Contains(EventLevels, level) {
+			return fmt.Errorf("%w: unknown level %q", ErrInvalidEventSink, level)
+		}
+	}
+
+	config := &sink.Config
+	switch sink.Kind {
+	case "http":
+		if !validHTTPURL(config.URL) {
+			return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidEventSink)
+		}
+	case "kafka":
+		if !validHTTPURL(config.URL) {
+			return fmt.Errorf("%w: url must be the http or https URL of a Kafka REST Proxy", ErrInvalidEventSink)
+		}
+		if config.Topic == "" || strings.ContainsAny(config.Topic, "/?#") {
+			return fmt.Errorf("%w: topic is required", ErrInvalidEventSink)
+		}
+	case "s3":
+		if config.Bucket == "" {
+			return fmt.Errorf("%w: bucket is required", ErrInvalidEventSink)
+		}
+		if config.Region == "" {
+			config.Region = "us-east-1"
+		}
+		if s.awsAccessKeyID == "" || s.awsSecretAccessKey == "" {
+			return fmt.Errorf("%w: S3 sinks need AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY on the server", ErrInvalidEventSink)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be one of %s", ErrInvalidEventSink, strings.Join(eventSinkKinds, ", "))
+	}
+	return nil
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// enabledSinks returns the enabled sinks, reloaded at most every
+// eventSinksRefresh since every stored event asks
+func (s *ForwardingService) enabledSinks() []models.EventSink {
+	s.mu.RLock()
+	if s.sinks != nil && time.Since(s.loadedAt) < eventSinksRefresh {
+		defer s.mu.RUnlock()
+		return s.sinks
+	}
+	s.mu.RUnlock()
+
+	all, err := s.db.GetEventSinks()
+	if err != nil {
+		log.Printf("Failed to load event sinks: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.sinks
+	}
+	enabled := []models.EventSink{}
+	for _, sink := range all {
+		if sink.Enabled {
+			enabled = append(enabled, sink)
+		}
+	}
+
+	s.mu.Lock()
+	s.sinks = enabled
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return enabled
+}
+
+func (s *ForwardingService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// sinkState tracks the forwarder's schedule for one sink
+type sinkState struct {
+	lastFlush time.Time
+	backoff   time.Duration
+	retryAt   time.Time
+}
+
+// StartForwarder delivers queued events to their sinks until ctx is done
+func (s *ForwardingService) StartForwarder(ctx context.Context) {
+	log.Println("Starting event forwarder...")
+
+	ticker := time.NewTicker(forwardInterval)
+	defer ticker.Stop()
+
+	states := make(map[uuid.UUID]*sinkState)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Event forwarder stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// Disabled sinks keep their queue until they are enabled again
+		for _, sink := range s.enabledSinks() {
+			state := states[sink.ID]
+			if state == nil {
+				state = &sinkState{lastFlush: time.Now()}
+				states[sink.ID] = state
+			}
+			s.flushSink(ctx, &sink, state)
+		}
+	}
+}
+
+func (s *ForwardingService) flushSink(ctx context.Context, sink *models.EventSink, state *sinkState) {
+	now := time.Now()
+	if now.Before(state.retryAt) {
+		return
+	}
+
+	for ctx.Err() == nil {
+		events, err := s.redis.PeekForwardEvents(ctx, sink.ID, forwardBatchSize)
+		if err != nil {
+			log.Printf("Failed to read queue of sink %s: %v", sink.Name, err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		// S3 objects are written once a minute unless a full batch is waiting
+		if sink.Kind == "s3" && len(events) < forwardBatchSize && now.Sub(state.lastFlush) < forwardS3Interval {
+			return
+		}
+
+		err = s.deliver(ctx, sink, events)
+		if recordErr := s.db.RecordEventSinkDelivery(sink.ID, time.Now().UTC(), err); recordErr != nil {
+			log.Printf("Failed to record delivery to sink %s: %v", sink.Name, recordErr)
+		}
+		if err != nil {
+			state.backoff = min(max(2*state.backoff, forwardInterval), forwardMaxBackoff)
+			state.retryAt = time.Now().Add(state.backoff)
+			log.Printf("Failed to forward %d events to sink %s, retrying in %s: %v", len(events), sink.Name, state.backoff, err)
+			return
+		}
+		state.backoff = 0
+		state.lastFlush = time.Now()
+
+		if err := s.redis.AckForwardEvents(ctx, sink.ID, len(events)); err != nil {
+			// The batch may be delivered again; receivers deduplicate on the event ID
+			log.Printf("Failed to remove forwarded events of sink %s: %v", sink.Name, err)
+			return
+		}
+		if len(events) < forwardBatchSize {
+			return
+		}
+	}
+}
+
+func (s *ForwardingService) deliver(ctx context.Context, sink *models.EventSink, events []string) error {
+	switch sink.Kind {
+	case "http":
+		return s.deliverHTTP(ctx, sink, events)
+	case "kafka":
+		return s.deliverKafka(ctx, sink, events)
+	case "s3":
+		return s.deliverS3(ctx, sink, events)
+	}
+	return fmt.Errorf("unknown sink kind %q", sink.Kind)
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidGELF is returned when a GELF message cannot be parsed
+var ErrInvalidGELF = errors.New("invalid GELF message")
+
+const (
+	// maxGELFMessageBytes caps a message after decompression, so a small
+	// compressed datagram cannot expand without bound
+	maxGELFMessageBytes = 1 << 20
+	// maxGELFChunks is the most chunks a UDP message may be split into
+	maxGELFChunks = 128
+	// maxGELFChunkSets and maxGELFBufferedBytes bound the partial messages
+	// held at once; chunks of new messages are dropped past either cap, so
+	// a flood of first chunks cannot exhaust memory
+	maxGELFChunkSets     = 1024
+	maxGELFBufferedBytes = 64 << 20
+	// gelfChunkTimeout is how long the chunks of a message are waited for
+	gelfChunkTimeout = 5 * time.Second
+	// gelfChunkHeaderBytes is the magic bytes, the message ID, the sequence
+	// number and the sequence count
+	gelfChunkHeaderBytes = 12
+)
+
+// gelfErrorLevel is the least severe syslog level recorded as an error
+// event: emergency (0) to error (3) become "error" and warning (4) becomes
+// "warning". Notice and info (5, 6) and debug (7) go to the log store.
+const gelfErrorLevel = 4
+
+// gelfMessage is a GELF 1.1 message. Additional fields are the ones
+// prefixed with an underscore, such as Docker's _container_name.
+type gelfMessage struct {
+	Host         string
+	ShortMessage string
+	FullMessage  string
+	Timestamp    *time.Time
+	Level        int
+	Fields       map[string]interface{}
+}
+
+// decompressGELF inflates gzip and zlib payloads; anything else is passed
+// through as uncompressed JSON
+func decompressGELF(data []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch {
+	case len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b:
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+	case len(data) >= 2 && data[0] == 0x78:
+		reader, err = zlib.NewReader(bytes.NewReader(data))
+	default:
+		if len(data) > maxGELFMessageBytes {
+			return nil, fmt.Errorf("%w: message over %d bytes", ErrInvalidGELF, maxGELFMessageBytes)
+		}
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGELF, err)
+	}
+	defer reader.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxGELFMessageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGELF, err)
+	}
+	if len(inflated) > maxGELFMessageBytes {
+		return nil, fmt.Errorf("%w: message over %d bytes", ErrInvalidGELF, maxGELFMessageBytes)
+	}
+	return inflated, nil
+}
+
+// parseGELF decodes a message, compressed or not. The level defaults to 1
+// (alert), as the GELF specification says.
+func parseGELF(data []byte) (*gelfMessage, error) {
+	data, err := decompressGELF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGELF, err)
+	}
+
+	message := &gelfMessage{Level: 1, Fields: map[string]interface{}{}}
+	message.ShortMessage, _ = raw["short_message"].(string)
+	if strings.TrimSpace(message.ShortMessage) == "" {
+		return nil, fmt.Errorf("%w: short_message is required", ErrInvalidGELF)
+	}
+	message.Host, _ = raw["host"].(string)
+	message.FullMessage, _ = raw["full_message"].(string)
+	if level, ok := raw["level"].(float64); ok {
+		if level < 0 || level > 7 {
+			return nil, fmt.Errorf("%w: level must be a syslog level from 0 to 7", ErrInvalidGELF)
+		}
+		message.Level = int(level)
+	}
+	if timestamp, ok := raw["timestamp"].(float64); ok && timestamp > 0 {
+		seconds, fraction := math.Modf(timestamp)
+		at := time.Unix(int64(seconds), int64(fraction*1e9)).UTC()
+		message.Timestamp = &at
+	}
+
+	for name, value := range raw {
+		// _id is reserved by the specification
+		if field, ok := strings.CutPrefix(name, "_"); ok && field != "" && field != "id" {
+			message.Fields[field] = value
+		}
+	}
+	// Deprecated GELF 1.0 fields
+	for _, name := range []string{"facility", "file", "line"} {
+		if value, ok := raw[name]; ok {
+			message.Fields[name] = value
+		}
+	}
+
+	return message, nil
+}
+
+// field returns an additional string field, matched case-insensitively
+// because Docker passes labels and environment variables with their own case
+func (m *gelfMessage) field(name string) string {
+	for key, value := range m.Fields {
+		if strings.EqualFold(key, name) {
+			if s, ok := value.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// source is the container name for Docker's log driver, else the tag or the
+// host, cut to the 50 characters of the source column
+func (m *gelfMessage) source() string {
+	source := "gelf"
+	for _, candidate := range []string{m.field("container_name"), m.field("tag"), m.Host} {
+		if candidate = strings.TrimPrefix(candidate, "/"); candidate != "" {
+			source = candidate
+			break
+		}
+	}
+	if shortened, cut := shortenContextValue(source, 50); cut {
+		source = shortened
+	}
+	return source
+}
+
+func (m *gelfMessage) context() map[string]interface{} {
+	details := make(map[string]interface{}, len(m.Fields)+2)
+	for name, value := range m.Fields {
+		details[name] = value
+	}
+	if m.Host != "" {
+		details["host"] = m.Host
+	}
+	if m.Timestamp != nil {
+		details["gelf_timestamp"] = m.Timestamp.Format(time.RFC3339Nano)
+	}
+	return details
+}
+
+func (m *gelfMessage) environment() string {
+	if environment := m.field("environment"); len(environment) <= 50 {
+		return environment
+	}
+	return ""
+}
+
+// isError reports whether the message is severe enough to be an error event
+func (m *gelfMessage) isError() bool {
+	return m.Level <= gelfErrorLevel
+}
+
+// toErrorRequest builds the error event. The full message, which GELF uses
+// for backtraces, becomes the stack trace.
+func (m *gelfMessage) toErrorRequest() *models.CreateErrorRequest {
+	level := "error"
+	if m.Level == gelfErrorLevel {
+		level = "warning"
+	}
+	req := &models.CreateErrorRequest{
+		Level:   level,
+		Message: strings.TrimSpace(m.ShortMessage),
+		Context: m.context(),
+		Source:  m.source(),
+	}
+	if m.FullMessage != "" {
+		fullMessage := m.FullMessage
+		req.StackTrace = &fullMessage
+	}
+	if environment := m.environment(); environment != "" {
+		req.Environment = &environment
+	}
+	return req
+}
+
+func (m *gelfMessage) toLogRequest() models.CreateLogRequest {
+	level := "info"
+	if m.Level == 7 {
+		level = "debug"
+	}
+	details := m.context()
+	if m.FullMessage != "" {
+		details["full_message"] = m.FullMessage
+	}
+	return models.CreateLogRequest{
+		Level:       level,
+		Message:     strings.TrimSpace(m.ShortMessage),
+		Source:      m.source(),
+		Environment: m.environment(),
+		Context:     details,
+		Timestamp:   m.Timestamp,
+	}
+}
+
+// gelfChunks reassembles chunked UDP messages. Messages missing chunks are
+// dropped once gelfChunkTimeout has passed since their first chunk.
+type gelfChunks struct {
+	mu       sync.Mutex
+	messages map[string]*gelfChunkSet
+	// buffered is the total size of the chunks held
+	buffered int
+}
+
+type gelfChunkSet struct {
+	started  time.Time
+	chunks   [][]byte
+	received int
+	size     int
+}
+
+func newGELFChunks() *gelfChunks {
+	return &gelfChunks{messages: make(map[string]*gelfChunkSet)}
+}
+
+func isGELFChunk(datagram []byte) bool {
+	return len(datagram) >= gelfChunkHeaderBytes && datagram[0] == 0x1e && datagram[1] == 0x0f
+}
+
+// add stores a chunk and returns the whole message once its last chunk arrived
+func (c *gelfChunks) add(datagram []byte, now time.Time) ([]byte, error) {
+	id := string(datagram[2:10])
+	sequence, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > maxGELFChunks || sequence >= count {
+		return nil, fmt.Errorf("%w: chunk %d of %d", ErrInvalidGELF, sequence, count)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chunkSize := len(datagram) - gelfChunkHeaderBytes
+	set, ok := c.messages[id]
+	if !ok {
+		if len(c.messages) >= maxGELFChunkSets || c.buffered+chunkSize > maxGELFBufferedBytes {
+			return nil, fmt.Errorf("%w: too many partial messages buffered", ErrInvalidGELF)
+		}
+		set = &gelfChunkSet{started: now, chunks: make([][]byte, count)}
+		c.messages[id] = set
+	}
+	if len(set.chunks) != count {
+		c.drop(id, set)
+		return nil, fmt.Errorf("%w: chunk count changed", ErrInvalidGELF)
+	}
+	if set.chunks[sequence] == nil {
+		set.chunks[sequence] = append([]byte(nil), datagram[gelfChunkHeaderBytes:]...)
+		set.received++
+		set.size += chunkSize
+		c.buffered += chunkSize
+	}
+	if set.size > maxGELFMessageBytes {
+		c.drop(id, set)
+		return nil, fmt.Errorf("%w: message over %d bytes", ErrInvalidGELF, maxGELFMessageBytes)
+	}
+	if set.received < count {
+		return nil, nil
+	}
+
+	c.drop(id, set)
+	return bytes.Join(set.chunks, nil), nil
+}
+
+// drop forgets a message's chunks; the caller holds c.mu
+func (c *gelfChunks) drop(id string, set *gelfChunkSet) {
+	delete(c.messages, id)
+	c.buffered -= set.size
+}
+
+// expire drops messages whose chunks did not all arrive in time and returns
+// how many were dropped
+func (c *gelfChunks) expire(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped := 0
+	for id, set := range c.messages {
+		if now.Sub(set.started) > gelfChunkTimeout {
+			c.drop(id, set)
+			dropped++
+		}
+	}
+	return dropped
+}
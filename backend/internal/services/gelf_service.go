@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// maxGELFDatagramBytes is the largest UDP datagram read; GELF chunks are at
+// most 8192 bytes
+const maxGELFDatagramBytes = 65536
+
+// GelfService accepts GELF (Graylog Extended Log Format) messages, which
+// Docker's gelf log driver and most log shippers can send. Messages at
+// warning level or above become error events; the rest go to the log store.
+type GelfService struct {
+	errorService *ErrorService
+	logService   *LogService
+	quotaService *QuotaService
+	// projectID is the project of messages received over UDP and TCP, which
+	// carry no API key
+	projectID *uuid.UUID
+}
+
+// NewGelfService creates the service. projectID may be empty, in which case
+// messages received over UDP and TCP belong to no project.
+func NewGelfService(errorService *ErrorService, logService *LogService, quotaService *QuotaService, projectID string) (*GelfService, error) {
+	s := &GelfService{
+		errorService: errorService,
+		logService:   logService,
+		quotaService: quotaService,
+	}
+	if projectID != "" {
+		id, err := uuid.Parse(projectID)
+		if err != nil {
+			return nil, err
+		}
+		s.projectID = &id
+	}
+	return s, nil
+}
+
+// Ingest records one GELF message, compressed or not. It returns false when
+// the message was an error event dropped by throttling.
+func (s *GelfService) Ingest(ctx context.Context, data []byte, projectID *uuid.UUID, ipAddress string) (bool, error) {
+	message, err := parseGELF(data)
+	if err != nil {
+		return false, err
+	}
+
+	if !message.isError() {
+		_, err := s.logService.IngestLogs(ctx, &models.IngestLogsRequest{
+			Logs: []models.CreateLogRequest{message.toLogRequest()},
+		}, projectID)
+		if errors.Is(err, ErrInvalidLog) {
+			return false, fmt.Errorf("%w: %v", ErrInvalidGELF, err)
+		}
+		return err == nil, err
+	}
+
+	if projectID != nil {
+		if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+			return false, err
+		}
+	}
+
+	_, err = s.errorService.CreateError(ctx, message.toErrorRequest(), projectID, "gelf", ipAddress)
+	if err == ErrEventThrottled {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListenUDP binds addr and receives GELF datagrams, chunked or not, until
+// ctx is done. It returns once the socket is bound.
+func (s *GelfService) ListenUDP(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening for GELF messages on udp %s", conn.LocalAddr())
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		chunks := newGELFChunks()
+		buffer := make([]byte, maxGELFDatagramBytes)
+		lastExpiry := time.Now()
+
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Println("GELF UDP listener stopped")
+					return
+				}
+				log.Printf("Failed to read GELF datagram: %v", err)
+				continue
+			}
+
+			now := time.Now()
+			if now.Sub(lastExpiry) > gelfChunkTimeout {
+				if dropped := chunks.expire(now); dropped > 0 {
+					log.Printf("Dropped %d incomplete GELF messages", dropped)
+				}
+				lastExpiry = now
+			}
+
+			datagram := buffer[:n]
+			if isGELFChunk(datagram) {
+				datagram, err = chunks.add(datagram, now)
+				if err != nil {
+					log.Printf("Dropped GELF chunk from %s: %v", from, err)
+					continue
+				}
+				if datagram == nil {
+					continue
+				}
+			}
+
+			s.ingestReceived(ctx, datagram, remoteIP(from))
+		}
+	}()
+
+	return nil
+}
+
+// ListenTCP binds addr and receives null-byte delimited GELF messages until
+// ctx is done. It returns once the socket is bound.
+func (s *GelfService) ListenTCP(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening for GELF messages on tcp %s", listener.Addr())
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Println("GELF TCP listener stopped")
+					return
+				}
+				log.Printf("Failed to accept GELF connection: %v", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			go s.serveTCP(ctx, conn)
+		}
+	}()
+
+	return nil
+}
+
+func (s *GelfService) serveTCP(ctx context.Context, conn net.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	ipAddress := remoteIP(conn.RemoteAddr())
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxGELFMessageBytes+1)
+	scanner.Split(splitGELFFrames)
+	for scanner.Scan() {
+		if frame := bytes.TrimSpace(scanner.Bytes()); len(frame) > 0 {
+			s.ingestReceived(ctx, frame, ipAddress)
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		log.Printf("Closed GELF connection from %s: %v", ipAddress, err)
+	}
+}
+
+// ingestReceived records a message from UDP or TCP, where there is no one to
+// answer, so failures are only logged
+func (s *GelfService) ingestReceived(ctx context.Context, data []byte, ipAddress string) {
+	if _, err := s.Ingest(ctx, data, s.projectID, ipAddress); err != nil {
+		log.Printf("Failed to record GELF message from %s: %v", ipAddress, err)
+	}
+}
+
+// splitGELFFrames splits a TCP stream on the null bytes ending each message
+func splitGELFFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
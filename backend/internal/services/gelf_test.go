@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testGELFMessage = `{"version":"1.1","host":"web-1","short_message":"connection refused",` +
+	`"full_message":"Traceback:\n  app.py:10","level":3,"timestamp":1735689600.5,` +
+	`"_container_name":"/api","_environment":"production","_id":"ignored","facility":"app"}`
+
+func TestParseGELF(t *testing.T) {
+	var gzipped, zlibbed bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte(testGELFMessage))
+	gw.Close()
+	zw := zlib.NewWriter(&zlibbed)
+	zw.Write([]byte(testGELFMessage))
+	zw.Close()
+
+	for name, data := range map[string][]byte{
+		"plain": []byte(testGELFMessage),
+		"gzip":  gzipped.Bytes(),
+		"zlib":  zlibbed.Bytes(),
+	} {
+		message, err := parseGELF(data)
+		if err != nil {
+			t.Fatalf("%s: parseGELF: %v", name, err)
+		}
+		if message.Level != 3 || !message.isError() {
+			t.Errorf("%s: Level = %d, want an error level 3", name, message.Level)
+		}
+		if want := time.Unix(1735689600, 5e8).UTC(); message.Timestamp == nil || !message.Timestamp.Equal(want) {
+			t.Errorf("%s: Timestamp = %v, want %v", name, message.Timestamp, want)
+		}
+		if _, ok := message.Fields["id"]; ok {
+			t.Errorf("%s: the reserved _id field was kept", name)
+		}
+		if message.Fields["facility"] != "app" {
+			t.Errorf("%s: facility = %v, want app", name, message.Fields["facility"])
+		}
+
+		req := message.toErrorRequest()
+		if req.Level != "error" || req.Message != "connection refused" || req.Source != "api" {
+			t.Errorf("%s: level, message, source = %q, %q, %q", name, req.Level, req.Message, req.Source)
+		}
+		if req.StackTrace == nil || *req.StackTrace != "Traceback:\n  app.py:10" {
+			t.Errorf("%s: StackTrace = %v, want the full message", name, req.StackTrace)
+		}
+		if req.Environment == nil || *req.Environment != "production" {
+			t.Errorf("%s: Environment = %v, want production", name, req.Environment)
+		}
+	}
+}
+
+func TestParseGELFDefaults(t *testing.T) {
+	message, err := parseGELF([]byte(`{"short_message":" disk full ","host":"db-2"}`))
+	if err != nil {
+		t.Fatalf("parseGELF: %v", err)
+	}
+	// The specification's default level is alert
+	if message.Level != 1 {
+		t.Errorf("Level = %d, want 1", message.Level)
+	}
+	if got := message.source(); got != "db-2" {
+		t.Errorf("source() = %q, want the host", got)
+	}
+
+	message.Level = gelfErrorLevel
+	if req := message.toErrorRequest(); req.Level != "warning" || req.Message != "disk full" {
+		t.Errorf("level, message = %q, %q, want warning, disk full", req.Level, req.Message)
+	}
+	message.Level = 6
+	if message.isError() {
+		t.Error("isError() = true for an info message")
+	}
+}
+
+func TestParseGELFRejectsInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", `short_message=oops`},
+		{"missing short_message", `{"host":"web-1"}`},
+		{"blank short_message", `{"short_message":"  "}`},
+		{"level out of range", `{"short_message":"oops","level":9}`},
+		{"oversized", `{"short_message":"` + string(bytes.Repeat([]byte("a"), maxGELFMessageBytes)) + `"}`},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseGELF([]byte(tt.data)); !errors.Is(err, ErrInvalidGELF) {
+			t.Errorf("%s: err = %v, want ErrInvalidGELF", tt.name, err)
+		}
+	}
+}
+
+func testGELFChunk(id byte, sequence, count int, data string) []byte {
+	chunk := []byte{0x1e, 0x0f, id, 0, 0, 0, 0, 0, 0, 0, byte(sequence), byte(count)}
+	return append(chunk, data...)
+}
+
+func TestGELFChunks(t *testing.T) {
+	chunks := newGELFChunks()
+	now := time.Now()
+
+	if message, err := chunks.add(testGELFChunk(1, 1, 2, `"oops"}`), now); message != nil || err != nil {
+		t.Fatalf("first chunk: message, err = %q, %v, want nil, nil", message, err)
+	}
+	message, err := chunks.add(testGELFChunk(1, 0, 2, `{"short_message":`), now)
+	if err != nil || string(message) != `{"short_message":"oops"}` {
+		t.Fatalf("last chunk: message, err = %q, %v", message, err)
+	}
+	if len(chunks.messages) != 0 || chunks.buffered != 0 {
+		t.Errorf("%d messages and %d bytes still buffered", len(chunks.messages), chunks.buffered)
+	}
+
+	if _, err := chunks.add(testGELFChunk(2, 2, 2, "x"), now); !errors.Is(err, ErrInvalidGELF) {
+		t.Errorf("sequence past count: err = %v, want ErrInvalidGELF", err)
+	}
+
+	chunks.add(testGELFChunk(3, 0, 2, "x"), now)
+	if dropped := chunks.expire(now.Add(gelfChunkTimeout + time.Second)); dropped != 1 {
+		t.Errorf("expire() = %d, want 1", dropped)
+	}
+	if chunks.buffered != 0 {
+		t.Errorf("%d bytes still buffered after expiry", chunks.buffered)
+	}
+}
+
+func TestGELFChunksCapsPartialMessages(t *testing.T) {
+	chunks := newGELFChunks()
+	now := time.Now()
+	for i := 0; i < maxGELFChunkSets; i++ {
+		chunk := testGELFChunk(0, 0, 2, "x")
+		chunk[2], chunk[3] = byte(i), byte(i>>8)
+		if _, err := chunks.add(chunk, now); err != nil {
+			t.Fatalf("chunk set %d: %v", i, err)
+		}
+	}
+	chunk := testGELFChunk(0, 0, 2, "x")
+	chunk[4] = 1
+	if _, err := chunks.add(chunk, now); !errors.Is(err, ErrInvalidGELF) {
+		t.Errorf("err = %v past %d partial messages, want ErrInvalidGELF", err, maxGELFChunkSets)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	goroutineHeader = regexp.MustCompile(`^goroutine (\d+) \[([^\]]*)\]:\s*$`)
+	goFileLine      = regexp.MustCompile(`^\s+(.+):(\d+)(?:\s+\+0x[0-9a-fA-F]+)?\s*$`)
+	goHexAddress    = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+)
+
+type goFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	InApp    bool   `json:"in_app"`
+
+	raw []string
+}
+
+// goStack is the panicking goroutine of a Go panic or fatal error trace
+type goStack struct {
+	Panic       []string  `json:"panic"`
+	GoroutineID int       `json:"goroutine_id"`
+	State       string    `json:"state"`
+	Goroutines  int       `json:"goroutines"`
+	Frames      []goFrame `json:"frames"`
+
+	header string
+}
+
+// parseGoStack parses a Go panic trace, keeping only the panicking goroutine
+// and dropping runtime frames. Frames whose function starts with "main." or
+// one of inAppPrefixes are marked in-app.
+func parseGoStack(trace string, inAppPrefixes []string) (*goStack, bool) {
+	lines := strings.Split(strings.ReplaceAll(trace, "\r\n", "\n"), "\n")
+
+	stack := &goStack{}
+	var current []goFrame
+	var currentID int
+	var currentState, currentHeader string
+	found, seenGoroutine := false, false
+
+	flush := func() {
+		if currentHeader == "" {
+			return
+		}
+		stack.Goroutines++
+		// Go prints the panicking goroutine first
+		if !found {
+			stack.GoroutineID = currentID
+			stack.State = currentState
+			stack.Frames = current
+			stack.header = currentHeader
+			found = true
+		}
+		current, currentHeader = nil, ""
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := goroutineHeader.FindStringSubmatch(line); m != nil {
+			flush()
+			seenGoroutine = true
+			currentID, _ = strconv.Atoi(m[1])
+			currentState = m[2]
+			currentHeader = line
+			continue
+		}
+
+		if currentHeader == "" {
+			if seenGoroutine {
+				continue
+			}
+			if strings.HasPrefix(line, "panic:") || strings.HasPrefix(line, "fatal error:") ||
+				(len(stack.Panic) > 0 && strings.TrimSpace(line) != "") {
+				stack.Panic = append(stack.Panic, line)
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		// A function line is followed by its tab-indented file:line
+		if i+1 >= len(lines) {
+			continue
+		}
+		location := goFileLine.FindStringSubmatch(lines[i+1])
+		if location == nil {
+			continue
+		}
+
+		frame := goFrame{
+			Function: goFunctionName(line),
+			File:     location[1],
+			raw:      []string{line, lines[i+1]},
+		}
+		frame.Line, _ = strconv.Atoi(location[2])
+		frame.InApp = isInAppFunction(frame.Function, inAppPrefixes)
+		i++
+
+		if isRuntimeFrame(frame.Function) {
+			continue
+		}
+		current = append(current, frame)
+	}
+	flush()
+
+	if !found {
+		return nil, false
+	}
+	return stack, true
+}
+
+// goFunctionName strips the argument list: "pkg.(*T).M(0x1, {0x2})" -> "pkg.(*T).M".
+// "created by pkg.f in goroutine 1" becomes "created by pkg.f".
+func goFunctionName(line string) string {
+	line = strings.TrimSpace(line)
+	if rest, ok := strings.CutPrefix(line, "created by "); ok {
+		if i := strings.Index(rest, " in goroutine"); i >= 0 {
+			rest = rest[:i]
+		}
+		return "created by " + rest
+	}
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isRuntimeFrame(function string) bool {
+	function = strings.TrimPrefix(function, "created by ")
+	return function == "panic" || strings.HasPrefix(function, "runtime.")
+}
+
+func isInAppFunction(function string, inAppPrefixes []string) bool {
+	function = strings.TrimPrefix(function, "created by ")
+	if strings.HasPrefix(function, "main.") {
+		return true
+	}
+	for _, prefix := range inAppPrefixes {
+		if prefix != "" && strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// render rebuilds the trace with the panic message and the trimmed panicking goroutine
+func (g *goStack) render() string {
+	var b strings.Builder
+	for _, line := range g.Panic {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if len(g.Panic) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(g.header)
+	for _, frame := range g.Frames {
+		for _, line := range frame.raw {
+			b.WriteString("\n")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// fingerprint groups on the message and function names of in-app frames (all
+// frames if none are in-app), ignoring goroutine IDs, addresses, and lines
+func (g *goStack) fingerprint(message string) string {
+	var functions []string
+	for _, frame := range g.Frames {
+		if frame.InApp {
+			functions = append(functions, frame.Function)
+		}
+	}
+	if len(functions) == 0 {
+		for _, frame := range g.Frames {
+			functions = append(functions, frame.Function)
+		}
+	}
+
+	data := goHexAddress.ReplaceAllString(message, "0x?") + "\n" + strings.Join(functions, "\n")
+	hash := sha256.Sum256([]byte(data))
+	return fmt.Sprintf("%x", hash)[:16]
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGoPanic = `panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a2b3c]
+
+goroutine 42 [running]:
+panic({0x6b1e20?, 0x8d2f40?})
+	/usr/local/go/src/runtime/panic.go:770 +0x132
+github.com/acme/shop/internal/cart.(*Cart).Total(0x0)
+	/app/internal/cart/cart.go:57 +0x1c
+main.handleCheckout({0x7f1a2c, 0xc000124000}, 0xc000132000)
+	/app/main.go:112 +0x45
+net/http.HandlerFunc.ServeHTTP(0x0?, {0x7f1a2c?, 0xc000124000?}, 0x0?)
+	/usr/local/go/src/net/http/server.go:2166 +0x29
+created by net/http.(*Server).Serve in goroutine 1
+	/usr/local/go/src/net/http/server.go:3285 +0x4b4
+
+goroutine 1 [IO wait]:
+internal/poll.runtime_pollWait(0x7f3c, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:345 +0x85
+`
+
+func TestParseGoStack(t *testing.T) {
+	stack, ok := parseGoStack(testGoPanic, []string{"github.com/acme/shop/"})
+	if !ok {
+		t.Fatal("parseGoStack did not recognize the panic")
+	}
+	if len(stack.Panic) != 2 || !strings.HasPrefix(stack.Panic[0], "panic: runtime error") {
+		t.Errorf("Panic = %q", stack.Panic)
+	}
+	if stack.GoroutineID != 42 || stack.State != "running" || stack.Goroutines != 2 {
+		t.Errorf("goroutine %d [%s] of %d, want 42 [running] of 2", stack.GoroutineID, stack.State, stack.Goroutines)
+	}
+
+	want := []goFrame{
+		{Function: "github.com/acme/shop/internal/cart.(*Cart).Total", File: "/app/internal/cart/cart.go", Line: 57, InApp: true},
+		{Function: "main.handleCheckout", File: "/app/main.go", Line: 112, InApp: true},
+		{Function: "net/http.HandlerFunc.ServeHTTP", File: "/usr/local/go/src/net/http/server.go", Line: 2166},
+		{Function: "created by net/http.(*Server).Serve", File: "/usr/local/go/src/net/http/server.go", Line: 3285},
+	}
+	if len(stack.Frames) != len(want) {
+		t.Fatalf("%d frames, want %d: %+v", len(stack.Frames), len(want), stack.Frames)
+	}
+	for i, frame := range stack.Frames {
+		frame.raw = nil
+		if !reflect.DeepEqual(frame, want[i]) {
+			t.Errorf("Frames[%d] = %+v, want %+v", i, frame, want[i])
+		}
+	}
+
+	rendered := stack.render()
+	if strings.Contains(rendered, "goroutine 1 [IO wait]") || strings.Contains(rendered, "runtime/panic.go") {
+		t.Errorf("render() kept other goroutines or runtime frames:\n%s", rendered)
+	}
+	if !strings.Contains(rendered, "goroutine 42 [running]:\ngithub.com/acme/shop/internal/cart.(*Cart).Total(0x0)") {
+		t.Errorf("render() lost the panicking goroutine:\n%s", rendered)
+	}
+}
+
+func TestParseGoStackRejectsOtherTraces(t *testing.T) {
+	for _, trace := range []string{
+		"",
+		"Traceback (most recent call last):\n  File \"app.py\", line 10, in <module>",
+		"panic: boom",
+	} {
+		if _, ok := parseGoStack(trace, nil); ok {
+			t.Errorf("parseGoStack(%q) recognized a Go panic", trace)
+		}
+	}
+}
+
+func TestGoStackFingerprint(t *testing.T) {
+	first, _ := parseGoStack(testGoPanic, nil)
+	// Another occurrence: a different goroutine, addresses and line offsets
+	other := strings.NewReplacer("goroutine 42", "goroutine 7", "0xc000124000", "0xc000998000", "+0x1c", "+0x20").Replace(testGoPanic)
+	second, _ := parseGoStack(other, nil)
+
+	message := "runtime error: invalid memory address or nil pointer dereference"
+	if first.fingerprint(message) != second.fingerprint(message) {
+		t.Error("fingerprints differ for the same panic")
+	}
+	if first.fingerprint(message) == first.fingerprint("index out of range") {
+		t.Error("fingerprints match for different messages")
+	}
+	if first.fingerprint("at 0xc000124000") != first.fingerprint("at 0xc000998000") {
+		t.Error("fingerprints differ on addresses in the message")
+	}
+}
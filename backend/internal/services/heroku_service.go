@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidHerokuLogs is returned when a logplex body cannot be parsed
+var ErrInvalidHerokuLogs = errors.New("invalid heroku logs")
+
+// herokuCriticalCodes are the platform errors that take the app down rather
+// than fail one request: crashes, boot timeouts and severe memory overruns
+var herokuCriticalCodes = map[string]bool{
+	"H10": true, // App crashed
+	"R10": true, // Boot timeout
+	"R15": true, // Memory quota vastly exceeded
+}
+
+var (
+	// key=value pairs of router lines; values with spaces are quoted
+	herokuLogField = regexp.MustCompile(`(\w+)=("[^"]*"|\S*)`)
+	// Error R14 (Memory quota exceeded)
+	herokuDynoError = regexp.MustCompile(`^Error (R\d+) \(([^)]+)\)(?: -> (.*))?`)
+	herokuCrashed   = regexp.MustCompile(`^State changed from \w+ to crashed`)
+	// Error lines in the usual formats of application loggers: ERROR as a
+	// word, level=error, "level": "error", and Ruby's "E, [" prefix
+	herokuAppError = regexp.MustCompile(`\b(ERROR|FATAL|CRITICAL)\b|\blevel=(error|fatal|critical)\b|"level":\s*"(error|fatal|critical)"|^([EF]), \[`)
+	// E, [2025-08-29T12:03:10.250000 #4] ERROR -- : is stripped, so the
+	// timestamp does not make every line its own group
+	herokuRubyPrefix = regexp.MustCompile(`^[A-Z], \[[^\]]*\]\s+\w+ -- [^:]*: `)
+)
+
+// herokuLine is one syslog frame of a logplex body:
+// <190>1 2025-08-29T12:03:10.250000+00:00 host app web.1 - message
+type herokuLine struct {
+	Timestamp string
+	AppName   string // app for the application's own output, heroku for the platform
+	ProcID    string // the dyno, e.g. web.1, or router
+	Message   string
+}
+
+// HerokuService turns Heroku log drain deliveries into error events: router
+// and dyno errors reported by the platform (H and R codes) and error lines
+// the application writes
+type HerokuService struct {
+	errorService *ErrorService
+	quotaService *QuotaService
+}
+
+func NewHerokuService(errorService *ErrorService, quotaService *QuotaService) *HerokuService {
+	return &HerokuService{
+		errorService: errorService,
+		quotaService: quotaService,
+	}
+}
+
+// IngestLogs records the errors in a logplex body. Other lines are dropped.
+// source names the app, since logplex frames do not. It returns the number
+// of events recorded.
+func (s *HerokuService) IngestLogs(ctx context.Context, body []byte, projectID *uuid.UUID, source, environment, drainToken, ipAddress string) (int, error) {
+	lines, err := parseLogplex(body)
+	if err != nil {
+		return 0, err
+	}
+	if source == "" || len(source) > 50 {
+		source = "heroku"
+	}
+
+	accepted := 0
+	for _, line := range lines {
+		req := line.toErrorRequest(source)
+		if req == nil {
+			continue
+		}
+		if drainToken != "" {
+			req.Context["heroku_drain_token"] = drainToken
+		}
+		if environment != "" {
+			req.Environment = &environment
+		}
+
+		if projectID != nil {
+			if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+				return accepted, err
+			}
+		}
+
+		_, err := s.errorService.CreateError(ctx, req, projectID, "logplex", ipAddress)
+		if err == ErrEventThrottled {
+			continue
+		}
+		if err != nil {
+			return accepted, err
+		}
+		accepted++
+	}
+
+	return accepted, nil
+}
+
+// parseLogplex splits an application/logplex-1 body, a run of syslog frames
+// each prefixed with its length in bytes
+func parseLogplex(body []byte) ([]herokuLine, error) {
+	var lines []herokuLine
+	for {
+		body = bytes.TrimLeft(body, " \r\n")
+		if len(body) == 0 {
+			break
+		}
+		space := bytes.IndexByte(body, ' ')
+		if space <= 0 {
+			return nil, fmt.Errorf("%w: missing frame length", ErrInvalidHerokuLogs)
+		}
+		length, err := strconv.Atoi(string(body[:space]))
+		if err != nil || length < 0 || length > len(body)-space-1 {
+			return nil, fmt.Errorf("%w: bad frame length %q", ErrInvalidHerokuLogs, body[:space])
+		}
+		frame := string(body[space+1 : space+1+length])
+		body = body[space+1+length:]
+
+		// PRI and version, timestamp, hostname, app name, proc ID, msg ID, message
+		fields := strings.SplitN(frame, " ", 7)
+		if len(fields) < 7 || !strings.HasPrefix(fields[0], "<") {
+			return nil, fmt.Errorf("%w: malformed syslog frame", ErrInvalidHerokuLogs)
+		}
+		lines = append(lines, herokuLine{
+			Timestamp: fields[1],
+			AppName:   fields[3],
+			ProcID:    fields[4],
+			Message:   strings.TrimRight(fields[6], "\r\n"),
+		})
+	}
+	return lines, nil
+}
+
+// processType is the dyno's process type, e.g. web for web.1
+func (l herokuLine) processType() string {
+	processType, _, _ := strings.Cut(l.ProcID, ".")
+	return processType
+}
+
+func (l herokuLine) toErrorRequest(source string) *models.CreateErrorRequest {
+	var req *models.CreateErrorRequest
+	switch {
+	case l.AppName == "heroku" && l.ProcID == "router":
+		req = l.routerError()
+	case l.AppName == "heroku":
+		req = l.dynoError()
+	case l.AppName == "app":
+		req = l.appError()
+	}
+	if req == nil {
+		return nil
+	}
+
+	req.Source = source
+	req.Context["heroku_timestamp"] = l.Timestamp
+	if l.ProcID != "router" {
+		req.Context["heroku_dyno"] = l.ProcID
+		req.Context["heroku_process_type"] = l.processType()
+	}
+	return req
+}
+
+// routerError reads a router line with an H code, e.g.
+// at=error code=H12 desc="Request timeout" method=GET path="/" ... status=503
+func (l herokuLine) routerError() *models.CreateErrorRequest {
+	fields := map[string]string{}
+	for _, match := range herokuLogField.FindAllStringSubmatch(l.Message, -1) {
+		fields[match[1]] = strings.Trim(match[2], `"`)
+	}
+	code := fields["code"]
+	if code == "" || (fields["at"] != "error" && fields["at"] != "warning") {
+		return nil
+	}
+
+	// The path stays in the context, so all requests failing with one code
+	// are one group
+	details := map[string]interface{}{"heroku_code": code}
+	for _, name := range []string{"method", "path", "host", "request_id", "dyno", "connect", "service", "status"} {
+		if value := fields[name]; value != "" {
+			details[name] = value
+		}
+	}
+	if dyno := fields["dyno"]; dyno != "" {
+		processType, _, _ := strings.Cut(dyno, ".")
+		details["heroku_process_type"] = processType
+	}
+
+	level := "error"
+	switch {
+	case herokuCriticalCodes[code]:
+		level = "critical"
+	case fields["at"] == "warning":
+		level = "warning"
+	}
+	return &models.CreateErrorRequest{
+		Level:   level,
+		Message: fmt.Sprintf("Heroku %s: %s", code, fields["desc"]),
+		Context: details,
+	}
+}
+
+// dynoError reads a platform line about a dyno: an R code error, or the
+// dyno crashing
+func (l herokuLine) dynoError() *models.CreateErrorRequest {
+	if match := herokuDynoError.FindStringSubmatch(l.Message); match != nil {
+		details := map[string]interface{}{"heroku_code": match[1]}
+		if match[3] != "" {
+			details["detail"] = match[3]
+		}
+		level := "error"
+		if herokuCriticalCodes[match[1]] {
+			level = "critical"
+		}
+		return &models.CreateErrorRequest{
+			Level:   level,
+			Message: fmt.Sprintf("Heroku %s: %s", match[1], match[2]),
+			Context: details,
+		}
+	}
+	if herokuCrashed.MatchString(l.Message) {
+		return &models.CreateErrorRequest{
+			Level:   "critical",
+			Message: "Heroku dyno crashed",
+			Context: map[string]interface{}{"detail": l.Message},
+		}
+	}
+	return nil
+}
+
+// appError reads a line the application wrote, which is an error when it
+// looks like one in a common logging format
+func (l herokuLine) appError() *models.CreateErrorRequest {
+	match := herokuAppError.FindStringSubmatch(l.Message)
+	if match == nil {
+		return nil
+	}
+	level := "error"
+	for _, severity := range match[1:] {
+		switch strings.ToLower(severity) {
+		case "fatal", "critical", "f":
+			level = "critical"
+		}
+	}
+
+	message := strings.TrimSpace(herokuRubyPrefix.ReplaceAllString(l.Message, ""))
+	if message == "" {
+		message = strings.TrimSpace(l.Message)
+	}
+	return &models.CreateErrorRequest{
+		Level:   level,
+		Message: message,
+		Context: map[string]interface{}{},
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// logplexFrame prefixes a syslog frame with its length, as logplex does
+func logplexFrame(frame string) string {
+	return fmt.Sprintf("%d %s", len(frame), frame)
+}
+
+func TestParseLogplex(t *testing.T) {
+	body := logplexFrame("<158>1 2025-08-29T12:03:10.250000+00:00 host heroku router - at=error code=H12 desc=\"Request timeout\" method=GET path=\"/checkout\" dyno=web.1 status=503\n") +
+		logplexFrame("<190>1 2025-08-29T12:03:11+00:00 host app web.2 - E, [2025-08-29T12:03:11.000000 #4] ERROR -- : undefined method `name' for nil") +
+		"\r\n"
+
+	lines, err := parseLogplex([]byte(body))
+	if err != nil {
+		t.Fatalf("parseLogplex: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want 2", len(lines))
+	}
+	if lines[0].AppName != "heroku" || lines[0].ProcID != "router" || lines[0].Timestamp != "2025-08-29T12:03:10.250000+00:00" {
+		t.Errorf("lines[0] = %+v", lines[0])
+	}
+	if lines[1].processType() != "web" {
+		t.Errorf("processType() = %q, want web", lines[1].processType())
+	}
+}
+
+func TestParseLogplexRejectsMalformedBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing length", "<190>1"},
+		{"length not a number", "abc <190>1 ts host app web.1 - hello"},
+		{"length past the body", "500 <190>1 ts host app web.1 - hello"},
+		{"too few fields", logplexFrame("<190>1 ts host app")},
+		{"missing priority", logplexFrame("190 ts host app web.1 - hello")},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseLogplex([]byte(tt.body)); !errors.Is(err, ErrInvalidHerokuLogs) {
+			t.Errorf("%s: err = %v, want ErrInvalidHerokuLogs", tt.name, err)
+		}
+	}
+}
+
+func TestHerokuLineToErrorRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        herokuLine
+		wantLevel   string
+		wantMessage string
+	}{
+		{"router error", herokuLine{AppName: "heroku", ProcID: "router", Message: `at=error code=H12 desc="Request timeout" method=GET path="/" status=503`},
+			"error", "Heroku H12: Request timeout"},
+		{"router crash", herokuLine{AppName: "heroku", ProcID: "router", Message: `at=error code=H10 desc="App crashed" method=GET path="/"`},
+			"critical", "Heroku H10: App crashed"},
+		{"router warning", herokuLine{AppName: "heroku", ProcID: "router", Message: `at=warning code=H27 desc="Client Request Interrupted"`},
+			"warning", "Heroku H27: Client Request Interrupted"},
+		{"router info", herokuLine{AppName: "heroku", ProcID: "router", Message: `at=info method=GET path="/" status=200`}, "", ""},
+		{"dyno error", herokuLine{AppName: "heroku", ProcID: "web.1", Message: "Error R14 (Memory quota exceeded)"},
+			"error", "Heroku R14: Memory quota exceeded"},
+		{"dyno crash", herokuLine{AppName: "heroku", ProcID: "web.1", Message: "State changed from up to crashed"},
+			"critical", "Heroku dyno crashed"},
+		{"ruby error", herokuLine{AppName: "app", ProcID: "web.1", Message: "E, [2025-08-29T12:03:10.250000 #4] ERROR -- : boom"},
+			"error", "boom"},
+		{"ruby fatal", herokuLine{AppName: "app", ProcID: "worker.1", Message: "F, [2025-08-29T12:03:10.250000 #4] FATAL -- : out of memory"},
+			"critical", "out of memory"},
+		{"logfmt error", herokuLine{AppName: "app", ProcID: "web.1", Message: "level=error msg=\"payment failed\""},
+			"error", "level=error msg=\"payment failed\""},
+		{"JSON critical", herokuLine{AppName: "app", ProcID: "web.1", Message: `{"level": "critical", "msg": "db down"}`},
+			"critical", `{"level": "critical", "msg": "db down"}`},
+		{"app info", herokuLine{AppName: "app", ProcID: "web.1", Message: "GET / 200 12ms"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		req := tt.line.toErrorRequest("heroku")
+		if tt.wantLevel == "" {
+			if req != nil {
+				t.Errorf("%s: got %q, want no error", tt.name, req.Message)
+			}
+			continue
+		}
+		if req == nil {
+			t.Errorf("%s: got no error, want %q", tt.name, tt.wantMessage)
+			continue
+		}
+		if req.Level != tt.wantLevel || req.Message != tt.wantMessage {
+			t.Errorf("%s: level, message = %q, %q, want %q, %q", tt.name, req.Level, req.Message, tt.wantLevel, tt.wantMessage)
+		}
+		if req.Source != "heroku" {
+			t.Errorf("%s: Source = %q, want heroku", tt.name, req.Source)
+		}
+	}
+}
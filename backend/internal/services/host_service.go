@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidHost is returned when a host check-in or query fails validation
+var ErrInvalidHost = errors.New("invalid host")
+
+// hostPruneInterval is how often host samples past the retention period are deleted
+const hostPruneInterval = time.Hour
+
+// HostService keeps the metrics host agents report on each check-in. A host
+// is down once it has not checked in for downAfter.
+type HostService struct {
+	db        *database.DB
+	downAfter time.Duration
+	retention time.Duration
+}
+
+func NewHostService(db *database.DB, downAfterSeconds, retentionDays int) *HostService {
+	return &HostService{
+		db:        db,
+		downAfter: time.Duration(downAfterSeconds) * time.Second,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// CheckIn validates and stores a host agent's check-in
+func (s *HostService) CheckIn(ctx context.Context, req *models.HostCheckInRequest) error {
+	if req.Hostname == "" || len(req.Hostname) > 255 {
+		return fmt.Errorf("%w: hostname is required and must be at most 255 characters", ErrInvalidHost)
+	}
+	if len(req.Environment) > 50 {
+		return fmt.Errorf("%w: environment must be at most 50 characters", ErrInvalidHost)
+	}
+	if len(req.AgentVersion) > 50 {
+		return fmt.Errorf("%w: agent_version must be at most 50 characters", ErrInvalidHost)
+	}
+	for name, percent := range map[string]float64{
+		"cpu_usage_percent":    req.CPUUsagePercent,
+		"memory_usage_percent": req.MemoryUsagePercent,
+		"disk_usage_percent":   req.DiskUsagePercent,
+	} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("%w: %s must be between 0 and 100", ErrInvalidHost, name)
+		}
+	}
+	if req.NetworkIO.BytesIn < 0 || req.NetworkIO.BytesOut < 0 || req.ActiveConnections < 0 || req.RequestsPerMinute < 0 {
+		return fmt.Errorf("%w: network_io, active_connections and requests_per_minute can't be negative", ErrInvalidHost)
+	}
+
+	return s.db.RecordHostCheckIn(req, time.Now().UTC())
+}
+
+// GetHosts returns a page of the hosts matching filter and how many match
+func (s *HostService) GetHosts(ctx context.Context, limit, offset int, filter models.HostFilter) ([]models.Host, int, error) {
+	if filter.Status != "" && filter.Status != "up" && filter.Status != "down" {
+		return nil, 0, fmt.Errorf("%w: status must be up or down", ErrInvalidHost)
+	}
+	return s.db.GetHosts(limit, offset, filter, s.upSince())
+}
+
+// GetHost returns a host with its check-ins over the trailing period
+func (s *HostService) GetHost(ctx context.Context, hostname string, period time.Duration) (*models.HostDetail, error) {
+	host, err := s.db.GetHost(hostname, s.upSince())
+	if err != nil {
+		return nil, err
+	}
+	samples, err := s.db.GetHostSamples(hostname, time.Now().UTC().Add(-period))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.HostDetail{Host: *host, Samples: samples}, nil
+}
+
+// DeleteHost forgets a decommissioned host, so it no longer counts as down
+func (s *HostService) DeleteHost(ctx context.Context, hostname string) error {
+	return s.db.DeleteHost(hostname)
+}
+
+// Totals combines the last check-in of every host that is up
+func (s *HostService) Totals(ctx context.Context) (*models.SystemMetrics, error) {
+	return s.db.GetHostTotals(s.upSince())
+}
+
+func (s *HostService) upSince() time.Time {
+	return time.Now().UTC().Add(-s.downAfter)
+}
+
+// StartSamplePruner periodically deletes host samples past the retention
+// period until ctx is done
+func (s *HostService) StartSamplePruner(ctx context.Context) {
+	log.Println("Starting host sample pruner...")
+
+	ticker := time.NewTicker(hostPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.pruneSamples()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Host sample pruner stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *HostService) pruneSamples() {
+	cutoff := time.Now().UTC().Add(-s.retention)
+	deleted, err := s.db.DeleteHostSamplesBefore(cutoff)
+	if err != nil {
+		log.Printf("Failed to prune host samples: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("HOST RETENTION: deleted %d host samples older than %s", deleted, cutoff.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"error-logs/internal/models"
+)
+
+// impactInterval is how often the impact scores of error groups are refreshed
+const impactInterval = 5 * time.Minute
+
+// StartImpactScorer refreshes the impact score of every active error group
+// until ctx is done
+func (s *ErrorService) StartImpactScorer(ctx context.Context) {
+	log.Println("Starting impact scorer...")
+
+	ticker := time.NewTicker(impactInterval)
+	defer ticker.Stop()
+
+	for {
+		if scored, err := s.db.RefreshImpactScores(time.Now().UTC()); err != nil {
+			log.Printf("Failed to refresh impact scores: %v", err)
+		} else {
+			log.Printf("IMPACT SCORES: refreshed %d error groups", scored)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Impact scorer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attachImpactScores sets each error's group impact score
+func (s *ErrorService) attachImpactScores(errors []models.Error) error {
+	fingerprints := []string{}
+	for _, e := range errors {
+		if e.Fingerprint != nil {
+			fingerprints = append(fingerprints, *e.Fingerprint)
+		}
+	}
+
+	scores, err := s.db.GetErrorGroupImpacts(fingerprints)
+	if err != nil {
+		return err
+	}
+	for i := range errors {
+		if errors[i].Fingerprint == nil {
+			continue
+		}
+		if score, ok := scores[*errors[i].Fingerprint]; ok {
+			errors[i].ImpactScore = &score
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// rollbarExport is a project's items and their occurrences, as returned by
+// Rollbar's items and instances APIs. Occurrences are matched to items by item_id.
+type rollbarExport struct {
+	Items       []rollbarItem       `json:"items"`
+	Occurrences []rollbarOccurrence `json:"occurrences"`
+	Instances   []rollbarOccurrence `json:"instances"`
+}
+
+type rollbarItem struct {
+	ID               importID        `json:"id"`
+	Title            string          `json:"title"`
+	Level            json.RawMessage `json:"level"`
+	Status           string          `json:"status"` // active, resolved, muted
+	Environment      string          `json:"environment"`
+	FirstOccurrence  int64           `json:"first_occurrence_timestamp"`
+	LastOccurrence   int64           `json:"last_occurrence_timestamp"`
+	TotalOccurrences importCount     `json:"total_occurrences"`
+}
+
+type rollbarOccurrence struct {
+	ID        importID    `json:"id"`
+	ItemID    importID    `json:"item_id"`
+	Timestamp int64       `json:"timestamp"`
+	Data      rollbarData `json:"data"`
+}
+
+type rollbarData struct {
+	UUID        string          `json:"uuid"`
+	Environment string          `json:"environment"`
+	Level       json.RawMessage `json:"level"`
+	Platform    string          `json:"platform"`
+	CodeVersion string          `json:"code_version"`
+	Body        struct {
+		Trace      *rollbarTrace  `json:"trace"`
+		TraceChain []rollbarTrace `json:"trace_chain"`
+		Message    *struct {
+			Body string `json:"body"`
+		} `json:"message"`
+	} `json:"body"`
+	Request struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Person map[string]interface{} `json:"person"`
+	Custom map[string]interface{} `json:"custom"`
+}
+
+type rollbarTrace struct {
+	Exception struct {
+		Class   string `json:"class"`
+		Message string `json:"message"`
+	} `json:"exception"`
+	Frames []struct {
+		Filename string `json:"filename"`
+		Method   string `json:"method"`
+		Lineno   int    `json:"lineno"`
+		Colno    int    `json:"colno"`
+	} `json:"frames"`
+}
+
+// rollbarLevels maps Rollbar's numeric levels to their names
+var rollbarLevels = map[int]string{10: "debug", 20: "info", 30: "warning", 40: "error", 50: "critical"}
+
+func parseRollbarExport(body []byte, source string) ([]importGroup, error) {
+	var export rollbarExport
+	if err := json.Unmarshal(body, &export); err != nil {
+		return nil, fmt.Errorf("%w: not a Rollbar export: %v", ErrInvalidImport, err)
+	}
+	if source == "" {
+		source = "rollbar"
+	}
+
+	groups := make([]importGroup, len(export.Items))
+	index := make(map[importID]int, len(export.Items))
+	for i, item := range export.Items {
+		groups[i] = importGroup{
+			Title:       item.Title,
+			Level:       rollbarLevel(item.Level),
+			Source:      source,
+			Environment: item.Environment,
+			Resolved:    item.Status == "resolved",
+			FirstSeen:   time.Unix(item.FirstOccurrence, 0).UTC(),
+			LastSeen:    time.Unix(item.LastOccurrence, 0).UTC(),
+			Count:       int(item.TotalOccurrences),
+			Context:     map[string]interface{}{"rollbar_item_id": string(item.ID)},
+		}
+		index[item.ID] = i
+	}
+
+	for _, occurrence := range append(export.Occurrences, export.Instances...) {
+		i, ok := index[occurrence.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: occurrence %s belongs to item %s, which is not in the export", ErrInvalidImport, occurrence.ID, occurrence.ItemID)
+		}
+		groups[i].Events = append(groups[i].Events, rollbarImportEvent(occurrence))
+	}
+
+	return groups, nil
+}
+
+func rollbarImportEvent(occurrence rollbarOccurrence) importEvent {
+	data := occurrence.Data
+	imported := importEvent{
+		Timestamp:   time.Unix(occurrence.Timestamp, 0).UTC(),
+		Level:       rollbarLevel(data.Level),
+		Environment: data.Environment,
+		Release:     optionalString(data.CodeVersion),
+		URL:         optionalString(data.Request.URL),
+		Context:     make(map[string]interface{}, len(data.Custom)+5),
+	}
+	for key, value := range data.Custom {
+		imported.Context[key] = value
+	}
+	imported.Context["rollbar_item_id"] = string(occurrence.ItemID)
+	imported.Context["rollbar_occurrence_id"] = string(occurrence.ID)
+	if data.UUID != "" {
+		imported.Context["rollbar_uuid"] = data.UUID
+	}
+	if data.Platform != "" {
+		imported.Context["platform"] = data.Platform
+	}
+	if len(data.Person) > 0 {
+		imported.Context["user"] = data.Person
+	}
+
+	// The first trace in a chain is the exception that was raised
+	trace := data.Body.Trace
+	if trace == nil && len(data.Body.TraceChain) > 0 {
+		trace = &data.Body.TraceChain[0]
+	}
+	switch {
+	case trace != nil:
+		imported.Message = trace.Exception.Class
+		if trace.Exception.Message != "" {
+			imported.Message += ": " + trace.Exception.Message
+		}
+		frames := make([]importFrame, len(trace.Frames))
+		for i, frame := range trace.Frames {
+			frames[i] = importFrame{Function: frame.Method, File: frame.Filename, Line: frame.Lineno, Column: frame.Colno}
+		}
+		imported.StackTrace = renderImportedStack(frames)
+	case data.Body.Message != nil:
+		imported.Message = data.Body.Message.Body
+	}
+
+	return imported
+}
+
+// rollbarLevel reads a level exported as a name or a number
+func rollbarLevel(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return name
+	}
+	var number int
+	if json.Unmarshal(raw, &number) == nil {
+		return rollbarLevels[number]
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// sentryExport is a project's issues and events, as returned by Sentry's
+// issues and events APIs. Events are matched to issues by groupID.
+type sentryExport struct {
+	Issues []sentryIssue `json:"issues"`
+	Events []sentryEvent `json:"events"`
+}
+
+type sentryIssue struct {
+	ID        importID    `json:"id"`
+	Title     string      `json:"title"`
+	Culprit   string      `json:"culprit"`
+	Level     string      `json:"level"`
+	Status    string      `json:"status"` // resolved, unresolved, ignored
+	Platform  string      `json:"platform"`
+	Count     importCount `json:"count"`
+	FirstSeen time.Time   `json:"firstSeen"`
+	LastSeen  time.Time   `json:"lastSeen"`
+	Project   struct {
+		Slug string `json:"slug"`
+	} `json:"project"`
+}
+
+type sentryEvent struct {
+	EventID     string                 `json:"eventID"`
+	GroupID     importID               `json:"groupID"`
+	Title       string                 `json:"title"`
+	Message     string                 `json:"message"`
+	DateCreated time.Time              `json:"dateCreated"`
+	Tags        []sentryTag            `json:"tags"`
+	Entries     []sentryEntry          `json:"entries"`
+	Contexts    map[string]interface{} `json:"contexts"`
+	User        map[string]interface{} `json:"user"`
+}
+
+type sentryTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type sentryEntry struct {
+	Type string          `json:"type"` // exception, message, request, ...
+	Data json.RawMessage `json:"data"`
+}
+
+type sentryException struct {
+	Values []struct {
+		Type       string `json:"type"`
+		Value      string `json:"value"`
+		Stacktrace *struct {
+			Frames []struct {
+				Function string `json:"function"`
+				Module   string `json:"module"`
+				Filename string `json:"filename"`
+				AbsPath  string `json:"absPath"`
+				LineNo   int    `json:"lineNo"`
+				ColNo    int    `json:"colNo"`
+			} `json:"frames"`
+		} `json:"stacktrace"`
+	} `json:"values"`
+}
+
+func parseSentryExport(body []byte, source string) ([]importGroup, error) {
+	var export sentryExport
+	if err := json.Unmarshal(body, &export); err != nil {
+		return nil, fmt.Errorf("%w: not a Sentry export: %v", ErrInvalidImport, err)
+	}
+
+	groups := make([]importGroup, len(export.Issues))
+	index := make(map[importID]int, len(export.Issues))
+	for i, issue := range export.Issues {
+		groupSource := source
+		if groupSource == "" {
+			groupSource = issue.Project.Slug
+		}
+		if groupSource == "" {
+			groupSource = issue.Platform
+		}
+		if groupSource == "" {
+			groupSource = "sentry"
+		}
+		groups[i] = importGroup{
+			Title:     issue.Title,
+			Level:     issue.Level,
+			Source:    groupSource,
+			Resolved:  issue.Status == "resolved",
+			FirstSeen: issue.FirstSeen,
+			LastSeen:  issue.LastSeen,
+			Count:     int(issue.Count),
+			Context:   map[string]interface{}{"sentry_issue_id": string(issue.ID)},
+		}
+		if issue.Culprit != "" {
+			groups[i].Context["culprit"] = issue.Culprit
+		}
+		index[issue.ID] = i
+	}
+
+	for _, event := range export.Events {
+		i, ok := index[event.GroupID]
+		if !ok {
+			return nil, fmt.Errorf("%w: event %s belongs to issue %s, which is not in the export", ErrInvalidImport, event.EventID, event.GroupID)
+		}
+		groups[i].Events = append(groups[i].Events, sentryImportEvent(event, groups[i].Title))
+	}
+
+	return groups, nil
+}
+
+func sentryImportEvent(event sentryEvent, title string) importEvent {
+	imported := importEvent{
+		Timestamp: event.DateCreated,
+		Message:   event.Message,
+		Context: map[string]interface{}{
+			"sentry_event_id": event.EventID,
+			"sentry_issue_id": string(event.GroupID),
+		},
+	}
+
+	tags := make(map[string]interface{})
+	for _, tag := range event.Tags {
+		switch tag.Key {
+		case "level":
+			imported.Level = tag.Value
+		case "environment":
+			imported.Environment = tag.Value
+		case "release":
+			imported.Release = optionalString(tag.Value)
+		case "url":
+			imported.URL = optionalString(tag.Value)
+		default:
+			tags[tag.Key] = tag.Value
+		}
+	}
+	if len(tags) > 0 {
+		imported.Context["tags"] = tags
+	}
+	for key, value := range event.Contexts {
+		imported.Context[key] = value
+	}
+	if len(event.User) > 0 {
+		imported.Context["user"] = event.User
+	}
+
+	for _, entry := range event.Entries {
+		switch entry.Type {
+		case "exception":
+			var exception sentryException
+			if json.Unmarshal(entry.Data, &exception) != nil || len(exception.Values) == 0 {
+				continue
+			}
+			// The last value is the exception that was raised
+			raised := exception.Values[len(exception.Values)-1]
+			imported.Message = raised.Type
+			if raised.Value != "" {
+				imported.Message += ": " + raised.Value
+			}
+			if raised.Stacktrace != nil {
+				frames := make([]importFrame, len(raised.Stacktrace.Frames))
+				for i, frame := range raised.Stacktrace.Frames {
+					file := frame.Filename
+					if file == "" {
+						file = frame.AbsPath
+					}
+					if file == "" {
+						file = frame.Module
+					}
+					frames[i] = importFrame{Function: frame.Function, File: file, Line: frame.LineNo, Column: frame.ColNo}
+				}
+				imported.StackTrace = renderImportedStack(frames)
+			}
+		case "message":
+			var message struct {
+				Formatted string `json:"formatted"`
+			}
+			if json.Unmarshal(entry.Data, &message) == nil && imported.Message == "" {
+				imported.Message = message.Formatted
+			}
+		case "request":
+			var request struct {
+				URL string `json:"url"`
+			}
+			if json.Unmarshal(entry.Data, &request) == nil && request.URL != "" {
+				imported.URL = &request.URL
+			}
+		}
+	}
+
+	if imported.Message == "" {
+		imported.Message = event.Title
+	}
+	if imported.Message == "" {
+		imported.Message = title
+	}
+	return imported
+}
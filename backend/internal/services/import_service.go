@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidImport is returned for an unknown format or an export that cannot be parsed
+var ErrInvalidImport = errors.New("invalid import")
+
+// importProgressEvery is how many events are imported between progress updates
+const importProgressEvery = 100
+
+// importGroup is an issue from another tracker with its exported events
+type importGroup struct {
+	Title       string
+	Level       string
+	Source      string
+	Environment string
+	Resolved    bool
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Count       int
+	Context     map[string]interface{}
+	Events      []importEvent
+}
+
+type importEvent struct {
+	Timestamp   time.Time
+	Level       string
+	Message     string
+	StackTrace  *string
+	Context     map[string]interface{}
+	Environment string
+	Release     *string
+	URL         *string
+}
+
+type importFrame struct {
+	Function string
+	File     string
+	Line     int
+	Column   int
+}
+
+// importParsers read an export into groups, using source for groups whose
+// export names none
+var importParsers = map[string]func(body []byte, source string) ([]importGroup, error){
+	"sentry":  parseSentryExport,
+	"rollbar": parseRollbarExport,
+}
+
+// ImportService imports the history of other error trackers from their
+// exports, so migrating teams keep their error groups and occurrences
+type ImportService struct {
+	db           *database.DB
+	errorService *ErrorService
+	audit        *AuditService
+}
+
+func NewImportService(db *database.DB, errorService *ErrorService, audit *AuditService) *ImportService {
+	return &ImportService{
+		db:           db,
+		errorService: errorService,
+		audit:        audit,
+	}
+}
+
+// StartImport parses an export and imports it in the background. The
+// returned job is pending; its progress is read with GetImportJob.
+func (s *ImportService) StartImport(ctx context.Context, format string, body []byte, projectID *uuid.UUID, source, actor string) (*models.ImportJob, error) {
+	parse, ok := importParsers[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: format must be sentry or rollbar", ErrInvalidImport)
+	}
+	groups, err := parse(body, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("%w: the export has no issues", ErrInvalidImport)
+	}
+
+	now := time.Now().UTC()
+	job := &models.ImportJob{
+		ID:          uuid.New(),
+		Format:      format,
+		Status:      "pending",
+		ProjectID:   projectID,
+		TotalGroups: len(groups),
+		CreatedBy:   actor,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, group := range groups {
+		job.TotalEvents += max(len(group.Events), 1)
+	}
+
+	if err := s.db.CreateImportJob(job); err != nil {
+		return nil, err
+	}
+	s.audit.Record("import.started", actor, "import_job", job.ID.String(), map[string]interface{}{
+		"format":       format,
+		"total_groups": job.TotalGroups,
+		"total_events": job.TotalEvents,
+	})
+
+	go s.runImport(*job, groups)
+
+	return withProgress(job), nil
+}
+
+// StartStaleJobReaper fails the imports whose instance stopped before
+// finishing them, until ctx is done
+func (s *ImportService) StartStaleJobReaper(ctx context.Context) {
+	runStaleJobReaper(ctx, "import", s.db.FailStaleImportJobs)
+}
+
+func (s *ImportService) GetImportJob(ctx context.Context, id uuid.UUID) (*models.ImportJob, error) {
+	job, err := s.db.GetImportJob(id)
+	if err != nil {
+		return nil, err
+	}
+	return withProgress(job), nil
+}
+
+func withProgress(job *models.ImportJob) *models.ImportJob {
+	if job.TotalEvents > 0 {
+		job.Progress = float64(job.ProcessedEvents) / float64(job.TotalEvents) * 100
+	}
+	return job
+}
+
+func (s *ImportService) runImport(job models.ImportJob, groups []importGroup) {
+	log.Printf("IMPORT STARTED: job %s, %s export, %d groups, %d events", job.ID, job.Format, job.TotalGroups, job.TotalEvents)
+	job.Status = "running"
+	s.updateImportJob(&job)
+	stopHeartbeat := startHeartbeat(func() error { return s.db.TouchImportJob(job.ID) })
+	defer stopHeartbeat()
+
+	var lastErr error
+	for _, group := range groups {
+		events := group.Events
+		if len(events) == 0 {
+			// Keep the group's history as a single occurrence carrying its count
+			events = []importEvent{{
+				Timestamp:   group.LastSeen,
+				Level:       group.Level,
+				Message:     group.Title,
+				Context:     group.Context,
+				Environment: group.Environment,
+			}}
+		}
+		fingerprint := generateFingerprint(events[0].Message, events[0].StackTrace)
+
+		for _, event := range events {
+			error := newImportedError(&job, &group, event, fingerprint, len(group.Events) == 0)
+			if err := s.errorService.storeImportedEvent(error); err != nil {
+				lastErr = err
+				job.FailedEvents++
+			} else {
+				job.ImportedEvents++
+			}
+
+			job.ProcessedEvents++
+			if job.ProcessedEvents%importProgressEvery == 0 {
+				s.updateImportJob(&job)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	job.Status = "completed"
+	job.CompletedAt = &now
+	if lastErr != nil {
+		message := fmt.Sprintf("%d events failed to import, last error: %v", job.FailedEvents, lastErr)
+		job.Error = &message
+		if job.ImportedEvents == 0 {
+			job.Status = "failed"
+		}
+	}
+	s.updateImportJob(&job)
+
+	log.Printf("IMPORT %s: job %s, %d imported, %d failed", strings.ToUpper(job.Status), job.ID, job.ImportedEvents, job.FailedEvents)
+	go s.errorService.cache.InvalidateAllCache(context.Background())
+}
+
+func (s *ImportService) updateImportJob(job *models.ImportJob) {
+	job.UpdatedAt = time.Now().UTC()
+	if err := s.db.UpdateImportJob(job); err != nil {
+		log.Printf("Failed to update import job %s: %v", job.ID, err)
+	}
+}
+
+// newImportedError maps an imported event to a stored occurrence, keeping its
+// original timestamps and the resolution status of its group
+func newImportedError(job *models.ImportJob, group *importGroup, event importEvent, fingerprint string, summary bool) *models.Error {
+	now := time.Now().UTC()
+
+	error := &models.Error{
+		ID:          uuid.New(),
+		Timestamp:   event.Timestamp,
+		Level:       normalizeImportLevel(event.Level, group.Level),
+		Message:     event.Message,
+		StackTrace:  event.StackTrace,
+		Context:     event.Context,
+		Source:      group.Source,
+		Environment: event.Environment,
+		URL:         event.URL,
+		Release:     event.Release,
+		ProjectID:   job.ProjectID,
+		Fingerprint: &fingerprint,
+		Count:       1,
+		FirstSeen:   event.Timestamp,
+		LastSeen:    event.Timestamp,
+		CreatedAt:   event.Timestamp,
+		UpdatedAt:   now,
+	}
+	if summary {
+		error.Count = max(group.Count, 1)
+		error.FirstSeen = group.FirstSeen
+	}
+	if error.Message == "" {
+		error.Message = group.Title
+	}
+	if error.Environment == "" {
+		error.Environment = group.Environment
+	}
+	if error.Environment == "" {
+		error.Environment = "production"
+	}
+	if error.Context == nil {
+		error.Context = make(map[string]interface{})
+	}
+	if group.Resolved {
+		resolvedBy := job.Format + " import"
+		error.Resolved = true
+		error.ResolvedBy = &resolvedBy
+	}
+	error.State = initialErrorState(error)
+
+	return error
+}
+
+// storeImportedEvent writes a historical event directly, skipping the queue,
+// throttling and new error alerts. The group's first seen is moved back if the
+// event is older than any stored one.
+func (s *ErrorService) storeImportedEvent(error *models.Error) error {
+	s.prepareForStorage(error)
+	if err := s.db.CreateError(error); err != nil {
+		return err
+	}
+
+	return s.db.RecordEarliestFirstSeen(&models.FirstSeenEvent{
+		Fingerprint: *error.Fingerprint,
+		ErrorID:     error.ID,
+		ProjectID:   error.ProjectID,
+		Message:     error.Message,
+		Level:       error.Level,
+		Source:      error.Source,
+		Environment: error.Environment,
+		FirstSeen:   error.FirstSeen,
+	})
+}
+
+// normalizeImportLevel maps another tracker's level onto ours
+func normalizeImportLevel(levels ...string) string {
+	for _, level := range levels {
+		switch strings.ToLower(level) {
+		case "fatal", "critical", "error":
+			return "error"
+		case "warning", "warn":
+			return "warning"
+		case "info":
+			return "info"
+		case "debug":
+			return "debug"
+		}
+	}
+	return "error"
+}
+
+// renderImportedStack renders frames, given oldest call first, most recent call first
+func renderImportedStack(frames []importFrame) *string {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	for i := len(frames) - 1; i >= 0; i-- {
+		frame := frames[i]
+		function := frame.Function
+		if function == "" {
+			function = "<anonymous>"
+		}
+		location := frame.File
+		if frame.Line > 0 {
+			location += ":" + strconv.Itoa(frame.Line)
+			if frame.Column > 0 {
+				location += ":" + strconv.Itoa(frame.Column)
+			}
+		}
+		fmt.Fprintf(&b, "  at %s (%s)\n", function, location)
+	}
+
+	trace := strings.TrimSuffix(b.String(), "\n")
+	return &trace
+}
+
+// importID is an identifier exported as either a string or a number
+type importID string
+
+func (id *importID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = importID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = importID(n.String())
+	return nil
+}
+
+// importCount is a count exported as either a string or a number
+type importCount int
+
+func (c *importCount) UnmarshalJSON(data []byte) error {
+	var id importID
+	if err := id.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	if id == "" {
+		*c = 0
+		return nil
+	}
+	n, err := strconv.Atoi(string(id))
+	if err != nil {
+		return err
+	}
+	*c = importCount(n)
+	return nil
+}
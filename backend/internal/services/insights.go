@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidInsightsWeek is returned for weeks that have not started yet
+var ErrInvalidInsightsWeek = errors.New("invalid insights week")
+
+// insightsInterval is how often the worker checks for ended weeks without insights
+const insightsInterval = time.Hour
+
+// insightsListLimit caps each list in the weekly insights
+const insightsListLimit = 5
+
+// insightsWeekStart returns the Monday 00:00 in loc starting the week of t
+func insightsWeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// GetInsights returns a project's insights for the week containing week, or
+// for the last full week when week is nil. Weeks start on Monday at midnight
+// in timezone, an IANA name or "" for UTC. Ended weeks are generated once and
+// stored; the current week is computed on each call.
+func (s *AnalyticsService) GetInsights(ctx context.Context, projectID uuid.UUID, week *time.Time, timezone string) (*models.WeeklyInsights, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	currentWeek := insightsWeekStart(now, loc)
+
+	weekStart := currentWeek.AddDate(0, 0, -7)
+	if week != nil {
+		// The week is a calendar date, so it names the same day in any timezone
+		weekStart = insightsWeekStart(time.Date(week.Year(), week.Month(), week.Day(), 12, 0, 0, 0, loc), loc)
+		if weekStart.After(currentWeek) {
+			return nil, fmt.Errorf("%w: week has not started", ErrInvalidInsightsWeek)
+		}
+	}
+
+	if weekStart.Before(currentWeek) {
+		insights, err := s.db.GetWeeklyInsights(projectID, weekStart)
+		if err != nil || insights != nil {
+			return insights, err
+		}
+	}
+
+	return s.generateInsights(projectID, weekStart, now)
+}
+
+func (s *AnalyticsService) generateInsights(projectID uuid.UUID, weekStart, now time.Time) (*models.WeeklyInsights, error) {
+	insights, err := s.db.ComputeWeeklyInsights(projectID, weekStart, insightsListLimit)
+	if err != nil {
+		return nil, err
+	}
+	insights.GeneratedAt = now
+	insights.Timezone = weekStart.Location().String()
+
+	if !insights.WeekEnd.After(now) {
+		if err := s.db.SaveWeeklyInsights(insights); err != nil {
+			return nil, err
+		}
+	}
+	return insights, nil
+}
+
+// StartInsightsWorker generates every project's insights once a week has
+// ended, until ctx is done
+func (s *AnalyticsService) StartInsightsWorker(ctx context.Context) {
+	log.Println("Starting insights worker...")
+
+	ticker := time.NewTicker(insightsInterval)
+	defer ticker.Stop()
+
+	for {
+		s.generateWeeklyInsights()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Insights worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *AnalyticsService) generateWeeklyInsights() {
+	now := time.Now().UTC()
+	weekStart := insightsWeekStart(now, time.UTC).AddDate(0, 0, -7)
+
+	projects, err := s.db.GetProjectIDs()
+	if err != nil {
+		log.Printf("Failed to load projects for insights: %v", err)
+		return
+	}
+
+	for _, projectID := range projects {
+		existing, err := s.db.GetWeeklyInsights(projectID, weekStart)
+		if err != nil {
+			log.Printf("Failed to check insights for project %s: %v", projectID, err)
+			continue
+		}
+		if existing != nil {
+			continue
+		}
+
+		if _, err := s.generateInsights(projectID, weekStart, now); err != nil {
+			log.Printf("Failed to generate insights for project %s: %v", projectID, err)
+			continue
+		}
+		log.Printf("INSIGHTS GENERATED: project %s, week of %s", projectID, weekStart.Format("2006-01-02"))
+	}
+}
+
+// FormatInsights renders insights as plain text for email digests
+func FormatInsights(insights *models.WeeklyInsights) string {
+	var b strings.Builder
+
+	weekStart := insights.WeekStart
+	if loc, err := loadTimezone(insights.Timezone); err == nil {
+		weekStart = weekStart.In(loc)
+	}
+	fmt.Fprintf(&b, "Week of %s\n\n", weekStart.Format("Jan 2, 2006"))
+	fmt.Fprintf(&b, "Errors: %d (previous week: %d)\n", insights.TotalErrors, insights.PreviousTotalErrors)
+	fmt.Fprintf(&b, "New error groups: %d\n", insights.NewErrorCount)
+	fmt.Fprintf(&b, "Regressions: %d\n", insights.RegressionCount)
+
+	if len(insights.NewErrors) > 0 {
+		b.WriteString("\nNew errors:\n")
+		for _, group := range insights.NewErrors {
+			fmt.Fprintf(&b, "- [%s] %s (%d events)\n", group.Source, truncate(group.Message, 100), group.Count)
+		}
+	}
+
+	for _, movers := range []struct {
+		title  string
+		movers []models.InsightMover
+	}{
+		{"Biggest increases", insights.MoversUp},
+		{"Biggest decreases", insights.MoversDown},
+	} {
+		if len(movers.movers) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", movers.title)
+		for _, mover := range movers.movers {
+			fmt.Fprintf(&b, "- [%s] %s: %d -> %d (%+d)\n", mover.Source, truncate(mover.Message, 100), mover.PreviousCount, mover.Count, mover.Change)
+		}
+	}
+
+	if len(insights.SlowestResolving) > 0 {
+		b.WriteString("\nSlowest to resolve:\n")
+		for _, resolution := range insights.SlowestResolving {
+			duration := time.Duration(resolution.ResolutionSeconds * float64(time.Second)).Round(time.Minute)
+			fmt.Fprintf(&b, "- [%s] %s: %s\n", resolution.Source, truncate(resolution.Message, 100), duration)
+		}
+	}
+
+	return b.String()
+}
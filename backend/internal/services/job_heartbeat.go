@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// jobHeartbeatInterval is how often a running export or import job
+	// records that its instance is still working on it
+	jobHeartbeatInterval = 30 * time.Second
+	// staleJobAfter is how long a job may go without a heartbeat before it
+	// counts as abandoned by an instance that stopped
+	staleJobAfter = 5 * time.Minute
+	// staleJobCheckInterval is how often abandoned jobs are looked for
+	staleJobCheckInterval = time.Minute
+)
+
+// staleJobMessage is the error of a job failed for a missing heartbeat
+const staleJobMessage = "the server running this job stopped before it finished; start it again"
+
+// startHeartbeat calls touch every jobHeartbeatInterval until the returned
+// function is called
+func startHeartbeat(touch func() error) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := touch(); err != nil {
+					log.Printf("Failed to record job heartbeat: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runStaleJobReaper fails the jobs that lost their heartbeat until ctx is
+// done. Jobs run in the instance that accepted them, so one that restarts or
+// crashes leaves its jobs behind.
+func runStaleJobReaper(ctx context.Context, kind string, failStale func(cutoff time.Time, message string) (int64, error)) {
+	log.Printf("Starting stale %s job reaper...", kind)
+
+	ticker := time.NewTicker(staleJobCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		failed, err := failStale(time.Now().UTC().Add(-staleJobAfter), staleJobMessage)
+		if err != nil {
+			log.Printf("Failed to fail stale %s jobs: %v", kind, err)
+		} else if failed > 0 {
+			log.Printf("Failed %d %s jobs abandoned by a stopped instance", failed, kind)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Stale %s job reaper stopped", kind)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidKubernetesEvent is returned when a Kubernetes event body cannot be parsed
+var ErrInvalidKubernetesEvent = errors.New("invalid kubernetes event")
+
+// MaxKubernetesEventBatch caps the number of events accepted per request
+const MaxKubernetesEventBatch = 500
+
+// kubernetesEventTTL is how long an event occurrence is remembered, so a
+// restarted watcher sending every event again does not record them twice.
+// The API server keeps events for an hour by default.
+const kubernetesEventTTL = 2 * time.Hour
+
+// kubernetesErrorReasons are the warning reasons recorded at level error:
+// crash loops, failed containers, OOM kills and evictions. Other warnings,
+// such as failed scheduling or probes, are often transient.
+var kubernetesErrorReasons = map[string]bool{
+	"BackOff":          true,
+	"CrashLoopBackOff": true,
+	"Failed":           true,
+	"OOMKilling":       true,
+	"Evicted":          true,
+	"NodeNotReady":     true,
+}
+
+// Generated pod name suffixes use an alphabet without vowels or 0, 1 and 3
+var (
+	podNameSuffix      = regexp.MustCompile(`-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+	podTemplateHash    = regexp.MustCompile(`-[bcdfghjklmnpqrstvwxz2456789]{6,10}$`)
+	statefulSetOrdinal = regexp.MustCompile(`-[0-9]+$`)
+	containerFieldPath = regexp.MustCompile(`^spec\.(?:init|ephemeral)?[cC]ontainers\{(.+)\}$`)
+)
+
+type kubernetesObjectRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	FieldPath string `json:"fieldPath"`
+}
+
+// kubernetesEvent holds the fields of both core/v1 and events.k8s.io/v1
+// events; the newer API renames involvedObject, message and count
+type kubernetesEvent struct {
+	Metadata struct {
+		Namespace string `json:"namespace"`
+		UID       string `json:"uid"`
+	} `json:"metadata"`
+	InvolvedObject  *kubernetesObjectRef `json:"involvedObject"`
+	Regarding       *kubernetesObjectRef `json:"regarding"`
+	Type            string               `json:"type"` // Normal, Warning
+	Reason          string               `json:"reason"`
+	Message         string               `json:"message"`
+	Note            string               `json:"note"`
+	Count           int                  `json:"count"`
+	DeprecatedCount int                  `json:"deprecatedCount"`
+	Series          *struct {
+		Count int `json:"count"`
+	} `json:"series"`
+	Source struct {
+		Component string `json:"component"`
+		Host      string `json:"host"`
+	} `json:"source"`
+	ReportingComponent  string `json:"reportingComponent"`
+	ReportingController string `json:"reportingController"`
+	FirstTimestamp      string `json:"firstTimestamp"`
+	LastTimestamp       string `json:"lastTimestamp"`
+	EventTime           string `json:"eventTime"`
+}
+
+// kubernetesEnvelope is a watch event ({"type": "ADDED", "object": ...}) or
+// an EventList ({"items": [...]}) wrapping the events
+type kubernetesEnvelope struct {
+	Type   string            `json:"type"`
+	Object json.RawMessage   `json:"object"`
+	Items  []json.RawMessage `json:"items"`
+}
+
+// KubernetesService turns Kubernetes warning events, such as pod crash
+// loops, into error events, so cluster problems show up next to the
+// application errors they cause
+type KubernetesService struct {
+	errorService *ErrorService
+	quotaService *QuotaService
+	redis        *redis.Client
+}
+
+func NewKubernetesService(errorService *ErrorService, quotaService *QuotaService, redis *redis.Client) *KubernetesService {
+	return &KubernetesService{
+		errorService: errorService,
+		quotaService: quotaService,
+		redis:        redis,
+	}
+}
+
+// IngestEvents parses events posted by an in-cluster watcher and records
+// each warning as an error event. Normal events, and occurrences already
+// recorded, are skipped. It returns the number of events recorded.
+func (s *KubernetesService) IngestEvents(ctx context.Context, body []byte, projectID *uuid.UUID, environment, cluster, userAgent, ipAddress string) (int, error) {
+	events, err := parseKubernetesEvents(body)
+	if err != nil {
+		return 0, err
+	}
+
+	accepted := 0
+	for _, event := range events {
+		if event.Type != "Warning" {
+			continue
+		}
+		if event.Metadata.UID != "" {
+			fresh, err := s.redis.ClaimKubernetesEvent(ctx, event.Metadata.UID, event.count(), kubernetesEventTTL)
+			if err != nil {
+				log.Printf("Failed to check kubernetes event %s: %v", event.Metadata.UID, err)
+			} else if !fresh {
+				continue
+			}
+		}
+
+		if projectID != nil {
+			if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+				return accepted, err
+			}
+		}
+
+		req, fingerprint := event.toErrorRequest(cluster)
+		if environment != "" {
+			req.Environment = &environment
+		}
+
+		_, err := s.errorService.recordEvent(ctx, req, fingerprint, projectID, userAgent, ipAddress)
+		if err == ErrEventThrottled {
+			continue
+		}
+		if err != nil {
+			return accepted, err
+		}
+		accepted++
+	}
+
+	return accepted, nil
+}
+
+// parseKubernetesEvents accepts a single event, a JSON array of events, an
+// EventList, or watch events wrapping them. Deleted events are dropped.
+func parseKubernetesEvents(body []byte) ([]kubernetesEvent, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: empty body", ErrInvalidKubernetesEvent)
+	}
+
+	var raw []json.RawMessage
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKubernetesEvent, err)
+		}
+	} else {
+		raw = []json.RawMessage{body}
+	}
+
+	var events []kubernetesEvent
+	for len(raw) > 0 {
+		item := raw[0]
+		raw = raw[1:]
+
+		var envelope kubernetesEnvelope
+		if err := json.Unmarshal(item, &envelope); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKubernetesEvent, err)
+		}
+		if envelope.Items != nil {
+			raw = append(raw, envelope.Items...)
+			continue
+		}
+		if envelope.Object != nil {
+			if envelope.Type == "DELETED" {
+				continue
+			}
+			item = envelope.Object
+		}
+
+		var event kubernetesEvent
+		if err := json.Unmarshal(item, &event); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKubernetesEvent, err)
+		}
+		if event.object() == nil {
+			return nil, fmt.Errorf("%w: missing involvedObject", ErrInvalidKubernetesEvent)
+		}
+		events = append(events, event)
+		if len(events) > MaxKubernetesEventBatch {
+			return nil, fmt.Errorf("%w: at most %d events per request", ErrInvalidKubernetesEvent, MaxKubernetesEventBatch)
+		}
+	}
+
+	return events, nil
+}
+
+func (e kubernetesEvent) object() *kubernetesObjectRef {
+	if e.Regarding != nil {
+		return e.Regarding
+	}
+	return e.InvolvedObject
+}
+
+// count is how often the event occurred, which grows as the API server
+// folds repeats into it
+func (e kubernetesEvent) count() int {
+	switch {
+	case e.Series != nil && e.Series.Count > 0:
+		return e.Series.Count
+	case e.Count > 0:
+		return e.Count
+	case e.DeprecatedCount > 0:
+		return e.DeprecatedCount
+	}
+	return 1
+}
+
+// toErrorRequest builds the error event and its fingerprint. Events group by
+// namespace, workload, container and reason, so a crash loop across every
+// pod of a Deployment is one error group; the pod is kept in the context.
+func (e kubernetesEvent) toErrorRequest(cluster string) (*models.CreateErrorRequest, string) {
+	object := e.object()
+	namespace := object.Namespace
+	if namespace == "" {
+		namespace = e.Metadata.Namespace
+	}
+	workload := kubernetesWorkload(object.Kind, object.Name)
+
+	var container string
+	if match := containerFieldPath.FindStringSubmatch(object.FieldPath); match != nil {
+		container = match[1]
+	}
+
+	message := e.Message
+	if message == "" {
+		message = e.Note
+	}
+	target := object.Kind + " " + object.Name
+	if namespace != "" {
+		target = object.Kind + " " + namespace + "/" + object.Name
+	}
+
+	level := "warning"
+	if kubernetesErrorReasons[e.Reason] {
+		level = "error"
+	}
+
+	details := map[string]interface{}{
+		"k8s_kind":   object.Kind,
+		"k8s_name":   object.Name,
+		"k8s_reason": e.Reason,
+		"k8s_count":  e.count(),
+	}
+	if namespace != "" {
+		details["k8s_namespace"] = namespace
+	}
+	if object.Kind == "Pod" {
+		details["k8s_pod"] = object.Name
+	}
+	if workload != object.Name {
+		details["k8s_workload"] = workload
+	}
+	if container != "" {
+		details["k8s_container"] = container
+	}
+	if node := e.Source.Host; node != "" {
+		details["k8s_node"] = node
+	} else if object.Kind == "Node" {
+		details["k8s_node"] = object.Name
+	}
+	for _, component := range []string{e.ReportingController, e.ReportingComponent, e.Source.Component} {
+		if component != "" {
+			details["k8s_component"] = component
+			break
+		}
+	}
+	if cluster != "" {
+		details["k8s_cluster"] = cluster
+	}
+	for name, value := range map[string]string{
+		"k8s_first_timestamp": e.FirstTimestamp,
+		"k8s_last_timestamp":  e.LastTimestamp,
+		"k8s_event_time":      e.EventTime,
+	} {
+		if value != "" {
+			details[name] = value
+		}
+	}
+
+	// The workload is usually the service, so its events join the service's
+	// own errors; names too long for a source fall back to "kubernetes"
+	source := workload
+	if source == "" || len(source) > 50 || object.Kind == "Node" {
+		source = "kubernetes"
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:   level,
+		Message: fmt.Sprintf("Kubernetes %s: %s: %s", e.Reason, target, strings.TrimSpace(message)),
+		Context: details,
+		Source:  source,
+	}
+	fingerprint := generateFingerprint(strings.Join([]string{"kubernetes", cluster, namespace, object.Kind, workload, container, e.Reason}, "\x00"), nil)
+	return req, fingerprint
+}
+
+// kubernetesWorkload strips the generated suffixes from a pod or ReplicaSet
+// name, e.g. "checkout-7d9f8b6c5d-x2k4q" to "checkout" and "db-0" to "db"
+func kubernetesWorkload(kind, name string) string {
+	switch kind {
+	case "Pod":
+		if trimmed := podNameSuffix.ReplaceAllString(name, ""); trimmed != name {
+			// Deployment pods also carry the ReplicaSet's pod template hash
+			return podTemplateHash.ReplaceAllString(trimmed, "")
+		}
+		return statefulSetOrdinal.ReplaceAllString(name, "")
+	case "ReplicaSet":
+		return podTemplateHash.ReplaceAllString(name, "")
+	}
+	return name
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/redis"
+)
+
+// leaderLockName is the Redis lock held by the instance running periodic jobs
+const leaderLockName = "leader"
+
+// LeaderElector runs periodic jobs on one instance at a time. Every instance
+// campaigns for a lease in Redis; the holder runs the jobs and renews the
+// lease, and the others take over once it lapses.
+type LeaderElector struct {
+	redis *redis.Client
+	id    string
+	lease time.Duration
+
+	jobs   []func(ctx context.Context)
+	leader atomic.Bool
+}
+
+// minLeaderLease keeps the renewal interval, a third of the lease, sensible
+const minLeaderLease = 3 * time.Second
+
+func NewLeaderElector(redis *redis.Client, lease time.Duration) *LeaderElector {
+	if lease < minLeaderLease {
+		lease = minLeaderLease
+	}
+	hostname, _ := os.Hostname()
+	return &LeaderElector{
+		redis: redis,
+		id:    fmt.Sprintf("%s-%s", hostname, uuid.NewString()),
+		lease: lease,
+	}
+}
+
+// Run registers a job to run while this instance is the leader. The job's
+// context is cancelled when leadership is lost, and the job is started
+// again if it is regained. Register jobs before calling Start.
+func (e *LeaderElector) Run(job func(ctx context.Context)) {
+	e.jobs = append(e.jobs, job)
+}
+
+// ID identifies this instance as the lease holder
+func (e *LeaderElector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this instance is running the periodic jobs
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start campaigns for leadership until ctx is done, then stops the jobs and
+// releases the lease
+func (e *LeaderElector) Start(ctx context.Context) {
+	log.Printf("Starting leader election as %s...", e.id)
+
+	// Renew well before the lease lapses so one slow round trip does not lose it
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+
+	var stopJobs func() <-chan struct{}
+	// stopped is closed once the jobs of the last lease have returned
+	var stopped <-chan struct{}
+	for {
+		held := e.campaign(ctx)
+		if !held && stopJobs != nil {
+			log.Printf("LEADER: %s lost the lease, stopping periodic jobs", e.id)
+			stopped = stopJobs()
+			stopJobs = nil
+			go e.warnOverrun(stopped)
+		}
+		// Jobs still stopping from an earlier lease must return before they
+		// are started again, so they never run twice on this instance
+		if held && stopJobs == nil && channelClosed(stopped) {
+			log.Printf("LEADER: %s is now running periodic jobs", e.id)
+			stopJobs = e.startJobs(ctx)
+		}
+		e.leader.Store(stopJobs != nil)
+
+		select {
+		case <-ctx.Done():
+			if stopJobs != nil {
+				<-stopJobs()
+				e.leader.Store(false)
+				if err := e.redis.ReleaseLock(context.Background(), leaderLockName, e.id); err != nil {
+					log.Printf("Failed to release leader lease: %v", err)
+				}
+			}
+			log.Println("Leader election stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign takes the lease, or extends it when this instance holds it. Any
+// Redis error counts as not holding it, since another instance may take over
+// once the lease lapses.
+func (e *LeaderElector) campaign(ctx context.Context) bool {
+	held, err := e.redis.AcquireLock(ctx, leaderLockName, e.id, e.lease)
+	if err != nil {
+		log.Printf("Leader election failed: %v", err)
+		return false
+	}
+	return held
+}
+
+// warnOverrun logs when jobs are still running after a lost lease has had
+// time to lapse, as another instance may be running them too by then
+func (e *LeaderElector) warnOverrun(stopped <-chan struct{}) {
+	select {
+	case <-stopped:
+	case <-time.After(e.lease):
+		log.Printf("LEADER: %s still has periodic jobs running after its lease lapsed; another instance may run them at the same time", e.id)
+	}
+}
+
+// startJobs runs every job in its own goroutine and returns a function that
+// cancels them. The channel it returns is closed once they have all returned;
+// the election goes on meanwhile.
+func (e *LeaderElector) startJobs(ctx context.Context) func() <-chan struct{} {
+	jobCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, job := range e.jobs {
+		wg.Add(1)
+		go func(job func(ctx context.Context)) {
+			defer wg.Done()
+			job(jobCtx)
+		}(job)
+	}
+	return func() <-chan struct{} {
+		cancel()
+		stopped := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(stopped)
+		}()
+		return stopped
+	}
+}
+
+// channelClosed reports whether ch is closed; a nil channel counts as closed
+func channelClosed(ch <-chan struct{}) bool {
+	if ch == nil {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
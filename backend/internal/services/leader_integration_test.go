@@ -0,0 +1,40 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"error-logs/internal/redis"
+)
+
+func TestAcquireLockExtendsOwnLease(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL is required for integration tests")
+	}
+	client, err := redis.NewClient(redisURL)
+	if err != nil {
+		t.Fatalf("connect to Redis: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	name := fmt.Sprintf("it-leader-%d", time.Now().UnixNano())
+	defer client.ReleaseLock(ctx, name, "a")
+
+	if held, err := client.AcquireLock(ctx, name, "a", time.Minute); err != nil || !held {
+		t.Fatalf("AcquireLock(a) = %t, %v, want the free lock", held, err)
+	}
+	// A renewal whose reply was lost leaves the lease with its owner
+	if held, err := client.AcquireLock(ctx, name, "a", time.Minute); err != nil || !held {
+		t.Errorf("AcquireLock(a) again = %t, %v, want its own lease extended", held, err)
+	}
+	if held, err := client.AcquireLock(ctx, name, "b", time.Minute); err != nil || held {
+		t.Errorf("AcquireLock(b) = %t, %v, want the lease held by a refused", held, err)
+	}
+}
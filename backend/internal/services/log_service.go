@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidLog is returned when a log event fails validation
+var ErrInvalidLog = errors.New("invalid log event")
+
+// MaxLogBatch caps the number of log events accepted per request
+const MaxLogBatch = 1000
+
+// logRetentionInterval is how often expired log events are pruned
+const logRetentionInterval = time.Hour
+
+// logLevels lists the accepted levels from least to most severe
+var logLevels = []string{"debug", "info", "warning", "error"}
+
+// LogLevelsAtOrAbove returns the levels at least as severe as minLevel,
+// or nil if minLevel is unknown
+func LogLevelsAtOrAbove(minLevel string) []string {
+	for i, level := range logLevels {
+		if level == minLevel {
+			return logLevels[i:]
+		}
+	}
+	return nil
+}
+
+func isLogLevel(level string) bool {
+	return LogLevelsAtOrAbove(level) != nil
+}
+
+type LogService struct {
+	db          *database.DB
+	redis       *redis.Client
+	sampleRates map[string]int
+	retention   time.Duration
+	limits      ContextLimits
+}
+
+func NewLogService(db *database.DB, redis *redis.Client, debugSampleRate, infoSampleRate, retentionDays int, limits ContextLimits) *LogService {
+	if retentionDays < 1 {
+		retentionDays = 1
+	}
+	return &LogService{
+		db:    db,
+		redis: redis,
+		sampleRates: map[string]int{
+			"debug": max(debugSampleRate, 1),
+			"info":  max(infoSampleRate, 1),
+		},
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		limits:    limits,
+	}
+}
+
+// IngestLogs validates a batch of log events, samples debug and info events
+// at their configured rates, and stores the rest
+func (s *LogService) IngestLogs(ctx context.Context, req *models.IngestLogsRequest, projectID *uuid.UUID) (*models.IngestLogsResponse, error) {
+	if len(req.Logs) == 0 {
+		return nil, fmt.Errorf("%w: at least one log event is required", ErrInvalidLog)
+	}
+	if len(req.Logs) > MaxLogBatch {
+		return nil, fmt.Errorf("%w: at most %d log events per request", ErrInvalidLog, MaxLogBatch)
+	}
+
+	now := time.Now().UTC()
+	events := make([]models.LogEvent, 0, len(req.Logs))
+	sampled := 0
+
+	for _, entry := range req.Logs {
+		if entry.Message == "" {
+			return nil, fmt.Errorf("%w: message is required", ErrInvalidLog)
+		}
+		if entry.Level == "" {
+			entry.Level = "info"
+		}
+		if !isLogLevel(entry.Level) {
+			return nil, fmt.Errorf("%w: level must be debug, info, warning, or error", ErrInvalidLog)
+		}
+		if len(entry.TraceID) > 128 {
+			return nil, fmt.Errorf("%w: trace_id must be at most 128 characters", ErrInvalidLog)
+		}
+
+		rate := s.sampleRates[entry.Level]
+		if rate == 0 {
+			rate = 1
+		}
+		if rate > 1 && rand.Intn(rate) != 0 {
+			sampled++
+			continue
+		}
+
+		event := models.LogEvent{
+			Timestamp:   now,
+			Level:       entry.Level,
+			Message:     entry.Message,
+			Source:      entry.Source,
+			Environment: entry.Environment,
+			Context:     entry.Context,
+			ProjectID:   projectID,
+			SampleRate:  rate,
+		}
+		if entry.Timestamp != nil {
+			event.Timestamp = entry.Timestamp.UTC()
+		}
+		if event.Source == "" {
+			event.Source = "unknown"
+		}
+		if event.Context == nil {
+			event.Context = make(map[string]interface{})
+		}
+		s.limits.Apply(event.Context)
+		if entry.TraceID != "" {
+			traceID := entry.TraceID
+			event.TraceID = &traceID
+		}
+
+		events = append(events, event)
+	}
+
+	if len(events) > 0 {
+		if err := s.db.InsertLogEvents(events); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.IngestLogsResponse{
+		Accepted: len(events),
+		Sampled:  sampled,
+	}, nil
+}
+
+func (s *LogService) GetLogs(ctx context.Context, limit, offset int, filter models.LogFilter) (*models.LogListResponse, error) {
+	events, total, err := s.db.GetLogEvents(limit, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LogListResponse{
+		Logs:  events,
+		Total: total,
+		Page:  (offset / limit) + 1,
+		Limit: limit,
+	}, nil
+}
+
+// StartRetentionWorker periodically deletes log events past the retention period
+func (s *LogService) StartRetentionWorker(ctx context.Context) {
+	log.Println("Starting log retention worker...")
+
+	ticker := time.NewTicker(logRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		s.pruneLogs()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Log retention worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *LogService) pruneLogs() {
+	cutoff := time.Now().UTC().Add(-s.retention)
+	deleted, err := s.db.DeleteLogEventsBefore(cutoff)
+	if err != nil {
+		log.Printf("Failed to prune log events: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("LOG RETENTION: deleted %d log events older than %s", deleted, cutoff.Format(time.RFC3339))
+	}
+}
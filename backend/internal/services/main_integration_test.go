@@ -0,0 +1,121 @@
+//go:build integration
+
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+
+	"error-logs/internal/redis"
+)
+
+// containerLifetime bounds how long the containers outlive a test run that
+// is killed before it can remove them
+const containerLifetime = 10 * time.Minute
+
+// TestMain starts disposable Postgres and Redis containers for the
+// integration tests unless DATABASE_URL and REDIS_URL point at servers
+// already. Without either, and without Docker, the tests skip.
+func TestMain(m *testing.M) {
+	if os.Getenv("DATABASE_URL") != "" && os.Getenv("REDIS_URL") != "" {
+		os.Exit(m.Run())
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		log.Printf("Docker is not available, integration tests need DATABASE_URL and REDIS_URL: %v", err)
+		os.Exit(m.Run())
+	}
+	pool.MaxWait = 2 * time.Minute
+
+	containers, err := startTestContainers(pool)
+	if err != nil {
+		for _, container := range containers {
+			pool.Purge(container)
+		}
+		log.Fatalf("start test containers: %v", err)
+	}
+
+	code := m.Run()
+	for _, container := range containers {
+		if err := pool.Purge(container); err != nil {
+			log.Printf("remove container %s: %v", container.Container.Name, err)
+		}
+	}
+	os.Exit(code)
+}
+
+// startTestContainers runs the Postgres and Redis images of
+// docker-compose.yml, waits until both accept connections and points
+// DATABASE_URL and REDIS_URL at them
+func startTestContainers(pool *dockertest.Pool) ([]*dockertest.Resource, error) {
+	var containers []*dockertest.Resource
+	noRestart := func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	}
+
+	postgres, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "17-alpine",
+		Env: []string{
+			"POSTGRES_DB=error_logs_test",
+			"POSTGRES_USER=error_logs_user",
+			"POSTGRES_PASSWORD=error_logs_password",
+		},
+	}, noRestart)
+	if err != nil {
+		return containers, fmt.Errorf("run postgres: %w", err)
+	}
+	containers = append(containers, postgres)
+	postgres.Expire(uint(containerLifetime.Seconds()))
+
+	redisContainer, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "redis",
+		Tag:        "8-alpine",
+	}, noRestart)
+	if err != nil {
+		return containers, fmt.Errorf("run redis: %w", err)
+	}
+	containers = append(containers, redisContainer)
+	redisContainer.Expire(uint(containerLifetime.Seconds()))
+
+	databaseURL := fmt.Sprintf("postgres://error_logs_user:error_logs_password@%s/error_logs_test?sslmode=disable",
+		postgres.GetHostPort("5432/tcp"))
+	redisURL := fmt.Sprintf("redis://%s/0", redisContainer.GetHostPort("6379/tcp"))
+
+	if err := pool.Retry(func() error {
+		db, err := sql.Open("postgres", databaseURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.Ping()
+	}); err != nil {
+		return containers, fmt.Errorf("wait for postgres: %w", err)
+	}
+	if err := pool.Retry(func() error {
+		client, err := redis.NewClient(redisURL)
+		if err != nil {
+			return err
+		}
+		return client.Close()
+	}); err != nil {
+		return containers, fmt.Errorf("wait for redis: %w", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	os.Setenv("REDIS_URL", redisURL)
+	return containers, nil
+}
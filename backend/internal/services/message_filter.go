@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+var (
+	// ErrInvalidMessageFilter is returned for a message filter that can't be
+	// applied
+	ErrInvalidMessageFilter = errors.New("invalid message filter")
+	// ErrMessageRegexTimeout is returned when a message regex ran past
+	// database.MessageRegexTimeout
+	ErrMessageRegexTimeout = fmt.Errorf("message regex took longer than %s; use a simpler pattern or narrow the filter", database.MessageRegexTimeout)
+)
+
+// maxMessageRegexLength keeps patterns short enough to reason about
+const maxMessageRegexLength = 500
+
+// checkMessageFilter validates the message filter. Patterns are checked with
+// Go's RE2 syntax, which Postgres also accepts for the common cases.
+func checkMessageFilter(filter models.ErrorFilter) error {
+	switch filter.MessageMatch {
+	case "", "substring", "exact":
+	case "regex":
+		if len(filter.Message) > maxMessageRegexLength {
+			return fmt.Errorf("%w: message regex must be at most %d characters", ErrInvalidMessageFilter, maxMessageRegexLength)
+		}
+		if _, err := regexp.Compile(filter.Message); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessageFilter, err)
+		}
+	default:
+		return fmt.Errorf("%w: message_match must be substring, exact or regex", ErrInvalidMessageFilter)
+	}
+	if filter.Message == "" && (filter.MessageMatch != "" || filter.MessageNegate) {
+		return fmt.Errorf("%w: message is required with message_match and message_negate", ErrInvalidMessageFilter)
+	}
+	return nil
+}
+
+// messageMatches applies the message filter to an event outside the
+// database
+func messageMatches(e *models.Error, filter models.ErrorFilter) bool {
+	if filter.Message == "" {
+		return true
+	}
+
+	var matched bool
+	switch filter.MessageMatch {
+	case "exact":
+		matched = e.Message == filter.Message
+	case "regex":
+		re, err := regexp.Compile(filter.Message)
+		matched = err == nil && re.MatchString(e.Message)
+	default:
+		matched = strings.Contains(strings.ToLower(e.Message), strings.ToLower(filter.Message))
+	}
+	return matched != filter.MessageNegate
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidMetric is returned when a metric sample or query fails validation
+var ErrInvalidMetric = errors.New("invalid metric")
+
+// MaxMetricBatch caps the number of samples accepted per request
+const MaxMetricBatch = 1000
+
+var metricNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,200}$`)
+
+type MetricsService struct {
+	db    *database.DB
+	redis *redis.Client
+}
+
+func NewMetricsService(db *database.DB, redis *redis.Client) *MetricsService {
+	return &MetricsService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// IngestMetrics validates a batch of counter and gauge samples and folds them
+// into the rollup tables. Samples without a timestamp are recorded at now.
+func (s *MetricsService) IngestMetrics(ctx context.Context, req *models.IngestMetricsRequest) (int, error) {
+	if len(req.Metrics) == 0 {
+		return 0, fmt.Errorf("%w: at least one sample is required", ErrInvalidMetric)
+	}
+	if len(req.Metrics) > MaxMetricBatch {
+		return 0, fmt.Errorf("%w: at most %d samples per request", ErrInvalidMetric, MaxMetricBatch)
+	}
+
+	now := time.Now().UTC()
+	for i := range req.Metrics {
+		sample := &req.Metrics[i]
+		if !metricNamePattern.MatchString(sample.Name) {
+			return 0, fmt.Errorf("%w: name %q must be 1-200 letters, digits, '.', '_' or '-'", ErrInvalidMetric, sample.Name)
+		}
+		if sample.Type == "" {
+			sample.Type = "counter"
+		}
+		if sample.Type != "counter" && sample.Type != "gauge" {
+			return 0, fmt.Errorf("%w: type must be counter or gauge", ErrInvalidMetric)
+		}
+		if sample.Timestamp == nil {
+			sample.Timestamp = &now
+		}
+	}
+
+	if err := s.db.RecordMetricSamples(req.Metrics); err != nil {
+		return 0, err
+	}
+
+	return len(req.Metrics), nil
+}
+
+func (s *MetricsService) GetMetricSeries(ctx context.Context, name, resolution string, tags map[string]string, period time.Duration) (*models.MetricSeriesResponse, error) {
+	if !metricNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: a valid metric name is required", ErrInvalidMetric)
+	}
+	if resolution != "minute" && resolution != "hour" {
+		return nil, fmt.Errorf("%w: resolution must be minute or hour", ErrInvalidMetric)
+	}
+
+	since := time.Now().UTC().Add(-period)
+	return s.db.GetMetricSeries(name, resolution, tags, since)
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	minidumpSignature = 0x504d444d // "MDMP"
+
+	threadListStream = 3
+	moduleListStream = 4
+	exceptionStream  = 6
+	systemInfoStream = 7
+
+	cvSignaturePDB70 = 0x53445352 // "RSDS"
+	cvSignatureELF   = 0x4270454c // "BpEL"
+
+	// maxScannedFrames and maxScannedStackBytes bound the stack scan of the
+	// crashing thread
+	maxScannedFrames     = 64
+	maxScannedStackBytes = 256 << 10
+)
+
+type minidumpModule struct {
+	Name    string `json:"name"`
+	Base    uint64 `json:"base"`
+	Size    uint32 `json:"size"`
+	DebugID string `json:"debug_id,omitempty"`
+}
+
+type minidumpFrame struct {
+	Address uint64
+	Module  *minidumpModule
+}
+
+// minidump is the crash summary extracted from a Breakpad/Crashpad minidump
+type minidump struct {
+	OS               string
+	Arch             string
+	ExceptionCode    uint32
+	ExceptionAddress uint64
+	CrashThreadID    uint32
+	Modules          []minidumpModule
+	Frames           []minidumpFrame
+}
+
+type minidumpReader struct {
+	r     io.ReaderAt
+	size  int64
+	order binary.ByteOrder
+}
+
+// bytes reads size bytes at rva. Sizes come from the dump itself, so they
+// are checked against the file before anything is allocated.
+func (m *minidumpReader) bytes(rva uint32, size uint32) ([]byte, error) {
+	if int64(rva)+int64(size) > m.size {
+		return nil, fmt.Errorf("truncated minidump: %d bytes at offset %d run past its %d bytes", size, rva, m.size)
+	}
+	buf := make([]byte, size)
+	if _, err := m.r.ReadAt(buf, int64(rva)); err != nil {
+		return nil, fmt.Errorf("truncated minidump at offset %d: %w", rva, err)
+	}
+	return buf, nil
+}
+
+// parseMinidump reads the system info, module list, and exception streams and
+// recovers a basic stack for the crashing thread. Frames after the first are
+// found by scanning the thread's stack memory for return addresses inside
+// known modules, so they may include stale values; full unwinding needs
+// symbol files and is not attempted. size is the length of the dump in r.
+func parseMinidump(r io.ReaderAt, size int64) (*minidump, error) {
+	m := &minidumpReader{r: r, size: size, order: binary.LittleEndian}
+
+	header, err := m.bytes(0, 32)
+	if err != nil {
+		return nil, err
+	}
+	if m.order.Uint32(header[0:]) != minidumpSignature {
+		return nil, fmt.Errorf("not a minidump")
+	}
+	streamCount := m.order.Uint32(header[8:])
+	directoryRVA := m.order.Uint32(header[12:])
+	if streamCount > 1024 {
+		return nil, fmt.Errorf("too many streams: %d", streamCount)
+	}
+
+	directory, err := m.bytes(directoryRVA, streamCount*12)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make(map[uint32][2]uint32) // type -> size, rva
+	for i := uint32(0); i < streamCount; i++ {
+		entry := directory[i*12:]
+		streams[m.order.Uint32(entry)] = [2]uint32{m.order.Uint32(entry[4:]), m.order.Uint32(entry[8:])}
+	}
+
+	dump := &minidump{OS: "unknown", Arch: "unknown"}
+	archCode := uint16(0xffff)
+
+	if stream, ok := streams[systemInfoStream]; ok && stream[0] >= 24 {
+		info, err := m.bytes(stream[1], 24)
+		if err != nil {
+			return nil, err
+		}
+		archCode = m.order.Uint16(info[0:])
+		dump.Arch = minidumpArchName(archCode)
+		dump.OS = minidumpOSName(m.order.Uint32(info[20:]))
+	}
+
+	if stream, ok := streams[moduleListStream]; ok {
+		if dump.Modules, err = m.modules(stream[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	stream, ok := streams[exceptionStream]
+	if !ok || stream[0] < 168 {
+		return nil, fmt.Errorf("minidump has no exception stream")
+	}
+	exception, err := m.bytes(stream[1], 168)
+	if err != nil {
+		return nil, err
+	}
+	dump.CrashThreadID = m.order.Uint32(exception[0:])
+	dump.ExceptionCode = m.order.Uint32(exception[8:])
+	dump.ExceptionAddress = m.order.Uint64(exception[24:])
+	contextSize, contextRVA := m.order.Uint32(exception[160:]), m.order.Uint32(exception[164:])
+
+	dump.Frames = append(dump.Frames, minidumpFrame{Address: dump.ExceptionAddress, Module: dump.moduleAt(dump.ExceptionAddress)})
+
+	if stream, ok := streams[threadListStream]; ok {
+		// Stack scanning is best effort; the exception frame alone is still useful
+		if frames, err := m.scanStack(dump, stream[1], archCode, contextSize, contextRVA); err == nil {
+			dump.Frames = append(dump.Frames, frames...)
+		}
+	}
+
+	return dump, nil
+}
+
+func (m *minidumpReader) modules(rva uint32) ([]minidumpModule, error) {
+	countBuf, err := m.bytes(rva, 4)
+	if err != nil {
+		return nil, err
+	}
+	count := m.order.Uint32(countBuf)
+	if count > 4096 {
+		return nil, fmt.Errorf("too many modules: %d", count)
+	}
+
+	const moduleSize = 108
+	raw, err := m.bytes(rva+4, count*moduleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]minidumpModule, 0, count)
+	for i := uint32(0); i < count; i++ {
+		entry := raw[i*moduleSize:]
+		module := minidumpModule{
+			Base: m.order.Uint64(entry[0:]),
+			Size: m.order.Uint32(entry[8:]),
+		}
+		if name, err := m.utf16String(m.order.Uint32(entry[20:])); err == nil {
+			module.Name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+		}
+		cvSize, cvRVA := m.order.Uint32(entry[76:]), m.order.Uint32(entry[80:])
+		if cvSize >= 4 && cvSize <= 4096 {
+			if cv, err := m.bytes(cvRVA, cvSize); err == nil {
+				module.DebugID = codeViewDebugID(cv, m.order)
+			}
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+func (m *minidumpReader) utf16String(rva uint32) (string, error) {
+	lengthBuf, err := m.bytes(rva, 4)
+	if err != nil {
+		return "", err
+	}
+	length := m.order.Uint32(lengthBuf)
+	if length > 4096 {
+		return "", fmt.Errorf("string too long")
+	}
+	raw, err := m.bytes(rva+4, length)
+	if err != nil {
+		return "", err
+	}
+	units := make([]uint16, length/2)
+	for i := range units {
+		units[i] = m.order.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// codeViewDebugID formats the Breakpad debug identifier: GUID and age for PDB
+// records, the first 16 bytes of the build ID (as a GUID, age 0) for ELF records
+func codeViewDebugID(cv []byte, order binary.ByteOrder) string {
+	guid := make([]byte, 16)
+	var age uint32
+	switch order.Uint32(cv) {
+	case cvSignaturePDB70:
+		if len(cv) < 24 {
+			return ""
+		}
+		copy(guid, cv[4:20])
+		age = order.Uint32(cv[20:])
+	case cvSignatureELF:
+		copy(guid, cv[4:])
+	default:
+		return ""
+	}
+
+	// The first three GUID fields are stored little-endian
+	guid[0], guid[1], guid[2], guid[3] = guid[3], guid[2], guid[1], guid[0]
+	guid[4], guid[5] = guid[5], guid[4]
+	guid[6], guid[7] = guid[7], guid[6]
+	return fmt.Sprintf("%X%X", guid, age)
+}
+
+// scanStack reads the crashing thread's stack pointer from its context and
+// collects stack words that point into a module
+func (m *minidumpReader) scanStack(dump *minidump, threadListRVA uint32, archCode uint16, contextSize, contextRVA uint32) ([]minidumpFrame, error) {
+	spOffset, wordSize := uint32(0), uint32(8)
+	switch archCode {
+	case 0: // x86
+		spOffset, wordSize = 0xc4, 4
+	case 9: // amd64
+		spOffset = 0x98
+	case 12, 0x8003: // arm64
+		spOffset = 0x100
+	default:
+		return nil, fmt.Errorf("unsupported architecture")
+	}
+	if contextSize < spOffset+wordSize {
+		return nil, fmt.Errorf("thread context too small")
+	}
+
+	spBuf, err := m.bytes(contextRVA+spOffset, wordSize)
+	if err != nil {
+		return nil, err
+	}
+	sp := m.word(spBuf, wordSize)
+
+	countBuf, err := m.bytes(threadListRVA, 4)
+	if err != nil {
+		return nil, err
+	}
+	count := m.order.Uint32(countBuf)
+	if count > 65536 {
+		return nil, fmt.Errorf("too many threads")
+	}
+
+	const threadSize = 48
+	threads, err := m.bytes(threadListRVA+4, count*threadSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		thread := threads[i*threadSize:]
+		if m.order.Uint32(thread) != dump.CrashThreadID {
+			continue
+		}
+
+		stackStart := m.order.Uint64(thread[24:])
+		stackSize, stackRVA := m.order.Uint32(thread[32:]), m.order.Uint32(thread[36:])
+		if sp < stackStart || sp >= stackStart+uint64(stackSize) {
+			return nil, fmt.Errorf("stack pointer outside stack memory")
+		}
+		offset := uint32(sp - stackStart)
+		scanned := min(stackSize-offset, maxScannedStackBytes)
+		stack, err := m.bytes(stackRVA+offset, scanned)
+		if err != nil {
+			return nil, err
+		}
+
+		var frames []minidumpFrame
+		for pos := uint32(0); pos+wordSize <= uint32(len(stack)) && len(frames) < maxScannedFrames; pos += wordSize {
+			address := m.word(stack[pos:], wordSize)
+			if module := dump.moduleAt(address); module != nil {
+				frames = append(frames, minidumpFrame{Address: address, Module: module})
+			}
+		}
+		return frames, nil
+	}
+
+	return nil, fmt.Errorf("crashing thread not found")
+}
+
+func (m *minidumpReader) word(buf []byte, size uint32) uint64 {
+	if size == 4 {
+		return uint64(m.order.Uint32(buf))
+	}
+	return m.order.Uint64(buf)
+}
+
+func (d *minidump) moduleAt(address uint64) *minidumpModule {
+	for i := range d.Modules {
+		module := &d.Modules[i]
+		if address >= module.Base && address < module.Base+uint64(module.Size) {
+			return module
+		}
+	}
+	return nil
+}
+
+// crashModule is the module the exception address falls in
+func (d *minidump) crashModule() string {
+	if len(d.Frames) > 0 && d.Frames[0].Module != nil {
+		return d.Frames[0].Module.Name
+	}
+	return "unknown module"
+}
+
+// exceptionName maps the exception code to a readable name for the platform
+func (d *minidump) exceptionName() string {
+	var names map[uint32]string
+	switch d.OS {
+	case "windows":
+		names = map[uint32]string{
+			0xc0000005: "EXCEPTION_ACCESS_VIOLATION",
+			0xc000001d: "EXCEPTION_ILLEGAL_INSTRUCTION",
+			0xc0000094: "EXCEPTION_INT_DIVIDE_BY_ZERO",
+			0xc00000fd: "EXCEPTION_STACK_OVERFLOW",
+			0xc0000409: "EXCEPTION_STACK_BUFFER_OVERRUN",
+			0x80000003: "EXCEPTION_BREAKPOINT",
+			0xe06d7363: "Unhandled C++ exception",
+		}
+	case "macos", "ios":
+		names = map[uint32]string{
+			1:  "EXC_BAD_ACCESS",
+			2:  "EXC_BAD_INSTRUCTION",
+			3:  "EXC_ARITHMETIC",
+			6:  "EXC_BREAKPOINT",
+			10: "EXC_CRASH",
+			11: "EXC_RESOURCE",
+			12: "EXC_GUARD",
+		}
+	case "linux", "android":
+		names = map[uint32]string{
+			4:  "SIGILL",
+			5:  "SIGTRAP",
+			6:  "SIGABRT",
+			7:  "SIGBUS",
+			8:  "SIGFPE",
+			11: "SIGSEGV",
+		}
+	}
+	if name, ok := names[d.ExceptionCode]; ok {
+		return name
+	}
+	return fmt.Sprintf("exception 0x%x", d.ExceptionCode)
+}
+
+// render formats the frames as "N  module + 0xoffset (0xaddress)"
+func (d *minidump) render() string {
+	lines := make([]string, 0, len(d.Frames))
+	for i, frame := range d.Frames {
+		if frame.Module == nil {
+			lines = append(lines, fmt.Sprintf("%-3d <unknown> (0x%x)", i, frame.Address))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-3d %s + 0x%x (0x%x)", i, frame.Module.Name, frame.Address-frame.Module.Base, frame.Address))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func minidumpArchName(code uint16) string {
+	switch code {
+	case 0:
+		return "x86"
+	case 5:
+		return "arm"
+	case 9:
+		return "amd64"
+	case 12, 0x8003:
+		return "arm64"
+	}
+	return "unknown"
+}
+
+func minidumpOSName(platform uint32) string {
+	switch platform {
+	case 0, 1, 2:
+		return "windows"
+	case 0x8101:
+		return "macos"
+	case 0x8102:
+		return "ios"
+	case 0x8201:
+		return "linux"
+	case 0x8203:
+		return "android"
+	}
+	return "unknown"
+}
+
+// fingerprint groups on the exception and the crashing module offset, which
+// are stable across runs of the same build unlike absolute addresses
+func (d *minidump) fingerprint() string {
+	data := d.exceptionName() + "\n" + d.crashModule()
+	if len(d.Frames) > 0 && d.Frames[0].Module != nil {
+		data += fmt.Sprintf("+0x%x", d.Frames[0].Address-d.Frames[0].Module.Base)
+	}
+	hash := sha256.Sum256([]byte(data))
+	return fmt.Sprintf("%x", hash)[:16]
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidMinidump is returned when an uploaded file is not a usable minidump
+var ErrInvalidMinidump = errors.New("invalid minidump")
+
+// maxMinidumpAnnotations caps the number of upload form fields kept on the event
+const maxMinidumpAnnotations = 50
+
+type MinidumpService struct {
+	db           *database.DB
+	redis        *redis.Client
+	errorService *ErrorService
+	quotaService *QuotaService
+	storageDir   string
+}
+
+func NewMinidumpService(db *database.DB, redis *redis.Client, errorService *ErrorService, quotaService *QuotaService, storageDir string) *MinidumpService {
+	return &MinidumpService{
+		db:           db,
+		redis:        redis,
+		errorService: errorService,
+		quotaService: quotaService,
+		storageDir:   storageDir,
+	}
+}
+
+// IngestMinidump stores a Breakpad/Crashpad minidump and records an error event
+// with source "native" (or annotations["source"]) carrying the crashing
+// thread's stack and the module list. "ver" or "release" annotations set the
+// release, "environment" the environment; other annotations go into context.
+func (s *MinidumpService) IngestMinidump(ctx context.Context, content io.Reader, annotations map[string]string, projectID *uuid.UUID, userAgent, ipAddress string) (*models.Minidump, error) {
+	if err := os.MkdirAll(s.storageDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create minidump storage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.storageDir, "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	dump, err := parseMinidump(tmp, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMinidump, err)
+	}
+
+	if projectID != nil {
+		if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+			return nil, err
+		}
+	}
+
+	record := &models.Minidump{
+		ID:          uuid.New(),
+		ProjectID:   projectID,
+		Fingerprint: dump.fingerprint(),
+		OS:          dump.OS,
+		Arch:        dump.Arch,
+		Size:        size,
+		Checksum:    fmt.Sprintf("%x", hash.Sum(nil)),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	req := s.buildErrorRequest(dump, record.ID, annotations)
+	record.Release = req.Release
+
+	event, err := s.errorService.recordEvent(ctx, req, record.Fingerprint, projectID, userAgent, ipAddress)
+	if err != nil && err != ErrEventThrottled {
+		return nil, err
+	}
+	// Throttled crashes are still stored so the dump can be inspected
+	if event != nil {
+		record.ErrorID = &event.ID
+	}
+
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), s.minidumpPath(record.ID)); err != nil {
+		return nil, fmt.Errorf("failed to store minidump: %w", err)
+	}
+	if err := s.db.CreateMinidump(record); err != nil {
+		os.Remove(s.minidumpPath(record.ID))
+		return nil, err
+	}
+
+	log.Printf("MINIDUMP STORED: %s %s/%s in %s (%d bytes)", dump.exceptionName(), dump.OS, dump.Arch, dump.crashModule(), size)
+	return record, nil
+}
+
+func (s *MinidumpService) buildErrorRequest(dump *minidump, id uuid.UUID, annotations map[string]string) *models.CreateErrorRequest {
+	stackTrace := dump.render()
+
+	details := map[string]interface{}{
+		"minidump_id":   id.String(),
+		"os":            dump.OS,
+		"arch":          dump.Arch,
+		"crash_address": fmt.Sprintf("0x%x", dump.ExceptionAddress),
+		"crash_thread":  dump.CrashThreadID,
+		"modules":       dump.Modules,
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:      "critical",
+		Message:    fmt.Sprintf("%s in %s", dump.exceptionName(), dump.crashModule()),
+		StackTrace: &stackTrace,
+		Source:     "native",
+	}
+
+	extra := map[string]string{}
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := annotations[key]
+		switch key {
+		case "source":
+			req.Source = value
+		case "environment":
+			req.Environment = &value
+		case "release", "ver":
+			if req.Release == nil {
+				req.Release = &value
+			}
+		default:
+			if len(extra) < maxMinidumpAnnotations {
+				extra[key] = truncate(value, 1000)
+			}
+		}
+	}
+	if len(extra) > 0 {
+		details["annotations"] = extra
+	}
+
+	req.Context = details
+	return req
+}
+
+func (s *MinidumpService) GetMinidumps(ctx context.Context, fingerprint string, limit int) ([]models.Minidump, error) {
+	return s.db.GetMinidumps(fingerprint, limit)
+}
+
+// OpenMinidump returns the stored minidump and its raw contents
+func (s *MinidumpService) OpenMinidump(ctx context.Context, id uuid.UUID) (*models.Minidump, *os.File, error) {
+	record, err := s.db.GetMinidumpByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(s.minidumpPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("minidump not found")
+		}
+		return nil, nil, err
+	}
+	return record, f, nil
+}
+
+func (s *MinidumpService) minidumpPath(id uuid.UUID) string {
+	return filepath.Join(s.storageDir, id.String()+".dmp")
+}
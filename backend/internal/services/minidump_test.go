@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	testModuleBase = 0x7f0000000000
+	testStackStart = 0x10000
+	testThreadID   = 7
+)
+
+// testMinidump describes an amd64 Linux minidump with one module and one
+// thread. The counts and sizes override what the dump claims, so tests can
+// make it lie about its own layout.
+type testMinidump struct {
+	streamCount uint32
+	moduleCount uint32
+	threadCount uint32
+	stackSize   uint32
+	stack       []byte
+}
+
+func newTestMinidump() *testMinidump {
+	stack := make([]byte, 64)
+	binary.LittleEndian.PutUint64(stack[8:], testModuleBase+0x200)
+	binary.LittleEndian.PutUint64(stack[24:], testModuleBase+0x300)
+	return &testMinidump{streamCount: 4, moduleCount: 1, threadCount: 1, stack: stack}
+}
+
+func (d *testMinidump) build() []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	put32 := func(v uint32) { binary.Write(&buf, le, v) }
+	put64 := func(v uint64) { binary.Write(&buf, le, v) }
+	pad := func(n int) { buf.Write(make([]byte, n)) }
+	at := func() uint32 { return uint32(buf.Len()) }
+
+	// Header and a directory of four streams, filled in at the end
+	put32(minidumpSignature)
+	put32(0)
+	put32(d.streamCount)
+	put32(32)
+	pad(16)
+	directory := at()
+	pad(4 * 12)
+
+	systemInfo := at()
+	binary.Write(&buf, le, uint16(9)) // amd64
+	pad(18)
+	put32(0x8201) // linux
+
+	name := utf16.Encode([]rune("/usr/lib/libapp.so"))
+	nameRVA := at()
+	put32(uint32(len(name) * 2))
+	binary.Write(&buf, le, name)
+
+	moduleList := at()
+	put32(d.moduleCount)
+	put64(testModuleBase)
+	put32(0x10000)
+	pad(8)
+	put32(nameRVA)
+	pad(108 - 24)
+
+	context := at()
+	pad(0x98)
+	put64(testStackStart)
+	contextSize := at() - context
+
+	stackRVA := at()
+	buf.Write(d.stack)
+	stackSize := d.stackSize
+	if stackSize == 0 {
+		stackSize = uint32(len(d.stack))
+	}
+
+	threadList := at()
+	put32(d.threadCount)
+	put32(testThreadID)
+	pad(20)
+	put64(testStackStart)
+	put32(stackSize)
+	put32(stackRVA)
+	pad(8)
+
+	exception := at()
+	put32(testThreadID)
+	pad(4)
+	put32(11) // SIGSEGV
+	pad(12)
+	put64(testModuleBase + 0x100)
+	pad(160 - 32)
+	put32(contextSize)
+	put32(context)
+
+	dump := buf.Bytes()
+	entries := [][3]uint32{
+		{systemInfoStream, 24, systemInfo},
+		{moduleListStream, 4 + 108, moduleList},
+		{threadListStream, 4 + 48, threadList},
+		{exceptionStream, 168, exception},
+	}
+	for i, entry := range entries {
+		for j, v := range entry {
+			le.PutUint32(dump[int(directory)+i*12+j*4:], v)
+		}
+	}
+	return dump
+}
+
+func parseTestMinidump(d *testMinidump) (*minidump, error) {
+	data := d.build()
+	return parseMinidump(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestParseMinidump(t *testing.T) {
+	dump, err := parseTestMinidump(newTestMinidump())
+	if err != nil {
+		t.Fatalf("parseMinidump: %v", err)
+	}
+	if dump.OS != "linux" || dump.Arch != "amd64" {
+		t.Errorf("OS, Arch = %q, %q, want linux, amd64", dump.OS, dump.Arch)
+	}
+	if dump.exceptionName() != "SIGSEGV" {
+		t.Errorf("exceptionName() = %q, want SIGSEGV", dump.exceptionName())
+	}
+	if len(dump.Modules) != 1 || dump.Modules[0].Name != "libapp.so" {
+		t.Fatalf("Modules = %+v, want libapp.so", dump.Modules)
+	}
+	want := "0   libapp.so + 0x100 (0x7f0000000100)\n" +
+		"1   libapp.so + 0x200 (0x7f0000000200)\n" +
+		"2   libapp.so + 0x300 (0x7f0000000300)"
+	if got := dump.render(); got != want {
+		t.Errorf("render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseMinidumpRejectsBadLayouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(d *testMinidump)
+		wantErr string
+	}{
+		{"truncated directory", func(d *testMinidump) { d.streamCount = 1000 }, "truncated minidump"},
+		{"oversized directory", func(d *testMinidump) { d.streamCount = 1 << 30 }, "too many streams"},
+		{"truncated module list", func(d *testMinidump) { d.moduleCount = 4000 }, "truncated minidump"},
+		{"oversized module list", func(d *testMinidump) { d.moduleCount = 1 << 30 }, "too many modules"},
+	}
+
+	for _, tt := range tests {
+		d := newTestMinidump()
+		tt.modify(d)
+		_, err := parseTestMinidump(d)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMinidumpBoundsStackScan(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(d *testMinidump)
+	}{
+		{"truncated thread list", func(d *testMinidump) { d.threadCount = 60000 }},
+		{"oversized thread list", func(d *testMinidump) { d.threadCount = 1 << 30 }},
+		{"oversized stack", func(d *testMinidump) { d.stackSize = 0xfffffff0 }},
+		{"return addresses past the scanned stack", func(d *testMinidump) {
+			d.stack = make([]byte, maxScannedStackBytes+64)
+			binary.LittleEndian.PutUint64(d.stack[maxScannedStackBytes:], testModuleBase+0x200)
+		}},
+	}
+
+	for _, tt := range tests {
+		d := newTestMinidump()
+		tt.modify(d)
+		dump, err := parseTestMinidump(d)
+		if err != nil {
+			t.Errorf("%s: parseMinidump: %v", tt.name, err)
+			continue
+		}
+		// Only the exception frame is left when the stack cannot be scanned
+		if len(dump.Frames) != 1 {
+			t.Errorf("%s: %d frames, want 1", tt.name, len(dump.Frames))
+		}
+	}
+}
@@ -12,14 +12,18 @@ import (
 )
 
 type MonitoringService struct {
-	db    *database.DB
-	redis *redis.Client
+	db     *database.DB
+	redis  *redis.Client
+	leader *LeaderElector
+	hosts  *HostService
 }
 
-func NewMonitoringService(db *database.DB, redis *redis.Client) *MonitoringService {
+func NewMonitoringService(db *database.DB, redis *redis.Client, leader *LeaderElector, hosts *HostService) *MonitoringService {
 	return &MonitoringService{
-		db:    db,
-		redis: redis,
+		db:     db,
+		redis:  redis,
+		leader: leader,
+		hosts:  hosts,
 	}
 }
 
@@ -92,6 +96,7 @@ func (s *MonitoringService) checkDatabaseHealth() models.ServiceHealth {
 		responseTime = 0
 	}
 
+	stats := s.db.Stats()
 	return models.ServiceHealth{
 		Name:           "Database",
 		Status:         status,
@@ -99,8 +104,11 @@ func (s *MonitoringService) checkDatabaseHealth() models.ServiceHealth {
 		ResponseTimeMs: responseTime,
 		LastChecked:    time.Now().UTC(),
 		Details: map[string]interface{}{
-			"connections":     rand.Intn(50) + 10,
-			"max_connections": 100,
+			"connections":     stats.OpenConnections,
+			"in_use":          stats.InUse,
+			"idle":            stats.Idle,
+			"max_connections": stats.MaxOpenConnections,
+			"wait_count":      stats.WaitCount,
 		},
 	}
 }
@@ -130,27 +138,22 @@ func (s *MonitoringService) GetSystemMetrics(ctx context.Context, timeframe stri
 	// Try to get from cache first
 	if cachedMetrics, err := s.redis.GetCachedSystemMetrics(ctx); err == nil && cachedMetrics != nil {
 		log.Printf("CACHE HIT: GetSystemMetrics")
+		cachedMetrics.Database = s.databaseMetrics()
+		cachedMetrics.Redis = s.redisMetrics()
+		cachedMetrics.Instance = s.instanceMetrics()
 		return cachedMetrics, nil
 	}
 
-	log.Printf("CACHE MISS: GetSystemMetrics - generating mock metrics")
-
-	// For demo purposes, generate mock system metrics
-	// In a real implementation, this would collect actual system metrics
-	metrics := &models.SystemMetrics{
-		CPUUsagePercent:    65.2 + rand.Float64()*20,
-		MemoryUsagePercent: 78.1 + rand.Float64()*10,
-		DiskUsagePercent:   45.7 + rand.Float64()*10,
-		NetworkIO: struct {
-			BytesIn  int64 `json:"bytes_in"`
-			BytesOut int64 `json:"bytes_out"`
-		}{
-			BytesIn:  int64(1024000 + rand.Intn(512000)),
-			BytesOut: int64(2048000 + rand.Intn(1024000)),
-		},
-		ActiveConnections: 45 + rand.Intn(55),
-		RequestsPerMinute: 1200 + rand.Intn(300),
+	log.Printf("CACHE MISS: GetSystemMetrics - combining host check-ins")
+
+	// Host usage comes from the agents that checked in recently
+	metrics, err := s.hosts.Totals(ctx)
+	if err != nil {
+		return nil, err
 	}
+	metrics.Database = s.databaseMetrics()
+	metrics.Redis = s.redisMetrics()
+	metrics.Instance = s.instanceMetrics()
 
 	// Cache the result
 	go func() {
@@ -165,6 +168,53 @@ func (s *MonitoringService) GetSystemMetrics(ctx context.Context, timeframe stri
 	return metrics, nil
 }
 
+// databaseMetrics, redisMetrics and instanceMetrics read live state, which is
+// not cached
+func (s *MonitoringService) databaseMetrics() *models.DatabaseMetrics {
+	stats := s.db.Stats()
+	return &models.DatabaseMetrics{
+		SlowQueries:          s.db.SlowQueryCount(),
+		SlowQueryThresholdMS: s.db.SlowQueryThreshold().Milliseconds(),
+		Retries:              s.db.RetryCount(),
+		RetriesExhausted:     s.db.RetryExhaustedCount(),
+		Pool: models.PoolMetrics{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		},
+	}
+}
+
+func (s *MonitoringService) redisMetrics() *models.RedisMetrics {
+	queueLength, err := s.redis.QueueLength(context.Background())
+	if err != nil {
+		log.Printf("Failed to read error queue length: %v", err)
+	}
+	memory, err := s.redis.GetMemoryReport(context.Background())
+	if err != nil {
+		log.Printf("Failed to read Redis memory report: %v", err)
+	}
+	return &models.RedisMetrics{
+		Retries:          s.redis.RetryCount(),
+		RetriesExhausted: s.redis.RetryExhaustedCount(),
+		QueueLength:      queueLength,
+		Memory:           memory,
+	}
+}
+
+func (s *MonitoringService) instanceMetrics() *models.InstanceMetrics {
+	return &models.InstanceMetrics{
+		ID:     s.leader.ID(),
+		Leader: s.leader.IsLeader(),
+	}
+}
+
 func (s *MonitoringService) GetUptime(ctx context.Context) (*models.UptimeData, error) {
 	// Try to get from cache first
 	if cachedUptime, err := s.redis.GetCachedUptime(ctx); err == nil && cachedUptime != nil {
@@ -203,3 +253,7 @@ func (s *MonitoringService) GetUptime(ctx context.Context) (*models.UptimeData,
 
 	return uptime, nil
 }
+
+func (s *MonitoringService) GetThrottlePeriods(ctx context.Context, limit int) ([]models.ThrottlePeriod, error) {
+	return s.db.GetThrottlePeriods(limit)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+const (
+	// digestSampleCount is how many held subjects a digest lists
+	digestSampleCount = 5
+	// digestSendInterval is how often due digests are sent
+	digestSendInterval = 15 * time.Second
+)
+
+// NotificationBatcher rolls up notification storms. Once a rule has sent
+// more than threshold notifications to one channel within a minute, further
+// ones are held and summed up in a single digest at the end of the window.
+// A threshold of zero sends everything.
+type NotificationBatcher struct {
+	redis     *redis.Client
+	threshold int
+	window    time.Duration
+}
+
+func NewNotificationBatcher(redis *redis.Client, threshold int, window time.Duration) *NotificationBatcher {
+	return &NotificationBatcher{
+		redis:     redis,
+		threshold: threshold,
+		window:    window,
+	}
+}
+
+// Hold reports whether a notification to a rule's target was held for a
+// digest rather than to be sent now. If Redis fails it is sent.
+func (b *NotificationBatcher) Hold(ruleID uuid.UUID, target, subject string) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	ctx := context.Background()
+	key := digestKey(ruleID, target)
+	now := time.Now()
+	count, err := b.redis.CountNotification(ctx, key, now.Truncate(time.Minute))
+	if err != nil {
+		log.Printf("Failed to count notifications for rule %s: %v", ruleID, err)
+		return false
+	}
+	if count <= int64(b.threshold) {
+		return false
+	}
+
+	if err := b.redis.HoldNotification(ctx, key, subject, now.Add(b.window), digestSampleCount); err != nil {
+		log.Printf("Failed to hold notification for rule %s: %v", ruleID, err)
+		return false
+	}
+	return true
+}
+
+// digestKey identifies a rule's channel. Targets are hashed so webhook URLs
+// are not copied into Redis.
+func digestKey(ruleID uuid.UUID, target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return ruleID.String() + ":" + hex.EncodeToString(sum[:8])
+}
+
+// StartDigestSender sends due notification digests until ctx is done
+func (s *AlertsService) StartDigestSender(ctx context.Context) {
+	if s.batcher.threshold <= 0 {
+		return
+	}
+	log.Println("Starting notification digest sender...")
+
+	ticker := time.NewTicker(digestSendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Notification digest sender stopped")
+			return
+		case <-ticker.C:
+			s.sendDueDigests(ctx)
+		}
+	}
+}
+
+func (s *AlertsService) sendDueDigests(ctx context.Context) {
+	now := time.Now().UTC()
+	keys, err := s.batcher.redis.DueNotificationDigests(ctx, now)
+	if err != nil {
+		log.Printf("Failed to load notification digests: %v", err)
+		return
+	}
+
+	for _, key := range keys {
+		digest, err := s.batcher.redis.TakeNotificationDigest(ctx, key)
+		if err != nil {
+			log.Printf("Failed to take notification digest %s: %v", key, err)
+			continue
+		}
+		if digest == nil {
+			continue
+		}
+
+		ruleIDText, _, _ := strings.Cut(key, ":")
+		ruleID, err := uuid.Parse(ruleIDText)
+		if err != nil {
+			continue
+		}
+		rule, err := s.db.GetAlertRuleByID(ruleID)
+		if err != nil {
+			log.Printf("Dropping notification digest for rule %s: %v", ruleID, err)
+			continue
+		}
+		// The channel may have been removed from the rule since
+		for _, target := range rule.Notifications {
+			if digestKey(rule.ID, target) == key {
+				s.sendDigest(rule, target, digest, now)
+				break
+			}
+		}
+	}
+}
+
+func (s *AlertsService) sendDigest(rule *models.AlertRule, target string, digest *redis.NotificationDigest, now time.Time) {
+	window := now.Sub(digest.Since).Round(time.Minute)
+	if window < time.Minute {
+		window = time.Minute
+	}
+	log.Printf("NOTIFICATION DIGEST: rule %s (%s) - %d held notifications", rule.ID, rule.Name, digest.Count)
+
+	subject := fmt.Sprintf("[Alert] %s: %d more notifications in the last %s", rule.Name, digest.Count, window)
+	body := fmt.Sprintf("Alert rule %q sent more than %d notifications to this channel within a minute, so the next %d were held and are summed up here.\n\nLatest:\n",
+		rule.Name, s.batcher.threshold, digest.Count)
+	for _, sample := range digest.Samples {
+		body += "- " + sample + "\n"
+	}
+	s.deliver(rule, s.route([]string{target}, rule.Severity, now), subject, body, map[string]interface{}{
+		"rule_id":   rule.ID,
+		"rule_name": rule.Name,
+		"status":    "digest",
+		"count":     digest.Count,
+		"since":     digest.Since,
+		"until":     now,
+		"samples":   digest.Samples,
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidSchedule is returned for notification schedules that can't be saved
+var ErrInvalidSchedule = errors.New("invalid notification schedule")
+
+// notificationSchedulesRefresh bounds how long a schedule change takes to
+// reach other replicas
+const notificationSchedulesRefresh = time.Minute
+
+// scheduleDays are the accepted quiet_days, in time.Weekday order
+var scheduleDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func (s *AlertsService) GetNotificationSchedules(ctx context.Context) ([]models.NotificationSchedule, error) {
+	return s.db.GetNotificationSchedules()
+}
+
+func (s *AlertsService) CreateNotificationSchedule(ctx context.Context, req *models.CreateNotificationScheduleRequest) (*models.NotificationSchedule, error) {
+	now := time.Now().UTC()
+	schedule := &models.NotificationSchedule{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.applyScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.CreateNotificationSchedule(schedule); err != nil {
+		return nil, err
+	}
+	s.invalidateSchedules()
+	return schedule, nil
+}
+
+// UpdateNotificationSchedule replaces a schedule's settings
+func (s *AlertsService) UpdateNotificationSchedule(ctx context.Context, id uuid.UUID, req *models.CreateNotificationScheduleRequest) (*models.NotificationSchedule, error) {
+	schedule, err := s.db.GetNotificationSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+	schedule.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateNotificationSchedule(schedule); err != nil {
+		return nil, err
+	}
+	s.invalidateSchedules()
+	return schedule, nil
+}
+
+func (s *AlertsService) DeleteNotificationSchedule(ctx context.Context, id uuid.UUID) error {
+	if err := s.db.DeleteNotificationSchedule(id); err != nil {
+		return err
+	}
+	s.invalidateSchedules()
+	return nil
+}
+
+// applyScheduleRequest validates a request and copies it onto schedule. Each
+// target may have only one schedule.
+func (s *AlertsService) applyScheduleRequest(schedule *models.NotificationSchedule, req *models.CreateNotificationScheduleRequest) error {
+	if !validScheduleTarget(req.Target) {
+		return fmt.Errorf("%w: target must be email, email:<address>, team:<uuid> or webhook:<url>", ErrInvalidSchedule)
+	}
+	if req.Fallback != nil && (!validScheduleTarget(*req.Fallback) || *req.Fallback == req.Target) {
+		return fmt.Errorf("%w: fallback must be another email, email:<address>, team:<uuid> or webhook:<url> target", ErrInvalidSchedule)
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := loadTimezone(timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+
+	if (req.QuietStart == "") != (req.QuietEnd == "") {
+		return fmt.Errorf("%w: quiet_start and quiet_end must be set together", ErrInvalidSchedule)
+	}
+	if req.QuietStart != "" {
+		start, okStart := parseClock(req.QuietStart)
+		end, okEnd := parseClock(req.QuietEnd)
+		if !okStart || !okEnd {
+			return fmt.Errorf("%w: quiet_start and quiet_end must be HH:MM", ErrInvalidSchedule)
+		}
+		if start == end {
+			return fmt.Errorf("%w: quiet_start and quiet_end must differ", ErrInvalidSchedule)
+		}
+	}
+	days := []string{}
+	for _, day := range req.QuietDays {
+		day = strings.ToLower(day)
+		if !slices.Contains(scheduleDays, day) {
+			return fmt.Errorf("%w: quiet_days must be mon, tue, wed, thu, fri, sat or sun", ErrInvalidSchedule)
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	if req.QuietStart == "" && len(days) == 0 {
+		return fmt.Errorf("%w: set quiet_start and quiet_end, quiet_days, or both", ErrInvalidSchedule)
+	}
+
+	existing, err := s.db.GetNotificationSchedules()
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.Target == req.Target && other.ID != schedule.ID {
+			return fmt.Errorf("%w: target already has a schedule", ErrInvalidSchedule)
+		}
+	}
+
+	schedule.Target = req.Target
+	schedule.Timezone = timezone
+	schedule.QuietStart = req.QuietStart
+	schedule.QuietEnd = req.QuietEnd
+	schedule.QuietDays = days
+	schedule.Fallback = req.Fallback
+	schedule.CriticalOverride = req.CriticalOverride == nil || *req.CriticalOverride
+	return nil
+}
+
+func validScheduleTarget(target string) bool {
+	kind, value, _ := strings.Cut(target, ":")
+	switch kind {
+	case "email":
+		return true
+	case "team":
+		_, err := uuid.Parse(value)
+		return err == nil
+	case "webhook":
+		return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+	}
+	return false
+}
+
+// parseClock returns the minutes after midnight of an HH:MM time
+func parseClock(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// scheduleQuiet reports whether now falls in a schedule's quiet hours. A
+// window whose end is before its start runs past midnight.
+func scheduleQuiet(schedule *models.NotificationSchedule, now time.Time) bool {
+	loc, err := loadTimezone(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	if slices.Contains(schedule.QuietDays, scheduleDays[local.Weekday()]) {
+		return true
+	}
+
+	start, okStart := parseClock(schedule.QuietStart)
+	end, okEnd := parseClock(schedule.QuietEnd)
+	if !okStart || !okEnd {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// route applies quiet hours to the targets of a rule with the given
+// severity: a target in its quiet hours is replaced by its fallback, or left
+// out when it has none
+func (s *AlertsService) route(targets []string, severity string, now time.Time) []string {
+	schedules := s.notificationSchedules()
+	if len(schedules) == 0 {
+		return targets
+	}
+
+	routed := make([]string, 0, len(targets))
+	for _, target := range targets {
+		schedule := findSchedule(schedules, target)
+		if schedule == nil || !scheduleQuiet(schedule, now) || (severity == "critical" && schedule.CriticalOverride) {
+			routed = append(routed, target)
+			continue
+		}
+		if schedule.Fallback == nil {
+			log.Printf("NOTIFICATION SKIPPED: quiet hours of schedule %s", schedule.ID)
+			continue
+		}
+		log.Printf("NOTIFICATION REROUTED: quiet hours of schedule %s", schedule.ID)
+		routed = append(routed, *schedule.Fallback)
+	}
+	return dedupeStrings(routed)
+}
+
+func findSchedule(schedules []models.NotificationSchedule, target string) *models.NotificationSchedule {
+	for i := range schedules {
+		if schedules[i].Target == target {
+			return &schedules[i]
+		}
+	}
+	return nil
+}
+
+// notificationSchedules returns the schedules from memory, reloading them
+// once a minute. If they can't be loaded, the last ones loaded are used.
+func (s *AlertsService) notificationSchedules() []models.NotificationSchedule {
+	s.schedulesMu.RLock()
+	if s.schedules != nil && time.Since(s.schedulesLoadedAt) < notificationSchedulesRefresh {
+		defer s.schedulesMu.RUnlock()
+		return s.schedules
+	}
+	s.schedulesMu.RUnlock()
+
+	schedules, err := s.db.GetNotificationSchedules()
+	if err != nil {
+		log.Printf("Failed to load notification schedules: %v", err)
+		s.schedulesMu.RLock()
+		defer s.schedulesMu.RUnlock()
+		return s.schedules
+	}
+
+	s.schedulesMu.Lock()
+	s.schedules = schedules
+	s.schedulesLoadedAt = time.Now()
+	s.schedulesMu.Unlock()
+	return schedules
+}
+
+func (s *AlertsService) invalidateSchedules() {
+	s.schedulesMu.Lock()
+	s.schedules = nil
+	s.schedulesMu.Unlock()
+}
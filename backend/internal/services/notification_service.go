@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+const (
+	// notificationRetention is how long inbox entries are kept, read or not
+	notificationRetention = 90 * 24 * time.Hour
+	// notificationPurgeInterval is how often expired inbox entries are removed
+	notificationPurgeInterval = 6 * time.Hour
+	// notificationStreamBuffer is how many messages wait for a slow stream
+	// client before newer ones are dropped
+	notificationStreamBuffer = 100
+)
+
+// NotificationTypes are the kinds of inbox entries
+var NotificationTypes = []string{"alert", "threshold", "watch", "ownership"}
+
+// ErrInvalidNotificationFilter is returned for inbox filters that are not
+// understood
+var ErrInvalidNotificationFilter = errors.New("invalid notification filter")
+
+// NotificationService keeps team members' in-app inboxes. Entries are
+// recorded next to the emails members are sent, and new ones are pushed to
+// the member's stream clients on every instance through Redis.
+type NotificationService struct {
+	db    *database.DB
+	redis *redis.Client
+
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan *models.NotificationMessage]struct{}
+}
+
+func NewNotificationService(db *database.DB, redis *redis.Client) *NotificationService {
+	return &NotificationService{
+		db:          db,
+		redis:       redis,
+		subscribers: make(map[uuid.UUID]map[chan *models.NotificationMessage]struct{}),
+	}
+}
+
+// Record adds a copy of notification to the inbox of each member. Failures
+// are logged; the email that goes with it is sent regardless.
+func (s *NotificationService) Record(memberIDs []uuid.UUID, notification models.Notification) {
+	if len(memberIDs) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[uuid.UUID]bool, len(memberIDs))
+	notifications := make([]models.Notification, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+		n := notification
+		n.ID = uuid.New()
+		n.MemberID = memberID
+		n.CreatedAt = now
+		notifications = append(notifications, n)
+	}
+
+	if err := s.db.CreateNotifications(notifications); err != nil {
+		log.Printf("Failed to record %s notification: %v", notification.Type, err)
+		return
+	}
+	for i := range notifications {
+		s.publish(&models.NotificationMessage{
+			Type:         "notification",
+			MemberID:     notifications[i].MemberID,
+			Notification: &notifications[i],
+		})
+	}
+}
+
+func (s *NotificationService) publish(message *models.NotificationMessage) {
+	if err := s.redis.PublishNotification(context.Background(), message); err != nil {
+		log.Printf("Failed to publish notification for member %s: %v", message.MemberID, err)
+	}
+}
+
+// GetNotifications returns a page of a member's inbox, how many entries
+// match filter and how many are unread
+func (s *NotificationService) GetNotifications(ctx context.Context, memberID uuid.UUID, limit, offset int, filter models.NotificationFilter) ([]models.Notification, int, int, error) {
+	if err := s.checkInbox(memberID, filter); err != nil {
+		return nil, 0, 0, err
+	}
+
+	notifications, total, err := s.db.GetNotifications(memberID, limit, offset, filter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	unread, err := s.db.CountUnreadNotifications(memberID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return notifications, total, unread, nil
+}
+
+// UnreadCount returns how many of a member's notifications are unread
+func (s *NotificationService) UnreadCount(ctx context.Context, memberID uuid.UUID) (int, error) {
+	if err := s.checkInbox(memberID, models.NotificationFilter{}); err != nil {
+		return 0, err
+	}
+	return s.db.CountUnreadNotifications(memberID)
+}
+
+// MarkRead marks one of a member's notifications read and returns it
+func (s *NotificationService) MarkRead(ctx context.Context, memberID, id uuid.UUID) (*models.Notification, error) {
+	notification, err := s.db.GetNotification(memberID, id)
+	if err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.db.MarkNotificationsRead(memberID, []uuid.UUID{id}, models.NotificationFilter{}, now); err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &now
+	s.publish(&models.NotificationMessage{Type: "read", MemberID: memberID})
+	return notification, nil
+}
+
+// MarkAllRead marks every unread notification of a member matching filter
+// read and returns how many there were
+func (s *NotificationService) MarkAllRead(ctx context.Context, memberID uuid.UUID, filter models.NotificationFilter) (int64, error) {
+	if err := s.checkInbox(memberID, filter); err != nil {
+		return 0, err
+	}
+
+	updated, err := s.db.MarkNotificationsRead(memberID, nil, filter, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	if updated > 0 {
+		s.publish(&models.NotificationMessage{Type: "read", MemberID: memberID})
+	}
+	return updated, nil
+}
+
+// checkInbox validates filter and returns "team member not found" for unknown
+// members, so their empty inbox is not mistaken for a real one
+func (s *NotificationService) checkInbox(memberID uuid.UUID, filter models.NotificationFilter) error {
+	if filter.Type != "" && !slices.Contains(NotificationTypes, filter.Type) {
+		return fmt.Errorf("%w: type must be alert, threshold, watch or ownership", ErrInvalidNotificationFilter)
+	}
+	_, err := s.db.GetTeamMemberByID(memberID)
+	return err
+}
+
+// Start relays inbox changes to this instance's stream clients until ctx is
+// done. Every instance runs it.
+func (s *NotificationService) Start(ctx context.Context) {
+	log.Println("Starting notification relay...")
+	for {
+		messages, err := s.redis.SubscribeNotifications(ctx)
+		if err != nil {
+			log.Printf("Notification relay: %v", err)
+		} else {
+			for message := range messages {
+				s.deliver(message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Notification relay stopped")
+			return
+		case <-time.After(tailResubscribeDelay):
+		}
+	}
+}
+
+func (s *NotificationService) deliver(message *models.NotificationMessage) {
+	s.mu.RLock()
+	listening := len(s.subscribers[message.MemberID]) > 0
+	s.mu.RUnlock()
+	if !listening {
+		return
+	}
+
+	unread, err := s.db.CountUnreadNotifications(message.MemberID)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of member %s: %v", message.MemberID, err)
+		return
+	}
+	message.Unread = unread
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for stream := range s.subscribers[message.MemberID] {
+		select {
+		case stream <- message:
+		default:
+			// The client refetches its inbox on the next message
+		}
+	}
+}
+
+// Subscribe streams a member's inbox changes. Callers must Unsubscribe when
+// done.
+func (s *NotificationService) Subscribe(memberID uuid.UUID) chan *models.NotificationMessage {
+	stream := make(chan *models.NotificationMessage, notificationStreamBuffer)
+	s.mu.Lock()
+	if s.subscribers[memberID] == nil {
+		s.subscribers[memberID] = make(map[chan *models.NotificationMessage]struct{})
+	}
+	s.subscribers[memberID][stream] = struct{}{}
+	s.mu.Unlock()
+	return stream
+}
+
+func (s *NotificationService) Unsubscribe(memberID uuid.UUID, stream chan *models.NotificationMessage) {
+	s.mu.Lock()
+	delete(s.subscribers[memberID], stream)
+	if len(s.subscribers[memberID]) == 0 {
+		delete(s.subscribers, memberID)
+	}
+	s.mu.Unlock()
+}
+
+// StartNotificationPurger removes inbox entries past notificationRetention,
+// until ctx is done
+func (s *NotificationService) StartNotificationPurger(ctx context.Context) {
+	log.Println("Starting notification purger...")
+
+	ticker := time.NewTicker(notificationPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.db.DeleteNotificationsBefore(time.Now().UTC().Add(-notificationRetention))
+		if err != nil {
+			log.Printf("Failed to purge notifications: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Purged %d notifications", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Notification purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Notifier delivers notifications to people. Email is sent over SMTP when a
+// host is configured; otherwise notifications are only logged.
+type Notifier struct {
+	smtpHost     string
+	smtpPort     string
+	smtpUsername string
+	smtpPassword string
+	from         string
+}
+
+func NewNotifier(smtpHost, smtpPort, smtpUsername, smtpPassword, from string) *Notifier {
+	return &Notifier{
+		smtpHost:     smtpHost,
+		smtpPort:     smtpPort,
+		smtpUsername: smtpUsername,
+		smtpPassword: smtpPassword,
+		from:         from,
+	}
+}
+
+func (n *Notifier) SendEmail(to []string, subject, body string) error {
+	return n.sendEmail(to, subject, "", body)
+}
+
+// SendHTMLEmail sends an email whose body is an HTML document
+func (n *Notifier) SendHTMLEmail(to []string, subject, html string) error {
+	return n.sendEmail(to, subject, "MIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n", html)
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// SendHTMLEmailWithAttachment sends an HTML email with a file attached
+func (n *Notifier) SendHTMLEmailWithAttachment(to []string, subject, html string, attachment EmailAttachment) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	part.Write([]byte(html))
+
+	part, err = writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {attachment.ContentType},
+		"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, attachment.Filename)},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	// Lines of an encoded attachment may be at most 76 characters
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		part.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	part.Write([]byte(encoded + "\r\n"))
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	headers := fmt.Sprintf("MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n", writer.Boundary())
+	return n.sendEmail(to, subject, headers, body.String())
+}
+
+func (n *Notifier) sendEmail(to []string, subject, headers, body string) error {
+	if len(to) == 0 {
+		return nil
+	}
+
+	if n.smtpHost == "" {
+		log.Printf("NOTIFICATION (email disabled): to: %s, subject: %s", strings.Join(to, ", "), subject)
+		return nil
+	}
+
+	var auth smtp.Auth
+	if n.smtpUsername != "" {
+		auth = smtp.PlainAuth("", n.smtpUsername, n.smtpPassword, n.smtpHost)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n%s\r\n%s\r\n",
+		n.from, strings.Join(to, ", "), subject, headers, body)
+
+	if err := smtp.SendMail(n.smtpHost+":"+n.smtpPort, auth, n.from, to, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("NOTIFICATION SENT: email to: %s, subject: %s", strings.Join(to, ", "), subject)
+	return nil
+}
+
+// SendWebhook posts a JSON payload to an arbitrary URL
+func (n *Notifier) SendWebhook(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	log.Printf("NOTIFICATION SENT: webhook to: %s", url)
+	return nil
+}
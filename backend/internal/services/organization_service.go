@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidOrganization is returned for organization changes that can't be
+// applied, and for requests reaching outside the caller's organization
+var ErrInvalidOrganization = errors.New("invalid organization")
+
+// MemberRoles are the roles a team member can hold
+var MemberRoles = []string{"owner", "admin", "developer", "viewer"}
+
+const maxOrgNameLength = 100
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// OrganizationService manages organizations and their projects. Keys that
+// belong to an organization only ever see that organization; deployment-wide
+// keys (no org_id) see all of them.
+type OrganizationService struct {
+	db    *database.DB
+	audit *AuditService
+}
+
+func NewOrganizationService(db *database.DB, audit *AuditService) *OrganizationService {
+	return &OrganizationService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// GetSwitcher lists the organizations visible to a caller scoped to orgID,
+// each with its projects
+func (s *OrganizationService) GetSwitcher(ctx context.Context, orgID *uuid.UUID) ([]models.OrgSwitcherEntry, error) {
+	var orgs []models.Organization
+	if orgID != nil {
+		org, err := s.db.GetOrganization(*orgID)
+		if err != nil {
+			return nil, err
+		}
+		orgs = []models.Organization{*org}
+	} else {
+		var err error
+		if orgs, err = s.db.GetOrganizations(); err != nil {
+			return nil, err
+		}
+	}
+
+	projects, err := s.db.GetProjects(orgID)
+	if err != nil {
+		return nil, err
+	}
+	byOrg := make(map[uuid.UUID][]models.Project)
+	for _, project := range projects {
+		if project.OrgID != nil {
+			byOrg[*project.OrgID] = append(byOrg[*project.OrgID], project)
+		}
+	}
+
+	entries := make([]models.OrgSwitcherEntry, 0, len(orgs))
+	for _, org := range orgs {
+		entry := models.OrgSwitcherEntry{
+			Organization: org,
+			Projects:     byOrg[org.ID],
+			Current:      orgID != nil && *orgID == org.ID,
+		}
+		if entry.Projects == nil {
+			entry.Projects = []models.Project{}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetOrganization returns an organization, treating ones outside the caller's
+// organization as missing
+func (s *OrganizationService) GetOrganization(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID) (*models.Organization, error) {
+	if !OrgVisible(id, callerOrg) {
+		return nil, fmt.Errorf("organization not found")
+	}
+	return s.db.GetOrganization(id)
+}
+
+// CreateOrganization adds an organization. Only deployment-wide keys may
+// create organizations.
+func (s *OrganizationService) CreateOrganization(ctx context.Context, req *models.CreateOrganizationRequest, callerOrg *uuid.UUID, actor string) (*models.Organization, error) {
+	if callerOrg != nil {
+		return nil, fmt.Errorf("%w: organization-scoped keys cannot create organizations", ErrInvalidOrganization)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateOrgName(req.Name); err != nil {
+		return nil, err
+	}
+	if !slugPattern.MatchString(req.Slug) || len(req.Slug) > 100 {
+		return nil, fmt.Errorf("%w: slug must be lowercase letters, digits and dashes", ErrInvalidOrganization)
+	}
+	if _, err := s.db.GetOrganizationBySlug(req.Slug); err == nil {
+		return nil, fmt.Errorf("%w: an organization with slug %q already exists", ErrInvalidOrganization, req.Slug)
+	} else if err.Error() != "organization not found" {
+		return nil, err
+	}
+	if err := validateOrgSettings(&req.Settings); err != nil {
+		return nil, err
+	}
+
+	org := &models.Organization{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Slug:      req.Slug,
+		Settings:  req.Settings,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.db.CreateOrganization(org); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record("org.created", actor, "organization", org.ID.String(), map[string]interface{}{"slug": org.Slug})
+	return org, nil
+}
+
+// UpdateOrganization renames an organization or replaces its settings
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, id uuid.UUID, req *models.UpdateOrganizationRequest, callerOrg *uuid.UUID, actor string) (*models.Organization, error) {
+	org, err := s.GetOrganization(ctx, id, callerOrg)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if err := validateOrgName(name); err != nil {
+			return nil, err
+		}
+		org.Name = name
+	}
+	if req.Settings != nil {
+		if err := validateOrgSettings(req.Settings); err != nil {
+			return nil, err
+		}
+		org.Settings = *req.Settings
+	}
+
+	if err := s.db.UpdateOrganization(org); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record("org.updated", actor, "organization", org.ID.String(), nil)
+	return org, nil
+}
+
+// CreateProject adds a project to an organization
+func (s *OrganizationService) CreateProject(ctx context.Context, orgID uuid.UUID, req *models.CreateProjectRequest, callerOrg *uuid.UUID, actor string) (*models.Project, error) {
+	if _, err := s.GetOrganization(ctx, orgID, callerOrg); err != nil {
+		return nil, err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxOrgNameLength {
+		return nil, fmt.Errorf("%w: project name is required and must be at most %d characters", ErrInvalidOrganization, maxOrgNameLength)
+	}
+	if !slugPattern.MatchString(req.Slug) || len(req.Slug) > 100 {
+		return nil, fmt.Errorf("%w: project slug must be lowercase letters, digits and dashes", ErrInvalidOrganization)
+	}
+	exists, err := s.db.ProjectSlugExists(req.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: a project with slug %q already exists", ErrAlreadyExists, req.Slug)
+	}
+
+	project := &models.Project{
+		ID:        uuid.New(),
+		OrgID:     &orgID,
+		Name:      req.Name,
+		Slug:      req.Slug,
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := applyExternalID(&project.ExternalID, req.ExternalID); err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateProject(project); err != nil {
+		return nil, externalIDConflict(err, "project", project.ExternalID)
+	}
+
+	s.audit.Record("project.created", actor, "project", project.ID.String(), map[string]interface{}{
+		"org_id": orgID,
+		"slug":   project.Slug,
+	})
+	return project, nil
+}
+
+// OrgVisible reports whether a caller scoped to callerOrg may see orgID.
+// Deployment-wide callers (nil) see every organization.
+func OrgVisible(orgID uuid.UUID, callerOrg *uuid.UUID) bool {
+	return callerOrg == nil || *callerOrg == orgID
+}
+
+func validateOrgName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOrganization)
+	}
+	if len(name) > maxOrgNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidOrganization, maxOrgNameLength)
+	}
+	return nil
+}
+
+func validateOrgSettings(settings *models.OrgSettings) error {
+	if settings.DefaultRole != "" && !slices.Contains(MemberRoles, settings.DefaultRole) {
+		return fmt.Errorf("%w: default_role must be one of %s", ErrInvalidOrganization, strings.Join(MemberRoles, ", "))
+	}
+	for i, domain := range settings.AllowedEmailDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || strings.Contains(domain, "@") {
+			return fmt.Errorf("%w: allowed_email_domains must be bare domains like example.com", ErrInvalidOrganization)
+		}
+		settings.AllowedEmailDomains[i] = domain
+	}
+	return nil
+}
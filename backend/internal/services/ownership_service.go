@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// stackPathPattern picks file paths such as "src/payments/charge.go" out of stack traces
+var stackPathPattern = regexp.MustCompile(`[\w@.\-/\\]+\.[A-Za-z]+`)
+
+type OwnershipService struct {
+	db       *database.DB
+	redis    *redis.Client
+	notifier *Notifier
+	inbox    *NotificationService
+}
+
+func NewOwnershipService(db *database.DB, redis *redis.Client, notifier *Notifier, inbox *NotificationService) *OwnershipService {
+	return &OwnershipService{
+		db:       db,
+		redis:    redis,
+		notifier: notifier,
+		inbox:    inbox,
+	}
+}
+
+func (s *OwnershipService) GetOwnershipRules(ctx context.Context, projectID *uuid.UUID) ([]models.OwnershipRule, error) {
+	return s.db.GetOwnershipRules(projectID)
+}
+
+func (s *OwnershipService) CreateOwnershipRule(ctx context.Context, req *models.CreateOwnershipRuleRequest) (*models.OwnershipRule, error) {
+	rule := &models.OwnershipRule{
+		ID:        uuid.New(),
+		ProjectID: req.ProjectID,
+		MatchType: req.MatchType,
+		Pattern:   req.Pattern,
+		OwnerType: req.OwnerType,
+		OwnerID:   req.OwnerID,
+		Priority:  req.Priority,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.db.CreateOwnershipRule(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (s *OwnershipService) UpdateOwnershipRule(ctx context.Context, id uuid.UUID, req *models.CreateOwnershipRuleRequest) (*models.OwnershipRule, error) {
+	rule, err := s.db.GetOwnershipRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ProjectID = req.ProjectID
+	rule.MatchType = req.MatchType
+	rule.Pattern = req.Pattern
+	rule.OwnerType = req.OwnerType
+	rule.OwnerID = req.OwnerID
+	rule.Priority = req.Priority
+
+	if err := s.db.UpdateOwnershipRule(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (s *OwnershipService) DeleteOwnershipRule(ctx context.Context, id uuid.UUID) error {
+	return s.db.DeleteOwnershipRule(id)
+}
+
+// AssignOwner sets AssignedTo or AssignedTeam on an incoming event. Events of
+// an existing group inherit its assignment; the first event of a new group is
+// routed by the highest-priority matching ownership rule and the owner is notified.
+func (s *OwnershipService) AssignOwner(error *models.Error) {
+	if error.Fingerprint == nil || error.AssignedTo != nil || error.AssignedTeam != nil {
+		return
+	}
+
+	exists, assignee, team, err := s.db.GetGroupAssignee(*error.Fingerprint)
+	if err != nil {
+		log.Printf("Failed to look up group assignee: %v", err)
+		return
+	}
+	if exists {
+		error.AssignedTo = assignee
+		error.AssignedTeam = team
+		return
+	}
+
+	rules, err := s.db.GetOwnershipRules(error.ProjectID)
+	if err != nil {
+		log.Printf("Failed to load ownership rules: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if !matchesOwnershipRule(&rule, error) {
+			continue
+		}
+
+		ownerID := rule.OwnerID
+		if rule.OwnerType == "team" {
+			error.AssignedTeam = &ownerID
+		} else {
+			error.AssignedTo = &ownerID
+		}
+		log.Printf("OWNERSHIP: assigned new error group %s to %s %s (rule %s)", *error.Fingerprint, rule.OwnerType, ownerID, rule.ID)
+		go s.notifyOwner(rule.OwnerType, ownerID, error)
+		return
+	}
+}
+
+func (s *OwnershipService) notifyOwner(ownerType string, ownerID uuid.UUID, error *models.Error) {
+	var recipients []string
+	var memberIDs []uuid.UUID
+	if ownerType == "team" {
+		members, err := s.db.GetTeamMembersByTeam(ownerID)
+		if err != nil {
+			log.Printf("Failed to load owning team %s: %v", ownerID, err)
+			return
+		}
+		for _, member := range members {
+			recipients = append(recipients, member.Email)
+			memberIDs = append(memberIDs, member.ID)
+		}
+	} else {
+		member, err := s.db.GetTeamMemberByID(ownerID)
+		if err != nil {
+			log.Printf("Failed to load owner %s: %v", ownerID, err)
+			return
+		}
+		recipients = []string{member.Email}
+		memberIDs = []uuid.UUID{member.ID}
+	}
+
+	subject := fmt.Sprintf("[%s] New error assigned to you: %s", error.Source, truncate(error.Message, 80))
+	body := fmt.Sprintf("A new error group was assigned to you by an ownership rule.\n\nMessage: %s\nSource: %s\nEnvironment: %s\nError ID: %s\n",
+		error.Message, error.Source, error.Environment, error.ID)
+	var fingerprint string
+	if error.Fingerprint != nil {
+		fingerprint = *error.Fingerprint
+	}
+	body += formatRunbook(resolveRunbook(s.db, nil, fingerprint, error.Source))
+
+	if err := s.notifier.SendEmail(recipients, subject, body); err != nil {
+		log.Printf("Failed to notify owner %s: %v", ownerID, err)
+	}
+	errorID := error.ID
+	notification := models.Notification{
+		Type:    "ownership",
+		Subject: subject,
+		Body:    body,
+		ErrorID: &errorID,
+	}
+	if fingerprint != "" {
+		notification.Fingerprint = &fingerprint
+	}
+	s.inbox.Record(memberIDs, notification)
+}
+
+func matchesOwnershipRule(rule *models.OwnershipRule, error *models.Error) bool {
+	pattern, err := globToRegexp(rule.Pattern)
+	if err != nil {
+		log.Printf("Invalid ownership pattern %q: %v", rule.Pattern, err)
+		return false
+	}
+
+	switch rule.MatchType {
+	case "source":
+		return pattern.MatchString(error.Source)
+	case "url":
+		return error.URL != nil && pattern.MatchString(*error.URL)
+	case "path":
+		if error.StackTrace == nil {
+			return false
+		}
+		for _, path := range stackPathPattern.FindAllString(*error.StackTrace, -1) {
+			if pattern.MatchString(strings.ReplaceAll(path, "\\", "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// globToRegexp converts a CODEOWNERS-style glob into an anchored regexp.
+// "**" matches across path separators, "*" and "?" do not. Patterns without
+// a leading "/" may match at any directory depth.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !strings.HasPrefix(glob, "/") {
+		sb.WriteString("(.*/)?")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// truncate cuts s to at most max bytes, backing up to a rune boundary so a
+// multi-byte character is never split
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// com.example.Foo -> a.b:
+	proguardClassLine = regexp.MustCompile(`^(\S+)\s+->\s+(\S+):$`)
+	// 1:5:void onCreate(android.os.Bundle):20:24 -> a
+	proguardMethodLine = regexp.MustCompile(`^\s+(?:(\d+):(\d+):)?\S+\s+([^\s(]+)\([^)]*\)(?::(\d+)(?::(\d+))?)?\s+->\s+(\S+)$`)
+	// at a.b.c(SourceFile:12)
+	javaFramePattern = regexp.MustCompile(`^(\s*at\s+)([\w$.]+)\.([\w$<>]+)\(([^:)]*)(?::(\d+))?\)(.*)$`)
+	// a.b.c: message, or Caused by: a.b.c: message
+	javaExceptionPattern = regexp.MustCompile(`^(\s*(?:Caused by:\s+)?)([\w$]+(?:\.[\w$]+)+)(:.*)?$`)
+)
+
+type proguardMethod struct {
+	name                 string
+	obfStart, obfEnd     int
+	origStart, origEnd   int
+	hasObfRange, hasOrig bool
+}
+
+type proguardClass struct {
+	name       string
+	sourceFile string
+	methods    map[string][]proguardMethod // by obfuscated name
+}
+
+// proguardMapping is a parsed ProGuard/R8 mapping file keyed by obfuscated class name
+type proguardMapping struct {
+	classes map[string]*proguardClass
+}
+
+func parseProguardMapping(r io.Reader) (*proguardMapping, error) {
+	mapping := &proguardMapping{classes: make(map[string]*proguardClass)}
+	var current *proguardClass
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "#") {
+			// R8 records the original source file as a JSON comment after the class line
+			if current != nil {
+				var meta struct {
+					ID       string `json:"id"`
+					FileName string `json:"fileName"`
+				}
+				if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))), &meta) == nil &&
+					meta.ID == "sourceFile" && meta.FileName != "" {
+					current.sourceFile = meta.FileName
+				}
+			}
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+
+		if m := proguardClassLine.FindStringSubmatch(line); m != nil {
+			current = &proguardClass{name: m[1], methods: make(map[string][]proguardMethod)}
+			mapping.classes[m[2]] = current
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if m := proguardMethodLine.FindStringSubmatch(line); m != nil {
+			method := proguardMethod{name: m[3]}
+			if m[1] != "" {
+				method.hasObfRange = true
+				method.obfStart, _ = strconv.Atoi(m[1])
+				method.obfEnd, _ = strconv.Atoi(m[2])
+			}
+			if m[4] != "" {
+				method.hasOrig = true
+				method.origStart, _ = strconv.Atoi(m[4])
+				method.origEnd = method.origStart
+				if m[5] != "" {
+					method.origEnd, _ = strconv.Atoi(m[5])
+				}
+			}
+			current.methods[m[6]] = append(current.methods[m[6]], method)
+		}
+		// Field lines are not needed to retrace stack traces
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mapping: %w", err)
+	}
+	if len(mapping.classes) == 0 {
+		return nil, fmt.Errorf("no class mappings found")
+	}
+
+	return mapping, nil
+}
+
+// retrace rewrites obfuscated Java/Kotlin frames and exception class names,
+// reporting whether anything changed
+func (m *proguardMapping) retrace(stackTrace string) (string, bool) {
+	lines := strings.Split(stackTrace, "\n")
+	changed := false
+
+	for i, line := range lines {
+		if frame := javaFramePattern.FindStringSubmatch(line); frame != nil {
+			if rewritten, ok := m.retraceFrame(frame); ok {
+				lines[i] = rewritten
+				changed = true
+			}
+			continue
+		}
+		if exception := javaExceptionPattern.FindStringSubmatch(line); exception != nil {
+			if class, ok := m.classes[exception[2]]; ok {
+				lines[i] = exception[1] + class.name + exception[3]
+				changed = true
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n"), changed
+}
+
+func (m *proguardMapping) retraceFrame(frame []string) (string, bool) {
+	prefix, obfClass, obfMethod, file, lineStr, suffix := frame[1], frame[2], frame[3], frame[4], frame[5], frame[6]
+
+	class, ok := m.classes[obfClass]
+	if !ok {
+		return "", false
+	}
+
+	line, hasLine := 0, lineStr != ""
+	if hasLine {
+		line, _ = strconv.Atoi(lineStr)
+	}
+
+	methodName := obfMethod
+	origLine := line
+	for _, method := range class.methods[obfMethod] {
+		if method.hasObfRange && hasLine && (line < method.obfStart || line > method.obfEnd) {
+			continue
+		}
+		methodName = method.name
+		if method.hasOrig && hasLine {
+			origLine = method.origStart
+			if method.hasObfRange && method.origEnd > method.origStart {
+				origLine = method.origStart + (line - method.obfStart)
+			}
+		}
+		break
+	}
+
+	// Inlined methods are listed with a dotted owner; keep just the member name
+	if i := strings.LastIndex(methodName, "."); i >= 0 {
+		methodName = methodName[i+1:]
+	}
+
+	if class.sourceFile != "" {
+		file = class.sourceFile
+	} else if file == "" || file == "SourceFile" || file == "Unknown Source" {
+		file = proguardSourceFile(class.name)
+	}
+
+	location := file
+	if hasLine {
+		location += ":" + strconv.Itoa(origLine)
+	}
+
+	return fmt.Sprintf("%s%s.%s(%s)%s", prefix, class.name, methodName, location, suffix), true
+}
+
+// proguardSourceFile guesses Foo.java from com.example.Foo$Inner
+func proguardSourceFile(className string) string {
+	name := className[strings.LastIndex(className, ".")+1:]
+	if i := strings.Index(name, "$"); i >= 0 {
+		name = name[:i]
+	}
+	return name + ".java"
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrProjectInUse is returned when a project can't be deleted because API
+// keys or stored errors still belong to it
+var ErrProjectInUse = errors.New("project in use")
+
+// projectVisible reports whether a caller scoped to callerOrg may see a
+// project. Projects without an organization are deployment-wide.
+func projectVisible(project *models.Project, callerOrg *uuid.UUID) bool {
+	return callerOrg == nil || (project.OrgID != nil && *project.OrgID == *callerOrg)
+}
+
+// GetProjects lists the projects visible to the caller. A non-empty
+// externalID finds the project with that external ID.
+func (s *OrganizationService) GetProjects(ctx context.Context, callerOrg *uuid.UUID, externalID string) ([]models.Project, error) {
+	projects, err := s.db.GetProjects(callerOrg)
+	if err != nil || externalID == "" {
+		return projects, err
+	}
+	return slices.DeleteFunc(projects, func(project models.Project) bool {
+		return project.ExternalID == nil || *project.ExternalID != externalID
+	}), nil
+}
+
+// GetProject returns a project, treating ones outside the caller's
+// organization as missing
+func (s *OrganizationService) GetProject(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID) (*models.Project, error) {
+	project, err := s.db.GetProject(id)
+	if err != nil {
+		return nil, err
+	}
+	if !projectVisible(project, callerOrg) {
+		return nil, fmt.Errorf("project not found")
+	}
+	return project, nil
+}
+
+// UpdateProject renames a project or changes its slug or external ID. With
+// ifVersion set, the project is only updated if it is still at that version.
+func (s *OrganizationService) UpdateProject(ctx context.Context, id uuid.UUID, req *models.UpdateProjectRequest, callerOrg *uuid.UUID, ifVersion int, actor string) (*models.Project, error) {
+	project, err := s.GetProject(ctx, id, callerOrg)
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != 0 && project.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > maxOrgNameLength {
+			return nil, fmt.Errorf("%w: project name is required and must be at most %d characters", ErrInvalidOrganization, maxOrgNameLength)
+		}
+		project.Name = name
+	}
+	if req.Slug != nil && *req.Slug != project.Slug {
+		if !slugPattern.MatchString(*req.Slug) || len(*req.Slug) > 100 {
+			return nil, fmt.Errorf("%w: project slug must be lowercase letters, digits and dashes", ErrInvalidOrganization)
+		}
+		exists, err := s.db.ProjectSlugExists(*req.Slug)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: a project with slug %q already exists", ErrAlreadyExists, *req.Slug)
+		}
+		project.Slug = *req.Slug
+	}
+	if err := applyExternalID(&project.ExternalID, req.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.UpdateProject(project, ifVersion); err != nil {
+		return nil, externalIDConflict(err, "project", project.ExternalID)
+	}
+
+	s.audit.Record("project.updated", actor, "project", project.ID.String(), map[string]interface{}{
+		"slug": project.Slug,
+	})
+	return project, nil
+}
+
+// DeleteProject deletes a project and its settings. Projects that still have
+// API keys or stored errors are kept, so no data is orphaned. With ifVersion
+// set, the project is only deleted if it is still at that version.
+func (s *OrganizationService) DeleteProject(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID, ifVersion int, actor string) error {
+	project, err := s.GetProject(ctx, id, callerOrg)
+	if err != nil {
+		return err
+	}
+
+	hasKeys, err := s.db.ProjectHasAPIKeys(id)
+	if err != nil {
+		return err
+	}
+	if hasKeys {
+		return fmt.Errorf("%w: delete the project's API keys first", ErrProjectInUse)
+	}
+
+	if err := s.db.DeleteProject(id, ifVersion); err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return fmt.Errorf("%w: the project has received errors", ErrProjectInUse)
+		}
+		return err
+	}
+
+	s.audit.Record("project.deleted", actor, "project", id.String(), map[string]interface{}{
+		"slug": project.Slug,
+	})
+	return nil
+}
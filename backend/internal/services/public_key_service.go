@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+var (
+	botUserAgentPattern = regexp.MustCompile(`(?i)bot\b|crawler|spider|slurp|headlesschrome|phantomjs|lighthouse|curl/|wget/|python-requests`)
+
+	// Browser noise that carries no useful information
+	noiseMessages = []string{
+		"Script error.",
+		"Script error",
+		"ResizeObserver loop limit exceeded",
+		"ResizeObserver loop completed with undelivered notifications.",
+	}
+
+	extensionSchemes = []string{"chrome-extension://", "moz-extension://", "safari-extension://", "safari-web-extension://"}
+)
+
+// maxPublicMessageLength rejects oversized messages sent with public keys
+const maxPublicMessageLength = 8192
+
+// PublicKeyService guards ingestion through public (browser) API keys with
+// origin allowlists, per-IP rate limits, and spam filtering
+type PublicKeyService struct {
+	db        *database.DB
+	redis     *redis.Client
+	rateLimit int
+}
+
+func NewPublicKeyService(db *database.DB, redis *redis.Client, rateLimit int) *PublicKeyService {
+	return &PublicKeyService{
+		db:        db,
+		redis:     redis,
+		rateLimit: rateLimit,
+	}
+}
+
+// OriginAllowed checks the request origin against the key's allowlist.
+// Entries may be exact origins, "*.example.com" subdomain wildcards, or "*".
+func (s *PublicKeyService) OriginAllowed(key *models.APIKey, origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	host := parsed.Hostname()
+
+	for _, allowed := range key.AllowedOrigins {
+		switch {
+		case allowed == "*":
+			return true
+		case strings.HasPrefix(allowed, "*."):
+			suffix := allowed[1:]
+			if strings.HasSuffix(host, suffix) || host == allowed[2:] {
+				return true
+			}
+		case strings.EqualFold(strings.TrimSuffix(allowed, "/"), parsed.Scheme+"://"+parsed.Host):
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitStatus is where a client stands in its current rate limit window
+type RateLimitStatus struct {
+	Limit     int
+	Remaining int
+	Used      int       // requests made in the window, including refused ones
+	Reset     time.Time // when the window ends and the count starts over
+}
+
+// AllowRequest applies the per-IP rate limit for a public key and returns the
+// client's standing, or nil when no limit applies. It fails open if Redis is
+// unavailable.
+func (s *PublicKeyService) AllowRequest(ctx context.Context, keyID uuid.UUID, ipAddress string) (bool, *RateLimitStatus) {
+	if s.rateLimit <= 0 {
+		return true, nil
+	}
+
+	now := time.Now().UTC()
+	count, err := s.redis.IncrementRateLimit(ctx, "public:"+keyID.String()+":"+ipAddress, now)
+	if err != nil {
+		log.Printf("Failed to check public key rate limit: %v", err)
+		return true, nil
+	}
+
+	status := &RateLimitStatus{
+		Limit:     s.rateLimit,
+		Remaining: max(s.rateLimit-int(count), 0),
+		Used:      int(count),
+		Reset:     now.Truncate(time.Minute).Add(time.Minute),
+	}
+	if count > int64(s.rateLimit) {
+		if count == int64(s.rateLimit)+1 {
+			log.Printf("RATE LIMITED: public key %s from %s", keyID, ipAddress)
+		}
+		return false, status
+	}
+	return true, status
+}
+
+// FilterReason returns why an event sent with a public key should be dropped
+// as bot traffic or browser noise, or "" to accept it
+func (s *PublicKeyService) FilterReason(req *models.CreateErrorRequest, userAgent string) string {
+	if userAgent == "" || botUserAgentPattern.MatchString(userAgent) {
+		return "bot"
+	}
+	if len(req.Message) > maxPublicMessageLength {
+		return "oversized"
+	}
+
+	message := strings.TrimSpace(req.Message)
+	for _, noise := range noiseMessages {
+		if message == noise {
+			return "noise"
+		}
+	}
+
+	locations := []string{}
+	if req.StackTrace != nil {
+		locations = append(locations, *req.StackTrace)
+	}
+	if req.URL != nil {
+		locations = append(locations, *req.URL)
+	}
+	for _, location := range locations {
+		for _, scheme := range extensionSchemes {
+			if strings.Contains(location, scheme) {
+				return "browser-extension"
+			}
+		}
+	}
+
+	return ""
+}
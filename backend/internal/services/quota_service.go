@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrQuotaExceeded is returned when a project, or the organization it belongs
+// to, has used up its hard quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// quotasRefresh bounds how stale the in-memory quotas may get. Changes made
+// on another instance take effect within it.
+const quotasRefresh = 30 * time.Second
+
+// quotasRetry is how long a failed quota lookup is cached before the next
+// attempt, so a database outage does not cost a query per event
+const quotasRetry = 5 * time.Second
+
+// usageFlushInterval is how often usage counts are moved from Redis to the
+// database
+const usageFlushInterval = 15 * time.Second
+
+type QuotaService struct {
+	db    *database.DB
+	redis *redis.Client
+
+	mu     sync.RWMutex
+	quotas map[uuid.UUID]*projectQuotas
+}
+
+// projectQuotas are the limits an event of a project is checked against
+type projectQuotas struct {
+	project   *models.ProjectQuota
+	org       *models.OrgQuota // nil when the project has no organization
+	expiresAt time.Time
+}
+
+func NewQuotaService(db *database.DB, redis *redis.Client) *QuotaService {
+	return &QuotaService{
+		db:     db,
+		redis:  redis,
+		quotas: map[uuid.UUID]*projectQuotas{},
+	}
+}
+
+// RecordEvent meters an incoming event for the project and its organization.
+// It returns ErrQuotaExceeded when a hard limit of either is hit (the event is
+// counted as dropped) and warn=true when only a soft limit has been crossed.
+// The event is reserved against both in one step, so concurrent events stop
+// exactly at a hard limit; when the counters cannot be reached it is accepted.
+func (s *QuotaService) RecordEvent(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	now := time.Now().UTC()
+
+	limits := s.projectQuotas(projectID)
+	quota, orgQuota := limits.project, limits.org
+
+	reserve := []redis.UsageLimit{{ID: projectID, Daily: quota.DailyHardLimit, Monthly: quota.MonthlyHardLimit}}
+	if orgQuota != nil {
+		reserve = append(reserve, redis.UsageLimit{ID: orgQuota.OrgID, Daily: orgQuota.DailyHardLimit, Monthly: orgQuota.MonthlyHardLimit})
+	}
+	blocked, counts, err := s.redis.ReserveUsage(ctx, now, reserve)
+	if err != nil {
+		log.Printf("Failed to reserve usage for project %s: %v", projectID, err)
+		s.countUsage(ctx, projectID, now, true)
+		return false, nil
+	}
+
+	switch blocked {
+	case 0:
+		log.Printf("QUOTA EXCEEDED: project %s - daily: %d, monthly: %d, dropping event", projectID, counts[0][0], counts[0][1])
+	case 1:
+		log.Printf("QUOTA EXCEEDED: organization %s - daily: %d, monthly: %d, dropping event for project %s", orgQuota.OrgID, counts[1][0], counts[1][1], projectID)
+	}
+	if blocked >= 0 {
+		s.countUsage(ctx, projectID, now, false)
+		return false, ErrQuotaExceeded
+	}
+	s.countUsage(ctx, projectID, now, true)
+
+	daily, monthly := counts[0][0], counts[0][1]
+	warn := exceeds(daily, quota.DailySoftLimit) || exceeds(monthly, quota.MonthlySoftLimit)
+	if warn {
+		log.Printf("QUOTA WARNING: project %s - daily: %d, monthly: %d, soft limit reached", projectID, daily, monthly)
+	}
+	if orgQuota != nil {
+		orgDaily, orgMonthly := counts[1][0], counts[1][1]
+		if exceeds(orgDaily, orgQuota.DailySoftLimit) || exceeds(orgMonthly, orgQuota.MonthlySoftLimit) {
+			log.Printf("QUOTA WARNING: organization %s - daily: %d, monthly: %d, soft limit reached", orgQuota.OrgID, orgDaily, orgMonthly)
+			warn = true
+		}
+	}
+
+	return warn, nil
+}
+
+// countUsage adds the event to the usage the flusher stores in the database
+func (s *QuotaService) countUsage(ctx context.Context, projectID uuid.UUID, now time.Time, accepted bool) {
+	if err := s.redis.CountUsage(ctx, projectID, now, accepted); err != nil {
+		log.Printf("Failed to count usage for project %s: %v", projectID, err)
+	}
+}
+
+// StartUsageFlusher moves the accepted and dropped event counts to the
+// database until ctx is done
+func (s *QuotaService) StartUsageFlusher(ctx context.Context) {
+	log.Println("Starting usage flusher...")
+
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushUsage(context.Background())
+			log.Println("Usage flusher stopped")
+			return
+		case <-ticker.C:
+			s.flushUsage(ctx)
+		}
+	}
+}
+
+func (s *QuotaService) flushUsage(ctx context.Context) {
+	counts, err := s.redis.TakePendingUsage(ctx)
+	if err != nil {
+		log.Printf("Failed to read pending usage: %v", err)
+		return
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	if err := s.db.AddUsage(counts); err != nil {
+		log.Printf("Failed to store usage: %v", err)
+		// Put it back for the next flush rather than lose it
+		if err := s.redis.RestorePendingUsage(context.Background(), counts); err != nil {
+			log.Printf("Failed to restore pending usage: %v", err)
+		}
+	}
+}
+
+// Remaining returns how many more events the project can send today and this
+// month before a hard limit of the project or its organization drops them. ok
+// is false when neither has a hard limit or usage could not be read.
+func (s *QuotaService) Remaining(ctx context.Context, projectID uuid.UUID) (remaining int, ok bool) {
+	now := time.Now().UTC()
+	remaining = math.MaxInt
+	limits := s.projectQuotas(projectID)
+
+	if quota := limits.project; quota.DailyHardLimit > 0 || quota.MonthlyHardLimit > 0 {
+		if daily, monthly, err := s.redis.GetUsageCounters(ctx, projectID, now); err == nil {
+			remaining = min(remaining, headroom(daily, quota.DailyHardLimit), headroom(monthly, quota.MonthlyHardLimit))
+			ok = true
+		}
+	}
+
+	if orgQuota := limits.org; orgQuota != nil && (orgQuota.DailyHardLimit > 0 || orgQuota.MonthlyHardLimit > 0) {
+		if daily, monthly, err := s.redis.GetUsageCounters(ctx, orgQuota.OrgID, now); err == nil {
+			remaining = min(remaining, headroom(daily, orgQuota.DailyHardLimit), headroom(monthly, orgQuota.MonthlyHardLimit))
+			ok = true
+		}
+	}
+
+	if !ok {
+		return 0, false
+	}
+	return max(remaining, 0), true
+}
+
+// projectQuotas returns the quotas of a project and its organization,
+// reloading them from the database once they are older than quotasRefresh.
+// A failed lookup keeps the limits last loaded, or counts as unlimited when
+// there are none, as ingestion is never blocked by it; it is retried after
+// quotasRetry rather than on every event.
+func (s *QuotaService) projectQuotas(projectID uuid.UUID) *projectQuotas {
+	s.mu.RLock()
+	cached := s.quotas[projectID]
+	s.mu.RUnlock()
+	if cached != nil && time.Now().Before(cached.expiresAt) {
+		return cached
+	}
+
+	limits := &projectQuotas{expiresAt: time.Now().Add(quotasRefresh)}
+	failed := false
+	quota, err := s.db.GetProjectQuota(projectID)
+	if err != nil {
+		log.Printf("Failed to load quota for project %s: %v", projectID, err)
+		failed = true
+	}
+	limits.project = quota
+	if limits.org, err = s.db.GetProjectOrgQuota(projectID); err != nil {
+		log.Printf("Failed to load organization quota for project %s: %v", projectID, err)
+		failed = true
+	}
+	if failed {
+		limits.expiresAt = time.Now().Add(quotasRetry)
+		if cached != nil {
+			limits.project, limits.org = cached.project, cached.org
+		} else if limits.project == nil {
+			limits.project = &models.ProjectQuota{ProjectID: projectID}
+		}
+	}
+
+	s.mu.Lock()
+	s.quotas[projectID] = limits
+	s.mu.Unlock()
+	return limits
+}
+
+// invalidateQuotas drops the cached quotas so changes made here apply at once
+func (s *QuotaService) invalidateQuotas() {
+	s.mu.Lock()
+	s.quotas = map[uuid.UUID]*projectQuotas{}
+	s.mu.Unlock()
+}
+
+// orgUsage returns the current usage of the organization with the quota, or
+// zeros when quota is nil because the project has no organization
+func (s *QuotaService) orgUsage(ctx context.Context, quota *models.OrgQuota, now time.Time) (daily, monthly int) {
+	if quota == nil {
+		return 0, 0
+	}
+
+	daily, monthly, err := s.redis.GetUsageCounters(ctx, quota.OrgID, now)
+	if err != nil {
+		log.Printf("Failed to read usage counters for organization %s: %v", quota.OrgID, err)
+	}
+	return daily, monthly
+}
+
+func (s *QuotaService) GetQuota(ctx context.Context, projectID uuid.UUID, callerOrg *uuid.UUID) (*models.ProjectQuota, error) {
+	if err := s.checkProjectOrg(projectID, callerOrg); err != nil {
+		return nil, err
+	}
+	return s.db.GetProjectQuota(projectID)
+}
+
+func (s *QuotaService) UpdateQuota(ctx context.Context, projectID uuid.UUID, req *models.UpdateQuotaRequest, callerOrg *uuid.UUID) (*models.ProjectQuota, error) {
+	if err := s.checkProjectOrg(projectID, callerOrg); err != nil {
+		return nil, err
+	}
+
+	quota := &models.ProjectQuota{
+		ProjectID:        projectID,
+		DailySoftLimit:   req.DailySoftLimit,
+		DailyHardLimit:   req.DailyHardLimit,
+		MonthlySoftLimit: req.MonthlySoftLimit,
+		MonthlyHardLimit: req.MonthlyHardLimit,
+		UpdatedAt:        time.Now().UTC(),
+	}
+
+	if err := s.db.UpsertProjectQuota(quota); err != nil {
+		return nil, err
+	}
+	s.invalidateQuotas()
+
+	return quota, nil
+}
+
+func (s *QuotaService) GetOrgQuota(ctx context.Context, orgID uuid.UUID) (*models.OrgQuota, error) {
+	return s.db.GetOrgQuota(orgID)
+}
+
+func (s *QuotaService) UpdateOrgQuota(ctx context.Context, orgID uuid.UUID, req *models.UpdateQuotaRequest) (*models.OrgQuota, error) {
+	quota := &models.OrgQuota{
+		OrgID:            orgID,
+		DailySoftLimit:   req.DailySoftLimit,
+		DailyHardLimit:   req.DailyHardLimit,
+		MonthlySoftLimit: req.MonthlySoftLimit,
+		MonthlyHardLimit: req.MonthlyHardLimit,
+		UpdatedAt:        time.Now().UTC(),
+	}
+
+	if err := s.db.UpsertOrgQuota(quota); err != nil {
+		return nil, err
+	}
+	s.invalidateQuotas()
+
+	return quota, nil
+}
+
+// checkProjectOrg reports projects outside the caller's organization as missing
+func (s *QuotaService) checkProjectOrg(projectID uuid.UUID, callerOrg *uuid.UUID) error {
+	if callerOrg == nil {
+		return nil
+	}
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+	if project.OrgID == nil || *project.OrgID != *callerOrg {
+		return fmt.Errorf("project not found")
+	}
+	return nil
+}
+
+func (s *QuotaService) GetUsage(ctx context.Context, projectID, callerOrg *uuid.UUID, groupBy string, days int) (*models.UsageResponse, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	usage, err := s.db.GetUsage(projectID, callerOrg, groupBy, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UsageResponse{
+		GroupBy: groupBy,
+		Usage:   usage,
+	}, nil
+}
+
+// exceeds reports whether count has reached a limit; a zero limit is unlimited
+func exceeds(count, limit int) bool {
+	return limit > 0 && count >= limit
+}
+
+// headroom is how far count is below limit; a zero limit is unlimited
+func headroom(count, limit int) int {
+	if limit <= 0 {
+		return math.MaxInt
+	}
+	return limit - count
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrReadOnly is returned for writes refused while read-only mode is on
+var ErrReadOnly = errors.New("the API is in read-only mode")
+
+// readOnlyRefresh is how long a replica trusts its copy of the shared flag
+const readOnlyRefresh = 5 * time.Second
+
+// ReadOnlyService holds the read-only mode flag used during maintenance
+// windows and database migrations. The flag lives in Redis so every replica
+// sees it; READ_ONLY_MODE forces it on regardless.
+type ReadOnlyService struct {
+	redis  *redis.Client
+	audit  *AuditService
+	forced bool
+
+	mu        sync.Mutex
+	status    models.ReadOnlyStatus
+	checkedAt time.Time
+}
+
+func NewReadOnlyService(redis *redis.Client, audit *AuditService, forced bool) *ReadOnlyService {
+	return &ReadOnlyService{
+		redis:  redis,
+		audit:  audit,
+		forced: forced,
+	}
+}
+
+// Status reports the current mode, re-reading the shared flag at most every
+// few seconds
+func (s *ReadOnlyService) Status(ctx context.Context) models.ReadOnlyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checkedAt) >= readOnlyRefresh {
+		status, err := s.redis.GetReadOnlyMode(ctx)
+		if err != nil {
+			// Keep the last known mode rather than flapping while Redis is down
+			log.Printf("Failed to get read-only mode: %v", err)
+		} else {
+			s.status = status
+		}
+		s.checkedAt = time.Now()
+	}
+
+	status := s.status
+	if s.forced {
+		status.Enabled = true
+		status.Forced = true
+	}
+	return status
+}
+
+func (s *ReadOnlyService) Enabled(ctx context.Context) bool {
+	return s.Status(ctx).Enabled
+}
+
+// SetMode turns read-only mode on or off for every replica
+func (s *ReadOnlyService) SetMode(ctx context.Context, enabled bool, reason, actor string) (models.ReadOnlyStatus, error) {
+	status := models.ReadOnlyStatus{Enabled: enabled}
+	if enabled {
+		now := time.Now().UTC()
+		status.Reason = reason
+		status.Since = &now
+	}
+	if err := s.redis.SetReadOnlyMode(ctx, status); err != nil {
+		return models.ReadOnlyStatus{}, err
+	}
+
+	s.mu.Lock()
+	s.status = status
+	s.checkedAt = time.Now()
+	s.mu.Unlock()
+
+	action := "system.read_only_disabled"
+	if enabled {
+		action = "system.read_only_enabled"
+	}
+	s.audit.Record(action, actor, "system", "read_only", map[string]interface{}{"reason": reason})
+
+	return s.Status(ctx), nil
+}
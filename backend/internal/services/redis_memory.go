@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// RedisMemoryService keeps the caches in Redis within a memory budget, so
+// under memory pressure Redis drops cached queries rather than the error
+// queue
+type RedisMemoryService struct {
+	redis       *redis.Client
+	budgetBytes int64
+	interval    time.Duration
+}
+
+func NewRedisMemoryService(redis *redis.Client, budgetBytes int64, interval time.Duration) *RedisMemoryService {
+	return &RedisMemoryService{
+		redis:       redis,
+		budgetBytes: budgetBytes,
+		interval:    interval,
+	}
+}
+
+// CheckEvictionPolicy warns when Redis may evict keys without an expiry,
+// which include the error queue
+func (s *RedisMemoryService) CheckEvictionPolicy(ctx context.Context) {
+	_, max, policy, err := s.redis.MemoryInfo(ctx)
+	if err != nil {
+		log.Printf("Failed to check the Redis eviction policy: %v", err)
+		return
+	}
+	if max > 0 && queueEvictable(policy) {
+		log.Printf("WARNING: Redis maxmemory-policy is %s, which may evict queued errors under memory pressure; use volatile-lru or volatile-ttl", policy)
+	}
+}
+
+// StartMemoryMonitor measures Redis memory every interval, drops cache
+// families while the caches are over budget and saves a report for the
+// monitoring endpoint, until ctx is done
+func (s *RedisMemoryService) StartMemoryMonitor(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	log.Println("Starting Redis memory monitor...")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Redis memory monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RedisMemoryService) check(ctx context.Context) {
+	used, max, policy, err := s.redis.MemoryInfo(ctx)
+	if err != nil {
+		log.Printf("Failed to check Redis memory: %v", err)
+		return
+	}
+	report := &models.RedisMemoryReport{
+		UsedBytes:        used,
+		MaxBytes:         max,
+		EvictionPolicy:   policy,
+		QueueEvictable:   max > 0 && queueEvictable(policy),
+		CacheBudgetBytes: s.budgetBytes,
+		Families:         []models.RedisKeyFamilyUsage{},
+		Dropped:          []string{},
+		CheckedAt:        time.Now().UTC(),
+	}
+
+	var caches []redis.KeyFamily
+	cacheBytes := map[string]int64{}
+	for _, family := range redis.KeyFamilies {
+		usage, err := s.redis.MeasureKeyFamily(ctx, family)
+		if err != nil {
+			log.Printf("Failed to check Redis memory: %v", err)
+			return
+		}
+		if usage.ExpiryFixed > 0 {
+			log.Printf("REDIS MEMORY: gave %d %s keys without an expiry a TTL of %v", usage.ExpiryFixed, family.Name, family.TTL)
+		}
+		report.Families = append(report.Families, *usage)
+		if family.Cache {
+			report.CacheBytes += usage.Bytes
+			caches = append(caches, family)
+			cacheBytes[family.Name] = usage.Bytes
+		}
+	}
+
+	// Drop the largest cache families first until the rest fit
+	if s.budgetBytes > 0 && report.CacheBytes > s.budgetBytes {
+		sort.SliceStable(caches, func(i, j int) bool {
+			return cacheBytes[caches[i].Name] > cacheBytes[caches[j].Name]
+		})
+		remaining := report.CacheBytes
+		for _, family := range caches {
+			if remaining <= s.budgetBytes {
+				break
+			}
+			if err := s.redis.DropKeyFamily(ctx, family); err != nil {
+				log.Printf("Failed to drop cache: %v", err)
+				continue
+			}
+			remaining -= cacheBytes[family.Name]
+			report.Dropped = append(report.Dropped, family.Name)
+		}
+		log.Printf("REDIS MEMORY: caches used %d bytes of a %d byte budget, dropped %s", report.CacheBytes, s.budgetBytes, strings.Join(report.Dropped, ", "))
+	}
+
+	if err := s.redis.SaveMemoryReport(ctx, report, 3*s.interval); err != nil {
+		log.Printf("Failed to save Redis memory report: %v", err)
+	}
+}
+
+// queueEvictable reports whether an eviction policy may evict keys without
+// an expiry
+func queueEvictable(policy string) bool {
+	return strings.HasPrefix(policy, "allkeys-")
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"html/template"
+
+	"error-logs/internal/models"
+)
+
+// reportChartHeight is the height in pixels of the daily errors chart
+const reportChartHeight = 120
+
+type reportView struct {
+	Report      *models.Report
+	Title       string
+	Change      string
+	Bars        []reportBar
+	ChartWidth  int
+	ChartHeight int
+}
+
+type reportBar struct {
+	X, Y, Width, Height int
+	Label               string
+}
+
+func newReportView(report *models.Report) reportView {
+	view := reportView{
+		Report:      report,
+		Title:       reportTitle(report),
+		Change:      "n/a",
+		ChartHeight: reportChartHeight,
+	}
+	if report.PreviousTotalErrors > 0 {
+		change := float64(report.TotalErrors-report.PreviousTotalErrors) / float64(report.PreviousTotalErrors) * 100
+		view.Change = fmt.Sprintf("%+.0f%%", change)
+	}
+
+	peak := 0
+	for _, day := range report.Daily {
+		peak = max(peak, day.ErrorCount)
+	}
+	const barWidth, gap = 16, 4
+	for i, day := range report.Daily {
+		height := 0
+		if peak > 0 {
+			height = day.ErrorCount * reportChartHeight / peak
+		}
+		view.Bars = append(view.Bars, reportBar{
+			X:      i * (barWidth + gap),
+			Y:      reportChartHeight - height,
+			Width:  barWidth,
+			Height: height,
+			Label:  fmt.Sprintf("%s: %d errors", day.Timestamp.Format("Mon Jan 2"), day.ErrorCount),
+		})
+	}
+	view.ChartWidth = len(report.Daily) * (barWidth + gap)
+
+	return view
+}
+
+func reportTitle(report *models.Report) string {
+	last := report.End.AddDate(0, 0, -1)
+	if report.Period == "monthly" {
+		return report.Start.Format("January 2006")
+	}
+	return fmt.Sprintf("week of %s to %s", report.Start.Format("Jan 2"), last.Format("Jan 2, 2006"))
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"truncate": truncate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Error report: {{.Title}}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; color: #1f2933; margin: 24px; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+h2 { font-size: 16px; margin-top: 28px; }
+.muted { color: #616e7c; font-size: 13px; }
+.cards { display: flex; flex-wrap: wrap; gap: 12px; }
+.card { border: 1px solid #e4e7eb; border-radius: 6px; padding: 12px 16px; min-width: 120px; }
+.card .value { font-size: 22px; font-weight: 600; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e4e7eb; }
+th { color: #616e7c; font-weight: 600; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Error report: {{.Title}}</h1>
+<div class="muted">{{.Report.Start.Format "Jan 2, 2006 15:04"}} to {{.Report.End.Format "Jan 2, 2006 15:04"}} ({{.Report.Timezone}}), generated {{.Report.GeneratedAt.Format "Jan 2, 2006 15:04 MST"}}</div>
+
+<h2>Summary</h2>
+<div class="cards">
+<div class="card"><div class="muted">Errors</div><div class="value">{{.Report.TotalErrors}}</div><div class="muted">{{.Change}} vs previous {{if eq .Report.Period "monthly"}}month{{else}}week{{end}}</div></div>
+<div class="card"><div class="muted">Critical</div><div class="value">{{.Report.CriticalErrors}}</div></div>
+<div class="card"><div class="muted">Resolved</div><div class="value">{{.Report.ResolvedErrors}}</div></div>
+<div class="card"><div class="muted">New error groups</div><div class="value">{{.Report.NewErrorGroups}}</div></div>
+<div class="card"><div class="muted">Incidents</div><div class="value">{{.Report.IncidentsOpened}}</div><div class="muted">{{.Report.IncidentsResolved}} resolved</div></div>
+</div>
+
+<h2>Errors per day</h2>
+<svg width="{{.ChartWidth}}" height="{{.ChartHeight}}" role="img" aria-label="Errors per day">
+{{- range .Bars}}
+<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="#e12d39"><title>{{.Label}}</title></rect>
+{{- end}}
+</svg>
+
+<h2>Top errors</h2>
+{{if .Report.TopErrors}}
+<table>
+<tr><th>Message</th><th>Source</th><th class="num">Events</th><th>First seen in period</th></tr>
+{{- range .Report.TopErrors}}
+<tr><td>{{truncate .Message 120}}</td><td>{{.Source}}</td><td class="num">{{.Count}}</td><td>{{.FirstSeen.Format "Jan 2 15:04"}}</td></tr>
+{{- end}}
+</table>
+{{else}}
+<p class="muted">No errors in this period.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"error-logs/internal/models"
+)
+
+// reportPDFChartHeight is the height in millimetres of the daily errors chart
+const reportPDFChartHeight = 40
+
+// RenderReportPDF renders a report as an A4 PDF with the same sections as
+// the HTML page. The built-in fonts cover Western European scripts only;
+// other characters are printed as dots.
+func RenderReportPDF(report *models.Report) ([]byte, error) {
+	view := newReportView(report)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetTitle("Error report: "+view.Title, true)
+	pdf.AddPage()
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentWidth := width - left - right
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, text("Error report: "+view.Title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(97, 110, 124)
+	pdf.CellFormat(0, 5, text(fmt.Sprintf("%s to %s (%s), generated %s",
+		report.Start.Format("Jan 2, 2006 15:04"), report.End.Format("Jan 2, 2006 15:04"),
+		report.Timezone, report.GeneratedAt.Format("Jan 2, 2006 15:04 MST"))), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(31, 41, 51)
+
+	reportPDFHeading(pdf, "Summary")
+	previous := "week"
+	if report.Period == "monthly" {
+		previous = "month"
+	}
+	summary := [][2]string{
+		{"Errors", fmt.Sprintf("%d (%s vs previous %s)", report.TotalErrors, view.Change, previous)},
+		{"Critical", strconv.Itoa(report.CriticalErrors)},
+		{"Resolved", strconv.Itoa(report.ResolvedErrors)},
+		{"New error groups", strconv.Itoa(report.NewErrorGroups)},
+		{"Incidents", fmt.Sprintf("%d (%d resolved)", report.IncidentsOpened, report.IncidentsResolved)},
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	for _, row := range summary {
+		pdf.CellFormat(45, 6, row[0], "B", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth-45, 6, text(row[1]), "B", 1, "L", false, 0, "")
+	}
+
+	reportPDFHeading(pdf, "Errors per day")
+	if len(view.Bars) > 0 {
+		// The HTML chart's pixel layout, scaled to fit the page width
+		scale := min(contentWidth/float64(view.ChartWidth), 1)
+		top := pdf.GetY()
+		pdf.SetFillColor(225, 45, 57)
+		for _, bar := range view.Bars {
+			height := float64(bar.Height) * reportPDFChartHeight / float64(view.ChartHeight)
+			if height > 0 {
+				pdf.Rect(left+float64(bar.X)*scale, top+reportPDFChartHeight-height, float64(bar.Width)*scale, height, "F")
+			}
+		}
+		pdf.SetDrawColor(228, 231, 235)
+		pdf.Line(left, top+reportPDFChartHeight, left+float64(view.ChartWidth)*scale, top+reportPDFChartHeight)
+		pdf.SetY(top + reportPDFChartHeight + 2)
+	}
+
+	reportPDFHeading(pdf, "Top errors")
+	if len(report.TopErrors) == 0 {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 6, "No errors in this period.", "", 1, "L", false, 0, "")
+	} else {
+		columns := []struct {
+			title string
+			width float64
+			align string
+		}{
+			{"Message", contentWidth - 95, "L"},
+			{"Source", 35, "L"},
+			{"Events", 20, "R"},
+			{"First seen in period", 40, "L"},
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		for _, column := range columns {
+			pdf.CellFormat(column.width, 6, column.title, "B", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, group := range report.TopErrors {
+			values := []string{
+				reportPDFFit(pdf, text(truncate(group.Message, 200)), columns[0].width-2),
+				reportPDFFit(pdf, text(group.Source), columns[1].width-2),
+				strconv.Itoa(group.Count),
+				group.FirstSeen.Format("Jan 2 15:04"),
+			}
+			for i, column := range columns {
+				pdf.CellFormat(column.width, 6, values[i], "B", 0, column.align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render report PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ReportPDFFilename names a report's PDF, e.g. "error-report-weekly-2025-09-01.pdf"
+func ReportPDFFilename(report *models.Report) string {
+	return fmt.Sprintf("error-report-%s-%s.pdf", report.Period, report.Start.Format("2006-01-02"))
+}
+
+func reportPDFHeading(pdf *gofpdf.Fpdf, heading string) {
+	pdf.Ln(5)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 7, heading, "", 1, "L", false, 0, "")
+}
+
+// reportPDFFit shortens s to fit a table cell of width millimetres
+func reportPDFFit(pdf *gofpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
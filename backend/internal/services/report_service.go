@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidReport is returned for an unknown report period or one that has not started
+var ErrInvalidReport = errors.New("invalid report request")
+
+// reportSchedulerInterval is how often the scheduler checks for ended periods
+const reportSchedulerInterval = time.Hour
+
+// reportTopErrors caps the top error groups listed in a report
+const reportTopErrors = 10
+
+// reportDeliveryTTL keeps a delivery claim past the end of the longest period
+const reportDeliveryTTL = 40 * 24 * time.Hour
+
+// ReportService renders weekly and monthly summary reports and emails them to
+// stakeholders once each period has ended
+type ReportService struct {
+	db       *database.DB
+	redis    *redis.Client
+	notifier *Notifier
+
+	recipients []string
+	periods    []string
+	timezone   *time.Location
+}
+
+func NewReportService(db *database.DB, redis *redis.Client, notifier *Notifier, recipients, periods []string, timezone string) *ReportService {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		log.Printf("Invalid report timezone %q, scheduling reports in UTC", timezone)
+		loc = time.UTC
+	}
+	return &ReportService{
+		db:         db,
+		redis:      redis,
+		notifier:   notifier,
+		recipients: recipients,
+		periods:    periods,
+		timezone:   loc,
+	}
+}
+
+// ValidReportPeriod reports whether reports can be generated for the period
+func ValidReportPeriod(period string) bool {
+	return period == "weekly" || period == "monthly"
+}
+
+// reportPeriodStart returns the start, in loc, of the week (Monday) or month containing t
+func reportPeriodStart(period string, t time.Time, loc *time.Location) time.Time {
+	if period == "monthly" {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return insightsWeekStart(t, loc)
+}
+
+// reportPeriodShift moves a period start by n periods
+func reportPeriodShift(period string, start time.Time, n int) time.Time {
+	if period == "monthly" {
+		return start.AddDate(0, n, 0)
+	}
+	return start.AddDate(0, 0, 7*n)
+}
+
+// GetReport builds the report for the period containing date, or for the last
+// full period when date is nil. Periods start at midnight in timezone, an IANA
+// name or "" for UTC. The current period is reported up to now.
+func (s *ReportService) GetReport(ctx context.Context, period string, date *time.Time, timezone string) (*models.Report, error) {
+	if !ValidReportPeriod(period) {
+		return nil, fmt.Errorf("%w: period must be weekly or monthly", ErrInvalidReport)
+	}
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	current := reportPeriodStart(period, now, loc)
+
+	start := reportPeriodShift(period, current, -1)
+	if date != nil {
+		// The date is a calendar day, so it names the same day in any timezone
+		start = reportPeriodStart(period, time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc), loc)
+		if start.After(current) {
+			return nil, fmt.Errorf("%w: period has not started", ErrInvalidReport)
+		}
+	}
+
+	return s.buildReport(period, start, now)
+}
+
+func (s *ReportService) buildReport(period string, start, now time.Time) (*models.Report, error) {
+	end := reportPeriodShift(period, start, 1)
+	report, err := s.db.ComputeReport(reportPeriodShift(period, start, -1), start, end, reportTopErrors)
+	if err != nil {
+		return nil, err
+	}
+	report.Period = period
+	report.GeneratedAt = now
+	return report, nil
+}
+
+// RenderReportHTML renders a report as a standalone HTML page, suitable for
+// email
+func RenderReportHTML(report *models.Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, newReportView(report)); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// StartReportScheduler emails each configured period's report to the
+// recipients once the period has ended, until ctx is done
+func (s *ReportService) StartReportScheduler(ctx context.Context) {
+	if len(s.recipients) == 0 || len(s.periods) == 0 {
+		log.Println("Report scheduler disabled: no REPORT_RECIPIENTS configured")
+		return
+	}
+	log.Println("Starting report scheduler...")
+
+	ticker := time.NewTicker(reportSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendScheduledReports(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Report scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ReportService) sendScheduledReports(ctx context.Context) {
+	now := time.Now().UTC()
+
+	for _, period := range s.periods {
+		if !ValidReportPeriod(period) {
+			log.Printf("Skipping unknown report period %q", period)
+			continue
+		}
+		start := reportPeriodShift(period, reportPeriodStart(period, now, s.timezone), -1)
+
+		fresh, err := s.redis.ClaimReportDelivery(ctx, period, start, reportDeliveryTTL)
+		if err != nil {
+			log.Printf("Failed to claim %s report delivery: %v", period, err)
+			continue
+		}
+		if !fresh {
+			continue
+		}
+
+		report, err := s.buildReport(period, start, now)
+		if err != nil {
+			log.Printf("Failed to build %s report: %v", period, err)
+			continue
+		}
+		html, err := RenderReportHTML(report)
+		if err != nil {
+			log.Printf("Failed to render %s report: %v", period, err)
+			continue
+		}
+
+		pdf, err := RenderReportPDF(report)
+		if err != nil {
+			log.Printf("Failed to render %s report PDF: %v", period, err)
+			continue
+		}
+
+		subject := fmt.Sprintf("Error report: %s", reportTitle(report))
+		attachment := EmailAttachment{Filename: ReportPDFFilename(report), ContentType: "application/pdf", Data: pdf}
+		if err := s.notifier.SendHTMLEmailWithAttachment(s.recipients, subject, string(html), attachment); err != nil {
+			log.Printf("Failed to send %s report: %v", period, err)
+			continue
+		}
+		log.Printf("REPORT SENT: %s report for %s", period, start.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidRunbook is returned when a runbook URL or notes fail validation
+var ErrInvalidRunbook = errors.New("invalid runbook")
+
+// maxRunbookNotesLength bounds the notes copied into every notification
+const maxRunbookNotesLength = 4000
+
+func isHTTPURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// normalizeRunbook validates a runbook and returns nil when it is empty
+func normalizeRunbook(runbook *models.Runbook) (*models.Runbook, error) {
+	if runbook == nil {
+		return nil, nil
+	}
+	runbook.Notes = strings.TrimSpace(runbook.Notes)
+	if runbook.URL != nil && *runbook.URL == "" {
+		runbook.URL = nil
+	}
+	if runbook.URL != nil && !isHTTPURL(*runbook.URL) {
+		return nil, fmt.Errorf("runbook url must be an http(s) URL")
+	}
+	if len(runbook.Notes) > maxRunbookNotesLength {
+		return nil, fmt.Errorf("runbook notes must be at most %d characters", maxRunbookNotesLength)
+	}
+	if runbook.URL == nil && runbook.Notes == "" {
+		return nil, nil
+	}
+	return runbook, nil
+}
+
+// resolveRunbook picks the runbook for a notification: the rule's own, then
+// the error group's, then the runbook_url of the service in the catalog
+func resolveRunbook(db RunbookStore, own *models.Runbook, fingerprint, source string) *models.Runbook {
+	if own != nil {
+		return own
+	}
+	if fingerprint != "" {
+		runbook, err := db.GetErrorGroupRunbook(fingerprint)
+		if err != nil {
+			log.Printf("Failed to load runbook for error group %s: %v", fingerprint, err)
+		} else if runbook != nil {
+			return runbook
+		}
+	}
+	if source != "" {
+		service, err := db.GetServiceByName(source)
+		if err == nil && service.RunbookURL != nil {
+			return &models.Runbook{URL: service.RunbookURL}
+		}
+	}
+	return nil
+}
+
+// formatRunbook renders a runbook for notification emails
+func formatRunbook(runbook *models.Runbook) string {
+	if runbook == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nRunbook:")
+	if runbook.URL != nil {
+		b.WriteString(" " + *runbook.URL)
+	}
+	b.WriteString("\n")
+	if runbook.Notes != "" {
+		b.WriteString(runbook.Notes + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+// ErrInvalidSDKConfig is returned for SDK settings clients could not apply
+var ErrInvalidSDKConfig = errors.New("invalid SDK config")
+
+// sdkConfigsRefresh bounds how stale the in-memory configs may get, and so
+// how long a change takes to reach SDKs served by other replicas
+const sdkConfigsRefresh = time.Minute
+
+// Defaults served to projects without a config
+const (
+	defaultSDKFlushIntervalSeconds = 5
+	defaultScrubReplacement        = "[Filtered]"
+	maxSDKFlushIntervalSeconds     = 3600
+	maxScrubRules                  = 50
+)
+
+// SDKConfigService manages the remote configuration SDKs fetch on startup.
+// Configs are served from memory, since every deployed client asks for one.
+type SDKConfigService struct {
+	db *database.DB
+
+	mu       sync.RWMutex
+	configs  map[uuid.UUID]*models.SDKConfig
+	loadedAt time.Time
+}
+
+func NewSDKConfigService(db *database.DB) *SDKConfigService {
+	return &SDKConfigService{
+		db: db,
+	}
+}
+
+// ConfigFor returns the config for an API key's project, or the defaults when
+// the project has none or the key belongs to no project
+func (s *SDKConfigService) ConfigFor(ctx context.Context, projectID *uuid.UUID) *models.SDKConfig {
+	if projectID != nil {
+		if config := s.sdkConfigs()[*projectID]; config != nil {
+			return config
+		}
+	}
+	return defaultSDKConfig(projectID)
+}
+
+func (s *SDKConfigService) GetConfig(ctx context.Context, projectID uuid.UUID) (*models.SDKConfig, error) {
+	config, err := s.db.GetSDKConfig(projectID)
+	if err != nil && err.Error() == "SDK config not found" {
+		return defaultSDKConfig(&projectID), nil
+	}
+	return config, err
+}
+
+// UpdateConfig changes the fields set in the request, keeping the others
+func (s *SDKConfigService) UpdateConfig(ctx context.Context, projectID uuid.UUID, req *models.UpdateSDKConfigRequest) (*models.SDKConfig, error) {
+	config, err := s.GetConfig(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SampleRate != nil {
+		config.SampleRate = *req.SampleRate
+	}
+	if req.LevelSampleRates != nil {
+		config.LevelSampleRates = req.LevelSampleRates
+	}
+	if req.AllowedLevels != nil {
+		config.AllowedLevels = req.AllowedLevels
+	}
+	if req.ScrubRules != nil {
+		config.ScrubRules = req.ScrubRules
+	}
+	if req.FlushIntervalSeconds != nil {
+		config.FlushIntervalSeconds = *req.FlushIntervalSeconds
+	}
+	if err := validateSDKConfig(config); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	config.UpdatedAt = &now
+	if err := s.db.UpsertSDKConfig(config); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	return config, nil
+}
+
+// DeleteConfig returns the project to the defaults
+func (s *SDKConfigService) DeleteConfig(ctx context.Context, projectID uuid.UUID) error {
+	if err := s.db.DeleteSDKConfig(projectID); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func defaultSDKConfig(projectID *uuid.UUID) *models.SDKConfig {
+	return &models.SDKConfig{
+		ProjectID:            projectID,
+		SampleRate:           1,
+		LevelSampleRates:     map[string]float64{},
+		AllowedLevels:        slices.Clone(EventLevels),
+		ScrubRules:           []models.ScrubRule{},
+		FlushIntervalSeconds: defaultSDKFlushIntervalSeconds,
+	}
+}
+
+func validateSDKConfig(config *models.SDKConfig) error {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return fmt.Errorf("%w: sample_rate must be between 0 and 1", ErrInvalidSDKConfig)
+	}
+	for level, rate := range config.LevelSampleRates {
+		if !slices.Contains(EventLevels, level) {
+			return fmt.Errorf("%w: unknown level %q in level_sample_rates", ErrInvalidSDKConfig, level)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%w: sample rate for %s must be between 0 and 1", ErrInvalidSDKConfig, level)
+		}
+	}
+	for _, level := range config.AllowedLevels {
+		if !slices.Contains(EventLevels, level) {
+			return fmt.Errorf("%w: unknown level %q in allowed_levels", ErrInvalidSDKConfig, level)
+		}
+	}
+	if config.FlushIntervalSeconds < 1 || config.FlushIntervalSeconds > maxSDKFlushIntervalSeconds {
+		return fmt.Errorf("%w: flush_interval_seconds must be between 1 and %d", ErrInvalidSDKConfig, maxSDKFlushIntervalSeconds)
+	}
+
+	if len(config.ScrubRules) > maxScrubRules {
+		return fmt.Errorf("%w: at most %d scrub rules", ErrInvalidSDKConfig, maxScrubRules)
+	}
+	for i := range config.ScrubRules {
+		rule := &config.ScrubRules[i]
+		if rule.Field == "" && rule.Pattern == "" {
+			return fmt.Errorf("%w: scrub rule %d needs a field or a pattern", ErrInvalidSDKConfig, i)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("%w: scrub rule %d has an invalid pattern: %v", ErrInvalidSDKConfig, i, err)
+			}
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = defaultScrubReplacement
+		}
+	}
+	return nil
+}
+
+func (s *SDKConfigService) sdkConfigs() map[uuid.UUID]*models.SDKConfig {
+	s.mu.RLock()
+	if s.configs != nil && time.Since(s.loadedAt) < sdkConfigsRefresh {
+		defer s.mu.RUnlock()
+		return s.configs
+	}
+	s.mu.RUnlock()
+
+	list, err := s.db.GetSDKConfigs()
+	if err != nil {
+		log.Printf("Failed to load SDK configs: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.configs
+	}
+
+	configs := make(map[uuid.UUID]*models.SDKConfig, len(list))
+	for i := range list {
+		configs[*list[i].ProjectID] = &list[i]
+	}
+
+	s.mu.Lock()
+	s.configs = configs
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return configs
+}
+
+func (s *SDKConfigService) invalidate() {
+	s.mu.Lock()
+	s.configs = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/opensearch"
+)
+
+const (
+	// searchIndexBuffer bounds the changes waiting to be sent to OpenSearch;
+	// changes beyond it are dropped until a rebuild
+	searchIndexBuffer = 10000
+	// searchIndexBatchSize caps the changes sent in one bulk request
+	searchIndexBatchSize = 500
+	// searchIndexFlushInterval is how long a change may wait for a batch
+	searchIndexFlushInterval = time.Second
+	// searchMaxWindow is the deepest page OpenSearch serves by default
+	searchMaxWindow = 10000
+	// searchRebuildBatchSize is how many errors a rebuild reads per query
+	searchRebuildBatchSize = 1000
+)
+
+// errSearchWindow is returned for pages past searchMaxWindow, which are read
+// from the database instead
+var errSearchWindow = errors.New("page is beyond the OpenSearch result window")
+
+// searchIndexDefinition maps the mirrored fields. Indexed context fields are
+// exact-match keywords, like their GIN index in Postgres.
+var searchIndexDefinition = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"dynamic_templates": []interface{}{
+			map[string]interface{}{
+				"context_keywords": map[string]interface{}{
+					"path_match": "context.*",
+					"mapping":    map[string]interface{}{"type": "keyword"},
+				},
+			},
+		},
+		"properties": map[string]interface{}{
+			"timestamp":   map[string]interface{}{"type": "date"},
+			"level":       map[string]interface{}{"type": "keyword"},
+			"source":      map[string]interface{}{"type": "keyword"},
+			"environment": map[string]interface{}{"type": "keyword"},
+			"region":      map[string]interface{}{"type": "keyword"},
+			"deployment":  map[string]interface{}{"type": "keyword"},
+			"release":     map[string]interface{}{"type": "keyword"},
+			"fingerprint": map[string]interface{}{"type": "keyword"},
+			"project_id":  map[string]interface{}{"type": "keyword"},
+			"resolved":    map[string]interface{}{"type": "boolean"},
+			"state":       map[string]interface{}{"type": "keyword"},
+			"message":     map[string]interface{}{"type": "text"},
+			"stack_trace": map[string]interface{}{"type": "text"},
+			"url":         map[string]interface{}{"type": "text"},
+		},
+	},
+}
+
+// searchDocument is the part of an event mirrored into OpenSearch
+type searchDocument struct {
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Source      string            `json:"source"`
+	Environment string            `json:"environment"`
+	Region      *string           `json:"region,omitempty"`
+	Deployment  *string           `json:"deployment,omitempty"`
+	Release     *string           `json:"release,omitempty"`
+	Fingerprint *string           `json:"fingerprint,omitempty"`
+	ProjectID   *uuid.UUID        `json:"project_id,omitempty"`
+	Resolved    bool              `json:"resolved"`
+	State       string            `json:"state"`
+	Message     string            `json:"message"`
+	StackTrace  *string           `json:"stack_trace,omitempty"`
+	URL         *string           `json:"url,omitempty"`
+	Context     map[string]string `json:"context,omitempty"`
+}
+
+func newSearchDocument(event *models.Error) *searchDocument {
+	return &searchDocument{
+		Timestamp:   event.Timestamp,
+		Level:       event.Level,
+		Source:      event.Source,
+		Environment: event.Environment,
+		Region:      event.Region,
+		Deployment:  event.Deployment,
+		Release:     event.Release,
+		Fingerprint: event.Fingerprint,
+		ProjectID:   event.ProjectID,
+		Resolved:    event.Resolved,
+		State:       event.State,
+		Message:     event.Message,
+		StackTrace:  event.StackTrace,
+		URL:         event.URL,
+		Context:     event.IndexedContext,
+	}
+}
+
+// SearchIndexService mirrors stored error events into OpenSearch and answers
+// text searches from it. Without OPENSEARCH_URL every method is a no-op and
+// searches are served by Postgres. The mirror is best effort: changes are
+// sent in the background, and `reindex-search` rebuilds it from Postgres.
+type SearchIndexService struct {
+	db      *database.DB
+	client  *opensearch.Client // nil when OpenSearch is not configured
+	changes chan opensearch.BulkAction
+}
+
+func NewSearchIndexService(db *database.DB, client *opensearch.Client) *SearchIndexService {
+	return &SearchIndexService{
+		db:      db,
+		client:  client,
+		changes: make(chan opensearch.BulkAction, searchIndexBuffer),
+	}
+}
+
+// Handles reports whether an error list or facet query is answered by
+// OpenSearch. Only text searches are; watched groups, impact ordering, query
+// terms, message filters and organization scopes need Postgres.
+func (s *SearchIndexService) Handles(filter models.ErrorFilter) bool {
+	return s.client != nil && filter.Search != "" && filter.WatchedBy == nil && filter.Sort == "" && len(filter.Query) == 0 &&
+		filter.Message == "" && filter.OrgID == nil
+}
+
+// Index mirrors a stored event
+func (s *SearchIndexService) Index(event *models.Error) {
+	s.enqueue(opensearch.BulkAction{Op: "index", ID: event.ID.String(), Doc: newSearchDocument(event)})
+}
+
+// Remove drops an event from the mirror, e.g. when it is trashed
+func (s *SearchIndexService) Remove(id uuid.UUID) {
+	s.enqueue(opensearch.BulkAction{Op: "delete", ID: id.String()})
+}
+
+// Refresh mirrors an event's current state after it changed, or drops it if
+// it is no longer listed
+func (s *SearchIndexService) Refresh(id uuid.UUID) {
+	if s.client == nil {
+		return
+	}
+	go func() {
+		event, err := s.db.GetErrorByID(id)
+		if err != nil {
+			if err.Error() == "error not found" {
+				s.Remove(id)
+			} else {
+				log.Printf("Failed to read error %s for the search index: %v", id, err)
+			}
+			return
+		}
+		s.Index(event)
+	}()
+}
+
+// ReopenGroup marks every mirrored event of a regressed group unresolved
+func (s *SearchIndexService) ReopenGroup(fingerprint string) {
+	if s.client == nil {
+		return
+	}
+	query := map[string]interface{}{"term": map[string]interface{}{"fingerprint": fingerprint}}
+	if err := s.client.UpdateByQuery(context.Background(), query, "ctx._source.resolved = false; ctx._source.state = 'regressed'", nil); err != nil {
+		log.Printf("Failed to reopen group %s in the search index: %v", fingerprint, err)
+	}
+}
+
+// ResolveGroup marks every mirrored event of an auto-resolved group resolved
+func (s *SearchIndexService) ResolveGroup(fingerprint string) {
+	if s.client == nil {
+		return
+	}
+	query := map[string]interface{}{"term": map[string]interface{}{"fingerprint": fingerprint}}
+	if err := s.client.UpdateByQuery(context.Background(), query, "ctx._source.resolved = true; ctx._source.state = 'resolved'", nil); err != nil {
+		log.Printf("Failed to resolve group %s in the search index: %v", fingerprint, err)
+	}
+}
+
+func (s *SearchIndexService) enqueue(action opensearch.BulkAction) {
+	if s.client == nil {
+		return
+	}
+	select {
+	case s.changes <- action:
+	default:
+		log.Printf("Search index buffer full, dropped %s of error %s; run reindex-search to repair", action.Op, action.ID)
+	}
+}
+
+// searchQuery matches the filter: the text against the message (boosted),
+// stack trace and URL, and every other field exactly
+func searchQuery(filter models.ErrorFilter) map[string]interface{} {
+	match := map[string]interface{}{
+		"query":  filter.Search,
+		"fields": []string{"message^3", "stack_trace", "url"},
+	}
+	if filter.Fuzzy {
+		match["fuzziness"] = "AUTO"
+	}
+
+	filters := []interface{}{}
+	term := func(field, value string) {
+		if value != "" {
+			filters = append(filters, map[string]interface{}{"term": map[string]interface{}{field: value}})
+		}
+	}
+	term("level", filter.Level)
+	term("source", filter.Source)
+	term("environment", filter.Environment)
+	term("region", filter.Region)
+	term("deployment", filter.Deployment)
+	term("state", filter.State)
+	switch filter.Status {
+	case "resolved":
+		term("resolved", "true")
+	case "unresolved":
+		term("resolved", "false")
+	}
+	for key, value := range filter.Context {
+		term("context."+key, value)
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   []interface{}{map[string]interface{}{"multi_match": match}},
+			"filter": filters,
+		},
+	}
+}
+
+// Search returns the IDs of a page of matching events, most relevant first,
+// and the total number of matches
+func (s *SearchIndexService) Search(ctx context.Context, filter models.ErrorFilter, limit, offset int) ([]uuid.UUID, int, error) {
+	if offset+limit > searchMaxWindow {
+		return nil, 0, errSearchWindow
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err := s.client.Search(ctx, map[string]interface{}{
+		"query":            searchQuery(filter),
+		"from":             offset,
+		"size":             limit,
+		"track_total_hits": true,
+		"_source":          false,
+		"sort":             []interface{}{"_score", map[string]interface{}{"timestamp": "desc"}},
+	}, &result)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		if id, err := uuid.Parse(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, result.Hits.Total.Value, nil
+}
+
+// Facets counts the matching events per level, source, environment, region
+// and status
+func (s *SearchIndexService) Facets(ctx context.Context, filter models.ErrorFilter) (*models.ErrorFacetsResponse, error) {
+	type bucket struct {
+		Key         interface{} `json:"key"`
+		KeyAsString string      `json:"key_as_string"`
+		DocCount    int         `json:"doc_count"`
+	}
+	var result struct {
+		Aggregations map[string]struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"aggregations"`
+	}
+
+	aggs := map[string]interface{}{}
+	for _, field := range []string{"level", "source", "environment", "region", "resolved"} {
+		aggs[field] = map[string]interface{}{"terms": map[string]interface{}{"field": field, "size": 100}}
+	}
+	err := s.client.Search(ctx, map[string]interface{}{
+		"query": searchQuery(filter),
+		"size":  0,
+		"aggs":  aggs,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := func(field string) []models.FacetCount {
+		facets := []models.FacetCount{}
+		for _, b := range result.Aggregations[field].Buckets {
+			value := b.KeyAsString
+			if value == "" {
+				value = fmt.Sprint(b.Key)
+			}
+			if field == "resolved" {
+				value = map[bool]string{true: "resolved", false: "unresolved"}[value == "true"]
+			}
+			facets = append(facets, models.FacetCount{Value: value, Count: b.DocCount})
+		}
+		return facets
+	}
+	return &models.ErrorFacetsResponse{
+		Level:       counts("level"),
+		Source:      counts("source"),
+		Environment: counts("environment"),
+		Region:      counts("region"),
+		Status:      counts("resolved"),
+	}, nil
+}
+
+// StartIndexer sends mirrored changes to OpenSearch in batches until ctx is
+// done. Every instance runs it for the events it stores.
+func (s *SearchIndexService) StartIndexer(ctx context.Context) {
+	if s.client == nil {
+		return
+	}
+	log.Println("Starting search indexer...")
+
+	ready := false
+	ticker := time.NewTicker(searchIndexFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]opensearch.BulkAction, 0, searchIndexBatchSize)
+	flush := func() {
+		if !ready {
+			if err := s.client.EnsureIndex(ctx, searchIndexDefinition); err != nil {
+				log.Printf("Failed to create search index, keeping %d changes: %v", len(batch), err)
+				return
+			}
+			ready = true
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.client.Bulk(ctx, batch); err != nil {
+			log.Printf("Failed to index %d changes, dropping them; run reindex-search to repair: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Search indexer stopped")
+			return
+		case action := <-s.changes:
+			if len(batch) >= searchIndexBatchSize {
+				// Still waiting for the index to be created; keep the newest
+				batch = batch[1:]
+			}
+			batch = append(batch, action)
+			if len(batch) >= searchIndexBatchSize && ready {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Rebuild indexes every listed error from Postgres, oldest first, and
+// returns how many were indexed. Documents of errors deleted since are left
+// in place until they are next changed; they are never listed, since search
+// results are read back from Postgres.
+func (s *SearchIndexService) Rebuild(ctx context.Context) (int, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("OPENSEARCH_URL is not configured")
+	}
+	if err := s.client.EnsureIndex(ctx, searchIndexDefinition); err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	var afterTimestamp time.Time
+	var afterID *uuid.UUID
+	for {
+		events, err := s.db.GetErrorsForExport(models.ErrorFilter{}, nil, nil, afterTimestamp, afterID, searchRebuildBatchSize)
+		if err != nil {
+			return indexed, err
+		}
+		if len(events) == 0 {
+			return indexed, nil
+		}
+
+		actions := make([]opensearch.BulkAction, len(events))
+		for i := range events {
+			actions[i] = opensearch.BulkAction{Op: "index", ID: events[i].ID.String(), Doc: newSearchDocument(&events[i])}
+		}
+		if err := s.client.Bulk(ctx, actions); err != nil {
+			return indexed, err
+		}
+		indexed += len(events)
+
+		last := events[len(events)-1]
+		afterTimestamp, afterID = last.Timestamp, &last.ID
+		if indexed%(10*searchRebuildBatchSize) == 0 {
+			log.Printf("Indexed %s errors...", strconv.Itoa(indexed))
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidQuery is returned for an error query that can't be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	// maxQueryLength and maxQueryTerms keep generated SQL small
+	maxQueryLength = 1000
+	maxQueryTerms  = 20
+)
+
+// searchQueryFields are the fields a query term may name, besides
+// context.<key>. message matches text like a bare word; the others match
+// exactly.
+var searchQueryFields = []string{
+	"level", "source", "environment", "release", "region", "deployment",
+	"url", "fingerprint", "trace_id", "message",
+}
+
+// ParseSearchQuery parses an error query such as
+//
+//	level:error source:api "timeout" context.customer:acme -resolved
+//
+// Terms are separated by spaces and must all match. A term is field:value,
+// a word or "quoted phrase" to find in the message, or resolved. Values with
+// spaces are quoted, and a leading - negates a term.
+func ParseSearchQuery(query string) ([]models.SearchTerm, error) {
+	if len(query) > maxQueryLength {
+		return nil, fmt.Errorf("%w: query must be at most %d characters", ErrInvalidQuery, maxQueryLength)
+	}
+
+	tokens, err := splitSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > maxQueryTerms {
+		return nil, fmt.Errorf("%w: query may have at most %d terms", ErrInvalidQuery, maxQueryTerms)
+	}
+
+	terms := make([]models.SearchTerm, 0, len(tokens))
+	for _, token := range tokens {
+		term, err := parseSearchTerm(token)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// splitSearchQuery splits a query on spaces outside quotes
+func splitSearchQuery(query string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			token.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuery)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func parseSearchTerm(token string) (models.SearchTerm, error) {
+	var term models.SearchTerm
+	if rest, ok := strings.CutPrefix(token, "-"); ok && rest != "" {
+		term.Negate = true
+		token = rest
+	}
+
+	if strings.HasPrefix(token, `"`) {
+		term.Value = unquoteSearchValue(token)
+		if term.Value == "" || strings.Contains(token[1:len(token)-1], `"`) {
+			return term, fmt.Errorf("%w: %s is not a quoted phrase", ErrInvalidQuery, token)
+		}
+		return term, nil
+	}
+
+	field, value, ok := strings.Cut(token, ":")
+	if !ok {
+		if token == "resolved" {
+			term.Field = "resolved"
+		} else {
+			term.Value = token
+		}
+		return term, nil
+	}
+
+	key, isContext := strings.CutPrefix(field, "context.")
+	if (isContext && key == "") || (!isContext && !slices.Contains(searchQueryFields, field)) {
+		return term, fmt.Errorf("%w: unknown field %q; quote text that contains a colon", ErrInvalidQuery, field)
+	}
+	term.Field = field
+	term.Value = unquoteSearchValue(value)
+	if term.Value == "" {
+		return term, fmt.Errorf("%w: %s needs a value", ErrInvalidQuery, field)
+	}
+	return term, nil
+}
+
+func unquoteSearchValue(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// searchQueryContext returns the context keys a query filters on, to check
+// that they are indexed
+func searchQueryContext(terms []models.SearchTerm) map[string]string {
+	var keys map[string]string
+	for _, term := range terms {
+		if key, ok := strings.CutPrefix(term.Field, "context."); ok {
+			if keys == nil {
+				keys = make(map[string]string)
+			}
+			keys[key] = term.Value
+		}
+	}
+	return keys
+}
+
+// searchTermMatches reports whether an event matches one query term, for
+// filtering events outside the database
+func searchTermMatches(e *models.Error, term models.SearchTerm) bool {
+	var matched bool
+	switch term.Field {
+	case "", "message":
+		matched = strings.Contains(strings.ToLower(e.Message), strings.ToLower(term.Value))
+	case "resolved":
+		matched = e.Resolved
+	case "level":
+		matched = e.Level == term.Value
+	case "source":
+		matched = e.Source == term.Value
+	case "environment":
+		matched = e.Environment == term.Value
+	case "release":
+		matched = stringValue(e.Release) == term.Value
+	case "region":
+		matched = stringValue(e.Region) == term.Value
+	case "deployment":
+		matched = stringValue(e.Deployment) == term.Value
+	case "url":
+		matched = stringValue(e.URL) == term.Value
+	case "fingerprint":
+		matched = stringValue(e.Fingerprint) == term.Value
+	case "trace_id":
+		matched = stringValue(e.TraceID) == term.Value
+	default:
+		key := strings.TrimPrefix(term.Field, "context.")
+		v, ok := e.Context[key]
+		matched = ok && fmt.Sprint(v) == term.Value
+	}
+	return matched != term.Negate
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"error-logs/internal/models"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []models.SearchTerm
+	}{
+		{"", []models.SearchTerm{}},
+		{"timeout", []models.SearchTerm{{Value: "timeout"}}},
+		{`"connection reset"`, []models.SearchTerm{{Value: "connection reset"}}},
+		{"level:error source:api", []models.SearchTerm{{Field: "level", Value: "error"}, {Field: "source", Value: "api"}}},
+		{`context.customer:"Acme Corp" -resolved`, []models.SearchTerm{
+			{Field: "context.customer", Value: "Acme Corp"},
+			{Field: "resolved", Negate: true},
+		}},
+		{`-message:"not found"  -"bad gateway"`, []models.SearchTerm{
+			{Field: "message", Value: "not found", Negate: true},
+			{Value: "bad gateway", Negate: true},
+		}},
+		{"-", []models.SearchTerm{{Value: "-"}}},
+		{`"http://example.com"`, []models.SearchTerm{{Value: "http://example.com"}}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSearchQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchQueryRejectsInvalidQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unterminated quote", `"timeout`},
+		{"unknown field", "http://example.com"},
+		{"empty context key", "context.:acme"},
+		{"missing value", "level:"},
+		{"empty phrase", `""`},
+		{"quote inside a phrase", `"a"b"c"`},
+		{"too long", strings.Repeat("a", maxQueryLength+1)},
+		{"too many terms", strings.Repeat("a ", maxQueryTerms+1)},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseSearchQuery(tt.query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: err = %v, want ErrInvalidQuery", tt.name, err)
+		}
+	}
+}
+
+func TestSearchTermMatches(t *testing.T) {
+	release := "1.4.0"
+	e := &models.Error{
+		Message: "Connection Timeout to db",
+		Level:   "error",
+		Source:  "api",
+		Release: &release,
+		Context: map[string]interface{}{"customer": "acme", "attempt": 3},
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"timeout", true},
+		{"message:refused", false},
+		{"-refused", true},
+		{"level:error source:api", true},
+		{"level:warning", false},
+		{"release:1.4.0", true},
+		{"region:eu", false},
+		{"context.customer:acme", true},
+		{"context.attempt:3", true},
+		{"context.missing:x", false},
+		{"resolved", false},
+		{"-resolved", true},
+	}
+
+	for _, tt := range tests {
+		terms, err := ParseSearchQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseSearchQuery(%q): %v", tt.query, err)
+		}
+		got := true
+		for _, term := range terms {
+			got = got && searchTermMatches(e, term)
+		}
+		if got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
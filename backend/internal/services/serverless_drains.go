@@ -0,0 +1,271 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"error-logs/internal/models"
+)
+
+// vercelLine is one line of a Vercel log drain delivery
+type vercelLine struct {
+	ID              string `json:"id"`
+	Message         string `json:"message"`
+	Type            string `json:"type"`   // stdout, stderr, fatal, ...
+	Source          string `json:"source"` // build, static, lambda, edge, middleware, ...
+	Level           string `json:"level"`  // info, warning, error, fatal
+	ProjectID       string `json:"projectId"`
+	ProjectName     string `json:"projectName"`
+	DeploymentID    string `json:"deploymentId"`
+	Environment     string `json:"environment"` // production, preview
+	Branch          string `json:"branch"`
+	Host            string `json:"host"`
+	Path            string `json:"path"`
+	Entrypoint      string `json:"entrypoint"`
+	RequestID       string `json:"requestId"`
+	StatusCode      int    `json:"statusCode"`
+	ExecutionRegion string `json:"executionRegion"`
+	Proxy           *struct {
+		Method     string `json:"method"`
+		Host       string `json:"host"`
+		Path       string `json:"path"`
+		StatusCode int    `json:"statusCode"`
+		Region     string `json:"region"`
+	} `json:"proxy"`
+}
+
+// vercelFunctionSources are the sources that run functions; build and
+// static lines are not invocations
+var vercelFunctionSources = map[string]bool{
+	"lambda":     true,
+	"edge":       true,
+	"middleware": true,
+}
+
+// parseVercelDrain returns the function errors in a Vercel log drain
+// delivery: lines at level error or fatal, lines written to stderr, and
+// invocations that failed with a 5xx status without logging anything
+func parseVercelDrain(body []byte) ([]*models.CreateErrorRequest, error) {
+	lines, err := splitDrainLines(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*models.CreateErrorRequest
+	for _, raw := range lines {
+		var line vercelLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+		}
+		if req := line.toErrorRequest(); req != nil {
+			events = append(events, req)
+		}
+	}
+	return events, nil
+}
+
+func (l vercelLine) statusCode() int {
+	if l.Proxy != nil && l.Proxy.StatusCode > 0 {
+		return l.Proxy.StatusCode
+	}
+	return l.StatusCode
+}
+
+// function is the function that ran, e.g. "api/checkout.js", or the path
+// for middleware and edge requests that name none
+func (l vercelLine) function() string {
+	if l.Entrypoint != "" {
+		return l.Entrypoint
+	}
+	if l.Proxy != nil && l.Proxy.Path != "" {
+		return l.Proxy.Path
+	}
+	return l.Path
+}
+
+func (l vercelLine) toErrorRequest() *models.CreateErrorRequest {
+	if !vercelFunctionSources[l.Source] {
+		return nil
+	}
+
+	message := strings.TrimSpace(l.Message)
+	level := "error"
+	switch {
+	case l.Level == "fatal" || l.Type == "fatal":
+		level = "critical"
+	case l.Level == "error" || l.Type == "stderr":
+	case message == "" && l.statusCode() >= 500:
+		method, path := "", l.Path
+		if l.Proxy != nil {
+			method, path = l.Proxy.Method+" ", l.Proxy.Path
+		}
+		message = fmt.Sprintf("Function returned %d for %s%s", l.statusCode(), method, path)
+	default:
+		return nil
+	}
+	if message == "" {
+		return nil
+	}
+
+	// A runtime error line of a Lambda-backed function is read like Lambda's own
+	var stackTrace *string
+	if failure, ok := parseLambdaLine(message); ok {
+		message = failure.toErrorRequest("", nil).Message
+		if len(failure.Stack) > 0 {
+			stack := strings.Join(failure.Stack, "\n")
+			stackTrace = &stack
+		}
+	} else {
+		message, stackTrace = splitServerlessMessage(message)
+	}
+
+	details := map[string]interface{}{
+		"vercel_source": l.Source,
+	}
+	for name, value := range map[string]string{
+		"vercel_project_id":    l.ProjectID,
+		"vercel_project":       l.ProjectName,
+		"vercel_deployment_id": l.DeploymentID,
+		"vercel_branch":        l.Branch,
+		"vercel_request_id":    l.RequestID,
+		"vercel_entrypoint":    l.Entrypoint,
+		"host":                 l.Host,
+		"path":                 l.Path,
+	} {
+		if value != "" {
+			details[name] = value
+		}
+	}
+	if status := l.statusCode(); status != 0 {
+		details["status_code"] = status
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:      level,
+		Message:    message,
+		StackTrace: stackTrace,
+		Context:    details,
+		Source:     serverlessSource(l.function(), "vercel"),
+	}
+	if l.Environment != "" && len(l.Environment) <= 50 {
+		environment := l.Environment
+		req.Environment = &environment
+	}
+	if l.DeploymentID != "" && len(l.DeploymentID) <= maxDeploymentLength {
+		deployment := l.DeploymentID
+		req.Deployment = &deployment
+	}
+	region := l.ExecutionRegion
+	if region == "" && l.Proxy != nil {
+		region = l.Proxy.Region
+	}
+	if region != "" && len(region) <= maxRegionLength {
+		req.Region = &region
+	}
+	return req
+}
+
+// netlifyLine is one line of a Netlify log drain delivery
+type netlifyLine struct {
+	LogType      string          `json:"log_type"` // functions, edge_functions, traffic, deploys
+	FunctionName string          `json:"function_name"`
+	FunctionType string          `json:"function_type"`
+	Level        string          `json:"level"`
+	Message      string          `json:"message"`
+	RequestID    string          `json:"request_id"`
+	DeployID     string          `json:"deploy_id"`
+	SiteID       string          `json:"site_id"`
+	Path         string          `json:"path"`
+	Timestamp    json.RawMessage `json:"timestamp"`
+}
+
+// parseNetlifyDrain returns the function errors in a Netlify log drain
+// delivery. Traffic and deploy logs are ignored.
+func parseNetlifyDrain(body []byte) ([]*models.CreateErrorRequest, error) {
+	lines, err := splitDrainLines(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*models.CreateErrorRequest
+	for _, raw := range lines {
+		var line netlifyLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+		}
+		if req := line.toErrorRequest(); req != nil {
+			events = append(events, req)
+		}
+	}
+	return events, nil
+}
+
+func (l netlifyLine) toErrorRequest() *models.CreateErrorRequest {
+	if l.LogType != "functions" && l.LogType != "edge_functions" {
+		return nil
+	}
+
+	message := strings.TrimSpace(l.Message)
+	level := strings.ToLower(l.Level)
+	// Netlify Functions run on Lambda, so their error lines are Lambda's
+	failure, isLambdaError := parseLambdaLine(message)
+	if level != "error" && level != "fatal" && !isLambdaError {
+		return nil
+	}
+	if message == "" {
+		return nil
+	}
+
+	var stackTrace *string
+	if isLambdaError {
+		message = failure.toErrorRequest("", nil).Message
+		if len(failure.Stack) > 0 {
+			stack := strings.Join(failure.Stack, "\n")
+			stackTrace = &stack
+		}
+		if failure.Critical {
+			level = "fatal"
+		}
+	} else {
+		message, stackTrace = splitServerlessMessage(message)
+	}
+
+	details := map[string]interface{}{
+		"netlify_log_type": l.LogType,
+	}
+	for name, value := range map[string]string{
+		"netlify_function":      l.FunctionName,
+		"netlify_function_type": l.FunctionType,
+		"netlify_request_id":    l.RequestID,
+		"netlify_deploy_id":     l.DeployID,
+		"netlify_site_id":       l.SiteID,
+		"path":                  l.Path,
+	} {
+		if value != "" {
+			details[name] = value
+		}
+	}
+	if len(l.Timestamp) > 0 {
+		if timestamp, err := strconv.Unquote(string(l.Timestamp)); err == nil {
+			details["netlify_timestamp"] = timestamp
+		}
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:      "error",
+		Message:    message,
+		StackTrace: stackTrace,
+		Context:    details,
+		Source:     serverlessSource(l.FunctionName, "netlify"),
+	}
+	if level == "fatal" {
+		req.Level = "critical"
+	}
+	if l.DeployID != "" && len(l.DeployID) <= maxDeploymentLength {
+		deployment := l.DeployID
+		req.Deployment = &deployment
+	}
+	return req
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"error-logs/internal/models"
+)
+
+// maxLambdaPayloadBytes caps a subscription payload after decompression
+const maxLambdaPayloadBytes = 16 << 20
+
+// The lines the Lambda runtimes write for failed invocations
+var (
+	// 2024-05-01T12:00:00.000Z <request id> ERROR Invoke Error {...} (Node.js)
+	lambdaNodeLine = regexp.MustCompile(`(?s)^\d{4}-\d\d-\d\dT[\d:.]+Z\t([0-9a-f-]{36}|undefined)\t(ERROR|FATAL)\t(.*)$`)
+	// [ERROR] 2024-05-01T12:00:00.000Z <request id> message (Python logging)
+	lambdaPythonLine = regexp.MustCompile(`(?s)^\[(ERROR|CRITICAL)\]\t[^\t]+\t([0-9a-f-]{36})\t(.*)$`)
+	// [ERROR] ZeroDivisionError: division by zero (Python, uncaught)
+	lambdaPythonUncaught = regexp.MustCompile(`(?s)^\[ERROR\] (.*)$`)
+	lambdaTimeout        = regexp.MustCompile(`([0-9a-f-]{36}) Task timed out after ([\d.]+) seconds`)
+	lambdaRuntimeExit    = regexp.MustCompile(`(?s)^RequestId: ([0-9a-f-]{36}) Error: (Runtime exited[^\n]*)\n?(.*)$`)
+	// 2024/05/01/[$LATEST]0123abcd
+	lambdaStreamVersion = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// lambdaSubscription is the payload CloudWatch Logs sends to a subscription
+// filter's destination, once decompressed
+type lambdaSubscription struct {
+	MessageType string `json:"messageType"` // DATA_MESSAGE, CONTROL_MESSAGE
+	Owner       string `json:"owner"`
+	LogGroup    string `json:"logGroup"`
+	LogStream   string `json:"logStream"`
+	LogEvents   []struct {
+		ID        string `json:"id"`
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"logEvents"`
+	// AWSLogs is set when the payload is the event a subscribed Lambda
+	// function receives, which a forwarder can post unchanged
+	AWSLogs *struct {
+		Data string `json:"data"`
+	} `json:"awslogs"`
+}
+
+// lambdaError is an invocation error found in a log line
+type lambdaError struct {
+	Type      string
+	Message   string
+	Stack     []string
+	RequestID string
+	Critical  bool
+}
+
+// lambdaErrorPayload is how the runtimes serialize an error, in text log
+// lines after "Invoke Error" and in JSON log lines
+type lambdaErrorPayload struct {
+	ErrorType    string   `json:"errorType"`
+	ErrorMessage string   `json:"errorMessage"`
+	Stack        []string `json:"stack"`
+	StackTrace   []string `json:"stackTrace"`
+}
+
+func (p lambdaErrorPayload) stack() []string {
+	if len(p.StackTrace) > 0 {
+		return p.StackTrace
+	}
+	return p.Stack
+}
+
+// parseLambdaPayload decodes a subscription payload, gzip compressed or not,
+// or a subscribed function's event, and returns the invocation errors in it
+func parseLambdaPayload(body []byte) ([]*models.CreateErrorRequest, error) {
+	body, err := gunzipLambdaPayload(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload lambdaSubscription
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+	}
+	if payload.AWSLogs != nil {
+		data, err := base64.StdEncoding.DecodeString(payload.AWSLogs.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: awslogs.data: %v", ErrInvalidServerlessLogs, err)
+		}
+		if body, err = gunzipLambdaPayload(data); err != nil {
+			return nil, err
+		}
+		payload = lambdaSubscription{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+		}
+	}
+
+	// CloudWatch checks the destination is reachable with a control message
+	if payload.MessageType == "CONTROL_MESSAGE" {
+		return nil, nil
+	}
+	if payload.LogGroup == "" {
+		return nil, fmt.Errorf("%w: logGroup is required", ErrInvalidServerlessLogs)
+	}
+	if len(payload.LogEvents) > MaxServerlessBatch {
+		return nil, fmt.Errorf("%w: at most %d log lines per request", ErrInvalidServerlessLogs, MaxServerlessBatch)
+	}
+
+	function := payload.LogGroup
+	if name, ok := strings.CutPrefix(function, "/aws/lambda/"); ok {
+		function = name
+	}
+	var version string
+	if match := lambdaStreamVersion.FindStringSubmatch(payload.LogStream); match != nil {
+		version = match[1]
+	}
+
+	var events []*models.CreateErrorRequest
+	for _, logEvent := range payload.LogEvents {
+		failure, ok := parseLambdaLine(logEvent.Message)
+		if !ok {
+			continue
+		}
+
+		details := map[string]interface{}{
+			"lambda_function": function,
+			"log_group":       payload.LogGroup,
+			"log_stream":      payload.LogStream,
+		}
+		if version != "" {
+			details["lambda_version"] = version
+		}
+		if failure.RequestID != "" {
+			details["lambda_request_id"] = failure.RequestID
+		}
+		if failure.Type != "" {
+			details["lambda_error_type"] = failure.Type
+		}
+		if payload.Owner != "" {
+			details["aws_account_id"] = payload.Owner
+		}
+
+		events = append(events, failure.toErrorRequest(function, details))
+	}
+	return events, nil
+}
+
+func gunzipLambdaPayload(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+	}
+	defer reader.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxLambdaPayloadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+	}
+	if len(inflated) > maxLambdaPayloadBytes {
+		return nil, fmt.Errorf("%w: payload over %d bytes", ErrInvalidServerlessLogs, maxLambdaPayloadBytes)
+	}
+	return inflated, nil
+}
+
+// parseLambdaLine recognizes the error lines of the Node.js and Python
+// runtimes, in text or JSON log format, and the timeouts and crashes
+// reported by Lambda itself
+func parseLambdaLine(line string) (*lambdaError, bool) {
+	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, "{") {
+		return parseLambdaJSONLine(line)
+	}
+	if match := lambdaTimeout.FindStringSubmatch(line); match != nil {
+		// The duration is the function's configured timeout, so it does not
+		// split the group
+		return &lambdaError{
+			Type:      "Runtime.Timeout",
+			Message:   "Task timed out after " + match[2] + " seconds",
+			RequestID: match[1],
+			Critical:  true,
+		}, true
+	}
+	if match := lambdaRuntimeExit.FindStringSubmatch(line); match != nil {
+		failure := &lambdaError{Message: match[2], RequestID: match[1], Critical: true}
+		if errorType := strings.TrimSpace(match[3]); errorType != "" && !strings.Contains(errorType, "\n") {
+			failure.Type = errorType
+		}
+		return failure, true
+	}
+	if match := lambdaNodeLine.FindStringSubmatch(line); match != nil {
+		failure := parseLambdaText(match[3])
+		if match[1] != "undefined" {
+			failure.RequestID = match[1]
+		}
+		failure.Critical = match[2] == "FATAL"
+		return failure, true
+	}
+	if match := lambdaPythonLine.FindStringSubmatch(line); match != nil {
+		failure := parseLambdaText(match[3])
+		failure.RequestID = match[2]
+		failure.Critical = match[1] == "CRITICAL"
+		return failure, true
+	}
+	if match := lambdaPythonUncaught.FindStringSubmatch(line); match != nil {
+		return parseLambdaText(match[1]), true
+	}
+	return nil, false
+}
+
+// parseLambdaText reads the message of a text error line, which the Node.js
+// runtime follows with the serialized error for failed invocations:
+// "Invoke Error \t{"errorType": ...}"
+func parseLambdaText(text string) *lambdaError {
+	if i := strings.Index(text, "\t{"); i >= 0 {
+		var payload lambdaErrorPayload
+		if err := json.Unmarshal([]byte(text[i+1:]), &payload); err == nil && payload.ErrorMessage != "" {
+			return &lambdaError{Type: payload.ErrorType, Message: payload.ErrorMessage, Stack: payload.stack()}
+		}
+	}
+
+	message, stackTrace := splitServerlessMessage(text)
+	failure := &lambdaError{Message: message}
+	if stackTrace != nil {
+		failure.Stack = strings.Split(*stackTrace, "\n")
+	}
+	return failure
+}
+
+// parseLambdaJSONLine reads a line written in the JSON log format, where
+// the message is text or, for failed invocations, the serialized error
+func parseLambdaJSONLine(line string) (*lambdaError, bool) {
+	var entry struct {
+		Level     string          `json:"level"`
+		Message   json.RawMessage `json:"message"`
+		RequestID string          `json:"requestId"`
+		lambdaErrorPayload
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return nil, false
+	}
+	level := strings.ToUpper(entry.Level)
+	if level != "ERROR" && level != "FATAL" && level != "CRITICAL" {
+		return nil, false
+	}
+
+	failure := &lambdaError{RequestID: entry.RequestID, Critical: level != "ERROR"}
+	var payload lambdaErrorPayload
+	var text string
+	switch {
+	case json.Unmarshal(entry.Message, &payload) == nil && payload.ErrorMessage != "":
+		failure.Type, failure.Message, failure.Stack = payload.ErrorType, payload.ErrorMessage, payload.stack()
+	case entry.ErrorMessage != "":
+		failure.Type, failure.Message, failure.Stack = entry.ErrorType, entry.ErrorMessage, entry.stack()
+	case json.Unmarshal(entry.Message, &text) == nil && text != "":
+		parsed := parseLambdaText(text)
+		failure.Type, failure.Message, failure.Stack = parsed.Type, parsed.Message, parsed.Stack
+	default:
+		return nil, false
+	}
+	return failure, true
+}
+
+func (e *lambdaError) toErrorRequest(function string, details map[string]interface{}) *models.CreateErrorRequest {
+	message := e.Message
+	if e.Type != "" && !strings.HasPrefix(message, e.Type) {
+		message = e.Type + ": " + message
+	}
+	level := "error"
+	if e.Critical {
+		level = "critical"
+	}
+
+	req := &models.CreateErrorRequest{
+		Level:   level,
+		Message: message,
+		Context: details,
+		Source:  serverlessSource(function, "lambda"),
+	}
+	if len(e.Stack) > 0 {
+		stackTrace := strings.Join(e.Stack, "\n")
+		req.StackTrace = &stackTrace
+	}
+	return req
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidServerlessLogs is returned when a log drain or subscription
+// payload cannot be parsed
+var ErrInvalidServerlessLogs = errors.New("invalid serverless logs")
+
+// MaxServerlessBatch caps the number of log lines accepted per request
+const MaxServerlessBatch = 5000
+
+// Match the errors.region and errors.deployment columns
+const (
+	maxRegionLength     = 50
+	maxDeploymentLength = 100
+)
+
+// ServerlessService turns the logs of serverless platforms into error
+// events: Lambda logs from a CloudWatch Logs subscription, and Vercel and
+// Netlify log drains. Lines that are not errors are dropped, since the
+// platforms send every line the functions write.
+type ServerlessService struct {
+	errorService *ErrorService
+	quotaService *QuotaService
+	vercelSecret string
+}
+
+// NewServerlessService creates the service. vercelSecret, when set, is the
+// secret Vercel signs log drain requests with.
+func NewServerlessService(errorService *ErrorService, quotaService *QuotaService, vercelSecret string) *ServerlessService {
+	return &ServerlessService{
+		errorService: errorService,
+		quotaService: quotaService,
+		vercelSecret: vercelSecret,
+	}
+}
+
+// IngestLambda records the invocation errors in a CloudWatch Logs
+// subscription payload. It returns the number of events recorded.
+func (s *ServerlessService) IngestLambda(ctx context.Context, body []byte, projectID *uuid.UUID, environment, userAgent, ipAddress string) (int, error) {
+	events, err := parseLambdaPayload(body)
+	if err != nil {
+		return 0, err
+	}
+	return s.record(ctx, events, projectID, environment, userAgent, ipAddress)
+}
+
+// IngestVercel records the function errors in a Vercel log drain delivery
+func (s *ServerlessService) IngestVercel(ctx context.Context, body []byte, projectID *uuid.UUID, environment, userAgent, ipAddress string) (int, error) {
+	events, err := parseVercelDrain(body)
+	if err != nil {
+		return 0, err
+	}
+	return s.record(ctx, events, projectID, environment, userAgent, ipAddress)
+}
+
+// IngestNetlify records the function errors in a Netlify log drain delivery
+func (s *ServerlessService) IngestNetlify(ctx context.Context, body []byte, projectID *uuid.UUID, environment, userAgent, ipAddress string) (int, error) {
+	events, err := parseNetlifyDrain(body)
+	if err != nil {
+		return 0, err
+	}
+	return s.record(ctx, events, projectID, environment, userAgent, ipAddress)
+}
+
+// VerifyVercelSignature checks the x-vercel-signature header, an HMAC-SHA1
+// of the body. Every request passes when no secret is configured.
+func (s *ServerlessService) VerifyVercelSignature(body []byte, signature string) error {
+	if s.vercelSecret == "" {
+		return nil
+	}
+	mac := hmac.New(sha1.New, []byte(s.vercelSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return fmt.Errorf("%w: x-vercel-signature does not match", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// record stores the events, skipping throttled ones. environment applies to
+// events whose payload names none.
+func (s *ServerlessService) record(ctx context.Context, events []*models.CreateErrorRequest, projectID *uuid.UUID, environment, userAgent, ipAddress string) (int, error) {
+	accepted := 0
+	for _, req := range events {
+		if projectID != nil {
+			if _, err := s.quotaService.RecordEvent(ctx, *projectID); err == ErrQuotaExceeded {
+				return accepted, err
+			}
+		}
+
+		if req.Environment == nil && environment != "" {
+			req.Environment = &environment
+		}
+
+		_, err := s.errorService.CreateError(ctx, req, projectID, userAgent, ipAddress)
+		if err == ErrEventThrottled {
+			continue
+		}
+		if err != nil {
+			return accepted, err
+		}
+		accepted++
+	}
+
+	return accepted, nil
+}
+
+// splitDrainLines splits a log drain body, which is a JSON array or
+// newline-delimited JSON depending on how the drain was set up, into lines
+func splitDrainLines(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: empty body", ErrInvalidServerlessLogs)
+	}
+
+	var lines []json.RawMessage
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &lines); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		for decoder.More() {
+			var line json.RawMessage
+			if err := decoder.Decode(&line); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidServerlessLogs, err)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > MaxServerlessBatch {
+		return nil, fmt.Errorf("%w: at most %d log lines per request", ErrInvalidServerlessLogs, MaxServerlessBatch)
+	}
+	return lines, nil
+}
+
+// serverlessSource cuts a function name to the 50 characters of the source column
+func serverlessSource(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	if shortened, cut := shortenContextValue(name, 50); cut {
+		return shortened
+	}
+	return name
+}
+
+// splitServerlessMessage keeps the first line of a multi-line log message as
+// the message and the rest, usually a stack trace, as the stack trace
+func splitServerlessMessage(message string) (string, *string) {
+	message = strings.TrimSpace(message)
+	first, rest, found := strings.Cut(message, "\n")
+	if !found || strings.TrimSpace(rest) == "" {
+		return message, nil
+	}
+	return strings.TrimSpace(first), &message
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidService is returned when a catalog entry fails validation
+var ErrInvalidService = errors.New("invalid service")
+
+// maxServiceNameLength matches errors.source
+const maxServiceNameLength = 50
+
+// serviceTopErrorsLimit is the number of error groups shown on a service dashboard
+const serviceTopErrorsLimit = 10
+
+// maxServiceDependencies bounds what one service may depend on
+const maxServiceDependencies = 100
+
+type ServiceCatalogService struct {
+	db    *database.DB
+	redis *redis.Client
+}
+
+func NewServiceCatalogService(db *database.DB, redis *redis.Client) *ServiceCatalogService {
+	return &ServiceCatalogService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// GetServices lists the catalog along with recently seen sources that have not
+// been registered yet
+func (s *ServiceCatalogService) GetServices(ctx context.Context) (*models.ServiceListResponse, error) {
+	services, err := s.db.GetServices()
+	if err != nil {
+		return nil, err
+	}
+
+	unregistered, err := s.db.GetUnregisteredSources(time.Now().UTC().AddDate(0, 0, -30))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ServiceListResponse{
+		Services:            services,
+		UnregisteredSources: unregistered,
+	}, nil
+}
+
+func (s *ServiceCatalogService) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	return s.db.GetServiceByID(id)
+}
+
+func (s *ServiceCatalogService) CreateService(ctx context.Context, req *models.CreateServiceRequest) (*models.Service, error) {
+	if err := s.validate(req, nil); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	service := &models.Service{
+		ID:            uuid.New(),
+		Name:          req.Name,
+		Description:   req.Description,
+		OwnerTeam:     req.OwnerTeam,
+		RepositoryURL: req.RepositoryURL,
+		RunbookURL:    req.RunbookURL,
+		Dependencies:  req.Dependencies,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.db.CreateService(service); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func (s *ServiceCatalogService) UpdateService(ctx context.Context, id uuid.UUID, req *models.CreateServiceRequest) (*models.Service, error) {
+	service, err := s.db.GetServiceByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Dependencies == nil {
+		req.Dependencies = service.Dependencies
+	}
+	if err := s.validate(req, &id); err != nil {
+		return nil, err
+	}
+
+	oldName := service.Name
+	service.Name = req.Name
+	service.Description = req.Description
+	service.OwnerTeam = req.OwnerTeam
+	service.RepositoryURL = req.RepositoryURL
+	service.RunbookURL = req.RunbookURL
+	service.Dependencies = req.Dependencies
+	service.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateService(service, oldName); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func (s *ServiceCatalogService) DeleteService(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.GetServiceByID(id); err != nil {
+		return err
+	}
+	return s.db.DeleteService(id)
+}
+
+// GetDashboard aggregates a service's error stats, hourly trend, top error
+// groups, the alert rules scoped to it, and the open incidents they raised
+func (s *ServiceCatalogService) GetDashboard(ctx context.Context, id uuid.UUID) (*models.ServiceDashboard, error) {
+	service, err := s.db.GetServiceByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &models.ServiceDashboard{Service: *service, Monitors: []models.AlertRule{}}
+
+	if service.OwnerTeam != nil {
+		team, err := s.db.GetTeamByID(*service.OwnerTeam)
+		if err != nil && err.Error() != "team not found" {
+			return nil, err
+		}
+		dashboard.Owner = team
+	}
+
+	stats, err := s.db.GetServiceErrorStats(service.Name)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.Stats = *stats
+
+	if dashboard.Trend, err = s.db.GetServiceTrend(service.Name); err != nil {
+		return nil, err
+	}
+	if dashboard.TopErrors, err = s.db.GetServiceTopErrors(service.Name, serviceTopErrorsLimit); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.db.GetAlertRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.Scope.Source == service.Name {
+			dashboard.Monitors = append(dashboard.Monitors, rule)
+		}
+	}
+
+	if dashboard.OpenIncidents, err = s.db.GetOpenIncidentsForSource(service.Name); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+// GetDependencyGraph returns every catalog service and the components they
+// depend on, with the health of each. A node is impacted by the failing nodes
+// it depends on, directly or through others, which is the blast radius of
+// their failure.
+func (s *ServiceCatalogService) GetDependencyGraph(ctx context.Context) (*models.ServiceGraph, error) {
+	services, err := s.db.GetServices()
+	if err != nil {
+		return nil, err
+	}
+	errorCounts, err := s.db.GetErrorCountsBySource(time.Now().UTC().Add(-time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	incidentCounts, err := s.db.GetOpenIncidentCountsBySource()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*models.ServiceGraphNode{}
+	node := func(name string) *models.ServiceGraphNode {
+		if n, ok := nodes[name]; ok {
+			return n
+		}
+		n := &models.ServiceGraphNode{
+			Name:           name,
+			OpenIncidents:  incidentCounts[name],
+			ErrorsLastHour: errorCounts[name],
+			ImpactedBy:     []string{},
+		}
+		nodes[name] = n
+		return n
+	}
+
+	graph := &models.ServiceGraph{Nodes: []models.ServiceGraphNode{}, Edges: []models.ServiceGraphEdge{}}
+	dependencies := map[string][]string{}
+	for i := range services {
+		service := &services[i]
+		node(service.Name).ServiceID = &service.ID
+		for _, dependsOn := range service.Dependencies {
+			node(dependsOn)
+			dependencies[service.Name] = append(dependencies[service.Name], dependsOn)
+			graph.Edges = append(graph.Edges, models.ServiceGraphEdge{From: service.Name, To: dependsOn})
+		}
+	}
+
+	for _, n := range nodes {
+		switch {
+		case n.OpenIncidents > 0:
+			n.Status = "failing"
+		case n.ServiceID != nil || n.ErrorsLastHour > 0:
+			n.Status = "healthy"
+		default:
+			n.Status = "unknown"
+		}
+	}
+
+	// Walk each node's dependencies; cycles are cut by the visited set
+	for name, n := range nodes {
+		visited := map[string]bool{name: true}
+		queue := append([]string{}, dependencies[name]...)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if nodes[next].Status == "failing" {
+				n.ImpactedBy = append(n.ImpactedBy, next)
+			}
+			queue = append(queue, dependencies[next]...)
+		}
+		sort.Strings(n.ImpactedBy)
+	}
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		graph.Nodes = append(graph.Nodes, *nodes[name])
+	}
+	return graph, nil
+}
+
+// validate checks a create or update request; exclude is the service being
+// updated, which may keep its own name
+func (s *ServiceCatalogService) validate(req *models.CreateServiceRequest, exclude *uuid.UUID) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidService)
+	}
+	if len(req.Name) > maxServiceNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidService, maxServiceNameLength)
+	}
+
+	existing, err := s.db.GetServiceByName(req.Name)
+	if err != nil && err.Error() != "service not found" {
+		return err
+	}
+	if existing != nil && (exclude == nil || existing.ID != *exclude) {
+		return fmt.Errorf("%w: a service named %q already exists", ErrInvalidService, req.Name)
+	}
+
+	if req.OwnerTeam != nil {
+		if _, err := s.db.GetTeamByID(*req.OwnerTeam); err != nil {
+			if err.Error() == "team not found" {
+				return fmt.Errorf("%w: owner team not found", ErrInvalidService)
+			}
+			return err
+		}
+	}
+
+	if len(req.Dependencies) > maxServiceDependencies {
+		return fmt.Errorf("%w: at most %d dependencies may be declared", ErrInvalidService, maxServiceDependencies)
+	}
+	seen := make(map[string]bool, len(req.Dependencies))
+	dependencies := make([]string, 0, len(req.Dependencies))
+	for _, name := range req.Dependencies {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("%w: dependencies must not be empty", ErrInvalidService)
+		}
+		if len(name) > maxServiceNameLength {
+			return fmt.Errorf("%w: dependency names must be at most %d characters", ErrInvalidService, maxServiceNameLength)
+		}
+		if name == req.Name {
+			return fmt.Errorf("%w: a service can't depend on itself", ErrInvalidService)
+		}
+		if !seen[name] {
+			seen[name] = true
+			dependencies = append(dependencies, name)
+		}
+	}
+	sort.Strings(dependencies)
+	req.Dependencies = dependencies
+
+	links := []struct {
+		field string
+		value **string
+	}{
+		{"repository_url", &req.RepositoryURL},
+		{"runbook_url", &req.RunbookURL},
+	}
+	for _, link := range links {
+		if *link.value == nil || **link.value == "" {
+			*link.value = nil
+			continue
+		}
+		if !isHTTPURL(**link.value) {
+			return fmt.Errorf("%w: %s must be an http(s) URL", ErrInvalidService, link.field)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidSession is returned when a session ping fails validation
+var ErrInvalidSession = errors.New("invalid session")
+
+// MaxSessionBatch caps the number of session pings accepted per request
+const MaxSessionBatch = 1000
+
+var sessionStatuses = map[string]bool{
+	"ok":       true,
+	"exited":   true,
+	"crashed":  true,
+	"abnormal": true,
+}
+
+type SessionService struct {
+	db    *database.DB
+	redis *redis.Client
+}
+
+func NewSessionService(db *database.DB, redis *redis.Client) *SessionService {
+	return &SessionService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// RecordSessions validates and stores a batch of session start/end pings
+func (s *SessionService) RecordSessions(ctx context.Context, req *models.IngestSessionsRequest, projectID *uuid.UUID) (int, error) {
+	if len(req.Sessions) == 0 {
+		return 0, fmt.Errorf("%w: at least one session is required", ErrInvalidSession)
+	}
+	if len(req.Sessions) > MaxSessionBatch {
+		return 0, fmt.Errorf("%w: at most %d sessions per request", ErrInvalidSession, MaxSessionBatch)
+	}
+
+	now := time.Now().UTC()
+	for i := range req.Sessions {
+		update := &req.Sessions[i]
+		if update.SessionID == "" || len(update.SessionID) > 128 {
+			return 0, fmt.Errorf("%w: session_id is required and must be at most 128 characters", ErrInvalidSession)
+		}
+		if len(update.DistinctID) > 128 {
+			return 0, fmt.Errorf("%w: distinct_id must be at most 128 characters", ErrInvalidSession)
+		}
+		if update.Status == "" {
+			update.Status = "ok"
+		}
+		if !sessionStatuses[update.Status] {
+			return 0, fmt.Errorf("%w: status must be ok, exited, crashed, or abnormal", ErrInvalidSession)
+		}
+		if update.Timestamp == nil {
+			update.Timestamp = &now
+		}
+		if update.Started == nil {
+			update.Started = update.Timestamp
+		}
+	}
+
+	if err := s.db.UpsertSessions(req.Sessions, projectID); err != nil {
+		return 0, err
+	}
+
+	return len(req.Sessions), nil
+}
+
+func (s *SessionService) GetSessionHealth(ctx context.Context, release, environment, period string) (*models.SessionHealthResponse, error) {
+	if period == "" {
+		period = "week"
+	}
+	since := time.Now().UTC().Add(-parseSessionPeriod(period))
+
+	results, err := s.db.GetSessionHealth(release, environment, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionHealthResponse{
+		Period:  period,
+		Results: results,
+	}, nil
+}
+
+// parseSessionPeriod maps the analytics period names to a lookback window
+func parseSessionPeriod(period string) time.Duration {
+	switch period {
+	case "day":
+		return 24 * time.Hour
+	case "month":
+		return 30 * 24 * time.Hour
+	case "year":
+		return 365 * 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
@@ -2,6 +2,10 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +15,17 @@ import (
 	"error-logs/internal/redis"
 )
 
+var (
+	// ErrInvalidAPIKeyChange is returned for API key updates that can't be
+	// applied
+	ErrInvalidAPIKeyChange = errors.New("invalid API key change")
+	// ErrInvalidIntegration is returned when an integration fails validation
+	ErrInvalidIntegration = errors.New("invalid integration")
+)
+
+// IntegrationKinds are the services an integration can connect to
+var IntegrationKinds = []string{"slack", "email", "webhook", "pagerduty", "opsgenie"}
+
 type SettingsService struct {
 	db    *database.DB
 	redis *redis.Client
@@ -23,48 +38,200 @@ func NewSettingsService(db *database.DB, redis *redis.Client) *SettingsService {
 	}
 }
 
-func (s *SettingsService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
-	return s.db.GetAPIKeys()
+// GetAPIKeys lists the keys of the caller's organization; deployment-wide
+// callers (nil callerOrg) see every key. A non-empty externalID finds the key
+// with that external ID.
+func (s *SettingsService) GetAPIKeys(ctx context.Context, callerOrg *uuid.UUID, externalID string) ([]models.APIKey, error) {
+	keys, err := s.db.GetAPIKeys(callerOrg)
+	if err != nil || externalID == "" {
+		return keys, err
+	}
+	return slices.DeleteFunc(keys, func(key models.APIKey) bool {
+		return key.ExternalID == nil || *key.ExternalID != externalID
+	}), nil
 }
 
-func (s *SettingsService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, keyHash string) (*models.APIKey, error) {
+// CreateAPIKey creates a key in the caller's organization. Deployment-wide
+// callers may pick the organization, which otherwise follows the key's project.
+func (s *SettingsService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, keyHash string, callerOrg *uuid.UUID) (*models.APIKey, error) {
 	now := time.Now().UTC()
 
+	orgID := callerOrg
+	if orgID == nil && req.OrgID != nil {
+		if _, err := s.db.GetOrganization(*req.OrgID); err != nil {
+			if err.Error() == "organization not found" {
+				return nil, fmt.Errorf("%w: organization not found", ErrInvalidOrganization)
+			}
+			return nil, err
+		}
+		orgID = req.OrgID
+	}
+	if req.ProjectID != nil {
+		project, err := s.db.GetProject(*req.ProjectID)
+		if err != nil {
+			if err.Error() == "project not found" {
+				return nil, fmt.Errorf("%w: project not found", ErrInvalidOrganization)
+			}
+			return nil, err
+		}
+		if orgID == nil {
+			orgID = project.OrgID
+		} else if project.OrgID == nil || *project.OrgID != *orgID {
+			return nil, fmt.Errorf("%w: project belongs to another organization", ErrInvalidOrganization)
+		}
+	}
+
 	apiKey := &models.APIKey{
 		ID:          uuid.New(),
 		KeyHash:     keyHash,
 		Name:        req.Name,
 		Permissions: req.Permissions,
+		ProjectID:   req.ProjectID,
+		OrgID:       orgID,
 		Active:      true,
 		ExpiresAt:   req.ExpiresAt,
 		CreatedAt:   now,
 		LastUsed:    nil,
+
+		KeyType:        req.KeyType,
+		AllowedOrigins: req.AllowedOrigins,
+		Version:        1,
+	}
+	if err := applyExternalID(&apiKey.ExternalID, req.ExternalID); err != nil {
+		return nil, err
 	}
 
 	if err := s.db.CreateAPIKey(apiKey); err != nil {
-		return nil, err
+		return nil, externalIDConflict(err, "API key", apiKey.ExternalID)
 	}
 
 	return apiKey, nil
 }
 
-func (s *SettingsService) DeleteAPIKey(ctx context.Context, id uuid.UUID) error {
-	return s.db.DeleteAPIKey(id)
+// GetAPIKey returns an active key of the caller's organization
+func (s *SettingsService) GetAPIKey(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID) (*models.APIKey, error) {
+	key, err := s.db.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if callerOrg != nil && (key.OrgID == nil || *key.OrgID != *callerOrg) {
+		return nil, fmt.Errorf("API key not found")
+	}
+	return key, nil
+}
+
+// UpdateAPIKey changes the given fields of a key. With ifVersion set, the key
+// is only updated if it is still at that version.
+func (s *SettingsService) UpdateAPIKey(ctx context.Context, id uuid.UUID, req *models.UpdateAPIKeyRequest, callerOrg *uuid.UUID, ifVersion int) (*models.APIKey, error) {
+	key, err := s.GetAPIKey(ctx, id, callerOrg)
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != 0 && key.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, fmt.Errorf("%w: name can't be empty", ErrInvalidAPIKeyChange)
+		}
+		key.Name = *req.Name
+	}
+	if req.Permissions != nil {
+		if key.KeyType == "public" {
+			return nil, fmt.Errorf("%w: public keys can only ingest errors", ErrInvalidAPIKeyChange)
+		}
+		if len(*req.Permissions) == 0 {
+			return nil, fmt.Errorf("%w: permissions can't be empty", ErrInvalidAPIKeyChange)
+		}
+		key.Permissions = *req.Permissions
+	}
+	if req.ExpiresAt != nil {
+		key.ExpiresAt = req.ExpiresAt
+	}
+	if req.AllowedOrigins != nil {
+		if key.KeyType == "public" && len(*req.AllowedOrigins) == 0 {
+			return nil, fmt.Errorf("%w: public keys require at least one allowed origin", ErrInvalidAPIKeyChange)
+		}
+		key.AllowedOrigins = *req.AllowedOrigins
+	}
+	if err := applyExternalID(&key.ExternalID, req.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.UpdateAPIKey(key, ifVersion); err != nil {
+		return nil, externalIDConflict(err, "API key", key.ExternalID)
+	}
+	return key, nil
+}
+
+// DeleteAPIKey deactivates a key; with ifVersion set, only if it is still at
+// that version
+func (s *SettingsService) DeleteAPIKey(ctx context.Context, id uuid.UUID, callerOrg *uuid.UUID, ifVersion int) error {
+	if err := s.checkKeyOrg(id, callerOrg); err != nil {
+		return err
+	}
+	return s.db.DeleteAPIKey(id, ifVersion)
+}
+
+// checkKeyOrg reports keys outside the caller's organization as missing, so
+// callers can't act on, or learn about, another organization's keys
+func (s *SettingsService) checkKeyOrg(id uuid.UUID, callerOrg *uuid.UUID) error {
+	if callerOrg == nil {
+		return nil
+	}
+	keyOrg, err := s.db.GetAPIKeyOrgID(id)
+	if err != nil {
+		return err
+	}
+	if keyOrg == nil || *keyOrg != *callerOrg {
+		return fmt.Errorf("API key not found")
+	}
+	return nil
 }
 
-func (s *SettingsService) GetTeamMembers(ctx context.Context) ([]models.TeamMember, error) {
-	return s.db.GetTeamMembers()
+func (s *SettingsService) GetTeamMembers(ctx context.Context, callerOrg *uuid.UUID) ([]models.TeamMember, error) {
+	return s.db.GetOrgTeamMembers(callerOrg)
 }
 
-func (s *SettingsService) InviteTeamMember(ctx context.Context, req *models.InviteTeamMemberRequest) (*models.TeamMember, error) {
+// InviteTeamMember invites a member into the caller's organization, applying
+// its default role and allowed email domains
+func (s *SettingsService) InviteTeamMember(ctx context.Context, req *models.InviteTeamMemberRequest, callerOrg *uuid.UUID) (*models.TeamMember, error) {
 	now := time.Now().UTC()
 
+	orgID := callerOrg
+	if orgID == nil {
+		orgID = req.OrgID
+	}
+	if orgID != nil {
+		org, err := s.db.GetOrganization(*orgID)
+		if err != nil {
+			if err.Error() == "organization not found" {
+				return nil, fmt.Errorf("%w: organization not found", ErrInvalidOrganization)
+			}
+			return nil, err
+		}
+		if req.Role == "" {
+			req.Role = org.Settings.DefaultRole
+		}
+		if domains := org.Settings.AllowedEmailDomains; len(domains) > 0 {
+			_, domain, _ := strings.Cut(strings.ToLower(req.Email), "@")
+			if !slices.Contains(domains, domain) {
+				return nil, fmt.Errorf("%w: %s addresses can't join this organization", ErrInvalidOrganization, domain)
+			}
+		}
+	}
+	if req.Role == "" {
+		req.Role = "viewer"
+	}
+
 	member := &models.TeamMember{
 		ID:         uuid.New(),
 		Name:       req.Email, // For now, use email as name
 		Email:      req.Email,
 		Role:       req.Role,
 		Status:     "invited",
+		OrgID:      orgID,
 		LastActive: nil,
 		CreatedAt:  now,
 	}
@@ -76,29 +243,185 @@ func (s *SettingsService) InviteTeamMember(ctx context.Context, req *models.Invi
 	return member, nil
 }
 
-func (s *SettingsService) GetIntegrations(ctx context.Context) ([]models.Integration, error) {
-	// For demo purposes, return mock integrations
-	// In a real implementation, this would be stored in database
-	integrations := []models.Integration{
-		{
-			Name:   "slack",
-			Status: "connected",
-			Config: map[string]interface{}{
-				"webhook_url": "https://hooks.slack.com/...",
-				"channel":     "#alerts",
-			},
-			LastTest: &time.Time{}, // Mock last test time
-		},
-		{
-			Name:   "email",
-			Status: "configured",
-			Config: map[string]interface{}{
-				"smtp_server": "smtp.example.com",
-				"from_email":  "alerts@example.com",
-			},
-			LastTest: nil,
-		},
-	}
-
-	return integrations, nil
+// GetIntegrations lists the configured integrations. A non-empty externalID
+// finds the integration with that external ID.
+func (s *SettingsService) GetIntegrations(ctx context.Context, externalID string) ([]models.Integration, error) {
+	integrations, err := s.db.GetIntegrations()
+	if err != nil || externalID == "" {
+		return integrations, err
+	}
+	return slices.DeleteFunc(integrations, func(integration models.Integration) bool {
+		return integration.ExternalID == nil || *integration.ExternalID != externalID
+	}), nil
+}
+
+func (s *SettingsService) GetIntegration(ctx context.Context, id uuid.UUID) (*models.Integration, error) {
+	return s.db.GetIntegration(id)
+}
+
+func (s *SettingsService) CreateIntegration(ctx context.Context, req *models.CreateIntegrationRequest) (*models.Integration, error) {
+	now := time.Now().UTC()
+	integration := &models.Integration{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(req.Name),
+		Kind:      req.Kind,
+		Config:    req.Config,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if integration.Config == nil {
+		integration.Config = map[string]interface{}{}
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		integration.Secret = req.Secret
+	}
+	if err := validateIntegration(integration); err != nil {
+		return nil, err
+	}
+	if err := applyExternalID(&integration.ExternalID, req.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.CreateIntegration(integration); err != nil {
+		return nil, externalIDConflict(err, "integration", integration.ExternalID)
+	}
+	integration.HasSecret = integration.Secret != nil
+	integration.Status = integrationStatus(integration)
+	return integration, nil
+}
+
+// UpdateIntegration changes the given fields of an integration. With
+// ifVersion set, it is only updated if it is still at that version.
+func (s *SettingsService) UpdateIntegration(ctx context.Context, id uuid.UUID, req *models.UpdateIntegrationRequest, ifVersion int) (*models.Integration, error) {
+	integration, err := s.db.GetIntegration(id)
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != 0 && integration.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	if req.Name != nil {
+		integration.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Config != nil {
+		integration.Config = *req.Config
+		if integration.Config == nil {
+			integration.Config = map[string]interface{}{}
+		}
+	}
+	if req.Secret != nil {
+		integration.Secret = nil
+		if *req.Secret != "" {
+			integration.Secret = req.Secret
+		}
+	}
+	if req.Enabled != nil {
+		integration.Enabled = *req.Enabled
+	}
+	if err := validateIntegration(integration); err != nil {
+		return nil, err
+	}
+	if err := applyExternalID(&integration.ExternalID, req.ExternalID); err != nil {
+		return nil, err
+	}
+	integration.UpdatedAt = time.Now().UTC()
+
+	if err := s.db.UpdateIntegration(integration, ifVersion); err != nil {
+		return nil, externalIDConflict(err, "integration", integration.ExternalID)
+	}
+	integration.HasSecret = integration.Secret != nil
+	integration.Status = integrationStatus(integration)
+	return integration, nil
+}
+
+// DeleteIntegration removes an integration; with ifVersion set, only if it is
+// still at that version
+func (s *SettingsService) DeleteIntegration(ctx context.Context, id uuid.UUID, ifVersion int) error {
+	return s.db.DeleteIntegration(id, ifVersion)
+}
+
+func validateIntegration(integration *models.Integration) error {
+	if integration.Name == "" || len(integration.Name) > 100 {
+		return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidIntegration)
+	}
+	if !slices.Contains(IntegrationKinds, integration.Kind) {
+		return fmt.Errorf("%w: kind must be one of %s", ErrInvalidIntegration, strings.Join(IntegrationKinds, ", "))
+	}
+	if _, ok := integration.Config["secret"]; ok {
+		return fmt.Errorf("%w: set the secret with the secret field, it is not returned with the config", ErrInvalidIntegration)
+	}
+	return nil
+}
+
+func integrationStatus(integration *models.Integration) string {
+	if !integration.Enabled {
+		return "disabled"
+	}
+	return "configured"
+}
+
+func (s *SettingsService) GetTeams(ctx context.Context) ([]models.Team, error) {
+	return s.db.GetTeams()
+}
+
+func (s *SettingsService) GetTeam(ctx context.Context, id uuid.UUID) (*models.Team, error) {
+	return s.db.GetTeamByID(id)
+}
+
+func (s *SettingsService) CreateTeam(ctx context.Context, req *models.CreateTeamRequest) (*models.Team, error) {
+	team := &models.Team{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := s.db.CreateTeam(team, req.MemberIDs); err != nil {
+		return nil, err
+	}
+
+	return s.db.GetTeamByID(team.ID)
+}
+
+func (s *SettingsService) UpdateTeam(ctx context.Context, id uuid.UUID, req *models.CreateTeamRequest) (*models.Team, error) {
+	team, err := s.db.GetTeamByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	team.Name = req.Name
+	team.Description = req.Description
+
+	if err := s.db.UpdateTeam(team); err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (s *SettingsService) DeleteTeam(ctx context.Context, id uuid.UUID) error {
+	return s.db.DeleteTeam(id)
+}
+
+func (s *SettingsService) AddTeamMember(ctx context.Context, teamID, memberID uuid.UUID, callerOrg *uuid.UUID) (*models.Team, error) {
+	member, err := s.db.GetTeamMemberByID(memberID)
+	if err != nil {
+		return nil, err
+	}
+	if callerOrg != nil && (member.OrgID == nil || *member.OrgID != *callerOrg) {
+		return nil, fmt.Errorf("team member not found")
+	}
+
+	if err := s.db.AddTeamMembership(teamID, memberID); err != nil {
+		return nil, err
+	}
+
+	return s.db.GetTeamByID(teamID)
+}
+
+func (s *SettingsService) RemoveTeamMember(ctx context.Context, teamID, memberID uuid.UUID) error {
+	return s.db.RemoveTeamMembership(teamID, memberID)
 }
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrorStore stores error events and their groups for ErrorService
+type ErrorStore interface {
+	// WithContext returns the store with its queries bound to ctx, so a
+	// request's queries stop once its time budget is spent
+	WithContext(ctx context.Context) ErrorStore
+
+	CreateError(error *models.Error) error
+	GetErrors(limit, offset int, filter models.ErrorFilter) ([]models.Error, int, error)
+	GetErrorByID(id uuid.UUID) (*models.Error, error)
+	GetErrorsByIDs(ids []uuid.UUID) ([]models.Error, error)
+	GetErrorsByTraceID(orgID *uuid.UUID, traceID string, limit int) ([]models.Error, error)
+	GetErrorOrgID(id uuid.UUID) (*uuid.UUID, error)
+	FindTraceDuplicate(fingerprint, traceID, source string, since time.Time) (*uuid.UUID, error)
+	GetErrorFacets(filter models.ErrorFilter) (*models.ErrorFacetsResponse, error)
+	GetErrorUpdates(filter models.ErrorFilter, updatedAt time.Time, id uuid.UUID, settle time.Duration, limit int) ([]models.Error, time.Time, error)
+	GetStats(filter models.StatsFilter, timezone string) (*models.StatsResponse, error)
+
+	ResolveErrorInRelease(id uuid.UUID, from string, groupFrom []string, orgID *uuid.UUID, release, resolvedBy string, note *string) error
+	UnresolveError(id uuid.UUID, from string, orgID *uuid.UUID) error
+	SetErrorState(id uuid.UUID, from, to string, closed bool, actor string, note *string) error
+	GetPendingReleaseResolution(fingerprint string) (*string, error)
+	ReopenRegression(fingerprint string) error
+
+	GetAutoResolvePolicies() ([]models.AutoResolvePolicy, error)
+	GetAutoResolvePolicy(projectID uuid.UUID) (*models.AutoResolvePolicy, error)
+	UpsertAutoResolvePolicy(policy *models.AutoResolvePolicy) error
+	DeleteAutoResolvePolicy(projectID uuid.UUID) error
+	AutoResolveStaleErrors(projectID uuid.UUID, cutoff time.Time, note string) ([]uuid.UUID, []string, error)
+	ReopenAutoResolved(fingerprint string) (bool, error)
+
+	TrashError(id uuid.UUID) error
+	RestoreError(id uuid.UUID) error
+	DeleteTrashedError(id uuid.UUID) error
+	GetTrashedErrors(orgID *uuid.UUID, limit, offset int) ([]models.Error, int, error)
+	PurgeTrashedErrors(cutoff time.Time) (int64, error)
+
+	GetErrorGroupRunbook(fingerprint string) (*models.Runbook, error)
+	GetErrorGroupRunbooks(fingerprints []string) (map[string]*models.Runbook, error)
+	SetErrorGroupRunbook(fingerprint string, runbook *models.Runbook) error
+	DeleteErrorGroupRunbook(fingerprint string) error
+
+	RefreshImpactScores(now time.Time) (int64, error)
+	GetErrorGroupImpacts(fingerprints []string) (map[string]float64, error)
+	GetSuppressedCounts(fingerprints []string) (map[string]int, error)
+
+	RecordFirstSeen(event *models.FirstSeenEvent) (bool, error)
+	RecordEarliestFirstSeen(event *models.FirstSeenEvent) error
+	GetFirstSeenEvents(environment string, since time.Time, limit int) ([]models.FirstSeenEvent, error)
+}
+
+// postgresErrorStore is the ErrorStore on Postgres. It only wraps the
+// database so WithContext hands back an ErrorStore.
+type postgresErrorStore struct {
+	*database.DB
+}
+
+// NewPostgresErrorStore returns the ErrorStore backed by db
+func NewPostgresErrorStore(db *database.DB) ErrorStore {
+	return postgresErrorStore{db}
+}
+
+func (s postgresErrorStore) WithContext(ctx context.Context) ErrorStore {
+	return postgresErrorStore{s.DB.WithContext(ctx)}
+}
+
+// AlertStore stores alert rules and incidents for AlertsService
+type AlertStore interface {
+	GetAlertRules() ([]models.AlertRule, error)
+	ListAlertRules(limit, offset int, filter models.AlertRuleFilter) ([]models.AlertRule, int, error)
+	GetAlertRuleByID(id uuid.UUID) (*models.AlertRule, error)
+	CreateAlertRule(rule *models.AlertRule) error
+	UpdateAlertRule(rule *models.AlertRule, ifVersion int) error
+	UpdateAlertRuleState(rule *models.AlertRule) error
+	DeleteAlertRule(id uuid.UUID, ifVersion int) error
+	ApplyAlertRules(created, updated []models.AlertRule, deleted []uuid.UUID) error
+	EvaluateAlertMetrics(metrics []string, scope models.AlertScope, window time.Duration) (map[string]float64, error)
+	GetErrorCountBuckets(scope models.AlertScope, bucket time.Duration, since time.Time) ([]int, error)
+
+	GetIncidents(limit, offset int, filter models.IncidentFilter) ([]models.Incident, int, error)
+	GetIncidentByID(id uuid.UUID) (*models.Incident, error)
+	CreateIncident(incident *models.Incident) error
+	UpdateIncident(incident *models.Incident) error
+	AttachRelatedErrors(incidentID uuid.UUID, scope models.AlertScope, since, until time.Time, limit int) (int64, error)
+	GetIncidentRelatedErrors(incidentID uuid.UUID) ([]models.IncidentRelatedError, error)
+
+	GetThresholdSubscriptions() ([]models.ThresholdSubscription, error)
+	ListThresholdSubscriptions(limit, offset int, filter models.ThresholdSubscriptionFilter) ([]models.ThresholdSubscription, int, error)
+	CreateThresholdSubscription(sub *models.ThresholdSubscription) error
+	UpdateThresholdSubscriptionState(sub *models.ThresholdSubscription) error
+	DeleteThresholdSubscription(id uuid.UUID) error
+
+	GetNotificationSchedules() ([]models.NotificationSchedule, error)
+	GetNotificationSchedule(id uuid.UUID) (*models.NotificationSchedule, error)
+	CreateNotificationSchedule(schedule *models.NotificationSchedule) error
+	UpdateNotificationSchedule(schedule *models.NotificationSchedule) error
+	DeleteNotificationSchedule(id uuid.UUID) error
+
+	GetTeamMembers() ([]models.TeamMember, error)
+	GetTeamMemberByID(id uuid.UUID) (*models.TeamMember, error)
+	GetTeamMembersByTeam(teamID uuid.UUID) ([]models.TeamMember, error)
+
+	GetProject(id uuid.UUID) (*models.Project, error)
+
+	RunbookStore
+}
+
+// RunbookStore looks up the runbooks attached to notifications
+type RunbookStore interface {
+	GetErrorGroupRunbook(fingerprint string) (*models.Runbook, error)
+	GetServiceByName(name string) (*models.Service, error)
+}
+
+// CacheClient caches query results and the most recently received events
+type CacheClient interface {
+	GetCachedErrorList(ctx context.Context, key string) ([]models.Error, int, error)
+	CacheErrorList(ctx context.Context, key string, errors []models.Error, total int, ttl time.Duration) error
+	GetCachedErrorFacets(ctx context.Context, key string) (*models.ErrorFacetsResponse, error)
+	CacheErrorFacets(ctx context.Context, key string, facets *models.ErrorFacetsResponse, ttl time.Duration) error
+	GetCachedStats(ctx context.Context, key string) (*models.StatsResponse, error)
+	CacheStats(ctx context.Context, key string, stats *models.StatsResponse) error
+	GetCacheGeneration(ctx context.Context, name string) (int64, error)
+	RecordCacheAccess(ctx context.Context, entry string, now time.Time) error
+	InvalidateAllCache(ctx context.Context) error
+	GetRecentErrors(ctx context.Context, limit int) ([]models.Error, error)
+}
+
+// Queue holds ingested events until they are written to the ErrorStore
+type Queue interface {
+	QueueError(ctx context.Context, error *models.Error, maxLength int64) (int64, error)
+	DequeueError(ctx context.Context) (*models.Error, error)
+	RequeueError(ctx context.Context, error *models.Error) error
+}
+
+// The Postgres and Redis backends
+var (
+	_ ErrorStore  = postgresErrorStore{}
+	_ AlertStore  = (*database.DB)(nil)
+	_ CacheClient = (*redis.Client)(nil)
+	_ Queue       = (*redis.Client)(nil)
+)
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+)
+
+var (
+	// ErrSummariesDisabled is returned when no language model is configured
+	ErrSummariesDisabled = errors.New("error summaries are not configured")
+	// ErrSummaryFailed is returned when the language model could not be
+	// reached or gave no usable answer
+	ErrSummaryFailed = errors.New("failed to generate error summary")
+)
+
+const (
+	// summaryDisclaimer goes with every summary, cached or not
+	summaryDisclaimer = "Generated by a language model from one event of the error group. It may be incomplete or wrong; verify before acting on it."
+	// maxSummaryStackTrace and maxSummaryContext bound what is sent of an
+	// event, so huge stack traces do not run past the model's context window
+	maxSummaryStackTrace = 8000
+	maxSummaryContext    = 4000
+	// maxSummaryHypotheses caps the likely causes kept from an answer
+	maxSummaryHypotheses = 5
+	// maxSummaryResponseBytes bounds how much of the provider's response is read
+	maxSummaryResponseBytes = 1 << 20
+)
+
+const summarySystemPrompt = `You help developers triage application errors. Given one error event, explain in plain language what went wrong and list the most likely causes, most likely first. Be concise and concrete, and do not invent details that are not in the event.
+Answer with a JSON object only: {"summary": "two or three sentences", "hypotheses": ["likely cause", "..."]}`
+
+// SummaryService explains error groups on demand with a language model behind
+// an OpenAI-compatible chat completions endpoint. Summaries are stored per
+// group, so each group costs one call until it is refreshed or expires.
+type SummaryService struct {
+	db           *database.DB
+	errorService *ErrorService
+
+	apiURL string
+	apiKey string
+	model  string
+	ttl    time.Duration
+	client *http.Client
+}
+
+func NewSummaryService(db *database.DB, errorService *ErrorService, apiURL, apiKey, model string, ttlHours int) *SummaryService {
+	return &SummaryService{
+		db:           db,
+		errorService: errorService,
+		apiURL:       strings.TrimRight(apiURL, "/"),
+		apiKey:       apiKey,
+		model:        model,
+		ttl:          time.Duration(ttlHours) * time.Hour,
+		client:       &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Enabled reports whether a language model is configured
+func (s *SummaryService) Enabled() bool {
+	return s.apiURL != "" && s.model != ""
+}
+
+// Summarize returns the summary of the error's group, generating it from the
+// error when there is none yet, it has expired, or refresh is set
+func (s *SummaryService) Summarize(ctx context.Context, id uuid.UUID, refresh bool) (*models.ErrorSummary, error) {
+	if !s.Enabled() {
+		return nil, ErrSummariesDisabled
+	}
+
+	event, err := s.errorService.GetErrorByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Every event of a group shares its summary; an event without a
+	// fingerprint is a group of its own
+	fingerprint := id.String()
+	if event.Fingerprint != nil && *event.Fingerprint != "" {
+		fingerprint = *event.Fingerprint
+	}
+
+	if !refresh {
+		cached, err := s.db.GetErrorSummary(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil && (s.ttl <= 0 || time.Since(cached.GeneratedAt) < s.ttl) {
+			cached.Generated = true
+			cached.Disclaimer = summaryDisclaimer
+			cached.Cached = true
+			return cached, nil
+		}
+	}
+
+	summary, err := s.generate(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	summary.Fingerprint = fingerprint
+	if err := s.db.SaveErrorSummary(summary); err != nil {
+		// The summary is still worth returning; the next call generates it again
+		log.Printf("Failed to store error summary: %v", err)
+	}
+	log.Printf("ERROR SUMMARY GENERATED: fingerprint: %s, model: %s", fingerprint, s.model)
+	return summary, nil
+}
+
+// chatRequest and chatResponse are the parts of the OpenAI chat completions
+// API that summaries use
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (s *SummaryService) generate(ctx context.Context, event *models.Error) (*models.ErrorSummary, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: s.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: summarySystemPrompt},
+			{Role: "user", Content: summaryPrompt(event)},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summary request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create summary request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSummaryFailed, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxSummaryResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSummaryFailed, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: provider returned status %d", ErrSummaryFailed, resp.StatusCode)
+	}
+
+	var chat chatResponse
+	if err := json.Unmarshal(respBody, &chat); err != nil || len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("%w: unexpected provider response", ErrSummaryFailed)
+	}
+	summary, err := parseSummaryAnswer(chat.Choices[0].Message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Generated = true
+	summary.Disclaimer = summaryDisclaimer
+	summary.Model = s.model
+	summary.GeneratedAt = time.Now().UTC()
+	return summary, nil
+}
+
+// summaryPrompt describes the event to the model. The IP address, user agent
+// and URL are left out, as they say little about the cause and may identify
+// the user.
+func summaryPrompt(event *models.Error) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Level: %s\n", event.Level)
+	fmt.Fprintf(&sb, "Source: %s\n", event.Source)
+	if event.Environment != "" {
+		fmt.Fprintf(&sb, "Environment: %s\n", event.Environment)
+	}
+	if event.Release != nil && *event.Release != "" {
+		fmt.Fprintf(&sb, "Release: %s\n", *event.Release)
+	}
+	fmt.Fprintf(&sb, "Message: %s\n", event.Message)
+	if event.StackTrace != nil && *event.StackTrace != "" {
+		fmt.Fprintf(&sb, "Stack trace:\n%s\n", truncate(*event.StackTrace, maxSummaryStackTrace))
+	}
+	if len(event.Context) > 0 {
+		if contextJSON, err := json.Marshal(event.Context); err == nil {
+			fmt.Fprintf(&sb, "Context: %s\n", truncate(string(contextJSON), maxSummaryContext))
+		}
+	}
+	return sb.String()
+}
+
+// parseSummaryAnswer reads the model's JSON answer, which some models wrap
+// in a Markdown code fence
+func parseSummaryAnswer(content string) (*models.ErrorSummary, error) {
+	content = strings.TrimSpace(content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var answer struct {
+		Summary    string   `json:"summary"`
+		Hypotheses []string `json:"hypotheses"`
+	}
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return nil, fmt.Errorf("%w: the model's answer is not JSON", ErrSummaryFailed)
+	}
+	answer.Summary = strings.TrimSpace(answer.Summary)
+	if answer.Summary == "" {
+		return nil, fmt.Errorf("%w: the model's answer has no summary", ErrSummaryFailed)
+	}
+
+	hypotheses := []string{}
+	for _, hypothesis := range answer.Hypotheses {
+		if hypothesis = strings.TrimSpace(hypothesis); hypothesis != "" && len(hypotheses) < maxSummaryHypotheses {
+			hypotheses = append(hypotheses, hypothesis)
+		}
+	}
+	return &models.ErrorSummary{Summary: answer.Summary, Hypotheses: hypotheses}, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrInvalidDebugFile is returned when an uploaded debug file cannot be parsed
+var ErrInvalidDebugFile = errors.New("invalid debug file")
+
+// maxCachedDebugFiles bounds how many parsed debug files are kept in memory
+const maxCachedDebugFiles = 32
+
+type SymbolicationService struct {
+	db         *database.DB
+	redis      *redis.Client
+	flags      *FeatureFlagService
+	storageDir string
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]interface{} // *proguardMapping or *dsymSymbols
+}
+
+func NewSymbolicationService(db *database.DB, redis *redis.Client, flags *FeatureFlagService, storageDir string) *SymbolicationService {
+	return &SymbolicationService{
+		db:         db,
+		redis:      redis,
+		flags:      flags,
+		storageDir: storageDir,
+		cache:      make(map[uuid.UUID]interface{}),
+	}
+}
+
+// UploadDebugFile stores a dSYM DWARF binary or a ProGuard/R8 mapping for a
+// release. The file is parsed before it is accepted; dSYMs record their
+// Mach-O UUID as the build ID.
+func (s *SymbolicationService) UploadDebugFile(ctx context.Context, kind, release, name string, projectID *uuid.UUID, content io.Reader) (*models.DebugFile, error) {
+	if kind != "dsym" && kind != "proguard" {
+		return nil, fmt.Errorf("%w: kind must be dsym or proguard", ErrInvalidDebugFile)
+	}
+	if release == "" {
+		return nil, fmt.Errorf("%w: release is required", ErrInvalidDebugFile)
+	}
+
+	if err := os.MkdirAll(s.storageDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create symbol storage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.storageDir, "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	file := &models.DebugFile{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Kind:      kind,
+		Release:   release,
+		Name:      name,
+		Size:      size,
+		Checksum:  fmt.Sprintf("%x", hash.Sum(nil)),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var parsed interface{}
+	switch kind {
+	case "proguard":
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		mapping, err := parseProguardMapping(tmp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDebugFile, err)
+		}
+		if file.Name == "" {
+			file.Name = "mapping.txt"
+		}
+		parsed = mapping
+	case "dsym":
+		symbols, err := parseDSYM(tmp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDebugFile, err)
+		}
+		file.Name = dsymBinaryName(file.Name)
+		if file.Name == "" || file.Name == "." {
+			return nil, fmt.Errorf("%w: name must be the binary name, e.g. MyApp", ErrInvalidDebugFile)
+		}
+		if symbols.buildID != "" {
+			file.BuildID = &symbols.buildID
+		}
+		parsed = symbols
+	}
+
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), s.debugFilePath(file.ID)); err != nil {
+		return nil, fmt.Errorf("failed to store debug file: %w", err)
+	}
+
+	if err := s.db.CreateDebugFile(file); err != nil {
+		os.Remove(s.debugFilePath(file.ID))
+		return nil, err
+	}
+
+	s.cacheDebugFile(file.ID, parsed)
+	log.Printf("DEBUG FILE UPLOADED: %s %s for release %s (%d bytes)", file.Kind, file.Name, file.Release, file.Size)
+
+	return file, nil
+}
+
+func (s *SymbolicationService) GetDebugFiles(ctx context.Context, release string) ([]models.DebugFile, error) {
+	return s.db.GetDebugFiles(release)
+}
+
+func (s *SymbolicationService) DeleteDebugFile(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.GetDebugFileByID(id); err != nil {
+		return err
+	}
+	if err := s.db.DeleteDebugFile(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, id)
+	s.mu.Unlock()
+
+	if err := os.Remove(s.debugFilePath(id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove debug file %s: %v", id, err)
+	}
+	return nil
+}
+
+// Symbolicate rewrites mobile stack traces in the request using the debug
+// files uploaded for its release. It runs before fingerprinting so events
+// group on the original symbols rather than obfuscated names or addresses.
+// Projects with the symbolication flag off keep their traces as sent.
+func (s *SymbolicationService) Symbolicate(req *models.CreateErrorRequest, projectID *uuid.UUID) {
+	if req.Release == nil || *req.Release == "" || req.StackTrace == nil {
+		return
+	}
+	if !s.flags.Enabled(FlagSymbolication, nil, projectID) {
+		return
+	}
+
+	stackTrace := *req.StackTrace
+	kind := ""
+	for _, line := range strings.Split(stackTrace, "\n") {
+		if javaFramePattern.MatchString(line) {
+			kind = "proguard"
+			break
+		}
+		if appleFramePattern.MatchString(line) {
+			kind = "dsym"
+			break
+		}
+	}
+	if kind == "" {
+		return
+	}
+
+	files, err := s.db.FindDebugFiles(projectID, kind, *req.Release)
+	if err != nil {
+		log.Printf("Failed to find debug files for release %s: %v", *req.Release, err)
+		return
+	}
+
+	symbolicated := false
+	for _, file := range files {
+		parsed, err := s.loadDebugFile(file)
+		if err != nil {
+			log.Printf("Failed to load debug file %s: %v", file.ID, err)
+			continue
+		}
+
+		var changed bool
+		switch symbols := parsed.(type) {
+		case *proguardMapping:
+			stackTrace, changed = symbols.retrace(stackTrace)
+		case *dsymSymbols:
+			stackTrace, changed = symbols.symbolicate(stackTrace, file.Name)
+		}
+		if changed {
+			symbolicated = true
+			// One mapping describes the whole Android build
+			if kind == "proguard" {
+				break
+			}
+		}
+	}
+
+	if !symbolicated {
+		return
+	}
+
+	req.StackTrace = &stackTrace
+	if req.Context == nil {
+		req.Context = make(map[string]interface{})
+	}
+	req.Context["symbolicated"] = kind
+}
+
+func (s *SymbolicationService) debugFilePath(id uuid.UUID) string {
+	return filepath.Join(s.storageDir, id.String())
+}
+
+func (s *SymbolicationService) loadDebugFile(file models.DebugFile) (interface{}, error) {
+	s.mu.Lock()
+	parsed, ok := s.cache[file.ID]
+	s.mu.Unlock()
+	if ok {
+		return parsed, nil
+	}
+
+	f, err := os.Open(s.debugFilePath(file.ID))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch file.Kind {
+	case "proguard":
+		parsed, err = parseProguardMapping(f)
+	case "dsym":
+		parsed, err = parseDSYM(f)
+	default:
+		err = fmt.Errorf("unknown debug file kind: %s", file.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheDebugFile(file.ID, parsed)
+	return parsed, nil
+}
+
+func (s *SymbolicationService) cacheDebugFile(id uuid.UUID, parsed interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.cache) >= maxCachedDebugFiles {
+		for key := range s.cache {
+			delete(s.cache, key)
+			break
+		}
+	}
+	s.cache[id] = parsed
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+const (
+	// tailBufferSize is how many events wait for a slow live tail client
+	// before newer ones are dropped
+	tailBufferSize = 100
+	// tailHeldLimit is how many matching events a paused subscription holds
+	tailHeldLimit = 500
+	// tailResubscribeDelay is how long to wait before resubscribing to Redis
+	tailResubscribeDelay = 5 * time.Second
+)
+
+// ErrInvalidTailCommand is returned for live tail commands that are not
+// understood
+var ErrInvalidTailCommand = errors.New("invalid tail command")
+
+// TailService streams stored events to live tail subscribers. Stored events
+// are published through Redis, so subscribers see the events stored by every
+// instance.
+type TailService struct {
+	redis *redis.Client
+
+	mu            sync.RWMutex
+	subscriptions map[*TailSubscription]struct{}
+}
+
+func NewTailService(redis *redis.Client) *TailService {
+	return &TailService{
+		redis:         redis,
+		subscriptions: make(map[*TailSubscription]struct{}),
+	}
+}
+
+// Publish announces a stored event to the subscribers of every instance
+func (s *TailService) Publish(event *models.Error) {
+	if err := s.redis.PublishTailEvent(context.Background(), event); err != nil {
+		log.Printf("Failed to publish tail event %s: %v", event.ID, err)
+	}
+}
+
+// Start relays published events to this instance's subscribers until ctx is
+// done. Every instance runs it.
+func (s *TailService) Start(ctx context.Context) {
+	log.Println("Starting live tail relay...")
+	for {
+		events, err := s.redis.SubscribeTail(ctx)
+		if err != nil {
+			log.Printf("Live tail relay: %v", err)
+		} else {
+			for event := range events {
+				s.deliver(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Live tail relay stopped")
+			return
+		case <-time.After(tailResubscribeDelay):
+		}
+	}
+}
+
+func (s *TailService) deliver(event *models.Error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for subscription := range s.subscriptions {
+		subscription.deliver(event)
+	}
+}
+
+// Subscribe starts a subscription to stored events matching filter. Callers
+// must Unsubscribe when done.
+func (s *TailService) Subscribe(filter models.ErrorFilter) *TailSubscription {
+	subscription := &TailSubscription{
+		events: make(chan *models.Error, tailBufferSize),
+		filter: tailFilter(filter),
+	}
+	s.mu.Lock()
+	s.subscriptions[subscription] = struct{}{}
+	s.mu.Unlock()
+	return subscription
+}
+
+func (s *TailService) Unsubscribe(subscription *TailSubscription) {
+	s.mu.Lock()
+	delete(s.subscriptions, subscription)
+	s.mu.Unlock()
+}
+
+// tailFilter keeps the parts of an error list filter that can be checked
+// against a single event as it is stored
+func tailFilter(filter models.ErrorFilter) models.ErrorFilter {
+	filter.Status = ""
+	filter.WatchedBy = nil
+	filter.Sort = ""
+	filter.Fuzzy = false
+	return filter
+}
+
+// TailSubscription receives the stored events matching its filter. While
+// paused, matching events are held instead and sent on resume.
+type TailSubscription struct {
+	events chan *models.Error
+
+	mu      sync.Mutex
+	filter  models.ErrorFilter
+	paused  bool
+	held    []*models.Error
+	dropped int
+}
+
+// Events delivers matching events while the subscription is not paused
+func (t *TailSubscription) Events() <-chan *models.Error {
+	return t.events
+}
+
+func (t *TailSubscription) deliver(event *models.Error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !eventMatches(event, t.filter) {
+		return
+	}
+
+	if t.paused {
+		if len(t.held) < tailHeldLimit {
+			t.held = append(t.held, event)
+		} else {
+			t.dropped++
+		}
+		return
+	}
+
+	select {
+	case t.events <- event:
+	default:
+		t.dropped++
+	}
+}
+
+// Apply carries out a client command and returns the events to send now:
+// those held while paused, when resuming
+func (t *TailSubscription) Apply(command models.TailCommand) ([]*models.Error, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch command.Type {
+	case "filter":
+		if command.Filter == nil {
+			return nil, fmt.Errorf("%w: filter is required", ErrInvalidTailCommand)
+		}
+		t.filter = tailFilter(*command.Filter)
+	case "pause":
+		t.paused = true
+	case "resume":
+		t.paused = false
+		var held []*models.Error
+		for _, event := range t.held {
+			// The filter may have changed while paused
+			if eventMatches(event, t.filter) {
+				held = append(held, event)
+			}
+		}
+		t.held = nil
+		return held, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTailCommand, command.Type)
+	}
+	return nil, nil
+}
+
+// Status describes the current filter and whether the subscription is paused
+func (t *TailSubscription) Status() models.TailMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	filter := t.filter
+	paused := t.paused
+	return models.TailMessage{Type: "status", Filter: &filter, Paused: &paused}
+}
+
+// TakeDropped returns how many matching events were lost since the last
+// call, because the client was too slow or too much was held while paused
+func (t *TailSubscription) TakeDropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dropped := t.dropped
+	t.dropped = 0
+	return dropped
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/models"
+)
+
+// ErrInvalidSubscription is returned for threshold subscriptions that cannot be evaluated
+var ErrInvalidSubscription = errors.New("invalid threshold subscription")
+
+// defaultSubscriptionWindow makes a bare threshold mean "events per hour"
+const defaultSubscriptionWindow = "1h"
+
+// GetThresholdSubscriptions returns a page of the subscriptions matching filter and how many match
+func (s *AlertsService) GetThresholdSubscriptions(ctx context.Context, limit, offset int, filter models.ThresholdSubscriptionFilter) ([]models.ThresholdSubscription, int, error) {
+	return s.db.ListThresholdSubscriptions(limit, offset, filter)
+}
+
+func (s *AlertsService) CreateThresholdSubscription(ctx context.Context, req *models.CreateThresholdSubscriptionRequest) (*models.ThresholdSubscription, error) {
+	if req.Fingerprint == "" {
+		return nil, fmt.Errorf("%w: fingerprint is required", ErrInvalidSubscription)
+	}
+	if req.Threshold <= 0 {
+		return nil, fmt.Errorf("%w: threshold must be positive", ErrInvalidSubscription)
+	}
+	window := req.TimeWindow
+	if window == "" {
+		window = defaultSubscriptionWindow
+	}
+	if _, err := s.db.GetTeamMemberByID(req.MemberID); err != nil {
+		return nil, err
+	}
+
+	sub := &models.ThresholdSubscription{
+		ID:          uuid.New(),
+		MemberID:    req.MemberID,
+		Fingerprint: req.Fingerprint,
+		Threshold:   req.Threshold,
+		TimeWindow:  window,
+		State:       "ok",
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := s.db.CreateThresholdSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *AlertsService) DeleteThresholdSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.db.DeleteThresholdSubscription(id)
+}
+
+// evaluateThresholdSubscriptions checks every subscription, counting each
+// group once per distinct window
+func (s *AlertsService) evaluateThresholdSubscriptions(ctx context.Context) {
+	subs, err := s.db.GetThresholdSubscriptions()
+	if err != nil {
+		log.Printf("Failed to load threshold subscriptions: %v", err)
+		return
+	}
+
+	type countKey struct {
+		fingerprint string
+		window      time.Duration
+	}
+	counts := make(map[countKey]float64)
+
+	for i := range subs {
+		sub := &subs[i]
+		window := parseTimeWindow(sub.TimeWindow)
+		key := countKey{sub.Fingerprint, window}
+
+		count, ok := counts[key]
+		if !ok {
+			values, err := s.db.EvaluateAlertMetrics([]string{"error_count"}, models.AlertScope{Fingerprint: sub.Fingerprint}, window)
+			if err != nil {
+				log.Printf("Failed to evaluate threshold subscription %s: %v", sub.ID, err)
+				continue
+			}
+			count = values["error_count"]
+			counts[key] = count
+		}
+
+		s.evaluateThresholdSubscription(sub, count, window)
+	}
+}
+
+// evaluateThresholdSubscription emails the member once when the count goes
+// over the threshold, and rearms once it is back at or below it
+func (s *AlertsService) evaluateThresholdSubscription(sub *models.ThresholdSubscription, count float64, window time.Duration) {
+	exceeded := count > float64(sub.Threshold)
+
+	switch {
+	case exceeded && sub.State != "firing":
+		now := time.Now().UTC()
+		sub.State = "firing"
+		sub.LastTriggered = &now
+		s.notifyThresholdSubscription(sub, count, window)
+	case !exceeded && sub.State == "firing":
+		sub.State = "ok"
+	default:
+		return
+	}
+
+	if err := s.db.UpdateThresholdSubscriptionState(sub); err != nil {
+		log.Printf("Failed to update threshold subscription %s: %v", sub.ID, err)
+	}
+}
+
+func (s *AlertsService) notifyThresholdSubscription(sub *models.ThresholdSubscription, count float64, window time.Duration) {
+	member, err := s.db.GetTeamMemberByID(sub.MemberID)
+	if err != nil {
+		log.Printf("Failed to load member for threshold subscription %s: %v", sub.ID, err)
+		return
+	}
+
+	log.Printf("THRESHOLD EXCEEDED: subscription %s, group %s, %v events in %s", sub.ID, sub.Fingerprint, count, window)
+
+	subject := fmt.Sprintf("[Threshold] Error group %s exceeded %d events", sub.Fingerprint, sub.Threshold)
+	body := fmt.Sprintf("Error group %s had %v events in the last %s, over your threshold of %d.\n\nYou will not be emailed again until it drops back to the threshold.\n",
+		sub.Fingerprint, count, window, sub.Threshold)
+	go func() {
+		if err := s.notifier.SendEmail([]string{member.Email}, subject, body); err != nil {
+			log.Printf("Failed to send threshold subscription email: %v", err)
+		}
+	}()
+	fingerprint := sub.Fingerprint
+	s.inbox.Record([]uuid.UUID{member.ID}, models.Notification{
+		Type:        "threshold",
+		Subject:     subject,
+		Body:        body,
+		Fingerprint: &fingerprint,
+	})
+}
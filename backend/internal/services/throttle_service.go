@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrEventThrottled is returned when an event was dropped by spike protection
+var ErrEventThrottled = errors.New("event throttled")
+
+// suppressedFlushInterval is how often suppressed event counts are moved from
+// Redis to the database
+const suppressedFlushInterval = 15 * time.Second
+
+type ThrottleService struct {
+	db         *database.DB
+	redis      *redis.Client
+	threshold  int
+	sampleRate int
+}
+
+func NewThrottleService(db *database.DB, redis *redis.Client, threshold, sampleRate int) *ThrottleService {
+	if sampleRate < 1 {
+		sampleRate = 1
+	}
+	return &ThrottleService{
+		db:         db,
+		redis:      redis,
+		threshold:  threshold,
+		sampleRate: sampleRate,
+	}
+}
+
+// Allow counts the event against its fingerprint and source and reports
+// whether it should be stored. Once either exceeds the per-minute threshold
+// only one in sampleRate events is kept; the rest are counted as suppressed.
+func (s *ThrottleService) Allow(ctx context.Context, fingerprint, source string) bool {
+	if s.threshold <= 0 {
+		return true
+	}
+
+	now := time.Now().UTC()
+	dimensions := map[string]string{"fingerprint": fingerprint, "source": source}
+
+	throttled := []string{}
+	keep := true
+	for kind, key := range dimensions {
+		count, err := s.redis.IncrementSpikeCounter(ctx, kind, key, now)
+		if err != nil {
+			// Fail open so a Redis hiccup never drops events
+			log.Printf("Failed to increment spike counter: %v", err)
+			continue
+		}
+		if count <= int64(s.threshold) {
+			continue
+		}
+		throttled = append(throttled, kind)
+		if count%int64(s.sampleRate) != 0 {
+			keep = false
+		}
+	}
+
+	for _, kind := range throttled {
+		s.recordThrottle(ctx, kind, dimensions[kind], now, !keep)
+	}
+	if !keep {
+		// Dropped events still count toward their group
+		if err := s.redis.CountSuppressedEvent(ctx, fingerprint, now); err != nil {
+			log.Printf("Failed to count suppressed event: %v", err)
+		}
+	}
+
+	return keep
+}
+
+// StartSuppressedFlusher moves the counts of suppressed events to the
+// database until ctx is done
+func (s *ThrottleService) StartSuppressedFlusher(ctx context.Context) {
+	if s.threshold <= 0 {
+		return
+	}
+	log.Println("Starting suppressed event flusher...")
+
+	ticker := time.NewTicker(suppressedFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushSuppressed(context.Background())
+			log.Println("Suppressed event flusher stopped")
+			return
+		case <-ticker.C:
+			s.flushSuppressed(ctx)
+		}
+	}
+}
+
+func (s *ThrottleService) flushSuppressed(ctx context.Context) {
+	counts, err := s.redis.TakeSuppressedEvents(ctx)
+	if err != nil {
+		log.Printf("Failed to read suppressed events: %v", err)
+		return
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	if err := s.db.AddSuppressedEvents(counts); err != nil {
+		log.Printf("Failed to store suppressed events: %v", err)
+		// Put them back for the next flush rather than lose them
+		if err := s.redis.RestoreSuppressedEvents(context.Background(), counts); err != nil {
+			log.Printf("Failed to restore suppressed events: %v", err)
+		}
+	}
+}
+
+// SampleRate is the fraction of events spike protection keeps from a
+// throttled fingerprint or source
+func (s *ThrottleService) SampleRate() float64 {
+	return 1 / float64(s.sampleRate)
+}
+
+func (s *ThrottleService) recordThrottle(ctx context.Context, kind, key string, now time.Time, suppressed bool) {
+	periodID, err := s.redis.GetActiveThrottlePeriod(ctx, kind, key)
+	if err != nil {
+		log.Printf("Failed to get throttle period: %v", err)
+		return
+	}
+
+	isNew := false
+	if periodID == nil {
+		// Events on other instances may be opening the period at the same
+		// time; only the one that claims it creates the row
+		id, claimed, err := s.redis.ClaimThrottlePeriod(ctx, kind, key, uuid.New())
+		if err != nil {
+			log.Printf("Failed to claim throttle period: %v", err)
+			return
+		}
+		periodID = &id
+		if claimed {
+			isNew = true
+			period := &models.ThrottlePeriod{
+				ID:         id,
+				Kind:       kind,
+				Key:        key,
+				StartedAt:  now,
+				LastSeenAt: now,
+			}
+			if err := s.db.CreateThrottlePeriod(period); err != nil {
+				log.Printf("Failed to create throttle period: %v", err)
+				return
+			}
+			log.Printf("SPIKE PROTECTION: throttling %s %s", kind, key)
+		}
+	}
+
+	count, err := s.redis.TouchThrottlePeriod(ctx, kind, key, *periodID, suppressed)
+	if err != nil {
+		log.Printf("Failed to update throttle period: %v", err)
+		return
+	}
+
+	// Persist the running total on sampled events to keep Postgres writes low
+	if isNew || !suppressed || count%int64(s.sampleRate) == 0 {
+		id := *periodID
+		go func() {
+			if err := s.db.UpdateThrottlePeriod(id, int(count), now); err != nil {
+				log.Printf("Failed to persist throttle period: %v", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTimezone is returned for a tz parameter that is not an IANA zone name
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// loadTimezone resolves the tz parameter of stats, trends and insights, with
+// "" meaning UTC. Postgres and Go share the IANA database, so a zone Go knows
+// is one Postgres accepts.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"error-logs/internal/database"
+	"error-logs/internal/models"
+	"error-logs/internal/redis"
+)
+
+// ErrNoErrorGroup is returned when watching an error that has no fingerprint
+var ErrNoErrorGroup = errors.New("error has no group")
+
+// watchNotifyInterval limits how often watchers are emailed about new events
+// of the same group. Regressions are always sent.
+const watchNotifyInterval = time.Hour
+
+// WatchService keeps the team members watching error groups and emails them
+// about new events and regressions
+type WatchService struct {
+	db       *database.DB
+	redis    *redis.Client
+	notifier *Notifier
+	inbox    *NotificationService
+	audit    *AuditService
+}
+
+func NewWatchService(db *database.DB, redis *redis.Client, notifier *Notifier, inbox *NotificationService, audit *AuditService) *WatchService {
+	return &WatchService{
+		db:       db,
+		redis:    redis,
+		notifier: notifier,
+		inbox:    inbox,
+		audit:    audit,
+	}
+}
+
+// Watch adds a team member to the watchers of the group the error belongs to
+func (s *WatchService) Watch(ctx context.Context, errorID, memberID uuid.UUID, actor string) (*models.ErrorWatchersResponse, error) {
+	fingerprint, err := s.groupFingerprint(errorID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.GetTeamMemberByID(memberID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WatchErrorGroup(fingerprint, memberID); err != nil {
+		return nil, err
+	}
+	s.audit.Record("error.watched", actor, "error_group", fingerprint, map[string]interface{}{"member_id": memberID})
+	go s.redis.InvalidateAllCache(context.Background())
+
+	return s.watchers(fingerprint)
+}
+
+// Unwatch removes a team member from the watchers of the error's group
+func (s *WatchService) Unwatch(ctx context.Context, errorID, memberID uuid.UUID, actor string) error {
+	fingerprint, err := s.groupFingerprint(errorID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.UnwatchErrorGroup(fingerprint, memberID); err != nil {
+		return err
+	}
+	s.audit.Record("error.unwatched", actor, "error_group", fingerprint, map[string]interface{}{"member_id": memberID})
+	go s.redis.InvalidateAllCache(context.Background())
+	return nil
+}
+
+func (s *WatchService) GetWatchers(ctx context.Context, errorID uuid.UUID) (*models.ErrorWatchersResponse, error) {
+	fingerprint, err := s.groupFingerprint(errorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.watchers(fingerprint)
+}
+
+func (s *WatchService) watchers(fingerprint string) (*models.ErrorWatchersResponse, error) {
+	watchers, err := s.db.GetErrorGroupWatchers(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ErrorWatchersResponse{Fingerprint: fingerprint, Watchers: watchers}, nil
+}
+
+func (s *WatchService) groupFingerprint(errorID uuid.UUID) (string, error) {
+	error, err := s.db.GetErrorByID(errorID)
+	if err != nil {
+		return "", err
+	}
+	if error.Fingerprint == nil {
+		return "", ErrNoErrorGroup
+	}
+	return *error.Fingerprint, nil
+}
+
+// NotifyStored emails the watchers of a stored event's group: always for a
+// regression, and otherwise at most once per watchNotifyInterval
+func (s *WatchService) NotifyStored(error *models.Error) {
+	if error.Fingerprint == nil {
+		return
+	}
+	fingerprint := *error.Fingerprint
+
+	// The claim is taken first so busy groups do not query watchers per event
+	fresh, err := s.redis.ClaimWatchNotification(context.Background(), fingerprint, watchNotifyInterval)
+	if err != nil {
+		log.Printf("Failed to claim watch notification for group %s: %v", fingerprint, err)
+		return
+	}
+	if !fresh && !error.Regressed {
+		return
+	}
+
+	watchers, err := s.db.GetErrorGroupWatchers(fingerprint)
+	if err != nil {
+		log.Printf("Failed to load watchers of group %s: %v", fingerprint, err)
+		return
+	}
+	if len(watchers) == 0 {
+		return
+	}
+
+	recipients := make([]string, 0, len(watchers))
+	memberIDs := make([]uuid.UUID, 0, len(watchers))
+	for _, watcher := range watchers {
+		recipients = append(recipients, watcher.Email)
+		memberIDs = append(memberIDs, watcher.ID)
+	}
+
+	var subject, intro string
+	if error.Regressed {
+		subject = fmt.Sprintf("[Regression] %s: %s", error.Source, truncate(error.Message, 80))
+		intro = "An error you watch was resolved and has come back."
+	} else {
+		subject = fmt.Sprintf("[Watched] %s: %s", error.Source, truncate(error.Message, 80))
+		intro = fmt.Sprintf("An error you watch occurred again. Further events are not emailed for %s.", watchNotifyInterval)
+	}
+	body := fmt.Sprintf("%s\n\nMessage: %s\nLevel: %s\nSource: %s\nEnvironment: %s\nError ID: %s\nFingerprint: %s\n",
+		intro, error.Message, error.Level, error.Source, error.Environment, error.ID, fingerprint)
+	if error.Release != nil {
+		body += fmt.Sprintf("Release: %s\n", *error.Release)
+	}
+
+	if err := s.notifier.SendEmail(recipients, subject, body); err != nil {
+		log.Printf("Failed to notify watchers of group %s: %v", fingerprint, err)
+	}
+	errorID := error.ID
+	s.inbox.Record(memberIDs, models.Notification{
+		Type:        "watch",
+		Subject:     subject,
+		Body:        body,
+		ErrorID:     &errorID,
+		Fingerprint: &fingerprint,
+	})
+}
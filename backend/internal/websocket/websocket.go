@@ -0,0 +1,131 @@
+// Package websocket serves WebSocket connections for the API on top of
+// gorilla/websocket, which handles the RFC 6455 framing: masking, control
+// frames, fragmentation and read limits. It covers what the API needs: text
+// messages, pings and closing; extensions and subprotocols are not
+// negotiated.
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Close status codes
+const (
+	CloseNormal        = websocket.CloseNormalClosure
+	CloseGoingAway     = websocket.CloseGoingAway
+	CloseProtocolError = websocket.CloseProtocolError
+	CloseTooLarge      = websocket.CloseMessageTooBig
+)
+
+// writeTimeout bounds how long a slow client may hold up a write
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned once either side has closed the connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+var upgrader = websocket.Upgrader{
+	// Streams authenticate with an API key rather than cookies, so a page on
+	// another origin gains nothing the key does not already grant
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// IsUpgrade reports whether the request asks to switch to WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// Conn is a server-side WebSocket connection. ReadMessage must be called
+// from one goroutine; writes may come from any.
+type Conn struct {
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// Upgrade completes the handshake for a request and takes over its
+// connection. On failure it has already answered the request. Messages
+// larger than maxBytes are refused and close the connection.
+func Upgrade(w http.ResponseWriter, r *http.Request, maxBytes int64) (*Conn, error) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: upgrade failed: %w", err)
+	}
+	// Deadlines set by the HTTP server no longer apply
+	conn.NetConn().SetDeadline(time.Time{})
+	conn.SetReadLimit(maxBytes)
+
+	return &Conn{conn: conn}, nil
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// close frames on the way. It returns ErrClosed once the client has closed.
+// Any other error leaves the connection unusable; the caller closes it.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	_, message, err := c.conn.ReadMessage()
+	if err == nil {
+		return message, nil
+	}
+
+	c.writeMu.Lock()
+	closed := c.closed
+	c.writeMu.Unlock()
+	var closeErr *websocket.CloseError
+	if closed || errors.As(err, &closeErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return nil, ErrClosed
+	}
+	// Protocol errors and messages over the limit have been answered with a
+	// close frame already
+	return nil, fmt.Errorf("websocket: read failed: %w", err)
+}
+
+// WriteText sends a text message
+func (c *Conn) WriteText(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return fmt.Errorf("websocket: write failed: %w", err)
+	}
+	return nil
+}
+
+// Ping sends a ping; the client's pong is consumed by ReadMessage
+func (c *Conn) Ping() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+		return fmt.Errorf("websocket: write failed: %w", err)
+	}
+	return nil
+}
+
+// Close sends a close frame with the status code and reason, then closes
+// the connection. Closing twice is a no-op.
+func (c *Conn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+	return c.conn.Close()
+}
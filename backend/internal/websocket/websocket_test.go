@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMaxBytes = 64
+
+// testServer upgrades every request and reports what ReadMessage returns
+func testServer(t *testing.T) (string, <-chan []byte, <-chan error) {
+	t.Helper()
+	messages := make(chan []byte, 10)
+	errs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, testMaxBytes)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close(CloseNormal, "")
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			messages <- message
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.Listener.Addr().String(), messages, errs
+}
+
+// rawClient speaks the protocol frame by frame, so it can break the rules
+type rawClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRaw(t *testing.T, addr string) *rawClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET / HTTP/1.1\r\nHost: " + addr + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", response.StatusCode)
+	}
+	return &rawClient{conn: conn, reader: reader}
+}
+
+// writeFrame sends one frame, masked as clients must unless told otherwise
+func (c *rawClient) writeFrame(t *testing.T, fin bool, opcode byte, payload []byte, masked bool) {
+	t.Helper()
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	header := []byte{first}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, maskBit|byte(length))
+	case length <= 0xFFFF:
+		header = append(header, maskBit|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, maskBit|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	body := append([]byte(nil), payload...)
+	if masked {
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		header = append(header, mask...)
+		for i := range body {
+			body[i] ^= mask[i%4]
+		}
+	}
+	if _, err := c.conn.Write(append(header, body...)); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+// readFrame reads one unmasked server frame
+func (c *rawClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			t.Fatalf("read frame length: %v", err)
+		}
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("read frame payload: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// expectClose reads frames until the server's close frame and checks its code
+func (c *rawClient) expectClose(t *testing.T, code int) {
+	t.Helper()
+	for {
+		opcode, payload := c.readFrame(t)
+		if opcode != 0x8 {
+			continue
+		}
+		if len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+			t.Fatalf("close frame %v, want code %d", payload, code)
+		}
+		return
+	}
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server")
+	}
+	var zero T
+	return zero
+}
+
+func TestReadMessage(t *testing.T) {
+	addr, messages, _ := testServer(t)
+	client := dialRaw(t, addr)
+
+	client.writeFrame(t, true, 0x1, []byte("hello"), true)
+	if got := receive(t, messages); string(got) != "hello" {
+		t.Errorf("message = %q, want hello", got)
+	}
+}
+
+func TestFragmentedMessageWithPing(t *testing.T) {
+	addr, messages, _ := testServer(t)
+	client := dialRaw(t, addr)
+
+	client.writeFrame(t, false, 0x1, []byte("frag"), true)
+	client.writeFrame(t, true, 0x9, []byte("ping"), true)
+	client.writeFrame(t, false, 0x0, []byte("men"), true)
+	client.writeFrame(t, true, 0x0, []byte("ted"), true)
+
+	if opcode, payload := client.readFrame(t); opcode != 0xA || string(payload) != "ping" {
+		t.Errorf("reply to ping = opcode %x %q, want a pong echoing it", opcode, payload)
+	}
+	if got := receive(t, messages); string(got) != "fragmented" {
+		t.Errorf("message = %q, want fragmented", got)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	addr, _, errs := testServer(t)
+	client := dialRaw(t, addr)
+
+	client.writeFrame(t, true, 0x8, binary.BigEndian.AppendUint16(nil, CloseNormal), true)
+	if err := receive(t, errs); !errors.Is(err, ErrClosed) {
+		t.Errorf("ReadMessage error = %v, want ErrClosed", err)
+	}
+	client.expectClose(t, CloseNormal)
+}
+
+func TestProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames func(t *testing.T, c *rawClient)
+		code   int
+	}{
+		{"unmasked frame", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, true, 0x1, []byte("hello"), false)
+		}, CloseProtocolError},
+		{"control frame over 125 bytes", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, true, 0x9, []byte(strings.Repeat("p", 126)), true)
+		}, CloseProtocolError},
+		{"fragmented control frame", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, false, 0x9, []byte("ping"), true)
+		}, CloseProtocolError},
+		{"continuation without a start", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, true, 0x0, []byte("orphan"), true)
+		}, CloseProtocolError},
+		{"oversized message", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, true, 0x1, []byte(strings.Repeat("x", testMaxBytes+1)), true)
+		}, CloseTooLarge},
+		{"oversized fragmented message", func(t *testing.T, c *rawClient) {
+			c.writeFrame(t, false, 0x1, []byte(strings.Repeat("x", testMaxBytes/2)), true)
+			c.writeFrame(t, false, 0x0, []byte(strings.Repeat("x", testMaxBytes/2)), true)
+			c.writeFrame(t, true, 0x0, []byte("x"), true)
+		}, CloseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, messages, errs := testServer(t)
+			client := dialRaw(t, addr)
+
+			tt.frames(t, client)
+			if err := receive(t, errs); err == nil || errors.Is(err, ErrClosed) {
+				t.Errorf("ReadMessage error = %v, want a read failure", err)
+			}
+			select {
+			case message := <-messages:
+				t.Errorf("accepted message %q", message)
+			default:
+			}
+			client.expectClose(t, tt.code)
+		})
+	}
+}
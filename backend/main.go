@@ -17,11 +17,21 @@ import (
 
 	"error-logs/internal/config"
 	"error-logs/internal/database"
+	"error-logs/internal/encryption"
 	"error-logs/internal/handlers"
+	"error-logs/internal/opensearch"
 	"error-logs/internal/redis"
+	"error-logs/internal/retry"
+	"error-logs/internal/s3"
 	"error-logs/internal/services"
 )
 
+// startupRetryPolicy paces connection attempts while waiting for dependencies
+var startupRetryPolicy = retry.Policy{
+	BaseDelay: 500 * time.Millisecond,
+	MaxDelay:  5 * time.Second,
+}
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -31,47 +41,221 @@ func main() {
 	// Initialize configuration
 	cfg := config.Load()
 
+	// Postgres and Redis may still be starting during a deploy, so keep trying
+	// for a while before giving up
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), time.Duration(cfg.StartupWaitSeconds)*time.Second)
+	defer cancelStartup()
+
 	// Initialize database
-	db, err := database.Connect(cfg.DatabaseURL)
+	var db *database.DB
+	err := retry.Wait(startupCtx, startupRetryPolicy, func() error {
+		var err error
+		db, err = database.Connect(cfg.DatabaseURL, database.PoolOptions{
+			MaxOpenConns:       cfg.DBMaxOpenConns,
+			MaxIdleConns:       cfg.DBMaxIdleConns,
+			ConnMaxLifetime:    time.Duration(cfg.DBConnMaxLifetimeSeconds) * time.Second,
+			ConnMaxIdleTime:    time.Duration(cfg.DBConnMaxIdleTimeSeconds) * time.Second,
+			SlowQueryThreshold: time.Duration(cfg.SlowQueryThresholdMS) * time.Millisecond,
+		})
+		if err != nil {
+			log.Printf("Waiting for database: %v", err)
+		}
+		return err
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	redisClient, err := redis.NewClient(cfg.RedisURL)
+	keyring, err := encryption.ParseKeyring(cfg.EncryptionKeys)
+	if err != nil {
+		log.Fatalf("Invalid ENCRYPTION_KEYS: %v", err)
+	}
+	db.UseEncryption(keyring, cfg.EncryptIPAddresses)
+
+	// Admin command: re-encrypt stored secrets with the current key
+	if len(os.Args) > 1 && os.Args[1] == "rotate-encryption-key" {
+		result, err := db.RotateEncryption()
+		if err != nil {
+			log.Fatalf("Key rotation failed: %v", err)
+		}
+		log.Printf("Re-encrypted %d API key signing secrets, %d alert rules, %d notification schedules, %d event sinks, %d integrations and %d error IP addresses",
+			result.APIKeys, result.AlertRules, result.NotificationSchedules, result.EventSinks, result.Integrations, result.Errors)
+		return
+	}
+
+	// Admin commands: write the configuration to an encrypted backup file, or
+	// restore one, sealed with BACKUP_PASSPHRASE
+	if len(os.Args) > 1 && (os.Args[1] == "backup" || os.Args[1] == "restore") {
+		runBackupCommand(db, os.Args[1], os.Args[2:])
+		return
+	}
+
+	var searchClient *opensearch.Client
+	if cfg.OpenSearchURL != "" {
+		searchClient, err = opensearch.NewClient(cfg.OpenSearchURL, cfg.OpenSearchIndex, cfg.OpenSearchUsername, cfg.OpenSearchPassword)
+		if err != nil {
+			log.Fatalf("Invalid OPENSEARCH_URL: %v", err)
+		}
+	}
+	searchIndexService := services.NewSearchIndexService(db, searchClient)
+
+	// Admin command: rebuild the OpenSearch mirror from the database
+	if len(os.Args) > 1 && os.Args[1] == "reindex-search" {
+		indexed, err := searchIndexService.Rebuild(context.Background())
+		if err != nil {
+			log.Fatalf("Search reindex failed after %d errors: %v", indexed, err)
+		}
+		log.Printf("Indexed %d errors into OpenSearch", indexed)
+		return
+	}
+
+	var redisClient *redis.Client
+	err = retry.Wait(startupCtx, startupRetryPolicy, func() error {
+		var err error
+		redisClient, err = redis.NewClient(cfg.RedisURL)
+		if err != nil {
+			log.Printf("Waiting for Redis: %v", err)
+		}
+		return err
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer redisClient.Close()
 
-	redisClient.FlushAll(context.Background())
+	// Cached responses may predate a deploy; queued events and shared flags are kept
+	redisClient.InvalidateAllCache(context.Background())
 
 	// Initialize services
-	errorService := services.NewErrorService(db, redisClient)
+	notifier := services.NewNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	throttleService := services.NewThrottleService(db, redisClient, cfg.SpikeThresholdPerMinute, cfg.SpikeSampleRate)
+	notificationService := services.NewNotificationService(db, redisClient)
+	ownershipService := services.NewOwnershipService(db, redisClient, notifier, notificationService)
+	contextIndexService := services.NewContextIndexService(db, redisClient)
+	contextSchemaService := services.NewContextSchemaService(db)
+	sdkConfigService := services.NewSDKConfigService(db)
+	auditService := services.NewAuditService(db, redisClient)
+	featureFlagService := services.NewFeatureFlagService(db, auditService)
+	symbolicationService := services.NewSymbolicationService(db, redisClient, featureFlagService, cfg.SymbolStorageDir)
+	notificationBatcher := services.NewNotificationBatcher(redisClient, cfg.NotificationBatchThreshold, time.Duration(cfg.NotificationBatchWindowSeconds)*time.Second)
+	annotationService := services.NewAnnotationService(db, redisClient, auditService)
+	alertsService := services.NewAlertsService(db, notifier, notificationBatcher, notificationService, annotationService)
+	readOnlyService := services.NewReadOnlyService(redisClient, auditService, cfg.ReadOnlyMode)
+	watchService := services.NewWatchService(db, redisClient, notifier, notificationService, auditService)
+	forwardingService := services.NewForwardingService(db, redisClient, auditService, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey)
+	backupService := services.NewBackupService(db, auditService)
+	tailService := services.NewTailService(redisClient)
+	contextLimits := services.ContextLimits{MaxKeys: cfg.MaxContextKeys, MaxValueBytes: cfg.MaxContextValueBytes}
+	errorService := services.NewErrorService(services.NewPostgresErrorStore(db), redisClient, redisClient, throttleService, ownershipService, contextIndexService, contextSchemaService, symbolicationService, auditService, alertsService, readOnlyService, watchService, forwardingService, searchIndexService, tailService, cfg.MaxQueueLength, contextLimits, cfg.GoInAppPrefixes)
 	analyticsService := services.NewAnalyticsService(db, redisClient)
-	monitoringService := services.NewMonitoringService(db, redisClient)
-	alertsService := services.NewAlertsService(db, redisClient)
+	cacheWarmer := services.NewCacheWarmer(errorService, analyticsService, time.Duration(cfg.CacheWarmIntervalSeconds)*time.Second)
+	cacheRefresher := services.NewCacheRefresher(errorService, analyticsService, redisClient, time.Duration(cfg.CacheRefreshIntervalSeconds)*time.Second)
+	redisMemoryService := services.NewRedisMemoryService(redisClient, int64(cfg.RedisCacheBudgetMB)<<20, time.Duration(cfg.RedisMemoryCheckIntervalSeconds)*time.Second)
+	redisMemoryService.CheckEvictionPolicy(context.Background())
+	leaderElector := services.NewLeaderElector(redisClient, time.Duration(cfg.LeaderLeaseSeconds)*time.Second)
+	hostService := services.NewHostService(db, cfg.HostDownAfterSeconds, cfg.HostMetricsRetentionDays)
+	monitoringService := services.NewMonitoringService(db, redisClient, leaderElector, hostService)
 	settingsService := services.NewSettingsService(db, redisClient)
+	quotaService := services.NewQuotaService(db, redisClient)
+	organizationService := services.NewOrganizationService(db, auditService)
+	publicKeyService := services.NewPublicKeyService(db, redisClient, cfg.PublicKeyRateLimitPerMinute)
+	requestSigningService := services.NewRequestSigningService(redisClient, time.Duration(cfg.SignatureToleranceSeconds)*time.Second)
+	cspService := services.NewCSPService(errorService, quotaService, publicKeyService)
+	kubernetesService := services.NewKubernetesService(errorService, quotaService, redisClient)
+	serverlessService := services.NewServerlessService(errorService, quotaService, cfg.VercelLogDrainSecret)
+	herokuService := services.NewHerokuService(errorService, quotaService)
+	clusteringService := services.NewClusteringService(db, cfg.ErrorClusteringIntervalMinutes, cfg.ErrorClusteringSimilarityPercent)
+	summaryService := services.NewSummaryService(db, errorService, cfg.LLMAPIURL, cfg.LLMAPIKey, cfg.LLMModel, cfg.LLMSummaryTTLHours)
+	minidumpService := services.NewMinidumpService(db, redisClient, errorService, quotaService, cfg.MinidumpStorageDir)
+	serviceCatalogService := services.NewServiceCatalogService(db, redisClient)
+	dashboardService := services.NewDashboardService(db, redisClient)
+	metricsService := services.NewMetricsService(db, redisClient)
+	sessionService := services.NewSessionService(db, redisClient)
+	reportService := services.NewReportService(db, redisClient, notifier, cfg.ReportRecipients, cfg.ReportPeriods, cfg.ReportTimezone)
+	importService := services.NewImportService(db, errorService, auditService)
+	billingService := services.NewBillingService(db, redisClient, cfg.BillingWebhookURL, cfg.BillingWebhookSecret)
+
+	var exportS3 *s3.Client
+	if cfg.ExportS3Bucket != "" {
+		exportS3, err = s3.NewClient(cfg.ExportS3Endpoint, cfg.ExportS3Region, cfg.ExportS3Bucket, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey)
+		if err != nil {
+			log.Fatalf("Invalid export S3 configuration: %v", err)
+		}
+	}
+	exportService := services.NewExportService(db, contextIndexService, auditService, exportS3, cfg.ExportS3Prefix,
+		cfg.ExportStorageDir, time.Duration(cfg.ExportRetentionHours)*time.Hour, cfg.ExportSigningKey)
+	logService := services.NewLogService(db, redisClient, cfg.LogDebugSampleRate, cfg.LogInfoSampleRate, cfg.LogRetentionDays, contextLimits)
+	gelfService, err := services.NewGelfService(errorService, logService, quotaService, cfg.GELFProjectID)
+	if err != nil {
+		log.Fatalf("Invalid GELF_PROJECT_ID: %v", err)
+	}
 
 	// Initialize handlers
-	errorHandler := handlers.NewErrorHandler(errorService)
+	errorHandler := handlers.NewErrorHandler(errorService, quotaService, publicKeyService)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 	monitoringHandler := handlers.NewMonitoringHandler(monitoringService)
+	hostHandler := handlers.NewHostHandler(hostService)
 	alertsHandler := handlers.NewAlertsHandler(alertsService)
-	settingsHandler := handlers.NewSettingsHandler(settingsService)
+	knownAPIKeys := handlers.NewKnownAPIKeys()
+	settingsHandler := handlers.NewSettingsHandler(settingsService, knownAPIKeys)
+	quotaHandler := handlers.NewQuotaHandler(quotaService)
+	ownershipHandler := handlers.NewOwnershipHandler(ownershipService)
+	contextIndexHandler := handlers.NewContextIndexHandler(contextIndexService)
+	contextSchemaHandler := handlers.NewContextSchemaHandler(contextSchemaService)
+	sdkConfigHandler := handlers.NewSDKConfigHandler(sdkConfigService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, quotaService)
+	metricsHandler := handlers.NewMetricsHandler(metricsService)
+	logHandler := handlers.NewLogHandler(logService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	debugFileHandler := handlers.NewDebugFileHandler(symbolicationService, cfg.SymbolMaxUploadMB)
+	cspHandler := handlers.NewCSPHandler(cspService, quotaService)
+	kubernetesHandler := handlers.NewKubernetesHandler(kubernetesService, quotaService)
+	gelfHandler := handlers.NewGelfHandler(gelfService, quotaService)
+	serverlessHandler := handlers.NewServerlessHandler(serverlessService, quotaService, cfg.VercelVerifyToken)
+	herokuHandler := handlers.NewHerokuHandler(herokuService, quotaService)
+	clusteringHandler := handlers.NewClusteringHandler(clusteringService)
+	summaryHandler := handlers.NewSummaryHandler(summaryService)
+	minidumpHandler := handlers.NewMinidumpHandler(minidumpService, quotaService, cfg.MinidumpMaxUploadMB)
+	serviceCatalogHandler := handlers.NewServiceCatalogHandler(serviceCatalogService)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	annotationHandler := handlers.NewAnnotationHandler(annotationService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	readOnlyHandler := handlers.NewReadOnlyHandler(readOnlyService)
+	watchHandler := handlers.NewWatchHandler(watchService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	importHandler := handlers.NewImportHandler(importService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagService)
+	billingHandler := handlers.NewBillingHandler(billingService)
+	eventSinkHandler := handlers.NewEventSinkHandler(forwardingService)
+	backupHandler := handlers.NewBackupHandler(backupService)
+	tailHandler := handlers.NewTailHandler(tailService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+
+	trustedProxies, err := handlers.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(handlers.ClientIPMiddleware(trustedProxies))
+	r.Use(handlers.RequestIDHeaderMiddleware)
+	r.Use(handlers.TimeoutMiddleware(handlers.RequestTimeouts{
+		Default: time.Duration(cfg.RequestTimeoutSeconds) * time.Second,
+		Ingest:  time.Duration(cfg.IngestTimeoutSeconds) * time.Second,
+		Long:    time.Duration(cfg.LongRequestTimeoutSeconds) * time.Second,
+	}))
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "*"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-Quota-Warning", "X-Quota-Remaining", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-ID", "X-Feature-Flags", "ETag", "X-Client-Backoff", "Retry-After", "X-Queue-Warning"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -85,25 +269,195 @@ func main() {
 		})
 	})
 
+	// Signed export download links, which carry no API key
+	r.Get("/exports/{id}/download", exportHandler.DownloadExport)
+
 	// API routes
 	r.Route("/api", func(r chi.Router) {
 		// API Key authentication middleware
-		r.Use(handlers.APIKeyMiddleware(db))
+		r.Use(handlers.APIKeyMiddleware(db, knownAPIKeys))
+		r.Use(handlers.QuotaHeaderMiddleware(quotaService))
+		r.Use(handlers.PublicKeyMiddleware(publicKeyService))
+		r.Use(handlers.OrgKeyMiddleware(errorService))
+		if cfg.TLSClientCAFile != "" {
+			r.Use(handlers.ClientCertMiddleware())
+		}
+		bodyLimits := handlers.BodyLimits{
+			Default:   int64(cfg.MaxBodyKB) << 10,
+			Event:     int64(cfg.MaxEventBodyKB) << 10,
+			Batch:     int64(cfg.MaxBatchBodyKB) << 10,
+			Import:    int64(cfg.MaxImportMB) << 20,
+			Minidump:  int64(cfg.MinidumpMaxUploadMB) << 20,
+			DebugFile: int64(cfg.SymbolMaxUploadMB) << 20,
+		}
+		r.Use(handlers.BodyLimitMiddleware(bodyLimits))
+		r.Use(handlers.SignatureMiddleware(requestSigningService, bodyLimits))
+		r.Use(handlers.ReadOnlyMiddleware(readOnlyService))
+		r.Use(handlers.FeatureFlagMiddleware(featureFlagService))
 
 		// Error endpoints
 		r.Post("/errors", errorHandler.CreateError)
 		r.Get("/errors", errorHandler.GetErrors)
+		r.Get("/errors/facets", errorHandler.GetErrorFacets)
+		r.Get("/errors/updates", errorHandler.GetErrorUpdates)
+		r.Get("/errors/tail", tailHandler.TailErrors)
+		r.Get("/errors/first-seen", errorHandler.GetFirstSeen)
+		r.Get("/errors/states", errorHandler.GetErrorStates)
+		r.Get("/errors/trash", errorHandler.GetTrash)
+		r.Get("/errors/clusters", clusteringHandler.GetClusters)
+		r.Delete("/errors/trash/{id}", errorHandler.PurgeError)
+		r.Get("/errors/by-trace/{traceId}", errorHandler.GetErrorsByTrace)
 		r.Get("/errors/{id}", errorHandler.GetError)
 		r.Put("/errors/{id}/resolve", errorHandler.ResolveError)
+		r.Put("/errors/{id}/unresolve", errorHandler.UnresolveError)
+		r.Put("/errors/{id}/state", errorHandler.ChangeErrorState)
+		r.Put("/errors/{id}/restore", errorHandler.RestoreError)
+		r.Post("/errors/{id}/summarize", summaryHandler.Summarize)
+		r.Put("/errors/{id}/runbook", errorHandler.SetErrorRunbook)
+		r.Delete("/errors/{id}/runbook", errorHandler.DeleteErrorRunbook)
+		r.Put("/errors/{id}/watch", watchHandler.WatchError)
+		r.Delete("/errors/{id}/watch", watchHandler.UnwatchError)
+		r.Get("/errors/{id}/watchers", watchHandler.GetWatchers)
+		r.Get("/errors/{id}/events/export", exportHandler.ExportErrorEvents)
 		r.Delete("/errors/{id}", errorHandler.DeleteError)
 
+		// Remote configuration for SDKs
+		r.Get("/sdk/config", sdkConfigHandler.GetSDKConfig)
+
+		// Feature flags resolved for the API key
+		r.Get("/features", featureFlagHandler.GetFeatures)
+
 		// Stats endpoint
 		r.Get("/stats", errorHandler.GetStats)
 
+		// Content Security Policy violation reports
+		r.Post("/csp-reports", cspHandler.IngestReports)
+
+		// Kubernetes events from an in-cluster watcher
+		r.Post("/kubernetes/events", kubernetesHandler.IngestEvents)
+
+		// GELF messages, e.g. from Docker's gelf log driver
+		r.Post("/gelf", gelfHandler.Ingest)
+
+		// Serverless function logs: CloudWatch Logs subscriptions and log drains
+		r.Post("/serverless/lambda", serverlessHandler.IngestLambda)
+		r.Post("/serverless/vercel", serverlessHandler.IngestVercel)
+		r.Post("/serverless/netlify", serverlessHandler.IngestNetlify)
+
+		// Heroku HTTPS log drains
+		r.Post("/heroku/logs", herokuHandler.IngestLogs)
+
+		// Custom metrics ingestion
+		r.Post("/metrics", metricsHandler.IngestMetrics)
+
+		// Log event endpoints
+		r.Post("/logs", logHandler.IngestLogs)
+		r.Get("/logs", logHandler.GetLogs)
+
+		// Session tracking
+		r.Post("/sessions", sessionHandler.RecordSessions)
+
+		// Host agent check-ins
+		r.Route("/hosts", func(r chi.Router) {
+			r.Get("/", hostHandler.GetHosts)
+			r.Post("/check-in", hostHandler.CheckIn)
+			r.Get("/{hostname}", hostHandler.GetHost)
+			r.Delete("/{hostname}", hostHandler.DeleteHost)
+		})
+
+		// Debug files for mobile symbolication
+		r.Route("/debug-files", func(r chi.Router) {
+			r.Get("/", debugFileHandler.GetDebugFiles)
+			r.Post("/", debugFileHandler.UploadDebugFile)
+			r.Delete("/{id}", debugFileHandler.DeleteDebugFile)
+		})
+
+		// Native crash minidumps
+		r.Post("/minidump", minidumpHandler.UploadMinidump)
+		r.Route("/minidumps", func(r chi.Router) {
+			r.Get("/", minidumpHandler.GetMinidumps)
+			r.Get("/{id}/download", minidumpHandler.DownloadMinidump)
+		})
+
 		// Analytics endpoints
 		r.Route("/analytics", func(r chi.Router) {
 			r.Get("/trends", analyticsHandler.GetTrends)
+			r.Get("/heatmap", analyticsHandler.GetHeatmap)
+			r.Get("/insights", analyticsHandler.GetInsights)
 			r.Get("/performance", analyticsHandler.GetPerformanceMetrics)
+			r.Get("/metrics", metricsHandler.GetMetricSeries)
+			r.Get("/sessions", sessionHandler.GetSessionHealth)
+		})
+
+		// Markers on trend charts, and the deploy webhook that adds them
+		r.Route("/annotations", func(r chi.Router) {
+			r.Get("/", annotationHandler.GetAnnotations)
+			r.Post("/", annotationHandler.CreateAnnotation)
+			r.Delete("/{id}", annotationHandler.DeleteAnnotation)
+		})
+		r.Post("/deploys", annotationHandler.RecordDeploy)
+
+		// Summary reports
+		r.Get("/reports/{period}", reportHandler.GetReport)
+
+		// Imports from other error trackers
+		r.Post("/import", importHandler.StartImport)
+		r.Get("/import/{id}", importHandler.GetImportJob)
+
+		// Exports of errors, written in the background
+		r.Post("/exports", exportHandler.CreateExport)
+		r.Get("/exports/{id}", exportHandler.GetExport)
+
+		// Service catalog
+		r.Route("/services", func(r chi.Router) {
+			r.Get("/", serviceCatalogHandler.GetServices)
+			r.Post("/", serviceCatalogHandler.CreateService)
+			r.Get("/graph", serviceCatalogHandler.GetDependencyGraph)
+			r.Get("/{id}", serviceCatalogHandler.GetService)
+			r.Put("/{id}", serviceCatalogHandler.UpdateService)
+			r.Delete("/{id}", serviceCatalogHandler.DeleteService)
+			r.Get("/{id}/dashboard", serviceCatalogHandler.GetDashboard)
+		})
+
+		// Audit log
+		r.Get("/audit-log", auditHandler.GetAuditLog)
+
+		// Maintenance
+		r.Route("/admin", func(r chi.Router) {
+			r.Get("/read-only", readOnlyHandler.GetReadOnlyMode)
+			r.Put("/read-only", readOnlyHandler.SetReadOnlyMode)
+			r.Get("/usage", billingHandler.GetOrgUsage)
+			// Backups hold every secret and restores overwrite keys and
+			// integrations, so the API is off unless turned on
+			if cfg.BackupAPIEnabled {
+				r.Post("/backup", backupHandler.CreateBackup)
+				r.Post("/restore", backupHandler.RestoreBackup)
+			}
+			r.Route("/sinks", func(r chi.Router) {
+				r.Get("/", eventSinkHandler.GetEventSinks)
+				r.Post("/", eventSinkHandler.CreateEventSink)
+				r.Get("/{id}", eventSinkHandler.GetEventSink)
+				r.Put("/{id}", eventSinkHandler.UpdateEventSink)
+				r.Delete("/{id}", eventSinkHandler.DeleteEventSink)
+			})
+			r.Route("/feature-flags", func(r chi.Router) {
+				r.Get("/", featureFlagHandler.GetFeatureFlags)
+				r.Put("/{key}", featureFlagHandler.UpdateFeatureFlag)
+				r.Delete("/{key}", featureFlagHandler.DeleteFeatureFlag)
+				r.Put("/{key}/overrides", featureFlagHandler.SetFeatureFlagOverride)
+				r.Delete("/{key}/overrides/{scope}/{scopeId}", featureFlagHandler.DeleteFeatureFlagOverride)
+			})
+		})
+
+		// Dashboards
+		r.Route("/dashboards", func(r chi.Router) {
+			r.Get("/", dashboardHandler.GetDashboards)
+			r.Post("/", dashboardHandler.CreateDashboard)
+			r.Get("/{id}", dashboardHandler.GetDashboard)
+			r.Put("/{id}", dashboardHandler.UpdateDashboard)
+			r.Delete("/{id}", dashboardHandler.DeleteDashboard)
+			r.Put("/{id}/layout", dashboardHandler.UpdateLayout)
+			r.Get("/{id}/data", dashboardHandler.GetDashboardData)
 		})
 
 		// Monitoring endpoints
@@ -111,6 +465,16 @@ func main() {
 			r.Get("/services", monitoringHandler.GetServiceHealth)
 			r.Get("/metrics", monitoringHandler.GetSystemMetrics)
 			r.Get("/uptime", monitoringHandler.GetUptime)
+			r.Get("/throttling", monitoringHandler.GetThrottlePeriods)
+		})
+
+		// In-app notification inbox per team member
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/", notificationHandler.GetNotifications)
+			r.Get("/unread-count", notificationHandler.GetUnreadCount)
+			r.Get("/stream", notificationHandler.StreamNotifications)
+			r.Put("/read", notificationHandler.MarkAllRead)
+			r.Put("/{id}/read", notificationHandler.MarkRead)
 		})
 
 		// Alert endpoints
@@ -118,38 +482,185 @@ func main() {
 			r.Route("/rules", func(r chi.Router) {
 				r.Get("/", alertsHandler.GetAlertRules)
 				r.Post("/", alertsHandler.CreateAlertRule)
+				r.Put("/bulk", alertsHandler.ApplyAlertRules)
+				r.Get("/{id}", alertsHandler.GetAlertRule)
 				r.Put("/{id}", alertsHandler.UpdateAlertRule)
 				r.Delete("/{id}", alertsHandler.DeleteAlertRule)
 			})
+			r.Route("/templates", func(r chi.Router) {
+				r.Get("/", alertsHandler.GetAlertTemplates)
+				r.Post("/{id}/rules", alertsHandler.CreateAlertRuleFromTemplate)
+			})
 			r.Route("/incidents", func(r chi.Router) {
 				r.Get("/", alertsHandler.GetIncidents)
 				r.Post("/", alertsHandler.CreateIncident)
 				r.Put("/{id}", alertsHandler.UpdateIncident)
+				r.Get("/{id}/related-errors", alertsHandler.GetIncidentRelatedErrors)
+			})
+			r.Route("/subscriptions", func(r chi.Router) {
+				r.Get("/", alertsHandler.GetThresholdSubscriptions)
+				r.Post("/", alertsHandler.CreateThresholdSubscription)
+				r.Delete("/{id}", alertsHandler.DeleteThresholdSubscription)
+			})
+			r.Route("/schedules", func(r chi.Router) {
+				r.Get("/", alertsHandler.GetNotificationSchedules)
+				r.Post("/", alertsHandler.CreateNotificationSchedule)
+				r.Put("/{id}", alertsHandler.UpdateNotificationSchedule)
+				r.Delete("/{id}", alertsHandler.DeleteNotificationSchedule)
 			})
 		})
 
+		// Organization endpoints
+		r.Route("/orgs", func(r chi.Router) {
+			r.Get("/", organizationHandler.GetOrganizations)
+			r.Post("/", organizationHandler.CreateOrganization)
+			r.Get("/{id}", organizationHandler.GetOrganization)
+			r.Put("/{id}", organizationHandler.UpdateOrganization)
+			r.Post("/{id}/projects", organizationHandler.CreateProject)
+			r.Get("/{id}/quota", organizationHandler.GetOrgQuota)
+			r.Put("/{id}/quota", organizationHandler.UpdateOrgQuota)
+		})
+
+		// Projects are created within an organization
+		r.Route("/projects", func(r chi.Router) {
+			r.Get("/", organizationHandler.GetProjects)
+			r.Get("/{id}", organizationHandler.GetProject)
+			r.Put("/{id}", organizationHandler.UpdateProject)
+			r.Delete("/{id}", organizationHandler.DeleteProject)
+		})
+
 		// Settings endpoints
 		r.Route("/settings", func(r chi.Router) {
 			r.Route("/api-keys", func(r chi.Router) {
 				r.Get("/", settingsHandler.GetAPIKeys)
 				r.Post("/", settingsHandler.CreateAPIKey)
+				r.Get("/{id}", settingsHandler.GetAPIKey)
+				r.Put("/{id}", settingsHandler.UpdateAPIKey)
 				r.Delete("/{id}", settingsHandler.DeleteAPIKey)
+				r.Post("/{id}/signing", settingsHandler.EnableRequestSigning)
+				r.Delete("/{id}/signing", settingsHandler.DisableRequestSigning)
 			})
 			r.Route("/team", func(r chi.Router) {
 				r.Get("/", settingsHandler.GetTeamMembers)
 				r.Post("/invite", settingsHandler.InviteTeamMember)
 			})
-			r.Get("/integrations", settingsHandler.GetIntegrations)
+			r.Route("/teams", func(r chi.Router) {
+				r.Get("/", settingsHandler.GetTeams)
+				r.Post("/", settingsHandler.CreateTeam)
+				r.Get("/{id}", settingsHandler.GetTeam)
+				r.Put("/{id}", settingsHandler.UpdateTeam)
+				r.Delete("/{id}", settingsHandler.DeleteTeam)
+				r.Post("/{id}/members", settingsHandler.AddTeamMember)
+				r.Delete("/{id}/members/{memberId}", settingsHandler.RemoveTeamMember)
+			})
+			r.Route("/integrations", func(r chi.Router) {
+				r.Get("/", settingsHandler.GetIntegrations)
+				r.Post("/", settingsHandler.CreateIntegration)
+				r.Get("/{id}", settingsHandler.GetIntegration)
+				r.Put("/{id}", settingsHandler.UpdateIntegration)
+				r.Delete("/{id}", settingsHandler.DeleteIntegration)
+			})
+			r.Get("/usage", quotaHandler.GetUsage)
+			r.Route("/quotas", func(r chi.Router) {
+				r.Get("/{projectId}", quotaHandler.GetQuota)
+				r.Put("/{projectId}", quotaHandler.UpdateQuota)
+			})
+			r.Route("/indexed-fields", func(r chi.Router) {
+				r.Get("/", contextIndexHandler.GetIndexedFields)
+				r.Post("/", contextIndexHandler.CreateIndexedField)
+				r.Delete("/{key}", contextIndexHandler.DeleteIndexedField)
+			})
+			r.Route("/context-schemas/{projectId}", func(r chi.Router) {
+				r.Get("/", contextSchemaHandler.GetContextSchema)
+				r.Put("/", contextSchemaHandler.UpdateContextSchema)
+				r.Delete("/", contextSchemaHandler.DeleteContextSchema)
+				r.Get("/conformance", contextSchemaHandler.GetConformance)
+			})
+			r.Route("/auto-resolve/{projectId}", func(r chi.Router) {
+				r.Get("/", errorHandler.GetAutoResolvePolicy)
+				r.Put("/", errorHandler.UpdateAutoResolvePolicy)
+				r.Delete("/", errorHandler.DeleteAutoResolvePolicy)
+			})
+			r.Route("/sdk-config/{projectId}", func(r chi.Router) {
+				r.Get("/", sdkConfigHandler.GetProjectSDKConfig)
+				r.Put("/", sdkConfigHandler.UpdateProjectSDKConfig)
+				r.Delete("/", sdkConfigHandler.DeleteProjectSDKConfig)
+			})
+			r.Route("/ownership-rules", func(r chi.Router) {
+				r.Get("/", ownershipHandler.GetOwnershipRules)
+				r.Post("/", ownershipHandler.CreateOwnershipRule)
+				r.Put("/{id}", ownershipHandler.UpdateOwnershipRule)
+				r.Delete("/{id}", ownershipHandler.DeleteOwnershipRule)
+			})
 		})
 	})
 
-	// Start background worker for processing Redis queue
+	// Start background worker for processing Redis queue. Every instance
+	// consumes the queue; each event is popped by only one of them.
 	go errorService.StartQueueProcessor(context.Background())
 
+	// Every instance mirrors the events it stores when OpenSearch is enabled
+	go searchIndexService.StartIndexer(context.Background())
+
+	// Every instance relays stored events to its own live tail subscribers
+	go tailService.Start(context.Background())
+
+	// Every instance relays inbox changes to its own stream clients
+	go notificationService.Start(context.Background())
+
+	// Every instance accepts GELF over UDP and TCP when an address is set, so
+	// log drivers can send to any of them
+	if cfg.GELFUDPAddr != "" {
+		if err := gelfService.ListenUDP(context.Background(), cfg.GELFUDPAddr); err != nil {
+			log.Fatalf("Failed to listen for GELF on udp %s: %v", cfg.GELFUDPAddr, err)
+		}
+	}
+	if cfg.GELFTCPAddr != "" {
+		if err := gelfService.ListenTCP(context.Background(), cfg.GELFTCPAddr); err != nil {
+			log.Fatalf("Failed to listen for GELF on tcp %s: %v", cfg.GELFTCPAddr, err)
+		}
+	}
+
+	// Periodic jobs run only on the instance holding the leader lease
+	leaderElector.Run(alertsService.StartAlertEvaluator)
+	leaderElector.Run(alertsService.StartDigestSender)
+	leaderElector.Run(logService.StartRetentionWorker)
+	leaderElector.Run(hostService.StartSamplePruner)
+	leaderElector.Run(analyticsService.StartInsightsWorker)
+	leaderElector.Run(reportService.StartReportScheduler)
+	leaderElector.Run(errorService.StartImpactScorer)
+	leaderElector.Run(throttleService.StartSuppressedFlusher)
+	leaderElector.Run(quotaService.StartUsageFlusher)
+	leaderElector.Run(clusteringService.StartClusterer)
+	leaderElector.Run(errorService.StartAutoResolver)
+	leaderElector.Run(func(ctx context.Context) {
+		errorService.StartTrashPurger(ctx, time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+	})
+	leaderElector.Run(exportService.StartExportPurger)
+	leaderElector.Run(exportService.StartStaleJobReaper)
+	leaderElector.Run(importService.StartStaleJobReaper)
+	leaderElector.Run(cacheWarmer.Start)
+	leaderElector.Run(cacheRefresher.Start)
+	leaderElector.Run(redisMemoryService.StartMemoryMonitor)
+	leaderElector.Run(notificationService.StartNotificationPurger)
+	leaderElector.Run(billingService.StartUsageReporter)
+	leaderElector.Run(forwardingService.StartForwarder)
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan struct{})
+	go func() {
+		leaderElector.Start(leaderCtx)
+		close(leaderDone)
+	}()
+
 	// Start server
+	tlsConfig, err := serverTLSConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
 	server := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Addr:      ":" + cfg.Port,
+		Handler:   r,
+		TLSConfig: tlsConfig,
 	}
 
 	// Graceful shutdown
@@ -164,8 +675,18 @@ func main() {
 		server.Shutdown(ctx)
 	}()
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if tlsConfig != nil {
+		log.Printf("Server starting on port %s (HTTPS)", cfg.Port)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("Server starting on port %s", cfg.Port)
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed to start: %v", err)
 	}
+
+	// Hand the leader lease over instead of leaving it to lapse
+	stopLeader()
+	<-leaderDone
 }
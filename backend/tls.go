@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"error-logs/internal/config"
+)
+
+// serverTLSConfig builds the HTTPS configuration for serving TLS directly,
+// with the certificate in TLS_CERT_FILE or ones obtained over ACME for
+// ACME_DOMAINS. It returns nil when neither is set and a load balancer in
+// front of the API terminates TLS instead.
+func serverTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	var tlsConfig *tls.Config
+	switch {
+	case cfg.TLSCertFile != "" && len(cfg.ACMEDomains) > 0:
+		return nil, fmt.Errorf("set either TLS_CERT_FILE or ACME_DOMAINS, not both")
+	case cfg.TLSCertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case len(cfg.ACMEDomains) > 0:
+		tlsConfig = acmeTLSConfig(cfg)
+	default:
+		if cfg.TLSClientCAFile != "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE, or ACME_DOMAINS")
+		}
+		return nil, nil
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	if cfg.TLSClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		// Certificates are verified when presented; handlers.ClientCertMiddleware
+		// requires them on the ingestion routes only
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// acmeTLSConfig obtains and renews certificates for ACME_DOMAINS from Let's
+// Encrypt. Challenges are answered with TLS-ALPN-01 on the HTTPS port
+// itself, so it must be reachable on port 443. Certificates are kept in
+// ACME_CACHE_DIR and survive restarts.
+func acmeTLSConfig(cfg *config.Config) *tls.Config {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+		Cache:      autocert.DirCache(cfg.ACMECacheDir),
+		Email:      cfg.ACMEEmail,
+	}
+	return manager.TLSConfig()
+}
@@ -15,7 +15,7 @@ CREATE TABLE errors (
     source VARCHAR(50) NOT NULL, -- frontend, backend, api
     environment VARCHAR(50) DEFAULT 'production', -- production, development, staging
     user_agent TEXT,
-    ip_address INET,
+    ip_address TEXT, -- encrypted when ENCRYPT_IP_ADDRESSES is set
     url TEXT,
     fingerprint VARCHAR(64), -- for grouping similar errors
     resolved BOOLEAN DEFAULT FALSE,
@@ -23,7 +23,35 @@ CREATE TABLE errors (
     first_seen TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     last_seen TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    release VARCHAR(100), -- application release/version that produced the event
+    resolved_in_release VARCHAR(100), -- resolved until an event from this release or newer arrives
+    regressed BOOLEAN DEFAULT FALSE,
+    project_id UUID, -- project of the API key that reported the event
+    assigned_to UUID, -- owning team member, set by ownership rules
+    assigned_team UUID, -- owning team, set by ownership rules
+    indexed_context JSONB DEFAULT '{}', -- designated context keys, extracted at ingestion
+    trace_id VARCHAR(128), -- correlation ID from context.trace_id / context.request_id
+    resolved_by VARCHAR(255), -- who resolved the error: a name given in the request or the API key
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolution_note TEXT,
+    deleted_at TIMESTAMP WITH TIME ZONE, -- in the trash; purged after TRASH_RETENTION_DAYS
+    region VARCHAR(50), -- where the reporting instance runs, e.g. eu-west-1
+    deployment VARCHAR(100), -- deployment or cluster within the region
+    duplicate_of UUID, -- the same error from another source for the same trace; left out of stats
+    schema_violations TEXT[], -- context schema violations; NULL when the project has no schema
+    auto_resolved BOOLEAN DEFAULT FALSE, -- resolved by the project's auto-resolve policy; reopened by the next event
+    state VARCHAR(20) NOT NULL DEFAULT 'new' -- new, acknowledged, in_progress, resolved, ignored, regressed; resolved is true for resolved and ignored
+);
+
+-- Organizations group projects, API keys and members so one deployment can
+-- serve several business units in isolation
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    settings JSONB DEFAULT '{}', -- default_role, allowed_email_domains
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
 -- API keys table for authentication
@@ -33,17 +61,29 @@ CREATE TABLE api_keys (
     name VARCHAR(100) NOT NULL,
     permissions JSONB DEFAULT '["read"]',
     project_id UUID,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE, -- NULL for deployment-wide keys
+    key_type VARCHAR(20) DEFAULT 'secret', -- secret, public (browser ingestion only)
+    allowed_origins JSONB DEFAULT '[]', -- public keys: origins allowed to send events
+    signing_secret TEXT, -- secret keys: requests must carry an HMAC signature when set (encrypted)
     active BOOLEAN DEFAULT TRUE,
     expires_at TIMESTAMP WITH TIME ZONE,
+    external_id VARCHAR(255), -- caller-chosen ID, e.g. a Terraform resource address
+    version INTEGER NOT NULL DEFAULT 1, -- bumped by every change; returned as the ETag
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     last_used TIMESTAMP WITH TIME ZONE
 );
 
+-- Deleted keys are kept inactive, so only active keys hold their external ID
+CREATE UNIQUE INDEX idx_api_keys_external_id ON api_keys(external_id) WHERE active;
+
 -- Projects table (for multi-project support)
 CREATE TABLE projects (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(100) NOT NULL,
     slug VARCHAR(100) UNIQUE NOT NULL,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    external_id VARCHAR(255) UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
@@ -56,7 +96,21 @@ CREATE TABLE alert_rules (
     time_window VARCHAR(20) DEFAULT '5m',
     enabled BOOLEAN DEFAULT TRUE,
     notifications JSONB DEFAULT '[]',
+    scope JSONB DEFAULT '{}', -- optional source/environment/fingerprint/level filter
     last_triggered TIMESTAMP WITH TIME ZONE,
+    severity VARCHAR(20) NOT NULL DEFAULT 'high', -- low, medium, high, critical
+    renotify_interval VARCHAR(20) DEFAULT '', -- empty: notify once per firing
+    consecutive_evaluations INTEGER DEFAULT 1,
+    recovery_threshold INTEGER, -- NULL: clear at threshold
+    state VARCHAR(20) DEFAULT 'ok', -- ok, pending, firing
+    consecutive_breaches INTEGER DEFAULT 0,
+    last_notified TIMESTAMP WITH TIME ZONE,
+    incident_id UUID, -- incident opened by the current firing
+    peak_values JSONB DEFAULT '{}', -- highest metric values during the current firing
+    runbook_url VARCHAR(500),
+    runbook_notes TEXT NOT NULL DEFAULT '',
+    external_id VARCHAR(255) UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1, -- bumped by configuration changes, not by evaluations
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -69,19 +123,39 @@ CREATE TABLE incidents (
     status VARCHAR(20) DEFAULT 'open', -- open, investigating, resolved, closed
     description TEXT,
     assigned_to UUID,
+    assigned_team UUID,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE, -- NULL for deployment-wide incidents
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+CREATE INDEX idx_incidents_org ON incidents(org_id);
+
+-- Error groups attached to an incident opened by an alert rule, as they
+-- were in the rule's scope and window when it fired
+CREATE TABLE incident_related_errors (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    event_count INTEGER NOT NULL,
+    first_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (incident_id, fingerprint)
+);
+
 -- Team members table
 CREATE TABLE team_members (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(100) NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL,
+    email VARCHAR(100) NOT NULL,
     role VARCHAR(20) DEFAULT 'viewer', -- owner, admin, developer, viewer
     status VARCHAR(20) DEFAULT 'active', -- active, invited, suspended
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
     last_active TIMESTAMP WITH TIME ZONE,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, email)
 );
 
 -- Indexes for performance
@@ -89,8 +163,18 @@ CREATE INDEX idx_errors_timestamp ON errors(timestamp DESC);
 CREATE INDEX idx_errors_level ON errors(level);
 CREATE INDEX idx_errors_source ON errors(source);
 CREATE INDEX idx_errors_fingerprint ON errors(fingerprint);
+CREATE INDEX idx_errors_state ON errors(state);
+CREATE INDEX idx_errors_auto_resolved ON errors(fingerprint) WHERE auto_resolved = true AND resolved = true;
 CREATE INDEX idx_errors_resolved ON errors(resolved);
 CREATE INDEX idx_errors_environment ON errors(environment);
+CREATE INDEX idx_errors_region ON errors(region, deployment);
+CREATE INDEX idx_errors_trace_id ON errors(trace_id) WHERE trace_id IS NOT NULL;
+CREATE INDEX idx_errors_updated_at ON errors(updated_at, id);
+CREATE INDEX idx_errors_deleted_at ON errors(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_errors_schema_checked ON errors(project_id, timestamp) WHERE schema_violations IS NOT NULL;
+CREATE INDEX idx_alert_rules_created_at ON alert_rules(created_at DESC);
+CREATE INDEX idx_incidents_status_created_at ON incidents(status, created_at DESC);
+CREATE INDEX idx_incidents_created_at ON incidents(created_at DESC);
 
 -- Trigger to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -104,13 +188,536 @@ $$ language 'plpgsql';
 CREATE TRIGGER update_errors_updated_at BEFORE UPDATE
     ON errors FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
--- Insert sample project and API key
-INSERT INTO projects (name, slug) VALUES ('Default Project', 'default');
+-- Insert sample organization, project and API key
+INSERT INTO organizations (name, slug) VALUES ('Default Organization', 'default');
+
+INSERT INTO projects (name, slug, org_id)
+SELECT 'Default Project', 'default', id FROM organizations WHERE slug = 'default';
 
 -- Generate a sample API key (in production, this should be generated securely)
-INSERT INTO api_keys (key_hash, name, project_id) 
+INSERT INTO api_keys (key_hash, name, permissions, project_id) 
 SELECT 
     encode(sha256('test-api-key'::bytea), 'hex'),
     'Development Key',
+    '["read", "write"]',
     id
 FROM projects WHERE slug = 'default';
+
+-- Per-project ingestion quotas (0 means unlimited)
+CREATE TABLE project_quotas (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    daily_soft_limit INTEGER DEFAULT 0,
+    daily_hard_limit INTEGER DEFAULT 0,
+    monthly_soft_limit INTEGER DEFAULT 0,
+    monthly_hard_limit INTEGER DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Daily usage metering per project
+CREATE TABLE project_usage (
+    project_id UUID NOT NULL,
+    day DATE NOT NULL,
+    accepted INTEGER DEFAULT 0,
+    dropped INTEGER DEFAULT 0,
+    PRIMARY KEY (project_id, day)
+);
+
+-- Spike protection: periods during which a fingerprint or source was downsampled
+CREATE TABLE throttle_periods (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(20) NOT NULL, -- fingerprint, source
+    key VARCHAR(100) NOT NULL,
+    suppressed_count INTEGER DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_throttle_periods_started_at ON throttle_periods(started_at DESC);
+
+-- Events spike protection dropped from each error group, per UTC day, so
+-- group counts and stats still include them
+CREATE TABLE error_group_suppressed (
+    fingerprint VARCHAR(64) NOT NULL,
+    day DATE NOT NULL,
+    suppressed_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (fingerprint, day)
+);
+
+-- Ownership rules: CODEOWNERS-style routing of new error groups
+CREATE TABLE ownership_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID, -- NULL applies to every project
+    match_type VARCHAR(20) NOT NULL, -- source, url, path
+    pattern TEXT NOT NULL, -- glob, e.g. "src/payments/**" or "checkout-*"
+    owner_type VARCHAR(20) DEFAULT 'member', -- member, team
+    owner_id UUID NOT NULL,
+    priority INTEGER DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_errors_assigned_to ON errors(assigned_to);
+
+-- Teams group members so rules, alerts, and incidents can target a group
+CREATE TABLE teams (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE team_memberships (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES team_members(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (team_id, member_id)
+);
+
+-- Context keys extracted into errors.indexed_context for fast lookups
+CREATE TABLE indexed_context_fields (
+    key VARCHAR(100) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_errors_indexed_context ON errors USING GIN (indexed_context jsonb_path_ops);
+
+-- Custom metric samples rolled up per minute and per hour
+CREATE TABLE metric_rollups (
+    name VARCHAR(200) NOT NULL,
+    metric_type VARCHAR(20) NOT NULL, -- counter, gauge
+    tags JSONB NOT NULL DEFAULT '{}',
+    resolution VARCHAR(10) NOT NULL, -- minute, hour
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    sample_count BIGINT NOT NULL DEFAULT 0,
+    value_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    value_min DOUBLE PRECISION,
+    value_max DOUBLE PRECISION,
+    PRIMARY KEY (name, tags, resolution, bucket)
+);
+
+CREATE INDEX idx_metric_rollups_name_bucket ON metric_rollups(name, resolution, bucket DESC);
+
+-- Hosts with a reporting agent and the metrics of their last check-in
+CREATE TABLE hosts (
+    hostname VARCHAR(255) PRIMARY KEY,
+    environment VARCHAR(50) NOT NULL DEFAULT '',
+    agent_version VARCHAR(50) NOT NULL DEFAULT '',
+    cpu_usage_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    memory_usage_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    disk_usage_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    bytes_in BIGINT NOT NULL DEFAULT 0,
+    bytes_out BIGINT NOT NULL DEFAULT 0,
+    active_connections INTEGER NOT NULL DEFAULT 0,
+    requests_per_minute INTEGER NOT NULL DEFAULT 0,
+    first_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_hosts_last_seen ON hosts(last_seen);
+
+-- Every host check-in, pruned after HOST_METRICS_RETENTION_DAYS
+CREATE TABLE host_samples (
+    hostname VARCHAR(255) NOT NULL REFERENCES hosts(hostname) ON DELETE CASCADE,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    cpu_usage_percent DOUBLE PRECISION NOT NULL,
+    memory_usage_percent DOUBLE PRECISION NOT NULL,
+    disk_usage_percent DOUBLE PRECISION NOT NULL,
+    bytes_in BIGINT NOT NULL,
+    bytes_out BIGINT NOT NULL,
+    active_connections INTEGER NOT NULL,
+    requests_per_minute INTEGER NOT NULL,
+    PRIMARY KEY (hostname, timestamp)
+);
+
+CREATE INDEX idx_host_samples_timestamp ON host_samples(timestamp);
+
+-- Lightweight store for non-error log events; debug/info are sampled and
+-- everything is pruned after LOG_RETENTION_DAYS
+CREATE TABLE log_events (
+    id BIGSERIAL PRIMARY KEY,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    level VARCHAR(20) NOT NULL, -- debug, info, warning, error
+    message TEXT NOT NULL,
+    source VARCHAR(100),
+    environment VARCHAR(50),
+    context JSONB DEFAULT '{}',
+    trace_id VARCHAR(128),
+    project_id UUID,
+    sample_rate INTEGER NOT NULL DEFAULT 1 -- stored 1 in N of this level
+);
+
+CREATE INDEX idx_log_events_timestamp ON log_events(timestamp DESC);
+CREATE INDEX idx_log_events_level_timestamp ON log_events(level, timestamp DESC);
+CREATE INDEX idx_log_events_trace_id ON log_events(trace_id) WHERE trace_id IS NOT NULL;
+
+-- Sessions reported by SDKs, used for crash-free rates per release
+CREATE TABLE sessions (
+    session_id VARCHAR(128) PRIMARY KEY,
+    project_id UUID,
+    distinct_id VARCHAR(128), -- user identifier, for crash-free users
+    release VARCHAR(100) NOT NULL DEFAULT '',
+    environment VARCHAR(50) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'ok', -- ok, exited, crashed, abnormal
+    errors INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_sessions_release_env ON sessions(release, environment, started_at DESC);
+
+-- Uploaded debug files (iOS dSYMs, Android ProGuard/R8 mappings); contents
+-- live under SYMBOL_STORAGE_DIR
+CREATE TABLE debug_files (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID,
+    kind VARCHAR(20) NOT NULL, -- dsym, proguard
+    release VARCHAR(100) NOT NULL,
+    build_id VARCHAR(100), -- Mach-O UUID for dSYMs, optional mapping ID for ProGuard
+    name VARCHAR(255) NOT NULL, -- binary name for dSYMs, e.g. MyApp
+    size BIGINT NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_debug_files_release ON debug_files(release, kind);
+
+-- Native crash minidumps (Breakpad/Crashpad); contents live under
+-- MINIDUMP_STORAGE_DIR
+CREATE TABLE minidumps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID,
+    error_id UUID, -- event created from the minidump
+    fingerprint VARCHAR(64) NOT NULL,
+    release VARCHAR(100),
+    os VARCHAR(20) NOT NULL,
+    arch VARCHAR(20) NOT NULL,
+    size BIGINT NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_minidumps_fingerprint ON minidumps(fingerprint, created_at DESC);
+
+-- Service catalog; a service's name matches errors.source
+CREATE TABLE services (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    owner_team UUID REFERENCES teams(id) ON DELETE SET NULL,
+    repository_url VARCHAR(500),
+    runbook_url VARCHAR(500),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- What each catalog service depends on, by name: another catalog service, or
+-- a component outside the catalog such as a database
+CREATE TABLE service_dependencies (
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    depends_on VARCHAR(50) NOT NULL,
+    PRIMARY KEY (service_id, depends_on)
+);
+
+CREATE INDEX idx_service_dependencies_depends_on ON service_dependencies(depends_on);
+
+-- Runbooks attached to error groups
+CREATE TABLE error_group_runbooks (
+    fingerprint VARCHAR(64) PRIMARY KEY,
+    runbook_url VARCHAR(500),
+    runbook_notes TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- User-defined dashboards; widgets (including their layout) are stored inline
+CREATE TABLE dashboards (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    widgets JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Audit log of changes made through the API
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(50) NOT NULL, -- e.g. error.resolved, error.unresolved
+    actor VARCHAR(255) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(100) NOT NULL,
+    details JSONB DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id, created_at DESC);
+
+-- First occurrence of each error group per environment ('' when the event had none)
+CREATE TABLE error_first_seen (
+    fingerprint VARCHAR(64) NOT NULL,
+    environment VARCHAR(50) NOT NULL DEFAULT '',
+    error_id UUID NOT NULL,
+    project_id UUID REFERENCES projects(id),
+    message TEXT NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    first_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (fingerprint, environment)
+);
+
+CREATE INDEX idx_error_first_seen_first_seen ON error_first_seen(first_seen DESC);
+
+-- Weekly insights per project, generated once each week has ended
+CREATE TABLE weekly_insights (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    week_start TIMESTAMP WITH TIME ZONE NOT NULL, -- Monday 00:00 UTC
+    insights JSONB NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (project_id, week_start)
+);
+
+-- Team members watching error groups, notified of new events and regressions
+CREATE TABLE error_watches (
+    fingerprint VARCHAR(64) NOT NULL,
+    member_id UUID NOT NULL REFERENCES team_members(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (fingerprint, member_id)
+);
+
+CREATE INDEX idx_error_watches_member ON error_watches(member_id);
+
+-- Personal alerts on one error group, evaluated apart from alert_rules
+CREATE TABLE threshold_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    member_id UUID NOT NULL REFERENCES team_members(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    threshold INTEGER NOT NULL,
+    time_window VARCHAR(20) DEFAULT '1h',
+    state VARCHAR(20) DEFAULT 'ok', -- ok, firing
+    last_triggered TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_threshold_subscriptions_member ON threshold_subscriptions(member_id, created_at DESC);
+CREATE INDEX idx_threshold_subscriptions_fingerprint ON threshold_subscriptions(fingerprint);
+
+-- Impact score per unresolved error group, refreshed by the impact scorer
+CREATE TABLE error_group_impact (
+    fingerprint VARCHAR(64) PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL,
+    events_24h INTEGER NOT NULL DEFAULT 0,
+    users_24h INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_error_group_impact_score ON error_group_impact(score DESC);
+
+-- Error groups that look like the same root cause, replaced on each run of
+-- the clustering job. The cluster ID is the fingerprint of its largest group.
+CREATE TABLE error_clusters (
+    id VARCHAR(64) PRIMARY KEY,
+    project_id UUID,
+    similarity DOUBLE PRECISION NOT NULL,
+    event_count INTEGER NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_error_clusters_events ON error_clusters(event_count DESC);
+
+CREATE TABLE error_cluster_groups (
+    cluster_id VARCHAR(64) NOT NULL REFERENCES error_clusters(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    event_count INTEGER NOT NULL,
+    last_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    similarity DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (cluster_id, fingerprint)
+);
+
+-- Summaries of error groups generated by the configured language model,
+-- kept until refreshed or until they expire
+CREATE TABLE error_summaries (
+    fingerprint VARCHAR(64) PRIMARY KEY,
+    summary TEXT NOT NULL,
+    hypotheses TEXT[] NOT NULL DEFAULT '{}',
+    model VARCHAR(100) NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- JSON schema each project's event context is validated against at ingestion
+CREATE TABLE project_context_schemas (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    schema JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Imports of other error trackers' exports, run in the background
+CREATE TABLE import_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    format VARCHAR(20) NOT NULL, -- sentry, rollbar
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    project_id UUID,
+    total_groups INTEGER NOT NULL DEFAULT 0,
+    total_events INTEGER NOT NULL DEFAULT 0,
+    processed_events INTEGER NOT NULL DEFAULT 0,
+    imported_events INTEGER NOT NULL DEFAULT 0,
+    failed_events INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Exports of errors to CSV or JSON files, written in the background
+CREATE TABLE export_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    format VARCHAR(10) NOT NULL, -- csv, json
+    destination VARCHAR(20) NOT NULL, -- download, s3
+    filter JSONB NOT NULL DEFAULT '{}',
+    from_time TIMESTAMP WITH TIME ZONE,
+    to_time TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed, expired
+    project_id UUID,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    exported_rows INTEGER NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    s3_key TEXT,
+    error TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_export_jobs_expires_at ON export_jobs(expires_at) WHERE status = 'completed';
+
+-- Remote configuration served to each project's SDKs
+CREATE TABLE sdk_configs (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    sample_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+    level_sample_rates JSONB NOT NULL DEFAULT '{}',
+    allowed_levels JSONB NOT NULL DEFAULT '[]',
+    scrub_rules JSONB NOT NULL DEFAULT '[]',
+    flush_interval_seconds INTEGER NOT NULL DEFAULT 5,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Organization-wide ingestion quotas across all of an org's projects (0 means unlimited)
+CREATE TABLE organization_quotas (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    daily_soft_limit INTEGER DEFAULT 0,
+    daily_hard_limit INTEGER DEFAULT 0,
+    monthly_soft_limit INTEGER DEFAULT 0,
+    monthly_hard_limit INTEGER DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_projects_org_id ON projects(org_id);
+CREATE INDEX idx_api_keys_org_id ON api_keys(org_id);
+CREATE INDEX idx_team_members_org_id ON team_members(org_id);
+
+-- Feature flags gate capabilities during rollout. A project override wins over
+-- an organization override, which wins over the flag's default.
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE, -- default for orgs and projects without an override
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE feature_flag_overrides (
+    flag_key VARCHAR(100) NOT NULL, -- built-in flags may have no feature_flags row
+    scope VARCHAR(20) NOT NULL, -- org, project
+    scope_id UUID NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (flag_key, scope, scope_id)
+);
+
+-- External sinks that accepted error events are forwarded to
+CREATE TABLE event_sinks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- http, kafka, s3
+    config JSONB NOT NULL DEFAULT '{}', -- destination: url, topic, bucket, region, endpoint, prefix
+    secret TEXT, -- signs HTTP deliveries, or Kafka REST Proxy credentials; encrypted when ENCRYPTION_KEYS is set
+    levels TEXT[], -- forward only these levels; NULL forwards all
+    sources TEXT[], -- forward only these sources; NULL forwards all
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_delivered_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT, -- cleared by the next successful delivery
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Quiet hours for one alert notification target, e.g. email only during office hours
+CREATE TABLE notification_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    target TEXT NOT NULL, -- as in alert rule notifications; webhook URLs encrypted when ENCRYPTION_KEYS is set
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    quiet_start VARCHAR(5) NOT NULL DEFAULT '', -- HH:MM; quiet every day until quiet_end, '' for none
+    quiet_end VARCHAR(5) NOT NULL DEFAULT '',
+    quiet_days TEXT[], -- weekdays that are quiet all day: mon..sun
+    fallback TEXT, -- target notified instead during quiet hours; NULL drops the notification
+    critical_override BOOLEAN NOT NULL DEFAULT TRUE, -- critical rules notify during quiet hours
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- In-app inbox entries, one per team member notified
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    member_id UUID NOT NULL REFERENCES team_members(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL, -- alert, threshold, watch, ownership
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    rule_id UUID, -- alert rule that sent it, if any
+    error_id UUID,
+    fingerprint VARCHAR(64),
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_member ON notifications(member_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(member_id) WHERE read_at IS NULL;
+
+-- Event markers on trend charts: deploys, incidents and config changes
+CREATE TABLE annotations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE, -- NULL applies to every project
+    environment VARCHAR(50), -- NULL applies to every environment
+    source VARCHAR(50), -- NULL applies to every source
+    type VARCHAR(20) NOT NULL, -- deploy, incident, config-change
+    text TEXT NOT NULL,
+    url TEXT,
+    incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL, -- incident that created it
+    created_by VARCHAR(255) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_annotations_timestamp ON annotations(timestamp DESC);
+
+-- Per-project policy resolving error groups with no events for a number of days
+CREATE TABLE auto_resolve_policies (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    days INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Connected notification services, e.g. a Slack workspace or PagerDuty service
+CREATE TABLE integrations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- slack, email, webhook, pagerduty, opsgenie
+    config JSONB NOT NULL DEFAULT '{}', -- non-secret settings such as the channel
+    secret TEXT, -- token or webhook URL; encrypted when ENCRYPTION_KEYS is set
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    external_id VARCHAR(255) UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);